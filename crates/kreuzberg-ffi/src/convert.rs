@@ -0,0 +1,253 @@
+//! Document conversion FFI module.
+//!
+//! Exposes the LibreOffice conversion of the core (see `kreuzberg::extraction::libreoffice`) so
+//! that bindings convert documents with the same LibreOffice lookup, profile isolation and
+//! timeout handling the core uses for legacy Office formats.
+//!
+//! # Example (C)
+//!
+//! ```c
+//! CConvertedDocument* pdf = kreuzberg_convert_file("report.docx", "application/pdf", 0);
+//! if (pdf != NULL) {
+//!     fwrite(pdf->data, 1, pdf->len, out);
+//!     kreuzberg_free_converted_document(pdf);
+//! } else {
+//!     printf("Error: %s\n", kreuzberg_last_error());
+//! }
+//! ```
+
+use crate::{clear_last_error, set_last_error};
+use kreuzberg::extraction::libreoffice::{self, DEFAULT_CONVERSION_TIMEOUT};
+use std::ffi::CStr;
+use std::future::Future;
+use std::os::raw::c_char;
+use std::path::Path;
+use std::ptr;
+use std::sync::OnceLock;
+use tokio::runtime::Runtime;
+
+/// A converted document returned by `kreuzberg_convert_file` and `kreuzberg_convert_bytes`.
+///
+/// Free it with `kreuzberg_free_converted_document`.
+#[repr(C)]
+pub struct CConvertedDocument {
+    /// The converted document bytes
+    pub data: *mut u8,
+    /// The number of bytes in `data`
+    pub len: usize,
+}
+
+/// Reads a non-NULL UTF-8 C string argument, recording an error naming `arg` otherwise.
+///
+/// # Safety
+///
+/// `value` must be NULL or a valid null-terminated C string.
+unsafe fn str_arg<'a>(value: *const c_char, arg: &str) -> Option<&'a str> {
+    if value.is_null() {
+        set_last_error(format!("{} cannot be NULL", arg));
+        return None;
+    }
+    // SAFETY: Caller guarantees that value is a valid null-terminated C string.
+    match unsafe { CStr::from_ptr(value) }.to_str() {
+        Ok(s) => Some(s),
+        Err(e) => {
+            set_last_error(format!("Invalid UTF-8 in {}: {}", arg, e));
+            None
+        }
+    }
+}
+
+/// Runtime the conversions run on. It outlives each call so that the temporary directories the
+/// core removes in background tasks are cleaned up.
+static CONVERSION_RUNTIME: OnceLock<std::io::Result<Runtime>> = OnceLock::new();
+
+/// Runs a conversion to completion and hands the bytes to the caller.
+fn run_conversion(conversion: impl Future<Output = kreuzberg::Result<Vec<u8>>>) -> *mut CConvertedDocument {
+    let runtime = match CONVERSION_RUNTIME.get_or_init(|| {
+        tokio::runtime::Builder::new_multi_thread()
+            .worker_threads(1)
+            .enable_all()
+            .build()
+    }) {
+        Ok(runtime) => runtime,
+        Err(e) => {
+            set_last_error(format!("Failed to create runtime: {}", e));
+            return ptr::null_mut();
+        }
+    };
+    match runtime.block_on(conversion) {
+        Ok(bytes) => {
+            let data = Box::into_raw(bytes.into_boxed_slice());
+            Box::into_raw(Box::new(CConvertedDocument {
+                data: data as *mut u8,
+                len: data.len(),
+            }))
+        }
+        Err(e) => {
+            set_last_error(e.to_string());
+            ptr::null_mut()
+        }
+    }
+}
+
+fn timeout_or_default(timeout_seconds: u64) -> u64 {
+    if timeout_seconds == 0 {
+        DEFAULT_CONVERSION_TIMEOUT
+    } else {
+        timeout_seconds
+    }
+}
+
+/// Converts the document at `file_path` to `target_mime` (e.g. "application/pdf") using
+/// LibreOffice in headless mode.
+///
+/// # Arguments
+///
+/// - `file_path`: Path of the document; LibreOffice detects its format
+/// - `target_mime`: MIME type to convert to
+/// - `timeout_seconds`: Time after which the LibreOffice process is killed (0 for the default
+///   of 300 seconds)
+///
+/// # Safety
+///
+/// - `file_path` and `target_mime` must be valid null-terminated C strings
+/// - The returned pointer must be freed with `kreuzberg_free_converted_document`
+/// - Returns NULL on error (check `kreuzberg_last_error` for details)
+///
+/// # C Signature
+///
+/// ```c
+/// CConvertedDocument* kreuzberg_convert_file(const char* file_path, const char* target_mime, uint64_t timeout_seconds);
+/// ```
+#[unsafe(no_mangle)]
+pub unsafe extern "C" fn kreuzberg_convert_file(
+    file_path: *const c_char,
+    target_mime: *const c_char,
+    timeout_seconds: u64,
+) -> *mut CConvertedDocument {
+    crate::ffi_panic_guard!("kreuzberg_convert_file", {
+        clear_last_error();
+
+        let Some(path) = (unsafe { str_arg(file_path, "file_path") }) else {
+            return ptr::null_mut();
+        };
+        let Some(target) = (unsafe { str_arg(target_mime, "target_mime") }) else {
+            return ptr::null_mut();
+        };
+        run_conversion(libreoffice::convert_file(
+            Path::new(path),
+            target,
+            timeout_or_default(timeout_seconds),
+        ))
+    })
+}
+
+/// Converts an in-memory document of type `source_mime` to `target_mime` using LibreOffice in
+/// headless mode.
+///
+/// # Arguments
+///
+/// - `data`, `data_len`: The document bytes
+/// - `source_mime`: MIME type of the document
+/// - `target_mime`: MIME type to convert to
+/// - `timeout_seconds`: Time after which the LibreOffice process is killed (0 for the default
+///   of 300 seconds)
+///
+/// # Safety
+///
+/// - `data` must be a valid pointer to a byte array of length `data_len`
+/// - `source_mime` and `target_mime` must be valid null-terminated C strings
+/// - The returned pointer must be freed with `kreuzberg_free_converted_document`
+/// - Returns NULL on error (check `kreuzberg_last_error` for details)
+///
+/// # C Signature
+///
+/// ```c
+/// CConvertedDocument* kreuzberg_convert_bytes(const uint8_t* data, uintptr_t data_len,
+///                                             const char* source_mime, const char* target_mime,
+///                                             uint64_t timeout_seconds);
+/// ```
+#[unsafe(no_mangle)]
+pub unsafe extern "C" fn kreuzberg_convert_bytes(
+    data: *const u8,
+    data_len: usize,
+    source_mime: *const c_char,
+    target_mime: *const c_char,
+    timeout_seconds: u64,
+) -> *mut CConvertedDocument {
+    crate::ffi_panic_guard!("kreuzberg_convert_bytes", {
+        clear_last_error();
+
+        if data.is_null() || data_len == 0 {
+            set_last_error("data cannot be empty".to_string());
+            return ptr::null_mut();
+        }
+        let Some(source) = (unsafe { str_arg(source_mime, "source_mime") }) else {
+            return ptr::null_mut();
+        };
+        let Some(target) = (unsafe { str_arg(target_mime, "target_mime") }) else {
+            return ptr::null_mut();
+        };
+        // SAFETY: Caller guarantees that data points to data_len readable bytes.
+        let bytes = unsafe { std::slice::from_raw_parts(data, data_len) };
+        run_conversion(libreoffice::convert_bytes(
+            bytes,
+            source,
+            target,
+            timeout_or_default(timeout_seconds),
+        ))
+    })
+}
+
+/// Frees a document returned by `kreuzberg_convert_file` or `kreuzberg_convert_bytes`.
+///
+/// # Safety
+///
+/// - `document` must be NULL or a pointer returned by one of the conversion functions
+/// - `document` must not be used after this call
+///
+/// # C Signature
+///
+/// ```c
+/// void kreuzberg_free_converted_document(CConvertedDocument* document);
+/// ```
+#[unsafe(no_mangle)]
+pub unsafe extern "C" fn kreuzberg_free_converted_document(document: *mut CConvertedDocument) {
+    if document.is_null() {
+        return;
+    }
+    // SAFETY: The document and its data were allocated by run_conversion with Box::into_raw.
+    let document = unsafe { Box::from_raw(document) };
+    if !document.data.is_null() {
+        drop(unsafe { Box::from_raw(ptr::slice_from_raw_parts_mut(document.data, document.len)) });
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_convert_bytes_rejects_unsupported_target() {
+        let data = b"text";
+        let result = unsafe {
+            kreuzberg_convert_bytes(
+                data.as_ptr(),
+                data.len(),
+                c"text/plain".as_ptr(),
+                c"image/png".as_ptr(),
+                0,
+            )
+        };
+        assert!(result.is_null());
+        let error = unsafe { CStr::from_ptr(crate::kreuzberg_last_error()) };
+        assert!(error.to_str().unwrap().contains("Unsupported format"));
+    }
+
+    #[test]
+    fn test_convert_file_rejects_null_arguments() {
+        let result = unsafe { kreuzberg_convert_file(ptr::null(), c"application/pdf".as_ptr(), 0) };
+        assert!(result.is_null());
+        unsafe { kreuzberg_free_converted_document(ptr::null_mut()) };
+    }
+}
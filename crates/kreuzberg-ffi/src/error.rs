@@ -66,18 +66,16 @@
 //! - `kreuzberg_error_code_internal()` -> 7
 //! - `kreuzberg_error_code_count()` -> 8
 //! - `kreuzberg_error_code_name(code: u32)` -> *const c_char (error name)
+//! - `kreuzberg_stable_error_code(kind: *const c_char)` -> *const c_char (stable "KZB-NNNN" code)
 //!
 //! # Thread Safety
 //!
 //! All functions are thread-safe and have no runtime overhead (compile-time constants).
 
-use std::ffi::CString;
+use std::ffi::{CStr, CString};
 use std::os::raw::c_char;
 use std::ptr;
 
-#[cfg(test)]
-use std::ffi::CStr;
-
 /// Centralized error codes for all Kreuzberg bindings.
 ///
 /// These codes are the single source of truth for error classification across
@@ -384,6 +382,84 @@ pub extern "C" fn kreuzberg_error_code_description(code: u32) -> *const c_char {
     }
 }
 
+/// Stable, machine-readable error codes of the form `KZB-NNNN`, keyed by error kind.
+///
+/// The codes let users alert and aggregate on errors across bindings instead of parsing
+/// messages. They never change meaning between releases; new kinds may be added. The thousands
+/// digit is the numeric [`ErrorCode`] plus one, so every code of a family shares the native
+/// classification. Kinds the native library does not raise itself (e.g. `cancelled`) are
+/// listed so that every binding reports them with the same code.
+pub const STABLE_ERROR_CODES: &[(&str, &CStr)] = &[
+    ("unknown", c"KZB-0000"),
+    ("validation", c"KZB-1000"),
+    ("serialization", c"KZB-1100"),
+    ("parsing", c"KZB-2000"),
+    ("image_processing", c"KZB-2100"),
+    ("ocr", c"KZB-3000"),
+    ("missing_dependency", c"KZB-4000"),
+    ("io", c"KZB-5000"),
+    ("cache", c"KZB-5100"),
+    ("plugin", c"KZB-6000"),
+    ("unsupported_format", c"KZB-7000"),
+    ("internal", c"KZB-8000"),
+    ("panic", c"KZB-8001"),
+    ("lock_poisoned", c"KZB-8002"),
+    ("cancelled", c"KZB-8100"),
+    ("deadline_exceeded", c"KZB-8101"),
+];
+
+/// Returns the stable code of an error kind, or the `unknown` code (`KZB-0000`) for a kind
+/// without one.
+pub fn stable_error_code(kind: &str) -> &'static CStr {
+    STABLE_ERROR_CODES
+        .iter()
+        .find(|(name, _)| *name == kind)
+        .map_or(c"KZB-0000", |(_, code)| code)
+}
+
+impl ErrorCode {
+    /// Returns the stable `KZB-NNNN` code of this error code's family.
+    #[inline]
+    pub fn stable_code(self) -> &'static CStr {
+        stable_error_code(self.name())
+    }
+}
+
+/// Returns the stable `KZB-NNNN` code of an error kind as a C string.
+///
+/// # Arguments
+///
+/// - `kind`: Error kind, e.g. an error code name ("validation", "ocr") or one of "serialization",
+///   "image_processing", "cache", "panic", "lock_poisoned", "cancelled", "deadline_exceeded"
+///
+/// # Returns
+///
+/// Pointer to a null-terminated C string with the stable code (e.g., "KZB-1000").
+/// Returns a pointer to "KZB-0000" if the kind is NULL or unknown.
+///
+/// The returned pointer is valid for the lifetime of the program and should not be freed.
+///
+/// # Safety
+///
+/// - `kind` must be a valid null-terminated C string or NULL
+///
+/// # C Signature
+///
+/// ```c
+/// const char* kreuzberg_stable_error_code(const char* kind);
+/// ```
+#[unsafe(no_mangle)]
+pub unsafe extern "C" fn kreuzberg_stable_error_code(kind: *const c_char) -> *const c_char {
+    if kind.is_null() {
+        return stable_error_code("unknown").as_ptr();
+    }
+    // SAFETY: Caller guarantees that kind is a valid null-terminated C string.
+    match unsafe { CStr::from_ptr(kind) }.to_str() {
+        Ok(kind) => stable_error_code(kind).as_ptr(),
+        Err(_) => stable_error_code("unknown").as_ptr(),
+    }
+}
+
 // Error Details Structure for FFI
 //
 /// C-compatible structured error details returned by `kreuzberg_get_error_details()`.
@@ -653,6 +729,22 @@ pub unsafe extern "C" fn kreuzberg_classify_error(error_message: *const c_char)
 mod tests {
     use super::*;
 
+    #[test]
+    fn test_stable_error_codes() {
+        for code in 0..kreuzberg_error_code_count() {
+            let code = ErrorCode::from_code(code).unwrap();
+            let expected = format!("KZB-{}000", code as u32 + 1);
+            assert_eq!(code.stable_code().to_str().unwrap(), expected);
+        }
+        assert_eq!(stable_error_code("panic"), c"KZB-8001");
+        assert_eq!(stable_error_code("no_such_kind"), c"KZB-0000");
+
+        let code = unsafe { CStr::from_ptr(kreuzberg_stable_error_code(c"cancelled".as_ptr())) };
+        assert_eq!(code, c"KZB-8100");
+        let code = unsafe { CStr::from_ptr(kreuzberg_stable_error_code(ptr::null())) };
+        assert_eq!(code, c"KZB-0000");
+    }
+
     #[test]
     fn test_error_code_values() {
         assert_eq!(ErrorCode::Validation as u32, 0);
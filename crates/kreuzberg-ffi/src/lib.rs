@@ -3,30 +3,40 @@
 //! Provides a C-compatible API that can be consumed by Java (Panama FFI),
 //! Go (cgo), C# (P/Invoke), Zig, and other languages with C FFI support.
 
+mod allocator;
 mod batch_streaming;
 mod config;
+mod convert;
 mod error;
 mod panic_shield;
+mod pdf_pages;
 mod result;
 mod result_pool;
 mod result_view;
+mod statistics;
 mod string_intern;
 mod validation;
 
+pub use allocator::{CNativeMemoryStats, kreuzberg_native_memory_stats};
 pub use batch_streaming::{
     ErrorCallback, ResultCallback, kreuzberg_extract_batch_parallel, kreuzberg_extract_batch_streaming,
 };
+pub use convert::{
+    CConvertedDocument, kreuzberg_convert_bytes, kreuzberg_convert_file, kreuzberg_free_converted_document,
+};
 pub use error::ErrorCode as KreuzbergErrorCode;
 pub use error::{
     CErrorDetails, kreuzberg_classify_error, kreuzberg_error_code_count, kreuzberg_error_code_description,
     kreuzberg_error_code_internal, kreuzberg_error_code_io, kreuzberg_error_code_missing_dependency,
     kreuzberg_error_code_name, kreuzberg_error_code_ocr, kreuzberg_error_code_parsing, kreuzberg_error_code_plugin,
     kreuzberg_error_code_unsupported_format, kreuzberg_error_code_validation, kreuzberg_get_error_details,
+    kreuzberg_stable_error_code,
 };
 pub use panic_shield::{
     ErrorCode, StructuredError, clear_structured_error, get_last_error_code, get_last_error_message,
     get_last_panic_context, set_structured_error,
 };
+pub use pdf_pages::{kreuzberg_free_bytes, kreuzberg_pdf_page_count, kreuzberg_pdf_page_range};
 pub use result::{
     CMetadataField, kreuzberg_result_get_chunk_count, kreuzberg_result_get_detected_language,
     kreuzberg_result_get_metadata_field, kreuzberg_result_get_page_count,
@@ -38,6 +48,9 @@ pub use result_pool::{
 pub use result_view::{
     CExtractionResultView, kreuzberg_get_result_view, kreuzberg_view_get_content, kreuzberg_view_get_mime_type,
 };
+pub use statistics::{
+    kreuzberg_batch_extract_statistics_files, kreuzberg_extract_statistics_bytes, kreuzberg_extract_statistics_file,
+};
 pub use string_intern::{
     CStringInternStats, kreuzberg_free_interned_string, kreuzberg_intern_string, kreuzberg_string_intern_reset,
     kreuzberg_string_intern_stats,
@@ -49,6 +62,8 @@ use std::os::raw::c_char;
 use std::path::Path;
 use std::ptr;
 use std::sync::Arc;
+use std::sync::atomic::{AtomicU64, Ordering};
+use std::time::Duration;
 
 use async_trait::async_trait;
 use kreuzberg::core::config::{ExtractionConfig, OcrConfig};
@@ -1676,6 +1691,48 @@ pub unsafe extern "C" fn kreuzberg_version() -> *const c_char {
     concat!(env!("CARGO_PKG_VERSION"), "\0").as_ptr() as *const c_char
 }
 
+/// Hard timeout for plugin callbacks in milliseconds (0 = none).
+static PLUGIN_TIMEOUT_MS: AtomicU64 = AtomicU64::new(0);
+
+/// Set a hard timeout for every call into a plugin registered through a callback (OCR backends,
+/// post-processors, validators and document extractors).
+///
+/// A callback that has not returned within `timeout_ms` milliseconds fails its extraction with
+/// a plugin error. The callback itself cannot be interrupted: it keeps running on its worker
+/// thread and its result is discarded, so the timeout is a backstop for plugins that ignore
+/// their own deadlines. `0` disables the timeout, which is the default.
+///
+/// # Example (C)
+///
+/// ```c
+/// kreuzberg_set_plugin_timeout_ms(30000);
+/// ```
+#[unsafe(no_mangle)]
+pub extern "C" fn kreuzberg_set_plugin_timeout_ms(timeout_ms: u64) {
+    PLUGIN_TIMEOUT_MS.store(timeout_ms, Ordering::Relaxed);
+}
+
+/// Get the plugin callback timeout set with `kreuzberg_set_plugin_timeout_ms` (0 = none).
+#[unsafe(no_mangle)]
+pub extern "C" fn kreuzberg_plugin_timeout_ms() -> u64 {
+    PLUGIN_TIMEOUT_MS.load(Ordering::Relaxed)
+}
+
+/// Await a plugin callback task, failing with a plugin error once the timeout set with
+/// `kreuzberg_set_plugin_timeout_ms` has passed.
+async fn with_plugin_timeout<F: std::future::Future>(plugin_name: &str, task: F) -> Result<F::Output> {
+    let timeout_ms = PLUGIN_TIMEOUT_MS.load(Ordering::Relaxed);
+    if timeout_ms == 0 {
+        return Ok(task.await);
+    }
+    tokio::time::timeout(Duration::from_millis(timeout_ms), task)
+        .await
+        .map_err(|_| KreuzbergError::Plugin {
+            message: format!("Plugin callback did not return within {} ms", timeout_ms),
+            plugin_name: plugin_name.to_string(),
+        })
+}
+
 /// Type alias for the OCR backend callback function.
 ///
 /// # Parameters
@@ -1781,7 +1838,7 @@ impl OcrBackend for FfiOcrBackend {
         let image_data = image_bytes.to_vec();
         let config_json_owned = config_json.clone();
 
-        let result_text = tokio::task::spawn_blocking(move || {
+        let task = tokio::task::spawn_blocking(move || {
             let config_cstring = CString::new(config_json_owned).map_err(|e| KreuzbergError::Validation {
                 message: format!("Failed to create C string from config JSON: {}", e),
                 source: Some(Box::new(e)),
@@ -1808,12 +1865,13 @@ impl OcrBackend for FfiOcrBackend {
             unsafe { kreuzberg_free_string(result_ptr) };
 
             Ok(text)
-        })
-        .await
-        .map_err(|e| KreuzbergError::Ocr {
-            message: format!("OCR backend task panicked: {}", e),
-            source: Some(Box::new(e)),
-        })??;
+        });
+        let result_text = with_plugin_timeout(&self.name, task)
+            .await?
+            .map_err(|e| KreuzbergError::Ocr {
+                message: format!("OCR backend task panicked: {}", e),
+                source: Some(Box::new(e)),
+            })??;
 
         Ok(ExtractionResult {
             content: result_text,
@@ -2049,7 +2107,7 @@ impl kreuzberg::plugins::PostProcessor for FfiPostProcessor {
         let processor_name = self.name.clone();
         let result_json_owned = result_json.clone();
 
-        let processed_json = tokio::task::spawn_blocking(move || {
+        let task = tokio::task::spawn_blocking(move || {
             let result_cstring = CString::new(result_json_owned).map_err(|e| KreuzbergError::Validation {
                 message: format!("Failed to create C string from result JSON: {}", e),
                 source: Some(Box::new(e)),
@@ -2076,12 +2134,13 @@ impl kreuzberg::plugins::PostProcessor for FfiPostProcessor {
             unsafe { kreuzberg_free_string(processed_ptr) };
 
             Ok(json)
-        })
-        .await
-        .map_err(|e| KreuzbergError::Plugin {
-            message: format!("PostProcessor task panicked: {}", e),
-            plugin_name: self.name.clone(),
-        })??;
+        });
+        let processed_json = with_plugin_timeout(&self.name, task)
+            .await?
+            .map_err(|e| KreuzbergError::Plugin {
+                message: format!("PostProcessor task panicked: {}", e),
+                plugin_name: self.name.clone(),
+            })??;
 
         let processed_result: ExtractionResult =
             serde_json::from_str(&processed_json).map_err(|e| KreuzbergError::Plugin {
@@ -2502,7 +2561,7 @@ impl kreuzberg::plugins::DocumentExtractor for FfiDocumentExtractor {
         let mime_type_owned = mime_type.to_string();
         let config_json_owned = config_json.clone();
 
-        let result_json = tokio::task::spawn_blocking(move || {
+        let task = tokio::task::spawn_blocking(move || {
             let mime_cstr = match CString::new(mime_type_owned.clone()) {
                 Ok(s) => s,
                 Err(e) => {
@@ -2546,9 +2605,8 @@ impl kreuzberg::plugins::DocumentExtractor for FfiDocumentExtractor {
             })?;
 
             Ok(result_str.to_string())
-        })
-        .await
-        .map_err(|e| {
+        });
+        let result_json = with_plugin_timeout(&self.name, task).await?.map_err(|e| {
             KreuzbergError::Other(format!(
                 "Task join error in extractor '{}': {}",
                 extractor_name_error, e
@@ -2865,7 +2923,7 @@ impl kreuzberg::plugins::Validator for FfiValidator {
         let validator_name = self.name.clone();
         let result_json_owned = result_json.clone();
 
-        let error_msg = tokio::task::spawn_blocking(move || {
+        let task = tokio::task::spawn_blocking(move || {
             let result_cstring = CString::new(result_json_owned).map_err(|e| KreuzbergError::Validation {
                 message: format!("Failed to create C string from result JSON: {}", e),
                 source: Some(Box::new(e)),
@@ -2889,12 +2947,13 @@ impl kreuzberg::plugins::Validator for FfiValidator {
             unsafe { kreuzberg_free_string(error_ptr) };
 
             Ok(Some(error_msg))
-        })
-        .await
-        .map_err(|e| KreuzbergError::Plugin {
-            message: format!("Validator task panicked: {}", e),
-            plugin_name: self.name.clone(),
-        })??;
+        });
+        let error_msg = with_plugin_timeout(&self.name, task)
+            .await?
+            .map_err(|e| KreuzbergError::Plugin {
+                message: format!("Validator task panicked: {}", e),
+                plugin_name: self.name.clone(),
+            })??;
 
         if let Some(msg) = error_msg {
             return Err(KreuzbergError::Validation {
@@ -0,0 +1,136 @@
+//! PDF page range FFI module.
+//!
+//! Counts the pages of a PDF and copies a range of them into a new PDF, so that bindings can
+//! extract a long document a range of pages at a time and checkpoint the pages already done.
+//!
+//! # Example (C)
+//!
+//! ```c
+//! int32_t pages = kreuzberg_pdf_page_count(data, len);
+//! for (int32_t i = 0; i < pages; i++) {
+//!     uintptr_t page_len = 0;
+//!     uint8_t* page = kreuzberg_pdf_page_range(data, len, i, 1, &page_len);
+//!     if (page == NULL) {
+//!         printf("Error: %s\n", kreuzberg_last_error());
+//!         break;
+//!     }
+//!     CExtractionResult* result = kreuzberg_extract_bytes_sync(page, page_len, "application/pdf");
+//!     kreuzberg_free_bytes(page, page_len);
+//!     /* ... */
+//! }
+//! ```
+
+use crate::{clear_last_error, set_last_error};
+use std::ptr;
+
+/// Count the pages of a PDF.
+///
+/// # Safety
+///
+/// - `data` must be a valid pointer to a byte array of length `data_len`
+/// - Returns -1 on error (check `kreuzberg_last_error` for details)
+#[unsafe(no_mangle)]
+pub unsafe extern "C" fn kreuzberg_pdf_page_count(data: *const u8, data_len: usize) -> i32 {
+    crate::ffi_panic_guard_i32!("kreuzberg_pdf_page_count", {
+        clear_last_error();
+
+        if data.is_null() {
+            set_last_error("data cannot be NULL".to_string());
+            return -1;
+        }
+        let bytes = unsafe { std::slice::from_raw_parts(data, data_len) };
+
+        match kreuzberg::pdf::page_count(bytes) {
+            Ok(count) => i32::try_from(count).unwrap_or_else(|_| {
+                set_last_error(format!("PDF has too many pages: {}", count));
+                -1
+            }),
+            Err(e) => {
+                set_last_error(e.to_string());
+                -1
+            }
+        }
+    })
+}
+
+/// Copy `page_count` pages of a PDF, starting at the 0-based page `first_page`, into a new PDF.
+///
+/// # Safety
+///
+/// - `data` must be a valid pointer to a byte array of length `data_len`
+/// - `out_len` must be a valid pointer; the length of the new PDF is written to it
+/// - The returned pointer must be freed with `kreuzberg_free_bytes`, passing that length
+/// - Returns NULL on error (check `kreuzberg_last_error` for details)
+#[unsafe(no_mangle)]
+pub unsafe extern "C" fn kreuzberg_pdf_page_range(
+    data: *const u8,
+    data_len: usize,
+    first_page: usize,
+    page_count: usize,
+    out_len: *mut usize,
+) -> *mut u8 {
+    crate::ffi_panic_guard!("kreuzberg_pdf_page_range", {
+        clear_last_error();
+
+        if data.is_null() {
+            set_last_error("data cannot be NULL".to_string());
+            return ptr::null_mut();
+        }
+        if out_len.is_null() {
+            set_last_error("out_len cannot be NULL".to_string());
+            return ptr::null_mut();
+        }
+        let bytes = unsafe { std::slice::from_raw_parts(data, data_len) };
+
+        match kreuzberg::pdf::extract_page_range(bytes, first_page, page_count) {
+            Ok(range) => {
+                let range = range.into_boxed_slice();
+                unsafe { *out_len = range.len() };
+                Box::into_raw(range) as *mut u8
+            }
+            Err(e) => {
+                set_last_error(e.to_string());
+                ptr::null_mut()
+            }
+        }
+    })
+}
+
+/// Free a byte buffer returned by `kreuzberg_pdf_page_range`.
+///
+/// # Safety
+///
+/// - `data` must have been returned by `kreuzberg_pdf_page_range` with length `len`, or be NULL
+/// - `data` must not be used after this call
+#[unsafe(no_mangle)]
+pub unsafe extern "C" fn kreuzberg_free_bytes(data: *mut u8, len: usize) {
+    if !data.is_null() {
+        unsafe { drop(Box::from_raw(ptr::slice_from_raw_parts_mut(data, len))) };
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_pdf_page_count_rejects_invalid_input() {
+        unsafe {
+            assert_eq!(kreuzberg_pdf_page_count(ptr::null(), 0), -1);
+            let data = b"not a pdf";
+            assert_eq!(kreuzberg_pdf_page_count(data.as_ptr(), data.len()), -1);
+        }
+    }
+
+    #[test]
+    fn test_pdf_page_range_rejects_invalid_input() {
+        let mut len = 0usize;
+        let data = b"not a pdf";
+        unsafe {
+            assert!(kreuzberg_pdf_page_range(ptr::null(), 0, 0, 1, &mut len).is_null());
+            assert!(kreuzberg_pdf_page_range(data.as_ptr(), data.len(), 0, 1, ptr::null_mut()).is_null());
+            assert!(kreuzberg_pdf_page_range(data.as_ptr(), data.len(), 0, 1, &mut len).is_null());
+            kreuzberg_free_bytes(ptr::null_mut(), 0);
+        }
+    }
+}
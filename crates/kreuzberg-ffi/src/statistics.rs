@@ -0,0 +1,479 @@
+//! Statistics-only extraction FFI module.
+//!
+//! Extracts documents and returns aggregate statistics about them instead of their content, so
+//! that bindings offering a statistics-only mode never receive the content, tables, images or
+//! format metadata. The extraction runs without the result cache and the Tesseract OCR cache,
+//! which would otherwise persist the content.
+//!
+//! The statistics match those the Go binding computes for its own extractors: sentences, words,
+//! syllables and entities are counted with the same rules.
+//!
+//! # Example (C)
+//!
+//! ```c
+//! char* stats = kreuzberg_extract_statistics_file("report.pdf", NULL);
+//! if (stats != NULL) {
+//!     printf("%s\n", stats);
+//!     kreuzberg_free_string(stats);
+//! } else {
+//!     printf("Error: %s\n", kreuzberg_last_error());
+//! }
+//! ```
+
+use crate::{clear_last_error, parse_extraction_config_from_json, set_last_error, string_to_c_string};
+use kreuzberg::core::config::ExtractionConfig;
+use kreuzberg::types::ExtractionResult;
+use regex::Regex;
+use serde::Serialize;
+use std::collections::{BTreeMap, HashSet};
+use std::ffi::CStr;
+use std::os::raw::c_char;
+use std::path::Path;
+use std::ptr;
+use std::sync::LazyLock;
+
+/// Pattern-detected entities, in the order they are matched. Matches are removed from the text
+/// before later patterns run, so e.g. the digits of a URL are not also counted as a phone number.
+static ENTITY_PATTERNS: LazyLock<Vec<(&'static str, Regex)>> = LazyLock::new(|| {
+    [
+        ("email", r"[\pL\pN._%+-]+@[\pL\pN-]+(?:\.[\pL\pN-]+)*\.\pL{2,}"),
+        ("url", r#"(?i)\b(?:https?://|www\.)[^\s<>"]+"#),
+        (
+            "ip_address",
+            r"\b(?:(?:25[0-5]|2[0-4][0-9]|1?[0-9]?[0-9])\.){3}(?:25[0-5]|2[0-4][0-9]|1?[0-9]?[0-9])\b",
+        ),
+        (
+            "date",
+            r"\b(?:[0-9]{4}-[0-9]{2}-[0-9]{2}|[0-9]{1,2}[./][0-9]{1,2}[./][0-9]{2,4})\b",
+        ),
+        (
+            "money",
+            r"(?:[$€£¥]\s?[0-9][0-9,.]*[0-9]|\b[0-9][0-9,.]*[0-9]?\s?(?:USD|EUR|GBP|JPY|CHF)\b)",
+        ),
+        ("percent", r"\b[0-9]+(?:[.,][0-9]+)?\s?%"),
+        (
+            "phone",
+            r"(?:\+[0-9]{1,3}[\s.-]?)?(?:\([0-9]{1,4}\)[\s.-]?)?[0-9]{2,4}[\s.-][0-9]{2,4}[\s.-][0-9]{2,6}\b",
+        ),
+    ]
+    .into_iter()
+    .map(|(entity, pattern)| (entity, Regex::new(pattern).expect("entity patterns are valid")))
+    .collect()
+});
+
+/// Aggregate statistics about a document. None of the fields quote content.
+#[derive(Debug, Default, Serialize)]
+struct DocumentStatistics {
+    mime_type: String,
+    character_count: usize,
+    word_count: usize,
+    /// Number of distinct lower-cased words.
+    unique_word_count: usize,
+    sentence_count: usize,
+    line_count: usize,
+    page_count: usize,
+    table_count: usize,
+    image_count: usize,
+    /// Detected languages, when language detection is enabled.
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    languages: Vec<String>,
+    /// Pattern-detected entity counts by type.
+    #[serde(skip_serializing_if = "BTreeMap::is_empty")]
+    entities: BTreeMap<&'static str, usize>,
+    /// Only meaningful for English text.
+    flesch_reading_ease: f64,
+    lexical_diversity: f64,
+}
+
+/// The statistics-only form of an extraction result. A failed batch item keeps its error type
+/// but not its message, since parsers may quote the document in it.
+#[derive(Debug, Serialize)]
+struct StatisticsResult {
+    success: bool,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    error_type: Option<String>,
+    statistics: DocumentStatistics,
+}
+
+impl StatisticsResult {
+    fn from_result(result: ExtractionResult) -> Self {
+        let error_type = result.metadata.error.as_ref().map(|error| error.error_type.clone());
+        StatisticsResult {
+            success: error_type.is_none(),
+            error_type,
+            statistics: document_statistics(&result),
+        }
+    }
+}
+
+/// Compute the statistics of an extraction result.
+fn document_statistics(result: &ExtractionResult) -> DocumentStatistics {
+    let content = &result.content;
+    let mut stats = DocumentStatistics {
+        mime_type: result.mime_type.clone(),
+        character_count: content.chars().count(),
+        table_count: result.tables.len(),
+        image_count: result.images.as_ref().map_or(0, Vec::len),
+        page_count: result.pages.as_ref().map_or(0, Vec::len),
+        languages: result.detected_languages.clone().unwrap_or_default(),
+        entities: count_entities(content),
+        ..Default::default()
+    };
+    if !content.is_empty() {
+        stats.line_count = content.trim_end_matches('\n').matches('\n').count() + 1;
+    }
+    if stats.page_count == 0 {
+        stats.page_count = serde_json::to_value(&result.metadata)
+            .ok()
+            .and_then(|metadata| metadata.get("page_count").and_then(serde_json::Value::as_u64))
+            .map_or(0, |count| count as usize);
+    }
+
+    let mut unique = HashSet::new();
+    let mut syllables = 0usize;
+    for sentence in split_sentences(content) {
+        let words = sentence_words(sentence);
+        if words.is_empty() {
+            continue;
+        }
+        stats.sentence_count += 1;
+        stats.word_count += words.len();
+        for word in words {
+            syllables += estimate_syllables(word);
+            unique.insert(word.to_lowercase());
+        }
+    }
+    if stats.word_count > 0 {
+        let words = stats.word_count as f64;
+        stats.unique_word_count = unique.len();
+        stats.lexical_diversity = stats.unique_word_count as f64 / words;
+        stats.flesch_reading_ease =
+            206.835 - 1.015 * (words / stats.sentence_count as f64) - 84.6 * syllables as f64 / words;
+    }
+    stats
+}
+
+/// Count pattern-detected entities in text by type.
+fn count_entities(text: &str) -> BTreeMap<&'static str, usize> {
+    let mut counts = BTreeMap::new();
+    let mut text = text.to_string();
+    for (entity, pattern) in ENTITY_PATTERNS.iter() {
+        let matches = pattern.find_iter(&text).count();
+        if matches == 0 {
+            continue;
+        }
+        *counts.entry(*entity).or_insert(0) += matches;
+        text = pattern.replace_all(&text, " ").into_owned();
+    }
+    counts
+}
+
+/// Split text after sentence-ending punctuation followed by whitespace, and at blank lines so
+/// headings and list items without punctuation count as sentences.
+fn split_sentences(text: &str) -> Vec<&str> {
+    let mut sentences = Vec::new();
+    let mut start = 0;
+    let mut chars = text.char_indices().peekable();
+    while let Some((index, c)) = chars.next() {
+        let next = chars.peek().map(|&(_, next)| next);
+        let end = match c {
+            '.' | '!' | '?' | '…' => next.is_none_or(char::is_whitespace),
+            '。' | '！' | '？' => true,
+            '\n' => next == Some('\n'),
+            _ => false,
+        };
+        if end {
+            let after = index + c.len_utf8();
+            sentences.push(&text[start..after]);
+            start = after;
+        }
+    }
+    sentences.push(&text[start..]);
+    sentences
+}
+
+/// Return the words of a sentence: runs of letters and digits, with inner apostrophes and
+/// hyphens kept ("don't", "e-mail").
+fn sentence_words(sentence: &str) -> Vec<&str> {
+    sentence
+        .split(|c: char| !c.is_alphabetic() && !c.is_numeric() && c != '\'' && c != '’' && c != '-')
+        .map(|field| field.trim_matches(['\'', '’', '-']))
+        .filter(|word| !word.is_empty())
+        .collect()
+}
+
+/// Count vowel groups, ignoring a silent final "e"; words without vowels (numbers,
+/// abbreviations) count as one syllable.
+fn estimate_syllables(word: &str) -> usize {
+    let word = word.to_lowercase();
+    let mut count = 0;
+    let mut previous_vowel = false;
+    for c in word.chars() {
+        let vowel = "aeiouyàáâäèéêëìíîïòóôöùúûü".contains(c);
+        if vowel && !previous_vowel {
+            count += 1;
+        }
+        previous_vowel = vowel;
+    }
+    if count > 1 && word.ends_with('e') && !word.ends_with("le") {
+        count -= 1;
+    }
+    count.max(1)
+}
+
+/// Parse the optional config JSON and turn off the caches that would persist the content.
+///
+/// # Safety
+///
+/// `config_json` must be NULL or a valid null-terminated C string.
+unsafe fn statistics_config(config_json: *const c_char) -> Option<ExtractionConfig> {
+    let mut config = if config_json.is_null() {
+        ExtractionConfig::default()
+    } else {
+        // SAFETY: Caller guarantees that config_json is a valid null-terminated C string.
+        let config_str = match unsafe { CStr::from_ptr(config_json) }.to_str() {
+            Ok(s) => s,
+            Err(e) => {
+                set_last_error(format!("Invalid UTF-8 in config JSON: {}", e));
+                return None;
+            }
+        };
+        match parse_extraction_config_from_json(config_str) {
+            Ok(cfg) => cfg,
+            Err(e) => {
+                set_last_error(e);
+                return None;
+            }
+        }
+    };
+    config.use_cache = false;
+    if let Some(ocr) = config.ocr.as_mut() {
+        ocr.tesseract_config.get_or_insert_with(Default::default).use_cache = false;
+    }
+    Some(config)
+}
+
+/// Reads a non-NULL UTF-8 C string argument, recording an error naming `arg` otherwise.
+///
+/// # Safety
+///
+/// `value` must be NULL or a valid null-terminated C string.
+unsafe fn str_arg<'a>(value: *const c_char, arg: &str) -> Option<&'a str> {
+    if value.is_null() {
+        set_last_error(format!("{} cannot be NULL", arg));
+        return None;
+    }
+    // SAFETY: Caller guarantees that value is a valid null-terminated C string.
+    match unsafe { CStr::from_ptr(value) }.to_str() {
+        Ok(s) => Some(s),
+        Err(e) => {
+            set_last_error(format!("Invalid UTF-8 in {}: {}", arg, e));
+            None
+        }
+    }
+}
+
+fn to_json_c_string(value: &impl Serialize) -> *mut c_char {
+    match serde_json::to_string(value)
+        .map_err(|e| format!("Failed to serialize statistics: {}", e))
+        .and_then(string_to_c_string)
+    {
+        Ok(ptr) => ptr,
+        Err(e) => {
+            set_last_error(e);
+            ptr::null_mut()
+        }
+    }
+}
+
+fn extraction_to_json(extraction: kreuzberg::Result<ExtractionResult>) -> *mut c_char {
+    match extraction {
+        Ok(result) => to_json_c_string(&StatisticsResult::from_result(result)),
+        Err(e) => {
+            set_last_error(e.to_string());
+            ptr::null_mut()
+        }
+    }
+}
+
+/// Extracts the document at `file_path` and returns its statistics instead of its content.
+///
+/// The result is a JSON object `{"success": bool, "statistics": {...}}` whose `statistics` has
+/// the MIME type, character, word, unique word, sentence, line, page, table and image counts,
+/// the detected languages, the pattern-detected entity counts by type, the Flesch reading ease
+/// and the lexical diversity. The result cache and the Tesseract OCR cache are disabled.
+///
+/// # Safety
+///
+/// - `file_path` must be a valid null-terminated C string
+/// - `config_json` must be NULL or a valid null-terminated JSON string
+/// - The returned string must be freed with `kreuzberg_free_string`
+/// - Returns NULL on error (check `kreuzberg_last_error` for details)
+///
+/// # C Signature
+///
+/// ```c
+/// char* kreuzberg_extract_statistics_file(const char* file_path, const char* config_json);
+/// ```
+#[unsafe(no_mangle)]
+pub unsafe extern "C" fn kreuzberg_extract_statistics_file(
+    file_path: *const c_char,
+    config_json: *const c_char,
+) -> *mut c_char {
+    crate::ffi_panic_guard!("kreuzberg_extract_statistics_file", {
+        clear_last_error();
+
+        let Some(path) = (unsafe { str_arg(file_path, "file_path") }) else {
+            return ptr::null_mut();
+        };
+        let Some(config) = (unsafe { statistics_config(config_json) }) else {
+            return ptr::null_mut();
+        };
+        extraction_to_json(kreuzberg::extract_file_sync(Path::new(path), None, &config))
+    })
+}
+
+/// Extracts an in-memory document and returns its statistics instead of its content.
+///
+/// See `kreuzberg_extract_statistics_file` for the result.
+///
+/// # Safety
+///
+/// - `data` must point to `data_len` readable bytes
+/// - `mime_type` must be a valid null-terminated C string
+/// - `config_json` must be NULL or a valid null-terminated JSON string
+/// - The returned string must be freed with `kreuzberg_free_string`
+/// - Returns NULL on error (check `kreuzberg_last_error` for details)
+///
+/// # C Signature
+///
+/// ```c
+/// char* kreuzberg_extract_statistics_bytes(const uint8_t* data, uintptr_t data_len,
+///                                          const char* mime_type, const char* config_json);
+/// ```
+#[unsafe(no_mangle)]
+pub unsafe extern "C" fn kreuzberg_extract_statistics_bytes(
+    data: *const u8,
+    data_len: usize,
+    mime_type: *const c_char,
+    config_json: *const c_char,
+) -> *mut c_char {
+    crate::ffi_panic_guard!("kreuzberg_extract_statistics_bytes", {
+        clear_last_error();
+
+        if data.is_null() {
+            set_last_error("data cannot be NULL".to_string());
+            return ptr::null_mut();
+        }
+        let Some(mime) = (unsafe { str_arg(mime_type, "mime_type") }) else {
+            return ptr::null_mut();
+        };
+        let Some(config) = (unsafe { statistics_config(config_json) }) else {
+            return ptr::null_mut();
+        };
+        // SAFETY: Caller guarantees that data points to data_len readable bytes.
+        let bytes = unsafe { std::slice::from_raw_parts(data, data_len) };
+        extraction_to_json(kreuzberg::extract_bytes_sync(bytes, mime, &config))
+    })
+}
+
+/// Extracts the documents at `file_paths` in parallel and returns their statistics.
+///
+/// The result is a JSON array with one object per path, in order, shaped like the result of
+/// `kreuzberg_extract_statistics_file`. A document that failed to extract has `success` false
+/// and its `error_type`; the error message is dropped since parsers may quote the document in it.
+///
+/// # Safety
+///
+/// - `file_paths` must point to `count` valid null-terminated C strings
+/// - `config_json` must be NULL or a valid null-terminated JSON string
+/// - The returned string must be freed with `kreuzberg_free_string`
+/// - Returns NULL on error (check `kreuzberg_last_error` for details)
+///
+/// # C Signature
+///
+/// ```c
+/// char* kreuzberg_batch_extract_statistics_files(const char* const* file_paths, uintptr_t count,
+///                                                const char* config_json);
+/// ```
+#[unsafe(no_mangle)]
+pub unsafe extern "C" fn kreuzberg_batch_extract_statistics_files(
+    file_paths: *const *const c_char,
+    count: usize,
+    config_json: *const c_char,
+) -> *mut c_char {
+    crate::ffi_panic_guard!("kreuzberg_batch_extract_statistics_files", {
+        clear_last_error();
+
+        if file_paths.is_null() {
+            set_last_error("file_paths cannot be NULL".to_string());
+            return ptr::null_mut();
+        }
+        let Some(config) = (unsafe { statistics_config(config_json) }) else {
+            return ptr::null_mut();
+        };
+        let mut paths = Vec::with_capacity(count);
+        for i in 0..count {
+            // SAFETY: Caller guarantees that file_paths points to count pointers.
+            let Some(path) = (unsafe { str_arg(*file_paths.add(i), &format!("file path at index {}", i)) }) else {
+                return ptr::null_mut();
+            };
+            paths.push(Path::new(path));
+        }
+
+        match kreuzberg::batch_extract_file_sync(paths, &config) {
+            Ok(results) => {
+                let statistics: Vec<StatisticsResult> =
+                    results.into_iter().map(StatisticsResult::from_result).collect();
+                to_json_c_string(&statistics)
+            }
+            Err(e) => {
+                set_last_error(e.to_string());
+                ptr::null_mut()
+            }
+        }
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_split_sentences_and_words() {
+        let sentences = split_sentences("Hello world. It's an e-mail!\n\nHeading\nv1.2 works");
+        assert_eq!(
+            sentences,
+            vec!["Hello world.", " It's an e-mail!", "\n", "\nHeading\nv1.2 works"]
+        );
+        assert_eq!(sentence_words(sentences[1]), vec!["It's", "an", "e-mail"]);
+        assert_eq!(sentence_words("--'quoted'--"), vec!["quoted"]);
+    }
+
+    #[test]
+    fn test_estimate_syllables() {
+        assert_eq!(estimate_syllables("make"), 1);
+        assert_eq!(estimate_syllables("table"), 2);
+        assert_eq!(estimate_syllables("reading"), 2);
+        assert_eq!(estimate_syllables("42"), 1);
+    }
+
+    #[test]
+    fn test_count_entities_does_not_double_count() {
+        let counts =
+            count_entities("Mail ops@example.com or visit https://example.com/1234-5678-90 by 2024-01-31 for 15%.");
+        assert_eq!(counts.get("email"), Some(&1));
+        assert_eq!(counts.get("url"), Some(&1));
+        assert_eq!(counts.get("date"), Some(&1));
+        assert_eq!(counts.get("percent"), Some(&1));
+        assert_eq!(counts.get("phone"), None);
+    }
+
+    #[test]
+    fn test_statistics_config_disables_caches() {
+        let json = c"{\"use_cache\": true, \"ocr\": {\"backend\": \"tesseract\"}}";
+        let config = unsafe { statistics_config(json.as_ptr()) }.expect("config parses");
+        assert!(!config.use_cache);
+        assert!(!config.ocr.unwrap().tesseract_config.unwrap().use_cache);
+    }
+}
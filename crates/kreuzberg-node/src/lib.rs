@@ -1763,6 +1763,7 @@ impl TryFrom<JsExtractionResult> for RustExtractionResult {
                     cells: t.cells,
                     markdown: t.markdown,
                     page_number: t.page_number as usize,
+                    cell_bounds: None,
                 })
                 .collect(),
             detected_languages: val.detected_languages,
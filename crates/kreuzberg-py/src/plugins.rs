@@ -830,6 +830,7 @@ fn extract_tables(obj: &Bound<'_, PyAny>) -> Result<Vec<Table>> {
             cells,
             markdown,
             page_number,
+            cell_bounds: None,
         });
     }
 
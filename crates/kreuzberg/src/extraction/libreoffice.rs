@@ -198,6 +198,18 @@ pub async fn convert_office_doc(
     output_dir: &Path,
     target_format: &str,
     timeout_seconds: u64,
+) -> Result<Vec<u8>> {
+    run_conversion(input_path, output_dir, target_format, target_format, timeout_seconds).await
+}
+
+/// Run `soffice --convert-to <filter>` and read the output file, which LibreOffice names after
+/// the input with `extension`.
+async fn run_conversion(
+    input_path: &Path,
+    output_dir: &Path,
+    filter: &str,
+    extension: &str,
+    timeout_seconds: u64,
 ) -> Result<Vec<u8>> {
     let soffice_path = check_libreoffice_available().await?;
 
@@ -215,7 +227,7 @@ pub async fn convert_office_doc(
         .arg("--nolockcheck")
         .arg(user_install_arg)
         .arg("--convert-to")
-        .arg(target_format)
+        .arg(filter)
         .arg("--outdir")
         .arg(output_dir)
         .arg(input_path);
@@ -290,7 +302,7 @@ pub async fn convert_office_doc(
         .file_stem()
         .ok_or_else(|| KreuzbergError::parsing("Invalid input file name".to_string()))?;
 
-    let expected_output = output_dir.join(format!("{}.{}", input_stem.to_string_lossy(), target_format));
+    let expected_output = output_dir.join(format!("{}.{}", input_stem.to_string_lossy(), extension));
 
     let converted_bytes = fs::read(&expected_output).await.map_err(|e| {
         KreuzbergError::parsing(format!(
@@ -308,6 +320,81 @@ pub async fn convert_office_doc(
     Ok(converted_bytes)
 }
 
+/// MIME types `convert_file` and `convert_bytes` read and write, with the LibreOffice
+/// `--convert-to` filter and the file extension of each.
+const CONVERSION_FORMATS: &[(&str, &str, &str)] = &[
+    ("application/pdf", "pdf", "pdf"),
+    ("application/msword", "doc", "doc"),
+    (
+        "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+        "docx",
+        "docx",
+    ),
+    ("application/vnd.ms-powerpoint", "ppt", "ppt"),
+    (
+        "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+        "pptx",
+        "pptx",
+    ),
+    ("application/vnd.ms-excel", "xls", "xls"),
+    (
+        "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+        "xlsx",
+        "xlsx",
+    ),
+    ("application/vnd.oasis.opendocument.text", "odt", "odt"),
+    ("application/vnd.oasis.opendocument.spreadsheet", "ods", "ods"),
+    ("application/vnd.oasis.opendocument.presentation", "odp", "odp"),
+    ("application/rtf", "rtf", "rtf"),
+    ("text/html", "html", "html"),
+    ("text/plain", "txt:Text", "txt"),
+    ("text/csv", "csv", "csv"),
+];
+
+/// Returns the LibreOffice filter and file extension of a MIME type.
+fn conversion_format(mime_type: &str) -> Result<(&'static str, &'static str)> {
+    let mime_type = mime_type.trim().to_ascii_lowercase();
+    CONVERSION_FORMATS
+        .iter()
+        .find(|(mime, _, _)| *mime == mime_type)
+        .map(|(_, filter, extension)| (*filter, *extension))
+        .ok_or(KreuzbergError::UnsupportedFormat(mime_type))
+}
+
+/// Convert the document at `input_path` to `target_mime` (e.g. `application/pdf`) using
+/// LibreOffice. LibreOffice detects the input format itself.
+pub async fn convert_file(input_path: &Path, target_mime: &str, timeout_seconds: u64) -> Result<Vec<u8>> {
+    let (filter, extension) = conversion_format(target_mime)?;
+    if !fs::try_exists(input_path).await? {
+        return Err(KreuzbergError::Io(std::io::Error::new(
+            std::io::ErrorKind::NotFound,
+            format!("cannot read input file {}", input_path.display()),
+        )));
+    }
+
+    let output_dir_path = std::env::temp_dir().join(format!("kreuzberg_convert_{}_out", uuid::Uuid::new_v4()));
+    let _output_guard = TempDir::new(output_dir_path.clone()).await?;
+    run_conversion(input_path, &output_dir_path, filter, extension, timeout_seconds).await
+}
+
+/// Convert an in-memory document of type `source_mime` to `target_mime` using LibreOffice.
+pub async fn convert_bytes(
+    bytes: &[u8],
+    source_mime: &str,
+    target_mime: &str,
+    timeout_seconds: u64,
+) -> Result<Vec<u8>> {
+    let (_, source_extension) = conversion_format(source_mime)?;
+    conversion_format(target_mime)?;
+
+    let input_dir_path = std::env::temp_dir().join(format!("kreuzberg_convert_{}", uuid::Uuid::new_v4()));
+    let _input_guard = TempDir::new(input_dir_path.clone()).await?;
+    let input_path = input_dir_path.join(format!("input.{}", source_extension));
+    fs::write(&input_path, bytes).await?;
+
+    convert_file(&input_path, target_mime, timeout_seconds).await
+}
+
 /// Convert .doc to .docx using LibreOffice
 pub async fn convert_doc_to_docx(doc_bytes: &[u8]) -> Result<LibreOfficeConversionResult> {
     let temp_dir = std::env::temp_dir();
@@ -501,6 +588,25 @@ mod tests {
         let _ = fs::remove_dir_all(&output_dir).await;
     }
 
+    #[test]
+    fn test_conversion_format() {
+        assert_eq!(conversion_format("application/pdf").unwrap(), ("pdf", "pdf"));
+        assert_eq!(conversion_format(" Text/Plain ").unwrap(), ("txt:Text", "txt"));
+        assert!(matches!(
+            conversion_format("image/png"),
+            Err(KreuzbergError::UnsupportedFormat(_))
+        ));
+    }
+
+    #[tokio::test]
+    #[cfg(not(target_os = "windows"))]
+    async fn test_convert_bytes_rejects_unknown_formats() {
+        let result = convert_bytes(b"x", "text/plain", "image/png", DEFAULT_CONVERSION_TIMEOUT).await;
+        assert!(matches!(result, Err(KreuzbergError::UnsupportedFormat(_))));
+        let result = convert_bytes(b"x", "image/png", "application/pdf", DEFAULT_CONVERSION_TIMEOUT).await;
+        assert!(matches!(result, Err(KreuzbergError::UnsupportedFormat(_))));
+    }
+
     #[tokio::test]
     async fn test_conversion_result_structure() {
         let result = LibreOfficeConversionResult {
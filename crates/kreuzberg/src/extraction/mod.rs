@@ -66,7 +66,9 @@ pub use excel::{excel_to_markdown, read_excel_bytes, read_excel_file};
 pub use html::{convert_html_to_markdown, process_html};
 
 #[cfg(feature = "office")]
-pub use libreoffice::{check_libreoffice_available, convert_doc_to_docx, convert_ppt_to_pptx};
+pub use libreoffice::{
+    check_libreoffice_available, convert_bytes, convert_doc_to_docx, convert_file, convert_ppt_to_pptx,
+};
 
 #[cfg(feature = "office")]
 pub use office_metadata::{
@@ -92,6 +92,7 @@ fn convert_docx_table_to_table(docx_table: &docx_lite::Table, table_index: usize
         cells,
         markdown,
         page_number: table_index + 1,
+        cell_bounds: None,
     }
 }
 
@@ -45,6 +45,7 @@ impl ExcelExtractor {
                     cells: cells.clone(),
                     markdown: sheet.markdown.clone(),
                     page_number: sheet_index + 1,
+                    cell_bounds: None,
                 });
             }
         }
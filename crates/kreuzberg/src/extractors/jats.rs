@@ -376,6 +376,7 @@ fn extract_jats_all_in_one(content: &str) -> Result<(JatsMetadataExtracted, Stri
                                 cells: current_table.clone(),
                                 markdown,
                                 page_number: table_index + 1,
+                                cell_bounds: None,
                             });
                             table_index += 1;
                             current_table.clear();
@@ -441,6 +441,7 @@ impl<'a> LatexParser<'a> {
                 cells: rows,
                 markdown: markdown.clone(),
                 page_number: 1,
+                cell_bounds: None,
             };
             self.tables.push(table);
         }
@@ -227,6 +227,7 @@ impl MarkdownExtractor {
                             cells,
                             markdown,
                             page_number: idx + 1,
+                            cell_bounds: None,
                         });
                         table_index += 1;
                     }
@@ -181,6 +181,7 @@ impl OrgModeExtractor {
                             cells: current_table.clone(),
                             markdown,
                             page_number: 1,
+                            cell_bounds: None,
                         });
                         current_table.clear();
                     }
@@ -194,6 +195,7 @@ impl OrgModeExtractor {
                     cells: current_table,
                     markdown,
                     page_number: 1,
+                    cell_bounds: None,
                 });
             }
         }
@@ -153,7 +153,7 @@ fn extract_tables_from_document(
     document: &PdfDocument,
     _metadata: &crate::pdf::metadata::PdfExtractionMetadata,
 ) -> Result<Vec<Table>> {
-    use crate::ocr::table::{reconstruct_table, table_to_markdown};
+    use crate::ocr::table::{cell_bounds, reconstruct_table, table_to_markdown};
     use crate::pdf::table::extract_words_from_page;
 
     let mut all_tables = Vec::new();
@@ -174,11 +174,13 @@ fn extract_tables_from_document(
 
         if !table_cells.is_empty() {
             let markdown = table_to_markdown(&table_cells);
+            let bounds = cell_bounds(&words, &table_cells);
 
             all_tables.push(Table {
                 cells: table_cells,
                 markdown,
                 page_number: page_index + 1,
+                cell_bounds: Some(bounds),
             });
         }
     }
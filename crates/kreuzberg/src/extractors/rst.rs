@@ -345,6 +345,7 @@ impl RstExtractor {
             cells,
             markdown,
             page_number: 1,
+            cell_bounds: None,
         })
     }
 
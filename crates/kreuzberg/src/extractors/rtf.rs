@@ -172,6 +172,7 @@ fn extract_text_from_rtf(content: &str) -> (String, Vec<Table>) {
                     cells: state.rows,
                     markdown,
                     page_number: 1,
+                    cell_bounds: None,
                 });
             }
         }
@@ -880,6 +880,7 @@ mod tests {
                     ],
                     page_number: 1,
                     markdown: "| Col1 | Col2 |\n|------|------|\n| A    | B    |".to_string(),
+                    cell_bounds: None,
                 },
                 crate::Table {
                     cells: vec![
@@ -888,6 +889,7 @@ mod tests {
                     ],
                     page_number: 2,
                     markdown: "| X | Y |\n|---|---|\n| 1 | 2 |".to_string(),
+                    cell_bounds: None,
                 },
             ],
             detected_languages: None,
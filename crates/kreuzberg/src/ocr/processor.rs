@@ -8,7 +8,7 @@ use kreuzberg_tesseract::{TessPageSegMode, TesseractAPI};
 use super::cache::OcrCache;
 use super::error::OcrError;
 use super::hocr::convert_hocr_to_markdown;
-use super::table::{extract_words_from_tsv, reconstruct_table, table_to_markdown};
+use super::table::{cell_bounds, extract_words_from_tsv, reconstruct_table, table_to_markdown};
 use super::types::{BatchItemResult, TesseractConfig};
 use crate::types::{OcrExtractionResult, OcrTable};
 
@@ -475,10 +475,12 @@ impl OcrProcessor {
                     );
 
                     let markdown_table = table_to_markdown(&table);
+                    let bounds = cell_bounds(&words, &table);
                     tables.push(OcrTable {
                         cells: table,
                         markdown: markdown_table,
                         page_number: 0,
+                        cell_bounds: Some(bounds),
                     });
                 }
             }
@@ -0,0 +1,178 @@
+//! Cell geometry for reconstructed tables.
+//!
+//! `reconstruct_table` only returns the text of each cell, so the bounding boxes are recovered by
+//! matching every cell back to the words it was built from.
+
+use super::HocrWord;
+use crate::types::BoundingBox;
+
+/// Compute the bounding box of each cell of a table reconstructed from `words`.
+///
+/// The result is parallel to `cells` and uses the coordinates of `words`. Rows are matched top to
+/// bottom and cells left to right: each whitespace-separated token of a cell claims an unused word
+/// with the same text that starts right of the previous cell of the row, preferring the word
+/// closest to the row's vertical center. Cells that are empty or whose tokens cannot all be
+/// matched have no box.
+pub fn cell_bounds(words: &[HocrWord], cells: &[Vec<String>]) -> Vec<Vec<Option<BoundingBox>>> {
+    let mut used = vec![false; words.len()];
+    let mut bounds = Vec::with_capacity(cells.len());
+
+    for row in cells {
+        let mut row_center: Option<f64> = None;
+        let mut min_left = 0u32;
+        let mut row_bounds = Vec::with_capacity(row.len());
+
+        for cell in row {
+            let Some(matched) = match_cell(words, &used, cell, row_center, min_left) else {
+                row_bounds.push(None);
+                continue;
+            };
+            for &index in &matched {
+                used[index] = true;
+            }
+            let cell_box = union_bounds(words, &matched);
+            row_center.get_or_insert(center_y(&words[matched[0]]));
+            min_left = cell_box.left as u32 + 1;
+            row_bounds.push(Some(cell_box));
+        }
+
+        bounds.push(row_bounds);
+    }
+
+    bounds
+}
+
+/// Find the words a cell was built from, or `None` if any of its tokens has no match.
+fn match_cell(
+    words: &[HocrWord],
+    used: &[bool],
+    cell: &str,
+    row_center: Option<f64>,
+    min_left: u32,
+) -> Option<Vec<usize>> {
+    let mut matched: Vec<usize> = Vec::new();
+
+    for token in cell.split_whitespace() {
+        let distance = |word: &HocrWord| match row_center {
+            Some(center) => (center_y(word) - center).abs(),
+            None => f64::from(word.top),
+        };
+        let index = words
+            .iter()
+            .enumerate()
+            .filter(|&(index, word)| {
+                !used[index] && !matched.contains(&index) && word.text == token && word.left >= min_left
+            })
+            .min_by(|&(_, a), &(_, b)| distance(a).total_cmp(&distance(b)).then(a.left.cmp(&b.left)))
+            .map(|(index, _)| index)?;
+        matched.push(index);
+    }
+
+    if matched.is_empty() { None } else { Some(matched) }
+}
+
+fn union_bounds(words: &[HocrWord], indices: &[usize]) -> BoundingBox {
+    indices
+        .iter()
+        .map(|&index| word_bounds(&words[index]))
+        .reduce(|a, b| BoundingBox {
+            left: a.left.min(b.left),
+            top: a.top.min(b.top),
+            right: a.right.max(b.right),
+            bottom: a.bottom.max(b.bottom),
+        })
+        .expect("a matched cell has at least one word")
+}
+
+fn word_bounds(word: &HocrWord) -> BoundingBox {
+    BoundingBox {
+        left: f64::from(word.left),
+        top: f64::from(word.top),
+        right: f64::from(word.left) + f64::from(word.width),
+        bottom: f64::from(word.top) + f64::from(word.height),
+    }
+}
+
+fn center_y(word: &HocrWord) -> f64 {
+    f64::from(word.top) + f64::from(word.height) / 2.0
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn word(text: &str, left: u32, top: u32) -> HocrWord {
+        HocrWord {
+            text: text.to_string(),
+            left,
+            top,
+            width: 10 * text.len() as u32,
+            height: 12,
+            confidence: 95.0,
+        }
+    }
+
+    fn cells(rows: &[&[&str]]) -> Vec<Vec<String>> {
+        rows.iter()
+            .map(|row| row.iter().map(|cell| cell.to_string()).collect())
+            .collect()
+    }
+
+    #[test]
+    fn test_cell_bounds_matches_words_to_cells() {
+        let words = vec![
+            word("Name", 10, 100),
+            word("Total", 200, 100),
+            word("Red", 10, 130),
+            word("apple", 50, 131),
+            word("0", 200, 130),
+            word("Green", 10, 160),
+            word("0", 200, 161),
+        ];
+        let table = cells(&[&["Name", "Total"], &["Red apple", "0"], &["Green", "0"]]);
+
+        let bounds = cell_bounds(&words, &table);
+
+        assert_eq!(
+            bounds[1][0],
+            Some(BoundingBox {
+                left: 10.0,
+                top: 130.0,
+                right: 100.0,
+                bottom: 143.0,
+            })
+        );
+        assert_eq!(bounds[1][1].map(|b| b.top), Some(130.0));
+        assert_eq!(bounds[2][1].map(|b| b.top), Some(161.0));
+        assert_eq!(bounds[0][1].map(|b| b.left), Some(200.0));
+    }
+
+    #[test]
+    fn test_cell_bounds_prefers_the_row_of_the_cell() {
+        // Both "0" cells are misaligned with their rows, and the second row's comes first.
+        let words = vec![
+            word("0", 200, 124),
+            word("a", 10, 100),
+            word("b", 10, 130),
+            word("0", 200, 106),
+        ];
+        let table = cells(&[&["a", "0"], &["b", "0"]]);
+
+        let bounds = cell_bounds(&words, &table);
+
+        assert_eq!(bounds[0][1].map(|b| b.top), Some(106.0));
+        assert_eq!(bounds[1][1].map(|b| b.top), Some(124.0));
+    }
+
+    #[test]
+    fn test_cell_bounds_leaves_unmatched_and_empty_cells_without_box() {
+        let words = vec![word("x", 10, 10)];
+        let table = cells(&[&["x", "", "missing"]]);
+
+        let bounds = cell_bounds(&words, &table);
+
+        assert!(bounds[0][0].is_some());
+        assert_eq!(bounds[0][1], None);
+        assert_eq!(bounds[0][2], None);
+    }
+}
@@ -169,6 +169,7 @@ impl OcrBackend for TesseractBackend {
                     cells: t.cells,
                     markdown: t.markdown,
                     page_number: t.page_number,
+                    cell_bounds: t.cell_bounds,
                 })
                 .collect(),
             detected_languages: None,
@@ -223,6 +224,7 @@ impl OcrBackend for TesseractBackend {
                     cells: t.cells,
                     markdown: t.markdown,
                     page_number: t.page_number,
+                    cell_bounds: t.cell_bounds,
                 })
                 .collect(),
             detected_languages: None,
@@ -10,6 +10,7 @@
 //! - **Metadata extraction**: Parse PDF metadata (title, author, creation date, etc.)
 //! - **Image extraction**: Extract embedded images from PDF pages
 //! - **Page rendering**: Render PDF pages to images for OCR processing
+//! - **Page ranges**: Count pages and copy a range of pages into a new PDF
 //! - **Error handling**: Comprehensive PDF-specific error types
 //!
 //! # Example
@@ -46,6 +47,8 @@ pub mod images;
 #[cfg(feature = "pdf")]
 pub mod metadata;
 #[cfg(feature = "pdf")]
+pub mod pages;
+#[cfg(feature = "pdf")]
 pub mod rendering;
 #[cfg(feature = "pdf")]
 pub mod table;
@@ -61,6 +64,8 @@ pub use images::{PdfImage, PdfImageExtractor, extract_images_from_pdf};
 #[cfg(feature = "pdf")]
 pub use metadata::extract_metadata;
 #[cfg(feature = "pdf")]
+pub use pages::{extract_page_range, page_count};
+#[cfg(feature = "pdf")]
 pub use rendering::{PageRenderOptions, render_page_to_image};
 #[cfg(feature = "pdf")]
 pub use table::extract_words_from_page;
@@ -0,0 +1,137 @@
+//! Page ranges of PDF documents.
+//!
+//! Splitting a PDF lets callers extract a long document a range of pages at a time, e.g. to
+//! checkpoint the pages already extracted, with the same pipeline as a whole document.
+
+use super::error::{PdfError, Result};
+use lopdf::Document;
+
+/// Count the pages of a PDF.
+pub fn page_count(pdf_bytes: &[u8]) -> Result<usize> {
+    Ok(load(pdf_bytes)?.get_pages().len())
+}
+
+/// Copy `count` pages of a PDF, starting at the 0-based page `first`, into a new PDF.
+///
+/// The copy keeps the document catalog, so metadata and shared resources are preserved; objects
+/// only the other pages refer to are dropped.
+pub fn extract_page_range(pdf_bytes: &[u8], first: usize, count: usize) -> Result<Vec<u8>> {
+    if count == 0 {
+        return Err(PdfError::ExtractionFailed("page range is empty".to_string()));
+    }
+    let mut document = load(pdf_bytes)?;
+    let total = document.get_pages().len();
+    let end = first.saturating_add(count);
+    if end > total {
+        return Err(PdfError::PageNotFound(end));
+    }
+
+    let dropped: Vec<u32> = (1..=total)
+        .filter(|&number| number <= first || number > end)
+        .map(|number| number as u32)
+        .collect();
+    document.delete_pages(&dropped);
+    document.prune_objects();
+
+    let mut bytes = Vec::new();
+    document
+        .save_to(&mut bytes)
+        .map_err(|e| PdfError::IOError(e.to_string()))?;
+    Ok(bytes)
+}
+
+fn load(pdf_bytes: &[u8]) -> Result<Document> {
+    let document =
+        Document::load_mem(pdf_bytes).map_err(|e| PdfError::InvalidPdf(format!("Failed to load PDF: {}", e)))?;
+    if document.is_encrypted() {
+        return Err(PdfError::PasswordRequired);
+    }
+    Ok(document)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use lopdf::{Object, Stream, dictionary};
+
+    /// A PDF whose page `i` is `100 * (i + 1)` points wide.
+    fn pdf_with_pages(count: usize) -> Vec<u8> {
+        let mut document = Document::with_version("1.5");
+        let pages_id = document.new_object_id();
+        let kids: Vec<Object> = (0..count)
+            .map(|i| {
+                let content_id = document.add_object(Stream::new(dictionary! {}, Vec::new()));
+                let width = 100 * (i as i64 + 1);
+                let page_id = document.add_object(dictionary! {
+                    "Type" => "Page",
+                    "Parent" => pages_id,
+                    "MediaBox" => vec![0.into(), 0.into(), width.into(), 792.into()],
+                    "Contents" => content_id,
+                });
+                Object::Reference(page_id)
+            })
+            .collect();
+        document.objects.insert(
+            pages_id,
+            Object::Dictionary(dictionary! {
+                "Type" => "Pages",
+                "Kids" => kids,
+                "Count" => count as i64,
+            }),
+        );
+        let catalog_id = document.add_object(dictionary! {
+            "Type" => "Catalog",
+            "Pages" => pages_id,
+        });
+        document.trailer.set("Root", catalog_id);
+
+        let mut bytes = Vec::new();
+        document.save_to(&mut bytes).unwrap();
+        bytes
+    }
+
+    fn page_widths(pdf_bytes: &[u8]) -> Vec<i64> {
+        let document = Document::load_mem(pdf_bytes).unwrap();
+        document
+            .get_pages()
+            .values()
+            .map(|&id| {
+                let page = document.get_dictionary(id).unwrap();
+                page.get(b"MediaBox").unwrap().as_array().unwrap()[2].as_i64().unwrap()
+            })
+            .collect()
+    }
+
+    #[test]
+    fn test_page_count() {
+        assert_eq!(page_count(&pdf_with_pages(3)).unwrap(), 3);
+        assert!(page_count(b"not a pdf").is_err());
+    }
+
+    #[test]
+    fn test_extract_page_range_keeps_the_requested_pages() {
+        let pdf = pdf_with_pages(4);
+
+        assert_eq!(page_widths(&extract_page_range(&pdf, 1, 2).unwrap()), vec![200, 300]);
+        assert_eq!(page_widths(&extract_page_range(&pdf, 3, 1).unwrap()), vec![400]);
+        assert_eq!(
+            page_widths(&extract_page_range(&pdf, 0, 4).unwrap()),
+            vec![100, 200, 300, 400]
+        );
+    }
+
+    #[test]
+    fn test_extract_page_range_rejects_invalid_ranges() {
+        let pdf = pdf_with_pages(2);
+
+        assert!(matches!(extract_page_range(&pdf, 1, 2), Err(PdfError::PageNotFound(3))));
+        assert!(matches!(
+            extract_page_range(&pdf, 0, 0),
+            Err(PdfError::ExtractionFailed(_))
+        ));
+        assert!(matches!(
+            extract_page_range(&pdf, usize::MAX, 1),
+            Err(PdfError::PageNotFound(_))
+        ));
+    }
+}
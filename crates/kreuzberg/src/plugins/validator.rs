@@ -757,6 +757,7 @@ mod tests {
             cells: vec![vec!["A".to_string(), "B".to_string()]],
             markdown: "| A | B |".to_string(),
             page_number: 0,
+            cell_bounds: None,
         };
 
         let result = ExtractionResult {
@@ -613,6 +613,22 @@ pub struct Table {
     pub markdown: String,
     /// Page number where the table was found (1-indexed)
     pub page_number: usize,
+    /// Bounding box of each cell, parallel to `cells`.
+    ///
+    /// Only reported by the PDF and OCR table extractors, in PDF points for digital PDFs and in
+    /// pixels of the OCR input image for OCR. Cells that are empty or could not be located have
+    /// no box.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub cell_bounds: Option<Vec<Vec<Option<BoundingBox>>>>,
+}
+
+/// Axis-aligned rectangle on a page, with the origin at the top-left corner.
+#[derive(Debug, Clone, Copy, PartialEq, Serialize, Deserialize)]
+pub struct BoundingBox {
+    pub left: f64,
+    pub top: f64,
+    pub right: f64,
+    pub bottom: f64,
 }
 
 /// A text chunk with optional embedding and metadata.
@@ -910,6 +926,9 @@ pub struct OcrTable {
     pub markdown: String,
     /// Page number where the table was found (1-indexed)
     pub page_number: usize,
+    /// Bounding box of each cell, parallel to `cells`, in pixels of the OCR input image.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub cell_bounds: Option<Vec<Vec<Option<BoundingBox>>>>,
 }
 
 /// Image preprocessing configuration for OCR.
@@ -1216,6 +1235,7 @@ mod tests {
             cells: vec![vec!["A".to_string(), "B".to_string()]],
             markdown: "| A | B |\n|---|---|\n".to_string(),
             page_number: 1,
+            cell_bounds: None,
         };
 
         let json = serde_json::to_value(&table).unwrap();
@@ -1234,6 +1254,7 @@ mod tests {
             ],
             markdown: "| X | Y |\n|---|---|\n| 1 | 2 |\n".to_string(),
             page_number: 5,
+            cell_bounds: None,
         };
 
         let json = serde_json::to_string(&original).unwrap();
@@ -1250,6 +1271,7 @@ mod tests {
             cells: vec![vec!["shared".to_string()]],
             markdown: "| shared |".to_string(),
             page_number: 1,
+            cell_bounds: None,
         });
 
         let tables_before = vec![Arc::clone(&shared_table), Arc::clone(&shared_table)];
@@ -1265,11 +1287,13 @@ mod tests {
                 cells: vec![vec!["A".to_string()]],
                 markdown: "| A |".to_string(),
                 page_number: 1,
+                cell_bounds: None,
             },
             Table {
                 cells: vec![vec!["B".to_string()]],
                 markdown: "| B |".to_string(),
                 page_number: 2,
+                cell_bounds: None,
             },
         ];
 
@@ -1292,11 +1316,13 @@ mod tests {
                     cells: vec![vec!["Table1".to_string()]],
                     markdown: "| Table1 |".to_string(),
                     page_number: 3,
+                    cell_bounds: None,
                 }),
                 Arc::new(Table {
                     cells: vec![vec!["Table2".to_string()]],
                     markdown: "| Table2 |".to_string(),
                     page_number: 3,
+                    cell_bounds: None,
                 }),
             ],
             images: Vec::new(),
@@ -1365,6 +1391,7 @@ mod tests {
             cells: vec![vec!["shared across pages".to_string()]],
             markdown: "| shared across pages |".to_string(),
             page_number: 0,
+            cell_bounds: None,
         });
 
         let page1 = PageContent {
@@ -1415,12 +1442,14 @@ mod tests {
             cells: vec![vec!["A".to_string()]],
             markdown: "| A |".to_string(),
             page_number: 1,
+            cell_bounds: None,
         };
 
         let table2 = Table {
             cells: vec![vec!["B".to_string()]],
             markdown: "| B |".to_string(),
             page_number: 2,
+            cell_bounds: None,
         };
 
         let json = serde_json::to_string(&vec![table1, table2]).unwrap();
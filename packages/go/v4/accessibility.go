@@ -0,0 +1,543 @@
+package kreuzberg
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// AccessibilityIssue codes name the problems an AccessibilityReport can record.
+const (
+	AccessibilityUntagged            = "untagged"
+	AccessibilityMissingTitle        = "missing_title"
+	AccessibilityMissingLanguage     = "missing_language"
+	AccessibilityMissingAltText      = "missing_alt_text"
+	AccessibilityNoHeadings          = "no_headings"
+	AccessibilitySkippedHeadingLevel = "skipped_heading_level"
+	AccessibilityTableWithoutHeaders = "table_without_headers"
+)
+
+// AccessibilityIssue is one accessibility problem found in a document.
+type AccessibilityIssue struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// StructureElement is an element of a document's logical structure.
+type StructureElement struct {
+	// Role is the standard structure type, such as "H1", "P", "Figure" or "Table"; custom PDF
+	// roles are mapped through the document's role map.
+	Role string `json:"role"`
+	// CustomRole is the role as tagged when the role map translated it.
+	CustomRole string `json:"custom_role,omitempty"`
+	// Depth is the nesting depth below the structure root, starting at 0.
+	Depth int `json:"depth"`
+	// Page is the 1-based page the element is on, when the PDF records it.
+	Page       int    `json:"page,omitempty"`
+	Alt        string `json:"alt,omitempty"`
+	ActualText string `json:"actual_text,omitempty"`
+	Lang       string `json:"lang,omitempty"`
+	// Decorative marks DOCX images flagged as decorative, which need no alternative text.
+	Decorative bool `json:"decorative,omitempty"`
+}
+
+// AccessibilityReport describes the accessibility structure of a PDF or DOCX document and
+// scores it. PDFs are read through their structure tree (tags), in reading order; DOCX files
+// through their headings, tables and images in document order.
+type AccessibilityReport struct {
+	// Format is "pdf" or "docx".
+	Format string `json:"format"`
+	// Tagged reports whether a PDF is tagged with a logical structure. DOCX files always are.
+	Tagged bool `json:"tagged"`
+	// PDFUA is the PDF/UA part the document claims conformance to ("1", "2"), if any.
+	PDFUA    string `json:"pdfua,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Language string `json:"language,omitempty"`
+	// Structure lists the structure elements in reading order.
+	Structure         []StructureElement   `json:"structure,omitempty"`
+	Figures           int                  `json:"figures"`
+	FiguresWithAlt    int                  `json:"figures_with_alt"`
+	Headings          int                  `json:"headings"`
+	Tables            int                  `json:"tables"`
+	TablesWithHeaders int                  `json:"tables_with_headers"`
+	Issues            []AccessibilityIssue `json:"issues,omitempty"`
+	// Score rates the document from 0 to 100: tagging counts 30 points, alternative text for
+	// figures 25, the language 15, and the title, headings and table headers 10 each. Figures and
+	// tables score in proportion to those passing; skipped heading levels halve the heading
+	// points. An untagged PDF scores only its title and language.
+	Score int `json:"score"`
+}
+
+// Accessibility returns the report recorded when ExtractionConfig.Accessibility was set.
+func (m Metadata) Accessibility() (*AccessibilityReport, bool) {
+	var report AccessibilityReport
+	if found, err := m.Decode("accessibility", &report); !found || err != nil {
+		return nil, false
+	}
+	return &report, true
+}
+
+// maxStructureElements bounds the structure elements a report lists.
+const maxStructureElements = 100_000
+
+// AnalyzeAccessibility reads the accessibility structure of a PDF or DOCX document. It returns
+// nil without error for other formats.
+func AnalyzeAccessibility(data []byte) (*AccessibilityReport, error) {
+	var report *AccessibilityReport
+	switch {
+	case bytes.HasPrefix(bytes.TrimLeft(data[:min(len(data), 1024)], "\x00\r\n\t "), []byte("%PDF-")):
+		report = pdfAccessibility(data)
+	case isOOXMLPackage(data):
+		var err error
+		if report, err = docxAccessibility(data); err != nil || report == nil {
+			return nil, err
+		}
+	default:
+		return nil, nil
+	}
+	report.score()
+	return report, nil
+}
+
+// pdfAccessibility walks the structure tree of a PDF.
+func pdfAccessibility(data []byte) *AccessibilityReport {
+	report := &AccessibilityReport{Format: "pdf"}
+	doc := loadPDFObjects(data)
+	catalog := doc.catalog(data)
+
+	info, xmp := pdfInfoValues(data), xmpValues(data)
+	for _, values := range []map[string]any{xmp, info} {
+		if title, ok := values["title"].(string); ok && report.Title == "" {
+			report.Title = strings.TrimSpace(title)
+		}
+	}
+	if lang, ok := catalog["Lang"].([]byte); ok {
+		report.Language = strings.TrimSpace(decodePDFTextString(lang))
+	}
+	if language, ok := xmp["language"].(string); ok && report.Language == "" {
+		report.Language = language
+	}
+	if packets := ExtractXMP(data); len(packets) > 0 {
+		if part, ok := packets[len(packets)-1].Get(xmpNamespacePDFUAID, "part"); ok {
+			report.PDFUA = part.Text()
+		}
+	}
+
+	root := doc.dict(catalog["StructTreeRoot"])
+	markInfo := doc.dict(catalog["MarkInfo"])
+	report.Tagged = root != nil && doc.resolve(markInfo["Marked"]) == pdfKeyword("true")
+	if root == nil {
+		return report
+	}
+	w := &pdfStructureWalker{doc: doc, report: report, roleMap: doc.dict(root["RoleMap"]), pages: map[pdfRef]int{}, visited: map[pdfRef]bool{}}
+	w.numberPages(catalog["Pages"], 0)
+	w.walk(root["K"], -1, 0)
+	return report
+}
+
+const xmpNamespacePDFUAID = "http://www.aiim.org/pdfua/ns/id/"
+
+// pdfStructureWalker collects the elements of a PDF structure tree in reading order.
+type pdfStructureWalker struct {
+	doc     *pdfDocument
+	report  *AccessibilityReport
+	roleMap map[string]any
+	pages   map[pdfRef]int
+	visited map[pdfRef]bool
+	// tables holds the indices in Structure of the tables enclosing the current element.
+	tables    []int
+	hasHeader map[int]bool
+	pageCount int
+}
+
+// numberPages numbers the page objects below node in page tree order.
+func (w *pdfStructureWalker) numberPages(node any, depth int) {
+	ref, isRef := node.(pdfRef)
+	if isRef {
+		if _, seen := w.pages[ref]; seen {
+			return
+		}
+		w.pages[ref] = 0
+	}
+	dict := w.doc.dict(node)
+	if dict == nil || depth > 32 {
+		return
+	}
+	if kids, ok := w.doc.resolve(dict["Kids"]).([]any); ok {
+		for _, kid := range kids {
+			w.numberPages(kid, depth+1)
+		}
+		return
+	}
+	w.pageCount++
+	if isRef {
+		w.pages[ref] = w.pageCount
+	}
+}
+
+// walk visits the structure node k, which may be an element, an array of kids or marked
+// content, at the given depth.
+func (w *pdfStructureWalker) walk(k any, depth, page int) {
+	if len(w.report.Structure) >= maxStructureElements || depth > 256 {
+		return
+	}
+	if ref, ok := k.(pdfRef); ok {
+		if w.visited[ref] {
+			return
+		}
+		w.visited[ref] = true
+	}
+	switch node := w.doc.resolve(k).(type) {
+	case []any:
+		for _, kid := range node {
+			w.walk(kid, depth, page)
+		}
+	case map[string]any:
+		tag, ok := w.doc.resolve(node["S"]).(pdfName)
+		if !ok {
+			// Marked-content and object references carry no structure of their own.
+			return
+		}
+		if p, ok := w.pages[pdfRefOf(node["Pg"])]; ok && p > 0 {
+			page = p
+		}
+		element := StructureElement{Role: w.standardRole(string(tag)), Depth: depth + 1, Page: page}
+		if element.Role != string(tag) {
+			element.CustomRole = string(tag)
+		}
+		element.Alt = w.text(node["Alt"])
+		element.ActualText = w.text(node["ActualText"])
+		element.Lang = w.text(node["Lang"])
+		index := len(w.report.Structure)
+		w.report.Structure = append(w.report.Structure, element)
+		w.report.count(element)
+		switch element.Role {
+		case "Table":
+			w.tables = append(w.tables, index)
+			defer func() { w.tables = w.tables[:len(w.tables)-1] }()
+		case "TH":
+			if len(w.tables) > 0 {
+				if w.hasHeader == nil {
+					w.hasHeader = map[int]bool{}
+				}
+				table := w.tables[len(w.tables)-1]
+				if !w.hasHeader[table] {
+					w.hasHeader[table] = true
+					w.report.TablesWithHeaders++
+				}
+			}
+		}
+		w.walk(node["K"], depth+1, page)
+	}
+}
+
+func pdfRefOf(v any) pdfRef {
+	ref, _ := v.(pdfRef)
+	return ref
+}
+
+// standardRole maps a role through the role map, which may chain, to a standard one.
+func (w *pdfStructureWalker) standardRole(role string) string {
+	for range 8 {
+		mapped, ok := w.doc.resolve(w.roleMap[role]).(pdfName)
+		if !ok || string(mapped) == role {
+			break
+		}
+		role = string(mapped)
+	}
+	return role
+}
+
+func (w *pdfStructureWalker) text(v any) string {
+	s, _ := w.doc.resolve(v).([]byte)
+	return strings.TrimSpace(decodePDFTextString(s))
+}
+
+// headingLevelPattern matches the numbered heading roles.
+var headingLevelPattern = regexp.MustCompile(`^H([1-9])$`)
+
+// headingLevel returns the level of a heading role, 0 for the unnumbered "H", or -1 for other
+// roles.
+func headingLevel(role string) int {
+	if role == "H" {
+		return 0
+	}
+	if m := headingLevelPattern.FindStringSubmatch(role); m != nil {
+		return int(m[1][0] - '0')
+	}
+	return -1
+}
+
+// count updates the figure, heading and table counts with element.
+func (r *AccessibilityReport) count(element StructureElement) {
+	switch {
+	case element.Role == "Figure":
+		r.Figures++
+		if element.Alt != "" || element.ActualText != "" || element.Decorative {
+			r.FiguresWithAlt++
+		}
+	case element.Role == "Table":
+		r.Tables++
+	case headingLevel(element.Role) >= 0:
+		r.Headings++
+	}
+}
+
+// score records the issues of the report and rates it.
+func (r *AccessibilityReport) score() {
+	issue := func(code, format string, args ...any) {
+		r.Issues = append(r.Issues, AccessibilityIssue{Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+	points := 0.0
+	if r.Title != "" {
+		points += 10
+	} else {
+		issue(AccessibilityMissingTitle, "the document has no title")
+	}
+	if r.Language != "" {
+		points += 15
+	} else {
+		issue(AccessibilityMissingLanguage, "the document declares no language")
+	}
+	if !r.Tagged {
+		issue(AccessibilityUntagged, "the PDF is not tagged, so assistive technology cannot follow its structure")
+		r.Score = int(points)
+		return
+	}
+	points += 30
+
+	points += 25 * passRate(r.FiguresWithAlt, r.Figures)
+	if missing := r.Figures - r.FiguresWithAlt; missing > 0 {
+		issue(AccessibilityMissingAltText, "%d of %d figures have no alternative text", missing, r.Figures)
+	}
+
+	headingPoints := 10.0
+	if r.Headings == 0 {
+		headingPoints = 0
+		issue(AccessibilityNoHeadings, "the document has no headings to navigate by")
+	}
+	previous := 0
+	for _, e := range r.Structure {
+		level := headingLevel(e.Role)
+		if level <= 0 {
+			continue
+		}
+		if previous > 0 && level > previous+1 {
+			issue(AccessibilitySkippedHeadingLevel, "heading level jumps from H%d to H%d", previous, level)
+			headingPoints = 5
+		}
+		previous = level
+	}
+	points += headingPoints
+
+	points += 10 * passRate(r.TablesWithHeaders, r.Tables)
+	if missing := r.Tables - r.TablesWithHeaders; missing > 0 {
+		issue(AccessibilityTableWithoutHeaders, "%d of %d tables have no header cells", missing, r.Tables)
+	}
+	r.Score = int(math.Round(points))
+}
+
+// passRate returns the share of passing items, 1 when there are none.
+func passRate(passing, total int) float64 {
+	if total == 0 {
+		return 1
+	}
+	return float64(passing) / float64(total)
+}
+
+// docxCoreProperties holds the descriptive fields of docProps/core.xml.
+type docxCoreProperties struct {
+	Title    string `xml:"title"`
+	Language string `xml:"language"`
+}
+
+// docxAccessibility reads the headings, tables and images of a DOCX file. It returns nil for
+// other Office Open XML packages.
+func docxAccessibility(data []byte) (*AccessibilityReport, error) {
+	reader, err := openOOXML(data)
+	if err != nil {
+		return nil, err
+	}
+	document, err := reader.Open("word/document.xml")
+	if err != nil {
+		return nil, nil
+	}
+	defer document.Close()
+
+	report := &AccessibilityReport{Format: "docx", Tagged: true}
+	var core docxCoreProperties
+	if _, err := decodeOOXMLPart(reader, "docProps/core.xml", &core); err != nil {
+		return nil, err
+	}
+	report.Title, report.Language = strings.TrimSpace(core.Title), strings.TrimSpace(core.Language)
+
+	headings := map[string]string{}
+	if styles, err := reader.Open("word/styles.xml"); err == nil {
+		defaultLanguage, err := parseDocxStyles(styles, headings)
+		styles.Close()
+		if err != nil {
+			return nil, err
+		}
+		if report.Language == "" {
+			report.Language = defaultLanguage
+		}
+	}
+	if err := report.parseDocxDocument(document, headings); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// parseDocxStyles maps the paragraph styles that are headings to their roles and returns the
+// default language.
+func parseDocxStyles(r io.Reader, headings map[string]string) (string, error) {
+	var language, styleID string
+	inDefaults := false
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return language, nil
+		}
+		if err != nil {
+			return "", newParsingErrorWithContext("failed to parse word/styles.xml", err, ErrorCodeParsing, nil)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "docDefaults":
+				inDefaults = true
+			case "lang":
+				if inDefaults && language == "" {
+					language = xmlAttr(t, "val")
+				}
+			case "style":
+				styleID = xmlAttr(t, "styleId")
+			case "name":
+				name := strings.ToLower(xmlAttr(t, "val"))
+				if name == "title" {
+					headings[styleID] = "Title"
+				} else if level, ok := strings.CutPrefix(name, "heading "); ok {
+					if n, err := strconv.Atoi(level); err == nil && n >= 1 && n <= 9 {
+						headings[styleID] = "H" + level
+					}
+				}
+			case "outlineLvl":
+				if _, named := headings[styleID]; !named && styleID != "" {
+					if n, err := strconv.Atoi(xmlAttr(t, "val")); err == nil && n >= 0 && n < 9 {
+						headings[styleID] = "H" + strconv.Itoa(n+1)
+					}
+				}
+			}
+		case xml.EndElement:
+			if t.Name.Local == "docDefaults" {
+				inDefaults = false
+			}
+		}
+	}
+}
+
+// parseDocxDocument lists the headings, tables and images of word/document.xml in document
+// order.
+func (r *AccessibilityReport) parseDocxDocument(document io.Reader, headings map[string]string) error {
+	type table struct {
+		rows   int
+		header bool
+	}
+	var tables []table
+	figure := -1
+	decoder := xml.NewDecoder(document)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return newParsingErrorWithContext("failed to parse word/document.xml", err, ErrorCodeParsing, nil)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			if end, ok := token.(xml.EndElement); ok && end.Name.Local == "tbl" && len(tables) > 0 {
+				tables = tables[:len(tables)-1]
+			}
+			continue
+		}
+		if len(r.Structure) >= maxStructureElements {
+			break
+		}
+		switch start.Name.Local {
+		case "pStyle":
+			if role, ok := headings[xmlAttr(start, "val")]; ok {
+				r.Structure = append(r.Structure, StructureElement{Role: role, Depth: len(tables)})
+			}
+		case "tbl":
+			tables = append(tables, table{})
+			r.Structure = append(r.Structure, StructureElement{Role: "Table", Depth: len(tables) - 1})
+		case "tr":
+			if len(tables) > 0 {
+				tables[len(tables)-1].rows++
+			}
+		case "tblHeader":
+			// Only a repeated first row makes a header row.
+			if len(tables) > 0 {
+				if t := &tables[len(tables)-1]; t.rows == 1 && !t.header && xmlOnOff(start) {
+					t.header = true
+					r.TablesWithHeaders++
+				}
+			}
+		case "docPr":
+			alt := strings.TrimSpace(xmlAttr(start, "descr"))
+			if alt == "" {
+				alt = strings.TrimSpace(xmlAttr(start, "title"))
+			}
+			figure = len(r.Structure)
+			r.Structure = append(r.Structure, StructureElement{Role: "Figure", Depth: len(tables), Alt: alt})
+		case "decorative":
+			if figure >= 0 && xmlOnOff(start) {
+				r.Structure[figure].Decorative = true
+			}
+		}
+	}
+	for _, e := range r.Structure {
+		r.count(e)
+	}
+	return nil
+}
+
+// xmlOnOff reads an OOXML on/off property, which is on unless its val says otherwise.
+func xmlOnOff(e xml.StartElement) bool {
+	switch xmlAttr(e, "val") {
+	case "0", "false", "off":
+		return false
+	}
+	return true
+}
+
+// annotateAccessibility stores the accessibility report of data in
+// result.Metadata.Additional["accessibility"]. Formats other than PDF and DOCX are skipped, and
+// malformed DOCX parts are reported as a warning diagnostic.
+func annotateAccessibility(result *ExtractionResult, data []byte) error {
+	report, err := AnalyzeAccessibility(data)
+	if err != nil {
+		result.addDiagnostic("accessibility", DiagnosticSeverityWarning, fmt.Sprintf("accessibility report skipped: %v", err))
+		return nil
+	}
+	if report == nil {
+		return nil
+	}
+	raw, err := json.Marshal(report)
+	if err != nil {
+		return newSerializationErrorWithContext("failed to encode accessibility report", err, ErrorCodeValidation, nil)
+	}
+	if result.Metadata.Additional == nil {
+		result.Metadata.Additional = map[string]json.RawMessage{}
+	}
+	result.Metadata.Additional["accessibility"] = raw
+	return nil
+}
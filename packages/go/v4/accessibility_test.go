@@ -0,0 +1,151 @@
+package kreuzberg
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testTaggedPDF builds a tagged one-page PDF claiming PDF/UA-1. Its structure tree has a custom
+// heading role, a skipped heading level, a figure with and one without alternative text, and a
+// table with header cells.
+func testTaggedPDF() []byte {
+	xmp := `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` +
+		`<rdf:Description rdf:about="" xmlns:pdfuaid="http://www.aiim.org/pdfua/ns/id/" pdfuaid:part="1"/></rdf:RDF></x:xmpmeta>`
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R /StructTreeRoot 4 0 R /MarkInfo << /Marked true >> /Lang (en-GB) /Metadata 13 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] >>",
+		"<< /Type /StructTreeRoot /K 5 0 R /RoleMap << /Heading1 /H1 /Picture /Figure >> >>",
+		"<< /Type /StructElem /S /Document /K [6 0 R 7 0 R 8 0 R 9 0 R 10 0 R] >>",
+		"<< /Type /StructElem /S /Heading1 /Pg 3 0 R /K 0 >>",
+		"<< /Type /StructElem /S /Picture /Pg 3 0 R /Alt (A red bicycle) /K 1 >>",
+		"<< /Type /StructElem /S /H3 /Pg 3 0 R /K 2 >>",
+		"<< /Type /StructElem /S /Figure /Pg 3 0 R /K << /Type /MCR /MCID 3 >> >>",
+		"<< /Type /StructElem /S /Table /Pg 3 0 R /K [11 0 R] >>",
+		"<< /Type /StructElem /S /TR /K [12 0 R << /S /TD /K 5 >>] >>",
+		"<< /Type /StructElem /S /TH /K 4 >>",
+		fmt.Sprintf("<< /Type /Metadata /Subtype /XML /Length %d >>\nstream\n%s\nendstream", len(xmp), xmp),
+		"<< /Title (Cycling guide) >>",
+	}
+	var pdf strings.Builder
+	pdf.WriteString("%PDF-1.7\n")
+	for i, obj := range objects {
+		fmt.Fprintf(&pdf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	pdf.WriteString("trailer\n<< /Root 1 0 R /Info 14 0 R /Size 15 >>\n%%EOF\n")
+	return []byte(pdf.String())
+}
+
+func TestAnalyzeAccessibilityPDF(t *testing.T) {
+	report, err := AnalyzeAccessibility(testTaggedPDF())
+	if err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+	if report.Format != "pdf" || !report.Tagged || report.PDFUA != "1" || report.Title != "Cycling guide" || report.Language != "en-GB" {
+		t.Fatalf("unexpected document properties %+v", report)
+	}
+	roles := make([]string, len(report.Structure))
+	for i, e := range report.Structure {
+		roles[i] = fmt.Sprintf("%d:%s", e.Depth, e.Role)
+	}
+	if want := []string{"0:Document", "1:H1", "1:Figure", "1:H3", "1:Figure", "1:Table", "2:TR", "3:TH", "3:TD"}; !slices.Equal(roles, want) {
+		t.Fatalf("unexpected reading order %v", roles)
+	}
+	if e := report.Structure[2]; e.CustomRole != "Picture" || e.Alt != "A red bicycle" || e.Page != 1 {
+		t.Fatalf("unexpected figure %+v", e)
+	}
+	if report.Figures != 2 || report.FiguresWithAlt != 1 || report.Headings != 2 || report.Tables != 1 || report.TablesWithHeaders != 1 {
+		t.Fatalf("unexpected counts %+v", report)
+	}
+	var codes []string
+	for _, issue := range report.Issues {
+		codes = append(codes, issue.Code)
+	}
+	if !slices.Equal(codes, []string{AccessibilityMissingAltText, AccessibilitySkippedHeadingLevel}) {
+		t.Fatalf("unexpected issues %+v", report.Issues)
+	}
+	// 10 title + 15 language + 30 tagged + 12.5 alt text + 5 headings + 10 tables.
+	if report.Score != 83 {
+		t.Fatalf("unexpected score %d", report.Score)
+	}
+
+	untagged, err := AnalyzeAccessibility([]byte("%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\ntrailer\n<< /Root 1 0 R >>\n%%EOF\n"))
+	if err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+	if untagged.Tagged || untagged.Score != 0 || len(untagged.Issues) != 3 {
+		t.Fatalf("unexpected report for an untagged PDF %+v", untagged)
+	}
+}
+
+func buildDOCX(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range parts {
+		w, _ := zw.Create(name)
+		w.Write([]byte(body))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+const testAccessibleDocument = `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+ xmlns:wp="http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing" xmlns:adec="http://schemas.microsoft.com/office/drawing/2017/decorative"><w:body>
+<w:p><w:pPr><w:pStyle w:val="berschrift1"/></w:pPr><w:r><w:t>Intro</w:t></w:r></w:p>
+<w:p><w:r><w:drawing><wp:inline><wp:docPr id="1" name="Picture 1" descr="Sales by region"/></wp:inline></w:drawing></w:r></w:p>
+<w:p><w:r><w:drawing><wp:inline><wp:docPr id="2" name="Line 2"><a:extLst xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"><a:ext><adec:decorative val="1"/></a:ext></a:extLst></wp:docPr></wp:inline></w:drawing></w:r></w:p>
+<w:p><w:r><w:drawing><wp:inline><wp:docPr id="3" name="Picture 3"/></wp:inline></w:drawing></w:r></w:p>
+<w:p><w:pPr><w:pStyle w:val="Custom"/></w:pPr><w:r><w:t>Details</w:t></w:r></w:p>
+<w:tbl><w:tr><w:trPr><w:tblHeader/></w:trPr><w:tc><w:p/></w:tc></w:tr><w:tr><w:tc><w:p/></w:tc></w:tr></w:tbl>
+<w:tbl><w:tr><w:tc><w:p/></w:tc></w:tr><w:tr><w:trPr><w:tblHeader/></w:trPr><w:tc><w:p/></w:tc></w:tr></w:tbl>
+</w:body></w:document>`
+
+const testAccessibleStyles = `<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+<w:docDefaults><w:rPrDefault><w:rPr><w:lang w:val="de-DE"/></w:rPr></w:rPrDefault></w:docDefaults>
+<w:style w:type="paragraph" w:styleId="berschrift1"><w:name w:val="heading 1"/></w:style>
+<w:style w:type="paragraph" w:styleId="Custom"><w:name w:val="Custom"/><w:pPr><w:outlineLvl w:val="1"/></w:pPr></w:style>
+</w:styles>`
+
+func TestAnalyzeAccessibilityDOCX(t *testing.T) {
+	data := buildDOCX(t, map[string]string{"word/document.xml": testAccessibleDocument, "word/styles.xml": testAccessibleStyles})
+	result := &ExtractionResult{}
+	if err := annotateAccessibility(result, data); err != nil {
+		t.Fatalf("annotate: %v", err)
+	}
+	report, ok := result.Metadata.Accessibility()
+	if !ok || report.Format != "docx" || !report.Tagged || report.Language != "de-DE" || report.Title != "" {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	roles := make([]string, len(report.Structure))
+	for i, e := range report.Structure {
+		roles[i] = e.Role
+	}
+	if want := []string{"H1", "Figure", "Figure", "Figure", "H2", "Table", "Table"}; !slices.Equal(roles, want) {
+		t.Fatalf("unexpected structure %v", roles)
+	}
+	if report.Figures != 3 || report.FiguresWithAlt != 2 || !report.Structure[2].Decorative || report.Headings != 2 || report.TablesWithHeaders != 1 {
+		t.Fatalf("unexpected counts %+v", report)
+	}
+	if len(report.Issues) != 3 || report.Issues[0].Code != AccessibilityMissingTitle {
+		t.Fatalf("unexpected issues %+v", report.Issues)
+	}
+
+	if report, err := AnalyzeAccessibility(buildXLSX(t, nil, map[string]string{"Data": ""}, []string{"Data"})); err != nil || report != nil {
+		t.Fatalf("expected no report for a workbook, got %+v, %v", report, err)
+	}
+}
+
+func TestAccessibilityConfigMerge(t *testing.T) {
+	enabled := true
+	merged := MergeConfigs(&ExtractionConfig{}, &ExtractionConfig{Accessibility: &enabled})
+	if merged.Accessibility == nil || !*merged.Accessibility {
+		t.Fatalf("expected the accessibility switch to merge")
+	}
+}
@@ -0,0 +1,150 @@
+package kreuzberg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// defaultSinkChunkSize is the number of documents extracted per batch call when streaming to a sink.
+const defaultSinkChunkSize = 64
+
+// ResultSink receives batch results one document at a time, in input order. Failed documents
+// are delivered too, with Success false and Metadata.Error set, as in the slice-returning batch
+// functions. An error returned by Put stops the batch.
+type ResultSink interface {
+	Put(index int, result *ExtractionResult) error
+}
+
+// ResultSinkFunc adapts a function to a ResultSink.
+type ResultSinkFunc func(index int, result *ExtractionResult) error
+
+// Put calls f.
+func (f ResultSinkFunc) Put(index int, result *ExtractionResult) error {
+	return f(index, result)
+}
+
+// BatchSinkOptions controls BatchExtractFilesToSink/BatchExtractBytesToSink.
+type BatchSinkOptions struct {
+	// ChunkSize is the number of documents extracted per batch call (default 64). Only one
+	// chunk of results is held in memory at a time.
+	ChunkSize int
+}
+
+// sinkRecord is one line written by a JSONL sink.
+type sinkRecord struct {
+	Index  int               `json:"index"`
+	Result *ExtractionResult `json:"result"`
+}
+
+type jsonlSink struct {
+	enc *json.Encoder
+}
+
+// NewJSONLSink returns a sink that appends each result to w as a JSON line of the form
+// {"index": 0, "result": {...}}. Wrap w in a bufio.Writer for many small results and flush it
+// once the batch returns.
+func NewJSONLSink(w io.Writer) ResultSink {
+	return jsonlSink{enc: json.NewEncoder(w)}
+}
+
+func (s jsonlSink) Put(index int, result *ExtractionResult) error {
+	if err := s.enc.Encode(sinkRecord{Index: index, Result: result}); err != nil {
+		return newIOErrorWithContext(fmt.Sprintf("failed to write result %d", index), err, ErrorCodeIo, nil)
+	}
+	return nil
+}
+
+type dirSink struct {
+	dir string
+}
+
+// NewDirSink returns a sink that writes each result as JSON to "<index>.json" in dir, creating
+// dir if needed. Files are written to a temporary name and renamed into place, so an
+// interrupted batch leaves only complete files.
+func NewDirSink(dir string) ResultSink {
+	return dirSink{dir: dir}
+}
+
+func (s dirSink) Put(index int, result *ExtractionResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return newSerializationErrorWithContext(fmt.Sprintf("failed to encode result %d", index), err, ErrorCodeValidation, nil)
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return newIOErrorWithContext("failed to create result directory", err, ErrorCodeIo, nil)
+	}
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return newIOErrorWithContext(fmt.Sprintf("failed to write result %d", index), err, ErrorCodeIo, nil)
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(s.dir, fmt.Sprintf("%d.json", index)))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return newIOErrorWithContext(fmt.Sprintf("failed to write result %d", index), err, ErrorCodeIo, nil)
+	}
+	return nil
+}
+
+// BatchExtractFilesToSink extracts files like BatchExtractFilesSync, but in chunks whose results
+// are handed to sink and released before the next chunk starts, so memory stays bounded for
+// batches of thousands of documents. ctx is checked before each chunk.
+func BatchExtractFilesToSink(ctx context.Context, paths []string, config *ExtractionConfig, opts *BatchSinkOptions, sink ResultSink) error {
+	return batchExtractFilesToSink(ctx, defaultPluginRegistry, paths, config, opts, sink)
+}
+
+// BatchExtractBytesToSink is BatchExtractFilesToSink for in-memory documents.
+func BatchExtractBytesToSink(ctx context.Context, items []BytesWithMime, config *ExtractionConfig, opts *BatchSinkOptions, sink ResultSink) error {
+	return batchExtractBytesToSink(ctx, defaultPluginRegistry, items, config, opts, sink)
+}
+
+func batchExtractFilesToSink(ctx context.Context, plugins *pluginRegistry, paths []string, config *ExtractionConfig, opts *BatchSinkOptions, sink ResultSink) error {
+	return streamChunks(ctx, len(paths), opts, sink, func(start, end int) ([]*ExtractionResult, error) {
+		return batchExtractFiles(ctx, plugins, paths[start:end], config)
+	})
+}
+
+func batchExtractBytesToSink(ctx context.Context, plugins *pluginRegistry, items []BytesWithMime, config *ExtractionConfig, opts *BatchSinkOptions, sink ResultSink) error {
+	return streamChunks(ctx, len(items), opts, sink, func(start, end int) ([]*ExtractionResult, error) {
+		return batchExtractBytes(ctx, plugins, items[start:end], config)
+	})
+}
+
+// streamChunks runs extract over consecutive chunks of n documents and delivers the results to sink.
+func streamChunks(ctx context.Context, n int, opts *BatchSinkOptions, sink ResultSink, extract func(start, end int) ([]*ExtractionResult, error)) error {
+	if sink == nil {
+		return newValidationErrorWithContext("result sink cannot be nil", nil, ErrorCodeValidation, nil)
+	}
+	chunkSize := defaultSinkChunkSize
+	if opts != nil && opts.ChunkSize > 0 {
+		chunkSize = opts.ChunkSize
+	}
+	for start := 0; start < n; start += chunkSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		end := min(start+chunkSize, n)
+		results, err := extract(start, end)
+		if err != nil {
+			return err
+		}
+		for i, result := range results {
+			if result == nil {
+				continue
+			}
+			if err := sink.Put(start+i, result); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
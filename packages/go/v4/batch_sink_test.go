@@ -0,0 +1,104 @@
+package kreuzberg
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBatchExtractToSinkStreamsChunks(t *testing.T) {
+	var events []string
+	client := NewClient(nil)
+	client.RegisterPostProcessor("trace", 0, func(pc *PluginContext, result *ExtractionResult) error {
+		events = append(events, "extract")
+		return nil
+	})
+	items := make([]BytesWithMime, 5)
+	for i := range items {
+		items[i] = BytesWithMime{Data: []byte(testSRT), MimeType: mimeSRT}
+	}
+
+	var got []int
+	sink := ResultSinkFunc(func(index int, result *ExtractionResult) error {
+		events = append(events, "put")
+		if result.Content != wantSRTContent {
+			t.Errorf("unexpected content for %d: %q", index, result.Content)
+		}
+		got = append(got, index)
+		return nil
+	})
+	if err := client.BatchExtractBytesToSink(context.Background(), items, &BatchSinkOptions{ChunkSize: 2}, sink); err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+	if len(got) != 5 || got[4] != 4 {
+		t.Fatalf("expected results in input order, got %v", got)
+	}
+	want := []string{"extract", "extract", "put", "put", "extract", "extract", "put", "put", "extract", "put"}
+	if len(events) != len(want) {
+		t.Fatalf("expected chunked extraction %v, got %v", want, events)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Fatalf("expected chunked extraction %v, got %v", want, events)
+		}
+	}
+
+	stop := errors.New("disk full")
+	calls := 0
+	err := BatchExtractBytesToSink(context.Background(), items, nil, nil, ResultSinkFunc(func(int, *ExtractionResult) error {
+		calls++
+		return stop
+	}))
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Fatalf("expected the sink error to stop the batch, got %v after %d calls", err, calls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := BatchExtractBytesToSink(ctx, items, nil, nil, sink); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation, got %v", err)
+	}
+}
+
+func TestResultSinks(t *testing.T) {
+	dir := t.TempDir()
+	paths := []string{filepath.Join(dir, "a.srt"), filepath.Join(dir, "b.srt")}
+	for _, path := range paths {
+		os.WriteFile(path, []byte(testSRT), 0o600)
+	}
+
+	var buf bytes.Buffer
+	if err := BatchExtractFilesToSink(context.Background(), paths, nil, nil, NewJSONLSink(&buf)); err != nil {
+		t.Fatalf("jsonl: %v", err)
+	}
+	scanner := bufio.NewScanner(&buf)
+	for i := 0; scanner.Scan(); i++ {
+		var record sinkRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil || record.Index != i || record.Result.Content != wantSRTContent {
+			t.Fatalf("unexpected line %d: %s (%v)", i, scanner.Text(), err)
+		}
+	}
+
+	out := filepath.Join(dir, "results")
+	if err := BatchExtractFilesToSink(context.Background(), paths, nil, &BatchSinkOptions{ChunkSize: 1}, NewDirSink(out)); err != nil {
+		t.Fatalf("dir: %v", err)
+	}
+	entries, _ := os.ReadDir(out)
+	if len(entries) != 2 {
+		t.Fatalf("expected one file per document, got %v", entries)
+	}
+	data, _ := os.ReadFile(filepath.Join(out, "1.json"))
+	var result ExtractionResult
+	if err := json.Unmarshal(data, &result); err != nil || result.Content != wantSRTContent {
+		t.Fatalf("unexpected result file: %s (%v)", data, err)
+	}
+
+	if err := BatchExtractFilesToSink(context.Background(), paths, nil, nil, nil); err == nil {
+		t.Fatalf("expected an error for a nil sink")
+	}
+}
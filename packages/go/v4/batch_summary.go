@@ -0,0 +1,131 @@
+package kreuzberg
+
+import (
+	"context"
+	"os"
+	"time"
+	"unicode/utf8"
+)
+
+// BatchSummary aggregates a batch extraction: what went in, what came out and what failed.
+type BatchSummary struct {
+	Documents int `json:"documents"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	// BytesIn is the total size of the input documents.
+	BytesIn int64 `json:"bytes_in"`
+	// CharsOut is the total number of characters of extracted content.
+	CharsOut int64 `json:"chars_out"`
+	// Formats counts documents by MIME type ("unknown" when a failed document has none).
+	Formats map[string]int `json:"formats"`
+	// FailuresByKind counts failed documents by error type, e.g. "ParsingError".
+	FailuresByKind map[string]int `json:"failures_by_kind,omitempty"`
+	// WallTime is the elapsed time of the batch.
+	WallTime time.Duration `json:"wall_time"`
+	// CPUTime is the user and system CPU time the process used during the batch, including
+	// native worker threads (and any other work running concurrently in the process). It is 0
+	// where the platform does not report process CPU time.
+	CPUTime time.Duration `json:"cpu_time"`
+}
+
+// NewBatchSummary returns an empty summary, for accumulating results with Add, e.g. from a
+// ResultSink or a batch iterator.
+func NewBatchSummary() *BatchSummary {
+	return &BatchSummary{Formats: map[string]int{}, FailuresByKind: map[string]int{}}
+}
+
+// Add counts one document of bytesIn bytes and its result; a nil result counts as a failure.
+func (s *BatchSummary) Add(result *ExtractionResult, bytesIn int64) {
+	s.Documents++
+	s.BytesIn += bytesIn
+	if result == nil {
+		s.Failed++
+		s.FailuresByKind["unknown"]++
+		s.Formats["unknown"]++
+		return
+	}
+	format := result.MimeType
+	if format == "" {
+		format = "unknown"
+	}
+	s.Formats[format]++
+	if result.Metadata.Error != nil {
+		s.Failed++
+		kind := result.Metadata.Error.ErrorType
+		if kind == "" {
+			kind = "unknown"
+		}
+		s.FailuresByKind[kind]++
+		return
+	}
+	s.Succeeded++
+	s.CharsOut += int64(utf8.RuneCountInString(result.Content))
+}
+
+// BatchExtractFilesWithSummary is BatchExtractFilesSync with a summary of the batch. Input sizes
+// are taken from the file system before extraction.
+func BatchExtractFilesWithSummary(ctx context.Context, paths []string, config *ExtractionConfig) ([]*ExtractionResult, *BatchSummary, error) {
+	return batchExtractFilesWithSummary(ctx, defaultPluginRegistry, paths, config)
+}
+
+// BatchExtractBytesWithSummary is BatchExtractBytesSync with a summary of the batch.
+func BatchExtractBytesWithSummary(ctx context.Context, items []BytesWithMime, config *ExtractionConfig) ([]*ExtractionResult, *BatchSummary, error) {
+	return batchExtractBytesWithSummary(ctx, defaultPluginRegistry, items, config)
+}
+
+// BatchExtractFilesWithSummary extracts files with the client's config and plugins; see the
+// package-level BatchExtractFilesWithSummary.
+func (c *Client) BatchExtractFilesWithSummary(ctx context.Context, paths []string) ([]*ExtractionResult, *BatchSummary, error) {
+	return batchExtractFilesWithSummary(ctx, c.plugins, paths, c.config)
+}
+
+// BatchExtractBytesWithSummary extracts in-memory documents with the client's config and
+// plugins.
+func (c *Client) BatchExtractBytesWithSummary(ctx context.Context, items []BytesWithMime) ([]*ExtractionResult, *BatchSummary, error) {
+	return batchExtractBytesWithSummary(ctx, c.plugins, items, c.config)
+}
+
+func batchExtractFilesWithSummary(ctx context.Context, plugins *pluginRegistry, paths []string, config *ExtractionConfig) ([]*ExtractionResult, *BatchSummary, error) {
+	sizes := make([]int64, len(paths))
+	for i, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			sizes[i] = info.Size()
+		}
+	}
+	return summarizeBatch(ctx, sizes, func() ([]*ExtractionResult, error) {
+		return batchExtractFiles(ctx, plugins, paths, config)
+	})
+}
+
+func batchExtractBytesWithSummary(ctx context.Context, plugins *pluginRegistry, items []BytesWithMime, config *ExtractionConfig) ([]*ExtractionResult, *BatchSummary, error) {
+	sizes := make([]int64, len(items))
+	for i, item := range items {
+		sizes[i] = int64(len(item.Data))
+	}
+	return summarizeBatch(ctx, sizes, func() ([]*ExtractionResult, error) {
+		return batchExtractBytes(ctx, plugins, items, config)
+	})
+}
+
+// summarizeBatch runs a batch and summarizes its results; sizes holds the input size of each
+// document.
+func summarizeBatch(ctx context.Context, sizes []int64, run func() ([]*ExtractionResult, error)) ([]*ExtractionResult, *BatchSummary, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	start := time.Now()
+	cpuStart := processCPUTime()
+	results, err := run()
+	if err != nil {
+		return nil, nil, err
+	}
+	summary := NewBatchSummary()
+	summary.WallTime = time.Since(start)
+	if cpuStart > 0 {
+		summary.CPUTime = processCPUTime() - cpuStart
+	}
+	for i, result := range results {
+		summary.Add(result, sizes[i])
+	}
+	return results, summary, nil
+}
@@ -0,0 +1,56 @@
+package kreuzberg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestBatchSummaryAdd(t *testing.T) {
+	summary := NewBatchSummary()
+	summary.Add(&ExtractionResult{MimeType: mimeSRT, Content: "héllo", Success: true}, 100)
+	summary.Add(&ExtractionResult{MimeType: mimeSRT, Content: "bye", Success: true}, 50)
+	summary.Add(&ExtractionResult{MimeType: "application/pdf", Metadata: Metadata{Error: &ErrorMetadata{ErrorType: "ParsingError"}}}, 10)
+	summary.Add(nil, 0)
+
+	if summary.Documents != 4 || summary.Succeeded != 2 || summary.Failed != 2 || summary.BytesIn != 160 || summary.CharsOut != 8 {
+		t.Fatalf("unexpected totals %+v", summary)
+	}
+	if summary.Formats[mimeSRT] != 2 || summary.Formats["application/pdf"] != 1 || summary.Formats["unknown"] != 1 {
+		t.Fatalf("unexpected formats %v", summary.Formats)
+	}
+	if summary.FailuresByKind["ParsingError"] != 1 || summary.FailuresByKind["unknown"] != 1 {
+		t.Fatalf("unexpected failures %v", summary.FailuresByKind)
+	}
+}
+
+func TestBatchExtractFilesWithSummary(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for _, name := range []string{"a.srt", "b.srt"} {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(testSRT), 0o600)
+		paths = append(paths, path)
+	}
+	paths = append(paths, filepath.Join(dir, "missing.srt"))
+
+	results, summary, err := BatchExtractFilesWithSummary(t.Context(), paths, nil)
+	if err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+	if len(results) != 3 || summary.Documents != 3 || summary.Succeeded != 2 || summary.Failed != 1 || len(summary.FailuresByKind) != 1 {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+	if summary.BytesIn != int64(2*len(testSRT)) || summary.CharsOut != int64(2*utf8.RuneCountInString(results[0].Content)) || summary.Formats[mimeSRT] < 2 {
+		t.Fatalf("unexpected totals %+v", summary)
+	}
+	if summary.WallTime <= 0 {
+		t.Fatalf("expected the wall time to be recorded")
+	}
+
+	items := []BytesWithMime{{Data: []byte(testSRT), MimeType: mimeSRT}}
+	if _, summary, err := NewClient(nil).BatchExtractBytesWithSummary(t.Context(), items); err != nil || summary.Succeeded != 1 || summary.BytesIn != int64(len(testSRT)) {
+		t.Fatalf("unexpected bytes summary %+v, %v", summary, err)
+	}
+}
@@ -0,0 +1,418 @@
+// Package bench runs a labeled document corpus through one or more extraction settings and
+// reports latency, memory and accuracy metrics per document and per format.
+//
+// A corpus is a directory of fixture files in the format used by tools/benchmark-harness: one
+// JSON file per document, naming the document and, optionally, a ground-truth text file:
+//
+//	{
+//	  "document": "../docs/report.pdf",
+//	  "file_type": "pdf",
+//	  "ground_truth": {
+//	    "text_file": "../docs/report.txt",
+//	    "tables_file": "../docs/report.tables.json",
+//	    "source": "manual"
+//	  }
+//	}
+//
+// The optional tables file holds a JSON array of tables, each an array of rows of cell texts.
+//
+// Run extracts every fixture with every setting and aggregates the measurements:
+//
+//	corpus, err := bench.LoadCorpus("testdata/corpus")
+//	if err != nil {
+//		return err
+//	}
+//	report, err := bench.Run(ctx, corpus, bench.Options{
+//		Settings: []bench.Setting{
+//			{Name: "default"},
+//			{Name: "ocr", Config: &kreuzberg.ExtractionConfig{ForceOCR: kreuzberg.BoolPtr(true)}},
+//		},
+//		Iterations: 5,
+//		Warmup:     1,
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	for _, s := range report.Summaries {
+//		fmt.Printf("%s/%s: p95 %v, text F1 %.3f\n", s.Setting, s.FileType, s.Latency.P95, s.MeanTextF1)
+//	}
+//
+// Extractions run one at a time, so latency and memory measurements are not skewed by
+// concurrent work.
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	kreuzberg "github.com/kreuzberg-dev/kreuzberg/packages/go/v4"
+)
+
+// Fixture describes one labeled document of a corpus.
+type Fixture struct {
+	// Name is the fixture file name without its .json extension.
+	Name string `json:"-"`
+	// Document is the path of the document. LoadCorpus resolves it relative to the fixture file.
+	Document string `json:"document"`
+	// FileType is the format the document is reported under (default: its file extension).
+	FileType string `json:"file_type"`
+	// FileSize is the document size in bytes (default: the size on disk).
+	FileSize int64 `json:"file_size"`
+	// ExpectedFrameworks lists the frameworks expected to process the document.
+	ExpectedFrameworks []string `json:"expected_frameworks,omitempty"`
+	// Metadata holds free-form information about the document.
+	Metadata map[string]any `json:"metadata,omitempty"`
+	// GroundTruth, when set, enables accuracy metrics for the document.
+	GroundTruth *GroundTruth `json:"ground_truth,omitempty"`
+}
+
+// GroundTruth names the reference text of a document.
+type GroundTruth struct {
+	// TextFile is the path of the reference text. LoadCorpus resolves it relative to the fixture
+	// file.
+	TextFile string `json:"text_file"`
+	// TablesFile optionally names a JSON file holding the expected tables as arrays of rows of
+	// cell texts. LoadCorpus resolves it relative to the fixture file.
+	TablesFile string `json:"tables_file,omitempty"`
+	// Source records how the reference was produced ("pdf_text_layer", "markdown_file" or
+	// "manual").
+	Source string `json:"source"`
+}
+
+// LoadCorpus loads every *.json fixture below dir, sorted by path. Document and ground-truth
+// paths must be relative to their fixture file.
+func LoadCorpus(dir string) ([]Fixture, error) {
+	var corpus []Fixture
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".json" {
+			return err
+		}
+		fixture, err := loadFixture(path)
+		if err != nil {
+			return err
+		}
+		corpus = append(corpus, fixture)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return corpus, nil
+}
+
+func loadFixture(path string) (Fixture, error) {
+	var fixture Fixture
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fixture, fmt.Errorf("bench: read fixture: %w", err)
+	}
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return fixture, fmt.Errorf("bench: decode fixture %s: %w", path, err)
+	}
+	fixture.Name = strings.TrimSuffix(filepath.Base(path), ".json")
+	if fixture.Document == "" || filepath.IsAbs(fixture.Document) {
+		return fixture, fmt.Errorf("bench: fixture %s: document must be a relative path", path)
+	}
+	dir := filepath.Dir(path)
+	fixture.Document = filepath.Join(dir, fixture.Document)
+	if fixture.GroundTruth != nil {
+		gt := *fixture.GroundTruth
+		if gt.TextFile == "" || filepath.IsAbs(gt.TextFile) {
+			return fixture, fmt.Errorf("bench: fixture %s: ground_truth.text_file must be a relative path", path)
+		}
+		switch gt.Source {
+		case "pdf_text_layer", "markdown_file", "manual":
+		default:
+			return fixture, fmt.Errorf("bench: fixture %s: invalid ground_truth.source %q", path, gt.Source)
+		}
+		gt.TextFile = filepath.Join(dir, gt.TextFile)
+		if gt.TablesFile != "" {
+			if filepath.IsAbs(gt.TablesFile) {
+				return fixture, fmt.Errorf("bench: fixture %s: ground_truth.tables_file must be a relative path", path)
+			}
+			gt.TablesFile = filepath.Join(dir, gt.TablesFile)
+		}
+		fixture.GroundTruth = &gt
+	}
+	return fixture, nil
+}
+
+// Setting is a named extraction configuration to benchmark.
+type Setting struct {
+	Name string
+	// Config is the extraction config (nil uses library defaults).
+	Config *kreuzberg.ExtractionConfig
+}
+
+// Options configures Run.
+type Options struct {
+	// Settings are benchmarked in order (default: a single "default" setting).
+	Settings []Setting
+	// Iterations is the number of measured extractions per document and setting (default 1).
+	Iterations int
+	// Warmup is the number of unmeasured extractions run before the measured ones.
+	Warmup int
+}
+
+// Report holds the results of Run.
+type Report struct {
+	LibraryVersion string `json:"library_version"`
+	GoVersion      string `json:"go_version"`
+	// Documents has one entry per setting and fixture, in setting then corpus order.
+	Documents []DocumentResult `json:"documents"`
+	// Summaries aggregates Documents per setting and file type, in setting then file type order.
+	Summaries []FormatSummary `json:"summaries"`
+}
+
+// DocumentResult holds the measurements for one document under one setting.
+type DocumentResult struct {
+	Setting  string `json:"setting"`
+	Fixture  string `json:"fixture"`
+	Document string `json:"document"`
+	FileType string `json:"file_type"`
+	FileSize int64  `json:"file_size"`
+	// Error is the message of the first failed extraction; a failed document has no metrics.
+	Error     string          `json:"error,omitempty"`
+	Durations []time.Duration `json:"durations,omitempty"`
+	Latency   LatencyStats    `json:"latency"`
+	// AllocBytes is the mean Go heap allocation per extraction. Memory allocated by the native
+	// library is only visible in PeakRSSBytes.
+	AllocBytes uint64 `json:"alloc_bytes"`
+	// PeakRSSBytes is the peak resident set size of the process after the document was
+	// extracted (zero where the platform does not report it).
+	PeakRSSBytes uint64 `json:"peak_rss_bytes"`
+	// Quality compares the extracted content with the ground truth, when there is one.
+	Quality *Quality `json:"quality,omitempty"`
+}
+
+// FormatSummary aggregates the results of one setting for one file type.
+type FormatSummary struct {
+	Setting   string `json:"setting"`
+	FileType  string `json:"file_type"`
+	Documents int    `json:"documents"`
+	Failures  int    `json:"failures"`
+	// Latency covers every measured extraction of the successful documents.
+	Latency LatencyStats `json:"latency"`
+	// ThroughputBytesPerSec is the total size of the successful documents divided by the sum of
+	// their mean latencies.
+	ThroughputBytesPerSec float64 `json:"throughput_bytes_per_sec"`
+	MeanAllocBytes        uint64  `json:"mean_alloc_bytes"`
+	PeakRSSBytes          uint64  `json:"peak_rss_bytes"`
+	// Scored is the number of successful documents with a ground truth; the text metrics are
+	// zero when it is zero.
+	Scored        int     `json:"scored"`
+	MeanTextF1    float64 `json:"mean_text_f1"`
+	MeanNumericF1 float64 `json:"mean_numeric_f1"`
+	MeanCER       float64 `json:"mean_cer"`
+	MeanWER       float64 `json:"mean_wer"`
+	// Tables pools the table cells of the scored documents with ground-truth tables (nil when
+	// there are none).
+	Tables *TableScore `json:"tables,omitempty"`
+}
+
+// Run benchmarks every fixture of corpus with every setting. Extraction failures are recorded
+// in the report; Run itself only fails when ctx is done or a ground-truth file cannot be read.
+func Run(ctx context.Context, corpus []Fixture, opts Options) (*Report, error) {
+	settings := opts.Settings
+	if len(settings) == 0 {
+		settings = []Setting{{Name: "default"}}
+	}
+	iterations := max(opts.Iterations, 1)
+
+	truths := make([]*groundTruth, len(corpus))
+	for i, fixture := range corpus {
+		if fixture.GroundTruth == nil {
+			continue
+		}
+		truth, err := loadGroundTruth(*fixture.GroundTruth)
+		if err != nil {
+			return nil, fmt.Errorf("bench: read ground truth of %s: %w", fixture.Name, err)
+		}
+		truths[i] = truth
+	}
+
+	report := &Report{LibraryVersion: kreuzberg.LibraryVersion(), GoVersion: runtime.Version()}
+	for _, setting := range settings {
+		client := kreuzberg.NewClient(setting.Config)
+		for i, fixture := range corpus {
+			result, err := runDocument(ctx, client, setting.Name, fixture, truths[i], opts.Warmup, iterations)
+			if err != nil {
+				return nil, err
+			}
+			report.Documents = append(report.Documents, result)
+		}
+	}
+	report.Summaries = summarize(settings, report.Documents)
+	return report, nil
+}
+
+// groundTruth is the loaded reference of a fixture.
+type groundTruth struct {
+	text   string
+	tables [][][]string
+}
+
+func loadGroundTruth(gt GroundTruth) (*groundTruth, error) {
+	text, err := os.ReadFile(gt.TextFile)
+	if err != nil {
+		return nil, err
+	}
+	truth := &groundTruth{text: string(text)}
+	if gt.TablesFile != "" {
+		data, err := os.ReadFile(gt.TablesFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &truth.tables); err != nil {
+			return nil, fmt.Errorf("decode %s: %w", gt.TablesFile, err)
+		}
+	}
+	return truth, nil
+}
+
+func runDocument(ctx context.Context, client *kreuzberg.Client, setting string, fixture Fixture, truth *groundTruth, warmup, iterations int) (DocumentResult, error) {
+	result := DocumentResult{
+		Setting:  setting,
+		Fixture:  fixture.Name,
+		Document: fixture.Document,
+		FileType: fixture.FileType,
+		FileSize: fixture.FileSize,
+	}
+	if result.FileType == "" {
+		result.FileType = strings.TrimPrefix(strings.ToLower(filepath.Ext(fixture.Document)), ".")
+	}
+	if result.FileSize == 0 {
+		if info, err := os.Stat(fixture.Document); err == nil {
+			result.FileSize = info.Size()
+		}
+	}
+
+	for range warmup {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		if _, err := client.ExtractFile(ctx, fixture.Document); err != nil {
+			result.Error = err.Error()
+			return result, nil
+		}
+	}
+
+	var last *kreuzberg.ExtractionResult
+	var allocated uint64
+	var before, after runtime.MemStats
+	for range iterations {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		runtime.ReadMemStats(&before)
+		start := time.Now()
+		extraction, err := client.ExtractFile(ctx, fixture.Document)
+		elapsed := time.Since(start)
+		runtime.ReadMemStats(&after)
+		if err != nil {
+			result.Error = err.Error()
+			result.Durations = nil
+			return result, nil
+		}
+		result.Durations = append(result.Durations, elapsed)
+		allocated += after.TotalAlloc - before.TotalAlloc
+		last = extraction
+	}
+	result.Latency = latencyStats(result.Durations)
+	result.AllocBytes = allocated / uint64(iterations)
+	result.PeakRSSBytes = peakRSS()
+	if truth != nil {
+		quality := score(last, truth)
+		result.Quality = &quality
+	}
+	return result, nil
+}
+
+func summarize(settings []Setting, documents []DocumentResult) []FormatSummary {
+	type group struct {
+		summary   FormatSummary
+		durations []time.Duration
+		seconds   float64
+		bytes     int64
+		alloc     uint64
+	}
+	groups := map[[2]string]*group{}
+	order := map[string]int{}
+	for i, setting := range settings {
+		if _, ok := order[setting.Name]; !ok {
+			order[setting.Name] = i
+		}
+	}
+	for _, doc := range documents {
+		key := [2]string{doc.Setting, doc.FileType}
+		g := groups[key]
+		if g == nil {
+			g = &group{summary: FormatSummary{Setting: doc.Setting, FileType: doc.FileType}}
+			groups[key] = g
+		}
+		s := &g.summary
+		s.Documents++
+		if doc.Error != "" {
+			s.Failures++
+			continue
+		}
+		g.durations = append(g.durations, doc.Durations...)
+		g.seconds += doc.Latency.Mean.Seconds()
+		g.bytes += doc.FileSize
+		g.alloc += doc.AllocBytes
+		s.PeakRSSBytes = max(s.PeakRSSBytes, doc.PeakRSSBytes)
+		if doc.Quality != nil {
+			s.Scored++
+			s.MeanTextF1 += doc.Quality.TextF1
+			s.MeanNumericF1 += doc.Quality.NumericF1
+			s.MeanCER += doc.Quality.CER
+			s.MeanWER += doc.Quality.WER
+			if t := doc.Quality.Tables; t != nil {
+				if s.Tables == nil {
+					s.Tables = &TableScore{}
+				}
+				s.Tables.Matched += t.Matched
+				s.Tables.Extracted += t.Extracted
+				s.Tables.Expected += t.Expected
+			}
+		}
+	}
+
+	summaries := make([]FormatSummary, 0, len(groups))
+	for _, g := range groups {
+		s := g.summary
+		s.Latency = latencyStats(g.durations)
+		if succeeded := s.Documents - s.Failures; succeeded > 0 {
+			s.MeanAllocBytes = g.alloc / uint64(succeeded)
+		}
+		if g.seconds > 0 {
+			s.ThroughputBytesPerSec = float64(g.bytes) / g.seconds
+		}
+		if s.Scored > 0 {
+			s.MeanTextF1 /= float64(s.Scored)
+			s.MeanNumericF1 /= float64(s.Scored)
+			s.MeanCER /= float64(s.Scored)
+			s.MeanWER /= float64(s.Scored)
+		}
+		if s.Tables != nil {
+			s.Tables.finish()
+		}
+		summaries = append(summaries, s)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		a, b := summaries[i], summaries[j]
+		if order[a.Setting] != order[b.Setting] {
+			return order[a.Setting] < order[b.Setting]
+		}
+		return a.FileType < b.FileType
+	})
+	return summaries
+}
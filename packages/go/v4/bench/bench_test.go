@@ -0,0 +1,117 @@
+package bench_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	kreuzberg "github.com/kreuzberg-dev/kreuzberg/packages/go/v4"
+	"github.com/kreuzberg-dev/kreuzberg/packages/go/v4/bench"
+)
+
+const testSRT = "1\r\n00:00:01,600 --> 00:00:04,200\r\nHello there.\r\n\r\n2\r\n00:00:05,000 --> 00:00:07,250\r\nGeneral Kenobi, 66 times!\r\n\r\n"
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+}
+
+func writeCorpus(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "docs", "exact.srt"), testSRT)
+	writeFile(t, filepath.Join(dir, "docs", "exact.txt"), "hello there general kenobi 66 times")
+	writeFile(t, filepath.Join(dir, "docs", "partial.srt"), testSRT)
+	writeFile(t, filepath.Join(dir, "docs", "partial.txt"), "Hello there. General Kenobi, 67 times!")
+	writeFile(t, filepath.Join(dir, "docs", "partial.tables.json"), `[[["Jedi", "Rank"], ["Kenobi", "General"]]]`)
+	writeFile(t, filepath.Join(dir, "fixtures", "exact.json"), `{"document": "../docs/exact.srt", "ground_truth": {"text_file": "../docs/exact.txt", "source": "manual"}}`)
+	writeFile(t, filepath.Join(dir, "fixtures", "srt", "partial.json"), `{"document": "../../docs/partial.srt", "file_type": "srt", "ground_truth": {"text_file": "../../docs/partial.txt", "tables_file": "../../docs/partial.tables.json", "source": "manual"}}`)
+	writeFile(t, filepath.Join(dir, "fixtures", "missing.json"), `{"document": "../docs/missing.srt", "file_type": "srt"}`)
+	return filepath.Join(dir, "fixtures")
+}
+
+func TestRunReportsMetricsPerFormat(t *testing.T) {
+	corpus, err := bench.LoadCorpus(writeCorpus(t))
+	if err != nil {
+		t.Fatalf("load corpus: %v", err)
+	}
+	if len(corpus) != 3 || corpus[0].Name != "exact" || corpus[1].Name != "missing" || corpus[2].Name != "partial" {
+		t.Fatalf("unexpected corpus: %+v", corpus)
+	}
+
+	report, err := bench.Run(context.Background(), corpus, bench.Options{
+		Settings:   []bench.Setting{{Name: "default"}, {Name: "no-cache", Config: &kreuzberg.ExtractionConfig{UseCache: kreuzberg.BoolPtr(false)}}},
+		Iterations: 3,
+		Warmup:     1,
+	})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(report.Documents) != 6 || len(report.Summaries) != 2 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+
+	exact, missing, partial := report.Documents[0], report.Documents[1], report.Documents[2]
+	if exact.Error != "" || len(exact.Durations) != 3 || exact.FileType != "srt" || exact.FileSize != int64(len(testSRT)) {
+		t.Fatalf("unexpected result: %+v", exact)
+	}
+	if exact.Latency.Min > exact.Latency.Median || exact.Latency.Median > exact.Latency.Max {
+		t.Fatalf("inconsistent latency: %+v", exact.Latency)
+	}
+	if exact.Quality == nil || exact.Quality.TextF1 != 1 || exact.Quality.NumericF1 != 1 || exact.Quality.Tables != nil {
+		t.Fatalf("expected a perfect score, got %+v", exact.Quality)
+	}
+	if missing.Error == "" || missing.Durations != nil || missing.Quality != nil {
+		t.Fatalf("expected a failed document, got %+v", missing)
+	}
+	if q := partial.Quality; q == nil || q.TextF1 <= 0.8 || q.TextF1 >= 1 || q.NumericF1 != 0 || q.CER <= 0 || q.WER <= 0 || q.Tables == nil || q.Tables.Expected != 4 || q.Tables.Recall != 0 {
+		t.Fatalf("expected a partial score, got %+v", q)
+	}
+
+	summary := report.Summaries[0]
+	if summary.Setting != "default" || summary.FileType != "srt" || summary.Documents != 3 || summary.Failures != 1 || summary.Scored != 2 {
+		t.Fatalf("unexpected summary: %+v", summary)
+	}
+	if want := (exact.Quality.TextF1 + partial.Quality.TextF1) / 2; summary.MeanTextF1 != want {
+		t.Fatalf("mean text F1 = %v, want %v", summary.MeanTextF1, want)
+	}
+	if summary.Tables == nil || summary.Tables.Expected != 4 || summary.MeanWER != (exact.Quality.WER+partial.Quality.WER)/2 {
+		t.Fatalf("unexpected summary accuracy: %+v", summary)
+	}
+	if summary.ThroughputBytesPerSec <= 0 || summary.Latency.P95 < summary.Latency.Median {
+		t.Fatalf("unexpected summary metrics: %+v", summary)
+	}
+	if report.Summaries[1].Setting != "no-cache" {
+		t.Fatalf("expected summaries in setting order, got %+v", report.Summaries)
+	}
+	if _, err := json.Marshal(report); err != nil {
+		t.Fatalf("encode report: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := bench.Run(ctx, corpus, bench.Options{}); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestLoadCorpusValidatesFixtures(t *testing.T) {
+	for name, fixture := range map[string]string{
+		"absolute document": `{"document": "/etc/passwd"}`,
+		"bad source":        `{"document": "a.srt", "ground_truth": {"text_file": "a.txt", "source": "guess"}}`,
+		"invalid json":      `{"document":`,
+	} {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "f.json"), fixture)
+		if _, err := bench.LoadCorpus(dir); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package bench
+
+import (
+	"strings"
+
+	kreuzberg "github.com/kreuzberg-dev/kreuzberg/packages/go/v4"
+)
+
+// CharacterErrorRate returns the character error rate of hypothesis against reference: the
+// number of character insertions, deletions and substitutions needed to turn one into the other,
+// divided by the length of reference. Runs of whitespace count as a single space and leading and
+// trailing whitespace is ignored, so layout differences do not dominate the score. The rate
+// exceeds 1 when hypothesis is much longer than reference; an empty reference yields 0 for an
+// empty hypothesis and 1 otherwise.
+//
+// The cost grows with the length of the texts times their distance, so very different
+// multi-megabyte texts are best compared page by page.
+func CharacterErrorRate(hypothesis, reference string) float64 {
+	return errorRate([]rune(normalizeSpace(hypothesis)), []rune(normalizeSpace(reference)))
+}
+
+// WordErrorRate returns the word error rate of hypothesis against reference: the number of word
+// insertions, deletions and substitutions divided by the number of words in reference. Words are
+// separated by whitespace and compared exactly. Empty references are handled as in
+// CharacterErrorRate.
+func WordErrorRate(hypothesis, reference string) float64 {
+	return errorRate(strings.Fields(hypothesis), strings.Fields(reference))
+}
+
+func normalizeSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func errorRate[T comparable](hypothesis, reference []T) float64 {
+	if len(reference) == 0 {
+		if len(hypothesis) == 0 {
+			return 0
+		}
+		return 1
+	}
+	return float64(editDistance(hypothesis, reference)) / float64(len(reference))
+}
+
+// editDistance returns the Levenshtein distance of a and b. It runs the dynamic program in a
+// diagonal band that doubles until it contains an optimal path, which takes O(len * distance)
+// time instead of O(len(a) * len(b)) for similar inputs.
+func editDistance[T comparable](a, b []T) int {
+	if len(a) < len(b) {
+		a, b = b, a
+	}
+	if len(b) == 0 {
+		return len(a)
+	}
+	for band := max(len(a)-len(b), 32); ; band *= 2 {
+		d := bandedEditDistance(a, b, band)
+		if d <= band || band >= len(a) {
+			return d
+		}
+	}
+}
+
+// bandedEditDistance computes the edit distance of a and b (len(a) >= len(b)) through cells
+// within band of the diagonal. The result is exact when it does not exceed band; otherwise it
+// is band+1. band must be at least len(a)-len(b).
+func bandedEditDistance[T comparable](a, b []T, band int) int {
+	inf := band + 1
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = min(j, inf)
+	}
+	for i := 1; i <= len(a); i++ {
+		lo, hi := max(1, i-band), min(len(b), i+band)
+		cur[0] = min(i, inf)
+		if lo > 1 {
+			cur[lo-1] = inf
+		}
+		for j := lo; j <= hi; j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j-1]+cost, prev[j]+1, cur[j-1]+1, inf)
+		}
+		if hi < len(b) {
+			cur[hi+1] = inf
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// TableScore is the precision and recall of extracted table cells against ground-truth cells.
+type TableScore struct {
+	// Matched is the number of extracted cells that equal the ground-truth cell at the same row
+	// and column.
+	Matched int `json:"matched"`
+	// Extracted and Expected count the non-empty extracted and ground-truth cells.
+	Extracted int     `json:"extracted"`
+	Expected  int     `json:"expected"`
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	F1        float64 `json:"f1"`
+}
+
+// CompareTableCells scores the cells of one extracted table against a ground-truth table. Cells
+// match when they sit at the same row and column and their text is equal after collapsing
+// whitespace; empty cells are ignored.
+func CompareTableCells(got, want [][]string) TableScore {
+	var score TableScore
+	score.add(got, want)
+	score.finish()
+	return score
+}
+
+// CompareTables scores all tables extracted from a document against its ground-truth tables.
+// Each ground-truth table is paired with the not yet paired extracted table sharing the most
+// cells with it, and the cell counts of all pairs are pooled; extracted tables left unpaired
+// lower precision and ground-truth tables left unpaired lower recall.
+func CompareTables(got []kreuzberg.Table, want [][][]string) TableScore {
+	var score TableScore
+	paired := make([]bool, len(got))
+	for _, wantCells := range want {
+		best, bestMatched := -1, -1
+		for i, table := range got {
+			if paired[i] {
+				continue
+			}
+			if matched := CompareTableCells(table.Cells, wantCells).Matched; matched > bestMatched {
+				best, bestMatched = i, matched
+			}
+		}
+		if best < 0 {
+			score.add(nil, wantCells)
+			continue
+		}
+		paired[best] = true
+		score.add(got[best].Cells, wantCells)
+	}
+	for i, table := range got {
+		if !paired[i] {
+			score.add(table.Cells, nil)
+		}
+	}
+	score.finish()
+	return score
+}
+
+func (s *TableScore) add(got, want [][]string) {
+	for _, row := range got {
+		for _, cell := range row {
+			if normalizeSpace(cell) != "" {
+				s.Extracted++
+			}
+		}
+	}
+	for r, row := range want {
+		for c, cell := range row {
+			cell = normalizeSpace(cell)
+			if cell == "" {
+				continue
+			}
+			s.Expected++
+			if r < len(got) && c < len(got[r]) && normalizeSpace(got[r][c]) == cell {
+				s.Matched++
+			}
+		}
+	}
+}
+
+func (s *TableScore) finish() {
+	if s.Extracted > 0 {
+		s.Precision = float64(s.Matched) / float64(s.Extracted)
+	}
+	if s.Expected > 0 {
+		s.Recall = float64(s.Matched) / float64(s.Expected)
+	}
+	if s.Extracted == 0 && s.Expected == 0 {
+		s.Precision, s.Recall = 1, 1
+	}
+	if s.Precision+s.Recall > 0 {
+		s.F1 = 2 * s.Precision * s.Recall / (s.Precision + s.Recall)
+	}
+}
@@ -0,0 +1,100 @@
+package bench_test
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	kreuzberg "github.com/kreuzberg-dev/kreuzberg/packages/go/v4"
+	"github.com/kreuzberg-dev/kreuzberg/packages/go/v4/bench"
+)
+
+func TestErrorRates(t *testing.T) {
+	for _, tc := range []struct {
+		hypothesis, reference string
+		cer, wer              float64
+	}{
+		{"the quick brown fox", "the quick brown fox", 0, 0},
+		{"the  quick\nbrown fox ", "the quick brown fox", 0, 0},
+		{"the quikc brown fox", "the quick brown fox", 2.0 / 19, 1.0 / 4},
+		{"quick brown fox", "the quick brown fox", 4.0 / 19, 1.0 / 4},
+		{"", "abcd", 1, 1},
+		{"abcd", "", 1, 1},
+		{"", "", 0, 0},
+		{"kitten", "sitting", 3.0 / 7, 1},
+	} {
+		if got := bench.CharacterErrorRate(tc.hypothesis, tc.reference); math.Abs(got-tc.cer) > 1e-9 {
+			t.Errorf("CharacterErrorRate(%q, %q) = %v, want %v", tc.hypothesis, tc.reference, got, tc.cer)
+		}
+		if got := bench.WordErrorRate(tc.hypothesis, tc.reference); math.Abs(got-tc.wer) > 1e-9 {
+			t.Errorf("WordErrorRate(%q, %q) = %v, want %v", tc.hypothesis, tc.reference, got, tc.wer)
+		}
+	}
+}
+
+// naiveEditDistance is the textbook quadratic Levenshtein distance.
+func naiveEditDistance(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j-1]+cost, prev[j]+1, cur[j-1]+1)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func TestCharacterErrorRateMatchesQuadraticDistance(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	randomText := func(n int) string {
+		var b strings.Builder
+		for range n {
+			b.WriteByte("abc"[rng.Intn(3)])
+		}
+		return b.String()
+	}
+	for range 200 {
+		a, b := randomText(rng.Intn(150)), randomText(1+rng.Intn(150))
+		want := float64(naiveEditDistance([]rune(a), []rune(b))) / float64(len(b))
+		if got := bench.CharacterErrorRate(a, b); math.Abs(got-want) > 1e-9 {
+			t.Fatalf("CharacterErrorRate(%q, %q) = %v, want %v", a, b, got, want)
+		}
+	}
+}
+
+func TestCompareTables(t *testing.T) {
+	want := [][]string{{"Name", "Qty"}, {"Apples", "3"}, {"Pears", " 4 "}}
+	exact := bench.CompareTableCells([][]string{{"Name", "Qty"}, {"Apples", "3"}, {"Pears", "4"}}, want)
+	if exact.F1 != 1 || exact.Matched != 6 {
+		t.Fatalf("expected a perfect score, got %+v", exact)
+	}
+	// A wrong cell and an extra row: 5 of 7 extracted cells match 5 of 6 expected ones.
+	partial := bench.CompareTableCells([][]string{{"Name", "Qty"}, {"Apples", "8"}, {"Pears", "4"}, {"Total", ""}}, want)
+	if partial.Matched != 5 || partial.Extracted != 7 || partial.Expected != 6 || math.Abs(partial.Precision-5.0/7) > 1e-9 || math.Abs(partial.Recall-5.0/6) > 1e-9 {
+		t.Fatalf("unexpected partial score: %+v", partial)
+	}
+
+	other := [][]string{{"a", "b"}}
+	got := []kreuzberg.Table{{Cells: [][]string{{"x"}}}, {Cells: want}}
+	score := bench.CompareTables(got, [][][]string{want, other})
+	// want pairs with the second table; other pairs with the first and matches nothing.
+	if score.Matched != 6 || score.Extracted != 7 || score.Expected != 8 {
+		t.Fatalf("unexpected document score: %+v", score)
+	}
+	if empty := bench.CompareTables(nil, nil); empty.F1 != 1 {
+		t.Fatalf("expected a perfect score without tables, got %+v", empty)
+	}
+	if missed := bench.CompareTables(nil, [][][]string{want}); missed.Recall != 0 || missed.F1 != 0 {
+		t.Fatalf("expected zero recall, got %+v", missed)
+	}
+}
@@ -0,0 +1,119 @@
+package bench
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	kreuzberg "github.com/kreuzberg-dev/kreuzberg/packages/go/v4"
+)
+
+// LatencyStats summarizes extraction durations.
+type LatencyStats struct {
+	Mean   time.Duration `json:"mean"`
+	Median time.Duration `json:"median"`
+	P95    time.Duration `json:"p95"`
+	Min    time.Duration `json:"min"`
+	Max    time.Duration `json:"max"`
+}
+
+func latencyStats(durations []time.Duration) LatencyStats {
+	if len(durations) == 0 {
+		return LatencyStats{}
+	}
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+	var total time.Duration
+	for _, d := range sorted {
+		total += d
+	}
+	return LatencyStats{
+		Mean:   total / time.Duration(len(sorted)),
+		Median: percentile(sorted, 0.5),
+		P95:    percentile(sorted, 0.95),
+		Min:    sorted[0],
+		Max:    sorted[len(sorted)-1],
+	}
+}
+
+// percentile returns the nearest-rank percentile of sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
+
+// Quality compares extracted content with a ground truth.
+type Quality struct {
+	// TextF1 is the F1 score of the word tokens of the content against those of the ground
+	// truth, compared case-insensitively as multisets (0 to 1).
+	TextF1 float64 `json:"text_f1"`
+	// NumericF1 is the same score restricted to tokens containing a digit. It is 1 when neither
+	// text holds numbers.
+	NumericF1 float64 `json:"numeric_f1"`
+	// CER and WER are the character and word error rates of the content against the ground
+	// truth (see CharacterErrorRate and WordErrorRate).
+	CER float64 `json:"cer"`
+	WER float64 `json:"wer"`
+	// Tables scores the extracted tables against the ground-truth tables, when the fixture has a
+	// tables file.
+	Tables *TableScore `json:"tables,omitempty"`
+}
+
+func score(result *kreuzberg.ExtractionResult, truth *groundTruth) Quality {
+	got, want := tokenize(result.Content), tokenize(truth.text)
+	quality := Quality{
+		TextF1:    tokenF1(got, want),
+		NumericF1: tokenF1(numericTokens(got), numericTokens(want)),
+		CER:       CharacterErrorRate(result.Content, truth.text),
+		WER:       WordErrorRate(result.Content, truth.text),
+	}
+	if truth.tables != nil {
+		tables := CompareTables(result.Tables, truth.tables)
+		quality.Tables = &tables
+	}
+	return quality
+}
+
+func tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func numericTokens(tokens []string) []string {
+	var numeric []string
+	for _, token := range tokens {
+		if strings.IndexFunc(token, unicode.IsDigit) >= 0 {
+			numeric = append(numeric, token)
+		}
+	}
+	return numeric
+}
+
+func tokenF1(got, want []string) float64 {
+	if len(got) == 0 && len(want) == 0 {
+		return 1
+	}
+	if len(got) == 0 || len(want) == 0 {
+		return 0
+	}
+	counts := make(map[string]int, len(want))
+	for _, token := range want {
+		counts[token]++
+	}
+	matched := 0
+	for _, token := range got {
+		if counts[token] > 0 {
+			counts[token]--
+			matched++
+		}
+	}
+	if matched == 0 {
+		return 0
+	}
+	precision := float64(matched) / float64(len(got))
+	recall := float64(matched) / float64(len(want))
+	return 2 * precision * recall / (precision + recall)
+}
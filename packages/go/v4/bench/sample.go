@@ -0,0 +1,434 @@
+package bench
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Profile holds properties of a fixture that can be probed without extracting it, used to
+// stratify samples.
+type Profile struct {
+	// Format is the fixture's FileType, or its file extension.
+	Format string `json:"format"`
+	// Size is the document size in bytes.
+	Size int64 `json:"size"`
+	// Pages is the page (or slide) count: the "pages" or "page_count" fixture metadata, the page
+	// objects of a PDF, or the count recorded in the properties of an Office Open XML document.
+	// It is zero when unknown.
+	Pages int `json:"pages"`
+	// Language is the "language" fixture metadata or a guess from the first 32 KiB of plain-text
+	// formats: an ISO 639-1 code when the script or common words identify the language, the
+	// script name (e.g. "cyrillic") when only the script is known, and "" otherwise.
+	Language string `json:"language"`
+}
+
+// ProbeFixture reads the Profile of a fixture. It only scans the document for markers, which is
+// much cheaper than extracting it.
+func ProbeFixture(fixture Fixture) (Profile, error) {
+	profile := Profile{
+		Format: fixture.FileType,
+		Size:   fixture.FileSize,
+	}
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(fixture.Document)), ".")
+	if profile.Format == "" {
+		profile.Format = ext
+	}
+	info, err := os.Stat(fixture.Document)
+	if err != nil {
+		return profile, fmt.Errorf("bench: probe %s: %w", fixture.Name, err)
+	}
+	if profile.Size == 0 {
+		profile.Size = info.Size()
+	}
+
+	for _, key := range []string{"pages", "page_count"} {
+		if n, ok := fixture.Metadata[key].(float64); ok && n > 0 {
+			profile.Pages = int(n)
+			break
+		}
+	}
+	if profile.Pages == 0 {
+		switch ext {
+		case "pdf":
+			profile.Pages, err = probePDFPages(fixture.Document)
+		case "docx", "docm", "pptx", "pptm":
+			profile.Pages, err = probeOOXMLPages(fixture.Document)
+		}
+		if err != nil {
+			return profile, fmt.Errorf("bench: probe %s: %w", fixture.Name, err)
+		}
+	}
+
+	if lang, ok := fixture.Metadata["language"].(string); ok {
+		profile.Language = lang
+	} else if textFormats[ext] {
+		if profile.Language, err = probeLanguage(fixture.Document); err != nil {
+			return profile, fmt.Errorf("bench: probe %s: %w", fixture.Name, err)
+		}
+	}
+	return profile, nil
+}
+
+// pdfPageObject matches the type entry of a PDF page object, but not of the page tree.
+var pdfPageObject = regexp.MustCompile(`/Type\s*/Page\b`)
+
+// probePDFPages counts page objects in chunks, so large PDFs are not loaded at once. Pages
+// stored in compressed object streams are not visible to the scan.
+func probePDFPages(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	const chunkSize, overlap = 1 << 20, 32
+	buf := make([]byte, overlap+chunkSize)
+	carried, pages := 0, 0
+	for {
+		n, err := io.ReadFull(f, buf[carried:])
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return 0, err
+		}
+		data := buf[:carried+n]
+		// Matches starting in the last bytes of a chunk are counted with the next one, which
+		// starts with those bytes.
+		limit := len(data)
+		if err == nil {
+			limit -= overlap
+		}
+		for _, match := range pdfPageObject.FindAllIndex(data, -1) {
+			if match[0] < limit {
+				pages++
+			}
+		}
+		if err != nil {
+			return pages, nil
+		}
+		carried = copy(buf, data[limit:])
+	}
+}
+
+// probeOOXMLPages reads the page or slide count from docProps/app.xml.
+func probeOOXMLPages(path string) (int, error) {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return 0, err
+	}
+	defer archive.Close()
+	for _, file := range archive.File {
+		if file.Name != "docProps/app.xml" {
+			continue
+		}
+		r, err := file.Open()
+		if err != nil {
+			return 0, err
+		}
+		defer r.Close()
+		var props struct {
+			Pages  int `xml:"Pages"`
+			Slides int `xml:"Slides"`
+		}
+		if err := xml.NewDecoder(io.LimitReader(r, 1<<20)).Decode(&props); err != nil {
+			return 0, nil
+		}
+		return max(props.Pages, props.Slides), nil
+	}
+	return 0, nil
+}
+
+var textFormats = map[string]bool{
+	"txt": true, "text": true, "md": true, "markdown": true, "rst": true, "org": true,
+	"html": true, "htm": true, "xml": true, "csv": true, "tsv": true, "json": true,
+	"srt": true, "vtt": true, "tex": true, "eml": true,
+}
+
+// stopwords are frequent short words that tell Latin-script languages apart.
+var stopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "in", "that", "it", "with", "for"},
+	"de": {"der", "die", "und", "das", "ist", "nicht", "mit", "ein", "zu", "den"},
+	"fr": {"le", "la", "les", "et", "des", "est", "une", "pas", "que", "pour"},
+	"es": {"el", "los", "las", "y", "es", "una", "por", "con", "que", "para"},
+	"it": {"il", "che", "di", "e", "della", "sono", "per", "una", "non", "gli"},
+	"pt": {"o", "os", "as", "e", "que", "uma", "não", "com", "para", "do"},
+	"nl": {"de", "het", "een", "en", "van", "is", "niet", "dat", "op", "zijn"},
+}
+
+func probeLanguage(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	head, err := io.ReadAll(io.LimitReader(f, 32<<10))
+	if err != nil {
+		return "", err
+	}
+	return guessLanguage(string(bytes.ToValidUTF8(head, nil))), nil
+}
+
+func guessLanguage(text string) string {
+	scripts := map[string]int{}
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			scripts["latin"]++
+		case unicode.Is(unicode.Cyrillic, r):
+			scripts["cyrillic"]++
+		case unicode.Is(unicode.Greek, r):
+			scripts["el"]++
+		case unicode.Is(unicode.Arabic, r):
+			scripts["arabic"]++
+		case unicode.Is(unicode.Hebrew, r):
+			scripts["he"]++
+		case unicode.Is(unicode.Devanagari, r):
+			scripts["devanagari"]++
+		case unicode.Is(unicode.Thai, r):
+			scripts["th"]++
+		case unicode.Is(unicode.Hangul, r):
+			scripts["ko"]++
+		case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+			scripts["kana"]++
+		case unicode.Is(unicode.Han, r):
+			scripts["han"]++
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+	// Japanese mixes kana with Han; Chinese uses Han alone.
+	if scripts["kana"] > 0 {
+		scripts["ja"] = scripts["kana"] + scripts["han"]
+		delete(scripts, "kana")
+		delete(scripts, "han")
+	} else if scripts["han"] > 0 {
+		scripts["zh"] = scripts["han"]
+		delete(scripts, "han")
+	}
+	script, count := "", 0
+	for name, n := range scripts {
+		if n > count || (n == count && name < script) {
+			script, count = name, n
+		}
+	}
+	if count*2 < letters {
+		return ""
+	}
+	if script == "latin" {
+		return guessLatinLanguage(text)
+	}
+	return script
+}
+
+func guessLatinLanguage(text string) string {
+	words := map[string]int{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		words[word]++
+	}
+	best, bestHits, total := "latin", 0, 0
+	for _, n := range words {
+		total += n
+	}
+	langs := make([]string, 0, len(stopwords))
+	for lang := range stopwords {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	for _, lang := range langs {
+		hits := 0
+		for _, word := range stopwords[lang] {
+			hits += words[word]
+		}
+		if hits > bestHits {
+			best, bestHits = lang, hits
+		}
+	}
+	// Require stopwords to make up a noticeable share of the text.
+	if bestHits*20 < total {
+		return "latin"
+	}
+	return best
+}
+
+// Stratifier assigns a fixture to a stratum by returning its label.
+type Stratifier func(Profile) string
+
+// ByFormat stratifies by Profile.Format.
+func ByFormat(p Profile) string {
+	return p.Format
+}
+
+// ByLanguage stratifies by Profile.Language; fixtures of unknown language share a stratum.
+func ByLanguage(p Profile) string {
+	return p.Language
+}
+
+// BySize stratifies by document size, splitting at the given byte boundaries (e.g. 100<<10,
+// 1<<20, 10<<20). A document belongs to the first bucket whose boundary exceeds its size.
+func BySize(bounds ...int64) Stratifier {
+	bounds = slices.Clone(bounds)
+	slices.Sort(bounds)
+	return func(p Profile) string {
+		return bucket("size", p.Size, bounds)
+	}
+}
+
+// ByPages stratifies by page count, splitting at the given boundaries (e.g. 2, 10, 50).
+// Documents of unknown page count share the "pages:unknown" stratum.
+func ByPages(pageBounds ...int) Stratifier {
+	bounds := make([]int64, len(pageBounds))
+	for i, bound := range pageBounds {
+		bounds[i] = int64(bound)
+	}
+	slices.Sort(bounds)
+	return func(p Profile) string {
+		if p.Pages == 0 {
+			return "pages:unknown"
+		}
+		return bucket("pages", int64(p.Pages), bounds)
+	}
+}
+
+func bucket(name string, v int64, bounds []int64) string {
+	for _, bound := range bounds {
+		if v < bound {
+			return name + "<" + strconv.FormatInt(bound, 10)
+		}
+	}
+	if len(bounds) == 0 {
+		return name
+	}
+	return name + ">=" + strconv.FormatInt(bounds[len(bounds)-1], 10)
+}
+
+// SampleOptions configures Sample.
+type SampleOptions struct {
+	// Size is the number of fixtures to sample. The whole corpus is returned when it is not
+	// smaller than Size.
+	Size int
+	// Strata split the corpus; a fixture's stratum combines the labels of all stratifiers
+	// (default: ByFormat).
+	Strata []Stratifier
+	// MinPerStratum is the number of fixtures every stratum contributes, if it has that many
+	// (default 1), so rare formats stay represented. The sample exceeds Size when the strata
+	// outnumber it.
+	MinPerStratum int
+	// Seed makes the sample reproducible.
+	Seed uint64
+}
+
+// Sample returns a stratified random sample of corpus in corpus order. Fixtures are probed with
+// ProbeFixture and, after each stratum received MinPerStratum fixtures, the rest of the sample is
+// allocated to strata in proportion to their remaining fixtures.
+func Sample(corpus []Fixture, opts SampleOptions) ([]Fixture, error) {
+	if opts.Size <= 0 {
+		return nil, fmt.Errorf("bench: sample size must be positive")
+	}
+	if len(corpus) <= opts.Size {
+		return corpus, nil
+	}
+	strata := opts.Strata
+	if len(strata) == 0 {
+		strata = []Stratifier{ByFormat}
+	}
+	minPer := opts.MinPerStratum
+	if minPer <= 0 {
+		minPer = 1
+	}
+
+	groups := map[string][]int{}
+	var keys []string
+	for i, fixture := range corpus {
+		profile, err := ProbeFixture(fixture)
+		if err != nil {
+			return nil, err
+		}
+		labels := make([]string, len(strata))
+		for j, stratify := range strata {
+			labels[j] = stratify(profile)
+		}
+		key := strings.Join(labels, "\x00")
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], i)
+	}
+
+	quota := allocate(keys, groups, opts.Size, minPer)
+	rng := rand.New(rand.NewPCG(opts.Seed, 0))
+	var picked []int
+	for _, key := range keys {
+		members := slices.Clone(groups[key])
+		rng.Shuffle(len(members), func(i, j int) { members[i], members[j] = members[j], members[i] })
+		picked = append(picked, members[:quota[key]]...)
+	}
+	slices.Sort(picked)
+	sample := make([]Fixture, len(picked))
+	for i, idx := range picked {
+		sample[i] = corpus[idx]
+	}
+	return sample, nil
+}
+
+// allocate splits size between strata: each gets up to minPer fixtures, and the rest is shared
+// in proportion to the fixtures each stratum has left, by the largest remainder method.
+func allocate(keys []string, groups map[string][]int, size, minPer int) map[string]int {
+	quota := make(map[string]int, len(keys))
+	remaining := size
+	for _, key := range keys {
+		quota[key] = min(minPer, len(groups[key]))
+		remaining -= quota[key]
+	}
+	for remaining > 0 {
+		type share struct {
+			key       string
+			remainder float64
+		}
+		var shares []share
+		capacity, assigned := 0, 0
+		for _, key := range keys {
+			capacity += len(groups[key]) - quota[key]
+		}
+		if capacity == 0 {
+			break
+		}
+		for _, key := range keys {
+			free := len(groups[key]) - quota[key]
+			if free == 0 {
+				continue
+			}
+			exact := float64(remaining) * float64(free) / float64(capacity)
+			n := min(int(exact), free)
+			quota[key] += n
+			assigned += n
+			shares = append(shares, share{key, exact - float64(n)})
+		}
+		sort.SliceStable(shares, func(i, j int) bool { return shares[i].remainder > shares[j].remainder })
+		for _, s := range shares {
+			if assigned >= remaining {
+				break
+			}
+			if quota[s.key] < len(groups[s.key]) {
+				quota[s.key]++
+				assigned++
+			}
+		}
+		remaining -= assigned
+	}
+	return quota
+}
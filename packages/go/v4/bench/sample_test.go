@@ -0,0 +1,128 @@
+package bench_test
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/kreuzberg-dev/kreuzberg/packages/go/v4/bench"
+)
+
+func writeDOCX(t *testing.T, path string, pages int) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	w, _ := zw.Create("docProps/app.xml")
+	fmt.Fprintf(w, `<?xml version="1.0"?><Properties xmlns="http://schemas.openxmlformats.org/officeDocument/2006/extended-properties"><Pages>%d</Pages></Properties>`, pages)
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip: %v", err)
+	}
+}
+
+func TestProbeFixture(t *testing.T) {
+	dir := t.TempDir()
+	// The third page object straddles the end of the scanner's first read (1 MiB + 32 bytes).
+	pdf := "%PDF-1.4\n1 0 obj << /Type /Pages /Count 3 >> endobj\n2 0 obj << /Type /Page >> endobj\n3 0 obj <</Type/Page/Parent 1 0 R>> endobj\n"
+	pdf += strings.Repeat(" ", 1<<20+32-len(pdf)-8) + "<< /Type /Page >>\n" + strings.Repeat("%", 2<<20) + "\n%%EOF\n"
+	writeFile(t, filepath.Join(dir, "doc.pdf"), pdf)
+	writeDOCX(t, filepath.Join(dir, "doc.docx"), 7)
+	writeFile(t, filepath.Join(dir, "en.txt"), "The report covers the results of the survey and the plans for next year.")
+	writeFile(t, filepath.Join(dir, "de.md"), "# Bericht\n\nDer Bericht ist nicht fertig, und die Zahlen sind mit Vorsicht zu lesen.")
+	writeFile(t, filepath.Join(dir, "ru.txt"), "Отчёт о результатах опроса.")
+	writeFile(t, filepath.Join(dir, "ja.txt"), "これは日本語の文書です。")
+	writeFile(t, filepath.Join(dir, "zh.txt"), "这是中文文件。")
+
+	for _, tc := range []struct {
+		fixture bench.Fixture
+		want    bench.Profile
+	}{
+		{bench.Fixture{Document: "doc.pdf"}, bench.Profile{Format: "pdf", Size: int64(len(pdf)), Pages: 3}},
+		{bench.Fixture{Document: "doc.docx", FileType: "word"}, bench.Profile{Format: "word", Pages: 7}},
+		{bench.Fixture{Document: "en.txt"}, bench.Profile{Format: "txt", Language: "en"}},
+		{bench.Fixture{Document: "de.md"}, bench.Profile{Format: "md", Language: "de"}},
+		{bench.Fixture{Document: "ru.txt"}, bench.Profile{Format: "txt", Language: "cyrillic"}},
+		{bench.Fixture{Document: "ja.txt"}, bench.Profile{Format: "txt", Language: "ja"}},
+		{bench.Fixture{Document: "zh.txt"}, bench.Profile{Format: "txt", Language: "zh"}},
+		{bench.Fixture{Document: "en.txt", FileSize: 5, Metadata: map[string]any{"pages": 2.0, "language": "fr"}}, bench.Profile{Format: "txt", Size: 5, Pages: 2, Language: "fr"}},
+	} {
+		tc.fixture.Document = filepath.Join(dir, tc.fixture.Document)
+		got, err := bench.ProbeFixture(tc.fixture)
+		if err != nil {
+			t.Fatalf("probe %s: %v", tc.fixture.Document, err)
+		}
+		if tc.want.Size == 0 {
+			tc.want.Size = got.Size
+		}
+		if got != tc.want {
+			t.Errorf("ProbeFixture(%s) = %+v, want %+v", filepath.Base(tc.fixture.Document), got, tc.want)
+		}
+	}
+}
+
+func TestSampleStratifies(t *testing.T) {
+	dir := t.TempDir()
+	var corpus []bench.Fixture
+	add := func(name, content string) {
+		path := filepath.Join(dir, name)
+		writeFile(t, path, content)
+		corpus = append(corpus, bench.Fixture{Name: name, Document: path})
+	}
+	for i := range 16 {
+		add(fmt.Sprintf("note%02d.txt", i), strings.Repeat("x", 10+i*100))
+	}
+	for i := range 3 {
+		add(fmt.Sprintf("scan%d.pdf", i), "%PDF-1.4\n<< /Type /Page >>")
+	}
+	add("memo.srt", "1\n00:00:01,000 --> 00:00:02,000\nhi\n")
+
+	opts := bench.SampleOptions{Size: 6, Seed: 42}
+	sample, err := bench.Sample(corpus, opts)
+	if err != nil {
+		t.Fatalf("sample: %v", err)
+	}
+	formats := map[string]int{}
+	for _, fixture := range sample {
+		formats[filepath.Ext(fixture.Name)]++
+	}
+	// One fixture per format first; the remaining three are shared in proportion to what is left
+	// (15 texts, 2 PDFs).
+	if len(sample) != 6 || formats[".txt"] != 4 || formats[".pdf"] != 1 || formats[".srt"] != 1 {
+		t.Fatalf("unexpected sample: %v", formats)
+	}
+	position := func(f bench.Fixture) int {
+		return slices.IndexFunc(corpus, func(c bench.Fixture) bool { return c.Name == f.Name })
+	}
+	if !slices.IsSortedFunc(sample, func(a, b bench.Fixture) int { return position(a) - position(b) }) {
+		t.Fatalf("expected corpus order, got %v", sample)
+	}
+	again, _ := bench.Sample(corpus, opts)
+	if !slices.EqualFunc(sample, again, func(a, b bench.Fixture) bool { return a.Name == b.Name }) {
+		t.Fatalf("expected the same seed to give the same sample")
+	}
+
+	bySize, err := bench.Sample(corpus, bench.SampleOptions{Size: 3, Strata: []bench.Stratifier{bench.ByFormat, bench.BySize(1000)}, Seed: 1})
+	if err != nil {
+		t.Fatalf("sample: %v", err)
+	}
+	// Four strata (small text, large text, PDF, SRT) exceed the size of three.
+	if len(bySize) != 4 {
+		t.Fatalf("expected one fixture per stratum, got %d", len(bySize))
+	}
+	if all, _ := bench.Sample(corpus, bench.SampleOptions{Size: 100}); len(all) != len(corpus) {
+		t.Fatalf("expected the whole corpus, got %d fixtures", len(all))
+	}
+	if _, err := bench.Sample(corpus, bench.SampleOptions{}); err == nil {
+		t.Fatalf("expected an error for a zero size")
+	}
+	if got := bench.ByPages(2, 10)(bench.Profile{Pages: 12}); got != "pages>=10" {
+		t.Fatalf("ByPages = %q", got)
+	}
+}
@@ -67,11 +67,14 @@ const char *kreuzberg_error_code_description(uint32_t code);
 import "C"
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"unsafe"
 )
 
@@ -83,8 +86,66 @@ type BytesWithMime struct {
 
 // ExtractFileSync extracts content and metadata from the file at the provided path.
 func ExtractFileSync(path string, config *ExtractionConfig) (*ExtractionResult, error) {
-	cPath := C.CString(path)
-	defer C.free(unsafe.Pointer(cPath))
+	return extractFile(context.Background(), defaultPluginRegistry, path, config)
+}
+
+func extractFile(ctx context.Context, plugins *pluginRegistry, path string, config *ExtractionConfig) (*ExtractionResult, error) {
+	ctx, done := plugins.events.startExtraction(ctx, []eventDocument{{path: path}}, false, config)
+	result, err := extractFilePipeline(ctx, plugins, path, config)
+	done([]*ExtractionResult{result}, err)
+	return result, err
+}
+
+func extractFilePipeline(ctx context.Context, plugins *pluginRegistry, path string, config *ExtractionConfig) (*ExtractionResult, error) {
+	src := plugins.mime.source(path)
+	if err := plugins.formats.check(src); err != nil {
+		return nil, err
+	}
+	if key, ok := IdempotencyKeyFromContext(ctx); ok {
+		return extractIdempotent(ctx, key, src, config, func(ctx context.Context) (*ExtractionResult, error) {
+			return extractFilePipeline(ctx, plugins, path, config)
+		})
+	}
+	if dualRunSampled(config) {
+		return extractDual(ctx, config, path, "", func(ctx context.Context, cfg *ExtractionConfig) (*ExtractionResult, error) {
+			return extractFilePipeline(ctx, plugins, path, cfg)
+		})
+	}
+	if routingEnabled(config) {
+		return extractRouted(src, config, func(routed *ExtractionConfig) (*ExtractionResult, error) {
+			return extractFilePipeline(ctx, plugins, path, routed)
+		})
+	}
+	if degradationEnabled(config) {
+		return extractDegraded(config, func(cfg *ExtractionConfig) (*ExtractionResult, error) {
+			return extractFilePipeline(ctx, plugins, path, cfg)
+		})
+	}
+	result, err := extractPrimary(src, config)
+	if err != nil {
+		result, err = unlockDocument(ctx, src, config, err)
+	}
+	if err != nil {
+		result, err = runFallbackChain(src, config, err)
+		if err != nil {
+			return nil, triageError(src, config, err)
+		}
+	}
+	if err := finishResult(plugins, newPluginContext(ctx, path, nil, result.MimeType, config), result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func extractFileNative(path string, config *ExtractionConfig) (*ExtractionResult, error) {
+	defer pinNativeStack()()
+	cPath := newCString(path)
+	defer freeCBuffer(unsafe.Pointer(cPath))
+	if statisticsOnly(config) {
+		return extractStatisticsNative(config, func(cfg *C.char) *C.char {
+			return C.kreuzberg_extract_statistics_file(cPath, cfg)
+		})
+	}
 
 	cfgPtr, cfgCleanup, err := newConfigJSON(config)
 	if err != nil {
@@ -96,33 +157,94 @@ func ExtractFileSync(path string, config *ExtractionConfig) (*ExtractionResult,
 
 	var cRes *C.CExtractionResult
 	if cfgPtr != nil {
-		cRes = C.kreuzberg_extract_file_sync_with_config(cPath, cfgPtr)
+		cRes = trackFFIAlloc(FFIResourceResult, C.kreuzberg_extract_file_sync_with_config(cPath, cfgPtr))
 	} else {
-		cRes = C.kreuzberg_extract_file_sync(cPath)
+		cRes = trackFFIAlloc(FFIResourceResult, C.kreuzberg_extract_file_sync(cPath))
 	}
 
 	if cRes == nil {
 		return nil, lastError()
 	}
-	defer C.kreuzberg_free_result(cRes)
+	defer freeNativeResult(cRes)
 
 	return convertCResult(cRes)
 }
 
 // ExtractBytesSync extracts content and metadata from a byte array with the given MIME type.
 func ExtractBytesSync(data []byte, mimeType string, config *ExtractionConfig) (*ExtractionResult, error) {
+	return extractBytes(context.Background(), defaultPluginRegistry, data, mimeType, config)
+}
+
+func extractBytes(ctx context.Context, plugins *pluginRegistry, data []byte, mimeType string, config *ExtractionConfig) (*ExtractionResult, error) {
+	ctx, done := plugins.events.startExtraction(ctx, []eventDocument{{mimeType: mimeType}}, false, config)
+	result, err := extractBytesPipeline(ctx, plugins, data, mimeType, config)
+	done([]*ExtractionResult{result}, err)
+	return result, err
+}
+
+func extractBytesPipeline(ctx context.Context, plugins *pluginRegistry, data []byte, mimeType string, config *ExtractionConfig) (*ExtractionResult, error) {
+	mimeType = plugins.mime.resolveBytes(data, mimeType)
+	src := documentSource{data: data, mimeType: mimeType}
+	if err := plugins.formats.check(src); err != nil {
+		return nil, err
+	}
+	if key, ok := IdempotencyKeyFromContext(ctx); ok {
+		return extractIdempotent(ctx, key, src, config, func(ctx context.Context) (*ExtractionResult, error) {
+			return extractBytesPipeline(ctx, plugins, data, mimeType, config)
+		})
+	}
+	if dualRunSampled(config) {
+		if config.DualRun.Async {
+			// The shadow extraction may outlive the call, and with it the caller's buffer.
+			data = bytes.Clone(data)
+		}
+		return extractDual(ctx, config, "", mimeType, func(ctx context.Context, cfg *ExtractionConfig) (*ExtractionResult, error) {
+			return extractBytesPipeline(ctx, plugins, data, mimeType, cfg)
+		})
+	}
+	if routingEnabled(config) {
+		return extractRouted(src, config, func(routed *ExtractionConfig) (*ExtractionResult, error) {
+			return extractBytesPipeline(ctx, plugins, data, mimeType, routed)
+		})
+	}
+	if degradationEnabled(config) {
+		return extractDegraded(config, func(cfg *ExtractionConfig) (*ExtractionResult, error) {
+			return extractBytesPipeline(ctx, plugins, data, mimeType, cfg)
+		})
+	}
+	result, err := extractPrimary(src, config)
+	if err != nil {
+		result, err = unlockDocument(ctx, src, config, err)
+	}
+	if err != nil {
+		result, err = runFallbackChain(src, config, err)
+		if err != nil {
+			return nil, triageError(src, config, err)
+		}
+	}
+	if err := finishResult(plugins, newPluginContext(ctx, "", data, mimeType, config), result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func extractBytesNative(data []byte, mimeType string, config *ExtractionConfig) (*ExtractionResult, error) {
+	defer pinNativeStack()()
 	if len(data) == 0 {
 		return nil, newValidationErrorWithContext("data cannot be empty", nil, ErrorCodeValidation, nil)
 	}
 	if mimeType == "" {
 		return nil, newValidationErrorWithContext("mimeType is required", nil, ErrorCodeValidation, nil)
 	}
+	if statisticsOnly(config) {
+		return extractStatisticsBytesNative(data, mimeType, config)
+	}
 
-	buf := C.CBytes(data)
-	defer C.free(buf)
+	buf := newCBytes(data)
+	defer freeCBuffer(buf)
 
-	cMime := C.CString(mimeType)
-	defer C.free(unsafe.Pointer(cMime))
+	cMime := newCString(mimeType)
+	defer freeCBuffer(unsafe.Pointer(cMime))
 
 	cfgPtr, cfgCleanup, err := newConfigJSON(config)
 	if err != nil {
@@ -134,37 +256,110 @@ func ExtractBytesSync(data []byte, mimeType string, config *ExtractionConfig) (*
 
 	var cRes *C.CExtractionResult
 	if cfgPtr != nil {
-		cRes = C.kreuzberg_extract_bytes_sync_with_config((*C.uint8_t)(buf), C.uintptr_t(len(data)), cMime, cfgPtr)
+		cRes = trackFFIAlloc(FFIResourceResult, C.kreuzberg_extract_bytes_sync_with_config((*C.uint8_t)(buf), C.uintptr_t(len(data)), cMime, cfgPtr))
 	} else {
-		cRes = C.kreuzberg_extract_bytes_sync((*C.uint8_t)(buf), C.uintptr_t(len(data)), cMime)
+		cRes = trackFFIAlloc(FFIResourceResult, C.kreuzberg_extract_bytes_sync((*C.uint8_t)(buf), C.uintptr_t(len(data)), cMime))
 	}
 
 	if cRes == nil {
 		return nil, lastError()
 	}
-	defer C.kreuzberg_free_result(cRes)
+	defer freeNativeResult(cRes)
 
 	return convertCResult(cRes)
 }
 
 // BatchExtractFilesSync extracts multiple files sequentially but leverages the optimized batch pipeline.
 func BatchExtractFilesSync(paths []string, config *ExtractionConfig) ([]*ExtractionResult, error) {
+	return batchExtractFiles(context.Background(), defaultPluginRegistry, paths, config)
+}
+
+func batchExtractFiles(ctx context.Context, plugins *pluginRegistry, paths []string, config *ExtractionConfig) ([]*ExtractionResult, error) {
 	if len(paths) == 0 {
 		return []*ExtractionResult{}, nil
 	}
+	documents := make([]eventDocument, len(paths))
+	for i, path := range paths {
+		documents[i].path = path
+	}
+	ctx, done := plugins.events.startExtraction(ctx, documents, true, config)
+	results, err := batchExtractFilesPipeline(ctx, plugins, paths, config)
+	done(results, err)
+	return results, err
+}
 
-	cStrings := make([]*C.char, len(paths))
+func batchExtractFilesPipeline(ctx context.Context, plugins *pluginRegistry, paths []string, config *ExtractionConfig) ([]*ExtractionResult, error) {
+	if len(paths) == 0 {
+		return []*ExtractionResult{}, nil
+	}
+
+	sources := make([]documentSource, len(paths))
 	for i, path := range paths {
 		if path == "" {
 			return nil, newValidationErrorWithContext(fmt.Sprintf("path at index %d is empty", i), nil, ErrorCodeValidation, nil)
 		}
-		cStrings[i] = C.CString(path)
+		sources[i] = plugins.mime.source(path)
+		sources[i].denied = plugins.formats.check(sources[i])
+	}
+	if routingEnabled(config) {
+		return batchExtractRouted(sources, config, func(indices []int, routed *ExtractionConfig) ([]*ExtractionResult, error) {
+			subset := make([]string, len(indices))
+			for j, i := range indices {
+				subset[j] = paths[i]
+			}
+			return batchExtractFilesPipeline(withEventSubset(ctx, indices), plugins, subset, routed)
+		})
+	}
+	if degradationEnabled(config) {
+		return batchExtractDegraded(config, func(cfg *ExtractionConfig) ([]*ExtractionResult, error) {
+			return batchExtractFilesPipeline(ctx, plugins, paths, cfg)
+		})
+	}
+	results, err := batchExtractPrimary(sources, config, func(indices []int) ([]*ExtractionResult, error) {
+		subset := make([]string, len(indices))
+		for j, i := range indices {
+			subset[j] = paths[i]
+		}
+		return batchExtractFilesNative(subset, config)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, result := range results {
+		if itemErr := batchItemError(result); itemErr != nil && sources[i].denied == nil {
+			recovered, err := unlockDocument(ctx, sources[i], config, itemErr)
+			if err != nil {
+				recovered, err = runFallbackChain(sources[i], config, err)
+			}
+			if err == nil {
+				results[i], result = recovered, recovered
+			}
+		}
+		if result == nil {
+			continue
+		}
+		pc := newPluginContext(withEventIndex(ctx, i), paths[i], nil, result.MimeType, config)
+		if err := finishResult(plugins, pc, result); err != nil {
+			results[i] = markBatchItemFailed(result, pc.MimeType, err)
+		}
+	}
+	return results, nil
+}
+
+func batchExtractFilesNative(paths []string, config *ExtractionConfig) ([]*ExtractionResult, error) {
+	defer pinNativeStack()()
+	cStrings := make([]*C.char, len(paths))
+	for i, path := range paths {
+		cStrings[i] = newCString(path)
 	}
 	defer func() {
 		for _, ptr := range cStrings {
-			C.free(unsafe.Pointer(ptr))
+			freeCBuffer(unsafe.Pointer(ptr))
 		}
 	}()
+	if statisticsOnly(config) {
+		return batchExtractStatisticsFilesNative((**C.char)(unsafe.Pointer(&cStrings[0])), len(paths), config)
+	}
 
 	cfgPtr, cfgCleanup, err := newConfigJSON(config)
 	if err != nil {
@@ -174,24 +369,40 @@ func BatchExtractFilesSync(paths []string, config *ExtractionConfig) ([]*Extract
 		defer cfgCleanup()
 	}
 
-	batch := C.kreuzberg_batch_extract_files_sync((**C.char)(unsafe.Pointer(&cStrings[0])), C.uintptr_t(len(paths)), cfgPtr)
+	batch := trackFFIAlloc(FFIResourceBatchResult, C.kreuzberg_batch_extract_files_sync((**C.char)(unsafe.Pointer(&cStrings[0])), C.uintptr_t(len(paths)), cfgPtr))
 	if batch == nil {
 		return nil, lastError()
 	}
-	defer C.kreuzberg_free_batch_result(batch)
+	defer freeNativeBatchResult(batch)
 
 	return convertCBatchResult(batch)
 }
 
 // BatchExtractBytesSync processes multiple in-memory documents in one pass.
 func BatchExtractBytesSync(items []BytesWithMime, config *ExtractionConfig) ([]*ExtractionResult, error) {
+	return batchExtractBytes(context.Background(), defaultPluginRegistry, items, config)
+}
+
+func batchExtractBytes(ctx context.Context, plugins *pluginRegistry, items []BytesWithMime, config *ExtractionConfig) ([]*ExtractionResult, error) {
 	if len(items) == 0 {
 		return []*ExtractionResult{}, nil
 	}
+	documents := make([]eventDocument, len(items))
+	for i, item := range items {
+		documents[i].mimeType = item.MimeType
+	}
+	ctx, done := plugins.events.startExtraction(ctx, documents, true, config)
+	results, err := batchExtractBytesPipeline(ctx, plugins, items, config)
+	done(results, err)
+	return results, err
+}
 
-	cItems := make([]C.CBytesWithMime, len(items))
-	cBuffers := make([]unsafe.Pointer, len(items))
+func batchExtractBytesPipeline(ctx context.Context, plugins *pluginRegistry, items []BytesWithMime, config *ExtractionConfig) ([]*ExtractionResult, error) {
+	if len(items) == 0 {
+		return []*ExtractionResult{}, nil
+	}
 
+	sources := make([]documentSource, len(items))
 	for i, item := range items {
 		if len(item.Data) == 0 {
 			return nil, newValidationErrorWithContext(fmt.Sprintf("data at index %d is empty", i), nil, ErrorCodeValidation, nil)
@@ -199,9 +410,72 @@ func BatchExtractBytesSync(items []BytesWithMime, config *ExtractionConfig) ([]*
 		if item.MimeType == "" {
 			return nil, newValidationErrorWithContext(fmt.Sprintf("mimeType at index %d is empty", i), nil, ErrorCodeValidation, nil)
 		}
-		buf := C.CBytes(item.Data)
+	}
+	if !plugins.mime.empty() {
+		items = slices.Clone(items)
+	}
+	for i := range items {
+		items[i].MimeType = plugins.mime.resolveBytes(items[i].Data, items[i].MimeType)
+		sources[i] = documentSource{data: items[i].Data, mimeType: items[i].MimeType}
+		sources[i].denied = plugins.formats.check(sources[i])
+	}
+	if routingEnabled(config) {
+		return batchExtractRouted(sources, config, func(indices []int, routed *ExtractionConfig) ([]*ExtractionResult, error) {
+			subset := make([]BytesWithMime, len(indices))
+			for j, i := range indices {
+				subset[j] = items[i]
+			}
+			return batchExtractBytesPipeline(withEventSubset(ctx, indices), plugins, subset, routed)
+		})
+	}
+	if degradationEnabled(config) {
+		return batchExtractDegraded(config, func(cfg *ExtractionConfig) ([]*ExtractionResult, error) {
+			return batchExtractBytesPipeline(ctx, plugins, items, cfg)
+		})
+	}
+	results, err := batchExtractPrimary(sources, config, func(indices []int) ([]*ExtractionResult, error) {
+		subset := make([]BytesWithMime, len(indices))
+		for j, i := range indices {
+			subset[j] = items[i]
+		}
+		return batchExtractBytesNative(subset, config)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, result := range results {
+		if itemErr := batchItemError(result); itemErr != nil && sources[i].denied == nil {
+			recovered, err := unlockDocument(ctx, sources[i], config, itemErr)
+			if err != nil {
+				recovered, err = runFallbackChain(sources[i], config, err)
+			}
+			if err == nil {
+				results[i], result = recovered, recovered
+			}
+		}
+		if result == nil {
+			continue
+		}
+		pc := newPluginContext(withEventIndex(ctx, i), "", items[i].Data, items[i].MimeType, config)
+		if err := finishResult(plugins, pc, result); err != nil {
+			results[i] = markBatchItemFailed(result, pc.MimeType, err)
+		}
+	}
+	return results, nil
+}
+
+func batchExtractBytesNative(items []BytesWithMime, config *ExtractionConfig) ([]*ExtractionResult, error) {
+	defer pinNativeStack()()
+	if statisticsOnly(config) {
+		return batchExtractStatisticsBytesNative(items, config)
+	}
+	cItems := make([]C.CBytesWithMime, len(items))
+	cBuffers := make([]unsafe.Pointer, len(items))
+
+	for i, item := range items {
+		buf := newCBytes(item.Data)
 		cBuffers[i] = buf
-		mime := C.CString(item.MimeType)
+		mime := newCString(item.MimeType)
 
 		cItems[i] = C.CBytesWithMime{
 			data:      (*C.uint8_t)(buf),
@@ -212,11 +486,11 @@ func BatchExtractBytesSync(items []BytesWithMime, config *ExtractionConfig) ([]*
 	defer func() {
 		for i := range cItems {
 			if cItems[i].mime_type != nil {
-				C.free(unsafe.Pointer(cItems[i].mime_type))
+				freeCBuffer(unsafe.Pointer(cItems[i].mime_type))
 			}
 		}
 		for _, buf := range cBuffers {
-			C.free(buf)
+			freeCBuffer(buf)
 		}
 	}()
 
@@ -228,11 +502,11 @@ func BatchExtractBytesSync(items []BytesWithMime, config *ExtractionConfig) ([]*
 		defer cfgCleanup()
 	}
 
-	batch := C.kreuzberg_batch_extract_bytes_sync((*C.CBytesWithMime)(unsafe.Pointer(&cItems[0])), C.uintptr_t(len(items)), cfgPtr)
+	batch := trackFFIAlloc(FFIResourceBatchResult, C.kreuzberg_batch_extract_bytes_sync((*C.CBytesWithMime)(unsafe.Pointer(&cItems[0])), C.uintptr_t(len(items)), cfgPtr))
 	if batch == nil {
 		return nil, lastError()
 	}
-	defer C.kreuzberg_free_batch_result(batch)
+	defer freeNativeBatchResult(batch)
 
 	return convertCBatchResult(batch)
 }
@@ -240,41 +514,45 @@ func BatchExtractBytesSync(items []BytesWithMime, config *ExtractionConfig) ([]*
 // ExtractFileWithContext extracts content and metadata from a file at the given path,
 // respecting the provided context for cancellation. Note that extraction operations
 // cannot be interrupted mid-way; this cancellation check occurs before starting extraction.
+// An idempotency key carried by ctx deduplicates the extraction (see WithIdempotencyKey).
 func ExtractFileWithContext(ctx context.Context, path string, config *ExtractionConfig) (*ExtractionResult, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
-	return ExtractFileSync(path, config)
+	return extractFile(ctx, defaultPluginRegistry, path, config)
 }
 
 // ExtractBytesWithContext extracts content and metadata from a byte array,
 // respecting the provided context for cancellation. Note that extraction operations
 // cannot be interrupted mid-way; this cancellation check occurs before starting extraction.
+// An idempotency key carried by ctx deduplicates the extraction (see WithIdempotencyKey).
 func ExtractBytesWithContext(ctx context.Context, data []byte, mimeType string, config *ExtractionConfig) (*ExtractionResult, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
-	return ExtractBytesSync(data, mimeType, config)
+	return extractBytes(ctx, defaultPluginRegistry, data, mimeType, config)
 }
 
 // BatchExtractFilesWithContext extracts multiple files respecting the provided context
 // for cancellation. Note that extraction operations cannot be interrupted mid-way;
-// this cancellation check occurs before starting the batch operation.
+// this cancellation check occurs before starting the batch operation. ctx is passed on to
+// Go plugins (see PluginContext.Context).
 func BatchExtractFilesWithContext(ctx context.Context, paths []string, config *ExtractionConfig) ([]*ExtractionResult, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
-	return BatchExtractFilesSync(paths, config)
+	return batchExtractFiles(ctx, defaultPluginRegistry, paths, config)
 }
 
 // BatchExtractBytesWithContext processes multiple in-memory documents respecting the
 // provided context for cancellation. Note that extraction operations cannot be
 // interrupted mid-way; this cancellation check occurs before starting the batch operation.
+// ctx is passed on to Go plugins (see PluginContext.Context).
 func BatchExtractBytesWithContext(ctx context.Context, items []BytesWithMime, config *ExtractionConfig) ([]*ExtractionResult, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
-	return BatchExtractBytesSync(items, config)
+	return batchExtractBytes(ctx, defaultPluginRegistry, items, config)
 }
 
 // LibraryVersion returns the underlying Rust crate version string.
@@ -291,11 +569,11 @@ func LastErrorCode() ErrorCode {
 // LastPanicContext returns the panic context from the last FFI call if it was a panic.
 // Returns nil if the last error was not a panic or if no panic context is available.
 func LastPanicContext() *PanicContext {
-	panicPtr := C.kreuzberg_last_panic_context()
+	panicPtr := trackFFIAlloc(FFIResourceString, C.kreuzberg_last_panic_context())
 	if panicPtr == nil {
 		return nil
 	}
-	defer C.kreuzberg_free_string(panicPtr)
+	defer freeNativeString(panicPtr)
 
 	panicJSON := C.GoString(panicPtr)
 	if panicJSON == "" {
@@ -377,6 +655,112 @@ func convertCBatchResult(cBatch *C.CBatchResult) ([]*ExtractionResult, error) {
 	return results, nil
 }
 
+// finishResult records the XMP packet, custom document properties and Office statistics,
+// resolves metadata provenance, derives language hints, computes text statistics, generates
+// question/answer pairs and applies the Go-side result transforms selected in pc.Config, then
+// the Go plugins, then the content limit.
+func finishResult(plugins *pluginRegistry, pc *PluginContext, result *ExtractionResult) error {
+	if statisticsOnly(pc.Config) {
+		if err := reduceToStatistics(result); err != nil {
+			return err
+		}
+		return inspectReturned(pc, result)
+	}
+	if cfg := pc.Config; cfg != nil {
+		xmp := cfg.XMP != nil && *cfg.XMP
+		customProperties := cfg.OfficeCustomProperties != nil && *cfg.OfficeCustomProperties
+		officeStats := cfg.OfficeStats != nil && *cfg.OfficeStats
+		accessibility := cfg.Accessibility != nil && *cfg.Accessibility
+		var data []byte
+		if src := (documentSource{path: pc.DocumentPath, data: pc.data}); src.path != "" || src.data != nil {
+			if xmp || customProperties || officeStats || accessibility || cfg.MetadataProvenance != nil || cfg.HiddenText != nil && result.MimeType == "application/pdf" ||
+				cfg.Sanitize != nil && isHTMLResult(result) {
+				var err error
+				if data, err = src.bytes(); err != nil {
+					return err
+				}
+			}
+		}
+		if xmp {
+			if err := annotateXMP(result, data); err != nil {
+				return err
+			}
+		}
+		if customProperties {
+			if err := annotateCustomProperties(result, data); err != nil {
+				return err
+			}
+		}
+		if officeStats {
+			if err := annotateOfficeStats(result, data); err != nil {
+				return err
+			}
+		}
+		if accessibility {
+			if err := annotateAccessibility(result, data); err != nil {
+				return err
+			}
+		}
+		if cfg.MetadataProvenance != nil {
+			if err := annotateMetadataProvenance(result, data, cfg.MetadataProvenance); err != nil {
+				return err
+			}
+		}
+		if cfg.HiddenText != nil {
+			if err := annotateHiddenText(result, data, cfg.HiddenText); err != nil {
+				return err
+			}
+		}
+		if cfg.LanguageHints != nil && *cfg.LanguageHints {
+			if err := annotateLanguageHints(result); err != nil {
+				return err
+			}
+		}
+		if cfg.Sanitize != nil {
+			if err := sanitizeResult(result, data, cfg.Sanitize); err != nil {
+				return err
+			}
+		}
+		if cfg.TextStatistics != nil {
+			if err := annotateTextStatistics(result, cfg.TextStatistics); err != nil {
+				return err
+			}
+		}
+		if cfg.QA != nil {
+			if err := GenerateQAPairs(pc.Context(), result, cfg.QA); err != nil {
+				return err
+			}
+		}
+		canonical := cfg.CanonicalMarkdown != nil && *cfg.CanonicalMarkdown
+		switch {
+		case cfg.SourceAnchors != nil && *cfg.SourceAnchors:
+			annotateResultSourceSpans(result, canonical)
+		case canonical:
+			canonicalizeResultMarkdown(result)
+		}
+	}
+	if err := plugins.apply(pc, result); err != nil {
+		return err
+	}
+	if err := applyContentLimit(pc.Config, result); err != nil {
+		return err
+	}
+	return inspectReturned(pc, result)
+}
+
+// markBatchItemFailed records a Go-side failure on a batch item the same way the native
+// batch pipeline reports per-document errors, instead of failing the whole batch. A result left
+// to a timed-out plugin is replaced by an empty one, since the plugin may still be modifying it,
+// and so is a result withheld by a DataFlowInspector.
+func markBatchItemFailed(result *ExtractionResult, mimeType string, err error) *ExtractionResult {
+	if errors.Is(err, errPluginAbandoned) || errors.Is(err, ErrDataFlowBlocked) {
+		result = &ExtractionResult{MimeType: mimeType}
+	}
+	result.Success = false
+	result.Metadata.Error = &ErrorMetadata{ErrorType: "PluginError", Message: err.Error()}
+	return result
+}
+
 func decodeJSONCString[T any](ptr *C.char, target *T) error {
 	if ptr == nil {
 		return nil
@@ -392,16 +776,19 @@ func newConfigJSON(config *ExtractionConfig) (*C.char, func(), error) {
 	if config == nil {
 		return nil, nil, nil
 	}
-	data, err := json.Marshal(config)
-	if err != nil {
-		return nil, nil, newSerializationErrorWithContext("failed to encode config", err, ErrorCodeValidation, nil)
+	data, ok := compiledNativeJSON(config)
+	if !ok {
+		var err error
+		if data, err = nativeConfigJSON(config); err != nil {
+			return nil, nil, err
+		}
 	}
 	if len(data) == 0 {
 		return nil, nil, nil
 	}
-	cStr := C.CString(string(data))
+	cStr := newCString(string(data))
 	cleanup := func() {
-		C.free(unsafe.Pointer(cStr))
+		freeCBuffer(unsafe.Pointer(cStr))
 	}
 	return cStr, cleanup, nil
 }
@@ -417,9 +804,9 @@ func lastError() error {
 
 	// Check for panic context regardless of error code
 	var panicCtx *PanicContext
-	panicPtr := C.kreuzberg_last_panic_context()
+	panicPtr := trackFFIAlloc(FFIResourceString, C.kreuzberg_last_panic_context())
 	if panicPtr != nil {
-		defer C.kreuzberg_free_string(panicPtr)
+		defer freeNativeString(panicPtr)
 		panicJSON := C.GoString(panicPtr)
 		if panicJSON != "" {
 			var ctx PanicContext
@@ -446,14 +833,14 @@ func LoadExtractionConfigFromFile(path string) (*ExtractionConfig, error) {
 		return nil, newValidationErrorWithContext("config path cannot be empty", nil, ErrorCodeValidation, nil)
 	}
 
-	cPath := C.CString(path)
-	defer C.free(unsafe.Pointer(cPath))
+	cPath := newCString(path)
+	defer freeCBuffer(unsafe.Pointer(cPath))
 
-	ptr := C.kreuzberg_load_extraction_config_from_file(cPath)
+	ptr := trackFFIAlloc(FFIResourceString, C.kreuzberg_load_extraction_config_from_file(cPath))
 	if ptr == nil {
 		return nil, lastError()
 	}
-	defer C.kreuzberg_free_string(ptr)
+	defer freeNativeString(ptr)
 
 	raw := C.GoString(ptr)
 	cfg := &ExtractionConfig{}
@@ -498,38 +885,49 @@ func ConfigDiscover() (*ExtractionConfig, error) {
 	return nil, nil
 }
 
-// DetectMimeType detects MIME type from byte content using magic bytes.
+// DetectMimeType detects MIME type from byte content using magic bytes, applying the overrides
+// set with SetMimeTypeOverride.
 func DetectMimeType(data []byte) (string, error) {
+	return defaultPluginRegistry.mime.detectBytes(data)
+}
+
+func detectMimeTypeNative(data []byte) (string, error) {
 	if len(data) == 0 {
 		return "", newValidationErrorWithContext("data cannot be empty", nil, ErrorCodeValidation, nil)
 	}
 
-	buf := C.CBytes(data)
-	defer C.free(buf)
+	buf := newCBytes(data)
+	defer freeCBuffer(buf)
 
-	ptr := C.kreuzberg_detect_mime_type_from_bytes((*C.uint8_t)(buf), C.uintptr_t(len(data)))
+	ptr := trackFFIAlloc(FFIResourceString, C.kreuzberg_detect_mime_type_from_bytes((*C.uint8_t)(buf), C.uintptr_t(len(data))))
 	if ptr == nil {
 		return "", lastError()
 	}
-	defer C.kreuzberg_free_string(ptr)
+	defer freeNativeString(ptr)
 
 	return C.GoString(ptr), nil
 }
 
-// DetectMimeTypeFromPath detects MIME type from a file path (checks extension and content).
+// DetectMimeTypeFromPath detects MIME type from a file path (checks extension and content),
+// applying the mappings set with SetExtensionMimeType and SetMimeTypeOverride.
 func DetectMimeTypeFromPath(path string) (string, error) {
+	mimeType, _, err := defaultPluginRegistry.mime.detectPath(path)
+	return mimeType, err
+}
+
+func detectMimeTypeFromPathNative(path string) (string, error) {
 	if path == "" {
 		return "", newValidationErrorWithContext("path cannot be empty", nil, ErrorCodeValidation, nil)
 	}
 
-	cPath := C.CString(path)
-	defer C.free(unsafe.Pointer(cPath))
+	cPath := newCString(path)
+	defer freeCBuffer(unsafe.Pointer(cPath))
 
-	ptr := C.kreuzberg_detect_mime_type_from_path(cPath)
+	ptr := trackFFIAlloc(FFIResourceString, C.kreuzberg_detect_mime_type_from_path(cPath))
 	if ptr == nil {
 		return "", lastError()
 	}
-	defer C.kreuzberg_free_string(ptr)
+	defer freeNativeString(ptr)
 
 	return C.GoString(ptr), nil
 }
@@ -540,14 +938,14 @@ func GetExtensionsForMime(mimeType string) ([]string, error) {
 		return nil, newValidationErrorWithContext("mimeType cannot be empty", nil, ErrorCodeValidation, nil)
 	}
 
-	cMime := C.CString(mimeType)
-	defer C.free(unsafe.Pointer(cMime))
+	cMime := newCString(mimeType)
+	defer freeCBuffer(unsafe.Pointer(cMime))
 
-	ptr := C.kreuzberg_get_extensions_for_mime(cMime)
+	ptr := trackFFIAlloc(FFIResourceString, C.kreuzberg_get_extensions_for_mime(cMime))
 	if ptr == nil {
 		return nil, lastError()
 	}
-	defer C.kreuzberg_free_string(ptr)
+	defer freeNativeString(ptr)
 
 	jsonStr := C.GoString(ptr)
 	var extensions []string
@@ -563,14 +961,14 @@ func ValidateMimeType(mimeType string) (string, error) {
 		return "", newValidationErrorWithContext("mimeType cannot be empty", nil, ErrorCodeValidation, nil)
 	}
 
-	cMime := C.CString(mimeType)
-	defer C.free(unsafe.Pointer(cMime))
+	cMime := newCString(mimeType)
+	defer freeCBuffer(unsafe.Pointer(cMime))
 
-	ptr := C.kreuzberg_validate_mime_type(cMime)
+	ptr := trackFFIAlloc(FFIResourceString, C.kreuzberg_validate_mime_type(cMime))
 	if ptr == nil {
 		return "", lastError()
 	}
-	defer C.kreuzberg_free_string(ptr)
+	defer freeNativeString(ptr)
 
 	return C.GoString(ptr), nil
 }
@@ -587,11 +985,11 @@ type EmbeddingPreset struct {
 
 // ListEmbeddingPresets returns available embedding preset names.
 func ListEmbeddingPresets() ([]string, error) {
-	ptr := C.kreuzberg_list_embedding_presets()
+	ptr := trackFFIAlloc(FFIResourceString, C.kreuzberg_list_embedding_presets())
 	if ptr == nil {
 		return nil, lastError()
 	}
-	defer C.kreuzberg_free_string(ptr)
+	defer freeNativeString(ptr)
 
 	raw := C.GoString(ptr)
 	if raw == "" {
@@ -610,14 +1008,14 @@ func GetEmbeddingPreset(name string) (*EmbeddingPreset, error) {
 		return nil, newValidationErrorWithContext("preset name cannot be empty", nil, ErrorCodeValidation, nil)
 	}
 
-	cName := C.CString(name)
-	defer C.free(unsafe.Pointer(cName))
+	cName := newCString(name)
+	defer freeCBuffer(unsafe.Pointer(cName))
 
-	ptr := C.kreuzberg_get_embedding_preset(cName)
+	ptr := trackFFIAlloc(FFIResourceString, C.kreuzberg_get_embedding_preset(cName))
 	if ptr == nil {
 		return nil, lastError()
 	}
-	defer C.kreuzberg_free_string(ptr)
+	defer freeNativeString(ptr)
 
 	var preset EmbeddingPreset
 	if err := json.Unmarshal([]byte(C.GoString(ptr)), &preset); err != nil {
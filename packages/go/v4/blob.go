@@ -0,0 +1,102 @@
+package kreuzberg
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// DocumentBlob is a document stored in a database BLOB column. It implements sql.Scanner and
+// driver.Valuer for the document bytes, so documents can be scanned straight out of rows and
+// extracted, e.g. when migrating a legacy database:
+//
+//	var doc kreuzberg.DocumentBlob
+//	err := db.QueryRowContext(ctx, "SELECT body, mime FROM attachments WHERE id = ?", id).
+//		Scan(&doc, &doc.MimeType)
+//	...
+//	result, err := doc.Extract(ctx, config)
+//
+// The MIME type lives in its own column, if any; without one it is detected from the content.
+type DocumentBlob struct {
+	Data     []byte
+	MimeType string
+}
+
+// Scan implements sql.Scanner. It copies the column value, since drivers may reuse the buffer
+// after the next call to Next. NULL scans as an empty document.
+func (b *DocumentBlob) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		b.Data = nil
+	case []byte:
+		b.Data = append([]byte(nil), v...)
+	case string:
+		b.Data = []byte(v)
+	default:
+		return newValidationErrorWithContext(fmt.Sprintf("cannot scan %T into a DocumentBlob", src), nil, ErrorCodeValidation, nil)
+	}
+	return nil
+}
+
+// Value implements driver.Valuer, storing the document bytes (NULL for a nil document).
+func (b DocumentBlob) Value() (driver.Value, error) {
+	if b.Data == nil {
+		return nil, nil
+	}
+	return b.Data, nil
+}
+
+// IsNull reports whether the column was NULL (or the document is otherwise unset).
+func (b DocumentBlob) IsNull() bool {
+	return b.Data == nil
+}
+
+// BytesWithMime returns the document as a batch item for BatchExtractBytesWithContext, detecting
+// its MIME type if unset.
+func (b DocumentBlob) BytesWithMime() (BytesWithMime, error) {
+	mimeType, err := b.mimeType()
+	return BytesWithMime{Data: b.Data, MimeType: mimeType}, err
+}
+
+// Extract extracts the document, detecting its MIME type if unset. A NULL document returns a
+// ValidationError.
+func (b DocumentBlob) Extract(ctx context.Context, config *ExtractionConfig) (*ExtractionResult, error) {
+	mimeType, err := b.mimeType()
+	if err != nil {
+		return nil, err
+	}
+	return ExtractBytesWithContext(ctx, b.Data, mimeType, config)
+}
+
+// ExtractBlob extracts a document scanned from a database using the client's config and plugins.
+func (c *Client) ExtractBlob(ctx context.Context, blob DocumentBlob) (*ExtractionResult, error) {
+	mimeType, err := blob.mimeType()
+	if err != nil {
+		return nil, err
+	}
+	return c.ExtractBytes(ctx, blob.Data, mimeType)
+}
+
+// mimeType returns the MIME type of the document: the stored one, else the natively detected
+// one, else one sniffed from the leading bytes.
+func (b DocumentBlob) mimeType() (string, error) {
+	if len(b.Data) == 0 {
+		return "", newValidationErrorWithContext("document blob is empty", nil, ErrorCodeValidation, nil)
+	}
+	if b.MimeType != "" {
+		return b.MimeType, nil
+	}
+	detected, err := DetectMimeType(b.Data)
+	if err == nil && detected != "" {
+		return detected, nil
+	}
+	if sniffed, _, _ := strings.Cut(http.DetectContentType(b.Data), ";"); sniffed != "application/octet-stream" {
+		return sniffed, nil
+	}
+	if err == nil {
+		err = newValidationErrorWithContext("cannot detect the MIME type of the document blob", nil, ErrorCodeValidation, nil)
+	}
+	return "", err
+}
@@ -0,0 +1,56 @@
+package kreuzberg
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDocumentBlobScanAndValue(t *testing.T) {
+	var blob DocumentBlob
+	column := []byte(testSRT)
+	if err := blob.Scan(column); err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	column[0] = 'X'
+	if string(blob.Data) != testSRT {
+		t.Fatalf("expected the scanned bytes to be copied")
+	}
+	if value, err := blob.Value(); err != nil || string(value.([]byte)) != testSRT {
+		t.Fatalf("unexpected value %v, %v", value, err)
+	}
+
+	if err := blob.Scan(nil); err != nil || !blob.IsNull() {
+		t.Fatalf("expected NULL to scan as an empty document, got %v", err)
+	}
+	if value, _ := blob.Value(); value != nil {
+		t.Fatalf("expected a NULL value, got %v", value)
+	}
+	if err := blob.Scan("text"); err != nil || string(blob.Data) != "text" {
+		t.Fatalf("expected strings to scan, got %v", err)
+	}
+	var validationErr *ValidationError
+	if err := blob.Scan(42); !errors.As(err, &validationErr) {
+		t.Fatalf("expected a ValidationError, got %v", err)
+	}
+}
+
+func TestDocumentBlobExtract(t *testing.T) {
+	blob := DocumentBlob{Data: []byte(testSRT), MimeType: mimeSRT}
+	result, err := blob.Extract(context.Background(), nil)
+	if err != nil || result.Content != wantSRTContent {
+		t.Fatalf("unexpected result %+v, %v", result, err)
+	}
+	if result, err := NewClient(nil).ExtractBlob(context.Background(), blob); err != nil || result.Content != wantSRTContent {
+		t.Fatalf("unexpected client result %+v, %v", result, err)
+	}
+
+	item, err := DocumentBlob{Data: []byte("<html><body>hi</body></html>")}.BytesWithMime()
+	if err != nil || item.MimeType != "text/html" {
+		t.Fatalf("expected the MIME type to be detected, got %q, %v", item.MimeType, err)
+	}
+	var validationErr *ValidationError
+	if _, err := (DocumentBlob{}).Extract(context.Background(), nil); !errors.As(err, &validationErr) {
+		t.Fatalf("expected a ValidationError for a NULL document, got %v", err)
+	}
+}
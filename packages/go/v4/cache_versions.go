@@ -0,0 +1,131 @@
+package kreuzberg
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// resultVersionsMagic separates version index IDs from cache keys.
+const resultVersionsMagic = "KZV1"
+
+// resultVersionsMu serializes version index updates within the process. Concurrent processes
+// sharing a cache directory may lose each other's index updates, never entries.
+var resultVersionsMu sync.Mutex
+
+// CacheVersion is one cached extraction of a document.
+type CacheVersion struct {
+	// LibraryVersion is the native library version that produced the result.
+	LibraryVersion string `json:"library_version"`
+	// ConfigHash identifies the extraction config (see ResultCache.ConfigHash).
+	ConfigHash string    `json:"config_hash"`
+	StoredAt   time.Time `json:"stored_at"`
+	// Key identifies the entry for ResultCache.Load.
+	Key string `json:"key"`
+}
+
+func (c *resultCache) indexPath(document string) string {
+	return filepath.Join(c.dir, "versions", document[:2], document+".kzv")
+}
+
+// versions returns the version index of a document, oldest first. The index is sealed like the
+// entries, so it reveals neither the document nor the library versions it was extracted with.
+func (c *resultCache) versions(document string) []CacheVersion {
+	plaintext := c.open(c.indexPath(document), resultVersionsMagic+document)
+	var versions []CacheVersion
+	if plaintext == nil || json.Unmarshal(plaintext, &versions) != nil {
+		return nil
+	}
+	return versions
+}
+
+// storeVersion stores result under key and records it in the document's version index,
+// replacing an older entry for the same library version and config and removing the oldest
+// versions beyond RetainVersions.
+func (c *resultCache) storeVersion(key, document string, result *ExtractionResult) error {
+	if err := c.store(key, result); err != nil {
+		return err
+	}
+	resultVersionsMu.Lock()
+	defer resultVersionsMu.Unlock()
+	versions := slices.DeleteFunc(c.versions(document), func(v CacheVersion) bool { return v.Key == key })
+	versions = append(versions, CacheVersion{LibraryVersion: LibraryVersion(), ConfigHash: c.configHash, StoredAt: time.Now().UTC(), Key: key})
+	if retain := c.cfg.RetainVersions; retain > 0 && len(versions) > retain {
+		for _, old := range versions[:len(versions)-retain] {
+			os.Remove(c.path(old.Key))
+		}
+		versions = versions[len(versions)-retain:]
+	}
+	plaintext, err := json.Marshal(versions)
+	if err != nil {
+		return newSerializationErrorWithContext("failed to encode cache version index", err, ErrorCodeValidation, nil)
+	}
+	return c.seal(c.indexPath(document), resultVersionsMagic+document, plaintext)
+}
+
+// ResultCache gives access to the encrypted result cache, including results stored under other
+// library versions and configs. After an upgrade, FileVersions and BytesVersions list what
+// earlier releases produced for a document and Load fetches it, e.g. to compare against a fresh
+// extraction before switching over.
+type ResultCache struct {
+	cache *resultCache
+}
+
+// OpenResultCache opens the encrypted result cache configured by config.CacheEncryption.
+func OpenResultCache(config *ExtractionConfig) (*ResultCache, error) {
+	cache, err := openResultCache(config)
+	if err != nil {
+		return nil, err
+	}
+	if cache == nil {
+		return nil, newValidationErrorWithContext("config does not enable the encrypted result cache", nil, ErrorCodeValidation, nil)
+	}
+	return &ResultCache{cache: cache}, nil
+}
+
+// ConfigHash identifies the cache's config independently of the library version, so versions
+// with the same ConfigHash differ only in the library that produced them.
+func (c *ResultCache) ConfigHash() string {
+	return c.cache.configHash
+}
+
+// FileVersions lists the cached versions of the file at path, oldest first.
+func (c *ResultCache) FileVersions(path string) ([]CacheVersion, error) {
+	return c.documentVersions(documentSource{path: path})
+}
+
+// BytesVersions lists the cached versions of an in-memory document, oldest first.
+func (c *ResultCache) BytesVersions(data []byte, mimeType string) ([]CacheVersion, error) {
+	return c.documentVersions(documentSource{data: data, mimeType: mimeType})
+}
+
+func (c *ResultCache) documentVersions(src documentSource) ([]CacheVersion, error) {
+	_, document, err := c.cache.keys(src)
+	if err != nil {
+		return nil, err
+	}
+	resultVersionsMu.Lock()
+	defer resultVersionsMu.Unlock()
+	versions := slices.DeleteFunc(c.cache.versions(document), func(v CacheVersion) bool {
+		_, err := os.Stat(c.cache.path(v.Key))
+		return err != nil
+	})
+	slices.SortStableFunc(versions, func(a, b CacheVersion) int { return a.StoredAt.Compare(b.StoredAt) })
+	return versions, nil
+}
+
+// Load returns the result stored for version.
+func (c *ResultCache) Load(version CacheVersion) (*ExtractionResult, error) {
+	if raw, err := hex.DecodeString(version.Key); err != nil || len(raw) != 32 {
+		return nil, newValidationErrorWithContext("invalid cache version key", err, ErrorCodeValidation, nil)
+	}
+	result := c.cache.load(version.Key)
+	if result == nil {
+		return nil, newCacheErrorWithContext("cached version is missing or cannot be decrypted", nil, ErrorCodeIo, nil)
+	}
+	return result, nil
+}
@@ -0,0 +1,70 @@
+package kreuzberg
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestResultCacheKeepsVersionsPerConfig(t *testing.T) {
+	dir := t.TempDir()
+	newConfig := func(delimiter rune) *ExtractionConfig {
+		return &ExtractionConfig{
+			CSV:             &CSVConfig{Delimiter: delimiter},
+			CacheEncryption: &CacheEncryptionConfig{Key: bytes.Repeat([]byte{3}, 32), Dir: dir, RetainVersions: 2},
+		}
+	}
+	configs := []*ExtractionConfig{newConfig(','), newConfig(';'), newConfig('|')}
+	for _, config := range configs[:2] {
+		if _, err := ExtractBytesSync([]byte(testSRT), mimeSRT, config); err != nil {
+			t.Fatalf("extract: %v", err)
+		}
+	}
+
+	cache, err := OpenResultCache(configs[1])
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	versions, err := cache.BytesVersions([]byte(testSRT), mimeSRT)
+	if err != nil {
+		t.Fatalf("versions: %v", err)
+	}
+	if len(versions) != 2 || versions[1].ConfigHash != cache.ConfigHash() || versions[0].ConfigHash == versions[1].ConfigHash || versions[0].LibraryVersion != LibraryVersion() {
+		t.Fatalf("unexpected versions: %+v", versions)
+	}
+	for _, v := range versions {
+		result, err := cache.Load(v)
+		if err != nil || result.Content != wantSRTContent {
+			t.Fatalf("load %s: %v, %v", v.Key, result, err)
+		}
+	}
+	if other, _ := cache.BytesVersions([]byte(testSRT+"\n"), mimeSRT); len(other) != 0 {
+		t.Fatalf("expected no versions for another document, got %+v", other)
+	}
+
+	// A third config pushes the oldest version out of the index and the cache.
+	if _, err := ExtractBytesSync([]byte(testSRT), mimeSRT, configs[2]); err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	retained, _ := cache.BytesVersions([]byte(testSRT), mimeSRT)
+	if len(retained) != 2 || retained[0].Key != versions[1].Key {
+		t.Fatalf("expected the oldest version to be dropped, got %+v", retained)
+	}
+	if _, err := os.Stat(cache.cache.path(versions[0].Key)); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected the dropped entry to be removed, got %v", err)
+	}
+	var cacheErr *CacheError
+	if _, err := cache.Load(versions[0]); !errors.As(err, &cacheErr) {
+		t.Fatalf("expected a CacheError for a dropped version, got %v", err)
+	}
+	if _, err := cache.Load(CacheVersion{Key: "../../etc"}); err == nil {
+		t.Fatalf("expected an invalid key to be rejected")
+	}
+}
+
+func TestOpenResultCacheRequiresEncryption(t *testing.T) {
+	if _, err := OpenResultCache(&ExtractionConfig{}); err == nil {
+		t.Fatalf("expected an error without CacheEncryption")
+	}
+}
@@ -0,0 +1,336 @@
+package kreuzberg
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// checkpointMagic versions the checkpoint file layout and the resume token derivation.
+const checkpointMagic = "KZP1"
+
+// PageSource is a document split into pages that ExtractPagesResumable extracts one at a time,
+// e.g. the pages of a PDF (see PDFPages) or the page images of a scan.
+//
+// Other multi-page formats, such as DOCX, have no page ranges in the native library: such a file
+// given as a single page is extracted in one call and cannot be resumed part-way.
+type PageSource interface {
+	// ID identifies the document across processes. A checkpoint is only resumed for the same ID.
+	ID() (string, error)
+	// Len returns the number of pages.
+	Len() int
+	// Page returns page i (0-based).
+	Page(i int) (BytesWithMime, error)
+}
+
+// PageFiles returns a PageSource with one page per file. Its ID covers the paths, sizes and
+// modification times, so replacing a page file invalidates the checkpoint.
+func PageFiles(paths ...string) PageSource {
+	return pageFiles(paths)
+}
+
+type pageFiles []string
+
+func (p pageFiles) ID() (string, error) {
+	h := sha256.New()
+	for _, path := range p {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", newIOErrorWithContext("failed to stat page file", err, ErrorCodeIo, nil)
+		}
+		fmt.Fprintf(h, "%q %d %d\n", path, info.Size(), info.ModTime().UnixNano())
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (p pageFiles) Len() int { return len(p) }
+
+func (p pageFiles) Page(i int) (BytesWithMime, error) {
+	src := documentSource{path: p[i]}
+	data, err := src.bytes()
+	if err != nil {
+		return BytesWithMime{}, err
+	}
+	return BytesWithMime{Data: data, MimeType: src.detectMimeType()}, nil
+}
+
+// PageBytes returns a PageSource over in-memory pages. Its ID is a digest of their content.
+func PageBytes(pages ...BytesWithMime) PageSource {
+	return pageBytes(pages)
+}
+
+type pageBytes []BytesWithMime
+
+func (p pageBytes) ID() (string, error) {
+	h := sha256.New()
+	for _, page := range p {
+		digest := sha256.Sum256(page.Data)
+		fmt.Fprintf(h, "%q %x\n", page.MimeType, digest)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (p pageBytes) Len() int { return len(p) }
+
+func (p pageBytes) Page(i int) (BytesWithMime, error) { return p[i], nil }
+
+// ResumeToken identifies the checkpoint of one document extracted with one config. It is
+// stable across processes, so it can be computed again with CheckpointToken after a crash.
+type ResumeToken string
+
+// CheckpointToken returns the resume token of pages extracted with config.
+func CheckpointToken(pages PageSource, config *ExtractionConfig) (ResumeToken, error) {
+	id, err := pages.ID()
+	if err != nil {
+		return "", err
+	}
+	digest, err := configDigest(checkpointMagic, config)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write(digest)
+	binary.Write(h, binary.BigEndian, uint64(pages.Len()))
+	h.Write([]byte(id))
+	return ResumeToken(hex.EncodeToString(h.Sum(nil))), nil
+}
+
+// CheckpointOptions controls ExtractPagesResumable.
+type CheckpointOptions struct {
+	// Dir holds the checkpoint files (default: "kreuzberg/checkpoints" in os.UserCacheDir).
+	Dir string
+	// Resume continues the checkpoint with this token. When empty, extraction starts at the
+	// first page and any checkpoint for the document is overwritten.
+	Resume ResumeToken
+	// OnPage, when set, is called after each page has been checkpointed with the number of
+	// completed pages; persisting token here is enough to resume after a crash.
+	OnPage func(token ResumeToken, completed, total int)
+}
+
+// checkpointInfo is reported in Metadata.Additional["checkpoint"].
+type checkpointInfo struct {
+	Token        ResumeToken `json:"token"`
+	Pages        int         `json:"pages"`
+	ResumedPages int         `json:"resumed_pages"`
+}
+
+type checkpointHeader struct {
+	Magic string `json:"magic"`
+	Pages int    `json:"pages"`
+}
+
+type checkpointPage struct {
+	Page   int               `json:"page"`
+	Result *ExtractionResult `json:"result"`
+}
+
+// ExtractPagesResumable extracts a large document page by page, appending each completed page
+// to a checkpoint file so that a crashed or restarted process can pass the ResumeToken in
+// opts.Resume and continue after the last completed page. Pages are extracted like
+// ExtractBytesSync (page files like ExtractFileSync), including post processors and validators,
+// and merged into one result with Pages filled in. The checkpoint is kept when extraction fails
+// or ctx is cancelled between pages, and removed once the document is complete. Resumption is
+// only as fine-grained as the pages of the PageSource (see PageSource).
+func ExtractPagesResumable(ctx context.Context, pages PageSource, config *ExtractionConfig, opts CheckpointOptions) (*ExtractionResult, error) {
+	total := pages.Len()
+	if total == 0 {
+		return nil, newValidationErrorWithContext("page source has no pages", nil, ErrorCodeValidation, nil)
+	}
+	token, err := CheckpointToken(pages, config)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Resume != "" && opts.Resume != token {
+		return nil, newValidationErrorWithContext("resume token does not match the document and config", nil, ErrorCodeValidation, nil)
+	}
+	dir := opts.Dir
+	if dir == "" {
+		base, err := os.UserCacheDir()
+		if err != nil {
+			return nil, newIOErrorWithContext("cannot determine the cache directory; set CheckpointOptions.Dir", err, ErrorCodeIo, nil)
+		}
+		dir = filepath.Join(base, "kreuzberg", "checkpoints")
+	}
+	path := filepath.Join(dir, string(token)+".ckpt")
+
+	var done []*ExtractionResult
+	if opts.Resume != "" {
+		if done, err = loadCheckpoint(path, total); err != nil {
+			return nil, err
+		}
+	}
+	resumed := len(done)
+	file, err := openCheckpoint(path, total, resumed > 0)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	for i := resumed; i < total; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		result, err := extractPage(ctx, pages, i, config)
+		if err != nil {
+			return nil, err
+		}
+		if err := appendCheckpoint(file, checkpointPage{Page: i, Result: result}); err != nil {
+			return nil, err
+		}
+		done = append(done, result)
+		if opts.OnPage != nil {
+			opts.OnPage(token, i+1, total)
+		}
+	}
+
+	file.Close()
+	os.Remove(path)
+	merged := mergePages(done)
+	raw, err := json.Marshal(checkpointInfo{Token: token, Pages: total, ResumedPages: resumed})
+	if err != nil {
+		return nil, newSerializationErrorWithContext("failed to encode checkpoint metadata", err, ErrorCodeValidation, nil)
+	}
+	if merged.Metadata.Additional == nil {
+		merged.Metadata.Additional = map[string]json.RawMessage{}
+	}
+	merged.Metadata.Additional["checkpoint"] = raw
+	return merged, nil
+}
+
+// extractPage extracts page i. Page files are extracted by path, so extractors can also be
+// selected by file extension.
+func extractPage(ctx context.Context, pages PageSource, i int, config *ExtractionConfig) (*ExtractionResult, error) {
+	if files, ok := pages.(pageFiles); ok {
+		return extractFile(ctx, defaultPluginRegistry, files[i], config)
+	}
+	page, err := pages.Page(i)
+	if err != nil {
+		return nil, err
+	}
+	return extractBytes(ctx, defaultPluginRegistry, page.Data, page.MimeType, config)
+}
+
+// loadCheckpoint returns the pages completed in the checkpoint at path, or none when there is
+// no usable checkpoint. A torn last line left by a crash is dropped and truncated away.
+func loadCheckpoint(path string, total int) ([]*ExtractionResult, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, newIOErrorWithContext("failed to read checkpoint", err, ErrorCodeIo, nil)
+	}
+	reader := bufio.NewReader(bytes.NewReader(data))
+	line, err := reader.ReadBytes('\n')
+	var header checkpointHeader
+	if err != nil || json.Unmarshal(line, &header) != nil || header.Magic != checkpointMagic || header.Pages != total {
+		return nil, nil
+	}
+	offset := int64(len(line))
+	var done []*ExtractionResult
+	for len(done) < total {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			break
+		}
+		var page checkpointPage
+		if json.Unmarshal(line, &page) != nil || page.Page != len(done) || page.Result == nil {
+			break
+		}
+		done = append(done, page.Result)
+		offset += int64(len(line))
+	}
+	if offset < int64(len(data)) {
+		if err := os.Truncate(path, offset); err != nil {
+			return nil, newIOErrorWithContext("failed to repair checkpoint", err, ErrorCodeIo, nil)
+		}
+	}
+	return done, nil
+}
+
+// openCheckpoint opens the checkpoint at path for appending, or creates it with a fresh header.
+func openCheckpoint(path string, total int, resume bool) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, newIOErrorWithContext("failed to create checkpoint directory", err, ErrorCodeIo, nil)
+	}
+	if resume {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, newIOErrorWithContext("failed to open checkpoint", err, ErrorCodeIo, nil)
+		}
+		return file, nil
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, newIOErrorWithContext("failed to create checkpoint", err, ErrorCodeIo, nil)
+	}
+	if err := appendCheckpoint(file, checkpointHeader{Magic: checkpointMagic, Pages: total}); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// appendCheckpoint writes record as one JSON line and syncs it, so a completed page survives a
+// crash of the process or the machine.
+func appendCheckpoint(w io.Writer, record any) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return newSerializationErrorWithContext("failed to encode checkpoint", err, ErrorCodeValidation, nil)
+	}
+	if _, err := w.Write(append(line, '\n')); err != nil {
+		return newIOErrorWithContext("failed to write checkpoint", err, ErrorCodeIo, nil)
+	}
+	if f, ok := w.(*os.File); ok {
+		if err := f.Sync(); err != nil {
+			return newIOErrorWithContext("failed to sync checkpoint", err, ErrorCodeIo, nil)
+		}
+	}
+	return nil
+}
+
+// mergePages combines per-page results into one document result. Metadata is taken from the
+// first page; tables are renumbered to the page they came from.
+func mergePages(pages []*ExtractionResult) *ExtractionResult {
+	merged := &ExtractionResult{MimeType: pages[0].MimeType, Metadata: pages[0].Metadata, Success: true}
+	merged.Metadata.Additional = nil
+	for key, value := range pages[0].Metadata.Additional {
+		if merged.Metadata.Additional == nil {
+			merged.Metadata.Additional = map[string]json.RawMessage{}
+		}
+		merged.Metadata.Additional[key] = value
+	}
+	contents := make([]string, len(pages))
+	languages := map[string]bool{}
+	for i, page := range pages {
+		number := i + 1
+		contents[i] = page.Content
+		tables := make([]Table, len(page.Tables))
+		for j, table := range page.Tables {
+			table.PageNumber = number
+			tables[j] = table
+		}
+		merged.Tables = append(merged.Tables, tables...)
+		merged.Images = append(merged.Images, page.Images...)
+		merged.Diagnostics = append(merged.Diagnostics, page.Diagnostics...)
+		merged.Pages = append(merged.Pages, PageContent{PageNumber: uint64(number), Content: page.Content, Tables: tables, Images: page.Images})
+		for _, lang := range page.DetectedLanguages {
+			if !languages[lang] {
+				languages[lang] = true
+				merged.DetectedLanguages = append(merged.DetectedLanguages, lang)
+			}
+		}
+	}
+	merged.Content = strings.Join(contents, "\n\n")
+	return merged
+}
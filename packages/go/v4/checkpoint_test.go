@@ -0,0 +1,127 @@
+package kreuzberg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// flakyPages serves SRT pages, failing every page from failAt on, and records which pages were read.
+type flakyPages struct {
+	n, failAt int
+	read      []int
+}
+
+func (p *flakyPages) ID() (string, error) { return "flaky", nil }
+func (p *flakyPages) Len() int            { return p.n }
+func (p *flakyPages) Page(i int) (BytesWithMime, error) {
+	p.read = append(p.read, i)
+	if p.failAt >= 0 && i >= p.failAt {
+		return BytesWithMime{}, newIOErrorWithContext("page unavailable", nil, ErrorCodeIo, nil)
+	}
+	return BytesWithMime{Data: []byte(testSRT), MimeType: mimeSRT}, nil
+}
+
+func TestExtractPagesResumable(t *testing.T) {
+	dir := t.TempDir()
+	pages := &flakyPages{n: 4, failAt: 2}
+	var token ResumeToken
+	opts := CheckpointOptions{Dir: dir, OnPage: func(tok ResumeToken, completed, total int) { token = tok }}
+
+	var ioErr *IOError
+	if _, err := ExtractPagesResumable(context.Background(), pages, nil, opts); !errors.As(err, &ioErr) {
+		t.Fatalf("expected the page error, got %v", err)
+	}
+	if want, err := CheckpointToken(pages, nil); err != nil || token != want {
+		t.Fatalf("expected OnPage to report token %q, got %q (%v)", want, token, err)
+	}
+	path := filepath.Join(dir, string(token)+".ckpt")
+	// Simulate a crash in the middle of writing the third page.
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	f.WriteString(`{"page":2,"result":{"cont`)
+	f.Close()
+
+	if _, err := ExtractPagesResumable(context.Background(), pages, nil, CheckpointOptions{Dir: dir, Resume: "other"}); err == nil {
+		t.Fatalf("expected an error for a foreign token")
+	}
+
+	pages.failAt, pages.read = -1, nil
+	opts.Resume = token
+	result, err := ExtractPagesResumable(context.Background(), pages, nil, opts)
+	if err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if len(pages.read) != 2 || pages.read[0] != 2 {
+		t.Fatalf("expected only pages 2 and 3 to be extracted again, got %v", pages.read)
+	}
+	if len(result.Pages) != 4 || result.Pages[3].PageNumber != 4 || result.Pages[0].Content != wantSRTContent {
+		t.Fatalf("unexpected pages: %+v", result.Pages)
+	}
+	if result.Content != strings.Repeat(wantSRTContent+"\n\n", 3)+wantSRTContent {
+		t.Fatalf("unexpected content: %q", result.Content)
+	}
+	var info checkpointInfo
+	if err := json.Unmarshal(result.Metadata.Additional["checkpoint"], &info); err != nil || info.ResumedPages != 2 || info.Pages != 4 {
+		t.Fatalf("unexpected checkpoint metadata: %+v, %v", info, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected the checkpoint to be removed, got %v", err)
+	}
+
+	// Without a resume token extraction starts over.
+	pages.read = nil
+	if _, err := ExtractPagesResumable(context.Background(), pages, nil, CheckpointOptions{Dir: dir}); err != nil || len(pages.read) != 4 {
+		t.Fatalf("expected a full extraction, got %v after reading %v", err, pages.read)
+	}
+}
+
+func TestCheckpointTokenDependsOnContentAndConfig(t *testing.T) {
+	dir := t.TempDir()
+	page := filepath.Join(dir, "page1.srt")
+	os.WriteFile(page, []byte(testSRT), 0o600)
+	files := PageFiles(page)
+	base, err := CheckpointToken(files, nil)
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+	if again, _ := CheckpointToken(PageFiles(page), nil); again != base {
+		t.Fatalf("expected a stable token")
+	}
+	if other, _ := CheckpointToken(files, &ExtractionConfig{ForceOCR: BoolPtr(true)}); other == base {
+		t.Fatalf("expected the config to change the token")
+	}
+	if a, _ := CheckpointToken(PageBytes(BytesWithMime{Data: []byte("a"), MimeType: "text/plain"}), nil); a == base {
+		t.Fatalf("expected a different document to change the token")
+	}
+
+	result, err := ExtractPagesResumable(context.Background(), files, nil, CheckpointOptions{Dir: dir})
+	if err != nil || result.Content != wantSRTContent {
+		t.Fatalf("unexpected result: %v, %v", result, err)
+	}
+	if _, err := ExtractPagesResumable(context.Background(), PageBytes(), nil, CheckpointOptions{Dir: dir}); err == nil {
+		t.Fatalf("expected an error without pages")
+	}
+}
+
+func TestPDFPages(t *testing.T) {
+	var validationErr *ValidationError
+	if _, err := PDFPages(nil); !errors.As(err, &validationErr) {
+		t.Fatalf("expected ValidationError for empty data, got %v", err)
+	}
+	if _, err := PDFPages([]byte("not a pdf")); err == nil {
+		t.Fatalf("expected an error for data that is not a PDF")
+	}
+
+	pages := &pdfPages{data: []byte("%PDF-1.7"), pages: 2}
+	if _, err := pages.Page(2); !errors.As(err, &validationErr) {
+		t.Fatalf("expected ValidationError for a page out of range, got %v", err)
+	}
+	id, _ := pages.ID()
+	if other, _ := (&pdfPages{data: []byte("%PDF-1.6"), pages: 2}).ID(); other == id {
+		t.Fatalf("expected a different PDF to change the ID")
+	}
+}
@@ -0,0 +1,264 @@
+package kreuzberg
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// CitationOptions controls Citations.
+type CitationOptions struct {
+	// MaxSnippets caps the number of citations returned (default 5).
+	MaxSnippets int
+	// MaxChars caps the length of a snippet in bytes (default 400). Longer passages are cut to
+	// a window around the best-matching query term, on word boundaries.
+	MaxChars int
+}
+
+// Citation is a passage of an extraction result that matches a query, with the location needed
+// to cite it.
+type Citation struct {
+	// ID numbers the citation from 1 in rank order, for "[n]" references in generated text.
+	ID int `json:"id"`
+	// Text is the passage, verbatim from the content.
+	Text string `json:"text"`
+	// Page is the 1-indexed page the passage starts on (0 when unknown).
+	Page uint64 `json:"page,omitempty"`
+	// ByteStart and ByteEnd locate Text in the result's Content.
+	ByteStart uint64 `json:"byte_start"`
+	ByteEnd   uint64 `json:"byte_end"`
+	// HeadingPath lists the Markdown headings the passage is nested under, outermost first.
+	HeadingPath []string `json:"heading_path,omitempty"`
+	// Score is the relevance of the passage to the query (higher is better).
+	Score float64 `json:"score"`
+}
+
+// citationCandidate is a passage considered by Citations.
+type citationCandidate struct {
+	start, end int
+	page       uint64
+	words      map[string]int
+}
+
+// Citations returns the passages of result that best match query, ready to be cited in a
+// grounded prompt (see FormatCitations). Passages are the result's chunks when chunking was
+// enabled, and its paragraphs otherwise; they are ranked by how often they contain the query's
+// words, weighted by how rare each word is across passages. Passages without any query word are
+// left out.
+func Citations(query string, result *ExtractionResult, opts CitationOptions) []Citation {
+	if result == nil {
+		return nil
+	}
+	maxSnippets, maxChars := opts.MaxSnippets, opts.MaxChars
+	if maxSnippets <= 0 {
+		maxSnippets = 5
+	}
+	if maxChars <= 0 {
+		maxChars = 400
+	}
+	terms := citationWords(query)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	content := result.Content
+	candidates := citationCandidates(result)
+	frequency := map[string]int{}
+	for _, c := range candidates {
+		for term := range terms {
+			if c.words[term] > 0 {
+				frequency[term]++
+			}
+		}
+	}
+	phrase := strings.ToLower(strings.Join(strings.Fields(query), " "))
+
+	var citations []Citation
+	for _, c := range candidates {
+		score := 0.0
+		for term := range terms {
+			if tf := c.words[term]; tf > 0 {
+				idf := math.Log(1 + float64(len(candidates))/float64(frequency[term]))
+				score += idf * (1 + math.Log(float64(tf)))
+			}
+		}
+		if score == 0 {
+			continue
+		}
+		if len(terms) > 1 && strings.Contains(strings.ToLower(strings.Join(strings.Fields(content[c.start:c.end]), " ")), phrase) {
+			score *= 1.5
+		}
+		start, end := citationWindow(content, c.start, c.end, maxChars, terms, frequency)
+		citations = append(citations, Citation{
+			Text:      content[start:end],
+			Page:      c.page,
+			ByteStart: uint64(start),
+			ByteEnd:   uint64(end),
+			Score:     score,
+		})
+	}
+	slices.SortStableFunc(citations, func(a, b Citation) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		}
+		return 0
+	})
+	if len(citations) > maxSnippets {
+		citations = citations[:maxSnippets]
+	}
+	headings := markdownHeadings(content)
+	for i := range citations {
+		citations[i].ID = i + 1
+		citations[i].HeadingPath = headingPathAt(headings, int(citations[i].ByteStart))
+	}
+	return citations
+}
+
+// FormatCitations renders citations for inclusion in an LLM prompt: one numbered block per
+// citation with its location, followed by the quoted passage, e.g.
+//
+//	[1] Methods > Sampling (page 4)
+//	> Participants were drawn from ...
+func FormatCitations(citations []Citation) string {
+	var b strings.Builder
+	for i, c := range citations {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "[%d]", c.ID)
+		if len(c.HeadingPath) > 0 {
+			b.WriteString(" " + strings.Join(c.HeadingPath, " > "))
+		}
+		if c.Page > 0 {
+			fmt.Fprintf(&b, " (page %d)", c.Page)
+		}
+		b.WriteString("\n")
+		for _, line := range strings.Split(strings.TrimSpace(c.Text), "\n") {
+			b.WriteString(strings.TrimRight("> "+strings.TrimSpace(line), " ") + "\n")
+		}
+	}
+	return b.String()
+}
+
+// citationCandidates splits the result into passages: its chunks, or the Markdown blocks of its
+// content that are not headings.
+func citationCandidates(result *ExtractionResult) []citationCandidate {
+	content := result.Content
+	var boundaries []PageBoundary
+	if result.Metadata.PageStructure != nil {
+		boundaries = result.Metadata.PageStructure.Boundaries
+	}
+	var candidates []citationCandidate
+	add := func(start, end int, page uint64) {
+		if page == 0 {
+			page = pageForOffset(boundaries, start)
+		}
+		candidates = append(candidates, citationCandidate{start: start, end: end, page: page, words: citationWords(content[start:end])})
+	}
+	for _, chunk := range result.Chunks {
+		start, end := int(chunk.Metadata.ByteStart), int(chunk.Metadata.ByteEnd)
+		if start >= end || end > len(content) {
+			continue
+		}
+		var page uint64
+		if chunk.Metadata.FirstPage != nil {
+			page = *chunk.Metadata.FirstPage
+		}
+		add(start, end, page)
+	}
+	if len(candidates) > 0 {
+		return candidates
+	}
+	for _, block := range splitSourceBlocks(content) {
+		if text := content[block.start:block.end]; !strings.Contains(text, "\n") && atxHeadingPattern.MatchString(text) {
+			continue
+		}
+		add(block.start, block.end, 0)
+	}
+	return candidates
+}
+
+// citationWords counts the lowercase words of text.
+func citationWords(text string) map[string]int {
+	words := map[string]int{}
+	for _, word := range sentenceWords(text) {
+		words[strings.ToLower(word)]++
+	}
+	return words
+}
+
+// citationWindow narrows [start, end) to at most maxChars bytes around the first occurrence of
+// the rarest query term, snapping to whitespace so words and UTF-8 sequences stay whole.
+func citationWindow(content string, start, end, maxChars int, terms map[string]int, frequency map[string]int) (int, int) {
+	if end-start <= maxChars {
+		return start, end
+	}
+	passage := strings.ToLower(content[start:end])
+	focus, rarest := 0, math.MaxInt
+	for term := range terms {
+		if i := strings.Index(passage, term); i >= 0 && frequency[term] > 0 && (frequency[term] < rarest || frequency[term] == rarest && i < focus) {
+			focus, rarest = i, frequency[term]
+		}
+	}
+	from := max(0, min(focus-maxChars/4, end-start-maxChars))
+	to := from + maxChars
+	if from > 0 {
+		if i := strings.IndexAny(passage[from:to], " \t\n"); i >= 0 {
+			from += i + 1
+		}
+	}
+	if to < end-start {
+		if i := strings.LastIndexAny(passage[from:to], " \t\n"); i > 0 {
+			to = from + i
+		}
+	}
+	text := content[start+from : start+to]
+	trimmed := strings.TrimSpace(text)
+	lead := strings.Index(text, trimmed)
+	return start + from + lead, start + from + lead + len(trimmed)
+}
+
+// markdownHeading is an ATX heading of the content.
+type markdownHeading struct {
+	offset int
+	level  int
+	text   string
+}
+
+func markdownHeadings(content string) []markdownHeading {
+	var headings []markdownHeading
+	offset := 0
+	for _, line := range strings.SplitAfter(content, "\n") {
+		if m := atxHeadingPattern.FindStringSubmatch(strings.TrimRight(line, "\r\n")); m != nil && m[2] != "" {
+			headings = append(headings, markdownHeading{offset: offset, level: len(m[1]), text: m[2]})
+		}
+		offset += len(line)
+	}
+	return headings
+}
+
+// headingPathAt returns the headings enclosing offset, outermost first.
+func headingPathAt(headings []markdownHeading, offset int) []string {
+	var stack []markdownHeading
+	for _, h := range headings {
+		if h.offset > offset {
+			break
+		}
+		for len(stack) > 0 && stack[len(stack)-1].level >= h.level {
+			stack = stack[:len(stack)-1]
+		}
+		stack = append(stack, h)
+	}
+	path := make([]string, len(stack))
+	for i, h := range stack {
+		path[i] = h.text
+	}
+	if len(path) == 0 {
+		return nil
+	}
+	return path
+}
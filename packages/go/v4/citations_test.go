@@ -0,0 +1,71 @@
+package kreuzberg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCitationsFromParagraphs(t *testing.T) {
+	content := "# Report\n\nIntroduction to the study.\n\n## Methods\n\n### Sampling\n\nParticipants were sampled from three regions. Sampling was stratified.\n\n## Results\n\nThe regions differed in response rate.\n"
+	result := &ExtractionResult{Content: content}
+	pageTwo := strings.Index(content, "## Results")
+	result.Metadata.PageStructure = &PageStructure{Boundaries: []PageBoundary{
+		{ByteStart: 0, ByteEnd: uint64(pageTwo), PageNumber: 1},
+		{ByteStart: uint64(pageTwo), ByteEnd: uint64(len(content)), PageNumber: 2},
+	}}
+
+	citations := Citations("sampling regions", result, CitationOptions{})
+	if len(citations) != 2 {
+		t.Fatalf("expected two citations, got %+v", citations)
+	}
+	first, second := citations[0], citations[1]
+	if first.ID != 1 || !strings.HasPrefix(first.Text, "Participants") || first.Page != 1 {
+		t.Fatalf("unexpected first citation %+v", first)
+	}
+	if strings.Join(first.HeadingPath, " > ") != "Report > Methods > Sampling" {
+		t.Fatalf("unexpected heading path %v", first.HeadingPath)
+	}
+	if content[first.ByteStart:first.ByteEnd] != first.Text {
+		t.Fatalf("byte range does not locate the text")
+	}
+	if second.ID != 2 || second.Page != 2 || strings.Join(second.HeadingPath, " > ") != "Report > Results" || second.Score >= first.Score {
+		t.Fatalf("unexpected second citation %+v", second)
+	}
+
+	want := "[1] Report > Methods > Sampling (page 1)\n> Participants were sampled from three regions. Sampling was stratified.\n\n[2] Report > Results (page 2)\n> The regions differed in response rate.\n"
+	if got := FormatCitations(citations); got != want {
+		t.Fatalf("unexpected formatting:\n%s", got)
+	}
+
+	if got := Citations("unrelated", result, CitationOptions{}); got != nil {
+		t.Fatalf("expected no citations, got %+v", got)
+	}
+	if got := Citations("regions", result, CitationOptions{MaxSnippets: 1}); len(got) != 1 {
+		t.Fatalf("expected the snippet cap to apply, got %d", len(got))
+	}
+}
+
+func TestCitationsWindowAndChunks(t *testing.T) {
+	content := strings.Repeat("filler words here ", 40) + "the needle sits here " + strings.Repeat("more filler text ", 40)
+	citations := Citations("needle", &ExtractionResult{Content: content}, CitationOptions{MaxChars: 80})
+	if len(citations) != 1 {
+		t.Fatalf("expected one citation, got %+v", citations)
+	}
+	c := citations[0]
+	if len(c.Text) > 80 || !strings.Contains(c.Text, "needle") || content[c.ByteStart:c.ByteEnd] != c.Text {
+		t.Fatalf("unexpected window %q [%d, %d)", c.Text, c.ByteStart, c.ByteEnd)
+	}
+	if strings.HasPrefix(c.Text, " ") || strings.HasSuffix(c.Text, " ") || !strings.HasPrefix(c.Text, strings.Fields(c.Text)[0]) {
+		t.Fatalf("expected the window to snap to words, got %q", c.Text)
+	}
+
+	page := uint64(7)
+	chunked := &ExtractionResult{Content: "alpha beta\n\ngamma delta", Chunks: []Chunk{
+		{Content: "alpha beta", Metadata: ChunkMetadata{ByteStart: 0, ByteEnd: 10}},
+		{Content: "gamma delta", Metadata: ChunkMetadata{ByteStart: 12, ByteEnd: 23, FirstPage: &page}},
+	}}
+	citations = Citations("Delta", chunked, CitationOptions{})
+	if len(citations) != 1 || citations[0].Text != "gamma delta" || citations[0].Page != 7 {
+		t.Fatalf("unexpected chunk citations %+v", citations)
+	}
+}
@@ -0,0 +1,150 @@
+package kreuzberg
+
+import (
+	"context"
+	"iter"
+)
+
+// Client bundles an ExtractionConfig with its own scope of Go-native plugins.
+//
+// Package-level Go plugins (RegisterValidatorFunc, RegisterPostProcessorFunc) are process-global,
+// which breaks down when two libraries in the same binary register conflicting processors.
+// Plugins registered on a Client only run for extractions performed through that Client, and
+// package-level Go plugins are not applied to them. Native plugins registered with a C callback
+// (RegisterValidator, RegisterPostProcessor, RegisterOCRBackend) remain process-global.
+//
+// A Client is safe for concurrent use.
+type Client struct {
+	config  *ExtractionConfig
+	plugins *pluginRegistry
+	// compiled is the CompiledConfig that owns config, if any.
+	compiled *CompiledConfig
+}
+
+// NewClient creates a Client that extracts with the given config. A nil config uses library defaults.
+func NewClient(config *ExtractionConfig) *Client {
+	return &Client{
+		config:  config,
+		plugins: &pluginRegistry{},
+	}
+}
+
+// Config returns the ExtractionConfig used by the client (nil when defaults are used). It must
+// not be modified while extractions are in flight. For a client created by
+// CompiledConfig.NewClient it returns a copy, since the compiled config is immutable.
+func (c *Client) Config() *ExtractionConfig {
+	if c.compiled != nil {
+		return c.compiled.Config()
+	}
+	return c.config
+}
+
+// RegisterPostProcessor registers a Go-native post processor scoped to this client.
+func (c *Client) RegisterPostProcessor(name string, priority int32, fn PostProcessorFunc) error {
+	return c.plugins.addPostProcessor(name, priority, fn)
+}
+
+// UnregisterPostProcessor removes a post processor from this client's scope.
+func (c *Client) UnregisterPostProcessor(name string) error {
+	return c.plugins.removePostProcessor(name)
+}
+
+// RegisterValidator registers a Go-native validator scoped to this client.
+func (c *Client) RegisterValidator(name string, priority int32, fn ValidatorFunc) error {
+	return c.plugins.addValidator(name, priority, fn)
+}
+
+// UnregisterValidator removes a validator from this client's scope.
+func (c *Client) UnregisterValidator(name string) error {
+	return c.plugins.removeValidator(name)
+}
+
+// SetExtensionMimeType makes the client's extractions treat files with extension as mimeType;
+// see the package-level SetExtensionMimeType. Package-level mappings do not apply to a Client.
+func (c *Client) SetExtensionMimeType(extension, mimeType string) error {
+	return c.plugins.mime.setExtension(extension, mimeType)
+}
+
+// SetMimeTypeOverride makes the client's extractions treat documents detected or passed as
+// detected as mimeType; see the package-level SetMimeTypeOverride.
+func (c *Client) SetMimeTypeOverride(detected, mimeType string) error {
+	return c.plugins.mime.setType(detected, mimeType)
+}
+
+// ClearMimeOverrides removes the client's MIME type overrides and extension mappings.
+func (c *Client) ClearMimeOverrides() {
+	c.plugins.mime.clear()
+}
+
+// DetectMimeTypeFromPath detects the MIME type of the file at path as the client's extractions
+// see it, with the client's overrides applied.
+func (c *Client) DetectMimeTypeFromPath(path string) (string, error) {
+	mimeType, _, err := c.plugins.mime.detectPath(path)
+	return mimeType, err
+}
+
+// ApplyPlugins runs the client's Go plugins against an existing result, in the same order
+// (post-processors, then validators, by descending priority) used after native extraction.
+func (c *Client) ApplyPlugins(pc *PluginContext, result *ExtractionResult) error {
+	if pc == nil {
+		return newValidationErrorWithContext("plugin context cannot be nil", nil, ErrorCodeValidation, nil)
+	}
+	return c.plugins.apply(pc, result)
+}
+
+// ExtractFile extracts the file at path using the client's config and plugins.
+// As with ExtractFileWithContext, cancellation is only checked before extraction starts.
+func (c *Client) ExtractFile(ctx context.Context, path string) (*ExtractionResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return extractFile(ctx, c.plugins, path, c.config)
+}
+
+// ExtractBytes extracts an in-memory document using the client's config and plugins.
+func (c *Client) ExtractBytes(ctx context.Context, data []byte, mimeType string) (*ExtractionResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return extractBytes(ctx, c.plugins, data, mimeType, c.config)
+}
+
+// BatchExtractFiles extracts multiple files using the client's config and plugins.
+func (c *Client) BatchExtractFiles(ctx context.Context, paths []string) ([]*ExtractionResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return batchExtractFiles(ctx, c.plugins, paths, c.config)
+}
+
+// BatchExtractBytes extracts multiple in-memory documents using the client's config and plugins.
+func (c *Client) BatchExtractBytes(ctx context.Context, items []BytesWithMime) ([]*ExtractionResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return batchExtractBytes(ctx, c.plugins, items, c.config)
+}
+
+// BatchExtractFilesToSink streams file results to sink using the client's config and plugins;
+// see the package-level BatchExtractFilesToSink.
+func (c *Client) BatchExtractFilesToSink(ctx context.Context, paths []string, opts *BatchSinkOptions, sink ResultSink) error {
+	return batchExtractFilesToSink(ctx, c.plugins, paths, c.config, opts, sink)
+}
+
+// BatchExtractBytesToSink streams in-memory document results to sink using the client's config
+// and plugins.
+func (c *Client) BatchExtractBytesToSink(ctx context.Context, items []BytesWithMime, opts *BatchSinkOptions, sink ResultSink) error {
+	return batchExtractBytesToSink(ctx, c.plugins, items, c.config, opts, sink)
+}
+
+// BatchExtractFilesSeq iterates over file results using the client's config and plugins; see
+// the package-level BatchExtractFilesSeq.
+func (c *Client) BatchExtractFilesSeq(ctx context.Context, paths []string, opts *BatchSinkOptions) iter.Seq2[BatchResult, error] {
+	return batchExtractFilesSeq(ctx, c.plugins, paths, c.config, opts)
+}
+
+// BatchExtractBytesSeq iterates over in-memory document results using the client's config and
+// plugins.
+func (c *Client) BatchExtractBytesSeq(ctx context.Context, items []BytesWithMime, opts *BatchSinkOptions) iter.Seq2[BatchResult, error] {
+	return batchExtractBytesSeq(ctx, c.plugins, items, c.config, opts)
+}
@@ -0,0 +1,74 @@
+package kreuzberg
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestClientPluginsAreScoped(t *testing.T) {
+	first := NewClient(nil)
+	second := NewClient(nil)
+	noop := func(ctx *PluginContext, result *ExtractionResult) error { return nil }
+
+	if err := first.RegisterValidator("shared-name", 0, noop); err != nil {
+		t.Fatalf("register on first client: %v", err)
+	}
+	if err := second.RegisterValidator("shared-name", 0, noop); err != nil {
+		t.Fatalf("same name on second client should not conflict: %v", err)
+	}
+
+	_, globalValidators := defaultPluginRegistry.snapshot()
+	for _, v := range globalValidators {
+		if v.name == "shared-name" {
+			t.Fatalf("client registration leaked into the global registry")
+		}
+	}
+
+	if err := first.UnregisterValidator("shared-name"); err != nil {
+		t.Fatalf("unregister on first client: %v", err)
+	}
+	_, secondValidators := second.plugins.snapshot()
+	if len(secondValidators) != 1 {
+		t.Fatalf("unregistering on one client affected another")
+	}
+}
+
+func TestClientAppliesOnlyItsOwnPlugins(t *testing.T) {
+	client := NewClient(nil)
+	called := false
+	if err := client.RegisterPostProcessor("mark", 0, func(ctx *PluginContext, result *ExtractionResult) error {
+		called = true
+		result.Content = "processed"
+		return nil
+	}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	result := &ExtractionResult{Content: "raw"}
+	if err := client.plugins.apply(newPluginContext(t.Context(), "", []byte("raw"), "text/plain", client.Config()), result); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if !called || result.Content != "processed" {
+		t.Fatalf("client post processor did not run")
+	}
+}
+
+func TestClientHonorsCanceledContext(t *testing.T) {
+	client := NewClient(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := client.ExtractFile(ctx, "doc.pdf"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if _, err := client.ExtractBytes(ctx, []byte("x"), "text/plain"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if _, err := client.BatchExtractFiles(ctx, []string{"doc.pdf"}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if _, err := client.BatchExtractBytes(ctx, []BytesWithMime{{Data: []byte("x"), MimeType: "text/plain"}}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
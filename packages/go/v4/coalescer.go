@@ -0,0 +1,202 @@
+package kreuzberg
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CoalescerOptions configures an ExtractionCoalescer.
+type CoalescerOptions struct {
+	// MaxBatchSize flushes a batch as soon as it holds this many documents (default 32).
+	MaxBatchSize int
+	// MaxLatency is the longest a document waits for others to share its batch (default 20ms).
+	// A document whose context has a deadline waits at most half the time left to it.
+	MaxLatency time.Duration
+}
+
+// ExtractionCoalescer merges single-document extractions submitted concurrently, e.g. by the
+// handlers of separate requests, into one batch call of the native library. It saves the
+// per-call overhead of crossing into the native library and lets the native batch pipeline
+// schedule the documents together. Each caller of Extract receives the result of its own
+// document. It is safe for concurrent use.
+//
+// It does not batch OCR across documents: the native OCR layer recognizes one image per backend
+// call, and Tesseract, its only built-in backend, has no batched recognition to feed, so every
+// image, and every page of a PDF, is still recognized on its own.
+type ExtractionCoalescer struct {
+	config *CompiledConfig
+	opts   CoalescerOptions
+
+	mu      sync.Mutex
+	queue   []*coalescedRequest
+	timer   *time.Timer
+	flushAt time.Time
+	closed  bool
+	running sync.WaitGroup
+}
+
+type coalescedRequest struct {
+	item  BytesWithMime
+	due   time.Time
+	reply chan coalescedReply
+}
+
+type coalescedReply struct {
+	result *ExtractionResult
+	err    error
+}
+
+// coalescedBatchExtract extracts a flushed batch; tests replace it. The config passed on is owned
+// by config, so the native calls reuse its precompiled JSON instead of encoding it per batch.
+var coalescedBatchExtract = func(items []BytesWithMime, config *CompiledConfig) ([]*ExtractionResult, error) {
+	return batchExtractBytes(context.Background(), defaultPluginRegistry, items, config.config)
+}
+
+// NewExtractionCoalescer returns a coalescer that extracts documents with config, which is
+// shared by every batch. Close it to flush the documents still waiting.
+func NewExtractionCoalescer(config *ExtractionConfig, opts *CoalescerOptions) (*ExtractionCoalescer, error) {
+	compiled, err := CompileConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	c := &ExtractionCoalescer{config: compiled}
+	if opts != nil {
+		c.opts = *opts
+	}
+	if c.opts.MaxBatchSize <= 0 {
+		c.opts.MaxBatchSize = 32
+	}
+	if c.opts.MaxLatency <= 0 {
+		c.opts.MaxLatency = 20 * time.Millisecond
+	}
+	return c, nil
+}
+
+// Extract queues a document for the next batch and waits for its result. A failure of the
+// document is returned as the error a single extraction would have returned; the other
+// documents of the batch are unaffected. When ctx ends first, the document is dropped from the
+// queue, or its result discarded if its batch is already running, and ctx's error is returned.
+func (c *ExtractionCoalescer) Extract(ctx context.Context, data []byte, mimeType string) (*ExtractionResult, error) {
+	if len(data) == 0 {
+		return nil, newValidationErrorWithContext("data cannot be empty", nil, ErrorCodeValidation, nil)
+	}
+	if mimeType == "" {
+		return nil, newValidationErrorWithContext("mimeType is required", nil, ErrorCodeValidation, nil)
+	}
+	now := time.Now()
+	req := &coalescedRequest{item: BytesWithMime{Data: data, MimeType: mimeType}, due: now.Add(c.opts.MaxLatency), reply: make(chan coalescedReply, 1)}
+	if deadline, ok := ctx.Deadline(); ok {
+		if half := now.Add(deadline.Sub(now) / 2); half.Before(req.due) {
+			req.due = half
+		}
+	}
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, newValidationErrorWithContext("extraction coalescer is closed", nil, ErrorCodeValidation, nil)
+	}
+	c.queue = append(c.queue, req)
+	if len(c.queue) >= c.opts.MaxBatchSize {
+		c.flushLocked()
+	} else {
+		c.scheduleLocked(req.due)
+	}
+	c.mu.Unlock()
+
+	select {
+	case reply := <-req.reply:
+		return reply.result, reply.err
+	case <-ctx.Done():
+		c.mu.Lock()
+		for i, queued := range c.queue {
+			if queued == req {
+				c.queue = append(c.queue[:i:i], c.queue[i+1:]...)
+				break
+			}
+		}
+		c.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// Close flushes the queued documents, waits for the running batches and rejects further
+// documents.
+func (c *ExtractionCoalescer) Close() {
+	c.mu.Lock()
+	c.closed = true
+	c.flushLocked()
+	c.mu.Unlock()
+	c.running.Wait()
+}
+
+// scheduleLocked arms the flush timer for due unless it fires earlier already.
+func (c *ExtractionCoalescer) scheduleLocked(due time.Time) {
+	if c.timer != nil && !due.Before(c.flushAt) {
+		return
+	}
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	c.flushAt = due
+	c.timer = time.AfterFunc(time.Until(due), func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.flushAt.Equal(due) {
+			c.flushLocked()
+		}
+	})
+}
+
+// flushLocked starts a batch with the queued documents and disarms the timer.
+func (c *ExtractionCoalescer) flushLocked() {
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer, c.flushAt = nil, time.Time{}
+	}
+	if len(c.queue) == 0 {
+		return
+	}
+	batch := c.queue
+	c.queue = nil
+	c.running.Add(1)
+	go func() {
+		defer c.running.Done()
+		c.run(batch)
+	}()
+}
+
+// run extracts batch and hands each caller the result of its document.
+func (c *ExtractionCoalescer) run(batch []*coalescedRequest) {
+	items := make([]BytesWithMime, len(batch))
+	for i, req := range batch {
+		items[i] = req.item
+	}
+	results, err := coalescedBatchExtract(items, c.config)
+	if err != nil && len(batch) > 1 {
+		// A batch fails as a whole when one of its documents is rejected up front; extract the
+		// documents on their own so the failure only reaches the caller it belongs to.
+		for _, req := range batch {
+			c.run([]*coalescedRequest{req})
+		}
+		return
+	}
+	for i, req := range batch {
+		reply := coalescedReply{err: err}
+		if err == nil {
+			if i < len(results) && results[i] != nil {
+				reply.result = results[i]
+				if itemErr := batchItemError(results[i]); itemErr != nil {
+					reply = coalescedReply{err: itemErr}
+				} else {
+					reply.result.addDiagnostic("coalesced", DiagnosticSeverityInfo, fmt.Sprintf("extracted in a batch of %d documents", len(batch)))
+				}
+			} else {
+				reply.err = newRuntimeErrorWithContext("batch returned no result for the document", nil, ErrorCodeInternal, nil)
+			}
+		}
+		req.reply <- reply
+	}
+}
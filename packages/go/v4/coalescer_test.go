@@ -0,0 +1,171 @@
+package kreuzberg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordCoalescedBatches replaces the batch extraction with one that records the batch sizes.
+func recordCoalescedBatches(t *testing.T) func() []int {
+	t.Helper()
+	var mu sync.Mutex
+	var sizes []int
+	original := coalescedBatchExtract
+	coalescedBatchExtract = func(items []BytesWithMime, config *CompiledConfig) ([]*ExtractionResult, error) {
+		mu.Lock()
+		sizes = append(sizes, len(items))
+		mu.Unlock()
+		return original(items, config)
+	}
+	t.Cleanup(func() {
+		coalescedBatchExtract = original
+	})
+	return func() []int {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]int(nil), sizes...)
+	}
+}
+
+func TestExtractionCoalescerAttribution(t *testing.T) {
+	batches := recordCoalescedBatches(t)
+	c, err := NewExtractionCoalescer(nil, &CoalescerOptions{MaxBatchSize: 3, MaxLatency: time.Hour})
+	if err != nil {
+		t.Fatalf("new coalescer: %v", err)
+	}
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for i := range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			name := fmt.Sprintf("Document%d", i)
+			result, err := c.Extract(t.Context(), []byte(strings.Replace(testSRT, "Kenobi", name, 1)), mimeSRT)
+			if err != nil || !strings.Contains(result.Content, name) {
+				t.Errorf("document %d: unexpected result %+v, %v", i, result, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if sizes := batches(); len(sizes) != 1 || sizes[0] != 3 {
+		t.Fatalf("expected one batch of 3, got %v", sizes)
+	}
+}
+
+func TestExtractionCoalescerLatency(t *testing.T) {
+	batches := recordCoalescedBatches(t)
+	c, _ := NewExtractionCoalescer(nil, &CoalescerOptions{MaxLatency: 10 * time.Millisecond})
+	defer c.Close()
+	if result, err := c.Extract(t.Context(), []byte(testSRT), mimeSRT); err != nil || result.Content != wantSRTContent {
+		t.Fatalf("unexpected result %+v, %v", result, err)
+	}
+
+	// A deadline shortens the wait to half the time left.
+	slow, _ := NewExtractionCoalescer(nil, &CoalescerOptions{MaxLatency: time.Hour})
+	defer slow.Close()
+	ctx, cancel := context.WithTimeout(t.Context(), 2*time.Second)
+	defer cancel()
+	if _, err := slow.Extract(ctx, []byte(testSRT), mimeSRT); err != nil {
+		t.Fatalf("expected the deadline to flush the batch, got %v", err)
+	}
+	if sizes := batches(); len(sizes) != 2 {
+		t.Fatalf("expected two batches, got %v", sizes)
+	}
+}
+
+func TestExtractionCoalescerFailures(t *testing.T) {
+	batches := recordCoalescedBatches(t)
+	c, _ := NewExtractionCoalescer(nil, &CoalescerOptions{MaxBatchSize: 2, MaxLatency: time.Hour})
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if _, err := c.Extract(ctx, []byte(testSRT), mimeSRT); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a cancelled document to return, got %v", err)
+	}
+
+	InjectFaults(t, Faults{FailRate: 1, Match: func(_, mimeType string) bool { return mimeType == "image/png" }})
+	errs := make(chan error, 2)
+	for _, item := range []BytesWithMime{{Data: []byte(testSRT), MimeType: mimeSRT}, {Data: []byte("broken"), MimeType: "image/png"}} {
+		go func() {
+			_, err := c.Extract(t.Context(), item.Data, item.MimeType)
+			errs <- err
+		}()
+	}
+	var failed int
+	for range 2 {
+		if err := <-errs; err != nil {
+			failed++
+		}
+	}
+	if failed != 1 {
+		t.Fatalf("expected only the broken document to fail, got %d failures", failed)
+	}
+	if sizes := batches(); len(sizes) != 1 || sizes[0] != 2 {
+		t.Fatalf("expected the cancelled document to be dropped from the batch, got %v", sizes)
+	}
+
+	c.Close()
+	if _, err := c.Extract(t.Context(), []byte(testSRT), mimeSRT); err == nil {
+		t.Fatalf("expected a closed coalescer to reject documents")
+	}
+	if _, err := NewExtractionCoalescer(&ExtractionConfig{Chunking: &ChunkingConfig{Preset: "balance"}}, nil); err == nil {
+		t.Fatalf("expected an invalid config to be rejected")
+	}
+}
+
+func TestExtractionCoalescerItemErrors(t *testing.T) {
+	original := coalescedBatchExtract
+	var mu sync.Mutex
+	var sizes []int
+	coalescedBatchExtract = func(items []BytesWithMime, config *CompiledConfig) ([]*ExtractionResult, error) {
+		if native, ok := compiledNativeJSON(config.config); !ok || !bytes.Equal(native, config.native) {
+			t.Errorf("expected the batch to reuse the precompiled config JSON")
+		}
+		mu.Lock()
+		sizes = append(sizes, len(items))
+		mu.Unlock()
+		// Like the batch validation, reject the whole batch for one bad document.
+		for _, item := range items {
+			if string(item.Data) == "rejected" {
+				return nil, newValidationErrorWithContext("rejected document", nil, ErrorCodeValidation, nil)
+			}
+		}
+		return original(items, config)
+	}
+	t.Cleanup(func() { coalescedBatchExtract = original })
+
+	c, _ := NewExtractionCoalescer(nil, &CoalescerOptions{MaxBatchSize: 2, MaxLatency: time.Hour})
+	defer c.Close()
+	if _, err := c.Extract(t.Context(), []byte(testSRT), ""); err == nil {
+		t.Fatalf("expected a document without a MIME type to be rejected before it is queued")
+	}
+
+	errs := make(chan error, 2)
+	for _, data := range []string{testSRT, "rejected"} {
+		go func() {
+			_, err := c.Extract(t.Context(), []byte(data), mimeSRT)
+			errs <- err
+		}()
+	}
+	var failed int
+	for range 2 {
+		if err := <-errs; err != nil {
+			failed++
+		}
+	}
+	if failed != 1 {
+		t.Fatalf("expected only the rejected document to fail, got %d failures", failed)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sizes) != 3 || sizes[0] != 2 {
+		t.Fatalf("expected the failed batch to be retried document by document, got %v", sizes)
+	}
+}
@@ -0,0 +1,94 @@
+package kreuzberg
+
+import (
+	"encoding/json"
+	"runtime"
+	"sync"
+	"weak"
+)
+
+// CompiledConfig is an immutable snapshot of an ExtractionConfig, validated and serialized for
+// the native library once, for hot loops that would otherwise re-encode the same config on
+// every extraction. Create it with CompileConfig and extract through NewClient. It is safe for
+// concurrent use.
+type CompiledConfig struct {
+	config *ExtractionConfig
+	native []byte
+}
+
+// compiledNativeConfigs maps the configs owned by CompiledConfigs to their native JSON. Entries
+// are keyed weakly and removed once the config is collected.
+var compiledNativeConfigs sync.Map // weak.Pointer[ExtractionConfig] -> []byte
+
+// CompileConfig validates config and snapshots it: later changes to config do not affect the
+// CompiledConfig. A nil config compiles to the library defaults.
+func CompileConfig(config *ExtractionConfig) (*CompiledConfig, error) {
+	owned := MergeConfigs(config, nil)
+	native, err := nativeConfigJSON(owned)
+	if err != nil {
+		return nil, err
+	}
+	key := weak.Make(owned)
+	compiledNativeConfigs.Store(key, native)
+	runtime.AddCleanup(owned, func(key weak.Pointer[ExtractionConfig]) {
+		compiledNativeConfigs.Delete(key)
+	}, key)
+	return &CompiledConfig{config: owned, native: native}, nil
+}
+
+// Config returns a copy of the compiled config.
+func (c *CompiledConfig) Config() *ExtractionConfig {
+	return MergeConfigs(c.config, nil)
+}
+
+// NativeJSON returns the JSON the config is passed to the native library as.
+func (c *CompiledConfig) NativeJSON() string {
+	return string(c.native)
+}
+
+// NewClient creates a Client that extracts with the compiled config, reusing its serialized
+// form on every native call.
+func (c *CompiledConfig) NewClient() *Client {
+	client := NewClient(c.config)
+	client.compiled = c
+	return client
+}
+
+// compiledNativeJSON returns the native JSON of a config owned by a CompiledConfig.
+func compiledNativeJSON(config *ExtractionConfig) ([]byte, bool) {
+	native, ok := compiledNativeConfigs.Load(weak.Make(config))
+	if !ok {
+		return nil, false
+	}
+	return native.([]byte), true
+}
+
+// nativeConfigJSON validates config and encodes it for the native library.
+func nativeConfigJSON(config *ExtractionConfig) ([]byte, error) {
+	if err := validateConfigValues(config); err != nil {
+		return nil, err
+	}
+	if cacheEncryptionActive(config) || statisticsOnly(config) {
+		// The Go binding caches encrypted results, and statistics-only results are reduced after
+		// they leave the native library; keep the native result and OCR caches from storing
+		// plaintext.
+		native := *config
+		native.UseCache = BoolPtr(false)
+		if config.OCR != nil {
+			ocr := *config.OCR
+			tesseract := TesseractConfig{}
+			if ocr.Tesseract != nil {
+				tesseract = *ocr.Tesseract
+			}
+			tesseract.UseCache = BoolPtr(false)
+			ocr.Tesseract = &tesseract
+			native.OCR = &ocr
+		}
+		config = &native
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, newSerializationErrorWithContext("failed to encode config", err, ErrorCodeValidation, nil)
+	}
+	return data, nil
+}
@@ -0,0 +1,90 @@
+package kreuzberg
+
+import (
+	"encoding/json"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCompileConfig(t *testing.T) {
+	if _, err := CompileConfig(&ExtractionConfig{Chunking: &ChunkingConfig{Preset: "balance"}}); err == nil {
+		t.Fatalf("expected an invalid config to fail to compile")
+	}
+
+	config := &ExtractionConfig{Chunking: &ChunkingConfig{MaxChars: IntPtr(40)}, ContentLimit: &ContentLimitConfig{MaxContentChars: 1000}}
+	want, _ := json.Marshal(config)
+	compiled, err := CompileConfig(config)
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	*config.Chunking.MaxChars = 10
+	config.UseCache = BoolPtr(false)
+	if compiled.NativeJSON() != string(want) || *compiled.Config().Chunking.MaxChars != 40 {
+		t.Fatalf("expected the compiled config to be a snapshot, got %s", compiled.NativeJSON())
+	}
+
+	client := compiled.NewClient()
+	client.Config().Chunking = nil
+	if client.Config().Chunking == nil {
+		t.Fatalf("expected the client to hand out copies of the compiled config")
+	}
+	if data, ok := compiledNativeJSON(client.config); !ok || string(data) != string(want) {
+		t.Fatalf("expected the native JSON to be reused")
+	}
+	copied := *client.config
+	if _, ok := compiledNativeJSON(&copied); ok {
+		t.Fatalf("expected a derived config to be encoded again")
+	}
+
+	result, err := client.ExtractBytes(t.Context(), []byte(testSRT), mimeSRT)
+	if err != nil || len(result.Chunks) < 2 {
+		t.Fatalf("unexpected result %+v, %v", result, err)
+	}
+}
+
+func TestCompiledConfigReleased(t *testing.T) {
+	count := func() int {
+		n := 0
+		compiledNativeConfigs.Range(func(any, any) bool { n++; return true })
+		return n
+	}
+	before := count()
+	if _, err := CompileConfig(&ExtractionConfig{}); err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); count() > before; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the compiled config to be released")
+		}
+		runtime.GC()
+	}
+}
+
+func TestSharedConfigConcurrentUse(t *testing.T) {
+	// Run with -race: the binding must only read a shared config, including the paths that
+	// derive variants of it.
+	config := &ExtractionConfig{
+		Chunking:     &ChunkingConfig{MaxChars: IntPtr(40)},
+		ContentLimit: &ContentLimitConfig{MaxContentChars: 10},
+		OCR:          &OCRConfig{Backend: OCRBackendTesseract},
+		Degradation:  DegradationDegrade,
+	}
+	compiled, err := CompileConfig(config)
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	clients := []*Client{NewClient(config), compiled.NewClient()}
+	var wg sync.WaitGroup
+	for i := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := clients[i%2].ExtractBytes(t.Context(), []byte(testSRT), mimeSRT); err != nil {
+				t.Errorf("extract: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -3,6 +3,7 @@ package kreuzberg
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 	"unsafe"
 )
 
@@ -35,6 +36,11 @@ func FloatPtr(f float64) *float64 {
 // ExtractionConfig mirrors the Rust ExtractionConfig structure and is serialized to JSON
 // before crossing the FFI boundary. Use pointer fields to omit values and rely on Kreuzberg
 // defaults whenever possible.
+//
+// A config may be shared by any number of concurrent extractions: the binding only reads it, and
+// copies it before deriving variants (routing profiles, fallbacks, degraded features). It must not
+// be modified while extractions using it are in flight; derive per-request variants with
+// MergeConfigs, and use CompileConfig to snapshot a config reused in hot loops.
 type ExtractionConfig struct {
 	// UseCache enables caching of extraction results for identical inputs.
 	UseCache *bool `json:"use_cache,omitempty"`
@@ -64,12 +70,117 @@ type ExtractionConfig struct {
 	Pages *PageConfig `json:"pages,omitempty"`
 	// MaxConcurrentExtractions limits the number of concurrent extraction operations.
 	MaxConcurrentExtractions *int `json:"max_concurrent_extractions,omitempty"`
+	// Labels carries caller-supplied key/value metadata (tenant, source system, etc.) that is
+	// forwarded to Go plugins via PluginContext. Labels are never sent to the native library.
+	Labels map[string]string `json:"-"`
+	// ValidationPolicy controls how Go validator warnings and failures affect the result.
+	ValidationPolicy *ValidationPolicy `json:"-"`
+	// Fallback configures per-MIME fallback chains tried when the primary extraction fails.
+	Fallback *FallbackConfig `json:"-"`
+	// CanonicalMarkdown rewrites Content and table Markdown into a canonical, stable form
+	// (see CanonicalizeMarkdown) so re-extractions only differ when the document does.
+	CanonicalMarkdown *bool `json:"-"`
+	// SourceAnchors sets ExtractionResult.AnnotatedContent to Content with an HTML comment before
+	// each Markdown block giving its page and byte span in Content (see ParseSourceAnchors).
+	// Content itself is left as is, so the spans, chunk offsets and page boundaries agree; with
+	// CanonicalMarkdown they all refer to the content before canonicalization.
+	SourceAnchors *bool `json:"-"`
+	// Spreadsheet enables row-capped streaming extraction for XLSX/XLSM documents.
+	Spreadsheet *SpreadsheetConfig `json:"-"`
+	// CSV enables structured CSV/TSV extraction with dialect control and schema inference.
+	CSV *CSVConfig `json:"-"`
+	// DataFiles tunes extraction of Parquet, Avro and ORC data files.
+	DataFiles *DataFileConfig `json:"-"`
+	// Database tunes extraction of SQLite and Microsoft Access database files.
+	Database *DatabaseConfig `json:"-"`
+	// StructuredData enables structure-aware extraction of JSON and YAML documents.
+	StructuredData *StructuredDataConfig `json:"-"`
+	// Logs enables line-structured extraction of log files.
+	Logs *LogConfig `json:"-"`
+	// XMLProfile enables semantic extraction of DocBook, TEI, JATS and PubMed XML.
+	XMLProfile *XMLProfileConfig `json:"-"`
+	// CacheEncryption encrypts cached results at rest. While caching is enabled (UseCache is
+	// not false), the Go binding caches results itself, sealed with AES-GCM, and both the native
+	// result cache and the Tesseract OCR cache are disabled so neither stores plaintext.
+	CacheEncryption *CacheEncryptionConfig `json:"-"`
+	// OCRAutoTune picks Tesseract parameters per document class by trying a small grid of
+	// candidates on the first document of each class.
+	OCRAutoTune *OCRAutoTuneConfig `json:"-"`
+	// Router picks a config profile per document from its fingerprint; the profile is merged
+	// over this config.
+	Router *Router `json:"-"`
+	// ContentLimit caps the length of Content with an explicit truncation policy.
+	ContentLimit *ContentLimitConfig `json:"-"`
+	// TextStatistics computes sentence, n-gram and readability statistics over Content.
+	TextStatistics *TextStatisticsConfig `json:"-"`
+	// MetadataProvenance records the source of common metadata fields and resolves conflicting
+	// values by source precedence.
+	MetadataProvenance *MetadataProvenanceConfig `json:"-"`
+	// XMP parses the document's embedded XMP packet into Metadata.Additional["xmp"] (see
+	// Metadata.XMP).
+	XMP *bool `json:"-"`
+	// OfficeCustomProperties reads the custom document properties of DOCX, XLSX and PPTX files
+	// into Metadata.Additional["custom_properties"] (see Metadata.CustomProperties).
+	OfficeCustomProperties *bool `json:"-"`
+	// OfficeStats reads the application statistics and revision details of DOCX, XLSX and PPTX
+	// files into Metadata.Additional["office_stats"] (see Metadata.OfficeStats).
+	OfficeStats *bool `json:"-"`
+	// Accessibility reads the tagged structure, alternative text and language of PDF and DOCX
+	// files and scores their accessibility into Metadata.Additional["accessibility"] (see
+	// Metadata.Accessibility).
+	Accessibility *bool `json:"-"`
+	// LanguageHints records locale, script and analyzer hints for the document languages in
+	// Metadata.Additional["language_hints"] (see Metadata.LanguageHints).
+	LanguageHints *bool `json:"-"`
+	// PluginTimeout bounds each Go post processor and validator call. The plugin's
+	// PluginContext.Context is cancelled at the deadline, and a plugin that has not returned
+	// shortly after fails the extraction (or the batch item) with a PluginError. Plugins
+	// registered with C callbacks run inside the native pipeline and are bounded by the
+	// process-wide SetNativePluginTimeout instead.
+	PluginTimeout time.Duration `json:"-"`
+	// StatisticsOnly reduces every result to aggregate statistics (see DocumentStatistics):
+	// content, tables, chunks, pages, images and format metadata are discarded before the result
+	// is returned, Go plugins do not run and the encrypted result cache is bypassed. Documents
+	// the native library extracts are reduced inside it, so their content never crosses the FFI
+	// boundary, and its result cache and Tesseract OCR cache are disabled to keep the content
+	// from being persisted. Documents handled by the built-in Go extractors are reduced in Go.
+	StatisticsOnly *bool `json:"-"`
+	// DualRun shadows single-document extractions with a second config and reports the
+	// differences (see DualRunConfig).
+	DualRun *DualRunConfig `json:"-"`
+	// Triage attaches a TriageReport to parsing errors (see ParsingError.Triage) and to the
+	// ErrorMetadata of batch items that failed to parse.
+	Triage *bool `json:"-"`
+	// TextEncoding forces the charset and byte order mark handling of text inputs.
+	TextEncoding *TextEncodingConfig `json:"-"`
+	// QA generates question/answer pairs per section with a caller-supplied generator and stores
+	// them in the chunk metadata (see QAConfig).
+	QA *QAConfig `json:"-"`
+	// Idempotency selects where extractions carrying an idempotency key store their results
+	// (see WithIdempotencyKey).
+	Idempotency *IdempotencyConfig `json:"-"`
+	// Degradation selects whether an extraction requesting a feature that cannot run, such as OCR
+	// without Tesseract language data, fails or continues without it and records the skipped
+	// feature in Diagnostics (see DegradationPolicy). Empty leaves it to the native library.
+	Degradation DegradationPolicy `json:"-"`
+	// PasswordProvider supplies passwords for encrypted documents on demand, so they need not be
+	// stored in PdfOptions.Passwords (see PasswordProvider).
+	PasswordProvider PasswordProvider `json:"-"`
+	// DataFlow passes extracted content to a DLP engine before it is cached or returned, which
+	// allows, blocks or redacts it and records the decision in an audit log (see DataFlowConfig).
+	DataFlow *DataFlowConfig `json:"-"`
+	// Sanitize looks for likely prompt-injection content, such as hidden text or instructions
+	// addressed to a language model, and reports or removes it (see SanitizeConfig).
+	Sanitize *SanitizeConfig `json:"-"`
+	// HiddenText reports text PDFs render invisibly and can exclude it from the content (see
+	// HiddenTextConfig).
+	HiddenText *HiddenTextConfig `json:"-"`
 }
 
 // OCRConfig selects and configures OCR backends.
 type OCRConfig struct {
-	// Backend selects the OCR backend (e.g., "tesseract", "easyocr").
-	Backend string `json:"backend,omitempty"`
+	// Backend selects the OCR backend (default OCRBackendTesseract).
+	Backend OCRBackend `json:"backend,omitempty"`
 	// Language specifies the language for OCR (e.g., "eng", "deu").
 	Language *string `json:"language,omitempty"`
 	// Tesseract contains Tesseract-specific configuration options.
@@ -150,8 +261,8 @@ type ChunkingConfig struct {
 	ChunkSize *int `json:"chunk_size,omitempty"`
 	// ChunkOverlap is the number of overlapping characters between chunks.
 	ChunkOverlap *int `json:"chunk_overlap,omitempty"`
-	// Preset selects a predefined chunking strategy (e.g., "default", "semantic").
-	Preset *string `json:"preset,omitempty"`
+	// Preset selects a predefined chunking strategy (e.g., ChunkingPresetBalanced).
+	Preset ChunkingPreset `json:"preset,omitempty"`
 	// Embedding configures embedding generation for chunks.
 	Embedding *EmbeddingConfig `json:"embedding,omitempty"`
 	// Enabled enables or disables chunking.
@@ -186,7 +297,8 @@ type FontConfig struct {
 type PdfConfig struct {
 	// ExtractImages enables image extraction from PDFs.
 	ExtractImages *bool `json:"extract_images,omitempty"`
-	// Passwords provides password(s) for encrypted PDFs (tried in order).
+	// Passwords provides password(s) for encrypted PDFs (tried in order). To fetch them only
+	// when a document needs them, use ExtractionConfig.PasswordProvider instead.
 	Passwords []string `json:"passwords,omitempty"`
 	// ExtractMetadata enables extraction of PDF metadata.
 	ExtractMetadata *bool `json:"extract_metadata,omitempty"`
@@ -196,8 +308,8 @@ type PdfConfig struct {
 
 // TokenReductionConfig governs token pruning before embeddings.
 type TokenReductionConfig struct {
-	// Mode selects the token reduction strategy (e.g., "aggressive", "conservative").
-	Mode string `json:"mode,omitempty"`
+	// Mode selects the token reduction level (e.g., TokenReductionModerate).
+	Mode TokenReductionMode `json:"mode,omitempty"`
 	// PreserveImportantWords preserves semantically important words during reduction.
 	PreserveImportantWords *bool `json:"preserve_important_words,omitempty"`
 }
@@ -286,8 +398,8 @@ type RakeParams struct {
 type HTMLPreprocessingOptions struct {
 	// Enabled enables HTML preprocessing.
 	Enabled *bool `json:"enabled,omitempty"`
-	// Preset selects a preprocessing strategy (e.g., "aggressive", "conservative").
-	Preset *string `json:"preset,omitempty"`
+	// Preset selects a preprocessing strategy (e.g., HTMLPreprocessingStandard).
+	Preset HTMLPreprocessingPreset `json:"preset,omitempty"`
 	// RemoveNavigation removes navigation elements from HTML.
 	RemoveNavigation *bool `json:"remove_navigation,omitempty"`
 	// RemoveForms removes form elements from HTML.
@@ -377,14 +489,14 @@ func ConfigFromJSON(jsonStr string) (*ExtractionConfig, error) {
 		return nil, newValidationErrorWithContext("JSON string cannot be empty", nil, ErrorCodeValidation, nil)
 	}
 
-	cJSON := C.CString(jsonStr)
-	defer C.free(unsafe.Pointer(cJSON))
+	cJSON := newCString(jsonStr)
+	defer freeCBuffer(unsafe.Pointer(cJSON))
 
-	ptr := C.kreuzberg_config_from_json(cJSON)
+	ptr := trackNativeConfig(C.kreuzberg_config_from_json(cJSON))
 	if ptr == nil {
 		return nil, lastError()
 	}
-	defer C.kreuzberg_config_free(ptr)
+	defer freeNativeConfig(ptr)
 
 	// Parse the config back from JSON to populate Go struct
 	cfg := &ExtractionConfig{}
@@ -401,8 +513,8 @@ func IsValidJSON(jsonStr string) bool {
 		return false
 	}
 
-	cJSON := C.CString(jsonStr)
-	defer C.free(unsafe.Pointer(cJSON))
+	cJSON := newCString(jsonStr)
+	defer freeCBuffer(unsafe.Pointer(cJSON))
 
 	result := int32(C.kreuzberg_config_is_valid(cJSON))
 	return result == 1
@@ -422,21 +534,21 @@ func ConfigToJSON(config *ExtractionConfig) (string, error) {
 
 	// Create a C config from JSON to get the serialized representation
 	jsonStr := string(data)
-	cJSON := C.CString(jsonStr)
-	defer C.free(unsafe.Pointer(cJSON))
+	cJSON := newCString(jsonStr)
+	defer freeCBuffer(unsafe.Pointer(cJSON))
 
-	ptr := C.kreuzberg_config_from_json(cJSON)
+	ptr := trackNativeConfig(C.kreuzberg_config_from_json(cJSON))
 	if ptr == nil {
 		return "", lastError()
 	}
-	defer C.kreuzberg_config_free(ptr)
+	defer freeNativeConfig(ptr)
 
 	// Get the serialized form from the FFI
-	cSerialized := C.kreuzberg_config_to_json(ptr)
+	cSerialized := trackFFIAlloc(FFIResourceString, C.kreuzberg_config_to_json(ptr))
 	if cSerialized == nil {
 		return "", lastError()
 	}
-	defer C.kreuzberg_free_string(cSerialized)
+	defer freeNativeString(cSerialized)
 
 	return C.GoString(cSerialized), nil
 }
@@ -458,23 +570,23 @@ func ConfigGetField(config *ExtractionConfig, fieldName string) (interface{}, er
 		return nil, newSerializationErrorWithContext("failed to encode config", err, ErrorCodeValidation, nil)
 	}
 
-	cJSON := C.CString(string(data))
-	defer C.free(unsafe.Pointer(cJSON))
+	cJSON := newCString(string(data))
+	defer freeCBuffer(unsafe.Pointer(cJSON))
 
-	ptr := C.kreuzberg_config_from_json(cJSON)
+	ptr := trackNativeConfig(C.kreuzberg_config_from_json(cJSON))
 	if ptr == nil {
 		return nil, lastError()
 	}
-	defer C.kreuzberg_config_free(ptr)
+	defer freeNativeConfig(ptr)
 
-	cFieldName := C.CString(fieldName)
-	defer C.free(unsafe.Pointer(cFieldName))
+	cFieldName := newCString(fieldName)
+	defer freeCBuffer(unsafe.Pointer(cFieldName))
 
-	cValue := C.kreuzberg_config_get_field(ptr, cFieldName)
+	cValue := trackFFIAlloc(FFIResourceString, C.kreuzberg_config_get_field(ptr, cFieldName))
 	if cValue == nil {
 		return nil, newValidationErrorWithContext(fmt.Sprintf("field not found: %s", fieldName), nil, ErrorCodeValidation, nil)
 	}
-	defer C.kreuzberg_free_string(cValue)
+	defer freeNativeString(cValue)
 
 	jsonStr := C.GoString(cValue)
 	var value interface{}
@@ -486,6 +598,7 @@ func ConfigGetField(config *ExtractionConfig, fieldName string) (interface{}, er
 
 // ConfigMerge merges an override config into a base config.
 // Non-nil/default fields from override are copied into base.
+// Nested structs are replaced as a whole; see MergeConfigs for a deep merge.
 // Returns an error if the merge fails.
 func ConfigMerge(base, override *ExtractionConfig) error {
 	if base == nil {
@@ -538,6 +651,111 @@ func ConfigMerge(base, override *ExtractionConfig) error {
 	if override.MaxConcurrentExtractions != nil {
 		base.MaxConcurrentExtractions = override.MaxConcurrentExtractions
 	}
+	if override.Labels != nil {
+		base.Labels = override.Labels
+	}
+	if override.ValidationPolicy != nil {
+		base.ValidationPolicy = override.ValidationPolicy
+	}
+	if override.Fallback != nil {
+		base.Fallback = override.Fallback
+	}
+	if override.CanonicalMarkdown != nil {
+		base.CanonicalMarkdown = override.CanonicalMarkdown
+	}
+	if override.SourceAnchors != nil {
+		base.SourceAnchors = override.SourceAnchors
+	}
+	if override.Spreadsheet != nil {
+		base.Spreadsheet = override.Spreadsheet
+	}
+	if override.CSV != nil {
+		base.CSV = override.CSV
+	}
+	if override.DataFiles != nil {
+		base.DataFiles = override.DataFiles
+	}
+	if override.Database != nil {
+		base.Database = override.Database
+	}
+	if override.StructuredData != nil {
+		base.StructuredData = override.StructuredData
+	}
+	if override.Logs != nil {
+		base.Logs = override.Logs
+	}
+	if override.XMLProfile != nil {
+		base.XMLProfile = override.XMLProfile
+	}
+	if override.CacheEncryption != nil {
+		base.CacheEncryption = override.CacheEncryption
+	}
+	if override.OCRAutoTune != nil {
+		base.OCRAutoTune = override.OCRAutoTune
+	}
+	if override.Router != nil {
+		base.Router = override.Router
+	}
+	if override.ContentLimit != nil {
+		base.ContentLimit = override.ContentLimit
+	}
+	if override.TextStatistics != nil {
+		base.TextStatistics = override.TextStatistics
+	}
+	if override.MetadataProvenance != nil {
+		base.MetadataProvenance = override.MetadataProvenance
+	}
+	if override.XMP != nil {
+		base.XMP = override.XMP
+	}
+	if override.OfficeCustomProperties != nil {
+		base.OfficeCustomProperties = override.OfficeCustomProperties
+	}
+	if override.OfficeStats != nil {
+		base.OfficeStats = override.OfficeStats
+	}
+	if override.Accessibility != nil {
+		base.Accessibility = override.Accessibility
+	}
+	if override.LanguageHints != nil {
+		base.LanguageHints = override.LanguageHints
+	}
+	if override.PluginTimeout != 0 {
+		base.PluginTimeout = override.PluginTimeout
+	}
+	if override.StatisticsOnly != nil {
+		base.StatisticsOnly = override.StatisticsOnly
+	}
+	if override.DualRun != nil {
+		base.DualRun = override.DualRun
+	}
+	if override.Triage != nil {
+		base.Triage = override.Triage
+	}
+	if override.TextEncoding != nil {
+		base.TextEncoding = override.TextEncoding
+	}
+	if override.QA != nil {
+		base.QA = override.QA
+	}
+	if override.Idempotency != nil {
+		base.Idempotency = override.Idempotency
+	}
+	if override.Degradation != "" {
+		base.Degradation = override.Degradation
+	}
+	if override.PasswordProvider != nil {
+		base.PasswordProvider = override.PasswordProvider
+	}
+	if override.DataFlow != nil {
+		base.DataFlow = override.DataFlow
+	}
+	if override.Sanitize != nil {
+		base.Sanitize = override.Sanitize
+	}
+	if override.HiddenText != nil {
+		base.HiddenText = override.HiddenText
+	}
 
 	return nil
 }
@@ -0,0 +1,251 @@
+package kreuzberg
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// ConfigFieldStatus classifies a field reported by DiffConfig.
+type ConfigFieldStatus string
+
+const (
+	// ConfigFieldChanged marks a field whose value differs from the library default.
+	ConfigFieldChanged ConfigFieldStatus = "changed"
+	// ConfigFieldRedundant marks a field set explicitly to the library default.
+	ConfigFieldRedundant ConfigFieldStatus = "redundant"
+	// ConfigFieldSet marks a field that is set while the native defaults are unavailable, so it
+	// cannot be compared.
+	ConfigFieldSet ConfigFieldStatus = "set"
+)
+
+// ConfigField describes one field set in an ExtractionConfig.
+type ConfigField struct {
+	// Path is the dotted JSON path of the field (e.g. "ocr.tesseract_config.psm"). Go-only
+	// fields, which have no JSON form, are reported by their Go field name (e.g. "CSV").
+	Path string `json:"path"`
+	// Value is the JSON value of the field, or nil when it cannot be encoded (e.g. callbacks).
+	Value any `json:"value"`
+	// Default is the library default of the field, or nil when there is none.
+	Default any               `json:"default"`
+	Status  ConfigFieldStatus `json:"status"`
+	// Ignored reports whether the field has no effect on documents of the report's MIME type.
+	Ignored bool `json:"ignored"`
+	// Reason explains why the field is ignored.
+	Reason string `json:"reason,omitempty"`
+}
+
+// ConfigReport is the result of DiffConfig.
+type ConfigReport struct {
+	// MimeType is the MIME type the fields were checked against (empty: not checked).
+	MimeType string `json:"mime_type,omitempty"`
+	// DefaultsKnown reports whether the native defaults could be loaded; without them, native
+	// fields are reported with ConfigFieldSet.
+	DefaultsKnown bool `json:"defaults_known"`
+	// Fields lists the set fields, sorted by path.
+	Fields []ConfigField `json:"fields"`
+}
+
+// Ignored returns the fields that have no effect on documents of the report's MIME type.
+func (r *ConfigReport) Ignored() []ConfigField {
+	var ignored []ConfigField
+	for _, f := range r.Fields {
+		if f.Ignored {
+			ignored = append(ignored, f)
+		}
+	}
+	return ignored
+}
+
+// String renders the report one field per line, e.g. for logs.
+func (r *ConfigReport) String() string {
+	var b strings.Builder
+	for _, f := range r.Fields {
+		value, _ := json.Marshal(f.Value)
+		fmt.Fprintf(&b, "%s = %s (%s", f.Path, value, f.Status)
+		if f.Status == ConfigFieldChanged {
+			def, _ := json.Marshal(f.Default)
+			fmt.Fprintf(&b, ", default %s", def)
+		}
+		if f.Ignored {
+			fmt.Fprintf(&b, "; %s", f.Reason)
+		}
+		b.WriteString(")\n")
+	}
+	return b.String()
+}
+
+// DiffConfig reports which fields of config are set, whether they differ from the library
+// defaults, and, when mimeType is given, which of them have no effect on documents of that type
+// (e.g. OCR options for a DOCX). It performs no extraction; use it to debug configurations that
+// seem to do nothing.
+//
+// Native fields are compared against the defaults of the native library. When these cannot be
+// loaded, the report still lists the set fields, with DefaultsKnown false. Go-only fields
+// default to unset, so any value of theirs is a change.
+func DiffConfig(config *ExtractionConfig, mimeType string) (*ConfigReport, error) {
+	if config == nil {
+		return nil, newValidationErrorWithContext("config cannot be nil", nil, ErrorCodeValidation, nil)
+	}
+	set, err := flattenConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	report := &ConfigReport{MimeType: strings.ToLower(strings.TrimSpace(mimeType))}
+	var defaults map[string]any
+	if raw, err := ConfigToJSON(&ExtractionConfig{}); err == nil {
+		var decoded map[string]any
+		if json.Unmarshal([]byte(raw), &decoded) == nil {
+			defaults = map[string]any{}
+			flattenJSON("", decoded, defaults)
+			report.DefaultsKnown = true
+		}
+	}
+
+	for _, path := range slices.Sorted(maps.Keys(set)) {
+		field := ConfigField{Path: path, Value: set[path], Status: ConfigFieldSet}
+		if report.DefaultsKnown {
+			field.Default = defaults[path]
+			field.Status = ConfigFieldChanged
+			if reflect.DeepEqual(field.Value, field.Default) {
+				field.Status = ConfigFieldRedundant
+			}
+		}
+		report.Fields = append(report.Fields, field)
+	}
+	report.Fields = append(report.Fields, goOnlyConfigFields(config)...)
+
+	if report.MimeType != "" {
+		for i := range report.Fields {
+			f := &report.Fields[i]
+			f.Reason = ignoredReason(config, f.Path, report.MimeType)
+			f.Ignored = f.Reason != ""
+		}
+	}
+	return report, nil
+}
+
+// flattenConfig returns the native fields of config keyed by dotted path.
+func flattenConfig(config *ExtractionConfig) (map[string]any, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, newSerializationErrorWithContext("failed to encode config", err, ErrorCodeValidation, nil)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, newSerializationErrorWithContext("failed to decode config", err, ErrorCodeValidation, nil)
+	}
+	flat := map[string]any{}
+	flattenJSON("", decoded, flat)
+	return flat, nil
+}
+
+// flattenJSON adds the leaves of value to out. Empty objects are kept as leaves, since setting
+// one (e.g. OCR: &OCRConfig{}) still enables the feature.
+func flattenJSON(prefix string, value any, out map[string]any) {
+	object, ok := value.(map[string]any)
+	if !ok || (len(object) == 0 && prefix != "") {
+		out[prefix] = value
+		return
+	}
+	for key, v := range object {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		flattenJSON(key, v, out)
+	}
+}
+
+// goOnlyConfigFields reports the set fields of config that are not passed to the native library.
+func goOnlyConfigFields(config *ExtractionConfig) []ConfigField {
+	var fields []ConfigField
+	v := reflect.ValueOf(config).Elem()
+	for i := range v.NumField() {
+		sf := v.Type().Field(i)
+		if sf.Tag.Get("json") != "-" || v.Field(i).IsZero() {
+			continue
+		}
+		field := ConfigField{Path: sf.Name, Status: ConfigFieldChanged}
+		if data, err := json.Marshal(v.Field(i).Interface()); err == nil {
+			json.Unmarshal(data, &field.Value)
+		}
+		fields = append(fields, field)
+	}
+	slices.SortFunc(fields, func(a, b ConfigField) int { return strings.Compare(a.Path, b.Path) })
+	return fields
+}
+
+var ooxmlMimeTypes = []string{
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	mimeXLSX,
+	mimeXLSM,
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation",
+}
+
+// configScopes maps top-level config fields that only affect some document types to a check of
+// whether they apply to a MIME type and a description of the types they apply to.
+var configScopes = map[string]struct {
+	applies func(config *ExtractionConfig, mimeType string) bool
+	scope   string
+}{
+	"ocr":                    {ocrApplies, "OCR only runs on PDFs and images"},
+	"force_ocr":              {ocrApplies, "OCR only runs on PDFs and images"},
+	"OCRAutoTune":            {ocrApplies, "OCR only runs on PDFs and images"},
+	"pdf_options":            {isMime("application/pdf"), "it only applies to PDFs"},
+	"html_options":           {isMime("text/html", "application/xhtml+xml"), "it only applies to HTML"},
+	"pages":                  {pagesApply, "only PDFs and presentations have pages"},
+	"OfficeCustomProperties": {isMime(ooxmlMimeTypes...), "it only applies to DOCX, XLSX and PPTX"},
+	"OfficeStats":            {isMime(ooxmlMimeTypes...), "it only applies to DOCX, XLSX and PPTX"},
+	"CSV":                    {goExtractorApplies("csv"), "it only applies to CSV and TSV"},
+	"Spreadsheet":            {goExtractorApplies("xlsx-streaming"), "it only applies to XLSX and XLSM"},
+	"DataFiles":              {goExtractorApplies("parquet", "avro", "orc"), "it only applies to Parquet, Avro and ORC"},
+	"Database":               {goExtractorApplies("sqlite", "access"), "it only applies to SQLite and Access databases"},
+	"StructuredData":         {goExtractorApplies("structured-data"), "it only applies to JSON and YAML"},
+	"Logs":                   {goExtractorApplies("logs"), "it only applies to log files"},
+	"XMLProfile":             {goExtractorApplies("xml-profiles"), "it only applies to XML"},
+	"TextEncoding":           {textEncodingApplies, "it only applies to its MimeTypes"},
+}
+
+// ignoredReason explains why the field at path has no effect on mimeType, or returns "".
+func ignoredReason(config *ExtractionConfig, path, mimeType string) string {
+	top, _, _ := strings.Cut(path, ".")
+	scope, ok := configScopes[top]
+	if !ok || scope.applies(config, mimeType) {
+		return ""
+	}
+	return fmt.Sprintf("ignored for %s: %s", mimeType, scope.scope)
+}
+
+func isMime(mimeTypes ...string) func(*ExtractionConfig, string) bool {
+	return func(_ *ExtractionConfig, mimeType string) bool { return claimsMime(mimeTypes, mimeType) }
+}
+
+func ocrApplies(_ *ExtractionConfig, mimeType string) bool {
+	return mimeType == "application/pdf" || strings.HasPrefix(mimeType, "image/")
+}
+
+func pagesApply(_ *ExtractionConfig, mimeType string) bool {
+	return mimeType == "application/pdf" || strings.Contains(mimeType, "presentation") || mimeType == "application/vnd.ms-powerpoint"
+}
+
+func goExtractorApplies(names ...string) func(*ExtractionConfig, string) bool {
+	return func(_ *ExtractionConfig, mimeType string) bool {
+		for _, extractor := range goPrimaryExtractors {
+			if slices.Contains(names, extractor.name) && claimsMime(extractor.mimeTypes, mimeType) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func textEncodingApplies(config *ExtractionConfig, mimeType string) bool {
+	mimeTypes := config.TextEncoding.MimeTypes
+	if len(mimeTypes) == 0 {
+		mimeTypes = defaultTextEncodingMimeTypes
+	}
+	return claimsMime(mimeTypes, mimeType)
+}
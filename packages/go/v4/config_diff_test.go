@@ -0,0 +1,78 @@
+package kreuzberg
+
+import (
+	"strings"
+	"testing"
+)
+
+func configFieldsByPath(report *ConfigReport) map[string]ConfigField {
+	fields := map[string]ConfigField{}
+	for _, f := range report.Fields {
+		fields[f.Path] = f
+	}
+	return fields
+}
+
+func TestDiffConfigReportsIgnoredFields(t *testing.T) {
+	psm := 6
+	force := true
+	config := &ExtractionConfig{
+		OCR:      &OCRConfig{Backend: "tesseract", Tesseract: &TesseractConfig{PSM: &psm}},
+		ForceOCR: &force,
+		Chunking: &ChunkingConfig{},
+		CSV:      &CSVConfig{Delimiter: ';'},
+	}
+	report, err := DiffConfig(config, "application/vnd.openxmlformats-officedocument.wordprocessingml.document")
+	if err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+	fields := configFieldsByPath(report)
+	for _, path := range []string{"ocr.backend", "ocr.tesseract_config.psm", "force_ocr", "CSV"} {
+		if f, ok := fields[path]; !ok || !f.Ignored || f.Reason == "" {
+			t.Fatalf("expected %s to be ignored for DOCX, got %+v", path, f)
+		}
+	}
+	if f := fields["chunking"]; f.Ignored || f.Value == nil {
+		t.Fatalf("expected chunking to apply, got %+v", f)
+	}
+	if csv := fields["CSV"]; csv.Status != ConfigFieldChanged || csv.Value.(map[string]any)["Delimiter"] != float64(';') {
+		t.Fatalf("unexpected Go-only field %+v", csv)
+	}
+	if len(report.Ignored()) != 4 || !strings.Contains(report.String(), "CSV = ") {
+		t.Fatalf("unexpected report:\n%s", report)
+	}
+
+	report, _ = DiffConfig(config, mimeCSV)
+	if ignored := report.Ignored(); len(ignored) != 3 || configFieldsByPath(report)["CSV"].Ignored {
+		t.Fatalf("expected only the OCR fields to be ignored for CSV, got %+v", ignored)
+	}
+	report, _ = DiffConfig(config, "image/png")
+	if ignored := report.Ignored(); len(ignored) != 1 || ignored[0].Path != "CSV" {
+		t.Fatalf("expected only CSV to be ignored for PNG, got %+v", ignored)
+	}
+	if report, _ := DiffConfig(config, ""); len(report.Ignored()) != 0 {
+		t.Fatalf("expected no ignored fields without a MIME type")
+	}
+}
+
+func TestDiffConfigStatuses(t *testing.T) {
+	if _, err := DiffConfig(nil, ""); err == nil {
+		t.Fatalf("expected an error for a nil config")
+	}
+	report, err := DiffConfig(&ExtractionConfig{}, "")
+	if err != nil || len(report.Fields) != 0 {
+		t.Fatalf("expected an empty report, got %+v, %v", report, err)
+	}
+
+	useCache := false
+	report, _ = DiffConfig(&ExtractionConfig{UseCache: &useCache}, "")
+	f := report.Fields[0]
+	switch {
+	case !report.DefaultsKnown:
+		if f.Status != ConfigFieldSet || f.Default != nil {
+			t.Fatalf("expected an uncompared field without native defaults, got %+v", f)
+		}
+	case f.Status != ConfigFieldChanged || f.Default != true:
+		t.Fatalf("expected use_cache to differ from the default, got %+v", f)
+	}
+}
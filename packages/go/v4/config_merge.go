@@ -0,0 +1,90 @@
+package kreuzberg
+
+import "reflect"
+
+// MergeConfigs returns a new config with override deep-merged over base, e.g. to apply small
+// per-request overrides to a service-wide config. Neither input is modified, and either may be
+// nil. Unlike ConfigMerge, which replaces nested structs as a whole, fields are merged
+// recursively:
+//
+//   - A nil pointer, map, slice or func in override inherits the value of base.
+//   - Nested config structs (e.g. OCR.Tesseract) are merged field by field.
+//   - Maps (e.g. Labels) are merged key by key; entries of override win.
+//   - Slices replace the value of base as a whole; a non-nil empty slice clears it.
+//   - Plain values (e.g. strings, PluginTimeout) override base when non-zero, since a zero
+//     value cannot be told apart from "unset".
+//   - Structs with unexported fields (e.g. caches) are taken as they are, not merged.
+//
+// The result owns its structs, scalar pointers, maps and slices, so changing it does not affect
+// base or override; map values, slice elements and shared objects such as caches are not copied.
+func MergeConfigs(base, override *ExtractionConfig) *ExtractionConfig {
+	merged := &ExtractionConfig{}
+	for _, config := range []*ExtractionConfig{base, override} {
+		if config != nil {
+			mergeValue(reflect.ValueOf(merged).Elem(), reflect.ValueOf(config).Elem())
+		}
+	}
+	return merged
+}
+
+// mergeValue merges src over dst, which holds values owned by the merge.
+func mergeValue(dst, src reflect.Value) {
+	switch src.Kind() {
+	case reflect.Pointer:
+		if src.IsNil() {
+			return
+		}
+		elem := src.Type().Elem()
+		if elem.Kind() == reflect.Struct && !mergeableStruct(elem) {
+			dst.Set(src)
+			return
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.New(elem))
+		}
+		if elem.Kind() == reflect.Struct {
+			mergeValue(dst.Elem(), src.Elem())
+		} else {
+			dst.Elem().Set(src.Elem())
+		}
+	case reflect.Struct:
+		if !mergeableStruct(src.Type()) {
+			if !src.IsZero() {
+				dst.Set(src)
+			}
+			return
+		}
+		for i := range src.NumField() {
+			mergeValue(dst.Field(i), src.Field(i))
+		}
+	case reflect.Map:
+		if src.IsNil() {
+			return
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMapWithSize(src.Type(), src.Len()))
+		}
+		for iter := src.MapRange(); iter.Next(); {
+			dst.SetMapIndex(iter.Key(), iter.Value())
+		}
+	case reflect.Slice:
+		if !src.IsNil() {
+			dst.Set(reflect.AppendSlice(reflect.MakeSlice(src.Type(), 0, src.Len()), src))
+		}
+	default:
+		if !src.IsZero() {
+			dst.Set(src)
+		}
+	}
+}
+
+// mergeableStruct reports whether every field of t is exported, so it can be merged field by
+// field.
+func mergeableStruct(t reflect.Type) bool {
+	for i := range t.NumField() {
+		if !t.Field(i).IsExported() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,53 @@
+package kreuzberg
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMergeConfigsDeepMerges(t *testing.T) {
+	psm, cache := 6, NewOCRTuningCache()
+	base := &ExtractionConfig{
+		OCR:         &OCRConfig{Backend: "tesseract", Tesseract: &TesseractConfig{Language: "eng", PSM: &psm}},
+		Labels:      map[string]string{"tenant": "a", "tier": "free"},
+		OCRAutoTune: &OCRAutoTuneConfig{PSMs: []int{3, 6}, Cache: cache},
+		Fallback:    &FallbackConfig{Chains: map[string][]FallbackStrategy{"*": {FallbackRepair}}},
+	}
+	override := &ExtractionConfig{
+		OCR:           &OCRConfig{Tesseract: &TesseractConfig{Language: "deu"}},
+		Labels:        map[string]string{"tier": "pro"},
+		OCRAutoTune:   &OCRAutoTuneConfig{PSMs: []int{11}},
+		PluginTimeout: time.Second,
+	}
+
+	merged := MergeConfigs(base, override)
+	if merged.OCR.Backend != "tesseract" || merged.OCR.Tesseract.Language != "deu" || *merged.OCR.Tesseract.PSM != 6 {
+		t.Fatalf("expected nested OCR settings to merge, got %+v / %+v", merged.OCR, merged.OCR.Tesseract)
+	}
+	if merged.Labels["tenant"] != "a" || merged.Labels["tier"] != "pro" {
+		t.Fatalf("expected labels to merge key by key, got %v", merged.Labels)
+	}
+	if !slices.Equal(merged.OCRAutoTune.PSMs, []int{11}) || merged.OCRAutoTune.Cache != cache {
+		t.Fatalf("expected slices to be replaced and caches shared, got %+v", merged.OCRAutoTune)
+	}
+	if merged.PluginTimeout != time.Second || merged.Fallback.Chains["*"][0] != FallbackRepair {
+		t.Fatalf("unexpected merge result %+v", merged)
+	}
+
+	// The result owns its values.
+	*merged.OCR.Tesseract.PSM = 11
+	merged.Labels["tenant"] = "b"
+	merged.OCRAutoTune.PSMs[0] = 4
+	if psm != 6 || base.Labels["tenant"] != "a" || override.OCRAutoTune.PSMs[0] != 11 || base.OCR.Tesseract.Language != "eng" {
+		t.Fatalf("expected the inputs to be left unchanged")
+	}
+
+	cleared := MergeConfigs(base, &ExtractionConfig{OCRAutoTune: &OCRAutoTuneConfig{PSMs: []int{}}})
+	if cleared.OCRAutoTune.PSMs == nil || len(cleared.OCRAutoTune.PSMs) != 0 {
+		t.Fatalf("expected an empty slice to clear the base value, got %v", cleared.OCRAutoTune.PSMs)
+	}
+	if MergeConfigs(nil, nil) == nil || MergeConfigs(base, nil).OCR.Tesseract == base.OCR.Tesseract {
+		t.Fatalf("expected a fresh copy of a single config")
+	}
+}
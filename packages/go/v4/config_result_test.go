@@ -686,3 +686,34 @@ func TestResultFromJSON(t *testing.T) {
 		t.Error("Success should be true")
 	}
 }
+
+func TestTableCellBounds(t *testing.T) {
+	raw := `{"content":"","mime_type":"application/pdf","metadata":{},"success":true,"tables":[{
+		"cells":[["a","b"],["","d"]],"markdown":"","page_number":1,
+		"cell_bounds":[
+			[{"left":10,"top":20,"right":50,"bottom":30},{"left":60,"top":20,"right":90,"bottom":30}],
+			[null,{"left":60,"top":40,"right":95,"bottom":52}]
+		]}]}`
+
+	result, err := kreuzberg.ResultFromJSON(raw)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	table := result.Tables[0]
+
+	if box, ok := table.CellBoundsAt(0, 1); !ok || box != (kreuzberg.BoundingBox{Left: 60, Top: 20, Right: 90, Bottom: 30}) {
+		t.Fatalf("unexpected bounds for (0,1): %+v", box)
+	}
+	if _, ok := table.CellBoundsAt(1, 0); ok {
+		t.Fatalf("cell without geometry should report no bounds")
+	}
+	if _, ok := table.CellBoundsAt(5, 5); ok {
+		t.Fatalf("out-of-range cell should report no bounds")
+	}
+	if bounds, ok := table.Bounds(); !ok || bounds != (kreuzberg.BoundingBox{Left: 10, Top: 20, Right: 95, Bottom: 52}) {
+		t.Fatalf("unexpected table bounds: %+v", bounds)
+	}
+	if _, ok := (&kreuzberg.Table{}).Bounds(); ok {
+		t.Fatalf("table without geometry should have no bounds")
+	}
+}
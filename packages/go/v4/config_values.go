@@ -0,0 +1,268 @@
+package kreuzberg
+
+/*
+#include "internal/ffi/kreuzberg.h"
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"unsafe"
+)
+
+// OCRBackend names an OCR backend. Besides the built-in backends, the names of backends
+// registered with RegisterOCRBackend are valid.
+type OCRBackend string
+
+const (
+	// OCRBackendTesseract is the Tesseract backend, the default.
+	OCRBackendTesseract OCRBackend = "tesseract"
+	// OCRBackendEasyOCR is the EasyOCR backend, available when registered by the Python binding.
+	OCRBackendEasyOCR OCRBackend = "easyocr"
+	// OCRBackendPaddleOCR is the PaddleOCR backend, available when registered by the Python
+	// binding.
+	OCRBackendPaddleOCR OCRBackend = "paddleocr"
+)
+
+// builtinOCRBackends lists the backends known to the native library.
+var builtinOCRBackends = []OCRBackend{OCRBackendTesseract, OCRBackendEasyOCR, OCRBackendPaddleOCR}
+
+// Validate reports an error for a backend that is not available (see ListAvailableOCRBackends),
+// e.g. EasyOCR in a process where the Python binding did not register it. The empty backend
+// selects the default and is valid.
+func (b OCRBackend) Validate() error {
+	if b == "" {
+		return nil
+	}
+	backends, err := ListAvailableOCRBackends()
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, backend := range backends {
+		if !backend.Available {
+			continue
+		}
+		if backend.Name == b {
+			return nil
+		}
+		names = append(names, string(backend.Name))
+	}
+	return invalidConfigValue("OCR backend", string(b), names)
+}
+
+// ChunkingPreset names a chunking preset, which sets the chunk size and overlap together with
+// the embedding model of the same name (see ListEmbeddingPresets).
+type ChunkingPreset string
+
+const (
+	// ChunkingPresetFast uses small chunks and a small, fast embedding model.
+	ChunkingPresetFast ChunkingPreset = "fast"
+	// ChunkingPresetBalanced trades quality against speed; it is the default embedding preset.
+	ChunkingPresetBalanced ChunkingPreset = "balanced"
+	// ChunkingPresetQuality uses large chunks and a large embedding model.
+	ChunkingPresetQuality ChunkingPreset = "quality"
+	// ChunkingPresetMultilingual uses a multilingual embedding model.
+	ChunkingPresetMultilingual ChunkingPreset = "multilingual"
+)
+
+var chunkingPresets = []ChunkingPreset{ChunkingPresetFast, ChunkingPresetBalanced, ChunkingPresetQuality, ChunkingPresetMultilingual}
+
+// Validate reports an error for an unknown preset. The empty preset is valid.
+func (p ChunkingPreset) Validate() error {
+	if p == "" || slices.Contains(chunkingPresets, p) {
+		return nil
+	}
+	return invalidConfigValue("chunking preset", string(p), stringValues(chunkingPresets))
+}
+
+// HTMLPreprocessingPreset names how aggressively HTML is cleaned before conversion.
+type HTMLPreprocessingPreset string
+
+const (
+	// HTMLPreprocessingMinimal removes only elements that never carry content, such as scripts.
+	HTMLPreprocessingMinimal HTMLPreprocessingPreset = "minimal"
+	// HTMLPreprocessingStandard also removes navigation, forms and other page chrome.
+	HTMLPreprocessingStandard HTMLPreprocessingPreset = "standard"
+	// HTMLPreprocessingAggressive keeps little beyond the main content.
+	HTMLPreprocessingAggressive HTMLPreprocessingPreset = "aggressive"
+)
+
+var htmlPreprocessingPresets = []HTMLPreprocessingPreset{HTMLPreprocessingMinimal, HTMLPreprocessingStandard, HTMLPreprocessingAggressive}
+
+// Validate reports an error for an unknown preset. The empty preset is valid.
+func (p HTMLPreprocessingPreset) Validate() error {
+	if p == "" || slices.Contains(htmlPreprocessingPresets, p) {
+		return nil
+	}
+	return invalidConfigValue("HTML preprocessing preset", string(p), stringValues(htmlPreprocessingPresets))
+}
+
+// TokenReductionMode names a token reduction level.
+type TokenReductionMode string
+
+const (
+	TokenReductionOff        TokenReductionMode = "off"
+	TokenReductionLight      TokenReductionMode = "light"
+	TokenReductionModerate   TokenReductionMode = "moderate"
+	TokenReductionAggressive TokenReductionMode = "aggressive"
+	TokenReductionMaximum    TokenReductionMode = "maximum"
+)
+
+var tokenReductionModes = []TokenReductionMode{TokenReductionOff, TokenReductionLight, TokenReductionModerate, TokenReductionAggressive, TokenReductionMaximum}
+
+// Validate reports an error for an unknown mode. The empty mode is valid.
+func (m TokenReductionMode) Validate() error {
+	if m == "" || slices.Contains(tokenReductionModes, m) {
+		return nil
+	}
+	return invalidConfigValue("token reduction mode", string(m), stringValues(tokenReductionModes))
+}
+
+// validateConfigValues checks the named values of config before it is passed to the native
+// library, which would otherwise ignore a misspelled name or fall back to a default.
+func validateConfigValues(config *ExtractionConfig) error {
+	if config == nil {
+		return nil
+	}
+	if config.OCR != nil {
+		if err := config.OCR.Backend.Validate(); err != nil {
+			return err
+		}
+	}
+	if config.Chunking != nil {
+		if err := config.Chunking.Preset.Validate(); err != nil {
+			return err
+		}
+	}
+	if config.TokenReduction != nil {
+		if err := config.TokenReduction.Mode.Validate(); err != nil {
+			return err
+		}
+	}
+	if config.HTMLOptions != nil && config.HTMLOptions.Preprocessing != nil {
+		if err := config.HTMLOptions.Preprocessing.Preset.Validate(); err != nil {
+			return err
+		}
+	}
+	if config.Sanitize != nil {
+		if err := config.Sanitize.Action.Validate(); err != nil {
+			return err
+		}
+	}
+	if config.Fallback != nil {
+		if _, err := config.Fallback.normalizedChains(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OCRBackendInfo describes an OCR backend.
+type OCRBackendInfo struct {
+	Name OCRBackend `json:"name"`
+	// BuiltIn reports whether the backend ships with Kreuzberg rather than a plugin.
+	BuiltIn bool `json:"built_in"`
+	// Available reports whether the backend is registered with the native library and can be
+	// selected.
+	Available bool `json:"available"`
+	// Languages lists the language codes the backend supports, when known.
+	Languages []string `json:"languages,omitempty"`
+}
+
+// ListAvailableOCRBackends describes the built-in OCR backends followed by the registered plugin
+// backends.
+func ListAvailableOCRBackends() ([]OCRBackendInfo, error) {
+	registered, err := ListOCRBackends()
+	if err != nil {
+		return nil, err
+	}
+	var infos []OCRBackendInfo
+	for _, backend := range builtinOCRBackends {
+		infos = append(infos, OCRBackendInfo{Name: backend, BuiltIn: true, Available: slices.Contains(registered, string(backend))})
+	}
+	slices.Sort(registered)
+	for _, name := range registered {
+		if !slices.Contains(builtinOCRBackends, OCRBackend(name)) {
+			infos = append(infos, OCRBackendInfo{Name: OCRBackend(name), Available: true})
+		}
+	}
+	for i := range infos {
+		languages, err := ocrBackendLanguages(infos[i].Name)
+		if err != nil {
+			return nil, err
+		}
+		infos[i].Languages = languages
+	}
+	return infos, nil
+}
+
+func ocrBackendLanguages(backend OCRBackend) ([]string, error) {
+	cBackend := newCString(string(backend))
+	defer freeCBuffer(unsafe.Pointer(cBackend))
+
+	listPtr := trackFFIAlloc(FFIResourceString, C.kreuzberg_get_ocr_languages(cBackend))
+	if listPtr == nil {
+		return nil, nil
+	}
+	defer freeNativeString(listPtr)
+
+	var languages []string
+	if err := json.Unmarshal([]byte(C.GoString(listPtr)), &languages); err != nil {
+		return nil, newSerializationErrorWithContext("failed to parse OCR backend languages", err, ErrorCodeValidation, nil)
+	}
+	return languages, nil
+}
+
+func invalidConfigValue(kind, value string, valid []string) error {
+	msg := fmt.Sprintf("invalid %s %q", kind, value)
+	if suggestion := closestName(value, valid); suggestion != "" {
+		msg += fmt.Sprintf(" (did you mean %q?)", suggestion)
+	} else {
+		msg += fmt.Sprintf(" (valid: %v)", valid)
+	}
+	return newValidationErrorWithContext(msg, nil, ErrorCodeValidation, nil)
+}
+
+// closestName returns the candidate within two edits of name, if any.
+func closestName(name string, candidates []string) string {
+	best, bestDistance := "", 3
+	for _, candidate := range candidates {
+		if d := editDistance(name, candidate); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b, in bytes.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func stringValues[T ~string](values []T) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = string(v)
+	}
+	return out
+}
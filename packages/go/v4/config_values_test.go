@@ -0,0 +1,70 @@
+package kreuzberg
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestConfigValuesValidate(t *testing.T) {
+	for _, err := range []error{
+		OCRBackend("").Validate(),
+		OCRBackendTesseract.Validate(),
+		ChunkingPresetMultilingual.Validate(),
+		HTMLPreprocessingStandard.Validate(),
+		TokenReductionMaximum.Validate(),
+	} {
+		if err != nil {
+			t.Fatalf("expected a valid value, got %v", err)
+		}
+	}
+
+	var validationErr *ValidationError
+	if err := OCRBackend("tessaract").Validate(); !errors.As(err, &validationErr) || !strings.Contains(err.Error(), `did you mean "tesseract"?`) {
+		t.Fatalf("expected a suggestion for a misspelled backend, got %v", err)
+	}
+	if registered, _ := ListOCRBackends(); !slices.Contains(registered, string(OCRBackendPaddleOCR)) {
+		if err := OCRBackendPaddleOCR.Validate(); !errors.As(err, &validationErr) {
+			t.Fatalf("expected a ValidationError for a backend that is not registered, got %v", err)
+		}
+	}
+	if err := TokenReductionMode("conservative").Validate(); err == nil || !strings.Contains(err.Error(), "valid: [off light moderate aggressive maximum]") {
+		t.Fatalf("expected the valid modes to be listed, got %v", err)
+	}
+
+	config := &ExtractionConfig{
+		OCR:         &OCRConfig{Backend: OCRBackendTesseract},
+		HTMLOptions: &HTMLConversionOptions{Preprocessing: &HTMLPreprocessingOptions{Preset: "agressive"}},
+	}
+	if _, _, err := newConfigJSON(config); err == nil || !strings.Contains(err.Error(), `invalid HTML preprocessing preset "agressive" (did you mean "aggressive"?)`) {
+		t.Fatalf("expected the config to be rejected before reaching the native library, got %v", err)
+	}
+
+	// The typed fields keep the JSON shape of the native config.
+	data, err := json.Marshal(&ExtractionConfig{Chunking: &ChunkingConfig{Preset: ChunkingPresetFast}, TokenReduction: &TokenReductionConfig{Mode: TokenReductionLight}})
+	if err != nil || !strings.Contains(string(data), `"preset":"fast"`) || !strings.Contains(string(data), `"mode":"light"`) {
+		t.Fatalf("unexpected JSON %s, %v", data, err)
+	}
+}
+
+func TestListAvailableOCRBackends(t *testing.T) {
+	backends, err := ListAvailableOCRBackends()
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(backends) < 3 {
+		t.Fatalf("expected the built-in backends, got %+v", backends)
+	}
+	for i, want := range builtinOCRBackends {
+		if backends[i].Name != want || !backends[i].BuiltIn {
+			t.Fatalf("unexpected backend %d: %+v", i, backends[i])
+		}
+	}
+	for _, backend := range backends[3:] {
+		if backend.BuiltIn || !backend.Available {
+			t.Fatalf("expected registered plugin backends to be available, got %+v", backend)
+		}
+	}
+}
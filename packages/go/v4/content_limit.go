@@ -0,0 +1,109 @@
+package kreuzberg
+
+import (
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+)
+
+// defaultTruncationMarker replaces the removed part of truncated content.
+const defaultTruncationMarker = "\n\n[content truncated]\n\n"
+
+// TruncationPolicy selects which part of oversized content is kept.
+type TruncationPolicy string
+
+const (
+	// TruncateHead keeps the beginning of the content.
+	TruncateHead TruncationPolicy = "head"
+	// TruncateTail keeps the end of the content.
+	TruncateTail TruncationPolicy = "tail"
+	// TruncateMiddle keeps the beginning and the end and removes the middle.
+	TruncateMiddle TruncationPolicy = "middle"
+)
+
+// ContentLimitConfig caps the size of ExtractionResult.Content for downstream systems with hard
+// payload limits. The limit is enforced after post processors and validators have run, and a
+// truncated result reports the policy and original length in
+// Metadata.Additional["truncation"] plus a warning diagnostic. Pages and Chunks are left as is.
+type ContentLimitConfig struct {
+	// MaxContentChars is the maximum length of Content in characters (Unicode code points),
+	// including the marker. Zero disables the limit.
+	MaxContentChars int
+	// Policy selects the part of the content that is kept (default TruncateHead).
+	Policy TruncationPolicy
+	// Marker is inserted where content was removed (default "\n\n[content truncated]\n\n"). Use
+	// an empty string for no marker. The marker is dropped if it does not fit the limit.
+	Marker *string
+}
+
+// truncationInfo is reported in Metadata.Additional["truncation"].
+type truncationInfo struct {
+	Policy        TruncationPolicy `json:"policy"`
+	OriginalChars int              `json:"original_chars"`
+	MaxChars      int              `json:"max_chars"`
+}
+
+// TruncateContent shortens content to at most cfg.MaxContentChars characters following
+// cfg.Policy and reports whether it was truncated.
+func TruncateContent(content string, cfg ContentLimitConfig) (string, bool, error) {
+	if cfg.MaxContentChars < 0 {
+		return "", false, newValidationErrorWithContext("MaxContentChars cannot be negative", nil, ErrorCodeValidation, nil)
+	}
+	policy := cfg.Policy
+	switch policy {
+	case "":
+		policy = TruncateHead
+	case TruncateHead, TruncateTail, TruncateMiddle:
+	default:
+		return "", false, newValidationErrorWithContext(fmt.Sprintf("unknown truncation policy %q", cfg.Policy), nil, ErrorCodeValidation, nil)
+	}
+	if cfg.MaxContentChars == 0 || utf8.RuneCountInString(content) <= cfg.MaxContentChars {
+		return content, false, nil
+	}
+
+	marker := defaultTruncationMarker
+	if cfg.Marker != nil {
+		marker = *cfg.Marker
+	}
+	if utf8.RuneCountInString(marker) > cfg.MaxContentChars {
+		marker = ""
+	}
+	runes := []rune(content)
+	keep := cfg.MaxContentChars - utf8.RuneCountInString(marker)
+	switch policy {
+	case TruncateHead:
+		return string(runes[:keep]) + marker, true, nil
+	case TruncateTail:
+		return marker + string(runes[len(runes)-keep:]), true, nil
+	default:
+		head := keep - keep/2
+		return string(runes[:head]) + marker + string(runes[len(runes)-keep/2:]), true, nil
+	}
+}
+
+// applyContentLimit enforces config.ContentLimit on result.
+func applyContentLimit(config *ExtractionConfig, result *ExtractionResult) error {
+	if config == nil || config.ContentLimit == nil {
+		return nil
+	}
+	cfg := *config.ContentLimit
+	original := utf8.RuneCountInString(result.Content)
+	content, truncated, err := TruncateContent(result.Content, cfg)
+	if err != nil || !truncated {
+		return err
+	}
+	if cfg.Policy == "" {
+		cfg.Policy = TruncateHead
+	}
+	raw, err := json.Marshal(truncationInfo{Policy: cfg.Policy, OriginalChars: original, MaxChars: cfg.MaxContentChars})
+	if err != nil {
+		return newSerializationErrorWithContext("failed to encode truncation metadata", err, ErrorCodeValidation, nil)
+	}
+	result.Content = content
+	if result.Metadata.Additional == nil {
+		result.Metadata.Additional = map[string]json.RawMessage{}
+	}
+	result.Metadata.Additional["truncation"] = raw
+	result.addDiagnostic("content_limit", DiagnosticSeverityWarning, fmt.Sprintf("content truncated from %d to %d characters (%s)", original, cfg.MaxContentChars, cfg.Policy))
+	return nil
+}
@@ -0,0 +1,70 @@
+package kreuzberg
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateContentPolicies(t *testing.T) {
+	marker := "~"
+	content := "αβγδεζηθικ" // 10 characters, 2 bytes each
+	cases := []struct {
+		policy TruncationPolicy
+		want   string
+	}{
+		{"", "αβγδ~"},
+		{TruncateHead, "αβγδ~"},
+		{TruncateTail, "~ηθικ"},
+		{TruncateMiddle, "αβ~ικ"},
+	}
+	for _, tc := range cases {
+		got, truncated, err := TruncateContent(content, ContentLimitConfig{MaxContentChars: 5, Policy: tc.policy, Marker: &marker})
+		if err != nil || !truncated || got != tc.want {
+			t.Errorf("%q: got %q, %v, %v; want %q", tc.policy, got, truncated, err, tc.want)
+		}
+	}
+
+	if got, truncated, _ := TruncateContent(content, ContentLimitConfig{MaxContentChars: 10}); truncated || got != content {
+		t.Fatalf("expected content within the limit to be kept, got %q", got)
+	}
+	if got, _, _ := TruncateContent(content, ContentLimitConfig{MaxContentChars: 3}); got != "αβγ" {
+		t.Fatalf("expected the default marker to be dropped when it does not fit, got %q", got)
+	}
+	var validationErr *ValidationError
+	if _, _, err := TruncateContent(content, ContentLimitConfig{MaxContentChars: 5, Policy: "random"}); !errors.As(err, &validationErr) {
+		t.Fatalf("expected a ValidationError for an unknown policy, got %v", err)
+	}
+}
+
+func TestContentLimitAppliesAfterPlugins(t *testing.T) {
+	client := NewClient(&ExtractionConfig{ContentLimit: &ContentLimitConfig{MaxContentChars: 40, Policy: TruncateTail}})
+	client.RegisterPostProcessor("grow", 0, func(pc *PluginContext, result *ExtractionResult) error {
+		result.Content += " -- appended by a post processor"
+		return nil
+	})
+	result, err := client.ExtractBytes(t.Context(), []byte(testSRT), mimeSRT)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if n := utf8.RuneCountInString(result.Content); n != 40 || !strings.HasSuffix(result.Content, "[content truncated]\n\n a post processor") {
+		t.Fatalf("expected the tail of the post-processed content, got %q (%d)", result.Content, n)
+	}
+	var info truncationInfo
+	if err := json.Unmarshal(result.Metadata.Additional["truncation"], &info); err != nil {
+		t.Fatalf("decode truncation metadata: %v", err)
+	}
+	if info.Policy != TruncateTail || info.MaxChars != 40 || info.OriginalChars != utf8.RuneCountInString(wantSRTContent)+32 {
+		t.Fatalf("unexpected truncation metadata: %+v", info)
+	}
+	if len(result.DiagnosticsBySeverity(DiagnosticSeverityWarning)) != 1 {
+		t.Fatalf("expected a warning diagnostic, got %+v", result.Diagnostics)
+	}
+
+	result, err = ExtractBytesSync([]byte(testSRT), mimeSRT, &ExtractionConfig{ContentLimit: &ContentLimitConfig{MaxContentChars: 1000}})
+	if err != nil || result.Content != wantSRTContent || result.Metadata.Additional["truncation"] != nil {
+		t.Fatalf("expected short content to pass through, got %v, %v", result, err)
+	}
+}
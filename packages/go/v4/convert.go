@@ -0,0 +1,131 @@
+package kreuzberg
+
+/*
+#include "internal/ffi/kreuzberg.h"
+#include <stdlib.h>
+#include <stdint.h>
+*/
+import "C"
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"runtime"
+	"time"
+	"unsafe"
+)
+
+// DefaultConversionTimeout bounds a ConvertDocument call when ctx carries no deadline.
+// It matches the timeout the core uses for its own LibreOffice conversions.
+const DefaultConversionTimeout = 300 * time.Second
+
+// ConvertDocument converts the file at inputPath to targetMime (e.g. "application/pdf") using
+// LibreOffice in headless mode, through the same native conversion the core uses for legacy
+// Office formats.
+//
+// The native library locates LibreOffice through KREUZBERG_LIBREOFFICE_PATH, SOFFICE_PATH,
+// LIBREOFFICE_PATH, well-known install locations, then PATH, and a MissingDependencyError is
+// returned when it cannot be found. The LibreOffice process is killed once ctx's deadline, or
+// DefaultConversionTimeout, passes; cancelling ctx without a deadline returns ctx's error right
+// away but leaves the process to finish. Conversions are throttled by the
+// DependencyLibreOffice limit (see SetDependencyLimit).
+func ConvertDocument(ctx context.Context, inputPath string, targetMime string) ([]byte, error) {
+	if inputPath == "" {
+		return nil, newValidationErrorWithContext("input path is required", nil, ErrorCodeValidation, nil)
+	}
+	if targetMime == "" {
+		return nil, newValidationErrorWithContext("target MIME type is required", nil, ErrorCodeValidation, nil)
+	}
+	return convertNative(ctx, func(timeout C.uint64_t) *C.CConvertedDocument {
+		cPath := newCString(inputPath)
+		defer freeCBuffer(unsafe.Pointer(cPath))
+		cTarget := newCString(targetMime)
+		defer freeCBuffer(unsafe.Pointer(cTarget))
+		return C.kreuzberg_convert_file(cPath, cTarget, timeout)
+	})
+}
+
+// ConvertDocumentBytes converts an in-memory document of type mimeType to targetMime.
+// See ConvertDocument for how LibreOffice is located and how ctx bounds the conversion.
+func ConvertDocumentBytes(ctx context.Context, data []byte, mimeType string, targetMime string) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, newValidationErrorWithContext("data cannot be empty", nil, ErrorCodeValidation, nil)
+	}
+	if mimeType == "" || targetMime == "" {
+		return nil, newValidationErrorWithContext("source and target MIME types are required", nil, ErrorCodeValidation, nil)
+	}
+	return convertNative(ctx, func(timeout C.uint64_t) *C.CConvertedDocument {
+		buf := newCBytes(data)
+		defer freeCBuffer(buf)
+		cSource := newCString(mimeType)
+		defer freeCBuffer(unsafe.Pointer(cSource))
+		cTarget := newCString(targetMime)
+		defer freeCBuffer(unsafe.Pointer(cTarget))
+		return C.kreuzberg_convert_bytes((*C.uint8_t)(buf), C.uintptr_t(len(data)), cSource, cTarget, timeout)
+	})
+}
+
+// conversionTimeout returns the time left to ctx, or DefaultConversionTimeout without a
+// deadline. The native timeout has a resolution of one second and 0 selects its default, so the
+// timeout is at least a second, also when the deadline passed just now.
+func conversionTimeout(ctx context.Context) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return DefaultConversionTimeout
+	}
+	return max(time.Until(deadline), time.Second)
+}
+
+// convertNative runs a native conversion with the timeout left to ctx, under the LibreOffice
+// dependency limit.
+func convertNative(ctx context.Context, convert func(timeout C.uint64_t) *C.CConvertedDocument) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	timeout := conversionTimeout(ctx)
+	release, err := AcquireDependency(ctx, DependencyLibreOffice)
+	if err != nil {
+		return nil, err
+	}
+
+	type outcome struct {
+		data []byte
+		err  error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		defer release()
+		// The native error is recorded per thread, so read it on the thread that converted.
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		defer pinNativeStack()()
+		doc := convert(C.uint64_t(math.Ceil(timeout.Seconds())))
+		if doc == nil {
+			done <- outcome{err: conversionError()}
+			return
+		}
+		defer C.kreuzberg_free_converted_document(doc)
+		done <- outcome{data: bytes.Clone(unsafe.Slice((*byte)(unsafe.Pointer(doc.data)), int(doc.len)))}
+	}()
+
+	select {
+	case out := <-done:
+		if out.err != nil && ctx.Err() != nil {
+			return nil, newParsingErrorWithContext("LibreOffice conversion did not complete", ctx.Err(), ErrorCodeParsing, nil)
+		}
+		return out.data, out.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// conversionError returns the error of the failed conversion on this thread. The native
+// library records conversion failures without an error code, so the message is classified.
+func conversionError() error {
+	errPtr := C.kreuzberg_last_error()
+	if errPtr == nil {
+		return newRuntimeErrorWithContext("document conversion failed", nil, ErrorCodeInternal, nil)
+	}
+	return classifyNativeError(C.GoString(errPtr), ErrorCode(C.kreuzberg_classify_error(errPtr)), nil)
+}
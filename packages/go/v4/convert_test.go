@@ -0,0 +1,99 @@
+package kreuzberg
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// fakeSoffice installs a shell script that answers `soffice --version` and mimics
+// `soffice --convert-to` by copying the input into --outdir with the target extension.
+func fakeSoffice(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake soffice script requires a POSIX shell")
+	}
+	script := `#!/bin/sh
+while [ $# -gt 0 ]; do
+  case "$1" in
+    --version) echo "LibreOffice 24.2"; exit 0 ;;
+    --convert-to) format="${2%%:*}"; shift 2 ;;
+    --outdir) outdir="$2"; shift 2 ;;
+    -*) shift ;;
+    *) input="$1"; shift ;;
+  esac
+done
+name=$(basename "$input"); stem="${name%.*}"
+cp "$input" "$outdir/$stem.$format"
+`
+	path := filepath.Join(t.TempDir(), "soffice")
+	if err := os.WriteFile(path, []byte(script), 0o700); err != nil {
+		t.Fatalf("write fake soffice: %v", err)
+	}
+	t.Setenv("KREUZBERG_LIBREOFFICE_PATH", path)
+}
+
+func TestConvertDocumentBytesRunsLibreOffice(t *testing.T) {
+	fakeSoffice(t)
+
+	out, err := ConvertDocumentBytes(context.Background(), []byte("<p>hi</p>"), "text/html", "application/pdf")
+	if err != nil {
+		t.Fatalf("convert: %v", err)
+	}
+	if string(out) != "<p>hi</p>" {
+		t.Fatalf("unexpected output: %q", out)
+	}
+}
+
+func TestConvertDocumentFromPath(t *testing.T) {
+	fakeSoffice(t)
+
+	input := filepath.Join(t.TempDir(), "report.docx")
+	if err := os.WriteFile(input, []byte("docx bytes"), 0o600); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	out, err := ConvertDocument(context.Background(), input, "application/pdf")
+	if err != nil || string(out) != "docx bytes" {
+		t.Fatalf("convert = %q, %v", out, err)
+	}
+}
+
+func TestConvertDocumentRejectsUnknownTargets(t *testing.T) {
+	_, err := ConvertDocumentBytes(context.Background(), []byte("x"), "text/plain", "image/png")
+	var unsupported *UnsupportedFormatError
+	if !errors.As(err, &unsupported) {
+		t.Fatalf("expected UnsupportedFormatError, got %T", err)
+	}
+}
+
+func TestConvertDocumentReportsMissingLibreOffice(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("well-known install locations differ per platform")
+	}
+	t.Setenv("KREUZBERG_LIBREOFFICE_PATH", "")
+	t.Setenv("SOFFICE_PATH", "")
+	t.Setenv("LIBREOFFICE_PATH", "")
+	t.Setenv("HOMEBREW_PREFIX", "")
+	t.Setenv("PATH", t.TempDir())
+
+	_, err := ConvertDocumentBytes(context.Background(), []byte("x"), "text/plain", "application/pdf")
+	var missing *MissingDependencyError
+	if !errors.As(err, &missing) {
+		t.Fatalf("expected MissingDependencyError, got %T: %v", err, err)
+	}
+}
+
+func TestConversionTimeout(t *testing.T) {
+	if timeout := conversionTimeout(context.Background()); timeout != DefaultConversionTimeout {
+		t.Fatalf("expected the default timeout without a deadline, got %v", timeout)
+	}
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Minute))
+	defer cancel()
+	if timeout := conversionTimeout(ctx); timeout != time.Second {
+		t.Fatalf("expected a passed deadline to give the shortest native timeout, got %v", timeout)
+	}
+}
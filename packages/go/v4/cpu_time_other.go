@@ -0,0 +1,10 @@
+//go:build !unix
+
+package kreuzberg
+
+import "time"
+
+// processCPUTime returns 0: process CPU time is not reported on this platform.
+func processCPUTime() time.Duration {
+	return 0
+}
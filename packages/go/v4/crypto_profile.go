@@ -0,0 +1,73 @@
+package kreuzberg
+
+import (
+	"crypto/cipher"
+	"crypto/fips140"
+	"sync/atomic"
+)
+
+// CryptoProfile names the cryptographic primitives the binding uses for result caching and
+// document fingerprinting (PluginContext.DocumentHash).
+type CryptoProfile string
+
+const (
+	// CryptoProfileStandard uses the Go standard library without restrictions.
+	CryptoProfileStandard CryptoProfile = "standard"
+	// CryptoProfileFIPS restricts hashing and encryption to FIPS 140-3 approved primitives of the
+	// Go Cryptographic Module: SHA-256 for fingerprints and cache keys, and AES-GCM with
+	// module-generated nonces for cache entries. Operations that need cryptography fail unless
+	// Go's FIPS 140-3 mode is enabled (GODEBUG=fips140=on, or a GOFIPS140 build).
+	CryptoProfileFIPS CryptoProfile = "fips140-3"
+)
+
+var cryptoProfile atomic.Value
+
+func init() {
+	profile := CryptoProfileStandard
+	if fipsBuild || fips140.Enabled() {
+		profile = CryptoProfileFIPS
+	}
+	cryptoProfile.Store(profile)
+}
+
+// ActiveCryptoProfile returns the crypto profile in effect. It is CryptoProfileFIPS in binaries
+// built with the kreuzberg_fips tag, when Go's FIPS 140-3 mode is enabled, or after
+// SetCryptoProfile(CryptoProfileFIPS); otherwise CryptoProfileStandard.
+func ActiveCryptoProfile() CryptoProfile {
+	return cryptoProfile.Load().(CryptoProfile)
+}
+
+// SetCryptoProfile switches the crypto profile for the process. Call it during startup, before
+// extractions run. The FIPS profile cannot be left in kreuzberg_fips builds or in Go FIPS 140-3
+// mode.
+func SetCryptoProfile(profile CryptoProfile) error {
+	switch profile {
+	case CryptoProfileFIPS:
+	case CryptoProfileStandard:
+		if fipsBuild || fips140.Enabled() {
+			return newValidationErrorWithContext("the FIPS crypto profile cannot be disabled in kreuzberg_fips builds or in Go FIPS 140-3 mode", nil, ErrorCodeValidation, nil)
+		}
+	default:
+		return newValidationErrorWithContext("unknown crypto profile: "+string(profile), nil, ErrorCodeValidation, nil)
+	}
+	cryptoProfile.Store(profile)
+	return nil
+}
+
+// checkCryptoProfile reports whether cryptographic operations may run under the active profile.
+func checkCryptoProfile() error {
+	if ActiveCryptoProfile() == CryptoProfileFIPS && !fips140.Enabled() {
+		return newValidationErrorWithContext("the FIPS crypto profile requires Go's FIPS 140-3 mode (GODEBUG=fips140=on)", nil, ErrorCodeValidation, nil)
+	}
+	return nil
+}
+
+// newCacheAEAD wraps block in AES-GCM. Under the FIPS profile the module generates the nonces, as
+// FIPS 140-3 requires; both variants produce entries laid out as nonce | ciphertext | tag, so
+// caches remain readable when the profile changes.
+func newCacheAEAD(block cipher.Block) (cipher.AEAD, error) {
+	if ActiveCryptoProfile() == CryptoProfileFIPS {
+		return cipher.NewGCMWithRandomNonce(block)
+	}
+	return cipher.NewGCM(block)
+}
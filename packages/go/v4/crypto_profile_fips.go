@@ -0,0 +1,6 @@
+//go:build kreuzberg_fips
+
+package kreuzberg
+
+// fipsBuild pins the FIPS crypto profile in binaries built with -tags kreuzberg_fips.
+const fipsBuild = true
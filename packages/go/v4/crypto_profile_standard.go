@@ -0,0 +1,5 @@
+//go:build !kreuzberg_fips
+
+package kreuzberg
+
+const fipsBuild = false
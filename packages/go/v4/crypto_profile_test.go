@@ -0,0 +1,80 @@
+package kreuzberg
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/fips140"
+	"errors"
+	"testing"
+)
+
+func TestCryptoProfileSwitching(t *testing.T) {
+	if fipsBuild || fips140.Enabled() {
+		t.Skip("FIPS profile is pinned in this build")
+	}
+	if got := ActiveCryptoProfile(); got != CryptoProfileStandard {
+		t.Fatalf("default profile = %q", got)
+	}
+	if err := SetCryptoProfile("weak"); err == nil {
+		t.Fatalf("expected an error for an unknown profile")
+	}
+
+	if err := SetCryptoProfile(CryptoProfileFIPS); err != nil {
+		t.Fatalf("enable FIPS profile: %v", err)
+	}
+	t.Cleanup(func() { SetCryptoProfile(CryptoProfileStandard) })
+	if status := SystemStatus(); status.CryptoProfile != CryptoProfileFIPS || status.FIPS140Mode {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+
+	// Without Go's FIPS 140-3 mode, crypto-dependent features refuse to run.
+	var validationErr *ValidationError
+	config := &ExtractionConfig{CacheEncryption: &CacheEncryptionConfig{Key: bytes.Repeat([]byte{1}, 32), Dir: t.TempDir()}}
+	if _, err := ExtractBytesSync([]byte(testSRT), mimeSRT, config); !errors.As(err, &validationErr) {
+		t.Fatalf("expected cache encryption to be refused, got %v", err)
+	}
+	if _, err := NewPluginContext("", []byte("doc"), "text/plain", nil).DocumentHash(); !errors.As(err, &validationErr) {
+		t.Fatalf("expected document hashing to be refused, got %v", err)
+	}
+
+	if err := SetCryptoProfile(CryptoProfileStandard); err != nil {
+		t.Fatalf("restore standard profile: %v", err)
+	}
+	if _, err := NewPluginContext("", []byte("doc"), "text/plain", nil).DocumentHash(); err != nil {
+		t.Fatalf("hash under the standard profile: %v", err)
+	}
+}
+
+func TestCacheAEADEntriesAreInterchangeable(t *testing.T) {
+	block, err := aes.NewCipher(bytes.Repeat([]byte{3}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fips, err := cipher.NewGCMWithRandomNonce(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	standard, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed := fips.Seal(nil, nil, []byte("result"), []byte("aad"))
+	nonce := sealed[:standard.NonceSize()]
+	if plain, err := standard.Open(nil, nonce, sealed[len(nonce):], []byte("aad")); err != nil || string(plain) != "result" {
+		t.Fatalf("standard profile cannot open FIPS entry: %q, %v", plain, err)
+	}
+	nonce = bytes.Repeat([]byte{9}, standard.NonceSize())
+	sealed = standard.Seal(append([]byte(nil), nonce...), nonce, []byte("result"), []byte("aad"))
+	if plain, err := fips.Open(nil, nil, sealed, []byte("aad")); err != nil || string(plain) != "result" {
+		t.Fatalf("FIPS profile cannot open standard entry: %q, %v", plain, err)
+	}
+}
+
+func TestSystemStatusListsGoExtractors(t *testing.T) {
+	status := SystemStatus()
+	if status.GoVersion == "" || !containsString(status.GoExtractors, "subtitles") {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+}
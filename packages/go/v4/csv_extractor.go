@@ -0,0 +1,313 @@
+package kreuzberg
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	mimeCSV = "text/csv"
+	mimeTSV = "text/tab-separated-values"
+)
+
+// CSVConfig enables structured CSV/TSV extraction. By default the native library treats these
+// files as plain text; with CSVConfig set they are parsed into a Table using the given dialect,
+// Content becomes a Markdown table, and the inferred column schema is reported in
+// Metadata.Additional ("csv_dialect", "columns", "row_count").
+type CSVConfig struct {
+	// Delimiter separates fields (0 = tab for TSV, otherwise sniffed from the first line
+	// among ',', ';', '\t' and '|').
+	Delimiter rune
+	// Quote encloses fields containing delimiters or line breaks (0 = '"'). A doubled quote
+	// inside a quoted field is a literal quote.
+	Quote rune
+	// HasHeader treats the first row as column names (nil = true). Without a header, columns
+	// are named column_1, column_2, ...
+	HasHeader *bool
+	// Encoding names the text encoding: "utf-8", "utf-16le", "utf-16be", "iso-8859-1",
+	// "iso-8859-15", "windows-1252", "cp437" or "cp850". Empty detects byte order marks and
+	// falls back to UTF-8, then Windows-1252. ExtractionConfig.TextEncoding overrides it.
+	Encoding string
+	// MaxRows caps the number of data rows kept (0 = unlimited); row_count still reports all rows.
+	MaxRows int
+}
+
+// CSVColumnType is an inferred column type.
+type CSVColumnType string
+
+const (
+	CSVColumnEmpty    CSVColumnType = "empty"
+	CSVColumnBoolean  CSVColumnType = "boolean"
+	CSVColumnInteger  CSVColumnType = "integer"
+	CSVColumnFloat    CSVColumnType = "float"
+	CSVColumnDate     CSVColumnType = "date"
+	CSVColumnDateTime CSVColumnType = "datetime"
+	CSVColumnString   CSVColumnType = "string"
+)
+
+// CSVColumn describes an inferred column, as reported in Metadata.Additional["columns"].
+type CSVColumn struct {
+	// Name is the header value (or column_N without a header).
+	Name string `json:"name"`
+	// Type is the narrowest type that fits every non-empty value.
+	Type CSVColumnType `json:"type"`
+	// Nullable reports whether any value in the column is empty.
+	Nullable bool `json:"nullable"`
+}
+
+// CSVDialect is the dialect actually used, as reported in Metadata.Additional["csv_dialect"].
+type CSVDialect struct {
+	Delimiter string `json:"delimiter"`
+	Quote     string `json:"quote"`
+	HasHeader bool   `json:"has_header"`
+	Encoding  string `json:"encoding"`
+}
+
+var csvDateLayouts = []string{"2006-01-02", "01/02/2006", "02.01.2006"}
+
+var csvDateTimeLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02 15:04"}
+
+func extractCSV(src documentSource, mimeType string, config *ExtractionConfig) (*ExtractionResult, error) {
+	data, err := src.bytes()
+	if err != nil {
+		return nil, err
+	}
+	opts := *config.CSV
+
+	text, encoding, err := decodeText(data, opts.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Quote == 0 {
+		opts.Quote = '"'
+	}
+	if opts.Delimiter == 0 {
+		if mimeType == mimeTSV {
+			opts.Delimiter = '\t'
+		} else {
+			opts.Delimiter = sniffCSVDelimiter(text, opts.Quote)
+		}
+	}
+	hasHeader := opts.HasHeader == nil || *opts.HasHeader
+
+	records, err := parseCSVRecords(text, opts.Delimiter, opts.Quote)
+	if err != nil {
+		return nil, err
+	}
+
+	width := 0
+	for _, record := range records {
+		width = max(width, len(record))
+	}
+	var header []string
+	body := records
+	if hasHeader && len(records) > 0 {
+		header, body = records[0], records[1:]
+	}
+	for len(header) < width {
+		header = append(header, fmt.Sprintf("column_%d", len(header)+1))
+	}
+
+	columns := inferCSVColumns(header, body)
+	rowCount := len(body)
+	if opts.MaxRows > 0 && len(body) > opts.MaxRows {
+		body = body[:opts.MaxRows]
+	}
+
+	cells := make([][]string, 0, len(body)+1)
+	cells = append(cells, header)
+	cells = append(cells, body...)
+
+	result := &ExtractionResult{MimeType: mimeType, Tables: []Table{}, Success: true}
+	if width > 0 {
+		markdown := spreadsheetMarkdown(cells)
+		result.Tables = append(result.Tables, Table{Cells: cells, Markdown: markdown, PageNumber: 1})
+		result.Content = strings.TrimRight(markdown, "\n")
+	}
+
+	additional := map[string]any{
+		"csv_dialect": CSVDialect{
+			Delimiter: string(opts.Delimiter),
+			Quote:     string(opts.Quote),
+			HasHeader: hasHeader,
+			Encoding:  encoding,
+		},
+		"columns":   columns,
+		"row_count": rowCount,
+		"truncated": len(body) < rowCount,
+	}
+	result.Metadata.Additional = make(map[string]json.RawMessage, len(additional))
+	for key, value := range additional {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, newSerializationErrorWithContext("failed to encode CSV metadata", err, ErrorCodeValidation, nil)
+		}
+		result.Metadata.Additional[key] = raw
+	}
+	return result, nil
+}
+
+// sniffCSVDelimiter picks the candidate delimiter that occurs most often, outside quotes, in
+// the first line.
+func sniffCSVDelimiter(text string, quote rune) rune {
+	counts := map[rune]int{}
+	inQuotes := false
+	for _, r := range text {
+		if r == quote {
+			inQuotes = !inQuotes
+			continue
+		}
+		if inQuotes {
+			continue
+		}
+		if r == '\n' || r == '\r' {
+			break
+		}
+		counts[r]++
+	}
+
+	best, bestCount := ',', 0
+	for _, candidate := range []rune{',', ';', '\t', '|'} {
+		if counts[candidate] > bestCount {
+			best, bestCount = candidate, counts[candidate]
+		}
+	}
+	return best
+}
+
+// parseCSVRecords splits text into records following RFC 4180 with a configurable delimiter and
+// quote character. Quoted fields may contain delimiters, line breaks and doubled quotes.
+func parseCSVRecords(text string, delimiter, quote rune) ([][]string, error) {
+	if delimiter == quote || delimiter == '\n' || delimiter == '\r' {
+		return nil, newValidationErrorWithContext("CSV delimiter must differ from the quote character and line breaks", nil, ErrorCodeValidation, nil)
+	}
+
+	var records [][]string
+	var record []string
+	var field strings.Builder
+	inQuotes, quoted, line := false, false, 1
+
+	endField := func() {
+		record = append(record, field.String())
+		field.Reset()
+		quoted = false
+	}
+	endRecord := func() {
+		endField()
+		if len(record) > 1 || record[0] != "" {
+			records = append(records, record)
+		}
+		record = nil
+	}
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case inQuotes:
+			switch {
+			case r == quote && i+1 < len(runes) && runes[i+1] == quote:
+				field.WriteRune(quote)
+				i++
+			case r == quote:
+				inQuotes = false
+			default:
+				if r == '\n' {
+					line++
+				}
+				field.WriteRune(r)
+			}
+		case r == quote && field.Len() == 0 && !quoted:
+			inQuotes, quoted = true, true
+		case r == delimiter:
+			endField()
+		case r == '\r' && i+1 < len(runes) && runes[i+1] == '\n':
+		case r == '\n' || r == '\r':
+			endRecord()
+			line++
+		default:
+			field.WriteRune(r)
+		}
+	}
+	if inQuotes {
+		return nil, newParsingErrorWithContext(fmt.Sprintf("unterminated quoted field starting before line %d", line), nil, ErrorCodeParsing, nil)
+	}
+	if field.Len() > 0 || len(record) > 0 || quoted {
+		endRecord()
+	}
+	return records, nil
+}
+
+func inferCSVColumns(header []string, rows [][]string) []CSVColumn {
+	columns := make([]CSVColumn, len(header))
+	for c, name := range header {
+		column := CSVColumn{Name: name, Type: CSVColumnEmpty}
+		for _, row := range rows {
+			value := ""
+			if c < len(row) {
+				value = strings.TrimSpace(row[c])
+			}
+			if value == "" {
+				column.Nullable = true
+				continue
+			}
+			column.Type = widenCSVType(column.Type, classifyCSVValue(value))
+		}
+		columns[c] = column
+	}
+	return columns
+}
+
+func classifyCSVValue(value string) CSVColumnType {
+	switch strings.ToLower(value) {
+	case "true", "false", "yes", "no":
+		return CSVColumnBoolean
+	}
+	if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return CSVColumnInteger
+	}
+	if strings.IndexFunc(value, func(r rune) bool { return r >= '0' && r <= '9' }) >= 0 {
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			return CSVColumnFloat
+		}
+	}
+	for _, layout := range csvDateLayouts {
+		if _, err := time.Parse(layout, value); err == nil {
+			return CSVColumnDate
+		}
+	}
+	for _, layout := range csvDateTimeLayouts {
+		if _, err := time.Parse(layout, value); err == nil {
+			return CSVColumnDateTime
+		}
+	}
+	return CSVColumnString
+}
+
+func widenCSVType(current, next CSVColumnType) CSVColumnType {
+	switch {
+	case current == CSVColumnEmpty || current == next:
+		return next
+	case (current == CSVColumnInteger && next == CSVColumnFloat) || (current == CSVColumnFloat && next == CSVColumnInteger):
+		return CSVColumnFloat
+	case (current == CSVColumnDate && next == CSVColumnDateTime) || (current == CSVColumnDateTime && next == CSVColumnDate):
+		return CSVColumnDateTime
+	default:
+		return CSVColumnString
+	}
+}
+
+func init() {
+	registerGoPrimaryExtractor(goPrimaryExtractor{
+		name:       "csv",
+		mimeTypes:  []string{mimeCSV, mimeTSV},
+		extensions: map[string]string{"csv": mimeCSV, "tsv": mimeTSV},
+		enabled: func(config *ExtractionConfig) bool {
+			return config != nil && config.CSV != nil
+		},
+		extract: extractCSV,
+	})
+}
@@ -0,0 +1,83 @@
+package kreuzberg
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestExtractCSVInfersSchemaAndDialect(t *testing.T) {
+	data := []byte("id;price;active;joined;note\r\n1;9.99;yes;2024-01-02;\"semi;colon\"\r\n2;10;no;2024-02-03 10:00:00;\"line\nbreak \"\"quoted\"\"\"\r\n3;;true;2024-03-04;plain\r\n")
+	cfg := &ExtractionConfig{CSV: &CSVConfig{}}
+
+	result, err := extractCSV(documentSource{data: data, mimeType: mimeCSV}, mimeCSV, cfg)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+
+	var dialect CSVDialect
+	if err := json.Unmarshal(result.Metadata.Additional["csv_dialect"], &dialect); err != nil {
+		t.Fatalf("decode dialect: %v", err)
+	}
+	if dialect.Delimiter != ";" || !dialect.HasHeader || dialect.Encoding != "utf-8" {
+		t.Fatalf("unexpected dialect: %+v", dialect)
+	}
+
+	var columns []CSVColumn
+	if err := json.Unmarshal(result.Metadata.Additional["columns"], &columns); err != nil {
+		t.Fatalf("decode columns: %v", err)
+	}
+	want := []CSVColumn{
+		{Name: "id", Type: CSVColumnInteger},
+		{Name: "price", Type: CSVColumnFloat, Nullable: true},
+		{Name: "active", Type: CSVColumnBoolean},
+		{Name: "joined", Type: CSVColumnDateTime},
+		{Name: "note", Type: CSVColumnString},
+	}
+	for i := range want {
+		if columns[i] != want[i] {
+			t.Fatalf("column %d = %+v, want %+v", i, columns[i], want[i])
+		}
+	}
+
+	cells := result.Tables[0].Cells
+	if len(cells) != 4 || cells[1][4] != "semi;colon" || cells[2][4] != "line\nbreak \"quoted\"" {
+		t.Fatalf("unexpected cells: %q", cells)
+	}
+	if string(result.Metadata.Additional["row_count"]) != "3" {
+		t.Fatalf("unexpected row_count: %s", result.Metadata.Additional["row_count"])
+	}
+}
+
+func TestExtractCSVHonorsExplicitDialect(t *testing.T) {
+	// Windows-1252 encoded, single-quoted, no header, capped to one row.
+	data := []byte("'caf\xe9'\t1\n'na\x96ve'\t2\n")
+	cfg := &ExtractionConfig{CSV: &CSVConfig{Quote: '\'', HasHeader: BoolPtr(false), Encoding: "windows-1252", MaxRows: 1}}
+
+	result, err := extractCSV(documentSource{data: data, mimeType: mimeTSV}, mimeTSV, cfg)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	cells := result.Tables[0].Cells
+	if cells[0][0] != "column_1" || cells[1][0] != "café" || len(cells) != 2 {
+		t.Fatalf("unexpected cells: %q", cells)
+	}
+	if string(result.Metadata.Additional["truncated"]) != "true" || string(result.Metadata.Additional["row_count"]) != "2" {
+		t.Fatalf("expected truncation to be reported")
+	}
+}
+
+func TestParseCSVRecordsRejectsUnterminatedQuotes(t *testing.T) {
+	if _, err := parseCSVRecords("a,\"b\nc", ',', '"'); err == nil {
+		t.Fatalf("expected error for unterminated quote")
+	}
+}
+
+func TestDecodeTextDetectsUTF16BOM(t *testing.T) {
+	text, encoding, err := decodeText([]byte{0xFF, 0xFE, 'h', 0, 'i', 0}, "")
+	if err != nil || text != "hi" || encoding != "utf-16le" {
+		t.Fatalf("decodeText = %q, %q, %v", text, encoding, err)
+	}
+	if _, _, err := decodeText([]byte("x"), "ebcdic"); err == nil {
+		t.Fatalf("expected unsupported encoding error")
+	}
+}
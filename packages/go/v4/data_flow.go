@@ -0,0 +1,247 @@
+package kreuzberg
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DataFlowStage names the boundary at which extracted content is inspected.
+type DataFlowStage string
+
+const (
+	// DataFlowStageCache inspects a result before it is stored in the Go result cache (see
+	// CacheEncryptionConfig). A blocked result is not cached; a redacted one is cached redacted.
+	DataFlowStageCache DataFlowStage = "cache"
+	// DataFlowStageReturn inspects a result after plugins and content limits ran, before it is
+	// returned to the caller or delivered to a batch sink.
+	DataFlowStageReturn DataFlowStage = "return"
+)
+
+// DataFlowAction is what a DataFlowInspector decides to do with a result.
+type DataFlowAction string
+
+const (
+	// DataFlowAllow lets the result through unchanged. It is the zero action.
+	DataFlowAllow DataFlowAction = "allow"
+	// DataFlowBlock withholds the result: the extraction fails with an error matching
+	// ErrDataFlowBlocked, and a failed batch item carries no content.
+	DataFlowBlock DataFlowAction = "block"
+	// DataFlowRedact replaces DataFlowDecision.Redactions in the text of the result.
+	DataFlowRedact DataFlowAction = "redact"
+)
+
+// ErrDataFlowBlocked is matched (via errors.Is) by the errors of extractions a
+// DataFlowInspector blocked.
+var ErrDataFlowBlocked = errors.New("blocked by data-flow policy")
+
+// dataFlowPluginName names data-flow enforcement in the PluginErrors it causes.
+const dataFlowPluginName = "data_flow"
+
+// defaultRedactionReplacement replaces redacted text when a decision sets no Replacement.
+const defaultRedactionReplacement = "[REDACTED]"
+
+// DataFlowEvent is the result a DataFlowInspector is asked about.
+type DataFlowEvent struct {
+	Stage DataFlowStage
+	// DocumentID is the path of the document, or empty for in-memory extractions.
+	DocumentID string
+	MimeType   string
+	// Labels are the caller-supplied labels from ExtractionConfig.Labels.
+	Labels map[string]string
+	// Result is the extracted result. Inspectors must not modify it; request a redaction instead.
+	Result *ExtractionResult
+}
+
+// DataFlowDecision is a DataFlowInspector's verdict on a result.
+type DataFlowDecision struct {
+	// Action is what to do with the result; empty allows it.
+	Action DataFlowAction
+	// Reason explains the decision in the audit log, e.g. the policy that matched.
+	Reason string
+	// Rules lists the identifiers of the policy rules that matched.
+	Rules []string
+	// Redactions are the literal strings DataFlowRedact replaces in the content, chunks, pages
+	// and tables of the result. They are never written to the audit log.
+	Redactions []string
+	// Replacement replaces each redaction (default "[REDACTED]").
+	Replacement string
+}
+
+// DataFlowInspector inspects extracted content on behalf of an external DLP engine. It is
+// called from concurrent extractions.
+type DataFlowInspector interface {
+	Inspect(ctx context.Context, event DataFlowEvent) (DataFlowDecision, error)
+}
+
+// DataFlowInspectorFunc adapts a function to a DataFlowInspector.
+type DataFlowInspectorFunc func(ctx context.Context, event DataFlowEvent) (DataFlowDecision, error)
+
+// Inspect calls f.
+func (f DataFlowInspectorFunc) Inspect(ctx context.Context, event DataFlowEvent) (DataFlowDecision, error) {
+	return f(ctx, event)
+}
+
+// DataFlowConfig enforces egress policies at the extraction boundary. Enforcement fails closed:
+// an extraction fails when the inspector or the audit log returns an error or the inspector
+// returns an unknown action.
+type DataFlowConfig struct {
+	Inspector DataFlowInspector
+	// Audit records every decision. Optional.
+	Audit AuditLog
+}
+
+// AuditRecord is one entry of an AuditLog.
+type AuditRecord struct {
+	Time       time.Time      `json:"time"`
+	Event      string         `json:"event"`
+	Stage      DataFlowStage  `json:"stage,omitempty"`
+	DocumentID string         `json:"document_id,omitempty"`
+	MimeType   string         `json:"mime_type,omitempty"`
+	Action     DataFlowAction `json:"action,omitempty"`
+	Reason     string         `json:"reason,omitempty"`
+	Rules      []string       `json:"rules,omitempty"`
+	// Redactions is the number of redacted occurrences.
+	Redactions int `json:"redactions,omitempty"`
+	// Error is set when the decision could not be made or applied.
+	Error string `json:"error,omitempty"`
+}
+
+// auditEventDataFlow is the AuditRecord.Event of data-flow decisions.
+const auditEventDataFlow = "data_flow"
+
+// AuditLog receives audit records. It is called from concurrent extractions.
+type AuditLog interface {
+	Record(ctx context.Context, record AuditRecord) error
+}
+
+// AuditLogFunc adapts a function to an AuditLog.
+type AuditLogFunc func(ctx context.Context, record AuditRecord) error
+
+// Record calls f.
+func (f AuditLogFunc) Record(ctx context.Context, record AuditRecord) error {
+	return f(ctx, record)
+}
+
+type jsonlAuditLog struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONLAuditLog returns an AuditLog that appends each record to w as a JSON line. Writes are
+// serialized, so w may be shared by concurrent extractions.
+func NewJSONLAuditLog(w io.Writer) AuditLog {
+	return &jsonlAuditLog{enc: json.NewEncoder(w)}
+}
+
+func (l *jsonlAuditLog) Record(_ context.Context, record AuditRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.enc.Encode(record)
+}
+
+// dataFlowEnabled reports whether config inspects extracted content.
+func dataFlowEnabled(config *ExtractionConfig) bool {
+	return config != nil && config.DataFlow != nil && config.DataFlow.Inspector != nil
+}
+
+// inspectDataFlow asks the configured inspector about result and applies its decision: it
+// redacts result in place or returns an error matching ErrDataFlowBlocked.
+func inspectDataFlow(ctx context.Context, config *ExtractionConfig, event DataFlowEvent) error {
+	if !dataFlowEnabled(config) {
+		return nil
+	}
+	event.Labels = config.Labels
+	record := AuditRecord{Event: auditEventDataFlow, Stage: event.Stage, DocumentID: event.DocumentID, MimeType: event.MimeType}
+	decision, err := config.DataFlow.Inspector.Inspect(ctx, event)
+	if err != nil {
+		err = fmt.Errorf("%w: inspector failed: %w", ErrDataFlowBlocked, err)
+		record.Action, record.Error = DataFlowBlock, err.Error()
+	} else {
+		record.Action, record.Reason, record.Rules = cmp.Or(decision.Action, DataFlowAllow), decision.Reason, decision.Rules
+		switch record.Action {
+		case DataFlowAllow:
+		case DataFlowRedact:
+			record.Redactions = redactResult(event.Result, decision.Redactions, cmp.Or(decision.Replacement, defaultRedactionReplacement))
+		case DataFlowBlock:
+			err = ErrDataFlowBlocked
+			if decision.Reason != "" {
+				err = fmt.Errorf("%w: %s", ErrDataFlowBlocked, decision.Reason)
+			}
+		default:
+			err = fmt.Errorf("%w: unknown data-flow action %q", ErrDataFlowBlocked, decision.Action)
+			record.Action, record.Error = DataFlowBlock, err.Error()
+		}
+	}
+	if audit := config.DataFlow.Audit; audit != nil {
+		record.Time = time.Now()
+		if auditErr := audit.Record(ctx, record); auditErr != nil && err == nil {
+			err = fmt.Errorf("%w: audit log failed: %w", ErrDataFlowBlocked, auditErr)
+		}
+	}
+	if err != nil {
+		return newPluginErrorWithContext(dataFlowPluginName, err.Error(), err, ErrorCodePlugin, nil)
+	}
+	return nil
+}
+
+// inspectReturned inspects result at the return stage.
+func inspectReturned(pc *PluginContext, result *ExtractionResult) error {
+	event := DataFlowEvent{Stage: DataFlowStageReturn, DocumentID: pc.DocumentPath, MimeType: pc.MimeType, Result: result}
+	return inspectDataFlow(pc.Context(), pc.Config, event)
+}
+
+// cacheable inspects result at the cache stage and reports whether it may be stored.
+func cacheable(config *ExtractionConfig, src documentSource, result *ExtractionResult) bool {
+	event := DataFlowEvent{Stage: DataFlowStageCache, DocumentID: src.path, MimeType: result.MimeType, Result: result}
+	return inspectDataFlow(context.Background(), config, event) == nil
+}
+
+// redactResult replaces each redaction in the text of result and returns the number of
+// occurrences replaced.
+func redactResult(result *ExtractionResult, redactions []string, replacement string) int {
+	redactions = slices.DeleteFunc(slices.Clone(redactions), func(s string) bool { return s == "" })
+	if len(redactions) == 0 {
+		return 0
+	}
+	// Longer redactions first, so one containing another is replaced whole.
+	slices.SortFunc(redactions, func(a, b string) int { return len(b) - len(a) })
+	pairs := make([]string, 0, 2*len(redactions))
+	for _, r := range redactions {
+		pairs = append(pairs, r, replacement)
+	}
+	replacer := strings.NewReplacer(pairs...)
+	count := 0
+	redact := func(s *string) {
+		redacted := replacer.Replace(*s)
+		count += strings.Count(redacted, replacement) - strings.Count(*s, replacement)
+		*s = redacted
+	}
+	redactTables := func(tables []Table) {
+		for i := range tables {
+			redact(&tables[i].Markdown)
+			for _, row := range tables[i].Cells {
+				for j := range row {
+					redact(&row[j])
+				}
+			}
+		}
+	}
+	redact(&result.Content)
+	for i := range result.Chunks {
+		redact(&result.Chunks[i].Content)
+	}
+	for i := range result.Pages {
+		redact(&result.Pages[i].Content)
+		redactTables(result.Pages[i].Tables)
+	}
+	redactTables(result.Tables)
+	return count
+}
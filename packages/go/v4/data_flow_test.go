@@ -0,0 +1,148 @@
+package kreuzberg
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestDataFlowRedact(t *testing.T) {
+	var audit bytes.Buffer
+	config := &ExtractionConfig{
+		Chunking: &ChunkingConfig{MaxChars: IntPtr(20)},
+		DataFlow: &DataFlowConfig{
+			Inspector: DataFlowInspectorFunc(func(_ context.Context, e DataFlowEvent) (DataFlowDecision, error) {
+				return DataFlowDecision{Action: DataFlowRedact, Reason: "names", Rules: []string{"pii.name"}, Redactions: []string{"Kenobi", "General Kenobi", ""}}, nil
+			}),
+			Audit: NewJSONLAuditLog(&audit),
+		},
+	}
+	result, err := ExtractBytesSync([]byte(testSRT), mimeSRT, config)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if want := strings.Replace(wantSRTContent, "General Kenobi", "[REDACTED]", 1); result.Content != want {
+		t.Fatalf("unexpected content %q", result.Content)
+	}
+	for _, chunk := range result.Chunks {
+		if strings.Contains(chunk.Content, "Kenobi") {
+			t.Fatalf("unredacted chunk %q", chunk.Content)
+		}
+	}
+	if strings.Contains(audit.String(), "Kenobi") {
+		t.Fatalf("the audit log leaks redacted text: %s", audit.String())
+	}
+	var record AuditRecord
+	if err := json.Unmarshal(audit.Bytes(), &record); err != nil {
+		t.Fatalf("decode audit record: %v", err)
+	}
+	if record.Event != auditEventDataFlow || record.Stage != DataFlowStageReturn || record.Action != DataFlowRedact || record.Redactions == 0 ||
+		record.Reason != "names" || !slices.Equal(record.Rules, []string{"pii.name"}) || record.MimeType != mimeSRT || record.Time.IsZero() {
+		t.Fatalf("unexpected audit record %+v", record)
+	}
+}
+
+func TestDataFlowBlock(t *testing.T) {
+	var mu sync.Mutex
+	var records []AuditRecord
+	config := &ExtractionConfig{DataFlow: &DataFlowConfig{
+		Inspector: DataFlowInspectorFunc(func(_ context.Context, e DataFlowEvent) (DataFlowDecision, error) {
+			if strings.Contains(e.Result.Content, "secret") {
+				return DataFlowDecision{Action: DataFlowBlock, Reason: "confidential"}, nil
+			}
+			return DataFlowDecision{}, nil
+		}),
+		Audit: AuditLogFunc(func(_ context.Context, r AuditRecord) error {
+			mu.Lock()
+			defer mu.Unlock()
+			records = append(records, r)
+			return nil
+		}),
+	}}
+
+	_, err := ExtractBytesSync([]byte(strings.Replace(testSRT, "bold", "secret", 1)), mimeSRT, config)
+	var pluginErr *PluginError
+	if !errors.Is(err, ErrDataFlowBlocked) || !errors.As(err, &pluginErr) || !strings.Contains(err.Error(), "confidential") {
+		t.Fatalf("expected the extraction to be blocked, got %v", err)
+	}
+
+	results, err := BatchExtractBytesSync([]BytesWithMime{
+		{Data: []byte(testSRT), MimeType: mimeSRT},
+		{Data: []byte(strings.Replace(testSRT, "bold", "secret", 1)), MimeType: mimeSRT},
+	}, config)
+	if err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+	if results[0].Content != wantSRTContent || results[1].Success || results[1].Content != "" || results[1].Metadata.Error == nil {
+		t.Fatalf("unexpected batch results %+v, %+v", results[0], results[1])
+	}
+	actions := make([]DataFlowAction, len(records))
+	for i, r := range records {
+		actions[i] = r.Action
+	}
+	slices.Sort(actions[1:])
+	if !slices.Equal(actions, []DataFlowAction{DataFlowBlock, DataFlowAllow, DataFlowBlock}) {
+		t.Fatalf("unexpected audit actions %v", actions)
+	}
+}
+
+func TestDataFlowFailsClosed(t *testing.T) {
+	allow := DataFlowInspectorFunc(func(context.Context, DataFlowEvent) (DataFlowDecision, error) { return DataFlowDecision{}, nil })
+	for name, flow := range map[string]*DataFlowConfig{
+		"inspector": {Inspector: DataFlowInspectorFunc(func(context.Context, DataFlowEvent) (DataFlowDecision, error) {
+			return DataFlowDecision{}, errors.New("engine unreachable")
+		})},
+		"action": {Inspector: DataFlowInspectorFunc(func(context.Context, DataFlowEvent) (DataFlowDecision, error) {
+			return DataFlowDecision{Action: "quarantine"}, nil
+		})},
+		"audit": {Inspector: allow, Audit: AuditLogFunc(func(context.Context, AuditRecord) error { return errors.New("disk full") })},
+	} {
+		if _, err := ExtractBytesSync([]byte(testSRT), mimeSRT, &ExtractionConfig{DataFlow: flow}); !errors.Is(err, ErrDataFlowBlocked) {
+			t.Errorf("%s: expected the extraction to be blocked, got %v", name, err)
+		}
+	}
+}
+
+func TestDataFlowCacheStage(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	os.Mkdir(dir, 0o700)
+	var audit bytes.Buffer
+	config := &ExtractionConfig{
+		CacheEncryption: &CacheEncryptionConfig{Key: bytes.Repeat([]byte("k"), 32), Dir: dir},
+		DataFlow: &DataFlowConfig{
+			Inspector: DataFlowInspectorFunc(func(_ context.Context, e DataFlowEvent) (DataFlowDecision, error) {
+				if e.Stage == DataFlowStageCache {
+					return DataFlowDecision{Action: DataFlowBlock, Reason: "never persist"}, nil
+				}
+				return DataFlowDecision{}, nil
+			}),
+			Audit: NewJSONLAuditLog(&audit),
+		},
+	}
+	for range 2 {
+		if result, err := ExtractBytesSync([]byte(testSRT), mimeSRT, config); err != nil || result.Content != wantSRTContent {
+			t.Fatalf("unexpected result %+v, %v", result, err)
+		}
+	}
+	if entries := cacheEntries(t, dir); len(entries) != 0 {
+		t.Fatalf("expected nothing to be cached, got %v", entries)
+	}
+	var stages []DataFlowStage
+	for scanner := bufio.NewScanner(&audit); scanner.Scan(); {
+		var record AuditRecord
+		json.Unmarshal(scanner.Bytes(), &record)
+		stages = append(stages, record.Stage)
+	}
+	want := []DataFlowStage{DataFlowStageCache, DataFlowStageReturn, DataFlowStageCache, DataFlowStageReturn}
+	if !slices.Equal(stages, want) {
+		t.Fatalf("unexpected stages %v", stages)
+	}
+}
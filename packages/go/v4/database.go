@@ -0,0 +1,161 @@
+package kreuzberg
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const defaultDatabaseMaxRows = 100
+
+// DatabaseConfig tunes extraction of database files (SQLite, Microsoft Access). Database files
+// are always handled by the Go binding; by default only the table list, schemas and row counts
+// are extracted.
+type DatabaseConfig struct {
+	// IncludeRows extracts table rows as Tables in addition to the schema.
+	IncludeRows bool
+	// MaxRows caps the rows extracted per table when IncludeRows is set (default 100).
+	MaxRows int
+	// Tables restricts extraction to the named tables, compared case-insensitively (all tables
+	// when empty).
+	Tables []string
+}
+
+// DatabaseTable describes a table of a database file, as reported in
+// Metadata.Additional["database_tables"].
+type DatabaseTable struct {
+	Name     string       `json:"name"`
+	RowCount int64        `json:"row_count"`
+	Columns  []DataColumn `json:"columns"`
+	// Truncated reports whether the extracted rows were capped by DatabaseConfig.MaxRows.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// databaseSummary is the format-independent description of a database file.
+type databaseSummary struct {
+	format string
+	tables []DatabaseTable
+	// rows holds the extracted rows per table, aligned with tables (nil when not requested).
+	rows [][][]string
+	// properties carries format-level facts such as page size or text encoding.
+	properties map[string]string
+}
+
+// databaseOptions is DatabaseConfig with defaults applied.
+type databaseOptions struct {
+	includeRows bool
+	maxRows     int
+	tables      map[string]bool
+}
+
+func newDatabaseOptions(config *ExtractionConfig) databaseOptions {
+	opts := databaseOptions{maxRows: defaultDatabaseMaxRows}
+	if config == nil || config.Database == nil {
+		return opts
+	}
+	opts.includeRows = config.Database.IncludeRows
+	if config.Database.MaxRows > 0 {
+		opts.maxRows = config.Database.MaxRows
+	}
+	if len(config.Database.Tables) > 0 {
+		opts.tables = make(map[string]bool, len(config.Database.Tables))
+		for _, name := range config.Database.Tables {
+			opts.tables[strings.ToLower(name)] = true
+		}
+	}
+	return opts
+}
+
+func (o databaseOptions) wantTable(name string) bool {
+	return o.tables == nil || o.tables[strings.ToLower(name)]
+}
+
+func (s *databaseSummary) toResult(mimeType string) (*ExtractionResult, error) {
+	overview := [][]string{{"table", "rows", "columns"}}
+	for _, t := range s.tables {
+		overview = append(overview, []string{t.Name, strconv.FormatInt(t.RowCount, 10), strconv.Itoa(len(t.Columns))})
+	}
+	sections := []string{fmt.Sprintf("## %s database\n\n%s", s.format, strings.TrimRight(spreadsheetMarkdown(overview), "\n"))}
+
+	result := &ExtractionResult{MimeType: mimeType, Tables: []Table{}, Success: true}
+	for i, t := range s.tables {
+		schema := [][]string{{"column", "type", "nullable"}}
+		for _, c := range t.Columns {
+			schema = append(schema, []string{c.Name, c.Type, strconv.FormatBool(c.Nullable)})
+		}
+		section := fmt.Sprintf("### %s\n\n%s\n\nRows: %d", t.Name, strings.TrimRight(spreadsheetMarkdown(schema), "\n"), t.RowCount)
+
+		if s.rows != nil && len(t.Columns) > 0 {
+			header := make([]string, len(t.Columns))
+			for j, c := range t.Columns {
+				header[j] = c.Name
+			}
+			cells := append([][]string{header}, s.rows[i]...)
+			markdown := spreadsheetMarkdown(cells)
+			result.Tables = append(result.Tables, Table{Cells: cells, Markdown: markdown, PageNumber: 1})
+			section += "\n\n" + strings.TrimRight(markdown, "\n")
+			if t.Truncated {
+				section += fmt.Sprintf("\n\n(showing first %d of %d rows)", len(s.rows[i]), t.RowCount)
+			}
+		}
+		sections = append(sections, section)
+	}
+	result.Content = strings.Join(sections, "\n\n")
+
+	additional := map[string]any{
+		"database_format": s.format,
+		"database_tables": s.tables,
+	}
+	if len(s.properties) > 0 {
+		additional["database_properties"] = s.properties
+	}
+	result.Metadata.Additional = make(map[string]json.RawMessage, len(additional))
+	for key, value := range additional {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, newSerializationErrorWithContext("failed to encode database metadata", err, ErrorCodeValidation, nil)
+		}
+		result.Metadata.Additional[key] = raw
+	}
+	return result, nil
+}
+
+func init() {
+	registerGoPrimaryExtractor(goPrimaryExtractor{
+		name:      "sqlite",
+		mimeTypes: []string{"application/vnd.sqlite3", "application/x-sqlite3"},
+		extensions: map[string]string{
+			"sqlite":  "application/vnd.sqlite3",
+			"sqlite3": "application/vnd.sqlite3",
+			"db":      "application/vnd.sqlite3",
+			"db3":     "application/vnd.sqlite3",
+		},
+		extract: func(src documentSource, mimeType string, config *ExtractionConfig) (*ExtractionResult, error) {
+			data, err := src.bytes()
+			if err != nil {
+				return nil, err
+			}
+			summary, err := parseSQLite(data, newDatabaseOptions(config))
+			if err != nil {
+				return nil, err
+			}
+			return summary.toResult(mimeType)
+		},
+	})
+	registerGoPrimaryExtractor(goPrimaryExtractor{
+		name:      "access",
+		mimeTypes: []string{"application/x-msaccess", "application/vnd.ms-access"},
+		extensions: map[string]string{
+			"mdb":   "application/x-msaccess",
+			"accdb": "application/x-msaccess",
+		},
+		extract: func(src documentSource, mimeType string, config *ExtractionConfig) (*ExtractionResult, error) {
+			summary, err := extractAccess(src, newDatabaseOptions(config))
+			if err != nil {
+				return nil, err
+			}
+			return summary.toResult(mimeType)
+		},
+	})
+}
@@ -0,0 +1,214 @@
+package kreuzberg
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// mdbTools runs the mdbtools command-line utilities against a single Access database.
+type mdbTools struct {
+	dir  string
+	path string
+	ctx  context.Context
+}
+
+// locateMDBTools finds the directory holding the mdbtools executables: KREUZBERG_MDBTOOLS_PATH,
+// then PATH.
+func locateMDBTools() (string, error) {
+	if dir := os.Getenv("KREUZBERG_MDBTOOLS_PATH"); dir != "" {
+		if info, err := os.Stat(filepath.Join(dir, "mdb-tables")); err == nil && info.Mode().IsRegular() {
+			return dir, nil
+		}
+	}
+	if path, err := exec.LookPath("mdb-tables"); err == nil {
+		return filepath.Dir(path), nil
+	}
+	return "", newMissingDependencyErrorWithContext("mdbtools",
+		"mdbtools (mdb-tables, mdb-schema, mdb-export) is required for Microsoft Access databases. "+
+			"Install it or set KREUZBERG_MDBTOOLS_PATH to the directory containing the executables.",
+		nil, ErrorCodeMissingDependency, nil)
+}
+
+func (m *mdbTools) command(name string, args ...string) *exec.Cmd {
+	// #nosec G204 -- executables are resolved from trusted configuration, arguments are not shell-interpreted
+	return exec.CommandContext(m.ctx, filepath.Join(m.dir, name), args...)
+}
+
+func (m *mdbTools) output(name string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := m.command(name, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		details := strings.TrimSpace(stderr.String())
+		if details == "" {
+			details = err.Error()
+		}
+		return nil, newParsingErrorWithContext(fmt.Sprintf("%s failed: %s", name, details), err, ErrorCodeParsing, nil)
+	}
+	return out, nil
+}
+
+// export reads up to limit rows of table and returns the header and rows. When countAll is set the
+// whole export is read and total is the number of rows; otherwise total is -1 if rows remain.
+func (m *mdbTools) export(table string, limit int, countAll bool) (header []string, rows [][]string, total int64, err error) {
+	ctx, cancel := context.WithCancel(m.ctx)
+	defer cancel()
+	// #nosec G204 -- executables are resolved from trusted configuration, arguments are not shell-interpreted
+	cmd := exec.CommandContext(ctx, filepath.Join(m.dir, "mdb-export"), m.path, table)
+	stdout, err := cmd.StdoutPipe()
+	if err == nil {
+		err = cmd.Start()
+	}
+	if err != nil {
+		return nil, nil, 0, newIOErrorWithContext("failed to start mdb-export", err, ErrorCodeIo, nil)
+	}
+	defer func() { _ = cmd.Wait() }()
+
+	reader := csv.NewReader(stdout)
+	reader.FieldsPerRecord = -1
+	rows = [][]string{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return header, rows, total, nil
+		}
+		if err != nil {
+			return nil, nil, 0, newParsingErrorWithContext(fmt.Sprintf("failed to read mdb-export output for %s", table), err, ErrorCodeParsing, nil)
+		}
+		switch {
+		case header == nil:
+			header = record
+			continue
+		case len(rows) < limit:
+			rows = append(rows, record)
+		case !countAll:
+			return header, rows, -1, nil
+		}
+		total++
+	}
+}
+
+// extractAccess describes a Microsoft Access database using mdbtools. Table schemas come from
+// mdb-schema, row counts from mdb-count, and rows from mdb-export.
+func extractAccess(src documentSource, opts databaseOptions) (*databaseSummary, error) {
+	dir, err := locateMDBTools()
+	if err != nil {
+		return nil, err
+	}
+	path := src.path
+	if path == "" {
+		tmp, err := os.MkdirTemp("", "kreuzberg_access_")
+		if err != nil {
+			return nil, newIOErrorWithContext("failed to create temporary directory", err, ErrorCodeIo, nil)
+		}
+		defer os.RemoveAll(tmp)
+		path = filepath.Join(tmp, "input.mdb")
+		if err := os.WriteFile(path, src.data, 0o600); err != nil {
+			return nil, newIOErrorWithContext("failed to write temporary database", err, ErrorCodeIo, nil)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultConversionTimeout)
+	defer cancel()
+	release, err := AcquireDependency(ctx, DependencyMDBTools)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	m := &mdbTools{dir: dir, path: path, ctx: ctx}
+
+	out, err := m.output("mdb-tables", "-1", path)
+	if err != nil {
+		return nil, err
+	}
+	schema := map[string][]DataColumn{}
+	if ddl, err := m.output("mdb-schema", path); err == nil {
+		schema = parseAccessSchema(string(ddl))
+	}
+
+	summary := &databaseSummary{format: "Microsoft Access", properties: map[string]string{"reader": "mdbtools"}}
+	if opts.includeRows {
+		summary.rows = [][][]string{}
+	}
+	for _, name := range strings.Split(string(out), "\n") {
+		name = strings.TrimSpace(name)
+		if name == "" || !opts.wantTable(name) {
+			continue
+		}
+		table := DatabaseTable{Name: name, Columns: schema[strings.ToLower(name)], RowCount: -1}
+		if countOut, err := m.output("mdb-count", path, name); err == nil {
+			if count, err := strconv.ParseInt(strings.TrimSpace(string(countOut)), 10, 64); err == nil {
+				table.RowCount = count
+			}
+		}
+
+		if opts.includeRows || table.Columns == nil || table.RowCount < 0 {
+			limit := 0
+			if opts.includeRows {
+				limit = opts.maxRows
+			}
+			// Older mdbtools lack mdb-count; the export is then read to the end to count rows.
+			header, rows, total, err := m.export(name, limit, table.RowCount < 0)
+			if err != nil {
+				return nil, err
+			}
+			if table.Columns == nil {
+				for _, column := range header {
+					table.Columns = append(table.Columns, DataColumn{Name: column, Nullable: true})
+				}
+			}
+			if table.RowCount < 0 {
+				table.RowCount = total
+			}
+			if opts.includeRows {
+				table.Truncated = table.RowCount > int64(len(rows))
+				summary.rows = append(summary.rows, rows)
+			}
+		}
+		if table.Columns == nil {
+			table.Columns = []DataColumn{}
+		}
+		summary.tables = append(summary.tables, table)
+	}
+	return summary, nil
+}
+
+// parseAccessSchema parses the CREATE TABLE statements printed by mdb-schema, keyed by
+// lower-cased table name.
+func parseAccessSchema(ddl string) map[string][]DataColumn {
+	schema := map[string][]DataColumn{}
+	upper := strings.ToUpper(ddl)
+	for start := strings.Index(upper, "CREATE TABLE"); start >= 0; {
+		next := strings.Index(upper[start+1:], "CREATE TABLE")
+		end := len(ddl)
+		if next >= 0 {
+			end = start + 1 + next
+		}
+		statement := ddl[start:end]
+		if semi := strings.Index(statement, ";"); semi >= 0 {
+			statement = statement[:semi]
+		}
+		header := strings.TrimSpace(statement[len("CREATE TABLE"):])
+		if open := strings.Index(header, "("); open >= 0 {
+			tokens := sqlTokens(header[:open])
+			if len(tokens) > 0 {
+				schema[strings.ToLower(unquoteSQLIdent(tokens[len(tokens)-1]))] = parseSQLiteCreateTable(statement).columns
+			}
+		}
+		if next < 0 {
+			break
+		}
+		start = end
+	}
+	return schema
+}
@@ -0,0 +1,556 @@
+package kreuzberg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+var sqliteMagic = []byte("SQLite format 3\x00")
+
+// sqliteMaxDepth bounds b-tree descent so corrupt files cannot recurse forever.
+const sqliteMaxDepth = 64
+
+const (
+	sqliteInteriorIndex = 2
+	sqliteInteriorTable = 5
+	sqliteLeafIndex     = 10
+	sqliteLeafTable     = 13
+)
+
+var sqliteEncodings = map[uint32]string{1: "utf-8", 2: "utf-16le", 3: "utf-16be"}
+
+// sqliteFile reads the b-tree pages of an in-memory SQLite database.
+type sqliteFile struct {
+	data     []byte
+	pageSize int
+	usable   int
+	encoding string
+}
+
+// sqliteVarint decodes SQLite's big-endian variable-length integer.
+func sqliteVarint(b []byte) (int64, int) {
+	var v uint64
+	for i := 0; i < 9 && i < len(b); i++ {
+		if i == 8 {
+			return int64(v<<8 | uint64(b[i])), 9
+		}
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i]&0x80 == 0 {
+			return int64(v), i + 1
+		}
+	}
+	return 0, 0
+}
+
+// page returns page n (1-based) and the offset of its b-tree header.
+func (f *sqliteFile) page(n uint32) ([]byte, int, error) {
+	start := int64(n-1) * int64(f.pageSize)
+	if n == 0 || start+int64(f.pageSize) > int64(len(f.data)) {
+		return nil, 0, fmt.Errorf("page %d out of range", n)
+	}
+	headerOffset := 0
+	if n == 1 {
+		headerOffset = 100
+	}
+	return f.data[start : start+int64(f.pageSize)], headerOffset, nil
+}
+
+// sqliteCellCount returns the number of cells of the b-tree page whose header of headerSize bytes
+// starts at page[h], checking that the cell pointer array fits in the page.
+func sqliteCellCount(page []byte, h, headerSize int) (int, error) {
+	cells := int(binary.BigEndian.Uint16(page[h+3:]))
+	if h+headerSize+2*cells > len(page) {
+		return 0, fmt.Errorf("cell pointer array of %d cells overflows the page", cells)
+	}
+	return cells, nil
+}
+
+// payload assembles a cell payload of total bytes starting at page[offset], following overflow
+// pages when the payload does not fit locally.
+func (f *sqliteFile) payload(page []byte, offset int, total int64, index bool) ([]byte, error) {
+	maxLocal := f.usable - 35
+	if index {
+		maxLocal = (f.usable-12)*64/255 - 23
+	}
+	if total < 0 || total > int64(len(f.data)) {
+		return nil, fmt.Errorf("invalid payload size %d", total)
+	}
+	if total <= int64(maxLocal) {
+		if offset+int(total) > len(page) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return page[offset : offset+int(total)], nil
+	}
+
+	minLocal := (f.usable-12)*32/255 - 23
+	local := minLocal + int((total-int64(minLocal))%int64(f.usable-4))
+	if local > maxLocal {
+		local = minLocal
+	}
+	if offset+local+4 > len(page) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	out := make([]byte, 0, total)
+	out = append(out, page[offset:offset+local]...)
+	next := binary.BigEndian.Uint32(page[offset+local:])
+	for visited := 0; int64(len(out)) < total; visited++ {
+		overflow, _, err := f.page(next)
+		if err != nil || visited > len(f.data)/f.pageSize {
+			return nil, fmt.Errorf("broken overflow chain: %v", err)
+		}
+		chunk := overflow[4:f.usable]
+		if remaining := total - int64(len(out)); int64(len(chunk)) > remaining {
+			chunk = chunk[:remaining]
+		}
+		out = append(out, chunk...)
+		next = binary.BigEndian.Uint32(overflow)
+	}
+	return out, nil
+}
+
+// errSQLiteStop ends a b-tree walk early.
+var errSQLiteStop = errors.New("stop")
+
+// walkTable visits every row of the table b-tree rooted at root in rowid order. visit may be nil
+// to only count rows; it receives a loader so callers skip payload assembly for rows they ignore.
+func (f *sqliteFile) walkTable(root uint32, visit func(rowid int64, load func() ([]byte, error)) error) (int64, error) {
+	var count int64
+	var walk func(n uint32, depth int) error
+	walk = func(n uint32, depth int) error {
+		if depth > sqliteMaxDepth {
+			return errors.New("b-tree too deep")
+		}
+		page, h, err := f.page(n)
+		if err != nil {
+			return err
+		}
+		pageType := page[h]
+		switch pageType {
+		case sqliteInteriorTable:
+			cells, err := sqliteCellCount(page, h, 12)
+			if err != nil {
+				return err
+			}
+			for i := 0; i < cells; i++ {
+				ptr := int(binary.BigEndian.Uint16(page[h+12+2*i:]))
+				if ptr+4 > len(page) {
+					return io.ErrUnexpectedEOF
+				}
+				if err := walk(binary.BigEndian.Uint32(page[ptr:]), depth+1); err != nil {
+					return err
+				}
+			}
+			return walk(binary.BigEndian.Uint32(page[h+8:]), depth+1)
+		case sqliteLeafTable:
+			cells, err := sqliteCellCount(page, h, 8)
+			if err != nil {
+				return err
+			}
+			for i := 0; i < cells; i++ {
+				count++
+				if visit == nil {
+					continue
+				}
+				ptr := int(binary.BigEndian.Uint16(page[h+8+2*i:]))
+				size, n1 := sqliteVarint(page[min(ptr, len(page)):])
+				rowid, n2 := sqliteVarint(page[min(ptr+n1, len(page)):])
+				if n1 == 0 || n2 == 0 {
+					return io.ErrUnexpectedEOF
+				}
+				offset := ptr + n1 + n2
+				if err := visit(rowid, func() ([]byte, error) { return f.payload(page, offset, size, false) }); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return fmt.Errorf("page %d is not a table b-tree page (type %d)", n, pageType)
+	}
+	err := walk(root, 0)
+	if errors.Is(err, errSQLiteStop) {
+		err = nil
+	}
+	return count, err
+}
+
+// countIndex counts the entries of an index b-tree, used for WITHOUT ROWID tables.
+func (f *sqliteFile) countIndex(root uint32) (int64, error) {
+	var walk func(n uint32, depth int) (int64, error)
+	walk = func(n uint32, depth int) (int64, error) {
+		if depth > sqliteMaxDepth {
+			return 0, errors.New("b-tree too deep")
+		}
+		page, h, err := f.page(n)
+		if err != nil {
+			return 0, err
+		}
+		switch page[h] {
+		case sqliteLeafIndex:
+			cells, err := sqliteCellCount(page, h, 8)
+			return int64(cells), err
+		case sqliteInteriorIndex:
+			cells, err := sqliteCellCount(page, h, 12)
+			if err != nil {
+				return 0, err
+			}
+			total := int64(cells)
+			for i := 0; i < cells; i++ {
+				ptr := int(binary.BigEndian.Uint16(page[h+12+2*i:]))
+				if ptr+4 > len(page) {
+					return 0, io.ErrUnexpectedEOF
+				}
+				count, err := walk(binary.BigEndian.Uint32(page[ptr:]), depth+1)
+				if err != nil {
+					return 0, err
+				}
+				total += count
+			}
+			count, err := walk(binary.BigEndian.Uint32(page[h+8:]), depth+1)
+			return total + count, err
+		}
+		return 0, fmt.Errorf("page %d is not an index b-tree page", n)
+	}
+	return walk(root, 0)
+}
+
+// decodeRecord decodes a record payload into nil, int64, float64, string or []byte values.
+func (f *sqliteFile) decodeRecord(payload []byte) ([]any, error) {
+	headerSize, n := sqliteVarint(payload)
+	if n == 0 || headerSize > int64(len(payload)) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	var types []int64
+	for pos := n; pos < int(headerSize); {
+		t, n := sqliteVarint(payload[pos:headerSize])
+		if n == 0 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		types = append(types, t)
+		pos += n
+	}
+
+	values := make([]any, len(types))
+	body := payload[headerSize:]
+	for i, t := range types {
+		var size int
+		switch {
+		case t >= 1 && t <= 4:
+			size = int(t)
+		case t == 5:
+			size = 6
+		case t == 6 || t == 7:
+			size = 8
+		case t >= 12:
+			size = int((t - 12) / 2)
+		}
+		if size > len(body) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		field := body[:size]
+		body = body[size:]
+
+		switch {
+		case t == 0:
+			values[i] = nil
+		case t >= 1 && t <= 6:
+			v := int64(int8(field[0]))
+			for _, b := range field[1:] {
+				v = v<<8 | int64(b)
+			}
+			values[i] = v
+		case t == 7:
+			values[i] = math.Float64frombits(binary.BigEndian.Uint64(field))
+		case t == 8, t == 9:
+			values[i] = t - 8
+		case t >= 12 && t%2 == 0:
+			values[i] = append([]byte(nil), field...)
+		case t >= 13:
+			text, _, err := decodeText(field, f.encoding)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = text
+		default:
+			return nil, fmt.Errorf("reserved serial type %d", t)
+		}
+	}
+	return values, nil
+}
+
+// parseSQLite lists the tables of a SQLite database with their schema and row counts, and
+// extracts rows when opts asks for them.
+func parseSQLite(data []byte, opts databaseOptions) (*databaseSummary, error) {
+	if len(data) < 100 || !bytes.HasPrefix(data, sqliteMagic) {
+		return nil, newUnsupportedFormatErrorWithContext("application/vnd.sqlite3", "not a SQLite 3 database", nil, ErrorCodeUnsupportedFormat, nil)
+	}
+	pageSize := int(binary.BigEndian.Uint16(data[16:]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 {
+		return nil, newParsingErrorWithContext(fmt.Sprintf("invalid SQLite page size %d", pageSize), nil, ErrorCodeParsing, nil)
+	}
+	f := &sqliteFile{
+		data:     data,
+		pageSize: pageSize,
+		usable:   pageSize - int(data[20]),
+		encoding: sqliteEncodings[binary.BigEndian.Uint32(data[56:])],
+	}
+	if f.encoding == "" {
+		f.encoding = "utf-8"
+	}
+	fail := func(err error) (*databaseSummary, error) {
+		return nil, newParsingErrorWithContext("failed to read SQLite database", err, ErrorCodeParsing, nil)
+	}
+
+	type schemaEntry struct {
+		name string
+		root uint32
+		sql  string
+	}
+	var entries []schemaEntry
+	if _, err := f.walkTable(1, func(_ int64, load func() ([]byte, error)) error {
+		payload, err := load()
+		if err != nil {
+			return err
+		}
+		record, err := f.decodeRecord(payload)
+		if err != nil {
+			return err
+		}
+		if len(record) < 5 || record[0] != "table" {
+			return nil
+		}
+		name, _ := record[1].(string)
+		root, _ := record[3].(int64)
+		sql, _ := record[4].(string)
+		if !strings.HasPrefix(name, "sqlite_") && opts.wantTable(name) {
+			entries = append(entries, schemaEntry{name: name, root: uint32(root), sql: sql})
+		}
+		return nil
+	}); err != nil {
+		return fail(err)
+	}
+
+	summary := &databaseSummary{
+		format: "SQLite",
+		properties: map[string]string{
+			"page_size":     fmt.Sprint(pageSize),
+			"text_encoding": f.encoding,
+		},
+	}
+	if opts.includeRows {
+		summary.rows = make([][][]string, 0, len(entries))
+	}
+	for _, entry := range entries {
+		def := parseSQLiteCreateTable(entry.sql)
+		table := DatabaseTable{Name: entry.name, Columns: def.columns}
+		if table.Columns == nil {
+			table.Columns = []DataColumn{}
+		}
+
+		if def.withoutRowid {
+			count, err := f.countIndex(entry.root)
+			if err != nil {
+				return fail(fmt.Errorf("table %s: %w", entry.name, err))
+			}
+			table.RowCount = count
+			summary.tables = append(summary.tables, table)
+			if summary.rows != nil {
+				// Rows of WITHOUT ROWID tables are stored in primary-key order; only counts are reported.
+				summary.rows = append(summary.rows, [][]string{})
+			}
+			continue
+		}
+
+		var rows [][]string
+		var visit func(int64, func() ([]byte, error)) error
+		if opts.includeRows {
+			visit = func(rowid int64, load func() ([]byte, error)) error {
+				if len(rows) >= opts.maxRows {
+					return nil
+				}
+				payload, err := load()
+				if err != nil {
+					return err
+				}
+				record, err := f.decodeRecord(payload)
+				if err != nil {
+					return err
+				}
+				row := make([]string, len(table.Columns))
+				for i := range row {
+					switch {
+					case i == def.rowidAlias:
+						row[i] = fmt.Sprint(rowid)
+					case i < len(record):
+						row[i] = formatPreviewValue(record[i])
+					}
+				}
+				rows = append(rows, row)
+				return nil
+			}
+		}
+		count, err := f.walkTable(entry.root, visit)
+		if err != nil {
+			return fail(fmt.Errorf("table %s: %w", entry.name, err))
+		}
+		table.RowCount = count
+		if opts.includeRows {
+			table.Truncated = count > int64(len(rows))
+			if rows == nil {
+				rows = [][]string{}
+			}
+			summary.rows = append(summary.rows, rows)
+		}
+		summary.tables = append(summary.tables, table)
+	}
+	return summary, nil
+}
+
+// sqliteTableDef is the part of a CREATE TABLE statement needed to describe and decode rows.
+type sqliteTableDef struct {
+	columns []DataColumn
+	// rowidAlias is the index of an INTEGER PRIMARY KEY column, whose value is the rowid
+	// rather than a record field (-1 when absent).
+	rowidAlias   int
+	withoutRowid bool
+}
+
+var sqliteConstraintKeywords = map[string]bool{
+	"CONSTRAINT": true, "PRIMARY": true, "NOT": true, "NULL": true, "UNIQUE": true, "CHECK": true,
+	"DEFAULT": true, "COLLATE": true, "REFERENCES": true, "GENERATED": true, "AS": true,
+}
+
+var sqliteTableConstraints = map[string]bool{"CONSTRAINT": true, "PRIMARY": true, "UNIQUE": true, "CHECK": true, "FOREIGN": true}
+
+// parseSQLiteCreateTable extracts column names, declared types and nullability from a
+// CREATE TABLE statement.
+func parseSQLiteCreateTable(sql string) sqliteTableDef {
+	def := sqliteTableDef{rowidAlias: -1}
+	open := strings.Index(sql, "(")
+	closing := strings.LastIndex(sql, ")")
+	if open < 0 || closing < open {
+		return def
+	}
+	def.withoutRowid = strings.Contains(strings.ToUpper(strings.Join(strings.Fields(sql[closing+1:]), " ")), "WITHOUT ROWID")
+
+	for _, part := range splitSQLList(sql[open+1 : closing]) {
+		tokens := sqlTokens(part)
+		if len(tokens) == 0 || sqliteTableConstraints[strings.ToUpper(tokens[0])] {
+			continue
+		}
+		column := DataColumn{Name: unquoteSQLIdent(tokens[0]), Nullable: true}
+		var typeTokens []string
+		i := 1
+		for ; i < len(tokens) && !sqliteConstraintKeywords[strings.ToUpper(tokens[i])]; i++ {
+			typeTokens = append(typeTokens, tokens[i])
+		}
+		column.Type = strings.Join(typeTokens, " ")
+		constraints := strings.ToUpper(strings.Join(tokens[i:], " "))
+		if strings.Contains(constraints, "NOT NULL") {
+			column.Nullable = false
+		}
+		if strings.Contains(constraints, "PRIMARY KEY") {
+			column.Nullable = false
+			if strings.EqualFold(column.Type, "INTEGER") && def.rowidAlias < 0 {
+				def.rowidAlias = len(def.columns)
+			}
+		}
+		def.columns = append(def.columns, column)
+	}
+	if def.withoutRowid {
+		def.rowidAlias = -1
+	}
+	return def
+}
+
+// splitSQLList splits s on commas that are not nested in parentheses or quotes.
+func splitSQLList(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '[':
+			quote = ']'
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// sqlTokens splits a column definition into identifiers, keeping quoted identifiers and
+// parenthesized type arguments such as "(10, 2)" attached to the preceding token.
+func sqlTokens(s string) []string {
+	var tokens []string
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"' || c == '`' || c == '[' || c == '\'':
+			end := byte(c)
+			if c == '[' {
+				end = ']'
+			}
+			j := strings.IndexByte(s[i+1:], end) + i + 1
+			if j <= i {
+				j = len(s) - 1
+			}
+			tokens = append(tokens, s[i:j+1])
+			i = j + 1
+		case c == '(':
+			j := strings.IndexByte(s[i:], ')')
+			if j < 0 {
+				j = len(s) - i - 1
+			}
+			if len(tokens) > 0 {
+				tokens[len(tokens)-1] += s[i : i+j+1]
+			}
+			i += j + 1
+		default:
+			j := strings.IndexAny(s[i:], " \t\n\r(")
+			if j < 0 {
+				j = len(s) - i
+			}
+			tokens = append(tokens, s[i:i+j])
+			i += j
+		}
+	}
+	return tokens
+}
+
+func unquoteSQLIdent(s string) string {
+	if len(s) >= 2 {
+		switch s[0] {
+		case '"', '`', '\'':
+			if s[len(s)-1] == s[0] {
+				return s[1 : len(s)-1]
+			}
+		case '[':
+			if s[len(s)-1] == ']' {
+				return s[1 : len(s)-1]
+			}
+		}
+	}
+	return s
+}
@@ -0,0 +1,260 @@
+package kreuzberg
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+const testSQLitePageSize = 512
+
+func appendSQLiteVarint(out []byte, v int64) []byte {
+	var groups []byte
+	for u := uint64(v); ; u >>= 7 {
+		groups = append([]byte{byte(u & 0x7f)}, groups...)
+		if u < 0x80 {
+			break
+		}
+	}
+	for i := range groups[:len(groups)-1] {
+		groups[i] |= 0x80
+	}
+	return append(out, groups...)
+}
+
+func sqliteRecord(values ...any) []byte {
+	var header, body []byte
+	for _, value := range values {
+		switch v := value.(type) {
+		case nil:
+			header = appendSQLiteVarint(header, 0)
+		case int:
+			header = appendSQLiteVarint(header, 6)
+			body = binary.BigEndian.AppendUint64(body, uint64(v))
+		case string:
+			header = appendSQLiteVarint(header, int64(13+2*len(v)))
+			body = append(body, v...)
+		}
+	}
+	return append(appendSQLiteVarint(nil, int64(len(header)+1)), append(header, body...)...)
+}
+
+// writeSQLitePage lays out page n of db as a b-tree page holding cells.
+func writeSQLitePage(db []byte, n int, pageType byte, rightMost uint32, cells [][]byte) {
+	page := db[(n-1)*testSQLitePageSize : n*testSQLitePageSize]
+	h := 0
+	if n == 1 {
+		h = 100
+	}
+	headerSize := 8
+	if pageType == sqliteInteriorTable || pageType == sqliteInteriorIndex {
+		headerSize = 12
+		binary.BigEndian.PutUint32(page[h+8:], rightMost)
+	}
+	page[h] = pageType
+	binary.BigEndian.PutUint16(page[h+3:], uint16(len(cells)))
+	end := len(page)
+	for i, cell := range cells {
+		end -= len(cell)
+		copy(page[end:], cell)
+		binary.BigEndian.PutUint16(page[h+headerSize+2*i:], uint16(end))
+	}
+	binary.BigEndian.PutUint16(page[h+5:], uint16(end))
+}
+
+func tableLeafCell(rowid int64, payload []byte) []byte {
+	return append(appendSQLiteVarint(appendSQLiteVarint(nil, int64(len(payload))), rowid), payload...)
+}
+
+func buildSQLite(t *testing.T) (data []byte, longNote string) {
+	t.Helper()
+	db := make([]byte, 6*testSQLitePageSize)
+	copy(db, sqliteMagic)
+	binary.BigEndian.PutUint16(db[16:], testSQLitePageSize)
+	db[18], db[19], db[21], db[22], db[23] = 1, 1, 64, 32, 32
+	binary.BigEndian.PutUint32(db[28:], 6)
+	binary.BigEndian.PutUint32(db[56:], 1)
+
+	writeSQLitePage(db, 1, sqliteLeafTable, 0, [][]byte{
+		tableLeafCell(1, sqliteRecord("table", "people", "people", 2, `CREATE TABLE "people" (id INTEGER PRIMARY KEY, name TEXT NOT NULL, balance DECIMAL(10, 2), note TEXT)`)),
+		tableLeafCell(2, sqliteRecord("table", "kv", "kv", 3, "CREATE TABLE kv (k TEXT PRIMARY KEY, v BLOB) WITHOUT ROWID")),
+		tableLeafCell(3, sqliteRecord("table", "sqlite_sequence", "sqlite_sequence", 4, "CREATE TABLE sqlite_sequence(name,seq)")),
+		tableLeafCell(4, sqliteRecord("index", "people_name", "people", 4, "CREATE INDEX people_name ON people(name)")),
+	})
+
+	// people: interior root (page 2) -> leaf page 5 (rows 1-2) and leaf page 6 (row 3, overflowing to page 4).
+	child := binary.BigEndian.AppendUint32(nil, 5)
+	writeSQLitePage(db, 2, sqliteInteriorTable, 6, [][]byte{appendSQLiteVarint(child, 2)})
+	writeSQLitePage(db, 5, sqliteLeafTable, 0, [][]byte{
+		tableLeafCell(1, sqliteRecord(nil, "Ada", 100, nil)),
+		tableLeafCell(2, sqliteRecord(nil, "Bob", 250, "short")),
+	})
+
+	longNote = strings.Repeat("0123456789", 60)
+	payload := sqliteRecord(nil, "Cy", 7, longNote)
+	const usable, minLocal = testSQLitePageSize, (testSQLitePageSize-12)*32/255 - 23
+	local := minLocal + (len(payload)-minLocal)%(usable-4)
+	cell := appendSQLiteVarint(appendSQLiteVarint(nil, int64(len(payload))), 3)
+	cell = append(cell, payload[:local]...)
+	cell = binary.BigEndian.AppendUint32(cell, 4)
+	writeSQLitePage(db, 6, sqliteLeafTable, 0, [][]byte{cell})
+	copy(db[3*testSQLitePageSize+4:], payload[local:])
+
+	// kv: WITHOUT ROWID tables are index b-trees.
+	writeSQLitePage(db, 3, sqliteLeafIndex, 0, [][]byte{
+		append([]byte{byte(len(sqliteRecord("a", "1")))}, sqliteRecord("a", "1")...),
+		append([]byte{byte(len(sqliteRecord("b", "2")))}, sqliteRecord("b", "2")...),
+	})
+	return db, longNote
+}
+
+func TestParseSQLiteSchemaAndCounts(t *testing.T) {
+	data, _ := buildSQLite(t)
+	summary, err := parseSQLite(data, newDatabaseOptions(nil))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if summary.rows != nil {
+		t.Fatalf("rows should not be extracted by default")
+	}
+	if len(summary.tables) != 2 {
+		t.Fatalf("unexpected tables: %+v", summary.tables)
+	}
+	people, kv := summary.tables[0], summary.tables[1]
+	if people.Name != "people" || people.RowCount != 3 || kv.Name != "kv" || kv.RowCount != 2 {
+		t.Fatalf("unexpected tables: %+v", summary.tables)
+	}
+	want := []DataColumn{
+		{Name: "id", Type: "INTEGER"},
+		{Name: "name", Type: "TEXT"},
+		{Name: "balance", Type: "DECIMAL(10, 2)", Nullable: true},
+		{Name: "note", Type: "TEXT", Nullable: true},
+	}
+	for i, c := range want {
+		if people.Columns[i] != c {
+			t.Fatalf("column %d = %+v, want %+v", i, people.Columns[i], c)
+		}
+	}
+}
+
+func TestParseSQLiteRows(t *testing.T) {
+	data, longNote := buildSQLite(t)
+	config := &ExtractionConfig{Database: &DatabaseConfig{IncludeRows: true, MaxRows: 10, Tables: []string{"PEOPLE"}}}
+	summary, err := parseSQLite(data, newDatabaseOptions(config))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(summary.tables) != 1 || len(summary.rows) != 1 {
+		t.Fatalf("table filter not applied: %+v", summary.tables)
+	}
+	rows := summary.rows[0]
+	if len(rows) != 3 || strings.Join(rows[0], ",") != "1,Ada,100," || strings.Join(rows[1], ",") != "2,Bob,250,short" {
+		t.Fatalf("unexpected rows: %v", rows)
+	}
+	if rows[2][0] != "3" || rows[2][3] != longNote {
+		t.Fatalf("overflow payload not reassembled: %q", rows[2][3])
+	}
+
+	config.Database.MaxRows = 2
+	summary, err = parseSQLite(data, newDatabaseOptions(config))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(summary.rows[0]) != 2 || !summary.tables[0].Truncated || summary.tables[0].RowCount != 3 {
+		t.Fatalf("row cap not honored: %+v", summary.tables[0])
+	}
+
+	result, err := summary.toResult("application/vnd.sqlite3")
+	if err != nil {
+		t.Fatalf("toResult: %v", err)
+	}
+	if len(result.Tables) != 1 || !strings.Contains(result.Content, "(showing first 2 of 3 rows)") {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	var tables []DatabaseTable
+	if err := json.Unmarshal(result.Metadata.Additional["database_tables"], &tables); err != nil || len(tables) != 1 || tables[0].RowCount != 3 {
+		t.Fatalf("unexpected database_tables: %s", result.Metadata.Additional["database_tables"])
+	}
+}
+
+func TestParseSQLiteRejectsOtherFiles(t *testing.T) {
+	if _, err := parseSQLite([]byte(strings.Repeat("x", 200)), newDatabaseOptions(nil)); err == nil {
+		t.Fatalf("expected error for non-SQLite data")
+	}
+}
+
+func TestParseSQLiteRejectsCorruptCellCounts(t *testing.T) {
+	// Page 2 is the interior root of "people", page 5 one of its leaves and page 3 the leaf of
+	// the WITHOUT ROWID table "kv".
+	for _, pageNumber := range []int{2, 3, 5} {
+		data, _ := buildSQLite(t)
+		binary.BigEndian.PutUint16(data[(pageNumber-1)*testSQLitePageSize+3:], 0xffff)
+		config := &ExtractionConfig{Database: &DatabaseConfig{IncludeRows: true, MaxRows: 10}}
+		_, err := parseSQLite(data, newDatabaseOptions(config))
+		var parsingErr *ParsingError
+		if !errors.As(err, &parsingErr) {
+			t.Fatalf("page %d: expected a parsing error, got %v", pageNumber, err)
+		}
+	}
+}
+
+// fakeMDBTools installs shell scripts that mimic mdbtools for a database with one table.
+func fakeMDBTools(t *testing.T, withCount bool) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake mdbtools scripts require a POSIX shell")
+	}
+	dir := t.TempDir()
+	scripts := map[string]string{
+		"mdb-tables": "printf 'Customers\\nOrders\\n'",
+		"mdb-schema": "printf 'CREATE TABLE [Customers]\\n (\\n\\t[ID]\\t\\t\\tLong Integer NOT NULL, \\n\\t[Name]\\t\\t\\tText (50)\\n);\\n'",
+		"mdb-export": `if [ "$2" = Customers ]; then printf 'ID,Name\n1,"Acme, Inc"\n2,Globex\n3,Initech\n'; else printf 'OrderID\n10\n'; fi`,
+	}
+	if withCount {
+		scripts["mdb-count"] = `if [ "$2" = Customers ]; then echo 3; else echo 1; fi`
+	}
+	for name, body := range scripts {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+body+"\n"), 0o700); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	t.Setenv("KREUZBERG_MDBTOOLS_PATH", dir)
+}
+
+func TestExtractAccessWithMDBTools(t *testing.T) {
+	for _, withCount := range []bool{true, false} {
+		fakeMDBTools(t, withCount)
+		opts := newDatabaseOptions(&ExtractionConfig{Database: &DatabaseConfig{IncludeRows: true, MaxRows: 2}})
+		summary, err := extractAccess(documentSource{data: []byte("access bytes")}, opts)
+		if err != nil {
+			t.Fatalf("extract (mdb-count=%v): %v", withCount, err)
+		}
+		if len(summary.tables) != 2 {
+			t.Fatalf("unexpected tables: %+v", summary.tables)
+		}
+		customers := summary.tables[0]
+		if customers.RowCount != 3 || !customers.Truncated || len(customers.Columns) != 2 || customers.Columns[1].Type != "Text(50)" || customers.Columns[0].Nullable {
+			t.Fatalf("unexpected customers table (mdb-count=%v): %+v", withCount, customers)
+		}
+		if len(summary.rows[0]) != 2 || summary.rows[0][0][1] != "Acme, Inc" {
+			t.Fatalf("unexpected rows: %v", summary.rows[0])
+		}
+		if orders := summary.tables[1]; orders.RowCount != 1 || orders.Columns[0].Name != "OrderID" {
+			t.Fatalf("unexpected orders table: %+v", orders)
+		}
+	}
+}
+
+func TestExtractAccessRequiresMDBTools(t *testing.T) {
+	t.Setenv("KREUZBERG_MDBTOOLS_PATH", "")
+	t.Setenv("PATH", t.TempDir())
+	_, err := extractAccess(documentSource{path: "db.mdb"}, newDatabaseOptions(nil))
+	if _, ok := err.(*MissingDependencyError); !ok {
+		t.Fatalf("expected MissingDependencyError, got %T %v", err, err)
+	}
+}
@@ -0,0 +1,181 @@
+package kreuzberg
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const defaultDataFilePreviewRows = 20
+
+// DataFileConfig tunes extraction of columnar data files (Parquet, Avro, ORC). These formats
+// are always handled by the Go binding; the config only adjusts the preview.
+type DataFileConfig struct {
+	// PreviewRows is the number of rows rendered in the preview table (default 20, negative
+	// disables the preview). Previews are available for Avro files using the null or deflate
+	// codec. Parquet and ORC report schema and row counts from the file footer only, so a
+	// positive PreviewRows is rejected with a ValidationError for those formats.
+	PreviewRows int
+}
+
+// DataColumn describes a column of a data file, as reported in Metadata.Additional["columns"].
+type DataColumn struct {
+	// Name is the column name; nested fields are joined with ".".
+	Name string `json:"name"`
+	// Type is the column type as named by the file format (e.g., "INT64", "string", "struct").
+	Type string `json:"type"`
+	// Nullable reports whether the column may hold nulls, when the format records it.
+	Nullable bool `json:"nullable"`
+}
+
+// dataFileSummary is the format-independent description of a data file.
+type dataFileSummary struct {
+	format   string
+	rowCount int64
+	columns  []DataColumn
+	// preview holds sampled rows aligned with columns (nil when unavailable).
+	preview [][]string
+	// properties carries format-level facts such as codec or writer.
+	properties map[string]string
+	// keyValues carries user metadata stored in the file.
+	keyValues map[string]string
+}
+
+func dataFilePreviewRows(config *ExtractionConfig) int {
+	if config == nil || config.DataFiles == nil || config.DataFiles.PreviewRows == 0 {
+		return defaultDataFilePreviewRows
+	}
+	return max(config.DataFiles.PreviewRows, 0)
+}
+
+func (s *dataFileSummary) toResult(mimeType string) (*ExtractionResult, error) {
+	schema := [][]string{{"column", "type", "nullable"}}
+	for _, c := range s.columns {
+		schema = append(schema, []string{c.Name, c.Type, strconv.FormatBool(c.Nullable)})
+	}
+	schemaMarkdown := spreadsheetMarkdown(schema)
+
+	result := &ExtractionResult{
+		MimeType: mimeType,
+		Tables:   []Table{{Cells: schema, Markdown: schemaMarkdown, PageNumber: 1}},
+		Success:  true,
+	}
+	sections := []string{
+		fmt.Sprintf("## %s schema\n\n%s", s.format, strings.TrimRight(schemaMarkdown, "\n")),
+		fmt.Sprintf("Rows: %d", s.rowCount),
+	}
+
+	if len(s.preview) > 0 {
+		header := make([]string, len(s.columns))
+		for i, c := range s.columns {
+			header[i] = c.Name
+		}
+		cells := append([][]string{header}, s.preview...)
+		previewMarkdown := spreadsheetMarkdown(cells)
+		result.Tables = append(result.Tables, Table{Cells: cells, Markdown: previewMarkdown, PageNumber: 1})
+		sections = append(sections, fmt.Sprintf("## Preview (first %d rows)\n\n%s", len(s.preview), strings.TrimRight(previewMarkdown, "\n")))
+	}
+	result.Content = strings.Join(sections, "\n\n")
+
+	additional := map[string]any{
+		"data_format": s.format,
+		"row_count":   s.rowCount,
+		"columns":     s.columns,
+	}
+	if len(s.properties) > 0 {
+		additional["data_file_properties"] = s.properties
+	}
+	if len(s.keyValues) > 0 {
+		additional["key_value_metadata"] = s.keyValues
+	}
+	result.Metadata.Additional = make(map[string]json.RawMessage, len(additional))
+	for key, value := range additional {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, newSerializationErrorWithContext("failed to encode data file metadata", err, ErrorCodeValidation, nil)
+		}
+		result.Metadata.Additional[key] = raw
+	}
+	return result, nil
+}
+
+// dataFileExtractor adapts a format parser to the goPrimaryExtractor signature.
+func dataFileExtractor(parse func(data []byte, previewRows int) (*dataFileSummary, error)) fallbackFunc {
+	return func(src documentSource, mimeType string, config *ExtractionConfig) (*ExtractionResult, error) {
+		data, err := src.bytes()
+		if err != nil {
+			return nil, err
+		}
+		summary, err := parse(data, dataFilePreviewRows(config))
+		if err != nil {
+			return nil, err
+		}
+		return summary.toResult(mimeType)
+	}
+}
+
+// dataFileFooterExtractor adapts a parser that reads only the file footer. Since no rows are
+// decoded, an explicitly requested preview is an error rather than silently omitted.
+func dataFileFooterExtractor(format string, parse func(data []byte) (*dataFileSummary, error)) fallbackFunc {
+	return func(src documentSource, mimeType string, config *ExtractionConfig) (*ExtractionResult, error) {
+		if config != nil && config.DataFiles != nil && config.DataFiles.PreviewRows > 0 {
+			msg := fmt.Sprintf("DataFiles.PreviewRows is not supported for %s files: only the footer is read", format)
+			return nil, newValidationErrorWithContext(msg, nil, ErrorCodeValidation, nil)
+		}
+		data, err := src.bytes()
+		if err != nil {
+			return nil, err
+		}
+		summary, err := parse(data)
+		if err != nil {
+			return nil, err
+		}
+		return summary.toResult(mimeType)
+	}
+}
+
+// formatPreviewValue renders a decoded value for a preview cell.
+func formatPreviewValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return fmt.Sprintf("0x%x", v)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case int32, int64, bool:
+		return fmt.Sprint(v)
+	default:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(raw)
+	}
+}
+
+func init() {
+	registerGoPrimaryExtractor(goPrimaryExtractor{
+		name:       "parquet",
+		mimeTypes:  []string{"application/vnd.apache.parquet", "application/x-parquet"},
+		extensions: map[string]string{"parquet": "application/vnd.apache.parquet"},
+		extract:    dataFileFooterExtractor("Parquet", parseParquetFooter),
+	})
+	registerGoPrimaryExtractor(goPrimaryExtractor{
+		name:       "avro",
+		mimeTypes:  []string{"application/vnd.apache.avro", "application/avro", "avro/binary"},
+		extensions: map[string]string{"avro": "application/vnd.apache.avro"},
+		extract:    dataFileExtractor(parseAvroContainer),
+	})
+	registerGoPrimaryExtractor(goPrimaryExtractor{
+		name:       "orc",
+		mimeTypes:  []string{"application/vnd.apache.orc", "application/x-orc"},
+		extensions: map[string]string{"orc": "application/vnd.apache.orc"},
+		extract:    dataFileFooterExtractor("ORC", parseORCFooter),
+	})
+}
@@ -0,0 +1,465 @@
+package kreuzberg
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+)
+
+var avroMagic = []byte("Obj\x01")
+
+// avroReader decodes the Avro binary encoding.
+type avroReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *avroReader) long() (int64, error) {
+	var value uint64
+	for shift := uint(0); shift < 64; shift += 7 {
+		if r.pos >= len(r.data) {
+			return 0, io.ErrUnexpectedEOF
+		}
+		b := r.data[r.pos]
+		r.pos++
+		value |= uint64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return int64(value>>1) ^ -int64(value&1), nil
+		}
+	}
+	return 0, fmt.Errorf("varint overflows 64 bits")
+}
+
+func (r *avroReader) fixed(n int64) ([]byte, error) {
+	if n < 0 || int64(len(r.data)-r.pos) < n {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := r.data[r.pos : r.pos+int(n)]
+	r.pos += int(n)
+	return b, nil
+}
+
+func (r *avroReader) bytes() ([]byte, error) {
+	n, err := r.long()
+	if err != nil {
+		return nil, err
+	}
+	return r.fixed(n)
+}
+
+// blockCount reads the item count of an array/map block; negative counts are followed by the
+// block's byte size, which is not needed for sequential decoding.
+func (r *avroReader) blockCount() (int64, error) {
+	count, err := r.long()
+	if err != nil || count >= 0 {
+		return count, err
+	}
+	if _, err := r.long(); err != nil {
+		return 0, err
+	}
+	return -count, nil
+}
+
+// parseAvroContainer reads an Avro object container file: the schema and metadata from the
+// header, the row count from the block headers, and up to previewRows decoded rows.
+func parseAvroContainer(data []byte, previewRows int) (*dataFileSummary, error) {
+	if !bytes.HasPrefix(data, avroMagic) {
+		return nil, newParsingErrorWithContext("not an Avro object container file", nil, ErrorCodeParsing, nil)
+	}
+	r := &avroReader{data: data, pos: len(avroMagic)}
+	fail := func(err error) (*dataFileSummary, error) {
+		return nil, newParsingErrorWithContext("failed to read Avro container", err, ErrorCodeParsing, nil)
+	}
+
+	meta := map[string][]byte{}
+	for {
+		count, err := r.blockCount()
+		if err != nil {
+			return fail(err)
+		}
+		if count == 0 {
+			break
+		}
+		for ; count > 0; count-- {
+			key, err := r.bytes()
+			if err != nil {
+				return fail(err)
+			}
+			value, err := r.bytes()
+			if err != nil {
+				return fail(err)
+			}
+			meta[string(key)] = value
+		}
+	}
+	sync, err := r.fixed(16)
+	if err != nil {
+		return fail(err)
+	}
+
+	var schema any
+	if err := json.Unmarshal(meta["avro.schema"], &schema); err != nil {
+		return nil, newParsingErrorWithContext("invalid Avro schema", err, ErrorCodeParsing, nil)
+	}
+	codec := string(meta["avro.codec"])
+	if codec == "" {
+		codec = "null"
+	}
+
+	dec := &avroDecoder{named: map[string]any{}}
+	dec.register(schema, "")
+	root := dec.resolve(schema)
+
+	summary := &dataFileSummary{
+		format:     "Avro",
+		properties: map[string]string{"codec": codec},
+		keyValues:  map[string]string{},
+	}
+	for key, value := range meta {
+		if !strings.HasPrefix(key, "avro.") {
+			summary.keyValues[key] = string(value)
+		}
+	}
+
+	fields, isRecord := avroRecordFields(root)
+	if isRecord {
+		for _, f := range fields {
+			summary.columns = append(summary.columns, DataColumn{Name: f.name, Type: dec.typeName(f.schema), Nullable: avroNullable(f.schema)})
+		}
+	} else {
+		summary.columns = []DataColumn{{Name: "value", Type: dec.typeName(root), Nullable: avroNullable(root)}}
+	}
+
+	canPreview := previewRows > 0 && (codec == "null" || codec == "deflate")
+	for r.pos < len(r.data) {
+		count, err := r.long()
+		if err != nil {
+			return fail(err)
+		}
+		size, err := r.long()
+		if err != nil {
+			return fail(err)
+		}
+		block, err := r.fixed(size)
+		if err != nil {
+			return fail(err)
+		}
+		marker, err := r.fixed(16)
+		if err != nil {
+			return fail(err)
+		}
+		if !bytes.Equal(marker, sync) {
+			return nil, newParsingErrorWithContext("Avro block sync marker mismatch", nil, ErrorCodeParsing, nil)
+		}
+		summary.rowCount += count
+
+		if !canPreview || len(summary.preview) >= previewRows {
+			continue
+		}
+		if codec == "deflate" {
+			if block, err = io.ReadAll(flate.NewReader(bytes.NewReader(block))); err != nil {
+				return fail(err)
+			}
+		}
+		br := &avroReader{data: block}
+		for i := int64(0); i < count && len(summary.preview) < previewRows; i++ {
+			value, err := dec.decode(br, root)
+			if err != nil {
+				return fail(err)
+			}
+			summary.preview = append(summary.preview, avroPreviewRow(value, fields, isRecord))
+		}
+	}
+	return summary, nil
+}
+
+type avroField struct {
+	name   string
+	schema any
+}
+
+func avroRecordFields(schema any) ([]avroField, bool) {
+	m, ok := schema.(map[string]any)
+	if !ok || m["type"] != "record" {
+		return nil, false
+	}
+	raw, _ := m["fields"].([]any)
+	fields := make([]avroField, 0, len(raw))
+	for _, f := range raw {
+		if fm, ok := f.(map[string]any); ok {
+			name, _ := fm["name"].(string)
+			fields = append(fields, avroField{name: name, schema: fm["type"]})
+		}
+	}
+	return fields, true
+}
+
+func avroNullable(schema any) bool {
+	if union, ok := schema.([]any); ok {
+		for _, branch := range union {
+			if branch == "null" {
+				return true
+			}
+		}
+	}
+	return schema == "null"
+}
+
+func avroPreviewRow(value any, fields []avroField, isRecord bool) []string {
+	if !isRecord {
+		return []string{formatPreviewValue(value)}
+	}
+	record, _ := value.(map[string]any)
+	row := make([]string, len(fields))
+	for i, f := range fields {
+		row[i] = formatPreviewValue(record[f.name])
+	}
+	return row
+}
+
+// avroDecoder decodes values against a parsed Avro schema, resolving named type references.
+type avroDecoder struct {
+	named map[string]any
+}
+
+func (d *avroDecoder) register(schema any, namespace string) {
+	switch s := schema.(type) {
+	case []any:
+		for _, branch := range s {
+			d.register(branch, namespace)
+		}
+	case map[string]any:
+		if ns, ok := s["namespace"].(string); ok {
+			namespace = ns
+		}
+		if name, ok := s["name"].(string); ok {
+			switch s["type"] {
+			case "record", "enum", "fixed", "error":
+				d.named[name] = s
+				if namespace != "" && !strings.Contains(name, ".") {
+					d.named[namespace+"."+name] = s
+				}
+			}
+		}
+		if fields, ok := s["fields"].([]any); ok {
+			for _, f := range fields {
+				if fm, ok := f.(map[string]any); ok {
+					d.register(fm["type"], namespace)
+				}
+			}
+		}
+		for _, key := range []string{"items", "values"} {
+			if nested, ok := s[key]; ok {
+				d.register(nested, namespace)
+			}
+		}
+		if nested, ok := s["type"].(map[string]any); ok {
+			d.register(nested, namespace)
+		}
+	}
+}
+
+func (d *avroDecoder) resolve(schema any) any {
+	if name, ok := schema.(string); ok {
+		if named, ok := d.named[name]; ok {
+			return named
+		}
+	}
+	return schema
+}
+
+func (d *avroDecoder) typeName(schema any) string {
+	switch s := d.resolve(schema).(type) {
+	case string:
+		return s
+	case []any:
+		var names []string
+		for _, branch := range s {
+			if branch != "null" {
+				names = append(names, d.typeName(branch))
+			}
+		}
+		return strings.Join(names, "|")
+	case map[string]any:
+		if logical, ok := s["logicalType"].(string); ok {
+			return logical
+		}
+		if t, ok := s["type"].(string); ok {
+			return t
+		}
+		return d.typeName(s["type"])
+	}
+	return "unknown"
+}
+
+func (d *avroDecoder) decode(r *avroReader, schema any) (any, error) {
+	switch s := d.resolve(schema).(type) {
+	case string:
+		return d.decodePrimitive(r, s)
+	case []any:
+		index, err := r.long()
+		if err != nil {
+			return nil, err
+		}
+		if index < 0 || index >= int64(len(s)) {
+			return nil, fmt.Errorf("union index %d out of range", index)
+		}
+		return d.decode(r, s[index])
+	case map[string]any:
+		return d.decodeComplex(r, s)
+	}
+	return nil, fmt.Errorf("unsupported Avro schema %v", schema)
+}
+
+func (d *avroDecoder) decodePrimitive(r *avroReader, name string) (any, error) {
+	switch name {
+	case "null":
+		return nil, nil
+	case "boolean":
+		b, err := r.fixed(1)
+		if err != nil {
+			return nil, err
+		}
+		return b[0] != 0, nil
+	case "int", "long":
+		return r.long()
+	case "float":
+		b, err := r.fixed(4)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float32frombits(binary.LittleEndian.Uint32(b)), nil
+	case "double":
+		b, err := r.fixed(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+	case "bytes":
+		return r.bytes()
+	case "string":
+		b, err := r.bytes()
+		return string(b), err
+	}
+	return nil, fmt.Errorf("unknown Avro type %q", name)
+}
+
+func (d *avroDecoder) decodeComplex(r *avroReader, s map[string]any) (any, error) {
+	var value any
+	var err error
+	switch t := s["type"].(type) {
+	case string:
+		switch t {
+		case "record", "error":
+			fields, _ := avroRecordFields(map[string]any{"type": "record", "fields": s["fields"]})
+			record := make(map[string]any, len(fields))
+			for _, f := range fields {
+				if record[f.name], err = d.decode(r, f.schema); err != nil {
+					return nil, err
+				}
+			}
+			return record, nil
+		case "enum":
+			index, err := r.long()
+			if err != nil {
+				return nil, err
+			}
+			symbols, _ := s["symbols"].([]any)
+			if index < 0 || index >= int64(len(symbols)) {
+				return nil, fmt.Errorf("enum index %d out of range", index)
+			}
+			return symbols[index], nil
+		case "array":
+			var items []any
+			for {
+				count, err := r.blockCount()
+				if err != nil || count == 0 {
+					return items, err
+				}
+				for ; count > 0; count-- {
+					item, err := d.decode(r, s["items"])
+					if err != nil {
+						return nil, err
+					}
+					items = append(items, item)
+				}
+			}
+		case "map":
+			entries := map[string]any{}
+			for {
+				count, err := r.blockCount()
+				if err != nil || count == 0 {
+					return entries, err
+				}
+				for ; count > 0; count-- {
+					key, err := r.bytes()
+					if err != nil {
+						return nil, err
+					}
+					if entries[string(key)], err = d.decode(r, s["values"]); err != nil {
+						return nil, err
+					}
+				}
+			}
+		case "fixed":
+			size, _ := s["size"].(float64)
+			value, err = r.fixed(int64(size))
+		default:
+			value, err = d.decodePrimitive(r, t)
+		}
+	default:
+		value, err = d.decode(r, t)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return applyAvroLogicalType(s, value), nil
+}
+
+// applyAvroLogicalType renders date, timestamp and decimal values in readable form.
+func applyAvroLogicalType(s map[string]any, value any) any {
+	logical, _ := s["logicalType"].(string)
+	switch v := value.(type) {
+	case int64:
+		switch logical {
+		case "date":
+			return time.Unix(v*86400, 0).UTC().Format("2006-01-02")
+		case "timestamp-millis":
+			return time.UnixMilli(v).UTC().Format(time.RFC3339Nano)
+		case "timestamp-micros":
+			return time.UnixMicro(v).UTC().Format(time.RFC3339Nano)
+		}
+	case []byte:
+		if logical == "decimal" {
+			unscaled := new(big.Int).SetBytes(v)
+			if len(v) > 0 && v[0]&0x80 != 0 {
+				unscaled.Sub(unscaled, new(big.Int).Lsh(big.NewInt(1), uint(8*len(v))))
+			}
+			scale, _ := s["scale"].(float64)
+			return formatDecimal(unscaled, int(scale))
+		}
+	}
+	return value
+}
+
+func formatDecimal(unscaled *big.Int, scale int) string {
+	digits := new(big.Int).Abs(unscaled).String()
+	sign := ""
+	if unscaled.Sign() < 0 {
+		sign = "-"
+	}
+	if scale <= 0 {
+		return sign + digits
+	}
+	for len(digits) <= scale {
+		digits = "0" + digits
+	}
+	return sign + digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+}
@@ -0,0 +1,244 @@
+package kreuzberg
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var orcCompressionKinds = []string{"NONE", "ZLIB", "SNAPPY", "LZO", "LZ4", "ZSTD"}
+
+var orcTypeKinds = []string{
+	"boolean", "tinyint", "smallint", "int", "bigint", "float", "double", "string", "binary", "timestamp",
+	"array", "map", "struct", "uniontype", "decimal", "date", "varchar", "char", "timestamp with local time zone",
+}
+
+// protoMessage is a decoded protobuf message: field number to the values seen for it.
+type protoMessage map[uint64][]any
+
+func decodeProto(data []byte) (protoMessage, error) {
+	msg := protoMessage{}
+	for pos := 0; pos < len(data); {
+		key, n := binary.Uvarint(data[pos:])
+		if n <= 0 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		pos += n
+		field, wireType := key>>3, key&7
+
+		var value any
+		switch wireType {
+		case 0:
+			v, n := binary.Uvarint(data[pos:])
+			if n <= 0 {
+				return nil, io.ErrUnexpectedEOF
+			}
+			pos += n
+			value = v
+		case 1:
+			if len(data)-pos < 8 {
+				return nil, io.ErrUnexpectedEOF
+			}
+			value = binary.LittleEndian.Uint64(data[pos:])
+			pos += 8
+		case 2:
+			length, n := binary.Uvarint(data[pos:])
+			if n <= 0 || length > uint64(len(data)-pos-n) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			pos += n
+			value = data[pos : pos+int(length)]
+			pos += int(length)
+		case 5:
+			if len(data)-pos < 4 {
+				return nil, io.ErrUnexpectedEOF
+			}
+			value = uint64(binary.LittleEndian.Uint32(data[pos:]))
+			pos += 4
+		default:
+			return nil, fmt.Errorf("unsupported protobuf wire type %d", wireType)
+		}
+		msg[field] = append(msg[field], value)
+	}
+	return msg, nil
+}
+
+func (m protoMessage) uint(field uint64) uint64 {
+	if values := m[field]; len(values) > 0 {
+		v, _ := values[len(values)-1].(uint64)
+		return v
+	}
+	return 0
+}
+
+func (m protoMessage) bytes(field uint64) []byte {
+	if values := m[field]; len(values) > 0 {
+		b, _ := values[len(values)-1].([]byte)
+		return b
+	}
+	return nil
+}
+
+// uints returns a repeated integer field, accepting both packed and unpacked encodings.
+func (m protoMessage) uints(field uint64) []uint64 {
+	var out []uint64
+	for _, value := range m[field] {
+		switch v := value.(type) {
+		case uint64:
+			out = append(out, v)
+		case []byte:
+			for pos := 0; pos < len(v); {
+				x, n := binary.Uvarint(v[pos:])
+				if n <= 0 {
+					break
+				}
+				out = append(out, x)
+				pos += n
+			}
+		}
+	}
+	return out
+}
+
+// decompressORC undoes ORC's chunked compression for the NONE and ZLIB codecs.
+func decompressORC(data []byte, compression uint64) ([]byte, error) {
+	if compression == 0 {
+		return data, nil
+	}
+	if compression != 1 {
+		return nil, fmt.Errorf("unsupported ORC compression %s", orcCompressionKinds[compression])
+	}
+	var out bytes.Buffer
+	for pos := 0; pos < len(data); {
+		if len(data)-pos < 3 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		header := uint32(data[pos]) | uint32(data[pos+1])<<8 | uint32(data[pos+2])<<16
+		pos += 3
+		length := int(header >> 1)
+		if length > len(data)-pos {
+			return nil, io.ErrUnexpectedEOF
+		}
+		chunk := data[pos : pos+length]
+		pos += length
+		if header&1 == 1 {
+			out.Write(chunk)
+			continue
+		}
+		if _, err := io.Copy(&out, flate.NewReader(bytes.NewReader(chunk))); err != nil {
+			return nil, err
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// parseORCFooter reads the ORC postscript and footer: compression, schema, row count, stripe
+// count, and user metadata. Footers compressed with codecs other than ZLIB are reported from
+// the postscript only.
+func parseORCFooter(data []byte) (*dataFileSummary, error) {
+	if len(data) < 4 || !bytes.HasPrefix(data, []byte("ORC")) {
+		return nil, newParsingErrorWithContext("not an ORC file", nil, ErrorCodeParsing, nil)
+	}
+	psLen := int(data[len(data)-1])
+	if psLen == 0 || psLen > len(data)-1 {
+		return nil, newParsingErrorWithContext("invalid ORC postscript length", nil, ErrorCodeParsing, nil)
+	}
+	ps, err := decodeProto(data[len(data)-1-psLen : len(data)-1])
+	if err != nil {
+		return nil, newParsingErrorWithContext("failed to decode ORC postscript", err, ErrorCodeParsing, nil)
+	}
+
+	compression := ps.uint(2)
+	summary := &dataFileSummary{format: "ORC", properties: map[string]string{}, keyValues: map[string]string{}}
+	if compression < uint64(len(orcCompressionKinds)) {
+		summary.properties["compression"] = orcCompressionKinds[compression]
+	}
+	if version := ps.uints(4); len(version) == 2 {
+		summary.properties["format_version"] = fmt.Sprintf("%d.%d", version[0], version[1])
+	}
+
+	footerLen := int(ps.uint(1))
+	footerEnd := len(data) - 1 - psLen
+	if footerLen > footerEnd {
+		return nil, newParsingErrorWithContext("invalid ORC footer length", nil, ErrorCodeParsing, nil)
+	}
+	raw, err := decompressORC(data[footerEnd-footerLen:footerEnd], compression)
+	if err != nil {
+		summary.properties["footer"] = "unavailable: " + err.Error()
+		return summary, nil
+	}
+	footer, err := decodeProto(raw)
+	if err != nil {
+		return nil, newParsingErrorWithContext("failed to decode ORC footer", err, ErrorCodeParsing, nil)
+	}
+
+	summary.rowCount = int64(footer.uint(6))
+	summary.properties["stripes"] = fmt.Sprint(len(footer[3]))
+	for _, item := range footer[5] {
+		if b, ok := item.([]byte); ok {
+			if kv, err := decodeProto(b); err == nil {
+				summary.keyValues[string(kv.bytes(1))] = string(kv.bytes(2))
+			}
+		}
+	}
+
+	types := make([]protoMessage, 0, len(footer[4]))
+	for _, item := range footer[4] {
+		b, _ := item.([]byte)
+		t, err := decodeProto(b)
+		if err != nil {
+			return nil, newParsingErrorWithContext("failed to decode ORC type", err, ErrorCodeParsing, nil)
+		}
+		types = append(types, t)
+	}
+	if len(types) > 0 {
+		root := types[0]
+		names := root[3]
+		for i, sub := range root.uints(2) {
+			name := fmt.Sprintf("_col%d", i)
+			if i < len(names) {
+				b, _ := names[i].([]byte)
+				name = string(b)
+			}
+			summary.columns = append(summary.columns, DataColumn{Name: name, Type: orcTypeName(types, sub, 0), Nullable: true})
+		}
+	}
+	return summary, nil
+}
+
+// orcTypeName renders a type in Hive notation, e.g. "array<struct<a:int>>".
+func orcTypeName(types []protoMessage, id uint64, depth int) string {
+	if id >= uint64(len(types)) || depth > 32 {
+		return "unknown"
+	}
+	t := types[id]
+	kind := t.uint(1)
+	if kind >= uint64(len(orcTypeKinds)) {
+		return "unknown"
+	}
+	name := orcTypeKinds[kind]
+	subtypes := t.uints(2)
+	switch name {
+	case "array", "map", "uniontype":
+		parts := make([]string, len(subtypes))
+		for i, sub := range subtypes {
+			parts[i] = orcTypeName(types, sub, depth+1)
+		}
+		return name + "<" + strings.Join(parts, ",") + ">"
+	case "struct":
+		parts := make([]string, len(subtypes))
+		for i, sub := range subtypes {
+			field := fmt.Sprintf("_col%d", i)
+			if i < len(t[3]) {
+				b, _ := t[3][i].([]byte)
+				field = string(b)
+			}
+			parts[i] = field + ":" + orcTypeName(types, sub, depth+1)
+		}
+		return name + "<" + strings.Join(parts, ",") + ">"
+	}
+	return name
+}
@@ -0,0 +1,259 @@
+package kreuzberg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+var parquetMagic = []byte("PAR1")
+
+var parquetPhysicalTypes = []string{"BOOLEAN", "INT32", "INT64", "INT96", "FLOAT", "DOUBLE", "BYTE_ARRAY", "FIXED_LEN_BYTE_ARRAY"}
+
+var parquetConvertedTypes = []string{
+	"UTF8", "MAP", "MAP_KEY_VALUE", "LIST", "ENUM", "DECIMAL", "DATE", "TIME_MILLIS", "TIME_MICROS",
+	"TIMESTAMP_MILLIS", "TIMESTAMP_MICROS", "UINT_8", "UINT_16", "UINT_32", "UINT_64", "INT_8", "INT_16",
+	"INT_32", "INT_64", "JSON", "BSON", "INTERVAL",
+}
+
+// thriftStruct is a decoded Thrift struct keyed by field id.
+type thriftStruct map[int16]any
+
+// thriftCompactReader decodes the Thrift compact protocol used by Parquet file metadata.
+type thriftCompactReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *thriftCompactReader) byte() (byte, error) {
+	if r.pos >= len(r.data) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *thriftCompactReader) uvarint() (uint64, error) {
+	value, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	r.pos += n
+	return value, nil
+}
+
+func (r *thriftCompactReader) varint() (int64, error) {
+	value, err := r.uvarint()
+	return int64(value>>1) ^ -int64(value&1), err
+}
+
+func (r *thriftCompactReader) readStruct() (thriftStruct, error) {
+	fields := thriftStruct{}
+	var lastID int16
+	for {
+		header, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		fieldType := header & 0x0f
+		if fieldType == 0 {
+			return fields, nil
+		}
+		id := lastID + int16(header>>4)
+		if header>>4 == 0 {
+			v, err := r.varint()
+			if err != nil {
+				return nil, err
+			}
+			id = int16(v)
+		}
+		lastID = id
+
+		var value any
+		switch fieldType {
+		case 1, 2:
+			value = fieldType == 1
+		default:
+			if value, err = r.readValue(fieldType); err != nil {
+				return nil, err
+			}
+		}
+		fields[id] = value
+	}
+}
+
+func (r *thriftCompactReader) readValue(valueType byte) (any, error) {
+	switch valueType {
+	case 1, 2:
+		b, err := r.byte()
+		return b == 1, err
+	case 3:
+		b, err := r.byte()
+		return int64(int8(b)), err
+	case 4, 5, 6:
+		return r.varint()
+	case 7:
+		if len(r.data)-r.pos < 8 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		v := math.Float64frombits(binary.LittleEndian.Uint64(r.data[r.pos:]))
+		r.pos += 8
+		return v, nil
+	case 8:
+		n, err := r.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		if uint64(len(r.data)-r.pos) < n {
+			return nil, io.ErrUnexpectedEOF
+		}
+		b := r.data[r.pos : r.pos+int(n)]
+		r.pos += int(n)
+		return b, nil
+	case 9, 10:
+		header, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		size := uint64(header >> 4)
+		if size == 15 {
+			if size, err = r.uvarint(); err != nil {
+				return nil, err
+			}
+		}
+		if size > uint64(len(r.data)-r.pos) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		items := make([]any, 0, size)
+		for i := uint64(0); i < size; i++ {
+			item, err := r.readValue(header & 0x0f)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case 11:
+		size, err := r.uvarint()
+		if err != nil || size == 0 {
+			return nil, err
+		}
+		types, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		for i := uint64(0); i < size; i++ {
+			if _, err := r.readValue(types >> 4); err != nil {
+				return nil, err
+			}
+			if _, err := r.readValue(types & 0x0f); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	case 12:
+		return r.readStruct()
+	}
+	return nil, fmt.Errorf("unknown thrift compact type %d", valueType)
+}
+
+func (s thriftStruct) int(id int16) (int64, bool) {
+	v, ok := s[id].(int64)
+	return v, ok
+}
+
+func (s thriftStruct) string(id int16) string {
+	b, _ := s[id].([]byte)
+	return string(b)
+}
+
+func (s thriftStruct) list(id int16) []any {
+	l, _ := s[id].([]any)
+	return l
+}
+
+// parseParquetFooter reads the Thrift-encoded FileMetaData from the Parquet footer: schema,
+// row count, row groups, writer, and key/value metadata.
+func parseParquetFooter(data []byte) (*dataFileSummary, error) {
+	if len(data) < 12 || !bytes.HasPrefix(data, parquetMagic) || !bytes.HasSuffix(data, parquetMagic) {
+		return nil, newParsingErrorWithContext("not a Parquet file", nil, ErrorCodeParsing, nil)
+	}
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	if footerLen <= 0 || footerLen > len(data)-12 {
+		return nil, newParsingErrorWithContext("invalid Parquet footer length", nil, ErrorCodeParsing, nil)
+	}
+	footer := data[len(data)-8-footerLen : len(data)-8]
+
+	meta, err := (&thriftCompactReader{data: footer}).readStruct()
+	if err != nil {
+		return nil, newParsingErrorWithContext("failed to decode Parquet file metadata", err, ErrorCodeParsing, nil)
+	}
+
+	rowCount, _ := meta.int(3)
+	summary := &dataFileSummary{
+		format:     "Parquet",
+		rowCount:   rowCount,
+		properties: map[string]string{"row_groups": fmt.Sprint(len(meta.list(4)))},
+		keyValues:  map[string]string{},
+	}
+	if version, ok := meta.int(1); ok {
+		summary.properties["format_version"] = fmt.Sprint(version)
+	}
+	if createdBy := meta.string(6); createdBy != "" {
+		summary.properties["created_by"] = createdBy
+	}
+	for _, item := range meta.list(5) {
+		if kv, ok := item.(thriftStruct); ok {
+			summary.keyValues[kv.string(1)] = kv.string(2)
+		}
+	}
+
+	schema := meta.list(2)
+	if len(schema) > 0 {
+		pos := 1
+		root, _ := schema[0].(thriftStruct)
+		children, _ := root.int(5)
+		for i := int64(0); i < children && pos < len(schema); i++ {
+			pos = collectParquetColumns(schema, pos, "", false, &summary.columns)
+		}
+	}
+	return summary, nil
+}
+
+// collectParquetColumns walks the flattened schema depth-first starting at pos, appending leaf
+// columns, and returns the position after the subtree.
+func collectParquetColumns(schema []any, pos int, prefix string, parentNullable bool, columns *[]DataColumn) int {
+	element, _ := schema[pos].(thriftStruct)
+	name := element.string(4)
+	if prefix != "" {
+		name = prefix + "." + name
+	}
+	repetition, _ := element.int(3)
+	nullable := parentNullable || repetition == 1
+	pos++
+
+	children, ok := element.int(5)
+	if ok && children > 0 {
+		for i := int64(0); i < children && pos < len(schema); i++ {
+			pos = collectParquetColumns(schema, pos, name, nullable, columns)
+		}
+		return pos
+	}
+
+	typeName := "UNKNOWN"
+	if physical, ok := element.int(1); ok && physical >= 0 && int(physical) < len(parquetPhysicalTypes) {
+		typeName = parquetPhysicalTypes[physical]
+	}
+	if converted, ok := element.int(6); ok && converted >= 0 && int(converted) < len(parquetConvertedTypes) {
+		typeName += "(" + parquetConvertedTypes[converted] + ")"
+	}
+	if repetition == 2 {
+		typeName = "REPEATED " + typeName
+	}
+	*columns = append(*columns, DataColumn{Name: strings.TrimPrefix(name, "."), Type: typeName, Nullable: nullable})
+	return pos
+}
@@ -0,0 +1,305 @@
+package kreuzberg
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type avroWriter struct{ bytes.Buffer }
+
+func (w *avroWriter) long(v int64) {
+	w.Write(binary.AppendUvarint(nil, uint64(v<<1^v>>63)))
+}
+
+func (w *avroWriter) str(s string) {
+	w.long(int64(len(s)))
+	w.WriteString(s)
+}
+
+const testAvroSchema = `{"type":"record","name":"Person","fields":[
+	{"name":"name","type":"string"},
+	{"name":"age","type":["null","int"]},
+	{"name":"color","type":{"type":"enum","name":"Color","symbols":["RED","GREEN"]}},
+	{"name":"born","type":{"type":"int","logicalType":"date"}}
+]}`
+
+func buildAvro(t *testing.T, codec string) []byte {
+	t.Helper()
+	sync := []byte("0123456789abcdef")
+
+	var w avroWriter
+	w.WriteString("Obj\x01")
+	w.long(3)
+	w.str("avro.schema")
+	w.str(testAvroSchema)
+	w.str("avro.codec")
+	w.str(codec)
+	w.str("origin")
+	w.str("unit-test")
+	w.long(0)
+	w.Write(sync)
+
+	var rows avroWriter
+	rows.str("Ada")
+	rows.long(1)
+	rows.long(36)
+	rows.long(1)
+	rows.long(0)
+	rows.str("Bob")
+	rows.long(0)
+	rows.long(0)
+	rows.long(19723)
+
+	block := rows.Bytes()
+	if codec == "deflate" {
+		var buf bytes.Buffer
+		fw, _ := flate.NewWriter(&buf, flate.DefaultCompression)
+		fw.Write(block)
+		fw.Close()
+		block = buf.Bytes()
+	}
+	w.long(2)
+	w.long(int64(len(block)))
+	w.Write(block)
+	w.Write(sync)
+	return w.Bytes()
+}
+
+func TestParseAvroContainer(t *testing.T) {
+	for _, codec := range []string{"null", "deflate"} {
+		summary, err := parseAvroContainer(buildAvro(t, codec), 10)
+		if err != nil {
+			t.Fatalf("%s: parse: %v", codec, err)
+		}
+		if summary.rowCount != 2 || summary.properties["codec"] != codec || summary.keyValues["origin"] != "unit-test" {
+			t.Fatalf("%s: unexpected summary: %+v", codec, summary)
+		}
+		wantColumns := []DataColumn{
+			{Name: "name", Type: "string"},
+			{Name: "age", Type: "int", Nullable: true},
+			{Name: "color", Type: "enum"},
+			{Name: "born", Type: "date"},
+		}
+		if len(summary.columns) != len(wantColumns) {
+			t.Fatalf("%s: unexpected columns: %+v", codec, summary.columns)
+		}
+		for i, want := range wantColumns {
+			if got := summary.columns[i]; got.Name != want.Name || got.Nullable != want.Nullable || !strings.Contains(got.Type, want.Type) {
+				t.Fatalf("%s: column %d = %+v, want %+v", codec, i, got, want)
+			}
+		}
+		want := [][]string{{"Ada", "36", "GREEN", "1970-01-01"}, {"Bob", "", "RED", "2024-01-01"}}
+		if len(summary.preview) != 2 || strings.Join(summary.preview[0], ",") != strings.Join(want[0], ",") || strings.Join(summary.preview[1], ",") != strings.Join(want[1], ",") {
+			t.Fatalf("%s: unexpected preview: %v", codec, summary.preview)
+		}
+	}
+
+	summary, err := parseAvroContainer(buildAvro(t, "null"), 1)
+	if err != nil || len(summary.preview) != 1 || summary.rowCount != 2 {
+		t.Fatalf("preview cap not honored: %+v, %v", summary, err)
+	}
+	if _, err := parseAvroContainer([]byte("nope"), 1); err == nil {
+		t.Fatalf("expected error for non-Avro input")
+	}
+}
+
+// thriftWriter emits the subset of the Thrift compact protocol needed for a Parquet footer.
+type thriftWriter struct {
+	bytes.Buffer
+	last []int16
+}
+
+func (w *thriftWriter) field(id int16, fieldType byte) {
+	last := w.last[len(w.last)-1]
+	w.WriteByte(byte(id-last)<<4 | fieldType)
+	w.last[len(w.last)-1] = id
+}
+
+func (w *thriftWriter) i64(id int16, v int64) {
+	w.field(id, 6)
+	w.Write(binary.AppendUvarint(nil, uint64(v<<1^v>>63)))
+}
+
+func (w *thriftWriter) str(id int16, s string) {
+	w.field(id, 8)
+	w.Write(binary.AppendUvarint(nil, uint64(len(s))))
+	w.WriteString(s)
+}
+
+func (w *thriftWriter) structList(id int16, n int) {
+	w.field(id, 9)
+	w.WriteByte(byte(n)<<4 | 12)
+}
+
+func (w *thriftWriter) begin() { w.last = append(w.last, 0) }
+
+func (w *thriftWriter) end() {
+	w.WriteByte(0)
+	w.last = w.last[:len(w.last)-1]
+}
+
+func buildParquet() []byte {
+	w := &thriftWriter{}
+	w.begin()
+	w.i64(1, 1)
+	w.structList(2, 4)
+	w.begin()
+	w.str(4, "schema")
+	w.i64(5, 2)
+	w.end()
+	w.begin()
+	w.i64(1, 2)
+	w.i64(3, 0)
+	w.str(4, "id")
+	w.end()
+	w.begin()
+	w.i64(3, 1)
+	w.str(4, "address")
+	w.i64(5, 1)
+	w.end()
+	w.begin()
+	w.i64(1, 6)
+	w.i64(3, 0)
+	w.str(4, "city")
+	w.i64(6, 0)
+	w.end()
+	w.i64(3, 42)
+	w.structList(4, 0)
+	w.structList(5, 1)
+	w.begin()
+	w.str(1, "writer.note")
+	w.str(2, "hello")
+	w.end()
+	w.str(6, "parquet-go test")
+	w.end()
+
+	footer := w.Bytes()
+	out := append([]byte("PAR1"), footer...)
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(footer)))
+	return append(out, "PAR1"...)
+}
+
+func TestParseParquetFooter(t *testing.T) {
+	summary, err := parseParquetFooter(buildParquet())
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if summary.rowCount != 42 || summary.properties["created_by"] != "parquet-go test" || summary.properties["row_groups"] != "0" {
+		t.Fatalf("unexpected summary: %+v", summary)
+	}
+	if summary.keyValues["writer.note"] != "hello" {
+		t.Fatalf("unexpected key/value metadata: %v", summary.keyValues)
+	}
+	want := []DataColumn{
+		{Name: "id", Type: "INT64"},
+		{Name: "address.city", Type: "BYTE_ARRAY(UTF8)", Nullable: true},
+	}
+	if len(summary.columns) != len(want) || summary.columns[0] != want[0] || summary.columns[1] != want[1] {
+		t.Fatalf("unexpected columns: %+v", summary.columns)
+	}
+	if _, err := parseParquetFooter([]byte("PAR1xxxxPAR1")); err == nil {
+		t.Fatalf("expected error for truncated footer")
+	}
+}
+
+func protoField(field uint64, value any) []byte {
+	switch v := value.(type) {
+	case uint64:
+		return binary.AppendUvarint(binary.AppendUvarint(nil, field<<3), v)
+	case []byte:
+		out := binary.AppendUvarint(nil, field<<3|2)
+		out = binary.AppendUvarint(out, uint64(len(v)))
+		return append(out, v...)
+	}
+	return nil
+}
+
+func buildORC() []byte {
+	concat := func(parts ...[]byte) []byte { return bytes.Join(parts, nil) }
+	root := concat(
+		protoField(1, uint64(12)),
+		protoField(2, []byte{1, 2}),
+		protoField(3, []byte("id")),
+		protoField(3, []byte("tags")),
+	)
+	footer := concat(
+		protoField(3, []byte{}),
+		protoField(4, root),
+		protoField(4, protoField(1, uint64(4))),
+		protoField(4, concat(protoField(1, uint64(10)), protoField(2, uint64(3)))),
+		protoField(4, protoField(1, uint64(7))),
+		protoField(5, concat(protoField(1, []byte("owner")), protoField(2, []byte("etl")))),
+		protoField(6, uint64(7)),
+	)
+	postscript := concat(
+		protoField(1, uint64(len(footer))),
+		protoField(2, uint64(0)),
+		protoField(8000, []byte("ORC")),
+	)
+	return concat([]byte("ORC"), footer, postscript, []byte{byte(len(postscript))})
+}
+
+func TestParseORCFooter(t *testing.T) {
+	summary, err := parseORCFooter(buildORC())
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if summary.rowCount != 7 || summary.properties["compression"] != "NONE" || summary.properties["stripes"] != "1" {
+		t.Fatalf("unexpected summary: %+v", summary)
+	}
+	if summary.keyValues["owner"] != "etl" {
+		t.Fatalf("unexpected user metadata: %v", summary.keyValues)
+	}
+	if len(summary.columns) != 2 || summary.columns[0].Type != "bigint" || summary.columns[1].Name != "tags" || summary.columns[1].Type != "array<string>" {
+		t.Fatalf("unexpected columns: %+v", summary.columns)
+	}
+}
+
+func TestDataFileExtractorsRouteByExtension(t *testing.T) {
+	extractor, mimeType := selectGoPrimaryExtractor(documentSource{path: "warehouse/people.parquet"}, nil)
+	if extractor == nil || extractor.name != "parquet" || mimeType != "application/vnd.apache.parquet" {
+		t.Fatalf("unexpected routing: %v %q", extractor, mimeType)
+	}
+
+	result, err := extractor.extract(documentSource{data: buildAvro(t, "null")}, "application/vnd.apache.avro", &ExtractionConfig{DataFiles: &DataFileConfig{PreviewRows: -1}})
+	if err == nil {
+		t.Fatalf("parquet parser should reject Avro data, got %+v", result)
+	}
+
+	avro, _ := selectGoPrimaryExtractor(documentSource{path: "people.avro"}, nil)
+	result, err = avro.extract(documentSource{data: buildAvro(t, "null")}, "application/vnd.apache.avro", &ExtractionConfig{DataFiles: &DataFileConfig{PreviewRows: -1}})
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if len(result.Tables) != 1 || !strings.Contains(result.Content, "Rows: 2") {
+		t.Fatalf("preview should be disabled: %+v", result)
+	}
+	var rowCount int64
+	if err := json.Unmarshal(result.Metadata.Additional["row_count"], &rowCount); err != nil || rowCount != 2 {
+		t.Fatalf("unexpected row_count: %s", result.Metadata.Additional["row_count"])
+	}
+}
+
+func TestDataFileFooterFormatsRejectPreviewRows(t *testing.T) {
+	parquet, _ := selectGoPrimaryExtractor(documentSource{path: "people.parquet"}, nil)
+	_, err := parquet.extract(documentSource{data: buildParquet()}, "application/vnd.apache.parquet", &ExtractionConfig{DataFiles: &DataFileConfig{PreviewRows: 5}})
+	var validation *ValidationError
+	if !errors.As(err, &validation) {
+		t.Fatalf("expected ValidationError for Parquet preview, got %v", err)
+	}
+
+	orc, _ := selectGoPrimaryExtractor(documentSource{path: "people.orc"}, nil)
+	if _, err := orc.extract(documentSource{data: buildORC()}, "application/vnd.apache.orc", &ExtractionConfig{DataFiles: &DataFileConfig{PreviewRows: 5}}); !errors.As(err, &validation) {
+		t.Fatalf("expected ValidationError for ORC preview, got %v", err)
+	}
+
+	result, err := orc.extract(documentSource{data: buildORC()}, "application/vnd.apache.orc", nil)
+	if err != nil || len(result.Tables) != 1 {
+		t.Fatalf("default config should extract the footer only: %+v, %v", result, err)
+	}
+}
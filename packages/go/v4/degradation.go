@@ -0,0 +1,234 @@
+package kreuzberg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// DegradationPolicy selects what happens when a feature the config requests cannot run, e.g.
+// OCR without Tesseract language data or embeddings without the model.
+type DegradationPolicy string
+
+const (
+	// DegradationFail fails the extraction with a MissingDependencyError naming the feature,
+	// before any document is extracted when the problem can be detected up front.
+	DegradationFail DegradationPolicy = "fail"
+	// DegradationDegrade extracts without the feature and records it on the result as a warning
+	// diagnostic with the source "degraded:<feature>" (see ExtractionResult.DegradedFeatures).
+	DegradationDegrade DegradationPolicy = "degrade"
+)
+
+var degradationPolicies = []DegradationPolicy{DegradationFail, DegradationDegrade}
+
+// Validate reports an error for an unknown policy. The empty policy leaves unavailable features
+// to the native library and is valid.
+func (p DegradationPolicy) Validate() error {
+	if p == "" || slices.Contains(degradationPolicies, p) {
+		return nil
+	}
+	return invalidConfigValue("degradation policy", string(p), stringValues(degradationPolicies))
+}
+
+// Optional features named in degradation diagnostics.
+const (
+	FeatureOCR        = "ocr"
+	FeatureEmbeddings = "embeddings"
+	FeatureKeywords   = "keywords"
+)
+
+// degradedSourcePrefix prefixes the source of the diagnostic recorded for a skipped feature.
+const degradedSourcePrefix = "degraded:"
+
+// DegradedFeatures returns the features that were skipped because they could not run, in the
+// order they were detected.
+func (r *ExtractionResult) DegradedFeatures() []string {
+	var features []string
+	for _, d := range r.Diagnostics {
+		if feature, ok := strings.CutPrefix(d.Source, degradedSourcePrefix); ok {
+			features = append(features, feature)
+		}
+	}
+	return features
+}
+
+// unavailableFeature is a requested feature that cannot run.
+type unavailableFeature struct {
+	name       string
+	dependency string
+	reason     string
+}
+
+func (f unavailableFeature) err() error {
+	return newMissingDependencyErrorWithContext(f.dependency, fmt.Sprintf("%s is unavailable: %s", f.name, f.reason), nil, ErrorCodeMissingDependency, nil)
+}
+
+func degradationEnabled(config *ExtractionConfig) bool {
+	return config != nil && config.Degradation != ""
+}
+
+// checkFeatures reports the requested features config enables that are known to be unavailable
+// before extraction. Features whose availability only shows during extraction are caught by
+// featureForError instead.
+func checkFeatures(config *ExtractionConfig) []unavailableFeature {
+	var missing []unavailableFeature
+	if ocrRequested(config) {
+		if f, ok := checkOCR(config.OCR); !ok {
+			missing = append(missing, f)
+		}
+	}
+	return missing
+}
+
+func ocrRequested(config *ExtractionConfig) bool {
+	return config.OCR != nil || (config.ForceOCR != nil && *config.ForceOCR)
+}
+
+// checkOCR verifies that the OCR backend is usable: Tesseract needs the language data of every
+// requested language, other backends must be registered.
+func checkOCR(ocr *OCRConfig) (unavailableFeature, bool) {
+	backend, language := OCRBackendTesseract, "eng"
+	if ocr != nil {
+		if ocr.Backend != "" {
+			backend = ocr.Backend
+		}
+		if ocr.Tesseract != nil && ocr.Tesseract.Language != "" {
+			language = ocr.Tesseract.Language
+		}
+		if ocr.Language != nil && *ocr.Language != "" {
+			language = *ocr.Language
+		}
+	}
+	if backend != OCRBackendTesseract {
+		registered, _ := ListOCRBackends()
+		if !slices.Contains(registered, string(backend)) {
+			return unavailableFeature{FeatureOCR, string(backend), fmt.Sprintf("OCR backend %q is not registered", backend)}, false
+		}
+		return unavailableFeature{}, true
+	}
+	if err := injectMissingDependency("tesseract"); err != nil {
+		return unavailableFeature{FeatureOCR, "tesseract", err.Error()}, false
+	}
+	dir := tessdataDir(LockOptions{})
+	if dir == "" {
+		return unavailableFeature{FeatureOCR, "tessdata", "no Tesseract language data directory found (set TESSDATA_PREFIX)"}, false
+	}
+	for _, lang := range strings.Split(language, "+") {
+		if _, err := os.Stat(filepath.Join(dir, lang+".traineddata")); err != nil {
+			return unavailableFeature{FeatureOCR, "tessdata", fmt.Sprintf("Tesseract language data %q not found in %s", lang, dir)}, false
+		}
+	}
+	return unavailableFeature{}, true
+}
+
+// featureForError returns the requested feature a native MissingDependencyError belongs to.
+func featureForError(config *ExtractionConfig, err error) (unavailableFeature, bool) {
+	var depErr *MissingDependencyError
+	if !errors.As(err, &depErr) {
+		return unavailableFeature{}, false
+	}
+	dependency := strings.ToLower(depErr.Dependency + " " + depErr.Error())
+	matches := func(words ...string) bool {
+		return slices.ContainsFunc(words, func(w string) bool { return strings.Contains(dependency, w) })
+	}
+	f := unavailableFeature{dependency: depErr.Dependency, reason: depErr.Error()}
+	switch {
+	case ocrRequested(config) && matches("tesseract", "tessdata", "ocr"):
+		f.name = FeatureOCR
+	case config.Chunking != nil && config.Chunking.Embedding != nil && matches("embed", "onnx", "model"):
+		f.name = FeatureEmbeddings
+	case config.Keywords != nil && matches("keyword", "yake", "rake"):
+		f.name = FeatureKeywords
+	default:
+		return unavailableFeature{}, false
+	}
+	return f, true
+}
+
+// withoutFeature returns a copy of config with the feature disabled.
+func withoutFeature(config *ExtractionConfig, feature string) *ExtractionConfig {
+	cfg := *config
+	switch feature {
+	case FeatureOCR:
+		cfg.OCR, cfg.ForceOCR = nil, nil
+	case FeatureEmbeddings:
+		chunking := *cfg.Chunking
+		chunking.Embedding = nil
+		cfg.Chunking = &chunking
+	case FeatureKeywords:
+		cfg.Keywords = nil
+	}
+	return &cfg
+}
+
+// extractDegraded applies config.Degradation to a single-document extraction. Under
+// DegradationDegrade, unavailable features are disabled before extraction, and a native
+// MissingDependencyError for a requested feature retries the extraction without it.
+func extractDegraded(config *ExtractionConfig, extract func(*ExtractionConfig) (*ExtractionResult, error)) (*ExtractionResult, error) {
+	if err := config.Degradation.Validate(); err != nil {
+		return nil, err
+	}
+	policy := config.Degradation
+	cfg := *config
+	cfg.Degradation = ""
+	current := &cfg
+
+	var skipped []unavailableFeature
+	for _, f := range checkFeatures(current) {
+		if policy == DegradationFail {
+			return nil, f.err()
+		}
+		skipped = append(skipped, f)
+		current = withoutFeature(current, f.name)
+	}
+	for {
+		result, err := extract(current)
+		if err == nil {
+			recordDegraded(result, skipped)
+			return result, nil
+		}
+		f, ok := featureForError(current, err)
+		if !ok || policy == DegradationFail {
+			return nil, err
+		}
+		skipped = append(skipped, f)
+		current = withoutFeature(current, f.name)
+	}
+}
+
+// batchExtractDegraded applies config.Degradation to a batch. Only the features detected before
+// extraction are degraded; a feature failing during the batch fails its items as usual.
+func batchExtractDegraded(config *ExtractionConfig, extract func(*ExtractionConfig) ([]*ExtractionResult, error)) ([]*ExtractionResult, error) {
+	if err := config.Degradation.Validate(); err != nil {
+		return nil, err
+	}
+	cfg := *config
+	cfg.Degradation = ""
+	current := &cfg
+	missing := checkFeatures(current)
+	for _, f := range missing {
+		if config.Degradation == DegradationFail {
+			return nil, f.err()
+		}
+		current = withoutFeature(current, f.name)
+	}
+	results, err := extract(current)
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		if result != nil {
+			recordDegraded(result, missing)
+		}
+	}
+	return results, nil
+}
+
+func recordDegraded(result *ExtractionResult, skipped []unavailableFeature) {
+	for _, f := range skipped {
+		result.addDiagnostic(degradedSourcePrefix+f.name, DiagnosticSeverityWarning, fmt.Sprintf("%s skipped: %s", f.name, f.reason))
+	}
+}
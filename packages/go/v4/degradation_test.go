@@ -0,0 +1,88 @@
+package kreuzberg
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDegradationMissingTessdata(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TESSDATA_PREFIX", dir)
+	config := &ExtractionConfig{OCR: &OCRConfig{Language: stringPtr("eng+deu")}, Degradation: DegradationDegrade}
+
+	result, err := ExtractBytesSync([]byte(testSRT), mimeSRT, config)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if features := result.DegradedFeatures(); !slices.Equal(features, []string{FeatureOCR}) {
+		t.Fatalf("unexpected degraded features %v", features)
+	}
+	if d := result.DiagnosticsBySeverity(DiagnosticSeverityWarning); len(d) != 1 || !strings.Contains(d[0].Message, `"eng" not found`) {
+		t.Fatalf("unexpected diagnostics %+v", result.Diagnostics)
+	}
+
+	results, err := BatchExtractBytesSync([]BytesWithMime{{Data: []byte(testSRT), MimeType: mimeSRT}}, config)
+	if err != nil || len(results) != 1 || !slices.Equal(results[0].DegradedFeatures(), []string{FeatureOCR}) {
+		t.Fatalf("expected the batch result to record the skipped feature, got %v", err)
+	}
+
+	config.Degradation = DegradationFail
+	var depErr *MissingDependencyError
+	if _, err := ExtractBytesSync([]byte(testSRT), mimeSRT, config); !errors.As(err, &depErr) || depErr.Dependency != "tessdata" {
+		t.Fatalf("expected a missing dependency error, got %v", err)
+	}
+
+	// With the language data in place nothing is degraded.
+	for _, lang := range []string{"eng", "deu"} {
+		os.WriteFile(filepath.Join(dir, lang+".traineddata"), []byte("data"), 0o600)
+	}
+	config.Degradation = DegradationDegrade
+	if result, err := ExtractBytesSync([]byte(testSRT), mimeSRT, config); err != nil || len(result.DegradedFeatures()) != 0 {
+		t.Fatalf("expected no degradation, got %+v, %v", result, err)
+	}
+}
+
+func TestDegradationNativeMissingDependency(t *testing.T) {
+	config := &ExtractionConfig{Chunking: &ChunkingConfig{Embedding: &EmbeddingConfig{}}, Degradation: DegradationDegrade}
+	var calls []*ExtractionConfig
+	extract := func(cfg *ExtractionConfig) (*ExtractionResult, error) {
+		calls = append(calls, cfg)
+		if cfg.Chunking.Embedding != nil {
+			return nil, newMissingDependencyErrorWithContext("onnxruntime", "", nil, ErrorCodeMissingDependency, nil)
+		}
+		return &ExtractionResult{Success: true}, nil
+	}
+
+	result, err := extractDegraded(config, extract)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if len(calls) != 2 || calls[1].Chunking == config.Chunking || config.Chunking.Embedding == nil {
+		t.Fatalf("expected a retry with a copy of the config without embeddings")
+	}
+	if features := result.DegradedFeatures(); !slices.Equal(features, []string{FeatureEmbeddings}) {
+		t.Fatalf("unexpected degraded features %v", features)
+	}
+
+	config.Degradation = DegradationFail
+	if _, err := extractDegraded(config, extract); err == nil {
+		t.Fatalf("expected the error to be returned under the fail policy")
+	}
+
+	// Errors unrelated to a requested feature are returned as they are.
+	config.Degradation = DegradationDegrade
+	config.Chunking = nil
+	if _, err := extractDegraded(config, func(*ExtractionConfig) (*ExtractionResult, error) {
+		return nil, newMissingDependencyErrorWithContext("soffice", "", nil, ErrorCodeMissingDependency, nil)
+	}); err == nil {
+		t.Fatalf("expected the error to be returned")
+	}
+
+	if err := DegradationPolicy("degraded").Validate(); err == nil || !strings.Contains(err.Error(), `did you mean "degrade"?`) {
+		t.Fatalf("expected an invalid policy to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,220 @@
+package kreuzberg
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Names of the external dependencies the binding throttles. Other names can be used with
+// AcquireDependency, e.g. for a remote OCR service called from a plugin.
+const (
+	// DependencyLibreOffice throttles ConvertDocument and ConvertDocumentBytes. Conversions the
+	// native core runs for legacy Office formats are not covered.
+	DependencyLibreOffice = "libreoffice"
+	// DependencyMDBTools throttles Microsoft Access extraction; one slot covers all mdbtools
+	// processes of a database.
+	DependencyMDBTools = "mdbtools"
+	// DependencyGoogleDrive throttles Google Drive API requests, including retries.
+	DependencyGoogleDrive = "google_drive"
+)
+
+// DependencyLimit throttles calls to an external dependency across the process. Callers over
+// the limits wait in line until their context is done.
+type DependencyLimit struct {
+	// MaxConcurrent caps the calls in progress at once (0 = unlimited).
+	MaxConcurrent int
+	// Rate caps how many calls start per second (0 = unlimited).
+	Rate float64
+	// Burst is how many calls may start back to back before Rate applies (default 1).
+	Burst int
+	// MaxQueued caps the callers waiting for a slot; further callers fail at once with an
+	// IOError instead of piling up (0 = unlimited).
+	MaxQueued int
+}
+
+// DependencyStats reports the state of a dependency's limiter.
+type DependencyStats struct {
+	InFlight int `json:"in_flight"`
+	Queued   int `json:"queued"`
+	// Started counts calls admitted since the limit was set.
+	Started int64 `json:"started"`
+	// Rejected counts callers turned away because the queue was full.
+	Rejected int64 `json:"rejected"`
+}
+
+type dependencyLimiter struct {
+	name  string
+	limit DependencyLimit
+	slots chan struct{}
+
+	mu sync.Mutex
+	// next is the earliest time the rate allows the next call to start, ignoring burst.
+	next  time.Time
+	stats DependencyStats
+}
+
+var dependencyLimiters = struct {
+	sync.Mutex
+	byName map[string]*dependencyLimiter
+}{byName: map[string]*dependencyLimiter{}}
+
+// SetDependencyLimit sets the limit for the named dependency; nil removes it. Calls already
+// admitted under a previous limit are not affected.
+func SetDependencyLimit(name string, limit *DependencyLimit) error {
+	if name == "" {
+		return newValidationErrorWithContext("dependency name cannot be empty", nil, ErrorCodeValidation, nil)
+	}
+	dependencyLimiters.Lock()
+	defer dependencyLimiters.Unlock()
+	if limit == nil {
+		delete(dependencyLimiters.byName, name)
+		return nil
+	}
+	if limit.MaxConcurrent < 0 || limit.Rate < 0 || limit.Burst < 0 || limit.MaxQueued < 0 {
+		return newValidationErrorWithContext(fmt.Sprintf("limit for %s cannot be negative", name), nil, ErrorCodeValidation, nil)
+	}
+	l := &dependencyLimiter{name: name, limit: *limit}
+	if l.limit.Burst == 0 {
+		l.limit.Burst = 1
+	}
+	if l.limit.MaxConcurrent > 0 {
+		l.slots = make(chan struct{}, l.limit.MaxConcurrent)
+	}
+	dependencyLimiters.byName[name] = l
+	return nil
+}
+
+// DependencyLimitStats reports the limiter state of the named dependency; ok is false when no
+// limit is set.
+func DependencyLimitStats(name string) (stats DependencyStats, ok bool) {
+	dependencyLimiters.Lock()
+	l := dependencyLimiters.byName[name]
+	dependencyLimiters.Unlock()
+	if l == nil {
+		return DependencyStats{}, false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stats, true
+}
+
+// AcquireDependency waits until the limit of the named dependency admits a call and returns a
+// function that must be called when the call is done. Without a limit it returns at once.
+// Dependencies that injected faults report missing fail with a MissingDependencyError.
+func AcquireDependency(ctx context.Context, name string) (release func(), err error) {
+	if err := injectMissingDependency(name); err != nil {
+		return nil, err
+	}
+	dependencyLimiters.Lock()
+	l := dependencyLimiters.byName[name]
+	dependencyLimiters.Unlock()
+	if l == nil {
+		return func() {}, ctx.Err()
+	}
+	return l.acquire(ctx)
+}
+
+func (l *dependencyLimiter) acquire(ctx context.Context) (func(), error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	l.mu.Lock()
+	wait := l.reserve(time.Now())
+	admitted := wait == 0 && l.trySlot()
+	if !admitted {
+		if l.limit.MaxQueued > 0 && l.stats.Queued >= l.limit.MaxQueued {
+			l.cancelReservation()
+			l.stats.Rejected++
+			l.mu.Unlock()
+			return nil, newIOErrorWithContext(fmt.Sprintf("too many calls waiting for %s", l.name), nil, ErrorCodeIo, nil)
+		}
+		l.stats.Queued++
+	}
+	l.mu.Unlock()
+
+	if !admitted {
+		err := l.waitTurn(ctx, wait)
+		l.mu.Lock()
+		l.stats.Queued--
+		l.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+	l.mu.Lock()
+	l.stats.InFlight++
+	l.stats.Started++
+	l.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			l.stats.InFlight--
+			l.mu.Unlock()
+			if l.slots != nil {
+				<-l.slots
+			}
+		})
+	}, nil
+}
+
+// reserve books the next start permitted by the rate and returns how long to wait for it.
+// l.mu must be held.
+func (l *dependencyLimiter) reserve(now time.Time) time.Duration {
+	if l.limit.Rate <= 0 {
+		return 0
+	}
+	interval := time.Duration(float64(time.Second) / l.limit.Rate)
+	start := l.next
+	if floor := now.Add(-time.Duration(l.limit.Burst-1) * interval); start.Before(floor) {
+		start = floor
+	}
+	l.next = start.Add(interval)
+	// The burst allowance lets calls start up to Burst-1 intervals ahead of the schedule.
+	return max(start.Add(time.Duration(l.limit.Burst-1)*interval).Sub(now), 0)
+}
+
+// cancelReservation gives back the reservation just made by reserve. l.mu must be held.
+func (l *dependencyLimiter) cancelReservation() {
+	if l.limit.Rate > 0 {
+		l.next = l.next.Add(-time.Duration(float64(time.Second) / l.limit.Rate))
+	}
+}
+
+// trySlot takes a concurrency slot without waiting.
+func (l *dependencyLimiter) trySlot() bool {
+	if l.slots == nil {
+		return true
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// waitTurn waits out a rate reservation and then for a concurrency slot.
+func (l *dependencyLimiter) waitTurn(ctx context.Context, wait time.Duration) error {
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	if l.slots == nil {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case l.slots <- struct{}{}:
+		return nil
+	}
+}
@@ -0,0 +1,134 @@
+package kreuzberg
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func setTestDependencyLimit(t *testing.T, name string, limit *DependencyLimit) {
+	t.Helper()
+	if err := SetDependencyLimit(name, limit); err != nil {
+		t.Fatalf("set limit: %v", err)
+	}
+	t.Cleanup(func() { SetDependencyLimit(name, nil) })
+}
+
+func TestDependencyLimitCapsConcurrency(t *testing.T) {
+	setTestDependencyLimit(t, "test-concurrency", &DependencyLimit{MaxConcurrent: 2})
+	var running, peak atomic.Int32
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			release, err := AcquireDependency(t.Context(), "test-concurrency")
+			if err != nil {
+				t.Errorf("acquire: %v", err)
+				return
+			}
+			defer release()
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			running.Add(-1)
+		})
+	}
+	wg.Wait()
+	stats, ok := DependencyLimitStats("test-concurrency")
+	if peak.Load() != 2 || !ok || stats.Started != 8 || stats.InFlight != 0 || stats.Queued != 0 {
+		t.Fatalf("unexpected peak %d, stats %+v", peak.Load(), stats)
+	}
+}
+
+func TestDependencyLimitSpacesCallsByRate(t *testing.T) {
+	setTestDependencyLimit(t, "test-rate", &DependencyLimit{Rate: 50, Burst: 2})
+	start := time.Now()
+	for range 4 {
+		release, err := AcquireDependency(t.Context(), "test-rate")
+		if err != nil {
+			t.Fatalf("acquire: %v", err)
+		}
+		release()
+	}
+	// Two calls start at once, the other two wait one 20ms interval each.
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond || elapsed > time.Second {
+		t.Fatalf("unexpected elapsed time %s", elapsed)
+	}
+}
+
+func TestDependencyLimitQueue(t *testing.T) {
+	setTestDependencyLimit(t, "test-queue", &DependencyLimit{MaxConcurrent: 1, MaxQueued: 1})
+	hold, err := AcquireDependency(t.Context(), "test-queue")
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	waiting := make(chan error, 1)
+	go func() {
+		release, err := AcquireDependency(t.Context(), "test-queue")
+		if err == nil {
+			release()
+		}
+		waiting <- err
+	}()
+	for stats, _ := DependencyLimitStats("test-queue"); stats.Queued != 1; stats, _ = DependencyLimitStats("test-queue") {
+		time.Sleep(time.Millisecond)
+	}
+
+	var ioErr *IOError
+	if _, err := AcquireDependency(t.Context(), "test-queue"); !errors.As(err, &ioErr) {
+		t.Fatalf("expected a full queue to reject, got %v", err)
+	}
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	hold()
+	hold() // releasing twice is harmless
+	if err := <-waiting; err != nil {
+		t.Fatalf("expected the queued caller to be admitted, got %v", err)
+	}
+	if stats, _ := DependencyLimitStats("test-queue"); stats.Rejected != 1 || stats.Started != 2 || stats.InFlight != 0 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	hold, _ = AcquireDependency(t.Context(), "test-queue")
+	defer hold()
+	if _, err := AcquireDependency(ctx, "test-queue"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the caller's deadline, got %v", err)
+	}
+}
+
+func TestSetDependencyLimitValidation(t *testing.T) {
+	if err := SetDependencyLimit("", &DependencyLimit{}); err == nil {
+		t.Fatalf("expected an error for an empty name")
+	}
+	if err := SetDependencyLimit("x", &DependencyLimit{Rate: -1}); err == nil {
+		t.Fatalf("expected an error for a negative rate")
+	}
+	if _, ok := DependencyLimitStats("x"); ok {
+		t.Fatalf("expected no limit after a rejected configuration")
+	}
+	release, err := AcquireDependency(t.Context(), "unlimited")
+	if err != nil {
+		t.Fatalf("expected unlimited dependencies to pass, got %v", err)
+	}
+	release()
+}
+
+func TestGoogleDriveRequestsAreThrottled(t *testing.T) {
+	setTestDependencyLimit(t, DependencyGoogleDrive, &DependencyLimit{MaxConcurrent: 1})
+	server := fakeDrive(t, map[string]string{
+		"subs": `{"id":"subs","name":"talk.srt","mimeType":"application/x-subrip"}`,
+	}, map[string]string{"subs": testSRT})
+	if _, err := ExtractGoogleDriveFile(t.Context(), "subs", &GoogleDriveConfig{BaseURL: server.URL, AccessToken: "secret"}); err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if stats, _ := DependencyLimitStats(DependencyGoogleDrive); stats.Started < 2 || stats.InFlight != 0 {
+		t.Fatalf("expected the metadata and media requests to be admitted, got %+v", stats)
+	}
+}
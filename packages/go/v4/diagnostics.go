@@ -0,0 +1,36 @@
+package kreuzberg
+
+// DiagnosticSeverity classifies a Diagnostic.
+type DiagnosticSeverity string
+
+const (
+	DiagnosticSeverityInfo    DiagnosticSeverity = "info"
+	DiagnosticSeverityWarning DiagnosticSeverity = "warning"
+	DiagnosticSeverityError   DiagnosticSeverity = "error"
+)
+
+// Diagnostic is a non-fatal observation recorded while producing an ExtractionResult.
+type Diagnostic struct {
+	// Source names the component that produced the diagnostic (e.g., a validator name).
+	Source string `json:"source"`
+	// Severity classifies the diagnostic.
+	Severity DiagnosticSeverity `json:"severity"`
+	// Message is a human-readable description.
+	Message string `json:"message"`
+}
+
+// addDiagnostic appends a diagnostic to the result.
+func (r *ExtractionResult) addDiagnostic(source string, severity DiagnosticSeverity, message string) {
+	r.Diagnostics = append(r.Diagnostics, Diagnostic{Source: source, Severity: severity, Message: message})
+}
+
+// DiagnosticsBySeverity returns the diagnostics with the given severity.
+func (r *ExtractionResult) DiagnosticsBySeverity(severity DiagnosticSeverity) []Diagnostic {
+	var out []Diagnostic
+	for _, d := range r.Diagnostics {
+		if d.Severity == severity {
+			out = append(out, d)
+		}
+	}
+	return out
+}
@@ -0,0 +1,567 @@
+package kreuzberg
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultLeaseTimeout       = 5 * time.Minute
+	defaultCoordinatorRetries = 3
+	defaultWorkerPollInterval = time.Second
+	// coordinatorProtocolVersion is the path segment that versions the coordinator protocol. A
+	// change to the request or reply bodies that old workers cannot read bumps it.
+	coordinatorProtocolVersion = "v1"
+)
+
+// maxCompletionBytes bounds a completion request body, which carries the extraction result; tests
+// lower it.
+var maxCompletionBytes int64 = 256 << 20
+
+// CoordinatorOptions configures a Coordinator.
+type CoordinatorOptions struct {
+	// LeaseTimeout is how long a worker may hold a task before it is handed to another worker
+	// (default 5 minutes). Set it well above the slowest expected extraction.
+	LeaseTimeout time.Duration
+	// MaxAttempts is the number of times a task is tried before it is reported as failed
+	// (default 3). Expired leases count as attempts.
+	MaxAttempts int
+	// Sink receives each successful result by task index. Calls are serialized. When Put fails
+	// the completion is rejected and the task is retried.
+	Sink ResultSink
+}
+
+// CoordinatorStats summarizes the progress of a distributed extraction.
+type CoordinatorStats struct {
+	Total     int `json:"total"`
+	Succeeded int `json:"succeeded"`
+	// Failed counts tasks that failed on every attempt.
+	Failed   int `json:"failed"`
+	Pending  int `json:"pending"`
+	InFlight int `json:"in_flight"`
+	// Retries counts attempts after the first, including those caused by expired leases.
+	Retries int `json:"retries"`
+	// Errors maps failed task indices to their last error.
+	Errors map[int]string `json:"errors,omitempty"`
+	// Workers reports per-worker totals by worker ID.
+	Workers map[string]WorkerStats `json:"workers"`
+}
+
+// WorkerStats are the totals of one worker.
+type WorkerStats struct {
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	// Busy is the time the worker spent extracting.
+	Busy time.Duration `json:"busy"`
+}
+
+type taskStatus int
+
+const (
+	taskPending taskStatus = iota
+	taskLeased
+	taskSucceeded
+	taskFailed
+)
+
+type coordinatorTask struct {
+	status   taskStatus
+	attempts int
+	lease    string
+	worker   string
+	deadline time.Time
+	lastErr  string
+}
+
+// Coordinator shards a corpus of document paths across worker processes. It is an
+// http.Handler: mount it on a server the workers can reach (adding TLS and authentication as
+// needed) and start workers with RunWorker pointing at its URL. Paths must be readable by the
+// workers, e.g. on a shared filesystem.
+//
+// The protocol is plain HTTP with JSON bodies, versioned by its path: POST /v1/lease hands out
+// tasks and POST /v1/complete reports results, so workers can also be written in other
+// languages. Requests for another protocol version are rejected with 400 Bad Request, so a
+// worker built against an incompatible release fails instead of misreading the bodies. A
+// completion too large to accept is rejected with 413 Request Entity Too Large; the worker then
+// reports the task as failed instead. The protocol is HTTP rather than gRPC so that it needs
+// nothing beyond the standard library and can be served behind any HTTP server or proxy.
+type Coordinator struct {
+	paths []string
+	opts  CoordinatorOptions
+
+	mu       sync.Mutex
+	tasks    []coordinatorTask
+	queue    []int
+	stats    CoordinatorStats
+	finished chan struct{}
+	sinkMu   sync.Mutex
+	// progress is when a worker last asked for or completed a task.
+	progress time.Time
+}
+
+// NewCoordinator returns a Coordinator for paths; opts may be nil.
+func NewCoordinator(paths []string, opts *CoordinatorOptions) (*Coordinator, error) {
+	for i, p := range paths {
+		if p == "" {
+			return nil, newValidationErrorWithContext(fmt.Sprintf("path at index %d is empty", i), nil, ErrorCodeValidation, nil)
+		}
+	}
+	c := &Coordinator{
+		paths:    append([]string(nil), paths...),
+		tasks:    make([]coordinatorTask, len(paths)),
+		queue:    make([]int, len(paths)),
+		finished: make(chan struct{}),
+		progress: time.Now(),
+		stats:    CoordinatorStats{Total: len(paths), Pending: len(paths), Errors: map[int]string{}, Workers: map[string]WorkerStats{}},
+	}
+	if opts != nil {
+		c.opts = *opts
+	}
+	if c.opts.LeaseTimeout <= 0 {
+		c.opts.LeaseTimeout = defaultLeaseTimeout
+	}
+	if c.opts.MaxAttempts <= 0 {
+		c.opts.MaxAttempts = defaultCoordinatorRetries
+	}
+	for i := range c.queue {
+		c.queue[i] = i
+	}
+	if len(paths) == 0 {
+		close(c.finished)
+	}
+	return c, nil
+}
+
+// leaseRequest is the body of POST /v1/lease.
+type leaseRequest struct {
+	Worker string `json:"worker"`
+	Max    int    `json:"max"`
+}
+
+// leasedTask is a task handed to a worker.
+type leasedTask struct {
+	Index int    `json:"index"`
+	Path  string `json:"path"`
+	Lease string `json:"lease"`
+}
+
+// leaseResponse is the reply to POST /v1/lease. Done reports that no work remains; an empty Tasks
+// with Done false means the remaining tasks are leased and the worker should poll again.
+type leaseResponse struct {
+	Tasks []leasedTask `json:"tasks"`
+	Done  bool         `json:"done"`
+}
+
+// completionRequest is the body of POST /v1/complete. Exactly one of Result and Error is set.
+type completionRequest struct {
+	Worker     string            `json:"worker"`
+	Index      int               `json:"index"`
+	Lease      string            `json:"lease"`
+	Result     *ExtractionResult `json:"result,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	DurationMS int64             `json:"duration_ms"`
+}
+
+// ServeHTTP implements the coordinator protocol.
+func (c *Coordinator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	dir, op := path.Split(r.URL.Path)
+	if version := path.Base(dir); version != coordinatorProtocolVersion {
+		http.Error(w, fmt.Sprintf("unsupported coordinator protocol version %q, expected %q", version, coordinatorProtocolVersion), http.StatusBadRequest)
+		return
+	}
+	switch op {
+	case "lease":
+		var req leaseRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Worker == "" {
+			http.Error(w, "invalid lease request", http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, c.lease(req.Worker, max(req.Max, 1), time.Now()))
+	case "complete":
+		var req completionRequest
+		err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxCompletionBytes)).Decode(&req)
+		if tooLarge := (*http.MaxBytesError)(nil); errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("completion exceeds %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil || req.Worker == "" {
+			http.Error(w, "invalid completion request", http.StatusBadRequest)
+			return
+		}
+		status, err := c.complete(req)
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// writeJSON encodes v before writing it, so that an encoding failure is reported as 500
+// Internal Server Error instead of a truncated body. A write error means the worker went away;
+// the tasks of an undelivered lease are handed out again once it expires.
+func writeJSON(w http.ResponseWriter, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, "failed to encode reply: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// lease hands up to n pending tasks to worker, first re-queueing expired leases.
+func (c *Coordinator) lease(worker string, n int, now time.Time) leaseResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reclaimExpired(now)
+	c.progress = now
+	resp := leaseResponse{Tasks: []leasedTask{}, Done: c.done()}
+	for len(resp.Tasks) < n && len(c.queue) > 0 {
+		i := c.queue[0]
+		c.queue = c.queue[1:]
+		task := &c.tasks[i]
+		if task.attempts > 0 {
+			c.stats.Retries++
+		}
+		task.status, task.worker, task.lease, task.deadline = taskLeased, worker, rand.Text(), now.Add(c.opts.LeaseTimeout)
+		task.attempts++
+		c.stats.Pending--
+		c.stats.InFlight++
+		resp.Tasks = append(resp.Tasks, leasedTask{Index: i, Path: c.paths[i], Lease: task.lease})
+	}
+	if _, ok := c.stats.Workers[worker]; !ok {
+		c.stats.Workers[worker] = WorkerStats{}
+	}
+	return resp
+}
+
+// reclaimExpired re-queues or fails tasks whose lease has expired. c.mu must be held.
+func (c *Coordinator) reclaimExpired(now time.Time) {
+	for i := range c.tasks {
+		task := &c.tasks[i]
+		if task.status == taskLeased && now.After(task.deadline) {
+			c.stats.InFlight--
+			c.retryOrFail(i, fmt.Sprintf("lease held by worker %q expired", task.worker))
+		}
+	}
+}
+
+// retryOrFail re-queues task i or marks it failed after MaxAttempts. c.mu must be held.
+func (c *Coordinator) retryOrFail(i int, reason string) {
+	task := &c.tasks[i]
+	task.lease, task.lastErr = "", reason
+	if task.attempts < c.opts.MaxAttempts {
+		task.status = taskPending
+		c.stats.Pending++
+		c.queue = append(c.queue, i)
+		return
+	}
+	task.status = taskFailed
+	c.stats.Failed++
+	c.stats.Errors[i] = reason
+	c.checkFinished()
+}
+
+// checkFinished closes c.finished once every task succeeded or failed. c.mu must be held.
+func (c *Coordinator) checkFinished() {
+	if c.done() {
+		select {
+		case <-c.finished:
+		default:
+			close(c.finished)
+		}
+	}
+}
+
+func (c *Coordinator) done() bool {
+	return c.stats.Succeeded+c.stats.Failed == c.stats.Total
+}
+
+// complete records a task outcome. Completions for a lease that is no longer current (the task
+// was re-leased after expiring) are rejected with 409 Conflict.
+func (c *Coordinator) complete(req completionRequest) (int, error) {
+	c.mu.Lock()
+	if req.Index < 0 || req.Index >= len(c.tasks) {
+		c.mu.Unlock()
+		return http.StatusBadRequest, fmt.Errorf("task %d does not exist", req.Index)
+	}
+	task := &c.tasks[req.Index]
+	if task.status != taskLeased || task.lease != req.Lease {
+		c.mu.Unlock()
+		return http.StatusConflict, fmt.Errorf("lease for task %d is no longer held", req.Index)
+	}
+	c.mu.Unlock()
+
+	outcome := req.Error
+	if outcome == "" && req.Result == nil {
+		outcome = "worker reported neither a result nor an error"
+	}
+	status := http.StatusNoContent
+	if outcome == "" && c.opts.Sink != nil {
+		c.sinkMu.Lock()
+		err := c.opts.Sink.Put(req.Index, req.Result)
+		c.sinkMu.Unlock()
+		if err != nil {
+			outcome, status = fmt.Sprintf("result sink failed: %v", err), http.StatusInternalServerError
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if task.status != taskLeased || task.lease != req.Lease {
+		// The lease expired while the result was being stored.
+		return http.StatusConflict, fmt.Errorf("lease for task %d is no longer held", req.Index)
+	}
+	c.stats.InFlight--
+	c.progress = time.Now()
+	worker := c.stats.Workers[req.Worker]
+	worker.Busy += time.Duration(req.DurationMS) * time.Millisecond
+	if outcome == "" {
+		task.status, task.lease = taskSucceeded, ""
+		c.stats.Succeeded++
+		worker.Succeeded++
+		c.checkFinished()
+	} else {
+		worker.Failed++
+		c.retryOrFail(req.Index, outcome)
+	}
+	c.stats.Workers[req.Worker] = worker
+	if status != http.StatusNoContent {
+		return status, fmt.Errorf("%s", outcome)
+	}
+	return status, nil
+}
+
+// Stats returns a snapshot of the progress.
+func (c *Coordinator) Stats() CoordinatorStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reclaimExpired(time.Now())
+	stats := c.stats
+	stats.Errors = make(map[int]string, len(c.stats.Errors))
+	for i, err := range c.stats.Errors {
+		stats.Errors[i] = err
+	}
+	stats.Workers = make(map[string]WorkerStats, len(c.stats.Workers))
+	for id, w := range c.stats.Workers {
+		stats.Workers[id] = w
+	}
+	return stats
+}
+
+// Wait blocks until every task has succeeded or failed, or ctx is done, and returns the final
+// statistics. Expired leases are reclaimed while waiting even when no worker is polling.
+func (c *Coordinator) Wait(ctx context.Context) (CoordinatorStats, error) {
+	ticker := time.NewTicker(min(c.opts.LeaseTimeout, time.Second))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.finished:
+			return c.Stats(), nil
+		case <-ctx.Done():
+			return c.Stats(), ctx.Err()
+		case <-ticker.C:
+			c.mu.Lock()
+			c.reclaimExpired(time.Now())
+			c.mu.Unlock()
+		}
+	}
+}
+
+// HealthCheck returns a readiness check for the worker pool: it fails while work remains but no
+// worker has asked for or completed a task for longer than stall, e.g. because every worker died.
+func (c *Coordinator) HealthCheck(stall time.Duration) HealthCheck {
+	return HealthCheck{Name: "coordinator", Check: func(context.Context) error {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if idle := time.Since(c.progress); !c.done() && idle > stall {
+			return fmt.Errorf("no worker progress for %s with %d tasks pending and %d in flight", idle.Round(time.Second), c.stats.Pending, c.stats.InFlight)
+		}
+		return nil
+	}}
+}
+
+// WorkerOptions configures RunWorker.
+type WorkerOptions struct {
+	// ID identifies the worker in the coordinator statistics (default "<hostname>-<pid>").
+	ID string
+	// Config configures extraction. Client.RunWorker uses the client's config instead.
+	Config *ExtractionConfig
+	// LeaseSize is the number of tasks leased per request (default 1).
+	LeaseSize int
+	// PollInterval is the wait before asking again when all remaining tasks are leased by
+	// other workers (default 1 second).
+	PollInterval time.Duration
+	// HTTPClient sends the coordinator requests (default http.DefaultClient).
+	HTTPClient *http.Client
+	// Drain, when closed, stops the worker from leasing more tasks: it finishes and reports the
+	// tasks it holds and returns nil. Close it on SIGTERM so a Supervisor can replace the worker
+	// without abandoning leases.
+	Drain <-chan struct{}
+}
+
+// RunWorker leases tasks from the coordinator at coordinatorURL, extracts them and reports the
+// results until the coordinator has no work left, opts.Drain is closed or ctx is done.
+// Extraction errors are reported to the coordinator, which retries the task; only coordinator
+// communication errors and ctx end the worker with an error. Once the coordinator answers the
+// first lease request, the worker reports READY=1 over NOTIFY_SOCKET (see Supervisor).
+func RunWorker(ctx context.Context, coordinatorURL string, opts *WorkerOptions) error {
+	var config *ExtractionConfig
+	if opts != nil {
+		config = opts.Config
+	}
+	return runWorker(ctx, defaultPluginRegistry, config, coordinatorURL, opts)
+}
+
+// RunWorker runs a worker that extracts with the client's config and plugins; see the
+// package-level RunWorker.
+func (c *Client) RunWorker(ctx context.Context, coordinatorURL string, opts *WorkerOptions) error {
+	return runWorker(ctx, c.plugins, c.config, coordinatorURL, opts)
+}
+
+func runWorker(ctx context.Context, plugins *pluginRegistry, config *ExtractionConfig, coordinatorURL string, opts *WorkerOptions) error {
+	var o WorkerOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.ID == "" {
+		host, _ := os.Hostname()
+		o.ID = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	if o.LeaseSize <= 0 {
+		o.LeaseSize = 1
+	}
+	if o.PollInterval <= 0 {
+		o.PollInterval = defaultWorkerPollInterval
+	}
+	if o.HTTPClient == nil {
+		o.HTTPClient = http.DefaultClient
+	}
+	base := strings.TrimSuffix(coordinatorURL, "/") + "/" + coordinatorProtocolVersion
+
+	notified := false
+	for {
+		select {
+		case <-o.Drain:
+			return nil
+		default:
+		}
+		var leased leaseResponse
+		if err := postCoordinator(ctx, o.HTTPClient, base+"/lease", leaseRequest{Worker: o.ID, Max: o.LeaseSize}, &leased); err != nil {
+			return err
+		}
+		if !notified {
+			sdNotify("READY=1")
+			notified = true
+		}
+		if len(leased.Tasks) == 0 {
+			if leased.Done {
+				return nil
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-o.Drain:
+				return nil
+			case <-time.After(o.PollInterval):
+			}
+			continue
+		}
+		for _, task := range leased.Tasks {
+			start := time.Now()
+			result, err := extractFile(ctx, plugins, task.Path, config)
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			completion := completionRequest{Worker: o.ID, Index: task.Index, Lease: task.Lease, Result: result, DurationMS: time.Since(start).Milliseconds()}
+			if err != nil {
+				completion.Result, completion.Error = nil, err.Error()
+			}
+			err = postCoordinator(ctx, o.HTTPClient, base+"/complete", completion, nil)
+			if coordinatorStatus(err) == http.StatusRequestEntityTooLarge {
+				// Report the result that does not fit as a failure, so the task is retried or failed.
+				completion.Result, completion.Error = nil, "result is too large to report to the coordinator"
+				err = postCoordinator(ctx, o.HTTPClient, base+"/complete", completion, nil)
+			}
+			if err != nil && !isCoordinatorConflict(err) {
+				return err
+			}
+		}
+	}
+}
+
+// coordinatorStatusError is a non-success reply from the coordinator.
+type coordinatorStatusError struct {
+	status int
+	body   string
+}
+
+func (e *coordinatorStatusError) Error() string {
+	return fmt.Sprintf("coordinator replied %d: %s", e.status, e.body)
+}
+
+// isCoordinatorConflict reports a completion for a lease that expired, or one the coordinator
+// could not store; both leave the task to be retried, so the worker carries on.
+func isCoordinatorConflict(err error) bool {
+	status := coordinatorStatus(err)
+	return status == http.StatusConflict || status == http.StatusInternalServerError
+}
+
+// coordinatorStatus returns the status of a non-success coordinator reply, or 0.
+func coordinatorStatus(err error) int {
+	var statusErr *coordinatorStatusError
+	if !errors.As(err, &statusErr) {
+		return 0
+	}
+	return statusErr.status
+}
+
+// postCoordinator posts body as JSON to url and decodes the reply into out when non-nil.
+func postCoordinator(ctx context.Context, client *http.Client, url string, body, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return newSerializationErrorWithContext("failed to encode coordinator request", err, ErrorCodeValidation, nil)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return newValidationErrorWithContext("invalid coordinator URL", err, ErrorCodeValidation, nil)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return newIOErrorWithContext("coordinator request failed", err, ErrorCodeIo, nil)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return newIOErrorWithContext("coordinator request failed", &coordinatorStatusError{status: resp.StatusCode, body: strings.TrimSpace(string(msg))}, ErrorCodeIo, nil)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return newSerializationErrorWithContext("failed to decode coordinator reply", err, ErrorCodeValidation, nil)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package kreuzberg
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCoordinatorShardsAcrossWorkers(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for _, name := range []string{"a.srt", "b.srt", "c.srt", "d.srt", "e.srt"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(testSRT), 0o600); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		paths = append(paths, path)
+	}
+	paths = append(paths, filepath.Join(dir, "missing.srt"))
+
+	var mu sync.Mutex
+	got := map[int]string{}
+	sink := ResultSinkFunc(func(index int, result *ExtractionResult) error {
+		mu.Lock()
+		defer mu.Unlock()
+		got[index] = result.Content
+		return nil
+	})
+	coordinator, err := NewCoordinator(paths, &CoordinatorOptions{MaxAttempts: 2, Sink: sink})
+	if err != nil {
+		t.Fatalf("new coordinator: %v", err)
+	}
+	server := httptest.NewServer(coordinator)
+	defer server.Close()
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i, id := range []string{"w1", "w2"} {
+		wg.Go(func() {
+			errs[i] = RunWorker(t.Context(), server.URL, &WorkerOptions{ID: id, LeaseSize: 2, PollInterval: 10 * time.Millisecond})
+		})
+	}
+	stats, err := coordinator.Wait(t.Context())
+	wg.Wait()
+	if err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+	for _, err := range errs {
+		if err != nil {
+			t.Fatalf("worker: %v", err)
+		}
+	}
+
+	if stats.Total != 6 || stats.Succeeded != 5 || stats.Failed != 1 || stats.Retries != 1 || stats.Pending != 0 || stats.InFlight != 0 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if _, ok := stats.Errors[5]; !ok || len(stats.Errors) != 1 {
+		t.Fatalf("expected the missing file to be reported, got %v", stats.Errors)
+	}
+	var done int
+	for _, w := range stats.Workers {
+		done += w.Succeeded + w.Failed
+	}
+	if len(stats.Workers) != 2 || done != 7 {
+		t.Fatalf("unexpected worker stats: %+v", stats.Workers)
+	}
+	if len(got) != 5 || !strings.Contains(got[0], wantSRTContent) {
+		t.Fatalf("unexpected sink contents: %v", got)
+	}
+}
+
+func TestCoordinatorLeaseExpiry(t *testing.T) {
+	coordinator, err := NewCoordinator([]string{"a.srt"}, &CoordinatorOptions{LeaseTimeout: time.Minute, MaxAttempts: 2})
+	if err != nil {
+		t.Fatalf("new coordinator: %v", err)
+	}
+	now := time.Now()
+	first := coordinator.lease("slow", 1, now)
+	if len(first.Tasks) != 1 || first.Done {
+		t.Fatalf("unexpected lease: %+v", first)
+	}
+	if again := coordinator.lease("fast", 1, now); len(again.Tasks) != 0 || again.Done {
+		t.Fatalf("expected no tasks while the lease is held, got %+v", again)
+	}
+
+	second := coordinator.lease("fast", 1, now.Add(2*time.Minute))
+	if len(second.Tasks) != 1 || second.Tasks[0].Lease == first.Tasks[0].Lease {
+		t.Fatalf("expected the expired task to be re-leased, got %+v", second)
+	}
+	status, err := coordinator.complete(completionRequest{Worker: "slow", Index: 0, Lease: first.Tasks[0].Lease, Result: &ExtractionResult{}})
+	if status != http.StatusConflict || err == nil {
+		t.Fatalf("expected a stale completion to conflict, got %d, %v", status, err)
+	}
+	if _, err := coordinator.complete(completionRequest{Worker: "fast", Index: 0, Lease: second.Tasks[0].Lease, Result: &ExtractionResult{}}); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	stats, err := coordinator.Wait(t.Context())
+	if err != nil || stats.Succeeded != 1 || stats.Retries != 1 || stats.Workers["fast"].Succeeded != 1 {
+		t.Fatalf("unexpected stats: %+v, %v", stats, err)
+	}
+	if resp := coordinator.lease("late", 1, now); !resp.Done {
+		t.Fatalf("expected done once every task finished, got %+v", resp)
+	}
+
+	if _, err := NewCoordinator([]string{""}, nil); err == nil {
+		t.Fatalf("expected an error for an empty path")
+	}
+}
+
+func TestRunWorkerDrain(t *testing.T) {
+	coordinator, err := NewCoordinator([]string{filepath.Join(t.TempDir(), "a.srt")}, nil)
+	if err != nil {
+		t.Fatalf("new coordinator: %v", err)
+	}
+	server := httptest.NewServer(coordinator)
+	defer server.Close()
+	drain := make(chan struct{})
+	close(drain)
+	if err := RunWorker(t.Context(), server.URL, &WorkerOptions{Drain: drain}); err != nil {
+		t.Fatalf("expected a drained worker to stop cleanly, got %v", err)
+	}
+	if stats := coordinator.Stats(); stats.Pending != 1 || stats.InFlight != 0 {
+		t.Fatalf("expected a drained worker to lease nothing, got %+v", stats)
+	}
+}
+
+func TestCoordinatorProtocolVersion(t *testing.T) {
+	coordinator, err := NewCoordinator([]string{"a.srt", "b.srt"}, nil)
+	if err != nil {
+		t.Fatalf("new coordinator: %v", err)
+	}
+	for target, want := range map[string]int{
+		"/lease":          http.StatusBadRequest,
+		"/v2/lease":       http.StatusBadRequest,
+		"/v1/lease":       http.StatusOK,
+		"/jobs/v1/lease":  http.StatusOK,
+		"/v1/unknown-op":  http.StatusNotFound,
+		"/v1/lease/extra": http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		coordinator.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, target, strings.NewReader(`{"worker": "w"}`)))
+		if rec.Code != want {
+			t.Errorf("%s: got status %d, want %d: %s", target, rec.Code, want, rec.Body)
+		}
+	}
+	if stats := coordinator.Stats(); stats.InFlight != 2 {
+		t.Fatalf("expected only the versioned requests to lease, got %+v", stats)
+	}
+}
+
+func TestCoordinatorRejectsOversizedCompletions(t *testing.T) {
+	maxCompletionBytes = 200
+	t.Cleanup(func() { maxCompletionBytes = 256 << 20 })
+	path := filepath.Join(t.TempDir(), "a.srt")
+	if err := os.WriteFile(path, []byte(testSRT), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	coordinator, err := NewCoordinator([]string{path}, &CoordinatorOptions{MaxAttempts: 1})
+	if err != nil {
+		t.Fatalf("new coordinator: %v", err)
+	}
+	server := httptest.NewServer(coordinator)
+	defer server.Close()
+
+	if err := RunWorker(t.Context(), server.URL, &WorkerOptions{ID: "w"}); err != nil {
+		t.Fatalf("expected the worker to carry on after an oversized completion, got %v", err)
+	}
+	stats := coordinator.Stats()
+	if stats.Failed != 1 || !strings.Contains(stats.Errors[0], "too large") || stats.Workers["w"].Failed != 1 {
+		t.Fatalf("expected the oversized result to fail the task, got %+v", stats)
+	}
+}
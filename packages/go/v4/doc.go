@@ -78,7 +78,7 @@
 //		ForceOCR:        false,
 //		ImageExtraction: &kreuzberg.ImageExtractionConfig{Enabled: true},
 //		OCR: &kreuzberg.OcrConfig{
-//			Backend:   "tesseract",
+//			Backend:   kreuzberg.OCRBackendTesseract,
 //			Language:  &lang,
 //			PageRange: nil,
 //		},
@@ -198,6 +198,16 @@
 // Validators are invoked after extraction and can modify the result payload.
 // Priority controls execution order (higher = runs first).
 //
+// Go-native plugins need no cgo exports and receive a PluginContext describing the
+// source document (path, lazily computed hash, MIME type, config, and caller labels):
+//
+//	kreuzberg.RegisterValidatorFunc("tenant-rules", 50, func(ctx *kreuzberg.PluginContext, r *kreuzberg.ExtractionResult) error {
+//		if tenant, _ := ctx.Label("tenant"); tenant == "acme" && r.Content == "" {
+//			return errors.New("acme documents must contain text")
+//		}
+//		return nil
+//	})
+//
 // # Chunking and Embeddings
 //
 // Extract documents in semantic chunks with optional embeddings:
@@ -227,7 +237,7 @@
 //	cfg := &kreuzberg.ExtractionConfig{
 //		ImageExtraction: &kreuzberg.ImageExtractionConfig{Enabled: true},
 //		OCR: &kreuzberg.OcrConfig{
-//			Backend: "tesseract",
+//			Backend: kreuzberg.OCRBackendTesseract,
 //		},
 //	}
 //	result, err := kreuzberg.ExtractFileSync("scanned.pdf", cfg)
@@ -0,0 +1,215 @@
+package kreuzberg
+
+import (
+	"context"
+	"os"
+	"sync"
+)
+
+// Document is an opened document whose parts are extracted on first access and kept, so callers
+// only pay for the parts they use. It is safe for concurrent use.
+//
+// Content, Metadata, Tables and Pages come from one extraction with image extraction and
+// chunking turned off, whatever the config asks for; splitting the content into pages costs
+// next to nothing next to parsing the document. Images and Chunks each add one extraction with
+// just their feature turned on, the first time they are called. When one of them is the first
+// access, its extraction serves the other parts too, so a Document read through Chunks alone
+// costs a single extraction.
+type Document struct {
+	src    documentSource
+	config *ExtractionConfig
+
+	mu     sync.Mutex
+	base   *ExtractionResult
+	images []ExtractedImage
+	chunks []Chunk
+	// loaded records the sections loaded so far.
+	loaded [documentSections]bool
+}
+
+// documentSection is a part of a Document loaded by its own extraction.
+type documentSection int
+
+const (
+	documentSectionBase documentSection = iota
+	documentSectionImages
+	documentSectionChunks
+	documentSections
+)
+
+// Open opens the document at path for lazy extraction with config. It only checks that the file
+// exists; extraction errors are returned by the accessors.
+func Open(path string, config *ExtractionConfig) (*Document, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, newIOErrorWithContext("failed to open document", err, ErrorCodeIo, nil)
+	}
+	if info.IsDir() {
+		return nil, newValidationErrorWithContext("cannot open a directory as a document: "+path, nil, ErrorCodeValidation, nil)
+	}
+	return &Document{src: documentSource{path: path}, config: MergeConfigs(config, nil)}, nil
+}
+
+// OpenBytes opens an in-memory document of the given MIME type for lazy extraction with config.
+// The Document keeps a reference to data, which the caller must not modify.
+func OpenBytes(data []byte, mimeType string, config *ExtractionConfig) (*Document, error) {
+	if len(data) == 0 {
+		return nil, newValidationErrorWithContext("data cannot be empty", nil, ErrorCodeValidation, nil)
+	}
+	return &Document{src: documentSource{data: data, mimeType: mimeType}, config: MergeConfigs(config, nil)}, nil
+}
+
+// Path returns the path the Document was opened from, or "" for in-memory documents.
+func (d *Document) Path() string {
+	return d.src.path
+}
+
+// MimeType returns the MIME type the document was extracted as.
+func (d *Document) MimeType() (string, error) {
+	base, err := d.loadBase()
+	if err != nil {
+		return "", err
+	}
+	return base.MimeType, nil
+}
+
+// Content returns the extracted text of the document.
+func (d *Document) Content() (string, error) {
+	base, err := d.loadBase()
+	if err != nil {
+		return "", err
+	}
+	return base.Content, nil
+}
+
+// Metadata returns the metadata of the document.
+func (d *Document) Metadata() (Metadata, error) {
+	base, err := d.loadBase()
+	if err != nil {
+		return Metadata{}, err
+	}
+	return base.Metadata, nil
+}
+
+// Tables returns the tables of the document.
+func (d *Document) Tables() ([]Table, error) {
+	base, err := d.loadBase()
+	if err != nil {
+		return nil, err
+	}
+	return base.Tables, nil
+}
+
+// Pages returns the content of each page, for formats with pages. Page tracking is enabled for
+// the extraction even when the config does not request it.
+func (d *Document) Pages() ([]PageContent, error) {
+	base, err := d.loadBase()
+	if err != nil {
+		return nil, err
+	}
+	return base.Pages, nil
+}
+
+// Images returns the images embedded in the document. Image extraction is enabled for the
+// extraction even when the config does not request it.
+func (d *Document) Images() ([]ExtractedImage, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.load(documentSectionImages); err != nil {
+		return nil, err
+	}
+	return d.images, nil
+}
+
+// Chunks returns the chunks of the document, chunked with the config's ChunkingConfig or the
+// default chunking when it has none.
+func (d *Document) Chunks() ([]Chunk, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.load(documentSectionChunks); err != nil {
+		return nil, err
+	}
+	return d.chunks, nil
+}
+
+// Release drops the loaded parts, so they are extracted again on next access.
+func (d *Document) Release() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.base, d.images, d.chunks = nil, nil, nil
+	d.loaded = [documentSections]bool{}
+}
+
+func (d *Document) loadBase() (*ExtractionResult, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.load(documentSectionBase); err != nil {
+		return nil, err
+	}
+	return d.base, nil
+}
+
+// load extracts section unless it was loaded before. The caller holds d.mu. A failed load is
+// retried on next access.
+func (d *Document) load(section documentSection) error {
+	if d.loaded[section] {
+		return nil
+	}
+	config := documentSectionConfig(d.config, section)
+	var result *ExtractionResult
+	var err error
+	if d.src.path != "" {
+		result, err = extractFile(context.Background(), defaultPluginRegistry, d.src.path, config)
+	} else {
+		result, err = extractBytes(context.Background(), defaultPluginRegistry, d.src.data, d.src.mimeType, config)
+	}
+	if err != nil {
+		return err
+	}
+	switch section {
+	case documentSectionImages:
+		d.images = result.Images
+	case documentSectionChunks:
+		d.chunks = result.Chunks
+	}
+	if !d.loaded[documentSectionBase] {
+		// Every extraction carries the base parts; keep them without the section-specific ones.
+		base := *result
+		base.Images, base.Chunks = nil, nil
+		d.base = &base
+		d.loaded[documentSectionBase] = true
+	}
+	d.loaded[section] = true
+	return nil
+}
+
+// documentSectionConfig derives the config that extracts section of a Document opened with
+// config: pages are always tracked, and image extraction and chunking are turned on only for
+// the section asking for them.
+func documentSectionConfig(config *ExtractionConfig, section documentSection) *ExtractionConfig {
+	derived := MergeConfigs(config, nil)
+	if derived.Pages == nil {
+		derived.Pages = &PageConfig{}
+	}
+	derived.Pages.ExtractPages = BoolPtr(true)
+
+	extractImages := section == documentSectionImages
+	if derived.Images == nil {
+		derived.Images = &ImageExtractionConfig{}
+	}
+	derived.Images.ExtractImages = BoolPtr(extractImages)
+	if derived.PdfOptions == nil {
+		derived.PdfOptions = &PdfConfig{}
+	}
+	derived.PdfOptions.ExtractImages = BoolPtr(extractImages)
+
+	if section != documentSectionChunks {
+		derived.Chunking = nil
+		return derived
+	}
+	if derived.Chunking == nil {
+		derived.Chunking = &ChunkingConfig{}
+	}
+	derived.Chunking.Enabled = BoolPtr(true)
+	return derived
+}
@@ -0,0 +1,159 @@
+package kreuzberg
+
+/*
+#include "internal/ffi/kreuzberg.h"
+#include <stdlib.h>
+#include <stdint.h>
+
+bool kreuzberg_register_document_extractor(const char *name, DocumentExtractorCallback callback, const char *mime_types, int32_t priority);
+
+// Implemented in Go (document_extractor_callback.go).
+extern char *kreuzbergGoDocumentExtractor(uint8_t *content, uintptr_t content_len, char *mime_type, char *config_json);
+*/
+import "C"
+
+import (
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// DocumentExtractorFunc is a Go-native document extractor. It receives the raw document bytes,
+// the MIME type selected by the native dispatcher, and the effective extraction config.
+type DocumentExtractorFunc func(data []byte, mimeType string, config *ExtractionConfig) (*ExtractionResult, error)
+
+type registeredExtractor struct {
+	name      string
+	mimeTypes []string
+	priority  int32
+	fn        DocumentExtractorFunc
+}
+
+// goExtractors tracks Go-native extractors. All of them share one native trampoline, which
+// dispatches to the highest-priority Go extractor claiming the requested MIME type.
+var goExtractors = struct {
+	sync.RWMutex
+	byName map[string]registeredExtractor
+}{byName: map[string]registeredExtractor{}}
+
+// RegisterDocumentExtractor registers a C-callable document extractor for the given MIME types.
+//
+// `callback` must follow the DocumentExtractorCallback contract; in particular the returned
+// JSON string must be freeable by kreuzberg_free_string.
+func RegisterDocumentExtractor(name string, mimeTypes []string, priority int32, callback C.DocumentExtractorCallback) error {
+	if name == "" {
+		return newValidationErrorWithContext("document extractor name cannot be empty", nil, ErrorCodeValidation, nil)
+	}
+	if callback == nil {
+		return newValidationErrorWithContext("document extractor callback cannot be nil", nil, ErrorCodeValidation, nil)
+	}
+	mimeList, err := joinMimeClaims(mimeTypes)
+	if err != nil {
+		return err
+	}
+
+	cName := newCString(name)
+	defer freeCBuffer(unsafe.Pointer(cName))
+	cMimes := newCString(mimeList)
+	defer freeCBuffer(unsafe.Pointer(cMimes))
+
+	if ok := C.kreuzberg_register_document_extractor(cName, callback, cMimes, C.int32_t(priority)); !bool(ok) {
+		return lastError()
+	}
+	return nil
+}
+
+// RegisterDocumentExtractorFunc registers a pure-Go document extractor with the native dispatcher.
+// Documents whose MIME type matches one of mimeTypes are routed to fn by ExtractFileSync,
+// ExtractBytesSync, and the batch APIs whenever priority beats the built-in extractors.
+//
+// File-based extraction relies on native MIME detection, so proprietary formats must either use
+// an extension the native library recognizes or be extracted with ExtractBytesSync and an explicit
+// MIME type. Errors returned by fn surface as a ParsingError from the native layer.
+func RegisterDocumentExtractorFunc(name string, mimeTypes []string, priority int32, fn DocumentExtractorFunc) error {
+	if name == "" {
+		return newValidationErrorWithContext("document extractor name cannot be empty", nil, ErrorCodeValidation, nil)
+	}
+	if fn == nil {
+		return newValidationErrorWithContext("document extractor func cannot be nil", nil, ErrorCodeValidation, nil)
+	}
+	if _, err := joinMimeClaims(mimeTypes); err != nil {
+		return err
+	}
+
+	goExtractors.Lock()
+	if _, exists := goExtractors.byName[name]; exists {
+		goExtractors.Unlock()
+		return newPluginErrorWithContext(name, "document extractor '"+name+"' is already registered", nil, ErrorCodePlugin, nil)
+	}
+	goExtractors.byName[name] = registeredExtractor{
+		name:      name,
+		mimeTypes: append([]string(nil), mimeTypes...),
+		priority:  priority,
+		fn:        fn,
+	}
+	goExtractors.Unlock()
+
+	callback := (C.DocumentExtractorCallback)(C.kreuzbergGoDocumentExtractor)
+	if err := RegisterDocumentExtractor(name, mimeTypes, priority, callback); err != nil {
+		forgetGoExtractor(name)
+		return err
+	}
+	return nil
+}
+
+// lookupGoExtractor returns the highest-priority Go extractor claiming mimeType.
+func lookupGoExtractor(mimeType string) (registeredExtractor, bool) {
+	goExtractors.RLock()
+	defer goExtractors.RUnlock()
+
+	var best registeredExtractor
+	found := false
+	for _, ext := range goExtractors.byName {
+		if !claimsMime(ext.mimeTypes, mimeType) {
+			continue
+		}
+		if !found || ext.priority > best.priority || (ext.priority == best.priority && ext.name < best.name) {
+			best = ext
+			found = true
+		}
+	}
+	return best, found
+}
+
+func forgetGoExtractor(name string) {
+	goExtractors.Lock()
+	delete(goExtractors.byName, name)
+	goExtractors.Unlock()
+}
+
+func forgetAllGoExtractors() {
+	goExtractors.Lock()
+	goExtractors.byName = map[string]registeredExtractor{}
+	goExtractors.Unlock()
+}
+
+func claimsMime(claims []string, mimeType string) bool {
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+	for _, claim := range claims {
+		if strings.ToLower(strings.TrimSpace(claim)) == mimeType {
+			return true
+		}
+	}
+	return false
+}
+
+func joinMimeClaims(mimeTypes []string) (string, error) {
+	if len(mimeTypes) == 0 {
+		return "", newValidationErrorWithContext("document extractor must claim at least one MIME type", nil, ErrorCodeValidation, nil)
+	}
+	cleaned := make([]string, 0, len(mimeTypes))
+	for _, mime := range mimeTypes {
+		mime = strings.TrimSpace(mime)
+		if mime == "" || strings.Contains(mime, ",") {
+			return "", newValidationErrorWithContext("invalid MIME type claim: "+mime, nil, ErrorCodeValidation, nil)
+		}
+		cleaned = append(cleaned, mime)
+	}
+	return strings.Join(cleaned, ","), nil
+}
@@ -0,0 +1,78 @@
+package kreuzberg
+
+/*
+#include <stdint.h>
+#include <stdlib.h>
+
+char *kreuzberg_clone_string(const char *s);
+*/
+import "C"
+
+import (
+	"encoding/json"
+	"unsafe"
+)
+
+// kreuzbergGoDocumentExtractor is the native trampoline shared by every extractor registered
+// through RegisterDocumentExtractorFunc.
+//
+//export kreuzbergGoDocumentExtractor
+func kreuzbergGoDocumentExtractor(content *C.uint8_t, contentLen C.uintptr_t, mimeType *C.char, configJSON *C.char) (out *C.char) {
+	defer func() {
+		if recover() != nil {
+			out = nil
+		}
+	}()
+
+	mime := C.GoString(mimeType)
+	ext, ok := lookupGoExtractor(mime)
+	if !ok {
+		return nil
+	}
+
+	var data []byte
+	if content != nil && contentLen > 0 {
+		data = C.GoBytes(unsafe.Pointer(content), C.int(contentLen))
+	}
+
+	var config *ExtractionConfig
+	if configJSON != nil {
+		if raw := C.GoString(configJSON); raw != "" {
+			config = &ExtractionConfig{}
+			if err := json.Unmarshal([]byte(raw), config); err != nil {
+				config = nil
+			}
+		}
+	}
+
+	result, err := ext.fn(data, mime, config)
+	if err != nil || result == nil {
+		return nil
+	}
+	encoded, err := encodeExtractorResult(result, mime)
+	if err != nil {
+		return nil
+	}
+
+	cJSON := C.CString(encoded)
+	defer C.free(unsafe.Pointer(cJSON))
+	// The native side releases the result with kreuzberg_free_string, so it must be Rust-allocated.
+	return C.kreuzberg_clone_string(cJSON)
+}
+
+// encodeExtractorResult serializes a Go-produced result into the shape the native
+// ExtractionResult deserializer expects (non-null tables, populated MIME type).
+func encodeExtractorResult(result *ExtractionResult, mimeType string) (string, error) {
+	out := *result
+	if out.MimeType == "" {
+		out.MimeType = mimeType
+	}
+	if out.Tables == nil {
+		out.Tables = []Table{}
+	}
+	data, err := json.Marshal(&out)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,77 @@
+package kreuzberg
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestLookupGoExtractorPrefersHighestPriority(t *testing.T) {
+	t.Cleanup(forgetAllGoExtractors)
+	noop := func(data []byte, mimeType string, config *ExtractionConfig) (*ExtractionResult, error) {
+		return &ExtractionResult{}, nil
+	}
+
+	goExtractors.Lock()
+	goExtractors.byName["low"] = registeredExtractor{name: "low", mimeTypes: []string{"application/x-acme"}, priority: 10, fn: noop}
+	goExtractors.byName["high"] = registeredExtractor{name: "high", mimeTypes: []string{"application/x-acme", "text/x-acme"}, priority: 50, fn: noop}
+	goExtractors.Unlock()
+
+	ext, ok := lookupGoExtractor("Application/X-Acme")
+	if !ok || ext.name != "high" {
+		t.Fatalf("expected high priority extractor, got %+v (found=%v)", ext.name, ok)
+	}
+	if _, ok := lookupGoExtractor("application/pdf"); ok {
+		t.Fatalf("no extractor should claim application/pdf")
+	}
+
+	forgetGoExtractor("high")
+	ext, ok = lookupGoExtractor("application/x-acme")
+	if !ok || ext.name != "low" {
+		t.Fatalf("expected fallback to low priority extractor, got %+v", ext.name)
+	}
+}
+
+func TestJoinMimeClaimsValidates(t *testing.T) {
+	joined, err := joinMimeClaims([]string{" application/x-acme ", "text/x-acme"})
+	if err != nil || joined != "application/x-acme,text/x-acme" {
+		t.Fatalf("unexpected join result %q, %v", joined, err)
+	}
+	if _, err := joinMimeClaims(nil); err == nil {
+		t.Fatalf("expected error for empty claim list")
+	}
+	if _, err := joinMimeClaims([]string{"a/b,c/d"}); err == nil {
+		t.Fatalf("expected error for comma in claim")
+	}
+}
+
+func TestEncodeExtractorResultMatchesNativeShape(t *testing.T) {
+	encoded, err := encodeExtractorResult(&ExtractionResult{Content: "hello"}, "application/x-acme")
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	var raw map[string]any
+	if err := json.Unmarshal([]byte(encoded), &raw); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if raw["mime_type"] != "application/x-acme" {
+		t.Fatalf("mime type not defaulted: %v", raw["mime_type"])
+	}
+	if tables, ok := raw["tables"].([]any); !ok || len(tables) != 0 {
+		t.Fatalf("tables must be an empty array, got %v", raw["tables"])
+	}
+}
+
+func TestRegisterDocumentExtractorFuncGuards(t *testing.T) {
+	noop := func(data []byte, mimeType string, config *ExtractionConfig) (*ExtractionResult, error) {
+		return &ExtractionResult{}, nil
+	}
+	if err := RegisterDocumentExtractorFunc("", []string{"text/x-acme"}, 0, noop); err == nil {
+		t.Fatalf("expected error for empty name")
+	}
+	if err := RegisterDocumentExtractorFunc("acme", []string{"text/x-acme"}, 0, nil); err == nil {
+		t.Fatalf("expected error for nil func")
+	}
+	if err := RegisterDocumentExtractorFunc("acme", nil, 0, noop); err == nil {
+		t.Fatalf("expected error for missing MIME claims")
+	}
+}
@@ -0,0 +1,110 @@
+package kreuzberg
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDocumentLazySections(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "talk.srt")
+	os.WriteFile(path, []byte(testSRT), 0o600)
+	var extractions atomic.Int32
+	config := &ExtractionConfig{
+		Chunking: &ChunkingConfig{MaxChars: IntPtr(20)},
+		DataFlow: &DataFlowConfig{Inspector: DataFlowInspectorFunc(func(context.Context, DataFlowEvent) (DataFlowDecision, error) {
+			extractions.Add(1)
+			return DataFlowDecision{}, nil
+		})},
+	}
+	doc, err := Open(path, config)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	config.Chunking = nil
+	if extractions.Load() != 0 || doc.Path() != path {
+		t.Fatalf("expected Open not to extract")
+	}
+
+	content, err := doc.Content()
+	if err != nil || content != wantSRTContent {
+		t.Fatalf("unexpected content %q, %v", content, err)
+	}
+	if _, err := doc.Metadata(); err != nil {
+		t.Fatalf("metadata: %v", err)
+	}
+	if mimeType, err := doc.MimeType(); err != nil || mimeType != mimeSRT {
+		t.Fatalf("unexpected MIME type %q, %v", mimeType, err)
+	}
+	if _, err := doc.Pages(); err != nil {
+		t.Fatalf("pages: %v", err)
+	}
+	if n := extractions.Load(); n != 1 {
+		t.Fatalf("expected one extraction for content, metadata and pages, got %d", n)
+	}
+
+	for range 2 {
+		chunks, err := doc.Chunks()
+		if err != nil || len(chunks) < 2 {
+			t.Fatalf("expected the chunking of the opened config, got %d chunks, %v", len(chunks), err)
+		}
+	}
+	if n := extractions.Load(); n != 2 {
+		t.Fatalf("expected chunks to be loaded once, got %d extractions", n)
+	}
+
+	doc.Release()
+	if _, err := doc.Chunks(); err != nil {
+		t.Fatalf("chunks: %v", err)
+	}
+	if _, err := doc.Tables(); err != nil || extractions.Load() != 3 {
+		t.Fatalf("expected a first access through Chunks to serve the other parts, got %d extractions, %v", extractions.Load(), err)
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	var ioErr *IOError
+	if _, err := Open(filepath.Join(t.TempDir(), "missing.pdf"), nil); !errors.As(err, &ioErr) {
+		t.Fatalf("expected an IO error, got %v", err)
+	}
+	var validationErr *ValidationError
+	if _, err := Open(t.TempDir(), nil); !errors.As(err, &validationErr) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	if _, err := OpenBytes(nil, mimeSRT, nil); !errors.As(err, &validationErr) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+
+	doc, err := OpenBytes([]byte("%PDF-1.7\nnot really"), "application/pdf", nil)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if _, err := doc.Content(); err == nil {
+		t.Fatalf("expected the broken document to fail to extract")
+	}
+}
+
+func TestDocumentSectionConfig(t *testing.T) {
+	config := &ExtractionConfig{Chunking: &ChunkingConfig{MaxChars: IntPtr(20), Enabled: BoolPtr(false)}, Pages: &PageConfig{InsertPageMarkers: BoolPtr(true)}, Images: &ImageExtractionConfig{ExtractImages: BoolPtr(true)}}
+	base := documentSectionConfig(config, documentSectionBase)
+	if base.Chunking != nil || !*base.Pages.ExtractPages || !*base.Pages.InsertPageMarkers || *base.Images.ExtractImages || *base.PdfOptions.ExtractImages {
+		t.Fatalf("unexpected base config %+v", base)
+	}
+	images := documentSectionConfig(config, documentSectionImages)
+	if !*images.Images.ExtractImages || !*images.PdfOptions.ExtractImages || images.Chunking != nil {
+		t.Fatalf("unexpected images config %+v", images)
+	}
+	chunks := documentSectionConfig(config, documentSectionChunks)
+	if !*chunks.Chunking.Enabled || *chunks.Chunking.MaxChars != 20 || *chunks.Images.ExtractImages {
+		t.Fatalf("unexpected chunks config %+v", chunks)
+	}
+	if config.Pages.ExtractPages != nil || *config.Chunking.Enabled || !*config.Images.ExtractImages {
+		t.Fatalf("expected the config to be left alone, got %+v", config)
+	}
+	if documentSectionConfig(nil, documentSectionChunks).Chunking == nil {
+		t.Fatalf("expected default chunking")
+	}
+}
@@ -0,0 +1,320 @@
+package kreuzberg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DualRunConfig runs a shadow extraction next to the primary one, e.g. to validate a config
+// change on a sample of production traffic before rolling it out. Callers always receive the
+// primary result; the shadow result and a diff against the primary go to Report, and shadow
+// failures never affect the primary extraction.
+//
+// Only single-document extractions are shadowed; batches ignore DualRun. To compare library
+// versions rather than configs, load what the previous release cached (see ResultCache) and
+// compare it with DiffResults.
+type DualRunConfig struct {
+	// Shadow is the candidate config. Its own DualRun setting is ignored.
+	Shadow *ExtractionConfig
+	// SampleRate is the fraction of extractions that are shadowed, in (0, 1] (0 = all).
+	SampleRate float64
+	// Async runs the shadow extraction in the background, so it adds no latency. Report is then
+	// called from another goroutine, after the primary result has been returned.
+	Async bool
+	// Report receives every comparison.
+	Report func(*DualRunReport)
+}
+
+// DualRunReport holds both results of a dual run and their differences.
+type DualRunReport struct {
+	// Path is the extracted file, or empty for in-memory documents.
+	Path     string
+	MimeType string
+	// Primary and Shadow are nil when the respective extraction failed.
+	Primary         *ExtractionResult
+	Shadow          *ExtractionResult
+	PrimaryError    error
+	ShadowError     error
+	PrimaryDuration time.Duration
+	ShadowDuration  time.Duration
+	Diff            ResultDiff
+}
+
+// ResultDiff summarizes how a result differs from a baseline. Deltas are the other result's
+// count minus the baseline's.
+type ResultDiff struct {
+	// Identical is true when both results encode to the same JSON.
+	Identical    bool `json:"identical"`
+	ContentEqual bool `json:"content_equal"`
+	// ContentSimilarity is the Dice coefficient of the two word multisets, from 0 (no shared
+	// words) to 1 (same words, in any order).
+	ContentSimilarity float64 `json:"content_similarity"`
+	// LinesAdded and LinesRemoved count lines present in only one result, ignoring order.
+	LinesAdded      int  `json:"lines_added"`
+	LinesRemoved    int  `json:"lines_removed"`
+	CharacterDelta  int  `json:"character_delta"`
+	TableDelta      int  `json:"table_delta"`
+	ChunkDelta      int  `json:"chunk_delta"`
+	PageDelta       int  `json:"page_delta"`
+	ImageDelta      int  `json:"image_delta"`
+	MimeTypeChanged bool `json:"mime_type_changed"`
+	SuccessChanged  bool `json:"success_changed"`
+	// LanguagesChanged reports a different DetectedLanguages list.
+	LanguagesChanged bool `json:"languages_changed"`
+	// MetadataAdded, MetadataRemoved and MetadataChanged list top-level metadata keys in their
+	// JSON form, sorted.
+	MetadataAdded   []string `json:"metadata_added,omitempty"`
+	MetadataRemoved []string `json:"metadata_removed,omitempty"`
+	MetadataChanged []string `json:"metadata_changed,omitempty"`
+}
+
+// String renders the diff as a one-line summary for logs.
+func (d ResultDiff) String() string {
+	if d.Identical {
+		return "identical"
+	}
+	parts := []string{fmt.Sprintf("similarity=%.3f lines=+%d/-%d chars=%+d", d.ContentSimilarity, d.LinesAdded, d.LinesRemoved, d.CharacterDelta)}
+	for _, delta := range []struct {
+		name string
+		n    int
+	}{{"tables", d.TableDelta}, {"chunks", d.ChunkDelta}, {"pages", d.PageDelta}, {"images", d.ImageDelta}} {
+		if delta.n != 0 {
+			parts = append(parts, fmt.Sprintf("%s=%+d", delta.name, delta.n))
+		}
+	}
+	for _, flag := range []struct {
+		name string
+		set  bool
+	}{{"mime_type", d.MimeTypeChanged}, {"success", d.SuccessChanged}, {"languages", d.LanguagesChanged}} {
+		if flag.set {
+			parts = append(parts, flag.name+" changed")
+		}
+	}
+	if n := len(d.MetadataAdded) + len(d.MetadataRemoved) + len(d.MetadataChanged); n > 0 {
+		parts = append(parts, fmt.Sprintf("metadata keys=%d", n))
+	}
+	return strings.Join(parts, " ")
+}
+
+// DiffResults compares result with baseline. Either may be nil, which compares as an empty
+// result.
+func DiffResults(baseline, result *ExtractionResult) ResultDiff {
+	if baseline == nil {
+		baseline = &ExtractionResult{}
+	}
+	if result == nil {
+		result = &ExtractionResult{}
+	}
+	d := ResultDiff{
+		ContentEqual:     baseline.Content == result.Content,
+		CharacterDelta:   len([]rune(result.Content)) - len([]rune(baseline.Content)),
+		TableDelta:       len(result.Tables) - len(baseline.Tables),
+		ChunkDelta:       len(result.Chunks) - len(baseline.Chunks),
+		PageDelta:        len(result.Pages) - len(baseline.Pages),
+		ImageDelta:       len(result.Images) - len(baseline.Images),
+		MimeTypeChanged:  baseline.MimeType != result.MimeType,
+		SuccessChanged:   baseline.Success != result.Success,
+		LanguagesChanged: !slices.Equal(baseline.DetectedLanguages, result.DetectedLanguages),
+	}
+	d.ContentSimilarity = diceSimilarity(strings.Fields(baseline.Content), strings.Fields(result.Content))
+	d.LinesRemoved, d.LinesAdded = multisetDifference(strings.Split(baseline.Content, "\n"), strings.Split(result.Content, "\n"))
+	d.MetadataAdded, d.MetadataRemoved, d.MetadataChanged = diffMetadata(baseline.Metadata, result.Metadata)
+
+	a, errA := json.Marshal(baseline)
+	b, errB := json.Marshal(result)
+	d.Identical = errA == nil && errB == nil && bytes.Equal(a, b)
+	return d
+}
+
+// diceSimilarity returns 2|A∩B| / (|A|+|B|) for the multisets of a and b.
+func diceSimilarity(a, b []string) float64 {
+	if len(a)+len(b) == 0 {
+		return 1
+	}
+	onlyA, _ := multisetDifference(a, b)
+	common := len(a) - onlyA
+	return 2 * float64(common) / float64(len(a)+len(b))
+}
+
+// multisetDifference counts the elements of a missing from b and of b missing from a.
+func multisetDifference(a, b []string) (onlyA, onlyB int) {
+	counts := make(map[string]int, len(a))
+	for _, s := range a {
+		counts[s]++
+	}
+	for _, s := range b {
+		if counts[s] > 0 {
+			counts[s]--
+		} else {
+			onlyB++
+		}
+	}
+	for _, n := range counts {
+		onlyA += n
+	}
+	return onlyA, onlyB
+}
+
+func diffMetadata(baseline, metadata Metadata) (added, removed, changed []string) {
+	a, b := metadataFields(baseline), metadataFields(metadata)
+	for key, value := range b {
+		if old, ok := a[key]; !ok {
+			added = append(added, key)
+		} else if !bytes.Equal(old, value) {
+			changed = append(changed, key)
+		}
+	}
+	for key := range a {
+		if _, ok := b[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+	slices.Sort(added)
+	slices.Sort(removed)
+	slices.Sort(changed)
+	return added, removed, changed
+}
+
+func metadataFields(m Metadata) map[string]json.RawMessage {
+	var fields map[string]json.RawMessage
+	if raw, err := json.Marshal(m); err == nil {
+		json.Unmarshal(raw, &fields)
+	}
+	return fields
+}
+
+// DualRunFile extracts path with config and with config.DualRun.Shadow and returns both results.
+// Unlike a DualRun set for regular extractions, it ignores SampleRate, Async and Report.
+func DualRunFile(ctx context.Context, path string, config *ExtractionConfig) (*DualRunReport, error) {
+	return dualRunFile(ctx, defaultPluginRegistry, path, config)
+}
+
+// DualRunBytes is DualRunFile for an in-memory document.
+func DualRunBytes(ctx context.Context, data []byte, mimeType string, config *ExtractionConfig) (*DualRunReport, error) {
+	return dualRunBytes(ctx, defaultPluginRegistry, data, mimeType, config)
+}
+
+// DualRunFile is the package-level DualRunFile with the client's config and plugins.
+func (c *Client) DualRunFile(ctx context.Context, path string) (*DualRunReport, error) {
+	return dualRunFile(ctx, c.plugins, path, c.config)
+}
+
+// DualRunBytes is the package-level DualRunBytes with the client's config and plugins.
+func (c *Client) DualRunBytes(ctx context.Context, data []byte, mimeType string) (*DualRunReport, error) {
+	return dualRunBytes(ctx, c.plugins, data, mimeType, c.config)
+}
+
+func dualRunFile(ctx context.Context, plugins *pluginRegistry, path string, config *ExtractionConfig) (*DualRunReport, error) {
+	if err := validateDualRun(config); err != nil {
+		return nil, err
+	}
+	report := &DualRunReport{Path: path}
+	runDual(ctx, report, config, false, func(ctx context.Context, cfg *ExtractionConfig) (*ExtractionResult, error) {
+		return extractFile(ctx, plugins, path, cfg)
+	})
+	return report, nil
+}
+
+func dualRunBytes(ctx context.Context, plugins *pluginRegistry, data []byte, mimeType string, config *ExtractionConfig) (*DualRunReport, error) {
+	if err := validateDualRun(config); err != nil {
+		return nil, err
+	}
+	report := &DualRunReport{MimeType: mimeType}
+	runDual(ctx, report, config, false, func(ctx context.Context, cfg *ExtractionConfig) (*ExtractionResult, error) {
+		return extractBytes(ctx, plugins, data, mimeType, cfg)
+	})
+	return report, nil
+}
+
+func validateDualRun(config *ExtractionConfig) error {
+	if config == nil || config.DualRun == nil || config.DualRun.Shadow == nil {
+		return newValidationErrorWithContext("DualRun.Shadow must be set", nil, ErrorCodeValidation, nil)
+	}
+	if rate := config.DualRun.SampleRate; rate < 0 || rate > 1 {
+		return newValidationErrorWithContext(fmt.Sprintf("DualRun.SampleRate must be in [0, 1], got %v", rate), nil, ErrorCodeValidation, nil)
+	}
+	return nil
+}
+
+// dualRunSampled reports whether this extraction should be shadowed.
+func dualRunSampled(config *ExtractionConfig) bool {
+	if config == nil || config.DualRun == nil {
+		return false
+	}
+	rate := config.DualRun.SampleRate
+	return rate == 0 || rate >= 1 || rand.Float64() < rate
+}
+
+// extractDual runs the primary extraction of a sampled document, shadows it and returns the
+// primary result.
+func extractDual(ctx context.Context, config *ExtractionConfig, path, mimeType string, extract func(context.Context, *ExtractionConfig) (*ExtractionResult, error)) (*ExtractionResult, error) {
+	if err := validateDualRun(config); err != nil {
+		return nil, err
+	}
+	report := &DualRunReport{Path: path, MimeType: mimeType}
+	result, err := runDual(ctx, report, config, config.DualRun.Async, extract)
+	if !config.DualRun.Async && config.DualRun.Report != nil {
+		config.DualRun.Report(report)
+	}
+	return result, err
+}
+
+// runDual runs the primary extraction, fills report and returns the primary outcome. In async
+// mode the shadow extraction runs in the background on a snapshot of the primary result, so
+// callers may modify theirs, and Report is called once it finishes.
+func runDual(ctx context.Context, report *DualRunReport, config *ExtractionConfig, async bool, extract func(context.Context, *ExtractionConfig) (*ExtractionResult, error)) (*ExtractionResult, error) {
+	primaryConfig := *config
+	primaryConfig.DualRun = nil
+	shadowConfig := *config.DualRun.Shadow
+	shadowConfig.DualRun = nil
+
+	start := time.Now()
+	result, err := extract(ctx, &primaryConfig)
+	report.PrimaryDuration = time.Since(start)
+	report.Primary, report.PrimaryError = result, err
+	if result != nil && report.MimeType == "" {
+		report.MimeType = result.MimeType
+	}
+
+	shadow := func(ctx context.Context) {
+		start := time.Now()
+		report.Shadow, report.ShadowError = extract(ctx, &shadowConfig)
+		report.ShadowDuration = time.Since(start)
+		report.Diff = DiffResults(report.Primary, report.Shadow)
+	}
+	if !async {
+		shadow(ctx)
+		return result, err
+	}
+	report.Primary = cloneResult(result)
+	go func() {
+		shadow(context.WithoutCancel(ctx))
+		if fn := config.DualRun.Report; fn != nil {
+			fn(report)
+		}
+	}()
+	return result, err
+}
+
+// cloneResult returns a deep copy of result through its JSON encoding, or result itself when it
+// cannot be encoded.
+func cloneResult(result *ExtractionResult) *ExtractionResult {
+	if result == nil {
+		return nil
+	}
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return result
+	}
+	var clone ExtractionResult
+	if json.Unmarshal(raw, &clone) != nil {
+		return result
+	}
+	return &clone
+}
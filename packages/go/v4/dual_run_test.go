@@ -0,0 +1,86 @@
+package kreuzberg
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDiffResults(t *testing.T) {
+	baseline := &ExtractionResult{Content: "a b c\nd e", MimeType: "text/plain", Success: true, Tables: []Table{{}}}
+	result := &ExtractionResult{Content: "a b c\nf", MimeType: "text/plain", Success: true, Metadata: Metadata{Additional: map[string]json.RawMessage{"note": json.RawMessage(`"x"`)}}}
+	d := DiffResults(baseline, result)
+	if d.Identical || d.ContentEqual || d.LinesAdded != 1 || d.LinesRemoved != 1 || d.TableDelta != -1 || d.CharacterDelta != -2 {
+		t.Fatalf("unexpected diff: %+v", d)
+	}
+	if d.ContentSimilarity != 6.0/9 {
+		t.Fatalf("unexpected similarity %v", d.ContentSimilarity)
+	}
+	if len(d.MetadataAdded) != 1 || d.MetadataAdded[0] != "note" || len(d.MetadataRemoved) != 0 {
+		t.Fatalf("unexpected metadata diff: %+v", d)
+	}
+	if same := DiffResults(baseline, baseline); !same.Identical || same.ContentSimilarity != 1 || same.String() != "identical" {
+		t.Fatalf("expected identical results, got %+v", same)
+	}
+	if empty := DiffResults(nil, nil); !empty.Identical {
+		t.Fatalf("expected nil results to compare equal, got %+v", empty)
+	}
+}
+
+func TestDualRunBytes(t *testing.T) {
+	config := &ExtractionConfig{DualRun: &DualRunConfig{Shadow: &ExtractionConfig{ContentLimit: &ContentLimitConfig{MaxContentChars: 12}}}}
+	report, err := DualRunBytes(t.Context(), []byte(testSRT), mimeSRT, config)
+	if err != nil {
+		t.Fatalf("dual run: %v", err)
+	}
+	if report.PrimaryError != nil || report.ShadowError != nil || report.Primary.Content != wantSRTContent || report.Shadow.Content != "Hello there." {
+		t.Fatalf("unexpected results: %+v", report)
+	}
+	if report.Diff.ContentEqual || report.Diff.LinesRemoved != 3 || report.Diff.ContentSimilarity >= 1 || report.MimeType != mimeSRT {
+		t.Fatalf("unexpected diff: %+v", report.Diff)
+	}
+	if _, err := DualRunBytes(t.Context(), []byte(testSRT), mimeSRT, nil); err == nil {
+		t.Fatalf("expected an error without DualRun.Shadow")
+	}
+}
+
+func TestDualRunShadowsExtractions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.srt")
+	if err := os.WriteFile(path, []byte(testSRT), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	reports := make(chan *DualRunReport, 1)
+	config := &ExtractionConfig{DualRun: &DualRunConfig{
+		Shadow: &ExtractionConfig{ContentLimit: &ContentLimitConfig{MaxContentChars: 5}},
+		Async:  true,
+		Report: func(r *DualRunReport) { reports <- r },
+	}}
+	result, err := NewClient(config).ExtractFile(t.Context(), path)
+	if err != nil || result.Content != wantSRTContent {
+		t.Fatalf("expected the primary result, got %v, %v", result, err)
+	}
+	result.Content = "modified by the caller"
+
+	select {
+	case report := <-reports:
+		if report.Path != path || report.Primary.Content != wantSRTContent || report.Shadow.Content != "Hello" || report.Diff.CharacterDelta >= 0 {
+			t.Fatalf("unexpected report: %+v", report)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("shadow extraction was not reported")
+	}
+
+	// A failing shadow never affects the primary extraction.
+	config.DualRun = &DualRunConfig{
+		Shadow: &ExtractionConfig{ContentLimit: &ContentLimitConfig{MaxContentChars: 5, Policy: "random"}},
+		Report: func(r *DualRunReport) { reports <- r },
+	}
+	if _, err := ExtractFileSync(path, config); err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if report := <-reports; report.ShadowError == nil || report.Shadow != nil || report.Primary == nil {
+		t.Fatalf("expected only the shadow to fail, got %+v", report)
+	}
+}
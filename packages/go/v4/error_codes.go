@@ -0,0 +1,143 @@
+package kreuzberg
+
+/*
+#include <stdlib.h>
+
+const char *kreuzberg_stable_error_code(const char *kind);
+*/
+import "C"
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode"
+	"unsafe"
+)
+
+// StableErrorCode is a machine-readable error code of the form "KZB-NNNN", for alerting and
+// aggregating on errors across bindings instead of parsing messages. The codes are defined by
+// the native library, which every binding reads them from. Codes never change meaning between
+// releases; new codes may be added. The thousands digit is the native ErrorCode plus one, so
+// every code within a family shares the native classification:
+//
+//	KZB-0000  unknown (not a Kreuzberg error)
+//	KZB-1000  validation          KZB-1100  serialization
+//	KZB-2000  parsing             KZB-2100  image processing
+//	KZB-3000  OCR
+//	KZB-4000  missing dependency
+//	KZB-5000  I/O                 KZB-5100  cache
+//	KZB-6000  plugin
+//	KZB-7000  unsupported format
+//	KZB-8000  internal            KZB-8001  panic in native code
+//	KZB-8002  lock poisoned       KZB-8100  cancelled
+//	KZB-8101  deadline exceeded
+type StableErrorCode string
+
+// The stable codes of the error kinds, read from the native library.
+var (
+	StableCodeUnknown           = stableErrorCode("unknown")
+	StableCodeValidation        = stableErrorCode("validation")
+	StableCodeSerialization     = stableErrorCode("serialization")
+	StableCodeParsing           = stableErrorCode("parsing")
+	StableCodeImageProcessing   = stableErrorCode("image_processing")
+	StableCodeOCR               = stableErrorCode("ocr")
+	StableCodeMissingDependency = stableErrorCode("missing_dependency")
+	StableCodeIO                = stableErrorCode("io")
+	StableCodeCache             = stableErrorCode("cache")
+	StableCodePlugin            = stableErrorCode("plugin")
+	StableCodeUnsupportedFormat = stableErrorCode("unsupported_format")
+	StableCodeInternal          = stableErrorCode("internal")
+	StableCodePanic             = stableErrorCode("panic")
+	StableCodeLockPoisoned      = stableErrorCode("lock_poisoned")
+	StableCodeCancelled         = stableErrorCode("cancelled")
+	StableCodeDeadlineExceeded  = stableErrorCode("deadline_exceeded")
+)
+
+// stableErrorCode returns the code the native library assigns to an error kind.
+func stableErrorCode(kind string) StableErrorCode {
+	cKind := C.CString(kind)
+	defer C.free(unsafe.Pointer(cKind))
+	return StableErrorCode(C.GoString(C.kreuzberg_stable_error_code(cKind)))
+}
+
+// stableCodesByKind maps each error kind to its code.
+var stableCodesByKind = map[ErrorKind]StableErrorCode{
+	ErrorKindValidation:        StableCodeValidation,
+	ErrorKindSerialization:     StableCodeSerialization,
+	ErrorKindParsing:           StableCodeParsing,
+	ErrorKindImageProcessing:   StableCodeImageProcessing,
+	ErrorKindOCR:               StableCodeOCR,
+	ErrorKindMissingDependency: StableCodeMissingDependency,
+	ErrorKindIO:                StableCodeIO,
+	ErrorKindCache:             StableCodeCache,
+	ErrorKindPlugin:            StableCodePlugin,
+	ErrorKindUnsupportedFormat: StableCodeUnsupportedFormat,
+	ErrorKindRuntime:           StableCodeInternal,
+}
+
+// StableCode returns the stable code of the error's kind; runtime errors caused by a panic in
+// native code report StableCodePanic.
+func (e *baseError) StableCode() StableErrorCode {
+	if e.kind == ErrorKindRuntime && e.panicCtx != nil {
+		return StableCodePanic
+	}
+	if code, ok := stableCodesByKind[e.kind]; ok {
+		return code
+	}
+	return StableCodeUnknown
+}
+
+// StableCode returns the stable code of the family of a native error code.
+func (ec ErrorCode) StableCode() StableErrorCode {
+	return stableErrorCode(ErrorCodeName(uint32(ec)))
+}
+
+// StableErrorCodeOf returns the stable code of err: the code of the first KreuzbergError in its
+// chain, StableCodeCancelled or StableCodeDeadlineExceeded for context errors, and
+// StableCodeUnknown for any other error. It returns "" for a nil error.
+func StableErrorCodeOf(err error) StableErrorCode {
+	if err == nil {
+		return ""
+	}
+	var kerr KreuzbergError
+	switch {
+	case errors.As(err, &kerr):
+		return kerr.StableCode()
+	case errors.Is(err, context.Canceled):
+		return StableCodeCancelled
+	case errors.Is(err, context.DeadlineExceeded):
+		return StableCodeDeadlineExceeded
+	default:
+		return StableCodeUnknown
+	}
+}
+
+// StableCode returns the stable code of a failed batch item from its ErrorType, which is either
+// the Go error type (e.g. "PluginError") or the native error variant (e.g. "Parsing { .. }").
+func (m *ErrorMetadata) StableCode() StableErrorCode {
+	if m == nil {
+		return ""
+	}
+	name := strings.TrimLeftFunc(m.ErrorType, unicode.IsSpace)
+	if i := strings.IndexFunc(name, func(r rune) bool { return !unicode.IsLetter(r) }); i >= 0 {
+		name = name[:i]
+	}
+	name = strings.ToLower(strings.TrimSuffix(name, "Error"))
+	switch name {
+	case "lockpoisoned":
+		return StableCodeLockPoisoned
+	case "imageprocessing":
+		return StableCodeImageProcessing
+	case "missingdependency":
+		return StableCodeMissingDependency
+	case "unsupportedformat":
+		return StableCodeUnsupportedFormat
+	case "", "other", "runtime":
+		return StableCodeInternal
+	}
+	if code, ok := stableCodesByKind[ErrorKind(name)]; ok {
+		return code
+	}
+	return StableCodeUnknown
+}
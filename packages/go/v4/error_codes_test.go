@@ -0,0 +1,56 @@
+package kreuzberg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestStableErrorCodes(t *testing.T) {
+	cases := []struct {
+		err  error
+		want StableErrorCode
+	}{
+		{newValidationErrorWithContext("bad", nil, ErrorCodeValidation, nil), "KZB-1000"},
+		{newSerializationErrorWithContext("bad json", nil, ErrorCodeValidation, nil), "KZB-1100"},
+		{newCacheErrorWithContext("disk full", nil, ErrorCodeIo, nil), "KZB-5100"},
+		{classifyNativeError("Missing dependency: tesseract", ErrorCodeMissingDependency, nil), "KZB-4000"},
+		{classifyNativeError("boom", ErrorCodeInternal, &PanicContext{File: "lib.rs"}), "KZB-8001"},
+		{fmt.Errorf("wrapped: %w", newPluginErrorWithContext("p", "failed", nil, ErrorCodePlugin, nil)), "KZB-6000"},
+		{context.Canceled, "KZB-8100"},
+		{fmt.Errorf("wait: %w", context.DeadlineExceeded), "KZB-8101"},
+		{errors.New("other"), "KZB-0000"},
+		{nil, ""},
+	}
+	for _, c := range cases {
+		if got := StableErrorCodeOf(c.err); got != c.want {
+			t.Errorf("StableErrorCodeOf(%v) = %q, want %q", c.err, got, c.want)
+		}
+	}
+
+	// Every native code maps into its own family.
+	for code := ErrorCodeValidation; code <= ErrorCodeInternal; code++ {
+		if got, want := code.StableCode(), StableErrorCode(fmt.Sprintf("KZB-%d000", code+1)); got != want {
+			t.Errorf("ErrorCode(%d).StableCode() = %q, want %q", code, got, want)
+		}
+	}
+}
+
+func TestErrorMetadataStableCode(t *testing.T) {
+	for errorType, want := range map[string]StableErrorCode{
+		"PluginError":                       StableCodePlugin,
+		"OCRError":                          StableCodeOCR,
+		"Parsing { message: \"x\" }":        StableCodeParsing,
+		"MissingDependency(\"tesseract\")":  StableCodeMissingDependency,
+		"ImageProcessing { message: \"\" }": StableCodeImageProcessing,
+		"LockPoisoned(\"cache\")":           StableCodeLockPoisoned,
+		"Io(Os { code: 2 })":                StableCodeIO,
+		"Other(\"x\")":                      StableCodeInternal,
+		"Mystery":                           StableCodeUnknown,
+	} {
+		if got := (&ErrorMetadata{ErrorType: errorType}).StableCode(); got != want {
+			t.Errorf("StableCode(%q) = %q, want %q", errorType, got, want)
+		}
+	}
+}
@@ -88,6 +88,8 @@ type KreuzbergError interface {
 	error
 	Kind() ErrorKind
 	Code() ErrorCode
+	// StableCode returns the machine-readable code shared with the other bindings.
+	StableCode() StableErrorCode
 	PanicCtx() *PanicContext
 }
 
@@ -97,6 +99,7 @@ type baseError struct {
 	cause      error
 	panicCtx   *PanicContext
 	nativeCode ErrorCode
+	triage     *TriageReport
 }
 
 func (e *baseError) Error() string {
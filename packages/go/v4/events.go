@@ -0,0 +1,267 @@
+package kreuzberg
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+)
+
+// EventType identifies a document lifecycle event.
+type EventType string
+
+const (
+	// EventExtractionStarted is emitted when an extraction call accepts a document.
+	EventExtractionStarted EventType = "extraction.started"
+	// EventExtractionCompleted is emitted with the result of a successful extraction.
+	EventExtractionCompleted EventType = "extraction.completed"
+	// EventExtractionFailed is emitted with the error of a failed extraction, including a failed
+	// item of a batch.
+	EventExtractionFailed EventType = "extraction.failed"
+	// EventCacheHit is emitted, before EventExtractionCompleted, when the result was served from
+	// the result cache.
+	EventCacheHit EventType = "cache.hit"
+	// EventPluginModified is emitted when a Go post-processor changed the result.
+	EventPluginModified EventType = "plugin.modified"
+)
+
+// Event is a document lifecycle event.
+type Event struct {
+	Type EventType `json:"type"`
+	Time time.Time `json:"time"`
+	// ExtractionID correlates the events of one extraction call; the items of a batch share it
+	// and are told apart by Index.
+	ExtractionID string `json:"extraction_id"`
+	// Batch is set for the events of batch calls; Index is then the position of the document in
+	// the batch.
+	Batch bool `json:"batch,omitempty"`
+	Index int  `json:"index"`
+	// DocumentPath is the path of the document (empty for in-memory documents).
+	DocumentPath string `json:"document_path,omitempty"`
+	// MimeType is the MIME type passed in or, once extracted, of the result.
+	MimeType string            `json:"mime_type,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	// Duration is the time since EventExtractionStarted, for completed and failed extractions.
+	Duration time.Duration `json:"duration,omitempty"`
+	// Plugin is the name of the post-processor of an EventPluginModified.
+	Plugin string `json:"plugin,omitempty"`
+	// Error and ErrorType describe the failure of an EventExtractionFailed.
+	Error     string `json:"error,omitempty"`
+	ErrorType string `json:"error_type,omitempty"`
+	// Result is the result of completed extractions and plugin modifications. Handlers must not
+	// modify it.
+	Result *ExtractionResult `json:"-"`
+}
+
+// EventHandler receives lifecycle events. Handlers are called synchronously from the extracting
+// goroutine, from concurrent extractions, so they should hand slow work (indexing, network
+// calls) off to a queue. A panicking handler does not fail the extraction.
+type EventHandler interface {
+	HandleEvent(ctx context.Context, event Event)
+}
+
+// EventHandlerFunc adapts a function to an EventHandler.
+type EventHandlerFunc func(ctx context.Context, event Event)
+
+// HandleEvent calls f.
+func (f EventHandlerFunc) HandleEvent(ctx context.Context, event Event) {
+	f(ctx, event)
+}
+
+// SubscribeEvents subscribes handler to the lifecycle events of package-level extractions, or to
+// the given types only, and returns a function that cancels the subscription. Handlers
+// subscribed to a Client receive its events instead.
+func SubscribeEvents(handler EventHandler, types ...EventType) func() {
+	return defaultPluginRegistry.events.subscribe(handler, types)
+}
+
+// SubscribeEvents subscribes handler to the lifecycle events of the client's extractions; see
+// the package-level SubscribeEvents.
+func (c *Client) SubscribeEvents(handler EventHandler, types ...EventType) func() {
+	return c.plugins.events.subscribe(handler, types)
+}
+
+type eventSubscriber struct {
+	handler EventHandler
+	// types filters the events; empty receives every event.
+	types []EventType
+}
+
+// eventBus delivers the events of a plugin scope to its subscribers.
+type eventBus struct {
+	mu          sync.RWMutex
+	subscribers []*eventSubscriber
+}
+
+func (b *eventBus) subscribe(handler EventHandler, types []EventType) func() {
+	if handler == nil {
+		return func() {}
+	}
+	sub := &eventSubscriber{handler: handler, types: slices.Clone(types)}
+	b.mu.Lock()
+	b.subscribers = append(b.subscribers, sub)
+	b.mu.Unlock()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			b.subscribers = slices.DeleteFunc(b.subscribers, func(s *eventSubscriber) bool { return s == sub })
+		})
+	}
+}
+
+// wants reports whether a subscriber receives events of type t.
+func (b *eventBus) wants(t EventType) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return slices.ContainsFunc(b.subscribers, func(s *eventSubscriber) bool {
+		return len(s.types) == 0 || slices.Contains(s.types, t)
+	})
+}
+
+func (b *eventBus) emit(ctx context.Context, event Event) {
+	b.mu.RLock()
+	subscribers := slices.Clone(b.subscribers)
+	b.mu.RUnlock()
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	for _, s := range subscribers {
+		if len(s.types) == 0 || slices.Contains(s.types, event.Type) {
+			deliverEvent(ctx, s.handler, event)
+		}
+	}
+}
+
+func deliverEvent(ctx context.Context, handler EventHandler, event Event) {
+	defer func() { _ = recover() }()
+	handler.HandleEvent(ctx, event)
+}
+
+// eventScopeKey is the context key of the eventScope of an extraction.
+type eventScopeKey struct{}
+
+// eventScope identifies the extraction, and the batch item, events are emitted for.
+type eventScope struct {
+	id    string
+	batch bool
+	index int
+	// subset maps the indices of a batch's subset, e.g. a routed group, to the batch's.
+	subset []int
+}
+
+func (s eventScope) event(t EventType, path, mimeType string, labels map[string]string) Event {
+	return Event{Type: t, ExtractionID: s.id, Batch: s.batch, Index: s.index, DocumentPath: path, MimeType: mimeType, Labels: labels}
+}
+
+// withEventIndex scopes the events emitted under ctx to item index of a batch.
+func withEventIndex(ctx context.Context, index int) context.Context {
+	scope, ok := ctx.Value(eventScopeKey{}).(eventScope)
+	if !ok {
+		return ctx
+	}
+	scope.index = index
+	if scope.subset != nil {
+		scope.index = scope.subset[index]
+	}
+	return context.WithValue(ctx, eventScopeKey{}, scope)
+}
+
+// withEventSubset scopes the events emitted under ctx to the batch items at indices.
+func withEventSubset(ctx context.Context, indices []int) context.Context {
+	scope, ok := ctx.Value(eventScopeKey{}).(eventScope)
+	if !ok {
+		return ctx
+	}
+	subset := slices.Clone(indices)
+	if scope.subset != nil {
+		for j, i := range indices {
+			subset[j] = scope.subset[i]
+		}
+	}
+	scope.subset = subset
+	return context.WithValue(ctx, eventScopeKey{}, scope)
+}
+
+// eventDocument is a document of an extraction call, as reported in its events.
+type eventDocument struct {
+	path     string
+	mimeType string
+}
+
+// startExtraction emits EventExtractionStarted for the documents of an extraction call and
+// returns the context to extract under and a function emitting the outcome of the call. It does
+// nothing without subscribers.
+func (b *eventBus) startExtraction(ctx context.Context, documents []eventDocument, batch bool, config *ExtractionConfig) (context.Context, func(results []*ExtractionResult, err error)) {
+	b.mu.RLock()
+	idle := len(b.subscribers) == 0
+	b.mu.RUnlock()
+	if idle {
+		return ctx, func([]*ExtractionResult, error) {}
+	}
+	var labels map[string]string
+	if config != nil {
+		labels = config.Labels
+	}
+	scope := eventScope{id: rand.Text(), batch: batch}
+	ctx = context.WithValue(ctx, eventScopeKey{}, scope)
+	start := time.Now()
+	for i, doc := range documents {
+		scope.index = i
+		b.emit(ctx, scope.event(EventExtractionStarted, doc.path, doc.mimeType, labels))
+	}
+	return ctx, func(results []*ExtractionResult, err error) {
+		duration := time.Since(start)
+		for i, doc := range documents {
+			scope.index = i
+			var result *ExtractionResult
+			if i < len(results) {
+				result = results[i]
+			}
+			event := scope.event(EventExtractionFailed, doc.path, doc.mimeType, labels)
+			event.Duration = duration
+			switch {
+			case err != nil:
+				event.Error, event.ErrorType = err.Error(), errorTypeName(err)
+			case result == nil:
+				event.Error = "batch item produced no result"
+			case result.Metadata.Error != nil:
+				event.MimeType = cmp.Or(result.MimeType, doc.mimeType)
+				event.Error, event.ErrorType = result.Metadata.Error.Message, result.Metadata.Error.ErrorType
+			default:
+				if result.cacheHit {
+					hit := scope.event(EventCacheHit, doc.path, result.MimeType, labels)
+					b.emit(ctx, hit)
+				}
+				event.Type, event.MimeType, event.Result = EventExtractionCompleted, result.MimeType, result
+			}
+			b.emit(ctx, event)
+		}
+	}
+}
+
+// resultFingerprint digests result, to tell whether a post-processor changed it.
+func resultFingerprint(result *ExtractionResult) [sha256.Size]byte {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return [sha256.Size]byte{}
+	}
+	return sha256.Sum256(data)
+}
+
+// emitPluginModified emits EventPluginModified for the post-processor named plugin when it
+// changed result from the before fingerprint.
+func (b *eventBus) emitPluginModified(pc *PluginContext, plugin string, before [sha256.Size]byte, result *ExtractionResult) {
+	if resultFingerprint(result) == before {
+		return
+	}
+	scope, _ := pc.Context().Value(eventScopeKey{}).(eventScope)
+	event := scope.event(EventPluginModified, pc.DocumentPath, result.MimeType, pc.Labels)
+	event.Plugin, event.Result = plugin, result
+	b.emit(pc.Context(), event)
+}
@@ -0,0 +1,130 @@
+package kreuzberg
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *eventRecorder) HandleEvent(_ context.Context, event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+// take returns the recorded events and forgets them.
+func (r *eventRecorder) take() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := r.events
+	r.events = nil
+	return events
+}
+
+func eventTypes(events []Event) []EventType {
+	types := make([]EventType, len(events))
+	for i, e := range events {
+		types[i] = e.Type
+	}
+	return types
+}
+
+func TestClientEvents(t *testing.T) {
+	config := &ExtractionConfig{
+		Labels:          map[string]string{"tenant": "acme"},
+		CacheEncryption: &CacheEncryptionConfig{Key: bytes.Repeat([]byte{1}, 32), Dir: t.TempDir()},
+	}
+	client := NewClient(config)
+	client.RegisterPostProcessor("upper", 10, func(_ *PluginContext, result *ExtractionResult) error {
+		result.Content = strings.ToUpper(result.Content)
+		return nil
+	})
+	client.RegisterPostProcessor("noop", 5, func(*PluginContext, *ExtractionResult) error { return nil })
+	recorder := &eventRecorder{}
+	unsubscribe := client.SubscribeEvents(recorder)
+	client.SubscribeEvents(EventHandlerFunc(func(context.Context, Event) { panic("broken subscriber") }))
+
+	result, err := client.ExtractBytes(t.Context(), []byte(testSRT), mimeSRT)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	events := recorder.take()
+	if want := []EventType{EventExtractionStarted, EventPluginModified, EventExtractionCompleted}; !slices.Equal(eventTypes(events), want) {
+		t.Fatalf("unexpected events %v", eventTypes(events))
+	}
+	if id := events[0].ExtractionID; id == "" || events[1].ExtractionID != id || events[2].ExtractionID != id {
+		t.Fatalf("expected the events to share an extraction ID, got %+v", events)
+	}
+	if e := events[1]; e.Plugin != "upper" || e.Result != result {
+		t.Fatalf("unexpected plugin event %+v", e)
+	}
+	if e := events[2]; e.Result != result || e.MimeType != mimeSRT || e.Labels["tenant"] != "acme" || e.Batch {
+		t.Fatalf("unexpected completion event %+v", e)
+	}
+
+	// The second extraction is served from the cache.
+	if _, err := client.ExtractBytes(t.Context(), []byte(testSRT), mimeSRT); err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if want := []EventType{EventExtractionStarted, EventPluginModified, EventCacheHit, EventExtractionCompleted}; !slices.Equal(eventTypes(recorder.take()), want) {
+		t.Fatalf("expected a cache hit")
+	}
+
+	client.DisableFormats(FeatureStructured)
+	items := []BytesWithMime{{Data: []byte(testSRT), MimeType: mimeSRT}, {Data: []byte(`{"a": 1}`), MimeType: mimeJSON}}
+	if _, err := client.BatchExtractBytes(t.Context(), items); err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+	var outcomes []string
+	for _, e := range recorder.take() {
+		if !e.Batch {
+			t.Fatalf("expected batch events, got %+v", e)
+		}
+		if e.Type == EventExtractionCompleted || e.Type == EventExtractionFailed {
+			outcomes = append(outcomes, string(e.Type)+":"+e.MimeType+":"+e.ErrorType)
+		}
+		if e.Type == EventPluginModified && e.Index != 0 {
+			t.Fatalf("unexpected plugin event index %d", e.Index)
+		}
+	}
+	if want := []string{"extraction.completed:" + mimeSRT + ":", "extraction.failed:" + mimeJSON + ":UnsupportedFormatError"}; !slices.Equal(outcomes, want) {
+		t.Fatalf("unexpected outcomes %v", outcomes)
+	}
+
+	if _, err := client.ExtractBytes(t.Context(), []byte(`{"a": 1}`), mimeJSON); err == nil {
+		t.Fatalf("expected the disabled format to fail")
+	}
+	if events := recorder.take(); len(events) != 2 || events[1].Type != EventExtractionFailed || events[1].ErrorType != "UnsupportedFormatError" {
+		t.Fatalf("unexpected failure events %+v", events)
+	}
+
+	unsubscribe()
+	client.ExtractBytes(t.Context(), []byte(testSRT), mimeSRT)
+	if events := recorder.take(); len(events) != 0 {
+		t.Fatalf("expected no events after unsubscribing, got %v", eventTypes(events))
+	}
+}
+
+func TestSubscribeEventsFiltersTypes(t *testing.T) {
+	recorder := &eventRecorder{}
+	unsubscribe := SubscribeEvents(recorder, EventExtractionCompleted)
+	defer unsubscribe()
+	NewClient(nil).ExtractBytes(t.Context(), []byte(testSRT), mimeSRT)
+	if events := recorder.take(); len(events) != 0 {
+		t.Fatalf("expected client extractions to stay out of package-level subscriptions, got %v", eventTypes(events))
+	}
+	if _, err := ExtractBytesSync([]byte(testSRT), mimeSRT, nil); err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if events := recorder.take(); len(events) != 1 || events[0].Type != EventExtractionCompleted || events[0].Result.Content != wantSRTContent {
+		t.Fatalf("unexpected events %+v", events)
+	}
+}
@@ -474,7 +474,7 @@ func TestConfigurationOptions(t *testing.T) {
 		config := &ExtractionConfig{
 			Chunking: &ChunkingConfig{
 				MaxChars: IntPtr(1000),
-				Preset:   StringPtr("default"),
+				Preset:   ChunkingPresetBalanced,
 			},
 		}
 		if config.Chunking == nil || config.Chunking.MaxChars == nil {
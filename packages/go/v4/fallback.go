@@ -0,0 +1,184 @@
+package kreuzberg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// FallbackStrategy names a step in a fallback extraction chain.
+type FallbackStrategy string
+
+const (
+	// FallbackForceOCR re-runs native extraction with ForceOCR enabled, rasterizing every page.
+	FallbackForceOCR FallbackStrategy = "force_ocr"
+)
+
+// FallbackConfig configures ordered fallback chains that run when the primary native
+// extraction fails, so a broken parser degrades gracefully instead of erroring.
+type FallbackConfig struct {
+	// Chains maps a MIME type to the strategies tried, in order, after the primary extraction
+	// fails. Keys may be exact MIME types ("application/pdf"), type wildcards ("image/*"),
+	// or "*" for any document. The most specific matching key wins.
+	Chains map[string][]FallbackStrategy
+	// Strings tunes the FallbackStrings strategy (nil uses defaults).
+	Strings *StringsOptions
+}
+
+// chainFor returns the fallback chain configured for mimeType.
+func (f *FallbackConfig) chainFor(mimeType string) []FallbackStrategy {
+	if f == nil || len(f.Chains) == 0 {
+		return nil
+	}
+	chains, _ := f.normalizedChains()
+	mimeType = normalizeChainKey(mimeType)
+	if chain, ok := chains[mimeType]; ok {
+		return chain
+	}
+	if slash := strings.Index(mimeType, "/"); slash > 0 {
+		if chain, ok := chains[mimeType[:slash]+"/*"]; ok {
+			return chain
+		}
+	}
+	return chains["*"]
+}
+
+// normalizedChains returns Chains keyed by lowercased MIME types, so "Application/PDF" matches
+// like "application/pdf". It reports an error for keys that only differ in case or spacing.
+func (f *FallbackConfig) normalizedChains() (map[string][]FallbackStrategy, error) {
+	chains := make(map[string][]FallbackStrategy, len(f.Chains))
+	var err error
+	for key, chain := range f.Chains {
+		normalized := normalizeChainKey(key)
+		if _, ok := chains[normalized]; ok && err == nil {
+			err = newValidationErrorWithContext(fmt.Sprintf("fallback chains configure %q more than once", normalized), nil, ErrorCodeValidation, nil)
+		}
+		chains[normalized] = chain
+	}
+	return chains, err
+}
+
+func normalizeChainKey(mimeType string) string {
+	return strings.ToLower(strings.TrimSpace(mimeType))
+}
+
+// documentSource identifies the input of an extraction so fallback steps can re-read it.
+type documentSource struct {
+	path     string
+	data     []byte
+	mimeType string
+	// denied fails the document in batches before it is extracted, e.g. because its format is
+	// disabled for the client.
+	denied error
+}
+
+func (s documentSource) bytes() ([]byte, error) {
+	if s.data != nil {
+		return s.data, nil
+	}
+	// #nosec G304 -- path was supplied by the caller for extraction
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, newIOErrorWithContext("failed to read document for fallback extraction", err, ErrorCodeIo, nil)
+	}
+	return data, nil
+}
+
+func (s documentSource) detectMimeType() string {
+	if s.mimeType != "" {
+		return s.mimeType
+	}
+	if s.path == "" {
+		return ""
+	}
+	mime, err := detectMimeTypeFromPathNative(s.path)
+	if err != nil {
+		return ""
+	}
+	return mime
+}
+
+type fallbackFunc func(src documentSource, mimeType string, config *ExtractionConfig) (*ExtractionResult, error)
+
+var fallbackStrategies = map[FallbackStrategy]fallbackFunc{
+	FallbackForceOCR: fallbackForceOCR,
+}
+
+func fallbackForceOCR(src documentSource, mimeType string, config *ExtractionConfig) (*ExtractionResult, error) {
+	ocrConfig := &ExtractionConfig{}
+	if config != nil {
+		copied := *config
+		ocrConfig = &copied
+	}
+	ocrConfig.ForceOCR = BoolPtr(true)
+
+	if src.data != nil {
+		return extractBytesNative(src.data, mimeType, ocrConfig)
+	}
+	return extractFileNative(src.path, ocrConfig)
+}
+
+// runFallbackChain tries the configured fallback strategies after primaryErr. It returns
+// primaryErr unchanged when no chain applies, and records the chosen path in Diagnostics.
+func runFallbackChain(src documentSource, config *ExtractionConfig, primaryErr error) (*ExtractionResult, error) {
+	if config == nil || config.Fallback == nil || len(config.Fallback.Chains) == 0 {
+		return nil, primaryErr
+	}
+	var validationErr *ValidationError
+	if errors.As(primaryErr, &validationErr) {
+		return nil, primaryErr
+	}
+
+	mimeType := src.detectMimeType()
+	chain := config.Fallback.chainFor(mimeType)
+	if len(chain) == 0 {
+		return nil, primaryErr
+	}
+
+	attempts := []Diagnostic{{
+		Source:   "fallback:native",
+		Severity: DiagnosticSeverityWarning,
+		Message:  fmt.Sprintf("primary extraction failed: %v", primaryErr),
+	}}
+	for _, strategy := range chain {
+		fn, ok := fallbackStrategies[strategy]
+		if !ok {
+			attempts = append(attempts, Diagnostic{
+				Source:   "fallback:" + string(strategy),
+				Severity: DiagnosticSeverityWarning,
+				Message:  "unknown fallback strategy skipped",
+			})
+			continue
+		}
+
+		result, err := fn(src, mimeType, config)
+		if err != nil {
+			attempts = append(attempts, Diagnostic{
+				Source:   "fallback:" + string(strategy),
+				Severity: DiagnosticSeverityWarning,
+				Message:  fmt.Sprintf("fallback failed: %v", err),
+			})
+			continue
+		}
+		if result.MimeType == "" {
+			result.MimeType = mimeType
+		}
+		result.Diagnostics = append(attempts, result.Diagnostics...)
+		result.addDiagnostic("fallback:"+string(strategy), DiagnosticSeverityInfo, fmt.Sprintf("extracted using fallback strategy %q", strategy))
+		return result, nil
+	}
+
+	return nil, primaryErr
+}
+
+// batchItemError reports the per-document failure recorded in a batch result, if any.
+func batchItemError(result *ExtractionResult) error {
+	if result == nil {
+		return newRuntimeErrorWithContext("batch item produced no result", nil, ErrorCodeInternal, nil)
+	}
+	if result.Metadata.Error != nil {
+		return newRuntimeErrorWithContext(result.Metadata.Error.Message, nil, ErrorCodeInternal, nil)
+	}
+	return nil
+}
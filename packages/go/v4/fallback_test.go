@@ -0,0 +1,106 @@
+package kreuzberg
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFallbackChainForPrefersMostSpecificKey(t *testing.T) {
+	cfg := &FallbackConfig{Chains: map[string][]FallbackStrategy{
+		"application/pdf": {FallbackForceOCR},
+		"image/*":         {"image-step"},
+		"*":               {"any-step"},
+	}}
+
+	if chain := cfg.chainFor("application/pdf"); len(chain) != 1 || chain[0] != FallbackForceOCR {
+		t.Fatalf("unexpected pdf chain: %v", chain)
+	}
+	if chain := cfg.chainFor("image/png"); len(chain) != 1 || chain[0] != "image-step" {
+		t.Fatalf("unexpected image chain: %v", chain)
+	}
+	if chain := cfg.chainFor("text/plain"); len(chain) != 1 || chain[0] != "any-step" {
+		t.Fatalf("unexpected wildcard chain: %v", chain)
+	}
+	var empty *FallbackConfig
+	if chain := empty.chainFor("application/pdf"); chain != nil {
+		t.Fatalf("nil config should have no chain")
+	}
+}
+
+func TestFallbackChainKeysAreCaseInsensitive(t *testing.T) {
+	cfg := &FallbackConfig{Chains: map[string][]FallbackStrategy{" Application/PDF": {FallbackForceOCR}, "IMAGE/*": {"image-step"}}}
+	if chain := cfg.chainFor("application/pdf"); len(chain) != 1 || chain[0] != FallbackForceOCR {
+		t.Fatalf("unexpected pdf chain: %v", chain)
+	}
+	if chain := cfg.chainFor("image/PNG"); len(chain) != 1 || chain[0] != "image-step" {
+		t.Fatalf("unexpected image chain: %v", chain)
+	}
+
+	duplicate := &ExtractionConfig{Fallback: &FallbackConfig{Chains: map[string][]FallbackStrategy{
+		"application/pdf": {FallbackForceOCR},
+		"Application/PDF": {"other"},
+	}}}
+	if err := validateConfigValues(duplicate); err == nil {
+		t.Fatalf("expected keys differing only in case to be rejected")
+	}
+}
+
+func TestRunFallbackChainRecordsChosenPath(t *testing.T) {
+	fallbackStrategies["test-broken"] = func(src documentSource, mimeType string, config *ExtractionConfig) (*ExtractionResult, error) {
+		return nil, errors.New("still broken")
+	}
+	fallbackStrategies["test-ok"] = func(src documentSource, mimeType string, config *ExtractionConfig) (*ExtractionResult, error) {
+		return &ExtractionResult{Content: "recovered", Success: true}, nil
+	}
+	t.Cleanup(func() {
+		delete(fallbackStrategies, "test-broken")
+		delete(fallbackStrategies, "test-ok")
+	})
+
+	cfg := &ExtractionConfig{Fallback: &FallbackConfig{Chains: map[string][]FallbackStrategy{
+		"application/x-test": {"test-broken", "test-ok"},
+	}}}
+	primary := newParsingErrorWithContext("xref table truncated", nil, ErrorCodeParsing, nil)
+
+	result, err := runFallbackChain(documentSource{data: []byte("x"), mimeType: "application/x-test"}, cfg, primary)
+	if err != nil {
+		t.Fatalf("expected fallback to recover: %v", err)
+	}
+	if result.Content != "recovered" || result.MimeType != "application/x-test" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	sources := make([]string, 0, len(result.Diagnostics))
+	for _, d := range result.Diagnostics {
+		sources = append(sources, d.Source)
+	}
+	want := []string{"fallback:native", "fallback:test-broken", "fallback:test-ok"}
+	if len(sources) != len(want) {
+		t.Fatalf("unexpected diagnostics: %v", sources)
+	}
+	for i := range want {
+		if sources[i] != want[i] {
+			t.Fatalf("unexpected diagnostics: %v", sources)
+		}
+	}
+}
+
+func TestRunFallbackChainReturnsPrimaryErrorWhenNotApplicable(t *testing.T) {
+	primary := newParsingErrorWithContext("broken", nil, ErrorCodeParsing, nil)
+	src := documentSource{data: []byte("x"), mimeType: "text/plain"}
+
+	if _, err := runFallbackChain(src, nil, primary); err != primary {
+		t.Fatalf("expected primary error without config, got %v", err)
+	}
+
+	cfg := &ExtractionConfig{Fallback: &FallbackConfig{Chains: map[string][]FallbackStrategy{"application/pdf": {FallbackForceOCR}}}}
+	if _, err := runFallbackChain(src, cfg, primary); err != primary {
+		t.Fatalf("expected primary error for unmatched MIME, got %v", err)
+	}
+
+	validation := newValidationErrorWithContext("data cannot be empty", nil, ErrorCodeValidation, nil)
+	cfg.Fallback.Chains["*"] = []FallbackStrategy{FallbackForceOCR}
+	if _, err := runFallbackChain(src, cfg, validation); err != validation {
+		t.Fatalf("validation errors must not trigger fallbacks, got %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package kreuzberg
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Faults configures fault injection for tests of code that embeds the binding, e.g. to exercise
+// retries, fallbacks and readiness handling without crafting corrupt documents. Faults apply to
+// every primary extraction in the process, single or batch, before any extractor or the native
+// library runs; the configured FallbackChain then runs as it would for a real failure.
+type Faults struct {
+	// FailRate is the fraction of extractions that fail with Err.
+	FailRate float64
+	// Err is the error injected failures return (default: a RuntimeError).
+	Err error
+	// PanicRate is the fraction of extractions that fail like a panic caught in the native
+	// library: a RuntimeError carrying a PanicContext.
+	PanicRate float64
+	// Delay is added to extractions before they run, simulating slow documents.
+	Delay time.Duration
+	// DelayRate is the fraction of extractions that are delayed (0 = all).
+	DelayRate float64
+	// MissingDependencies names dependencies reported as missing: AcquireDependency fails for
+	// them with a MissingDependencyError, and so do their readiness checks.
+	MissingDependencies []string
+	// Match restricts faults to the documents it returns true for (default: all). path is empty
+	// for in-memory documents, and mimeType is empty when the caller did not give one.
+	Match func(path, mimeType string) bool
+	// Rand makes injection reproducible (default: the global source).
+	Rand *rand.Rand
+}
+
+// FaultStats counts the faults injected so far.
+type FaultStats struct {
+	Extractions int
+	Failed      int
+	Panicked    int
+	Delayed     int
+	// MissingDependency counts dependency acquisitions and checks that were failed.
+	MissingDependency int
+}
+
+// FaultInjector is the active fault injection of a test.
+type FaultInjector struct {
+	faults Faults
+
+	mu    sync.Mutex
+	stats FaultStats
+}
+
+var activeFaults atomic.Pointer[FaultInjector]
+
+// InjectFaults enables faults until tb finishes. Faults are process-wide, so tests that inject
+// them must not run in parallel with other extracting tests; injecting while another injector
+// is active fails the test.
+func InjectFaults(tb testing.TB, faults Faults) *FaultInjector {
+	tb.Helper()
+	for _, rate := range []float64{faults.FailRate, faults.PanicRate, faults.DelayRate} {
+		if rate < 0 || rate > 1 {
+			tb.Fatalf("kreuzberg: fault rates must be in [0, 1], got %v", rate)
+		}
+	}
+	if faults.FailRate+faults.PanicRate > 1 {
+		tb.Fatalf("kreuzberg: FailRate and PanicRate add up to more than 1")
+	}
+	f := &FaultInjector{faults: faults}
+	if !activeFaults.CompareAndSwap(nil, f) {
+		tb.Fatalf("kreuzberg: faults are already injected")
+	}
+	tb.Cleanup(func() { activeFaults.CompareAndSwap(f, nil) })
+	return f
+}
+
+// Stats returns the faults injected so far.
+func (f *FaultInjector) Stats() FaultStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stats
+}
+
+func (f *FaultInjector) float64() float64 {
+	if f.faults.Rand != nil {
+		return f.faults.Rand.Float64()
+	}
+	return rand.Float64()
+}
+
+// injectExtractionFault applies the active faults to an extraction of src.
+func injectExtractionFault(src documentSource) error {
+	f := activeFaults.Load()
+	if f == nil || (f.faults.Match != nil && !f.faults.Match(src.path, src.mimeType)) {
+		return nil
+	}
+	f.mu.Lock()
+	f.stats.Extractions++
+	outcome := f.float64()
+	delayed := f.faults.Delay > 0 && (f.faults.DelayRate == 0 || f.float64() < f.faults.DelayRate)
+	if delayed {
+		f.stats.Delayed++
+	}
+	var err error
+	switch {
+	case outcome < f.faults.PanicRate:
+		f.stats.Panicked++
+		err = newRuntimeErrorWithContext("injected panic", nil, ErrorCodeInternal, &PanicContext{
+			File: "fault_injection.go", Function: "InjectFaults", Message: "injected panic", TimestampSec: time.Now().Unix(),
+		})
+	case outcome < f.faults.PanicRate+f.faults.FailRate:
+		f.stats.Failed++
+		err = f.faults.Err
+		if err == nil {
+			err = newRuntimeErrorWithContext("injected fault", nil, ErrorCodeInternal, nil)
+		}
+	}
+	f.mu.Unlock()
+
+	if delayed {
+		time.Sleep(f.faults.Delay)
+	}
+	return err
+}
+
+// injectMissingDependency fails lookups of dependencies the active faults report as missing.
+func injectMissingDependency(name string) error {
+	f := activeFaults.Load()
+	if f == nil || !slices.Contains(f.faults.MissingDependencies, name) {
+		return nil
+	}
+	f.mu.Lock()
+	f.stats.MissingDependency++
+	f.mu.Unlock()
+	return newMissingDependencyErrorWithContext(name, fmt.Sprintf("Missing dependency: %s (injected)", name), nil, ErrorCodeMissingDependency, nil)
+}
@@ -0,0 +1,75 @@
+package kreuzberg
+
+import (
+	"errors"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestInjectFaultsFailsExtractions(t *testing.T) {
+	t.Run("fail", func(t *testing.T) {
+		injected := errors.New("boom")
+		faults := InjectFaults(t, Faults{FailRate: 1, Err: injected})
+		if _, err := ExtractBytesSync([]byte(testSRT), mimeSRT, nil); !errors.Is(err, injected) {
+			t.Fatalf("expected the injected error, got %v", err)
+		}
+		results, err := BatchExtractBytesSync([]BytesWithMime{{Data: []byte(testSRT), MimeType: mimeSRT}}, nil)
+		if err != nil || len(results) != 1 || results[0].Metadata.Error == nil {
+			t.Fatalf("expected a failed batch item, got %v, %v", results, err)
+		}
+		if stats := faults.Stats(); stats.Extractions != 2 || stats.Failed != 2 {
+			t.Fatalf("unexpected stats: %+v", stats)
+		}
+	})
+	if _, err := ExtractBytesSync([]byte(testSRT), mimeSRT, nil); err != nil {
+		t.Fatalf("expected faults to end with the test, got %v", err)
+	}
+
+	t.Run("panic", func(t *testing.T) {
+		InjectFaults(t, Faults{PanicRate: 1, Match: func(path, mimeType string) bool { return path == "" }})
+		_, err := ExtractBytesSync([]byte(testSRT), mimeSRT, nil)
+		var runtimeErr *RuntimeError
+		if !errors.As(err, &runtimeErr) || runtimeErr.PanicCtx() == nil {
+			t.Fatalf("expected a RuntimeError with panic context, got %v", err)
+		}
+		path := filepath.Join(t.TempDir(), "a.srt")
+		if err := os.WriteFile(path, []byte(testSRT), 0o600); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		if _, err := ExtractFileSync(path, nil); err != nil {
+			t.Fatalf("expected unmatched documents to succeed, got %v", err)
+		}
+	})
+}
+
+func TestInjectFaultsRatesAndDelays(t *testing.T) {
+	faults := InjectFaults(t, Faults{FailRate: 0.5, Delay: 5 * time.Millisecond, DelayRate: 0.5, Rand: rand.New(rand.NewPCG(1, 2))})
+	start := time.Now()
+	for range 40 {
+		ExtractBytesSync([]byte(testSRT), mimeSRT, nil)
+	}
+	stats := faults.Stats()
+	if stats.Failed < 10 || stats.Failed > 30 || stats.Delayed < 10 || stats.Delayed > 30 {
+		t.Fatalf("expected about half the extractions to fail and be delayed, got %+v", stats)
+	}
+	if elapsed := time.Since(start); elapsed < time.Duration(stats.Delayed)*5*time.Millisecond {
+		t.Fatalf("expected %d delays, took %v", stats.Delayed, elapsed)
+	}
+}
+
+func TestInjectFaultsMissingDependencies(t *testing.T) {
+	faults := InjectFaults(t, Faults{MissingDependencies: []string{DependencyLibreOffice, "sh"}})
+	var missing *MissingDependencyError
+	if _, err := AcquireDependency(t.Context(), DependencyLibreOffice); !errors.As(err, &missing) {
+		t.Fatalf("expected a MissingDependencyError, got %v", err)
+	}
+	if report := CheckReadiness(t.Context(), &HealthOptions{Dependencies: []string{"sh"}}); report.OK() {
+		t.Fatalf("expected readiness to fail, got %+v", report)
+	}
+	if stats := faults.Stats(); stats.MissingDependency != 2 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
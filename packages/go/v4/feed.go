@@ -0,0 +1,444 @@
+package kreuzberg
+
+import (
+	"encoding/json"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// FeedFormat identifies the syndication format of a feed document.
+type FeedFormat string
+
+const (
+	FeedFormatRSS  FeedFormat = "rss"
+	FeedFormatRDF  FeedFormat = "rdf"
+	FeedFormatAtom FeedFormat = "atom"
+	FeedFormatOPML FeedFormat = "opml"
+)
+
+const (
+	mimeRSS  = "application/rss+xml"
+	mimeAtom = "application/atom+xml"
+	mimeOPML = "text/x-opml"
+)
+
+// Feed is the channel-level information of an RSS, Atom or OPML document.
+type Feed struct {
+	Format      FeedFormat `json:"format"`
+	Title       string     `json:"title,omitempty"`
+	Link        string     `json:"link,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Language    string     `json:"language,omitempty"`
+	// Updated is the feed's last-modified date, normalized to RFC 3339 when it can be parsed.
+	Updated string `json:"updated,omitempty"`
+	// Entries are the feed items; for OPML, the outline entries.
+	Entries []FeedEntry `json:"-"`
+}
+
+// FeedEntry is a single feed item. In the extracted content and in ExtractionResult.Pages each
+// entry occupies its own page, in document order.
+type FeedEntry struct {
+	Title string `json:"title,omitempty"`
+	Link  string `json:"link,omitempty"`
+	// ID is the entry's guid (RSS) or id (Atom).
+	ID string `json:"id,omitempty"`
+	// Published and Updated are normalized to RFC 3339 when they can be parsed.
+	Published  string   `json:"published,omitempty"`
+	Updated    string   `json:"updated,omitempty"`
+	Authors    []string `json:"authors,omitempty"`
+	Categories []string `json:"categories,omitempty"`
+	// Summary and Content are plain text; HTML markup is removed.
+	Summary string `json:"summary,omitempty"`
+	Content string `json:"content,omitempty"`
+	// FeedURL is the subscription URL of an OPML outline entry.
+	FeedURL string `json:"feed_url,omitempty"`
+}
+
+var (
+	feedHTMLDropped = regexp.MustCompile(`(?is)<(script|style)\b.*?</(script|style)\s*>`)
+	feedHTMLBreak   = regexp.MustCompile(`(?i)<\s*(br|hr)\b[^>]*>|</\s*(p|div|li|h[1-6]|tr|blockquote|pre|ul|ol|table)\s*>`)
+	feedHTMLTag     = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// feedHTMLText converts an HTML fragment to plain text, keeping block boundaries as paragraphs.
+func feedHTMLText(fragment string) string {
+	fragment = feedHTMLDropped.ReplaceAllString(fragment, "")
+	fragment = feedHTMLBreak.ReplaceAllString(fragment, "\n")
+	fragment = html.UnescapeString(feedHTMLTag.ReplaceAllString(fragment, ""))
+	var paragraphs []string
+	for _, line := range strings.Split(fragment, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			paragraphs = append(paragraphs, line)
+		}
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+var feedDateLayouts = []string{
+	time.RFC3339,
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	time.RFC822Z,
+	time.RFC822,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// normalizeFeedDate returns value as RFC 3339 when it matches a common feed date layout, and the
+// trimmed input otherwise.
+func normalizeFeedDate(value string) string {
+	value = strings.TrimSpace(value)
+	for _, layout := range feedDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Format(time.RFC3339)
+		}
+	}
+	return value
+}
+
+// parseFeed interprets an XML tree as a feed, returning nil when the root is not a known feed
+// element.
+func parseFeed(root *xmlElem) *Feed {
+	switch {
+	case root.name == "rss":
+		feed := parseRSSChannel(root.child("channel"), FeedFormatRSS)
+		for _, item := range root.child("channel").childrenNamed("item") {
+			feed.Entries = append(feed.Entries, parseRSSItem(item))
+		}
+		return feed
+	case root.name == "RDF":
+		// RSS 1.0 places items next to the channel rather than inside it.
+		feed := parseRSSChannel(root.child("channel"), FeedFormatRDF)
+		for _, item := range root.childrenNamed("item") {
+			feed.Entries = append(feed.Entries, parseRSSItem(item))
+		}
+		return feed
+	case root.name == "feed":
+		return parseAtomFeed(root)
+	case root.name == "opml":
+		return parseOPML(root)
+	}
+	return nil
+}
+
+func parseRSSChannel(channel *xmlElem, format FeedFormat) *Feed {
+	feed := &Feed{
+		Format:      format,
+		Title:       channel.child("title").text(),
+		Link:        channel.child("link").text(),
+		Description: feedHTMLText(channel.child("description").text()),
+		Language:    channel.child("language").text(),
+	}
+	if feed.Updated = channel.child("lastBuildDate", "pubDate").text(); feed.Updated == "" {
+		feed.Updated = channel.child("date").text()
+	}
+	feed.Updated = normalizeFeedDate(feed.Updated)
+	return feed
+}
+
+func parseRSSItem(item *xmlElem) FeedEntry {
+	entry := FeedEntry{
+		Title:   feedHTMLText(item.child("title").text()),
+		Link:    item.child("link").text(),
+		ID:      item.child("guid").text(),
+		Summary: feedHTMLText(item.child("description").rawText()),
+		Content: feedHTMLText(item.child("encoded").rawText()),
+	}
+	if entry.ID == "" {
+		entry.ID = item.attr("about")
+	}
+	published := item.child("pubDate").text()
+	if published == "" {
+		published = item.child("date").text()
+	}
+	entry.Published = normalizeFeedDate(published)
+	for _, c := range item.elements() {
+		switch c.name {
+		case "author", "creator":
+			entry.Authors = append(entry.Authors, c.text())
+		case "category", "subject":
+			entry.Categories = append(entry.Categories, c.text())
+		}
+	}
+	return entry
+}
+
+// atomText returns the text of an Atom text construct, which may carry escaped HTML or inline
+// XHTML depending on its type attribute.
+func atomText(e *xmlElem) string {
+	if e == nil {
+		return ""
+	}
+	if e.attr("type") == "html" {
+		return feedHTMLText(e.rawText())
+	}
+	return strings.TrimSpace(e.text())
+}
+
+// atomLink returns the alternate link of an Atom feed or entry.
+func atomLink(e *xmlElem) string {
+	var fallback string
+	for _, link := range e.childrenNamed("link") {
+		switch link.attr("rel") {
+		case "", "alternate":
+			return link.attr("href")
+		}
+		if fallback == "" {
+			fallback = link.attr("href")
+		}
+	}
+	return fallback
+}
+
+func atomAuthors(e *xmlElem) []string {
+	var names []string
+	for _, a := range e.childrenNamed("author") {
+		if name := a.child("name").text(); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func parseAtomFeed(root *xmlElem) *Feed {
+	feed := &Feed{
+		Format:      FeedFormatAtom,
+		Title:       atomText(root.child("title")),
+		Link:        atomLink(root),
+		Description: atomText(root.child("subtitle")),
+		Language:    root.attr("xml:lang"),
+		Updated:     normalizeFeedDate(root.child("updated").text()),
+	}
+	feedAuthors := atomAuthors(root)
+	for _, e := range root.childrenNamed("entry") {
+		entry := FeedEntry{
+			Title:     atomText(e.child("title")),
+			Link:      atomLink(e),
+			ID:        e.child("id").text(),
+			Published: normalizeFeedDate(e.child("published", "issued").text()),
+			Updated:   normalizeFeedDate(e.child("updated", "modified").text()),
+			Authors:   atomAuthors(e),
+			Summary:   atomText(e.child("summary")),
+			Content:   atomText(e.child("content")),
+		}
+		if len(entry.Authors) == 0 {
+			entry.Authors = feedAuthors
+		}
+		for _, c := range e.childrenNamed("category") {
+			term := c.attr("label")
+			if term == "" {
+				term = c.attr("term")
+			}
+			entry.Categories = append(entry.Categories, term)
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return feed
+}
+
+// parseOPML turns subscription outlines and leaf outlines into entries. The text of enclosing
+// outlines becomes the categories of the entries nested below them.
+func parseOPML(root *xmlElem) *Feed {
+	head := root.child("head")
+	feed := &Feed{Format: FeedFormatOPML, Title: head.child("title").text()}
+	if feed.Updated = head.child("dateModified").text(); feed.Updated == "" {
+		feed.Updated = head.child("dateCreated").text()
+	}
+	feed.Updated = normalizeFeedDate(feed.Updated)
+	owner := head.child("ownerName").text()
+
+	var walk func(outlines []*xmlElem, path []string)
+	walk = func(outlines []*xmlElem, path []string) {
+		for _, o := range outlines {
+			title := o.attr("text")
+			if title == "" {
+				title = o.attr("title")
+			}
+			children := o.childrenNamed("outline")
+			if o.attr("xmlUrl") != "" || len(children) == 0 {
+				entry := FeedEntry{
+					Title:      title,
+					Link:       o.attr("htmlUrl"),
+					FeedURL:    o.attr("xmlUrl"),
+					Published:  normalizeFeedDate(o.attr("created")),
+					Summary:    o.attr("description"),
+					Categories: append([]string(nil), path...),
+				}
+				if entry.Link == "" {
+					entry.Link = o.attr("url")
+				}
+				for _, c := range strings.Split(o.attr("category"), ",") {
+					if c = strings.TrimSpace(c); c != "" {
+						entry.Categories = append(entry.Categories, c)
+					}
+				}
+				if owner != "" {
+					entry.Authors = []string{owner}
+				}
+				feed.Entries = append(feed.Entries, entry)
+			}
+			if len(children) > 0 {
+				walk(children, append(path[:len(path):len(path)], title))
+			}
+		}
+	}
+	walk(root.child("body").childrenNamed("outline"), nil)
+	return feed
+}
+
+// renderFeedEntry renders one entry as a Markdown section.
+func renderFeedEntry(entry FeedEntry) string {
+	title := entry.Title
+	if title == "" {
+		title = "Untitled"
+	}
+	parts := []string{"## " + title}
+	var byline []string
+	if len(entry.Authors) > 0 {
+		byline = append(byline, strings.Join(entry.Authors, ", "))
+	}
+	if entry.Published != "" {
+		byline = append(byline, entry.Published)
+	} else if entry.Updated != "" {
+		byline = append(byline, entry.Updated)
+	}
+	if len(byline) > 0 {
+		parts = append(parts, strings.Join(byline, " · "))
+	}
+	if entry.Link != "" {
+		parts = append(parts, entry.Link)
+	} else if entry.FeedURL != "" {
+		parts = append(parts, entry.FeedURL)
+	}
+	if entry.Content != "" {
+		parts = append(parts, entry.Content)
+	} else if entry.Summary != "" {
+		parts = append(parts, entry.Summary)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// feedResult renders a feed with one page per entry. Chunks never split an entry.
+func feedResult(feed *Feed, mimeType string, config *ExtractionConfig) (*ExtractionResult, error) {
+	var content strings.Builder
+	var spans [][2]int
+	if header := joinNonEmpty("\n\n", prefixNonEmpty("# ", feed.Title), feed.Description); header != "" {
+		content.WriteString(header)
+		spans = append(spans, [2]int{0, content.Len()})
+	}
+	headerSpans := len(spans)
+	structure := &PageStructure{TotalCount: uint64(len(feed.Entries)), UnitType: PageUnitTypePage}
+	for i, entry := range feed.Entries {
+		if content.Len() > 0 {
+			content.WriteString("\n\n")
+		}
+		start := content.Len()
+		content.WriteString(renderFeedEntry(entry))
+		spans = append(spans, [2]int{start, content.Len()})
+		page := PageInfo{Number: uint64(i + 1)}
+		if entry.Title != "" {
+			page.Title = stringPtr(entry.Title)
+		}
+		structure.Pages = append(structure.Pages, page)
+		structure.Boundaries = append(structure.Boundaries, PageBoundary{ByteStart: uint64(start), ByteEnd: uint64(content.Len()), PageNumber: uint64(i + 1)})
+	}
+
+	result := &ExtractionResult{MimeType: mimeType, Content: content.String(), Tables: []Table{}, Success: true}
+	result.Metadata.PageStructure = structure
+	if feed.Language != "" {
+		result.Metadata.Language = stringPtr(feed.Language)
+	}
+	if feed.Updated != "" {
+		result.Metadata.Date = stringPtr(feed.Updated)
+	}
+	if config != nil && config.Pages != nil && config.Pages.ExtractPages != nil && *config.Pages.ExtractPages {
+		for i, span := range spans[headerSpans:] {
+			result.Pages = append(result.Pages, PageContent{PageNumber: uint64(i + 1), Content: result.Content[span[0]:span[1]]})
+		}
+	}
+	if maxChars := chunkingMaxChars(config, defaultLogChunkChars); maxChars > 0 {
+		result.Chunks = chunkOnBoundaries(result.Content, spans, maxChars)
+		for i := range result.Chunks {
+			meta := &result.Chunks[i].Metadata
+			var first, last uint64
+			for page, b := range structure.Boundaries {
+				if b.ByteStart < meta.ByteEnd && b.ByteEnd > meta.ByteStart {
+					if first == 0 {
+						first = uint64(page + 1)
+					}
+					last = uint64(page + 1)
+				}
+			}
+			if first > 0 {
+				meta.FirstPage, meta.LastPage = &first, &last
+			}
+		}
+	}
+
+	entries := feed.Entries
+	if entries == nil {
+		entries = []FeedEntry{}
+	}
+	additional := map[string]any{
+		"feed":         feed,
+		"feed_format":  feed.Format,
+		"feed_entries": entries,
+		"entry_count":  len(entries),
+	}
+	result.Metadata.Additional = make(map[string]json.RawMessage, len(additional))
+	for key, value := range additional {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, newSerializationErrorWithContext("failed to encode feed metadata", err, ErrorCodeValidation, nil)
+		}
+		result.Metadata.Additional[key] = raw
+	}
+	return result, nil
+}
+
+func prefixNonEmpty(prefix, value string) string {
+	if value == "" {
+		return ""
+	}
+	return prefix + value
+}
+
+// extractFeed extracts RSS, Atom and OPML documents; other XML is passed to the native extractor.
+func extractFeed(src documentSource, mimeType string, config *ExtractionConfig) (*ExtractionResult, error) {
+	data, err := src.bytes()
+	if err != nil {
+		return nil, err
+	}
+	root, _, _, err := parseXMLTree(data)
+	if err != nil {
+		return nil, newParsingErrorWithContext("failed to parse feed", err, ErrorCodeParsing, nil)
+	}
+	feed := parseFeed(root)
+	if feed == nil {
+		if src.path != "" {
+			return extractFileNative(src.path, config)
+		}
+		return extractBytesNative(src.data, mimeType, config)
+	}
+	return feedResult(feed, mimeType, config)
+}
+
+func init() {
+	registerGoPrimaryExtractor(goPrimaryExtractor{
+		name:      "feeds",
+		mimeTypes: []string{mimeRSS, mimeAtom, mimeOPML, "application/x-opml+xml"},
+		extensions: map[string]string{
+			"rss":  mimeRSS,
+			"atom": mimeAtom,
+			"opml": mimeOPML,
+		},
+		extract: extractFeed,
+	})
+}
@@ -0,0 +1,161 @@
+package kreuzberg
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const testRSS = `<?xml version="1.0"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <channel>
+    <title>Engineering Blog</title>
+    <link>https://example.com/</link>
+    <description>Notes &amp; news</description>
+    <language>en-us</language>
+    <lastBuildDate>Tue, 10 Jun 2025 04:00:00 GMT</lastBuildDate>
+    <item>
+      <title>Release 4.0</title>
+      <link>https://example.com/4.0</link>
+      <guid isPermaLink="false">post-40</guid>
+      <pubDate>Mon, 9 Jun 2025 09:30:00 +0200</pubDate>
+      <dc:creator>Sam Lee</dc:creator>
+      <category>releases</category>
+      <description>Short summary</description>
+      <content:encoded><![CDATA[<p>We shipped <b>4.0</b>.</p><script>track()</script><ul><li>Faster</li><li>Smaller</li></ul>]]></content:encoded>
+    </item>
+    <item>
+      <title>Hiring</title>
+      <description>&lt;p&gt;Join us&lt;/p&gt;</description>
+    </item>
+  </channel>
+</rss>`
+
+const testAtom = `<feed xmlns="http://www.w3.org/2005/Atom" xml:lang="de">
+  <title>Changelog</title>
+  <subtitle type="html">&lt;em&gt;All&lt;/em&gt; changes</subtitle>
+  <link rel="self" href="https://example.com/feed.atom"/>
+  <link href="https://example.com/changelog"/>
+  <updated>2025-01-02T03:04:05Z</updated>
+  <author><name>Docs Team</name></author>
+  <entry>
+    <title>Fixed parser</title>
+    <id>urn:uuid:1</id>
+    <link rel="alternate" href="https://example.com/1"/>
+    <published>2025-01-01T00:00:00+01:00</published>
+    <category term="fix" label="Bug fix"/>
+    <content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>No more crashes.</p></div></content>
+  </entry>
+</feed>`
+
+const testOPML = `<opml version="2.0">
+  <head><title>Subscriptions</title><ownerName>Kim</ownerName><dateCreated>Sat, 18 Jun 2005 12:11:52 GMT</dateCreated></head>
+  <body>
+    <outline text="Tech">
+      <outline text="Go Blog" type="rss" xmlUrl="https://go.dev/blog/feed.atom" htmlUrl="https://go.dev/blog" category="golang, lang"/>
+    </outline>
+    <outline text="Standalone" xmlUrl="https://example.com/rss"/>
+  </body>
+</opml>`
+
+func TestParseRSSFeed(t *testing.T) {
+	root, _, _, err := parseXMLTree([]byte(testRSS))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	feed := parseFeed(root)
+	if feed == nil || feed.Format != FeedFormatRSS || feed.Title != "Engineering Blog" || feed.Description != "Notes & news" || feed.Updated != "2025-06-10T04:00:00Z" {
+		t.Fatalf("unexpected feed: %+v", feed)
+	}
+	if len(feed.Entries) != 2 {
+		t.Fatalf("unexpected entries: %+v", feed.Entries)
+	}
+	first := feed.Entries[0]
+	if first.ID != "post-40" || first.Published != "2025-06-09T09:30:00+02:00" || first.Authors[0] != "Sam Lee" || first.Categories[0] != "releases" {
+		t.Fatalf("unexpected entry: %+v", first)
+	}
+	if first.Content != "We shipped 4.0.\n\nFaster\n\nSmaller" || first.Summary != "Short summary" {
+		t.Fatalf("unexpected entry text: %q / %q", first.Content, first.Summary)
+	}
+	if feed.Entries[1].Summary != "Join us" {
+		t.Fatalf("escaped HTML description not converted: %q", feed.Entries[1].Summary)
+	}
+}
+
+func TestParseAtomFeed(t *testing.T) {
+	root, _, _, err := parseXMLTree([]byte(testAtom))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	feed := parseFeed(root)
+	if feed.Format != FeedFormatAtom || feed.Link != "https://example.com/changelog" || feed.Description != "All changes" || feed.Language != "de" {
+		t.Fatalf("unexpected feed: %+v", feed)
+	}
+	entry := feed.Entries[0]
+	if entry.Link != "https://example.com/1" || entry.Content != "No more crashes." || entry.Categories[0] != "Bug fix" || entry.Authors[0] != "Docs Team" {
+		t.Fatalf("unexpected entry: %+v", entry)
+	}
+}
+
+func TestParseOPMLFeed(t *testing.T) {
+	root, _, _, err := parseXMLTree([]byte(testOPML))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	feed := parseFeed(root)
+	if feed.Format != FeedFormatOPML || feed.Updated != "2005-06-18T12:11:52Z" || len(feed.Entries) != 2 {
+		t.Fatalf("unexpected feed: %+v", feed)
+	}
+	goBlog := feed.Entries[0]
+	if goBlog.FeedURL != "https://go.dev/blog/feed.atom" || strings.Join(goBlog.Categories, "|") != "Tech|golang|lang" || goBlog.Authors[0] != "Kim" {
+		t.Fatalf("unexpected outline entry: %+v", goBlog)
+	}
+	if len(feed.Entries[1].Categories) != 0 {
+		t.Fatalf("top-level outline should have no categories: %+v", feed.Entries[1])
+	}
+}
+
+func TestExtractFeedPagesAndChunks(t *testing.T) {
+	extractor, mimeType := selectGoPrimaryExtractor(documentSource{path: "blog.rss"}, nil)
+	if extractor == nil || extractor.name != "feeds" || mimeType != mimeRSS {
+		t.Fatalf("unexpected routing: %v %q", extractor, mimeType)
+	}
+	config := &ExtractionConfig{
+		Pages:    &PageConfig{ExtractPages: BoolPtr(true)},
+		Chunking: &ChunkingConfig{MaxChars: IntPtr(40)},
+	}
+	result, err := extractor.extract(documentSource{data: []byte(testRSS)}, mimeType, config)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if !strings.HasPrefix(result.Content, "# Engineering Blog\n\nNotes & news\n\n## Release 4.0\n\nSam Lee · 2025-06-09T09:30:00+02:00") {
+		t.Fatalf("unexpected content:\n%s", result.Content)
+	}
+	if len(result.Pages) != 2 || !strings.HasPrefix(result.Pages[1].Content, "## Hiring") || result.Pages[1].PageNumber != 2 {
+		t.Fatalf("unexpected pages: %+v", result.Pages)
+	}
+	structure := result.Metadata.PageStructure
+	if structure == nil || structure.TotalCount != 2 || *structure.Pages[0].Title != "Release 4.0" {
+		t.Fatalf("unexpected page structure: %+v", structure)
+	}
+	if len(result.Chunks) != 3 || *result.Chunks[1].Metadata.FirstPage != 1 || *result.Chunks[2].Metadata.FirstPage != 2 {
+		t.Fatalf("expected header and one chunk per entry, got %+v", result.Chunks)
+	}
+	if *result.Metadata.Date != "2025-06-10T04:00:00Z" || *result.Metadata.Language != "en-us" {
+		t.Fatalf("unexpected metadata: %+v", result.Metadata)
+	}
+	var entries []FeedEntry
+	if err := json.Unmarshal(result.Metadata.Additional["feed_entries"], &entries); err != nil || len(entries) != 2 || entries[0].Title != "Release 4.0" {
+		t.Fatalf("unexpected feed entries: %s", result.Metadata.Additional["feed_entries"])
+	}
+}
+
+func TestXMLProfileRoutesFeeds(t *testing.T) {
+	result, err := extractXMLProfile(documentSource{data: []byte(testAtom)}, mimeXML, &ExtractionConfig{XMLProfile: &XMLProfileConfig{}})
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if string(result.Metadata.Additional["feed_format"]) != `"atom"` {
+		t.Fatalf("expected feed extraction, got %v", result.Metadata.Additional)
+	}
+}
@@ -0,0 +1,233 @@
+package kreuzberg
+
+/*
+#include "internal/ffi/kreuzberg.h"
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// FFIResourceKind classifies memory that crosses the FFI boundary.
+type FFIResourceKind string
+
+const (
+	// FFIResourceResult is an extraction result allocated by the native library.
+	FFIResourceResult FFIResourceKind = "result"
+	// FFIResourceBatchResult is a batch extraction result allocated by the native library.
+	FFIResourceBatchResult FFIResourceKind = "batch_result"
+	// FFIResourceString is a string allocated by the native library.
+	FFIResourceString FFIResourceKind = "string"
+	// FFIResourceConfig is a native ExtractionConfig handle.
+	FFIResourceConfig FFIResourceKind = "config"
+	// FFIResourceBuffer is a C buffer allocated by the binding to pass documents, paths, MIME
+	// types and configs to the native library.
+	FFIResourceBuffer FFIResourceKind = "buffer"
+)
+
+// FFILeak is an FFI allocation that has not been freed.
+type FFILeak struct {
+	Kind FFIResourceKind `json:"kind"`
+	// Age is the time since the allocation.
+	Age time.Duration `json:"age"`
+	// Stack is the Go call stack of the allocation.
+	Stack string `json:"stack"`
+}
+
+// FFIResourceReport summarizes FFI allocations observed since tracking was enabled.
+type FFIResourceReport struct {
+	// Enabled reports whether tracking is on.
+	Enabled bool `json:"enabled"`
+	// Allocated and Freed count allocations and frees per kind.
+	Allocated map[FFIResourceKind]uint64 `json:"allocated"`
+	Freed     map[FFIResourceKind]uint64 `json:"freed"`
+	// UnmatchedFrees counts frees of pointers that were not tracked as live, i.e. double frees or
+	// frees of allocations made before tracking was enabled.
+	UnmatchedFrees uint64 `json:"unmatched_frees"`
+	// Outstanding lists live allocations, oldest first.
+	Outstanding []FFILeak `json:"outstanding"`
+}
+
+type ffiAllocation struct {
+	kind  FFIResourceKind
+	at    time.Time
+	stack []uintptr
+}
+
+var ffiTracker struct {
+	enabled atomic.Bool
+	sync.Mutex
+	live      map[unsafe.Pointer]ffiAllocation
+	allocated map[FFIResourceKind]uint64
+	freed     map[FFIResourceKind]uint64
+	unmatched uint64
+}
+
+func init() {
+	if v := os.Getenv("KREUZBERG_DEBUG_FFI"); v != "" && v != "0" && !strings.EqualFold(v, "false") {
+		EnableFFITracking(true)
+	}
+}
+
+// EnableFFITracking turns FFI allocation tracking on or off. Tracking records the call stack of
+// every native result, native string, native config and C buffer the binding allocates, so
+// leaks can be reported with CheckFFILeaks. It costs a stack capture per allocation and is meant
+// for debugging and tests; setting KREUZBERG_DEBUG_FFI=1 enables it at startup. Enabling resets
+// previously collected data.
+func EnableFFITracking(enabled bool) {
+	ffiTracker.Lock()
+	defer ffiTracker.Unlock()
+	if enabled {
+		ffiTracker.live = map[unsafe.Pointer]ffiAllocation{}
+		ffiTracker.allocated = map[FFIResourceKind]uint64{}
+		ffiTracker.freed = map[FFIResourceKind]uint64{}
+		ffiTracker.unmatched = 0
+	}
+	ffiTracker.enabled.Store(enabled)
+}
+
+// FFIResources returns the allocations observed by FFI tracking.
+func FFIResources() FFIResourceReport {
+	ffiTracker.Lock()
+	defer ffiTracker.Unlock()
+	report := FFIResourceReport{
+		Enabled:        ffiTracker.enabled.Load(),
+		Allocated:      map[FFIResourceKind]uint64{},
+		Freed:          map[FFIResourceKind]uint64{},
+		UnmatchedFrees: ffiTracker.unmatched,
+	}
+	for kind, n := range ffiTracker.allocated {
+		report.Allocated[kind] = n
+	}
+	for kind, n := range ffiTracker.freed {
+		report.Freed[kind] = n
+	}
+	now := time.Now()
+	for _, alloc := range ffiTracker.live {
+		report.Outstanding = append(report.Outstanding, FFILeak{Kind: alloc.kind, Age: now.Sub(alloc.at), Stack: formatStack(alloc.stack)})
+	}
+	sort.Slice(report.Outstanding, func(i, j int) bool { return report.Outstanding[i].Age > report.Outstanding[j].Age })
+	return report
+}
+
+// CheckFFILeaks returns an error describing outstanding FFI allocations and unmatched frees, or
+// nil when there are none or tracking is off. Call it at shutdown, or at the end of a test run
+// (e.g. in TestMain), once no extraction is in flight.
+func CheckFFILeaks() error {
+	report := FFIResources()
+	if !report.Enabled || (len(report.Outstanding) == 0 && report.UnmatchedFrees == 0) {
+		return nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d FFI allocation(s) not freed, %d unmatched free(s)", len(report.Outstanding), report.UnmatchedFrees)
+	for _, leak := range report.Outstanding {
+		fmt.Fprintf(&b, "\n\n%s allocated %s ago at:\n%s", leak.Kind, leak.Age.Round(time.Millisecond), leak.Stack)
+	}
+	return newRuntimeErrorWithContext(b.String(), nil, ErrorCodeInternal, nil)
+}
+
+func formatStack(pcs []uintptr) string {
+	var b strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&b, "\t%s\n\t\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func recordFFIAlloc(kind FFIResourceKind, ptr unsafe.Pointer) {
+	if ptr == nil || !ffiTracker.enabled.Load() {
+		return
+	}
+	pcs := make([]uintptr, 32)
+	pcs = pcs[:runtime.Callers(3, pcs)]
+	ffiTracker.Lock()
+	defer ffiTracker.Unlock()
+	if ffiTracker.live == nil {
+		return
+	}
+	ffiTracker.live[ptr] = ffiAllocation{kind: kind, at: time.Now(), stack: pcs}
+	ffiTracker.allocated[kind]++
+}
+
+func recordFFIFree(kind FFIResourceKind, ptr unsafe.Pointer) {
+	if ptr == nil || !ffiTracker.enabled.Load() {
+		return
+	}
+	ffiTracker.Lock()
+	defer ffiTracker.Unlock()
+	if ffiTracker.live == nil {
+		return
+	}
+	if _, ok := ffiTracker.live[ptr]; !ok {
+		ffiTracker.unmatched++
+		return
+	}
+	delete(ffiTracker.live, ptr)
+	ffiTracker.freed[kind]++
+}
+
+// trackFFIAlloc records a native allocation and returns ptr, so it can wrap the allocating call.
+func trackFFIAlloc[T any](kind FFIResourceKind, ptr *T) *T {
+	recordFFIAlloc(kind, unsafe.Pointer(ptr))
+	return ptr
+}
+
+// trackNativeConfig is trackFFIAlloc for native configs, whose C type is incomplete in Go.
+func trackNativeConfig(ptr *C.ExtractionConfig) *C.ExtractionConfig {
+	recordFFIAlloc(FFIResourceConfig, unsafe.Pointer(ptr))
+	return ptr
+}
+
+// newCString is C.CString with FFI tracking; release it with freeCBuffer.
+func newCString(s string) *C.char {
+	ptr := C.CString(s)
+	recordFFIAlloc(FFIResourceBuffer, unsafe.Pointer(ptr))
+	return ptr
+}
+
+// newCBytes is C.CBytes with FFI tracking; release it with freeCBuffer.
+func newCBytes(data []byte) unsafe.Pointer {
+	ptr := C.CBytes(data)
+	recordFFIAlloc(FFIResourceBuffer, ptr)
+	return ptr
+}
+
+func freeCBuffer(ptr unsafe.Pointer) {
+	recordFFIFree(FFIResourceBuffer, ptr)
+	C.free(ptr)
+}
+
+func freeNativeString(ptr *C.char) {
+	recordFFIFree(FFIResourceString, unsafe.Pointer(ptr))
+	C.kreuzberg_free_string(ptr)
+}
+
+func freeNativeResult(ptr *C.CExtractionResult) {
+	recordFFIFree(FFIResourceResult, unsafe.Pointer(ptr))
+	C.kreuzberg_free_result(ptr)
+}
+
+func freeNativeBatchResult(ptr *C.CBatchResult) {
+	recordFFIFree(FFIResourceBatchResult, unsafe.Pointer(ptr))
+	C.kreuzberg_free_batch_result(ptr)
+}
+
+func freeNativeConfig(ptr *C.ExtractionConfig) {
+	recordFFIFree(FFIResourceConfig, unsafe.Pointer(ptr))
+	C.kreuzberg_config_free(ptr)
+}
@@ -0,0 +1,48 @@
+package kreuzberg
+
+import (
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestFFITrackingBalancesExtractionBuffers(t *testing.T) {
+	EnableFFITracking(true)
+	t.Cleanup(func() { EnableFFITracking(false) })
+
+	// The native call may fail in this environment; its buffers must be released either way.
+	_, _ = ExtractBytesSync([]byte("plain text"), "text/plain", &ExtractionConfig{UseCache: BoolPtr(false)})
+	report := FFIResources()
+	if report.Allocated[FFIResourceBuffer] < 3 || report.Allocated[FFIResourceBuffer] != report.Freed[FFIResourceBuffer] {
+		t.Fatalf("unbalanced buffers: %+v", report)
+	}
+	if err := CheckFFILeaks(); err != nil {
+		t.Fatalf("unexpected leaks: %v", err)
+	}
+}
+
+func TestFFITrackingReportsLeaksAndUnmatchedFrees(t *testing.T) {
+	EnableFFITracking(true)
+	t.Cleanup(func() { EnableFFITracking(false) })
+
+	leaked := newCString("leaked")
+	err := CheckFFILeaks()
+	if err == nil || !strings.Contains(err.Error(), "1 FFI allocation(s) not freed") || !strings.Contains(err.Error(), "TestFFITrackingReportsLeaksAndUnmatchedFrees") {
+		t.Fatalf("expected a leak report naming the allocating test, got %v", err)
+	}
+	freeCBuffer(unsafe.Pointer(leaked))
+	if err := CheckFFILeaks(); err != nil {
+		t.Fatalf("unexpected leaks after free: %v", err)
+	}
+
+	var stray byte
+	recordFFIFree(FFIResourceString, unsafe.Pointer(&stray))
+	if report := FFIResources(); report.UnmatchedFrees != 1 || CheckFFILeaks() == nil {
+		t.Fatalf("expected an unmatched free, got %+v", report)
+	}
+
+	EnableFFITracking(false)
+	if report := FFIResources(); report.Enabled {
+		t.Fatalf("tracking still enabled")
+	}
+}
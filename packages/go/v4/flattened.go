@@ -0,0 +1,210 @@
+package kreuzberg
+
+import "encoding/json"
+
+// FlatResult is a pointer-free view of an ExtractionResult (see ExtractionResult.Flattened) for
+// templating, logging and serialization where absent and empty values need not be told apart:
+// absent values are zero, and slices and maps are never nil.
+type FlatResult struct {
+	Content           string       `json:"content"`
+	MimeType          string       `json:"mime_type"`
+	Success           bool         `json:"success"`
+	DetectedLanguages []string     `json:"detected_languages"`
+	Metadata          FlatMetadata `json:"metadata"`
+	Tables            []FlatTable  `json:"tables"`
+	Chunks            []FlatChunk  `json:"chunks"`
+	Images            []FlatImage  `json:"images"`
+	Pages             []FlatPage   `json:"pages"`
+	Diagnostics       []Diagnostic `json:"diagnostics"`
+}
+
+// FlatMetadata is a pointer-free view of Metadata.
+type FlatMetadata struct {
+	Language string `json:"language"`
+	Date     string `json:"date"`
+	Subject  string `json:"subject"`
+	// Title is the document title from the format metadata (PDF, HTML, PPTX, ...).
+	Title      string     `json:"title"`
+	FormatType FormatType `json:"format_type"`
+	// Format holds the fields of the format metadata by JSON name, e.g. "producer" for PDFs.
+	// Numbers decode as float64.
+	Format map[string]any `json:"format"`
+	// PageCount is the number of pages, slides or sheets, from the page structure or the
+	// format metadata.
+	PageCount    int          `json:"page_count"`
+	PageUnit     PageUnitType `json:"page_unit"`
+	ErrorType    string       `json:"error_type"`
+	ErrorMessage string       `json:"error_message"`
+	// Additional holds Metadata.Additional decoded from JSON.
+	Additional map[string]any `json:"additional"`
+}
+
+// FlatTable is a pointer-free view of Table, without cell provenance.
+type FlatTable struct {
+	Cells      [][]string `json:"cells"`
+	Markdown   string     `json:"markdown"`
+	PageNumber int        `json:"page_number"`
+}
+
+// FlatChunk is a pointer-free view of Chunk with its metadata inlined.
+type FlatChunk struct {
+	Content     string    `json:"content"`
+	Embedding   []float32 `json:"embedding"`
+	ChunkIndex  int       `json:"chunk_index"`
+	TotalChunks int       `json:"total_chunks"`
+	TokenCount  int       `json:"token_count"`
+	ByteStart   uint64    `json:"byte_start"`
+	ByteEnd     uint64    `json:"byte_end"`
+	FirstPage   uint64    `json:"first_page"`
+	LastPage    uint64    `json:"last_page"`
+	StartTimeMs int64     `json:"start_time_ms"`
+	EndTimeMs   int64     `json:"end_time_ms"`
+}
+
+// FlatImage is a pointer-free view of ExtractedImage.
+type FlatImage struct {
+	Data             []byte `json:"data"`
+	Format           string `json:"format"`
+	ImageIndex       int    `json:"image_index"`
+	PageNumber       int    `json:"page_number"`
+	Width            uint32 `json:"width"`
+	Height           uint32 `json:"height"`
+	Colorspace       string `json:"colorspace"`
+	BitsPerComponent uint32 `json:"bits_per_component"`
+	IsMask           bool   `json:"is_mask"`
+	Description      string `json:"description"`
+	// OCRContent is the text recognized in the image, if OCR was applied to it.
+	OCRContent string `json:"ocr_content"`
+}
+
+// FlatPage is a pointer-free view of PageContent.
+type FlatPage struct {
+	PageNumber uint64      `json:"page_number"`
+	Content    string      `json:"content"`
+	Tables     []FlatTable `json:"tables"`
+	Images     []FlatImage `json:"images"`
+}
+
+// Flattened returns a pointer-free view of the result. A nil result yields the zero view (with
+// empty slices and maps).
+func (r *ExtractionResult) Flattened() FlatResult {
+	if r == nil {
+		r = &ExtractionResult{}
+	}
+	return FlatResult{
+		Content:           r.Content,
+		MimeType:          r.MimeType,
+		Success:           r.Success,
+		DetectedLanguages: flatSlice(r.DetectedLanguages, func(s string) string { return s }),
+		Metadata:          r.Metadata.Flattened(),
+		Tables:            flatSlice(r.Tables, flattenTable),
+		Chunks:            flatSlice(r.Chunks, flattenChunk),
+		Images:            flatSlice(r.Images, flattenImage),
+		Pages:             flatSlice(r.Pages, flattenPage),
+		Diagnostics:       flatSlice(r.Diagnostics, func(d Diagnostic) Diagnostic { return d }),
+	}
+}
+
+// Flattened returns a pointer-free view of the metadata. Entries of Format and Additional that
+// fail to decode are left out.
+func (m Metadata) Flattened() FlatMetadata {
+	flat := FlatMetadata{
+		Language:   deref(m.Language),
+		Date:       deref(m.Date),
+		Subject:    deref(m.Subject),
+		FormatType: m.Format.Type,
+		Format:     map[string]any{},
+		Additional: make(map[string]any, len(m.Additional)),
+	}
+	if fields, err := m.encodeFormat(); err == nil {
+		delete(fields, "format_type")
+		decodeRawFields(fields, flat.Format)
+	}
+	decodeRawFields(m.Additional, flat.Additional)
+	flat.Title, _ = flat.Format["title"].(string)
+	if count, ok := flat.Format["page_count"].(float64); ok {
+		flat.PageCount = int(count)
+	}
+	if m.PageStructure != nil {
+		flat.PageCount = int(m.PageStructure.TotalCount)
+		flat.PageUnit = m.PageStructure.UnitType
+	}
+	if m.Error != nil {
+		flat.ErrorType, flat.ErrorMessage = m.Error.ErrorType, m.Error.Message
+	}
+	return flat
+}
+
+func decodeRawFields(fields map[string]json.RawMessage, out map[string]any) {
+	for key, raw := range fields {
+		var value any
+		if json.Unmarshal(raw, &value) == nil {
+			out[key] = value
+		}
+	}
+}
+
+func flattenTable(t Table) FlatTable {
+	return FlatTable{Cells: flatSlice(t.Cells, func(row []string) []string { return row }), Markdown: t.Markdown, PageNumber: t.PageNumber}
+}
+
+func flattenChunk(c Chunk) FlatChunk {
+	return FlatChunk{
+		Content:     c.Content,
+		Embedding:   flatSlice(c.Embedding, func(f float32) float32 { return f }),
+		ChunkIndex:  c.Metadata.ChunkIndex,
+		TotalChunks: c.Metadata.TotalChunks,
+		TokenCount:  deref(c.Metadata.TokenCount),
+		ByteStart:   c.Metadata.ByteStart,
+		ByteEnd:     c.Metadata.ByteEnd,
+		FirstPage:   deref(c.Metadata.FirstPage),
+		LastPage:    deref(c.Metadata.LastPage),
+		StartTimeMs: deref(c.Metadata.StartTimeMs),
+		EndTimeMs:   deref(c.Metadata.EndTimeMs),
+	}
+}
+
+func flattenImage(img ExtractedImage) FlatImage {
+	flat := FlatImage{
+		Data:             img.Data,
+		Format:           img.Format,
+		ImageIndex:       img.ImageIndex,
+		PageNumber:       deref(img.PageNumber),
+		Width:            deref(img.Width),
+		Height:           deref(img.Height),
+		Colorspace:       deref(img.Colorspace),
+		BitsPerComponent: deref(img.BitsPerComponent),
+		IsMask:           img.IsMask,
+		Description:      deref(img.Description),
+	}
+	if img.OCRResult != nil {
+		flat.OCRContent = img.OCRResult.Content
+	}
+	return flat
+}
+
+func flattenPage(p PageContent) FlatPage {
+	return FlatPage{
+		PageNumber: p.PageNumber,
+		Content:    p.Content,
+		Tables:     flatSlice(p.Tables, flattenTable),
+		Images:     flatSlice(p.Images, flattenImage),
+	}
+}
+
+// flatSlice maps in to a non-nil slice.
+func flatSlice[T, F any](in []T, f func(T) F) []F {
+	out := make([]F, len(in))
+	for i, v := range in {
+		out[i] = f(v)
+	}
+	return out
+}
+
+func deref[T any](p *T) T {
+	if p == nil {
+		var zero T
+		return zero
+	}
+	return *p
+}
@@ -0,0 +1,46 @@
+package kreuzberg
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestExtractionResultFlattened(t *testing.T) {
+	lang, title, pages, tokens, width := "en", "Report", 3, 12, uint32(640)
+	result := &ExtractionResult{
+		Content:  "text",
+		MimeType: "application/pdf",
+		Success:  true,
+		Metadata: Metadata{
+			Language:   &lang,
+			Format:     FormatMetadata{Type: FormatPDF, Pdf: &PdfMetadata{Title: &title, PageCount: &pages}},
+			Additional: map[string]json.RawMessage{"source": json.RawMessage(`"scan"`)},
+		},
+		Chunks: []Chunk{{Content: "text", Metadata: ChunkMetadata{TokenCount: &tokens, ByteEnd: 4}}},
+		Images: []ExtractedImage{{Format: "png", Width: &width, OCRResult: &ExtractionResult{Content: "caption"}}},
+	}
+
+	flat := result.Flattened()
+	if flat.Metadata.Language != "en" || flat.Metadata.Date != "" || flat.Metadata.Title != "Report" || flat.Metadata.PageCount != 3 {
+		t.Fatalf("unexpected metadata %+v", flat.Metadata)
+	}
+	if flat.Metadata.FormatType != FormatPDF || flat.Metadata.Format["title"] != "Report" || flat.Metadata.Additional["source"] != "scan" {
+		t.Fatalf("unexpected format metadata %+v", flat.Metadata)
+	}
+	if c := flat.Chunks[0]; c.TokenCount != 12 || c.ByteEnd != 4 || c.FirstPage != 0 {
+		t.Fatalf("unexpected chunk %+v", c)
+	}
+	if img := flat.Images[0]; img.Width != 640 || img.Height != 0 || img.OCRContent != "caption" {
+		t.Fatalf("unexpected image %+v", img)
+	}
+	if flat.Tables == nil || flat.Pages == nil || flat.DetectedLanguages == nil || flat.Chunks[0].Embedding == nil {
+		t.Fatalf("expected empty slices rather than nil")
+	}
+
+	var nilResult *ExtractionResult
+	data, err := json.Marshal(nilResult.Flattened())
+	if err != nil || strings.Contains(string(data), "null") {
+		t.Fatalf("expected a zero view without nulls, got %s, %v", data, err)
+	}
+}
@@ -0,0 +1,234 @@
+package kreuzberg
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// FormatFeature names a family of formats whose handlers a Client can disable, so a service
+// that only needs a handful of formats never feeds documents to the parsers of the others.
+type FormatFeature string
+
+const (
+	// FeatureArchives is archive expansion: ZIP, TAR, 7z, RAR and compressed streams.
+	FeatureArchives FormatFeature = "archives"
+	// FeatureSVG is SVG parsing.
+	FeatureSVG FormatFeature = "svg"
+	// FeatureImages is raster images, which are extracted with OCR.
+	FeatureImages FormatFeature = "images"
+	FeaturePDF    FormatFeature = "pdf"
+	// FeatureOffice is Office Open XML, OpenDocument, legacy binary Office, RTF and iWork files.
+	FeatureOffice FormatFeature = "office"
+	// FeatureEmail is email messages and Outlook files.
+	FeatureEmail FormatFeature = "email"
+	// FeatureEbooks is EPUB, Mobipocket and FictionBook files.
+	FeatureEbooks FormatFeature = "ebooks"
+	FeatureHTML   FormatFeature = "html"
+	// FeatureXML is XML documents other than SVG and XHTML, including feeds.
+	FeatureXML FormatFeature = "xml"
+	// FeatureStructured is JSON, YAML and TOML.
+	FeatureStructured FormatFeature = "structured"
+	// FeatureDatabases is SQLite and Access databases.
+	FeatureDatabases FormatFeature = "databases"
+	// FeatureDataFiles is Avro, Parquet and ORC files.
+	FeatureDataFiles FormatFeature = "data_files"
+	// FeatureText is plain text and the other text/* formats: Markdown, CSV, logs and subtitles.
+	FeatureText FormatFeature = "text"
+)
+
+// ErrFormatDisabled is matched (via errors.Is) by the errors of extractions rejected because
+// their format is disabled for the Client.
+var ErrFormatDisabled = errors.New("format disabled")
+
+// formatFeatureTypes maps MIME types to format features. The first match wins, so SVG is
+// matched before the other images.
+var formatFeatureTypes = []struct {
+	feature FormatFeature
+	types   []string
+	// prefixes match MIME types by prefix.
+	prefixes []string
+}{
+	{FeatureSVG, []string{"image/svg+xml"}, nil},
+	{FeatureImages, nil, []string{"image/"}},
+	{FeaturePDF, []string{"application/pdf"}, nil},
+	{FeatureEbooks, []string{"application/epub+zip", "application/x-mobipocket-ebook", "application/x-fictionbook+xml"}, nil},
+	{FeatureArchives, []string{
+		"application/zip", "application/x-zip-compressed", "application/x-tar", "application/gzip", "application/x-gzip",
+		"application/x-7z-compressed", "application/x-rar-compressed", "application/vnd.rar", "application/x-bzip2",
+		"application/x-xz", "application/zstd",
+	}, nil},
+	{FeatureEmail, []string{"application/vnd.ms-outlook"}, []string{"message/"}},
+	{FeatureOffice, []string{"application/msword", "application/rtf", "text/rtf", mimePages, mimeNumbers, mimeKeynote}, []string{
+		"application/vnd.openxmlformats-officedocument.", "application/vnd.oasis.opendocument.", "application/vnd.ms-excel",
+		"application/vnd.ms-powerpoint", "application/vnd.ms-word",
+	}},
+	{FeatureHTML, []string{"text/html", "application/xhtml+xml"}, nil},
+	{FeatureStructured, []string{mimeJSON, mimeNDJSON, mimeYAML, "application/yaml", "text/yaml", "application/toml", "application/x-toml"}, nil},
+	{FeatureDatabases, []string{"application/vnd.sqlite3", "application/x-sqlite3", "application/x-msaccess", "application/vnd.ms-access"}, nil},
+	{FeatureDataFiles, []string{"application/avro", "application/vnd.apache.avro", "application/vnd.apache.parquet", "application/x-parquet", "application/vnd.apache.orc", "application/x-orc"}, nil},
+	{FeatureXML, []string{mimeXML, "text/xml", mimeOPML}, nil},
+	{FeatureText, []string{mimeSRT}, []string{"text/"}},
+}
+
+// formatFeatures lists every FormatFeature.
+var formatFeatures = func() []FormatFeature {
+	features := make([]FormatFeature, len(formatFeatureTypes))
+	for i, entry := range formatFeatureTypes {
+		features[i] = entry.feature
+	}
+	return features
+}()
+
+// Validate reports an error for an unknown feature.
+func (f FormatFeature) Validate() error {
+	if slices.Contains(formatFeatures, f) {
+		return nil
+	}
+	return invalidConfigValue("format feature", string(f), stringValues(formatFeatures))
+}
+
+// FormatFeatureOf returns the format feature handling mimeType, or "" when none does.
+func FormatFeatureOf(mimeType string) FormatFeature {
+	mimeType = strings.ToLower(strings.TrimSpace(strings.SplitN(mimeType, ";", 2)[0]))
+	for _, entry := range formatFeatureTypes {
+		if slices.Contains(entry.types, mimeType) || slices.ContainsFunc(entry.prefixes, func(prefix string) bool { return strings.HasPrefix(mimeType, prefix) }) {
+			return entry.feature
+		}
+	}
+	if strings.HasSuffix(mimeType, "+xml") {
+		return FeatureXML
+	}
+	return ""
+}
+
+// formatFlags holds the format features disabled in a plugin scope. The zero value enables
+// every format.
+type formatFlags struct {
+	mu       sync.RWMutex
+	disabled map[FormatFeature]bool
+	// allowOnly rejects formats that belong to no feature, and documents whose format cannot be
+	// detected.
+	allowOnly bool
+}
+
+// DisableFormats disables the handlers of features for the client's extractions: documents of
+// those formats are rejected with an UnsupportedFormatError matching ErrFormatDisabled before
+// any parser, fallback strategy or cache sees them. Formats are identified by the MIME type
+// passed in or detected, after the client's MIME type overrides.
+//
+// The check applies to the documents passed in; formats nested inside them, such as an archive
+// attached to an email, are handled by the parser of the outer document.
+func (c *Client) DisableFormats(features ...FormatFeature) error {
+	return c.plugins.formats.set(features, true)
+}
+
+// EnableFormats enables the handlers of features disabled by DisableFormats or AllowOnlyFormats.
+func (c *Client) EnableFormats(features ...FormatFeature) error {
+	return c.plugins.formats.set(features, false)
+}
+
+// AllowOnlyFormats disables every format feature except features, as well as formats that
+// belong to no feature and documents whose format cannot be detected. EnableFormats can enable
+// further features afterwards.
+func (c *Client) AllowOnlyFormats(features ...FormatFeature) error {
+	for _, feature := range features {
+		if err := feature.Validate(); err != nil {
+			return err
+		}
+	}
+	f := &c.plugins.formats
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.disabled = map[FormatFeature]bool{}
+	for _, feature := range formatFeatures {
+		if !slices.Contains(features, feature) {
+			f.disabled[feature] = true
+		}
+	}
+	f.allowOnly = true
+	return nil
+}
+
+// DisabledFormats returns the format features disabled for the client, in declaration order.
+func (c *Client) DisabledFormats() []FormatFeature {
+	f := &c.plugins.formats
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	var disabled []FormatFeature
+	for _, feature := range formatFeatures {
+		if f.disabled[feature] {
+			disabled = append(disabled, feature)
+		}
+	}
+	return disabled
+}
+
+func (f *formatFlags) set(features []FormatFeature, disabled bool) error {
+	for _, feature := range features {
+		if err := feature.Validate(); err != nil {
+			return err
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, feature := range features {
+		if disabled {
+			if f.disabled == nil {
+				f.disabled = map[FormatFeature]bool{}
+			}
+			f.disabled[feature] = true
+		} else {
+			delete(f.disabled, feature)
+		}
+	}
+	return nil
+}
+
+// check returns an error matching ErrFormatDisabled when the format of src is disabled.
+func (f *formatFlags) check(src documentSource) error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if len(f.disabled) == 0 && !f.allowOnly {
+		return nil
+	}
+	mimeType := formatMimeType(src)
+	if mimeType == "" {
+		if f.allowOnly {
+			return newUnsupportedFormatErrorWithContext("", "the document format cannot be detected and only allowed formats are extracted", ErrFormatDisabled, ErrorCodeUnsupportedFormat, nil)
+		}
+		return nil
+	}
+	feature := FormatFeatureOf(mimeType)
+	switch {
+	case feature == "" && f.allowOnly:
+		return newUnsupportedFormatErrorWithContext(mimeType, fmt.Sprintf("%s belongs to no allowed format", mimeType), ErrFormatDisabled, ErrorCodeUnsupportedFormat, nil)
+	case f.disabled[feature]:
+		return newUnsupportedFormatErrorWithContext(mimeType, fmt.Sprintf("%s handlers are disabled (%s)", feature, mimeType), ErrFormatDisabled, ErrorCodeUnsupportedFormat, nil)
+	}
+	return nil
+}
+
+// formatMimeType returns the MIME type of src as given or detected, falling back to the
+// extensions the Go extractors claim, or "".
+func formatMimeType(src documentSource) string {
+	if mimeType := src.detectMimeType(); mimeType != "" {
+		return mimeType
+	}
+	if src.path == "" {
+		if mimeType, err := detectMimeTypeNative(src.data); err == nil {
+			return mimeType
+		}
+		return ""
+	}
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(src.path), "."))
+	for _, extractor := range goPrimaryExtractors {
+		if mimeType, ok := extractor.extensions[ext]; ok {
+			return mimeType
+		}
+	}
+	return ""
+}
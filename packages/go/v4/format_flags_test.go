@@ -0,0 +1,101 @@
+package kreuzberg
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestFormatFeatureOf(t *testing.T) {
+	for mimeType, want := range map[string]FormatFeature{
+		"image/svg+xml":                 FeatureSVG,
+		"image/png":                     FeatureImages,
+		"application/zip":               FeatureArchives,
+		"application/epub+zip":          FeatureEbooks,
+		"message/rfc822":                FeatureEmail,
+		mimePages:                       FeatureOffice,
+		"text/html; charset=utf-8":      FeatureHTML,
+		"application/atom+xml":          FeatureXML,
+		mimeJSON:                        FeatureStructured,
+		mimeSRT:                         FeatureText,
+		"text/csv":                      FeatureText,
+		"application/octet-stream":      "",
+		"application/vnd.ms-excel":      FeatureOffice,
+		"application/x-fictionbook+xml": FeatureEbooks,
+	} {
+		if got := FormatFeatureOf(mimeType); got != want {
+			t.Errorf("%s: got %q, want %q", mimeType, got, want)
+		}
+	}
+}
+
+func TestClientDisableFormats(t *testing.T) {
+	client := NewClient(nil)
+	if err := client.DisableFormats(FeatureText, "spreadsheets"); err == nil {
+		t.Fatalf("expected an unknown feature to be rejected")
+	}
+	if len(client.DisabledFormats()) != 0 {
+		t.Fatalf("expected a rejected call to disable nothing")
+	}
+	if err := client.DisableFormats(FeatureText, FeatureArchives); err != nil {
+		t.Fatalf("disable: %v", err)
+	}
+	if got := client.DisabledFormats(); !slices.Equal(got, []FormatFeature{FeatureArchives, FeatureText}) {
+		t.Fatalf("unexpected disabled formats %v", got)
+	}
+
+	_, err := client.ExtractBytes(t.Context(), []byte(testSRT), mimeSRT)
+	var unsupported *UnsupportedFormatError
+	if !errors.Is(err, ErrFormatDisabled) || !errors.As(err, &unsupported) {
+		t.Fatalf("expected the disabled format to be rejected, got %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "talk.srt")
+	os.WriteFile(path, []byte(testSRT), 0o600)
+	if _, err := client.ExtractFile(t.Context(), path); !errors.Is(err, ErrFormatDisabled) {
+		t.Fatalf("expected the disabled file to be rejected, got %v", err)
+	}
+
+	// The flags are scoped to the client.
+	if result, err := ExtractBytesSync([]byte(testSRT), mimeSRT, nil); err != nil || result.Content != wantSRTContent {
+		t.Fatalf("expected package-level extractions to ignore the client's flags, got %v", err)
+	}
+	client.EnableFormats(FeatureText)
+	if result, err := client.ExtractBytes(t.Context(), []byte(testSRT), mimeSRT); err != nil || result.Content != wantSRTContent {
+		t.Fatalf("expected the re-enabled format to extract, got %v", err)
+	}
+
+	// In batches only the items of disabled formats fail.
+	client.DisableFormats(FeatureStructured)
+	items := []BytesWithMime{{Data: []byte(`{"a": 1}`), MimeType: mimeJSON}, {Data: []byte(testSRT), MimeType: mimeSRT}}
+	results, err := client.BatchExtractBytes(t.Context(), items)
+	if err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+	if e := results[0].Metadata.Error; e == nil || e.ErrorType != "UnsupportedFormatError" {
+		t.Fatalf("expected the disabled item to fail, got %+v", results[0])
+	}
+	if results[1].Metadata.Error != nil {
+		t.Fatalf("expected the enabled item to succeed, got %+v", results[1].Metadata.Error)
+	}
+}
+
+func TestClientAllowOnlyFormats(t *testing.T) {
+	client := NewClient(nil)
+	if err := client.AllowOnlyFormats(FeatureText); err != nil {
+		t.Fatalf("allow only: %v", err)
+	}
+	if got := client.DisabledFormats(); len(got) != len(formatFeatures)-1 || slices.Contains(got, FeatureText) {
+		t.Fatalf("unexpected disabled formats %v", got)
+	}
+	if _, err := client.ExtractBytes(t.Context(), []byte(testSRT), mimeSRT); err != nil {
+		t.Fatalf("expected the allowed format to extract, got %v", err)
+	}
+	if _, err := client.ExtractBytes(t.Context(), []byte(`{"a": 1}`), mimeJSON); !errors.Is(err, ErrFormatDisabled) {
+		t.Fatalf("expected other formats to be rejected, got %v", err)
+	}
+	if _, err := client.ExtractBytes(t.Context(), []byte("data"), "application/x-unknown"); !errors.Is(err, ErrFormatDisabled) {
+		t.Fatalf("expected formats belonging to no feature to be rejected, got %v", err)
+	}
+}
@@ -4,3 +4,8 @@ go 1.25
 
 // Local development; remove this section for published releases
 // replace github.com/kreuzberg-dev/kreuzberg => ../../
+
+require (
+	github.com/fsnotify/fsnotify v1.9.0
+	golang.org/x/sys v0.40.0
+)
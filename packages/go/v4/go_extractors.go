@@ -0,0 +1,206 @@
+package kreuzberg
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// goPrimaryExtractor is a built-in extractor implemented in Go. When its config gate is on, it
+// replaces the native extractor for the MIME types it claims, e.g. to stream formats the native
+// library would materialize in memory.
+type goPrimaryExtractor struct {
+	name      string
+	mimeTypes []string
+	// extensions maps file extensions (without the dot) to MIME types, so path-based extractions
+	// can match without native MIME detection.
+	extensions map[string]string
+	// enabled reports whether config opts into this extractor (nil = always enabled).
+	enabled func(config *ExtractionConfig) bool
+	extract fallbackFunc
+}
+
+var goPrimaryExtractors []goPrimaryExtractor
+
+func registerGoPrimaryExtractor(extractor goPrimaryExtractor) {
+	goPrimaryExtractors = append(goPrimaryExtractors, extractor)
+}
+
+// selectGoPrimaryExtractor returns the built-in Go extractor to use for src, together with the
+// resolved MIME type. MIME detection only runs when some extractor is enabled by config.
+func selectGoPrimaryExtractor(src documentSource, config *ExtractionConfig) (*goPrimaryExtractor, string) {
+	var candidates []*goPrimaryExtractor
+	for i := range goPrimaryExtractors {
+		if extractor := &goPrimaryExtractors[i]; extractor.enabled == nil || extractor.enabled(config) {
+			candidates = append(candidates, extractor)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, ""
+	}
+
+	if src.mimeType == "" && src.path != "" {
+		ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(src.path), "."))
+		for _, extractor := range candidates {
+			if mimeType, ok := extractor.extensions[ext]; ok {
+				return extractor, mimeType
+			}
+		}
+		if !anyNeedsDetection(candidates) {
+			return nil, ""
+		}
+	}
+
+	mimeType := src.detectMimeType()
+	for _, extractor := range candidates {
+		if claimsMime(extractor.mimeTypes, mimeType) {
+			return extractor, mimeType
+		}
+	}
+	return nil, ""
+}
+
+// anyNeedsDetection reports whether some candidate can only be matched by detected MIME type.
+func anyNeedsDetection(candidates []*goPrimaryExtractor) bool {
+	for _, extractor := range candidates {
+		if len(extractor.extensions) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// extractPrimary runs the built-in Go extractor selected for src, or the native extractor,
+// serving results from the encrypted result cache when it is configured.
+func extractPrimary(src documentSource, config *ExtractionConfig) (*ExtractionResult, error) {
+	cache, err := openResultCache(config)
+	if err != nil {
+		return nil, err
+	}
+	if cache != nil {
+		return cachedExtract(cache, src, config, func() (*ExtractionResult, error) {
+			return extractPrimaryUncached(src, config)
+		})
+	}
+	return extractPrimaryUncached(src, config)
+}
+
+func extractPrimaryUncached(src documentSource, config *ExtractionConfig) (*ExtractionResult, error) {
+	if mimeType, ok := textEncodingMimeType(src, config); ok {
+		return extractTranscoded(src, mimeType, config)
+	}
+	if err := injectExtractionFault(src); err != nil {
+		return nil, err
+	}
+	if extractor, mimeType := selectGoPrimaryExtractor(src, config); extractor != nil {
+		return extractor.extract(src, mimeType, config)
+	}
+	if ocrAutoTuneApplies(src, config) {
+		return extractAutoTuned(src, config)
+	}
+	return extractNative(src, config)
+}
+
+// extractNative passes src to the native library: by path, unless a MIME type overrides the
+// one the library would detect from it.
+func extractNative(src documentSource, config *ExtractionConfig) (*ExtractionResult, error) {
+	if src.path == "" {
+		return extractBytesNative(src.data, src.mimeType, config)
+	}
+	if src.mimeType == "" {
+		return extractFileNative(src.path, config)
+	}
+	data, err := src.bytes()
+	if err != nil {
+		return nil, err
+	}
+	return extractBytesNative(data, src.mimeType, config)
+}
+
+// batchExtractPrimary extracts the sources claimed by built-in Go extractors in Go and passes
+// the rest, by index, to nativeBatch. Results keep the input order; Go extraction failures are
+// reported per item like native batch failures.
+func batchExtractPrimary(sources []documentSource, config *ExtractionConfig, nativeBatch func(indices []int) ([]*ExtractionResult, error)) ([]*ExtractionResult, error) {
+	cache, err := openResultCache(config)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*ExtractionResult, len(sources))
+	cacheKeys := make([]string, len(sources))
+	documents := make([]string, len(sources))
+	native := make([]int, 0, len(sources))
+	for i, src := range sources {
+		if cache != nil && src.denied == nil {
+			if cacheKeys[i], documents[i], err = cache.keys(src); err == nil {
+				if results[i] = cache.load(cacheKeys[i]); results[i] != nil {
+					cacheKeys[i] = ""
+					continue
+				}
+			}
+		}
+		extractor, mimeType := selectGoPrimaryExtractor(src, config)
+		var result *ExtractionResult
+		err = src.denied
+		if err == nil {
+			err = injectExtractionFault(src)
+		}
+		switch {
+		case err != nil:
+			// Denied documents and injected faults fail the item before anything runs, even one the
+			// native batch handles.
+		case extractor != nil:
+			result, err = extractor.extract(src, mimeType, config)
+		case ocrAutoTuneApplies(src, config):
+			mimeType = src.detectMimeType()
+			result, err = extractAutoTuned(src, config)
+		case src.path != "" && src.mimeType != "":
+			// The native batch takes paths, which would lose the overridden MIME type.
+			mimeType = src.mimeType
+			result, err = extractNative(src, config)
+		default:
+			native = append(native, i)
+			continue
+		}
+		if err != nil {
+			result = &ExtractionResult{
+				MimeType: mimeType,
+				Tables:   []Table{},
+				Metadata: Metadata{Error: &ErrorMetadata{ErrorType: errorTypeName(err), Message: err.Error()}},
+			}
+		}
+		results[i] = result
+	}
+
+	if len(native) > 0 {
+		nativeResults, err := nativeBatch(native)
+		if err != nil {
+			return nil, err
+		}
+		for j, i := range native {
+			if j < len(nativeResults) {
+				results[i] = nativeResults[j]
+			}
+		}
+	}
+	triageBatchItems(sources, results, config)
+	for i, key := range cacheKeys {
+		if key == "" || batchItemError(results[i]) != nil || !cacheable(config, sources[i], results[i]) {
+			continue
+		}
+		if err := cache.storeVersion(key, documents[i], results[i]); err != nil {
+			results[i].addDiagnostic("cache", DiagnosticSeverityWarning, err.Error())
+		}
+	}
+	return results, nil
+}
+
+// errorTypeName returns the error type recorded in ErrorMetadata, e.g. "ParsingError".
+func errorTypeName(err error) string {
+	var kerr KreuzbergError
+	if errors.As(err, &kerr) {
+		name := fmt.Sprintf("%T", kerr)
+		return name[strings.LastIndex(name, ".")+1:]
+	}
+	return "RuntimeError"
+}
@@ -0,0 +1,54 @@
+package kreuzberg
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBatchExtractPrimarySplitsGoAndNativeItems(t *testing.T) {
+	saved := goPrimaryExtractors
+	t.Cleanup(func() { goPrimaryExtractors = saved })
+	goPrimaryExtractors = nil
+	registerGoPrimaryExtractor(goPrimaryExtractor{
+		name:      "test",
+		mimeTypes: []string{"text/x-go-handled"},
+		enabled:   func(config *ExtractionConfig) bool { return true },
+		extract: func(src documentSource, mimeType string, config *ExtractionConfig) (*ExtractionResult, error) {
+			if string(src.data) == "bad" {
+				return nil, newParsingErrorWithContext("cannot parse", nil, ErrorCodeParsing, nil)
+			}
+			return &ExtractionResult{Content: "go:" + string(src.data), Success: true}, nil
+		},
+	})
+
+	sources := []documentSource{
+		{data: []byte("a"), mimeType: "text/x-go-handled"},
+		{data: []byte("b"), mimeType: "text/plain"},
+		{data: []byte("bad"), mimeType: "text/x-go-handled"},
+		{data: []byte("c"), mimeType: "text/plain"},
+	}
+	var nativeIndices []int
+	results, err := batchExtractPrimary(sources, nil, func(indices []int) ([]*ExtractionResult, error) {
+		nativeIndices = indices
+		out := make([]*ExtractionResult, len(indices))
+		for j, i := range indices {
+			out[j] = &ExtractionResult{Content: "native:" + string(sources[i].data), Success: true}
+		}
+		return out, nil
+	})
+	if err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+	if len(nativeIndices) != 2 || nativeIndices[0] != 1 || nativeIndices[1] != 3 {
+		t.Fatalf("unexpected native indices: %v", nativeIndices)
+	}
+	if results[0].Content != "go:a" || results[1].Content != "native:b" || results[3].Content != "native:c" {
+		t.Fatalf("results out of order: %v", results)
+	}
+	if itemErr := batchItemError(results[2]); itemErr == nil || results[2].Metadata.Error.ErrorType != "ParsingError" {
+		t.Fatalf("expected failed Go item to be reported per item, got %+v", results[2].Metadata.Error)
+	}
+	if errorTypeName(errors.New("plain")) != "RuntimeError" {
+		t.Fatalf("non-kreuzberg errors should be reported as RuntimeError")
+	}
+}
@@ -0,0 +1,287 @@
+package kreuzberg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultGoogleDriveBaseURL = "https://www.googleapis.com/drive/v3"
+	googleAppsPrefix          = "application/vnd.google-apps."
+	googleAppsShortcut        = googleAppsPrefix + "shortcut"
+	googleDriveMaxAttempts    = 3
+)
+
+// defaultGoogleExportMimeTypes maps Google Workspace types to the formats they are exported as.
+var defaultGoogleExportMimeTypes = map[string]string{
+	googleAppsPrefix + "document":     "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	googleAppsPrefix + "spreadsheet":  "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	googleAppsPrefix + "presentation": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	googleAppsPrefix + "drawing":      "application/pdf",
+}
+
+// GoogleDriveConfig configures ExtractGoogleDriveFile.
+type GoogleDriveConfig struct {
+	// HTTPClient sends the Drive API requests (default http.DefaultClient). Pass a client that
+	// authorizes requests, such as one built with golang.org/x/oauth2, or set AccessToken.
+	HTTPClient *http.Client
+	// AccessToken is sent as an OAuth 2.0 bearer token when set.
+	AccessToken string
+	// ExportMimeTypes overrides the export format per Google Workspace MIME type, e.g.
+	// "application/vnd.google-apps.spreadsheet" to "text/csv".
+	ExportMimeTypes map[string]string
+	// MaxBytes limits the size of the downloaded file (0 = unlimited).
+	MaxBytes int64
+	// Extraction configures extraction of the downloaded file.
+	Extraction *ExtractionConfig
+	// BaseURL overrides the Drive API v3 endpoint.
+	BaseURL string
+}
+
+// GoogleDriveFile describes the Drive file an extraction result was produced from. It is
+// reported in Metadata.Additional["google_drive"].
+type GoogleDriveFile struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	MimeType     string `json:"mime_type"`
+	ModifiedTime string `json:"modified_time,omitempty"`
+	WebViewLink  string `json:"web_view_link,omitempty"`
+	// ExportMimeType is the format a Google Workspace file was exported as.
+	ExportMimeType string `json:"export_mime_type,omitempty"`
+	// ShortcutID is the ID of the shortcut that was resolved to this file, if any.
+	ShortcutID string `json:"shortcut_id,omitempty"`
+}
+
+type googleDriveFileResource struct {
+	ID              string            `json:"id"`
+	Name            string            `json:"name"`
+	MimeType        string            `json:"mimeType"`
+	ModifiedTime    string            `json:"modifiedTime"`
+	WebViewLink     string            `json:"webViewLink"`
+	ExportLinks     map[string]string `json:"exportLinks"`
+	ShortcutDetails *struct {
+		TargetID string `json:"targetId"`
+	} `json:"shortcutDetails"`
+}
+
+// googleDriveError is the error body returned by Google APIs.
+type googleDriveError struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Errors  []struct {
+			Reason string `json:"reason"`
+		} `json:"errors"`
+	} `json:"error"`
+}
+
+type googleDriveAPI struct {
+	cfg     *GoogleDriveConfig
+	client  *http.Client
+	baseURL string
+}
+
+func newGoogleDriveAPI(cfg *GoogleDriveConfig) *googleDriveAPI {
+	api := &googleDriveAPI{cfg: cfg, client: cfg.HTTPClient, baseURL: strings.TrimRight(cfg.BaseURL, "/")}
+	if api.client == nil {
+		api.client = http.DefaultClient
+	}
+	if api.baseURL == "" {
+		api.baseURL = defaultGoogleDriveBaseURL
+	}
+	return api
+}
+
+// get performs a GET request, retrying rate-limited and server errors. It returns the response
+// body and, for API errors, the first error reason reported by Drive.
+func (api *googleDriveAPI) get(ctx context.Context, rawURL string, limit int64) ([]byte, string, error) {
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+		if err != nil {
+			return nil, "", newValidationErrorWithContext("invalid Google Drive request", err, ErrorCodeValidation, nil)
+		}
+		if api.cfg.AccessToken != "" {
+			req.Header.Set("Authorization", "Bearer "+api.cfg.AccessToken)
+		}
+		release, err := AcquireDependency(ctx, DependencyGoogleDrive)
+		if err != nil {
+			return nil, "", err
+		}
+		resp, err := api.client.Do(req)
+		if err != nil {
+			release()
+			return nil, "", newIOErrorWithContext("Google Drive request failed", err, ErrorCodeIo, nil)
+		}
+		body, err := readLimited(resp.Body, limit)
+		resp.Body.Close()
+		release()
+		if err != nil {
+			return nil, "", err
+		}
+		if resp.StatusCode < 300 {
+			return body, "", nil
+		}
+
+		var apiErr googleDriveError
+		_ = json.Unmarshal(body, &apiErr)
+		reason := ""
+		if len(apiErr.Error.Errors) > 0 {
+			reason = apiErr.Error.Errors[0].Reason
+		}
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 ||
+			reason == "rateLimitExceeded" || reason == "userRateLimitExceeded"
+		if retryable && attempt < googleDriveMaxAttempts {
+			delay := time.Duration(attempt) * time.Second
+			if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+				delay = time.Duration(seconds) * time.Second
+			}
+			select {
+			case <-ctx.Done():
+				return nil, "", ctx.Err()
+			case <-time.After(delay):
+			}
+			continue
+		}
+
+		message := apiErr.Error.Message
+		if message == "" {
+			message = http.StatusText(resp.StatusCode)
+		}
+		return nil, reason, newIOErrorWithContext(fmt.Sprintf("Google Drive API error (HTTP %d): %s", resp.StatusCode, message), nil, ErrorCodeIo, nil)
+	}
+}
+
+// readLimited reads r, failing when it is longer than limit bytes (0 = unlimited).
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	if limit > 0 {
+		r = io.LimitReader(r, limit+1)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, newIOErrorWithContext("failed to read Google Drive response", err, ErrorCodeIo, nil)
+	}
+	if limit > 0 && int64(len(data)) > limit {
+		return nil, newValidationErrorWithContext(fmt.Sprintf("Google Drive file exceeds the %d byte limit", limit), nil, ErrorCodeValidation, nil)
+	}
+	return data, nil
+}
+
+func (api *googleDriveAPI) file(ctx context.Context, fileID string) (*googleDriveFileResource, error) {
+	query := url.Values{
+		"fields":            {"id,name,mimeType,modifiedTime,webViewLink,exportLinks,shortcutDetails"},
+		"supportsAllDrives": {"true"},
+	}
+	body, _, err := api.get(ctx, api.baseURL+"/files/"+url.PathEscape(fileID)+"?"+query.Encode(), 0)
+	if err != nil {
+		return nil, err
+	}
+	var file googleDriveFileResource
+	if err := json.Unmarshal(body, &file); err != nil {
+		return nil, newParsingErrorWithContext("failed to decode Google Drive file metadata", err, ErrorCodeParsing, nil)
+	}
+	return &file, nil
+}
+
+// download fetches the content of a Drive file, exporting Google Workspace files. Exports larger
+// than the export endpoint allows are retried through the file's export link.
+func (api *googleDriveAPI) download(ctx context.Context, file *googleDriveFileResource) ([]byte, string, error) {
+	if !strings.HasPrefix(file.MimeType, googleAppsPrefix) {
+		query := url.Values{"alt": {"media"}, "supportsAllDrives": {"true"}}
+		data, _, err := api.get(ctx, api.baseURL+"/files/"+url.PathEscape(file.ID)+"?"+query.Encode(), api.cfg.MaxBytes)
+		return data, "", err
+	}
+
+	exportMime := api.cfg.ExportMimeTypes[file.MimeType]
+	if exportMime == "" {
+		exportMime = defaultGoogleExportMimeTypes[file.MimeType]
+	}
+	if exportMime == "" {
+		return nil, "", newUnsupportedFormatErrorWithContext(file.MimeType, fmt.Sprintf("Google Drive files of type %s cannot be exported for extraction", file.MimeType), nil, ErrorCodeUnsupportedFormat, nil)
+	}
+	query := url.Values{"mimeType": {exportMime}}
+	data, reason, err := api.get(ctx, api.baseURL+"/files/"+url.PathEscape(file.ID)+"/export?"+query.Encode(), api.cfg.MaxBytes)
+	if reason == "exportSizeLimitExceeded" && file.ExportLinks[exportMime] != "" {
+		data, _, err = api.get(ctx, file.ExportLinks[exportMime], api.cfg.MaxBytes)
+	}
+	return data, exportMime, err
+}
+
+// ExtractGoogleDriveFile downloads a Google Drive file and extracts it. Docs, Sheets, Slides and
+// Drawings are exported to DOCX, XLSX, PPTX and PDF respectively (see
+// GoogleDriveConfig.ExportMimeTypes); shortcuts are resolved to their target. The Drive file is
+// described in Metadata.Additional["google_drive"].
+func ExtractGoogleDriveFile(ctx context.Context, fileID string, cfg *GoogleDriveConfig) (*ExtractionResult, error) {
+	return extractGoogleDriveFile(ctx, defaultPluginRegistry, fileID, cfg, nil)
+}
+
+// ExtractGoogleDriveFile downloads and extracts a Google Drive file using the client's config and
+// plugins. cfg.Extraction, when set, overrides the client's config.
+func (c *Client) ExtractGoogleDriveFile(ctx context.Context, fileID string, cfg *GoogleDriveConfig) (*ExtractionResult, error) {
+	return extractGoogleDriveFile(ctx, c.plugins, fileID, cfg, c.config)
+}
+
+func extractGoogleDriveFile(ctx context.Context, plugins *pluginRegistry, fileID string, cfg *GoogleDriveConfig, fallbackConfig *ExtractionConfig) (*ExtractionResult, error) {
+	if fileID == "" {
+		return nil, newValidationErrorWithContext("Google Drive file ID cannot be empty", nil, ErrorCodeValidation, nil)
+	}
+	if cfg == nil {
+		cfg = &GoogleDriveConfig{}
+	}
+	api := newGoogleDriveAPI(cfg)
+
+	file, err := api.file(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	info := GoogleDriveFile{}
+	if file.MimeType == googleAppsShortcut {
+		if file.ShortcutDetails == nil || file.ShortcutDetails.TargetID == "" {
+			return nil, newValidationErrorWithContext(fmt.Sprintf("Google Drive shortcut %s has no target", fileID), nil, ErrorCodeValidation, nil)
+		}
+		info.ShortcutID = file.ID
+		if file, err = api.file(ctx, file.ShortcutDetails.TargetID); err != nil {
+			return nil, err
+		}
+	}
+	info.ID, info.Name, info.MimeType = file.ID, file.Name, file.MimeType
+	info.ModifiedTime, info.WebViewLink = file.ModifiedTime, file.WebViewLink
+
+	data, exportMime, err := api.download(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+	info.ExportMimeType = exportMime
+	mimeType := file.MimeType
+	if exportMime != "" {
+		mimeType = exportMime
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	config := cfg.Extraction
+	if config == nil {
+		config = fallbackConfig
+	}
+	result, err := extractBytes(ctx, plugins, data, mimeType, config)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := json.Marshal(info)
+	if err != nil {
+		return nil, newSerializationErrorWithContext("failed to encode Google Drive metadata", err, ErrorCodeValidation, nil)
+	}
+	if result.Metadata.Additional == nil {
+		result.Metadata.Additional = map[string]json.RawMessage{}
+	}
+	result.Metadata.Additional["google_drive"] = raw
+	return result, nil
+}
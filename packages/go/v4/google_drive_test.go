@@ -0,0 +1,123 @@
+package kreuzberg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeDrive serves the subset of the Drive v3 API used by ExtractGoogleDriveFile.
+func fakeDrive(t *testing.T, files map[string]string, media map[string]string) *httptest.Server {
+	t.Helper()
+	throttled := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"code":401,"message":"Invalid Credentials","errors":[{"reason":"authError"}]}}`))
+			return
+		}
+		switch {
+		case r.URL.Path == "/files/sheet/export":
+			if r.URL.Query().Get("mimeType") != "text/csv" {
+				t.Errorf("unexpected export format %q", r.URL.Query().Get("mimeType"))
+			}
+			if !throttled {
+				throttled = true
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.Write([]byte("name,qty\napples,3\n"))
+		case r.URL.Query().Get("alt") == "media":
+			body, ok := media[r.URL.Path[len("/files/"):]]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(body))
+		default:
+			body, ok := files[r.URL.Path[len("/files/"):]]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"error":{"code":404,"message":"File not found: x.","errors":[{"reason":"notFound"}]}}`))
+				return
+			}
+			w.Write([]byte(body))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestExtractGoogleDriveFileExportsWorkspaceFiles(t *testing.T) {
+	server := fakeDrive(t, map[string]string{
+		"link":  `{"id":"link","mimeType":"application/vnd.google-apps.shortcut","shortcutDetails":{"targetId":"sheet"}}`,
+		"sheet": `{"id":"sheet","name":"Inventory","mimeType":"application/vnd.google-apps.spreadsheet","modifiedTime":"2025-01-01T00:00:00Z"}`,
+	}, nil)
+
+	cfg := &GoogleDriveConfig{
+		AccessToken:     "secret",
+		BaseURL:         server.URL,
+		ExportMimeTypes: map[string]string{"application/vnd.google-apps.spreadsheet": "text/csv"},
+		Extraction:      &ExtractionConfig{CSV: &CSVConfig{}},
+	}
+	result, err := ExtractGoogleDriveFile(context.Background(), "link", cfg)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if len(result.Tables) != 1 || result.Tables[0].Cells[1][0] != "apples" {
+		t.Fatalf("unexpected tables: %+v", result.Tables)
+	}
+	var info GoogleDriveFile
+	if err := json.Unmarshal(result.Metadata.Additional["google_drive"], &info); err != nil {
+		t.Fatalf("decode metadata: %v", err)
+	}
+	want := GoogleDriveFile{ID: "sheet", Name: "Inventory", MimeType: "application/vnd.google-apps.spreadsheet", ModifiedTime: "2025-01-01T00:00:00Z", ExportMimeType: "text/csv", ShortcutID: "link"}
+	if info != want {
+		t.Fatalf("metadata = %+v, want %+v", info, want)
+	}
+}
+
+func TestExtractGoogleDriveFileDownloadsBinaryFiles(t *testing.T) {
+	server := fakeDrive(t, map[string]string{
+		"subs": `{"id":"subs","name":"talk.srt","mimeType":"application/x-subrip"}`,
+		"form": `{"id":"form","name":"Survey","mimeType":"application/vnd.google-apps.form"}`,
+	}, map[string]string{"subs": testSRT})
+
+	client := NewClient(nil)
+	cfg := &GoogleDriveConfig{AccessToken: "secret", BaseURL: server.URL}
+	result, err := client.ExtractGoogleDriveFile(context.Background(), "subs", cfg)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if result.Content != "Hello there.\n\nGeneral Kenobi!\nYou are a bold one." {
+		t.Fatalf("unexpected content: %q", result.Content)
+	}
+
+	_, err = ExtractGoogleDriveFile(context.Background(), "form", cfg)
+	var unsupported *UnsupportedFormatError
+	if !errors.As(err, &unsupported) {
+		t.Fatalf("expected UnsupportedFormatError for forms, got %v", err)
+	}
+	if _, err := ExtractGoogleDriveFile(context.Background(), "subs", &GoogleDriveConfig{BaseURL: server.URL, MaxBytes: 10, AccessToken: "secret"}); err == nil {
+		t.Fatalf("expected size limit error")
+	}
+}
+
+func TestExtractGoogleDriveFileReportsAPIErrors(t *testing.T) {
+	server := fakeDrive(t, nil, nil)
+	_, err := ExtractGoogleDriveFile(context.Background(), "missing", &GoogleDriveConfig{AccessToken: "secret", BaseURL: server.URL})
+	var ioErr *IOError
+	if !errors.As(err, &ioErr) || ioErr.Error() == "" {
+		t.Fatalf("expected IOError, got %v", err)
+	}
+	if _, err := ExtractGoogleDriveFile(context.Background(), "missing", &GoogleDriveConfig{BaseURL: server.URL}); err == nil {
+		t.Fatalf("expected authorization error")
+	}
+	if _, err := ExtractGoogleDriveFile(context.Background(), "", nil); err == nil {
+		t.Fatalf("expected validation error for empty file ID")
+	}
+}
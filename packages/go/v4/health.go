@@ -0,0 +1,204 @@
+package kreuzberg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+const defaultHealthTimeout = 5 * time.Second
+
+// HealthCheck is a named readiness check.
+type HealthCheck struct {
+	Name string
+	// Optional checks are reported but do not make the report fail.
+	Optional bool
+	// Check returns nil when healthy. It should honor ctx, which carries HealthOptions.Timeout.
+	Check func(ctx context.Context) error
+}
+
+// HealthOptions configures ReadinessHandler and CheckReadiness.
+type HealthOptions struct {
+	// Config is the extraction config the process serves. When it enables the encrypted result
+	// cache, readiness requires the cache key to resolve and the cache directory to be writable.
+	Config *ExtractionConfig
+	// Dependencies lists executables that must be on PATH, e.g. "soffice".
+	Dependencies []string
+	// OptionalDependencies lists executables that are reported but not required.
+	OptionalDependencies []string
+	// Checks are additional checks, e.g. Coordinator.HealthCheck.
+	Checks []HealthCheck
+	// Timeout bounds each check (default 5 seconds).
+	Timeout time.Duration
+}
+
+// HealthStatus is the outcome of a check or of a whole report.
+type HealthStatus string
+
+const (
+	HealthStatusOK   HealthStatus = "ok"
+	HealthStatusFail HealthStatus = "fail"
+)
+
+// HealthCheckResult is the outcome of one check.
+type HealthCheckResult struct {
+	Name       string       `json:"name"`
+	Status     HealthStatus `json:"status"`
+	Optional   bool         `json:"optional,omitempty"`
+	Error      string       `json:"error,omitempty"`
+	DurationMS int64        `json:"duration_ms"`
+}
+
+// HealthReport is the body served by ReadinessHandler and LivenessHandler. Status is "fail" when
+// any required check failed.
+type HealthReport struct {
+	Status HealthStatus        `json:"status"`
+	Checks []HealthCheckResult `json:"checks"`
+}
+
+// OK reports whether every required check passed.
+func (r HealthReport) OK() bool {
+	return r.Status == HealthStatusOK
+}
+
+// nativeLibraryCheck verifies that the native library is loaded and answers calls.
+func nativeLibraryCheck() HealthCheck {
+	return HealthCheck{Name: "native_library", Check: func(context.Context) error {
+		if LibraryVersion() == "" {
+			return errors.New("native library did not report a version")
+		}
+		return nil
+	}}
+}
+
+// dependencyCheck verifies that an executable is on PATH.
+func dependencyCheck(name string, optional bool) HealthCheck {
+	return HealthCheck{Name: "dependency:" + name, Optional: optional, Check: func(context.Context) error {
+		if err := injectMissingDependency(name); err != nil {
+			return err
+		}
+		_, err := exec.LookPath(name)
+		return err
+	}}
+}
+
+// resultCacheCheck verifies that the encrypted result cache configured by config can be opened
+// and written.
+func resultCacheCheck(config *ExtractionConfig) HealthCheck {
+	return HealthCheck{Name: "result_cache", Check: func(context.Context) error {
+		cache, err := openResultCache(config)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(cache.dir, 0o700); err != nil {
+			return newCacheErrorWithContext("failed to create cache directory", err, ErrorCodeIo, nil)
+		}
+		probe, err := os.CreateTemp(cache.dir, ".health-*")
+		if err != nil {
+			return newCacheErrorWithContext("cache directory is not writable", err, ErrorCodeIo, nil)
+		}
+		probe.Close()
+		return os.Remove(probe.Name())
+	}}
+}
+
+// CheckReadiness runs the native library check, the checks configured by opts (nil for none)
+// and opts.Checks concurrently.
+func CheckReadiness(ctx context.Context, opts *HealthOptions) HealthReport {
+	var o HealthOptions
+	if opts != nil {
+		o = *opts
+	}
+	checks := []HealthCheck{nativeLibraryCheck()}
+	for _, name := range o.Dependencies {
+		checks = append(checks, dependencyCheck(name, false))
+	}
+	for _, name := range o.OptionalDependencies {
+		checks = append(checks, dependencyCheck(name, true))
+	}
+	if cacheEncryptionActive(o.Config) {
+		checks = append(checks, resultCacheCheck(o.Config))
+	}
+	checks = append(checks, o.Checks...)
+	return runHealthChecks(ctx, checks, o.Timeout)
+}
+
+// runHealthChecks runs checks concurrently, each bounded by timeout. A check that does not return
+// in time is reported as failed; it is left running, so checks should honor their context.
+func runHealthChecks(ctx context.Context, checks []HealthCheck, timeout time.Duration) HealthReport {
+	if timeout <= 0 {
+		timeout = defaultHealthTimeout
+	}
+	report := HealthReport{Status: HealthStatusOK, Checks: make([]HealthCheckResult, len(checks))}
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Go(func() {
+			report.Checks[i] = runHealthCheck(ctx, check, timeout)
+		})
+	}
+	wg.Wait()
+	for _, result := range report.Checks {
+		if result.Status != HealthStatusOK && !result.Optional {
+			report.Status = HealthStatusFail
+		}
+	}
+	return report
+}
+
+func runHealthCheck(ctx context.Context, check HealthCheck, timeout time.Duration) HealthCheckResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("check panicked: %v", r)
+			}
+		}()
+		done <- check.Check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	result := HealthCheckResult{Name: check.Name, Status: HealthStatusOK, Optional: check.Optional, DurationMS: time.Since(start).Milliseconds()}
+	if err != nil {
+		result.Status, result.Error = HealthStatusFail, err.Error()
+	}
+	return result
+}
+
+// ReadinessHandler serves CheckReadiness as JSON for a Kubernetes readiness probe: 200 when every
+// required check passes and 503 otherwise. opts may be nil.
+func ReadinessHandler(opts *HealthOptions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveHealthReport(w, CheckReadiness(r.Context(), opts))
+	})
+}
+
+// LivenessHandler serves a Kubernetes liveness probe. It only checks that the native library
+// answers calls, so a hung library restarts the pod while missing dependencies or an unreachable
+// cache (see ReadinessHandler) merely take it out of rotation.
+func LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveHealthReport(w, runHealthChecks(r.Context(), []HealthCheck{nativeLibraryCheck()}, 0))
+	})
+}
+
+func serveHealthReport(w http.ResponseWriter, report HealthReport) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if !report.OK() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
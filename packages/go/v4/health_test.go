@@ -0,0 +1,106 @@
+package kreuzberg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckReadiness(t *testing.T) {
+	nativeOK := LibraryVersion() != ""
+	report := CheckReadiness(t.Context(), &HealthOptions{
+		OptionalDependencies: []string{"kreuzberg-no-such-binary"},
+		Checks: []HealthCheck{
+			{Name: "ok", Check: func(context.Context) error { return nil }},
+			{Name: "flaky", Optional: true, Check: func(context.Context) error { return errors.New("degraded") }},
+		},
+	})
+	want := map[string]HealthStatus{"native_library": HealthStatusFail, "dependency:kreuzberg-no-such-binary": HealthStatusFail, "ok": HealthStatusOK, "flaky": HealthStatusFail}
+	if nativeOK {
+		want["native_library"] = HealthStatusOK
+	}
+	if len(report.Checks) != len(want) {
+		t.Fatalf("unexpected checks: %+v", report.Checks)
+	}
+	for _, check := range report.Checks {
+		if check.Status != want[check.Name] {
+			t.Fatalf("unexpected result for %s: %+v", check.Name, check)
+		}
+	}
+	if report.OK() != nativeOK {
+		t.Fatalf("expected only required checks to decide the status, got %+v", report)
+	}
+
+	report = CheckReadiness(t.Context(), &HealthOptions{
+		Timeout: 10 * time.Millisecond,
+		Checks:  []HealthCheck{{Name: "hung", Check: func(ctx context.Context) error { <-ctx.Done(); time.Sleep(time.Second); return nil }}},
+	})
+	if hung := report.Checks[1]; hung.Status != HealthStatusFail || hung.Error != context.DeadlineExceeded.Error() || report.OK() {
+		t.Fatalf("expected a hung check to time out, got %+v", report)
+	}
+}
+
+func TestResultCacheCheck(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	config := &ExtractionConfig{CacheEncryption: &CacheEncryptionConfig{Key: make([]byte, 32), KeyID: "k1", Dir: dir}}
+	if err := resultCacheCheck(config).Check(t.Context()); err != nil {
+		t.Fatalf("expected a writable cache to pass, got %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("expected the probe to be removed, got %v", entries)
+	}
+
+	config.CacheEncryption.KeyProvider = func(string) ([]byte, error) { return nil, errors.New("kms unavailable") }
+	if err := resultCacheCheck(config).Check(t.Context()); err == nil {
+		t.Fatalf("expected an unresolvable key to fail")
+	}
+}
+
+func TestHealthHandlers(t *testing.T) {
+	failing := &HealthOptions{Checks: []HealthCheck{{Name: "down", Check: func(context.Context) error { return errors.New("down") }}}}
+	rec := httptest.NewRecorder()
+	ReadinessHandler(failing).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var report HealthReport
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if rec.Code != http.StatusServiceUnavailable || report.Status != HealthStatusFail || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected readiness reply %d: %+v", rec.Code, report)
+	}
+
+	rec = httptest.NewRecorder()
+	LivenessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	wantCode := http.StatusOK
+	if LibraryVersion() == "" {
+		wantCode = http.StatusServiceUnavailable
+	}
+	if rec.Code != wantCode {
+		t.Fatalf("unexpected liveness status %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestCoordinatorHealthCheck(t *testing.T) {
+	coordinator, err := NewCoordinator([]string{"a.srt"}, nil)
+	if err != nil {
+		t.Fatalf("new coordinator: %v", err)
+	}
+	if err := coordinator.HealthCheck(time.Minute).Check(t.Context()); err != nil {
+		t.Fatalf("expected a fresh coordinator to be healthy, got %v", err)
+	}
+	coordinator.progress = time.Now().Add(-2 * time.Minute)
+	if err := coordinator.HealthCheck(time.Minute).Check(t.Context()); err == nil {
+		t.Fatalf("expected a stalled coordinator to fail")
+	}
+	lease := coordinator.lease("w", 1, time.Now())
+	coordinator.complete(completionRequest{Worker: "w", Index: 0, Lease: lease.Tasks[0].Lease, Result: &ExtractionResult{}})
+	coordinator.progress = time.Now().Add(-2 * time.Minute)
+	if err := coordinator.HealthCheck(time.Minute).Check(t.Context()); err != nil {
+		t.Fatalf("expected a finished coordinator to be healthy, got %v", err)
+	}
+}
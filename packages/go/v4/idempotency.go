@@ -0,0 +1,301 @@
+package kreuzberg
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IdempotencyKeyHeader is the request header IdempotencyMiddleware reads the key from.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+const (
+	defaultIdempotencyTTL = 24 * time.Hour
+	maxIdempotencyKeyLen  = 255
+	idempotencyMagic      = "kreuzberg-idempotency"
+)
+
+// IdempotencyConfig configures how extractions carrying an idempotency key (see
+// WithIdempotencyKey) are deduplicated.
+type IdempotencyConfig struct {
+	// Store holds the results of keyed extractions. By default they are sealed into the
+	// encrypted result cache when CacheEncryption is active, so every process sharing the cache
+	// directory deduplicates against them, and kept in a process-wide memory store otherwise.
+	Store IdempotencyStore
+	// TTL is how long a stored result is replayed for its key (default 24 hours). Later
+	// requests with the key extract again.
+	TTL time.Duration
+}
+
+// IdempotencyRecord is the stored outcome of a keyed extraction.
+type IdempotencyRecord struct {
+	// Fingerprint identifies the request: a digest of the document and the extraction config.
+	Fingerprint string `json:"fingerprint"`
+	// Result is the extraction result replayed for the key.
+	Result *ExtractionResult `json:"result"`
+	// Created is when the result was stored.
+	Created time.Time `json:"created"`
+}
+
+// IdempotencyStore stores the results of keyed extractions, e.g. in Redis or a database shared
+// by every server and queue consumer. Implementations must be safe for concurrent use.
+type IdempotencyStore interface {
+	// Load returns the record stored for key, or nil when there is none.
+	Load(ctx context.Context, key string) (*IdempotencyRecord, error)
+	// Store records the outcome of the extraction for key, replacing any earlier record.
+	Store(ctx context.Context, key string, record *IdempotencyRecord) error
+}
+
+// MemoryIdempotencyStore is an IdempotencyStore in process memory. Records older than its TTL are
+// dropped as new ones are stored.
+type MemoryIdempotencyStore struct {
+	ttl       time.Duration
+	mu        sync.Mutex
+	records   map[string]*IdempotencyRecord
+	nextSweep int
+}
+
+// NewMemoryIdempotencyStore returns an empty memory store that keeps records for ttl (default 24
+// hours).
+func NewMemoryIdempotencyStore(ttl time.Duration) *MemoryIdempotencyStore {
+	if ttl <= 0 {
+		ttl = defaultIdempotencyTTL
+	}
+	return &MemoryIdempotencyStore{ttl: ttl, records: map[string]*IdempotencyRecord{}, nextSweep: 1024}
+}
+
+// Load implements IdempotencyStore.
+func (s *MemoryIdempotencyStore) Load(_ context.Context, key string) (*IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record := s.records[key]
+	if record == nil || time.Since(record.Created) >= s.ttl {
+		return nil, nil
+	}
+	return record, nil
+}
+
+// Store implements IdempotencyStore.
+func (s *MemoryIdempotencyStore) Store(_ context.Context, key string, record *IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[key] = record
+	if len(s.records) >= s.nextSweep {
+		for k, r := range s.records {
+			if time.Since(r.Created) >= s.ttl {
+				delete(s.records, k)
+			}
+		}
+		s.nextSweep = max(1024, 2*len(s.records))
+	}
+	return nil
+}
+
+// defaultIdempotencyStore backs keyed extractions whose config names no store and does not
+// encrypt the cache.
+var defaultIdempotencyStore = NewMemoryIdempotencyStore(defaultIdempotencyTTL)
+
+// cacheIdempotencyStore seals records into the "idempotency" directory of the encrypted result
+// cache. Expired records stay on disk until their key is reused.
+type cacheIdempotencyStore struct {
+	cache *resultCache
+}
+
+// entry returns the file holding the record for key and the ID its ciphertext is bound to.
+func (s cacheIdempotencyStore) entry(key string) (path, id string) {
+	sum := sha256.Sum256([]byte(idempotencyMagic + "\x00" + key))
+	id = hex.EncodeToString(sum[:])
+	return filepath.Join(s.cache.dir, "idempotency", id[:2], id+".kzc"), id
+}
+
+func (s cacheIdempotencyStore) Load(_ context.Context, key string) (*IdempotencyRecord, error) {
+	plaintext := s.cache.open(s.entry(key))
+	if plaintext == nil {
+		return nil, nil
+	}
+	var record IdempotencyRecord
+	if err := json.Unmarshal(plaintext, &record); err != nil {
+		return nil, nil
+	}
+	return &record, nil
+}
+
+func (s cacheIdempotencyStore) Store(_ context.Context, key string, record *IdempotencyRecord) error {
+	plaintext, err := json.Marshal(record)
+	if err != nil {
+		return newSerializationErrorWithContext("failed to encode idempotency record", err, ErrorCodeValidation, nil)
+	}
+	path, id := s.entry(key)
+	return s.cache.seal(path, id, plaintext)
+}
+
+type idempotencyKeyContext struct{}
+
+// WithIdempotencyKey returns a context that deduplicates single-document extractions started with
+// it by the caller-supplied key, e.g. a queue message ID or an Idempotency-Key header. The first
+// successful extraction with a key is stored (see IdempotencyConfig) and replayed to later
+// requests with the same key, so retried deliveries do not extract again; concurrent requests
+// with the key wait for the first one. Reusing a key for a different document or config fails
+// with a ValidationError. Failed extractions are not stored, so a retry extracts again.
+//
+// Keys apply to ExtractFileWithContext, ExtractBytesWithContext and the Client methods that take
+// a context; batch functions ignore them. Keys are at most 255 bytes. gRPC and queue consumers
+// wrap the request context with the key from their metadata or message; HTTP handlers can use
+// IdempotencyMiddleware.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyContext{}, key)
+}
+
+// IdempotencyKeyFromContext returns the idempotency key carried by ctx, if any.
+func IdempotencyKeyFromContext(ctx context.Context) (string, bool) {
+	key, _ := ctx.Value(idempotencyKeyContext{}).(string)
+	return key, key != ""
+}
+
+// IdempotencyMiddleware attaches the Idempotency-Key header of each request to its context (see
+// WithIdempotencyKey), for extraction handlers served with RunService or any other server.
+// Requests with a key longer than 255 bytes are rejected with 400 Bad Request.
+func IdempotencyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimSpace(r.Header.Get(IdempotencyKeyHeader))
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			http.Error(w, fmt.Sprintf("%s is longer than %d bytes", IdempotencyKeyHeader, maxIdempotencyKeyLen), http.StatusBadRequest)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithIdempotencyKey(r.Context(), key)))
+	})
+}
+
+// idempotencyFlight is a keyed extraction in progress; requests with the same key wait for it.
+type idempotencyFlight struct {
+	done        chan struct{}
+	fingerprint string
+	result      *ExtractionResult
+	err         error
+}
+
+var (
+	idempotencyFlightsMu sync.Mutex
+	idempotencyFlights   = map[string]*idempotencyFlight{}
+)
+
+// extractIdempotent runs extract for src once per idempotency key, replaying the stored result
+// to later requests with the key. extract receives a context without the key.
+func extractIdempotent(ctx context.Context, key string, src documentSource, config *ExtractionConfig, extract func(context.Context) (*ExtractionResult, error)) (*ExtractionResult, error) {
+	if len(key) > maxIdempotencyKeyLen {
+		return nil, newValidationErrorWithContext(fmt.Sprintf("idempotency key is longer than %d bytes", maxIdempotencyKeyLen), nil, ErrorCodeValidation, nil)
+	}
+	inner := WithIdempotencyKey(ctx, "")
+	fingerprint, err := idempotencyFingerprint(src, config)
+	if err != nil {
+		// An unreadable document fails the extraction itself, with its usual error.
+		return extract(inner)
+	}
+	store, ttl, err := idempotencyStoreFor(config)
+	if err != nil {
+		return nil, err
+	}
+
+	idempotencyFlightsMu.Lock()
+	if flight := idempotencyFlights[key]; flight != nil {
+		idempotencyFlightsMu.Unlock()
+		select {
+		case <-flight.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if flight.fingerprint != fingerprint {
+			return nil, idempotencyConflict(key)
+		}
+		return cloneResult(flight.result), flight.err
+	}
+	flight := &idempotencyFlight{done: make(chan struct{}), fingerprint: fingerprint}
+	idempotencyFlights[key] = flight
+	idempotencyFlightsMu.Unlock()
+	defer func() {
+		idempotencyFlightsMu.Lock()
+		delete(idempotencyFlights, key)
+		idempotencyFlightsMu.Unlock()
+		close(flight.done)
+	}()
+
+	record, loadErr := store.Load(ctx, key)
+	if loadErr == nil && record != nil && record.Result != nil && time.Since(record.Created) < ttl {
+		if record.Fingerprint != fingerprint {
+			flight.err = idempotencyConflict(key)
+			return nil, flight.err
+		}
+		flight.result = record.Result
+		return cloneResult(record.Result), nil
+	}
+
+	result, err := extract(inner)
+	if err != nil {
+		flight.err = err
+		return nil, err
+	}
+	flight.result = cloneResult(result)
+	if loadErr != nil {
+		result.addDiagnostic("idempotency", DiagnosticSeverityWarning, fmt.Sprintf("failed to look up idempotency key: %v", loadErr))
+	} else if err := store.Store(ctx, key, &IdempotencyRecord{Fingerprint: fingerprint, Result: flight.result, Created: time.Now()}); err != nil {
+		result.addDiagnostic("idempotency", DiagnosticSeverityWarning, fmt.Sprintf("failed to store idempotency record: %v", err))
+	}
+	return result, nil
+}
+
+func idempotencyConflict(key string) error {
+	return newValidationErrorWithContext(fmt.Sprintf("idempotency key %q was already used for a different document or config", key), nil, ErrorCodeValidation, nil)
+}
+
+// idempotencyStoreFor returns the store and TTL that config selects for keyed extractions.
+func idempotencyStoreFor(config *ExtractionConfig) (IdempotencyStore, time.Duration, error) {
+	ttl := defaultIdempotencyTTL
+	if config != nil && config.Idempotency != nil {
+		if config.Idempotency.TTL > 0 {
+			ttl = config.Idempotency.TTL
+		}
+		if config.Idempotency.Store != nil {
+			return config.Idempotency.Store, ttl, nil
+		}
+	}
+	cache, err := openResultCache(config)
+	if err != nil {
+		return nil, 0, err
+	}
+	if cache != nil {
+		return cacheIdempotencyStore{cache: cache}, ttl, nil
+	}
+	return defaultIdempotencyStore, ttl, nil
+}
+
+// idempotencyFingerprint digests the document, its MIME type or file extension and the config,
+// so a key reused for a different request is detected.
+func idempotencyFingerprint(src documentSource, config *ExtractionConfig) (string, error) {
+	configKey, err := configDigest(idempotencyMagic, config)
+	if err != nil {
+		return "", err
+	}
+	data, err := src.bytes()
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256(data)
+	h := sha256.New()
+	for _, part := range []string{string(configKey), src.mimeType, strings.ToLower(filepath.Ext(src.path)), string(digest[:])} {
+		binary.Write(h, binary.BigEndian, uint64(len(part)))
+		h.Write([]byte(part))
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,128 @@
+package kreuzberg
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingIdempotencyStore is a memory store that counts the records it stores.
+type countingIdempotencyStore struct {
+	*MemoryIdempotencyStore
+	mu     sync.Mutex
+	stores int
+}
+
+func (s *countingIdempotencyStore) Store(ctx context.Context, key string, record *IdempotencyRecord) error {
+	s.mu.Lock()
+	s.stores++
+	s.mu.Unlock()
+	return s.MemoryIdempotencyStore.Store(ctx, key, record)
+}
+
+func TestExtractIdempotent(t *testing.T) {
+	store := &countingIdempotencyStore{MemoryIdempotencyStore: NewMemoryIdempotencyStore(time.Hour)}
+	client := NewClient(&ExtractionConfig{Idempotency: &IdempotencyConfig{Store: store}})
+	ctx := WithIdempotencyKey(t.Context(), "msg-1")
+
+	first, err := client.ExtractBytes(ctx, []byte(testSRT), mimeSRT)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	first.Content = "changed by the caller"
+
+	// Retried and concurrent deliveries replay the stored result.
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := client.ExtractBytes(ctx, []byte(testSRT), mimeSRT)
+			if err != nil || result.Content == "changed by the caller" || result.Content == "" {
+				t.Errorf("unexpected replay %+v, %v", result, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if store.stores != 1 {
+		t.Fatalf("expected one stored extraction, got %d", store.stores)
+	}
+
+	var validationErr *ValidationError
+	if _, err := client.ExtractBytes(ctx, []byte(testSRT+"\n3\n00:00:09,000 --> 00:00:10,000\nMore\n"), mimeSRT); !errors.As(err, &validationErr) {
+		t.Fatalf("expected a reused key to be rejected, got %v", err)
+	}
+
+	if _, err := client.ExtractBytes(WithIdempotencyKey(t.Context(), "msg-2"), []byte(testSRT), mimeSRT); err != nil || store.stores != 2 {
+		t.Fatalf("expected a new key to extract, got %d stores, %v", store.stores, err)
+	}
+	if _, err := client.ExtractBytes(t.Context(), []byte(testSRT), mimeSRT); err != nil || store.stores != 2 {
+		t.Fatalf("expected unkeyed extractions to bypass the store, got %d stores, %v", store.stores, err)
+	}
+
+	// Failed extractions are not stored.
+	if _, err := client.ExtractBytes(WithIdempotencyKey(t.Context(), "msg-3"), nil, mimeSRT); err == nil || store.stores != 2 {
+		t.Fatalf("expected the failure to be returned and not stored, got %d stores, %v", store.stores, err)
+	}
+}
+
+func TestExtractIdempotentEncryptedCache(t *testing.T) {
+	dir := t.TempDir()
+	config := &ExtractionConfig{CacheEncryption: &CacheEncryptionConfig{Key: make([]byte, 32), KeyID: "k1", Dir: dir}}
+	ctx := WithIdempotencyKey(t.Context(), "delivery-7")
+	first, err := ExtractBytesWithContext(ctx, []byte(testSRT), mimeSRT, config)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+
+	store, _, err := idempotencyStoreFor(config)
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	record, err := store.Load(t.Context(), "delivery-7")
+	if err != nil || record == nil || record.Result.Content != first.Content {
+		t.Fatalf("expected the record in the encrypted cache, got %+v, %v", record, err)
+	}
+
+	// Another process sharing the cache directory but holding a different key sees a miss.
+	other := &ExtractionConfig{CacheEncryption: &CacheEncryptionConfig{Key: []byte(strings.Repeat("x", 32)), KeyID: "k2", Dir: dir}}
+	store, _, _ = idempotencyStoreFor(other)
+	if record, err := store.Load(t.Context(), "delivery-7"); err != nil || record != nil {
+		t.Fatalf("expected a miss without the key, got %+v, %v", record, err)
+	}
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	var got string
+	handler := IdempotencyMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = IdempotencyKeyFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/extract", nil)
+	req.Header.Set(IdempotencyKeyHeader, " abc ")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if got != "abc" {
+		t.Fatalf("unexpected key %q", got)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/extract", nil)
+	req.Header.Set(IdempotencyKeyHeader, strings.Repeat("k", 256))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected an oversized key to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestMemoryIdempotencyStoreExpires(t *testing.T) {
+	store := NewMemoryIdempotencyStore(time.Minute)
+	store.Store(t.Context(), "old", &IdempotencyRecord{Created: time.Now().Add(-2 * time.Minute)})
+	if record, _ := store.Load(t.Context(), "old"); record != nil {
+		t.Fatalf("expected an expired record to be ignored")
+	}
+}
@@ -0,0 +1,629 @@
+package kreuzberg
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// IMAPSecurity selects how the connection to an IMAP server is secured.
+type IMAPSecurity string
+
+const (
+	// IMAPSecurityTLS connects over implicit TLS (port 993). This is the default.
+	IMAPSecurityTLS IMAPSecurity = ""
+	// IMAPSecurityStartTLS upgrades a plaintext connection with STARTTLS (port 143).
+	IMAPSecurityStartTLS IMAPSecurity = "starttls"
+	// IMAPSecurityNone uses an unencrypted connection. Only use it for local servers.
+	IMAPSecurityNone IMAPSecurity = "none"
+)
+
+const imapMaxMIMEDepth = 16
+
+// imapMaxLiteralBytes caps the literals read from the server when IMAPServer.MaxMessageBytes is
+// unlimited, so a hostile server cannot announce an arbitrarily large literal.
+const imapMaxLiteralBytes = 1 << 30
+
+// IMAPServer configures the IMAP connection used by ExtractIMAPFolder.
+type IMAPServer struct {
+	// Address is the server's host:port. The port defaults to 993, or 143 without implicit TLS.
+	Address string
+	// Security selects implicit TLS, STARTTLS or plaintext.
+	Security IMAPSecurity
+	// TLSConfig overrides the TLS configuration; ServerName defaults to the address's host.
+	TLSConfig *tls.Config
+	// MaxMessages limits the number of messages fetched per call (0 = unlimited). Remaining
+	// messages are picked up by the next call from IMAPFolderResult.LastUID.
+	MaxMessages int
+	// MaxMessageBytes skips messages larger than this many bytes (0 = unlimited). Literals larger
+	// than it, or than 1 GiB when unlimited, fail the connection.
+	MaxMessageBytes int64
+	// Extraction configures extraction of messages and attachments.
+	Extraction *ExtractionConfig
+}
+
+// IMAPCredentials authenticates an IMAP session. When AccessToken is set, the session
+// authenticates with SASL XOAUTH2 (Gmail, Microsoft 365); otherwise with LOGIN.
+type IMAPCredentials struct {
+	Username    string
+	Password    string
+	AccessToken string
+}
+
+// IMAPCheckpoint records how far a folder has been read. The zero value reads the whole folder.
+type IMAPCheckpoint struct {
+	// UIDValidity is the folder's UIDVALIDITY when the checkpoint was taken (0 = unknown).
+	UIDValidity uint32
+	// LastUID is the highest UID that was processed.
+	LastUID uint32
+}
+
+// IMAPFolderResult is the outcome of ExtractIMAPFolder.
+type IMAPFolderResult struct {
+	// Folder is the mailbox that was read.
+	Folder string
+	// UIDValidity identifies the mailbox's UID numbering.
+	UIDValidity uint32
+	// LastUID is the highest UID that was processed, or the checkpoint's LastUID when there were
+	// no new messages.
+	LastUID uint32
+	// Reset reports that the folder's UIDVALIDITY differed from the checkpoint's, so its UIDs
+	// were reassigned and the folder was re-read from UID 0.
+	Reset bool
+	// Remaining is the number of newer messages left out because of IMAPServer.MaxMessages.
+	Remaining int
+	// Messages lists the fetched messages in UID order.
+	Messages []IMAPMessage
+}
+
+// IMAPMessage is an extracted message.
+type IMAPMessage struct {
+	UID          uint32
+	InternalDate time.Time
+	Size         int
+	// Result is the extraction result for the message (nil when it could not be extracted). The
+	// IMAP source is described in Metadata.Additional["imap"].
+	Result *ExtractionResult
+	// Attachments lists the message's attachments, each extracted separately.
+	Attachments []IMAPAttachment
+	// Err reports why the message could not be fetched or extracted.
+	Err error
+}
+
+// IMAPAttachment is an extracted email attachment.
+type IMAPAttachment struct {
+	Filename string
+	MimeType string
+	Size     int
+	Result   *ExtractionResult
+	Err      error
+}
+
+// imapSource describes where a message was read from. It is reported in
+// Metadata.Additional["imap"].
+type imapSource struct {
+	Folder       string `json:"folder"`
+	UID          uint32 `json:"uid"`
+	UIDValidity  uint32 `json:"uid_validity"`
+	InternalDate string `json:"internal_date,omitempty"`
+}
+
+// Checkpoint returns the checkpoint to pass to the next ExtractIMAPFolder call.
+func (r *IMAPFolderResult) Checkpoint() IMAPCheckpoint {
+	return IMAPCheckpoint{UIDValidity: r.UIDValidity, LastUID: r.LastUID}
+}
+
+// ExtractIMAPFolder fetches the messages in folder with a UID greater than since.LastUID and
+// extracts them, including their attachments. The folder is opened read-only, so messages are
+// not marked as seen. Store IMAPFolderResult.Checkpoint and pass it on the next run to only
+// process new mail. When the folder's UIDVALIDITY changed since the checkpoint, its UIDs are no
+// longer comparable and the whole folder is read again (IMAPFolderResult.Reset).
+//
+// Failures to extract individual messages are reported in IMAPMessage.Err. When the connection
+// fails part way, the messages processed so far are returned together with the error, and the
+// result's Checkpoint can still be used.
+func ExtractIMAPFolder(ctx context.Context, server IMAPServer, creds IMAPCredentials, folder string, since IMAPCheckpoint) (*IMAPFolderResult, error) {
+	return extractIMAPFolder(ctx, defaultPluginRegistry, server, creds, folder, since, nil)
+}
+
+// ExtractIMAPFolder fetches and extracts new messages using the client's config and plugins.
+// server.Extraction, when set, overrides the client's config.
+func (c *Client) ExtractIMAPFolder(ctx context.Context, server IMAPServer, creds IMAPCredentials, folder string, since IMAPCheckpoint) (*IMAPFolderResult, error) {
+	return extractIMAPFolder(ctx, c.plugins, server, creds, folder, since, c.config)
+}
+
+func extractIMAPFolder(ctx context.Context, plugins *pluginRegistry, server IMAPServer, creds IMAPCredentials, folder string, since IMAPCheckpoint, fallbackConfig *ExtractionConfig) (*IMAPFolderResult, error) {
+	if folder == "" {
+		return nil, newValidationErrorWithContext("IMAP folder cannot be empty", nil, ErrorCodeValidation, nil)
+	}
+	config := server.Extraction
+	if config == nil {
+		config = fallbackConfig
+	}
+
+	conn, err := dialIMAP(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.close()
+	stop := context.AfterFunc(ctx, func() { conn.conn.Close() })
+	defer stop()
+	wrap := func(err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return err
+	}
+
+	if err := conn.login(creds); err != nil {
+		return nil, wrap(err)
+	}
+	result := &IMAPFolderResult{Folder: folder, LastUID: since.LastUID}
+	if result.UIDValidity, err = conn.examine(folder); err != nil {
+		return nil, wrap(err)
+	}
+	if since.UIDValidity != 0 && since.UIDValidity != result.UIDValidity {
+		result.Reset, result.LastUID = true, 0
+	}
+	uids, err := conn.searchSince(result.LastUID)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	if server.MaxMessages > 0 && len(uids) > server.MaxMessages {
+		result.Remaining = len(uids) - server.MaxMessages
+		uids = uids[:server.MaxMessages]
+	}
+
+	for _, uid := range uids {
+		msg, raw, found, err := conn.fetch(uid, server.MaxMessageBytes)
+		if err != nil {
+			return result, wrap(err)
+		}
+		result.LastUID = uid
+		if !found {
+			// Expunged since the search.
+			continue
+		}
+		if msg.Err == nil {
+			extractIMAPMessage(ctx, plugins, &msg, raw, imapSource{Folder: folder, UID: uid, UIDValidity: result.UIDValidity}, config)
+		}
+		result.Messages = append(result.Messages, msg)
+	}
+	conn.logout()
+	return result, nil
+}
+
+// extractIMAPMessage extracts a fetched message and each of its attachments.
+func extractIMAPMessage(ctx context.Context, plugins *pluginRegistry, msg *IMAPMessage, raw []byte, source imapSource, config *ExtractionConfig) {
+	if !msg.InternalDate.IsZero() {
+		source.InternalDate = msg.InternalDate.UTC().Format(time.RFC3339)
+	}
+
+	result, err := extractBytes(ctx, plugins, raw, "message/rfc822", config)
+	if err == nil {
+		var info json.RawMessage
+		if info, err = json.Marshal(source); err != nil {
+			err = newSerializationErrorWithContext("failed to encode IMAP metadata", err, ErrorCodeValidation, nil)
+		} else {
+			if result.Metadata.Additional == nil {
+				result.Metadata.Additional = map[string]json.RawMessage{}
+			}
+			result.Metadata.Additional["imap"] = info
+		}
+	}
+	msg.Result, msg.Err = result, err
+
+	parts, err := emailAttachments(raw)
+	if err != nil {
+		msg.Err = errors.Join(msg.Err, err)
+	}
+	for _, part := range parts {
+		att := IMAPAttachment{Filename: part.filename, MimeType: part.mimeType, Size: len(part.data)}
+		att.Result, att.Err = extractBytes(ctx, plugins, part.data, part.mimeType, config)
+		msg.Attachments = append(msg.Attachments, att)
+	}
+}
+
+// emailPart is a decoded attachment of a MIME message.
+type emailPart struct {
+	filename string
+	mimeType string
+	data     []byte
+}
+
+// emailAttachments returns the attachments of a MIME message. Forwarded messages are returned as
+// message/rfc822 attachments rather than searched for attachments of their own.
+func emailAttachments(raw []byte) ([]emailPart, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, newParsingErrorWithContext("failed to parse email message", err, ErrorCodeParsing, nil)
+	}
+	var parts []emailPart
+	err = collectEmailAttachments(textproto.MIMEHeader(msg.Header), msg.Body, 0, &parts)
+	return parts, err
+}
+
+func collectEmailAttachments(header textproto.MIMEHeader, body io.Reader, depth int, parts *[]emailPart) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		if depth >= imapMaxMIMEDepth {
+			return nil
+		}
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return newParsingErrorWithContext("failed to parse multipart email body", err, ErrorCodeParsing, nil)
+			}
+			if err := collectEmailAttachments(part.Header, part, depth+1, parts); err != nil {
+				return err
+			}
+		}
+	}
+
+	disposition, dispParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	filename := dispParams["filename"]
+	if filename == "" {
+		filename = params["name"]
+	}
+	if decoded, err := new(mime.WordDecoder).DecodeHeader(filename); err == nil {
+		filename = decoded
+	}
+	if disposition != "attachment" && (disposition == "inline" || filename == "") {
+		return nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(header.Get("Content-Transfer-Encoding"))) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return newParsingErrorWithContext(fmt.Sprintf("failed to decode attachment %q", filename), err, ErrorCodeParsing, nil)
+	}
+	if mediaType == "application/octet-stream" || mediaType == "" {
+		if byExt, _, err := mime.ParseMediaType(mime.TypeByExtension(path.Ext(filename))); err == nil {
+			mediaType = byExt
+		}
+	}
+	*parts = append(*parts, emailPart{filename: filename, mimeType: mediaType, data: data})
+	return nil
+}
+
+// imapConn is a minimal IMAP4rev1 client covering the commands needed to read a mailbox.
+type imapConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	tag  int
+	// maxLiteral is the largest literal the server may send, in bytes.
+	maxLiteral int64
+}
+
+// imapResponse is an untagged server response with the literals it contained.
+type imapResponse struct {
+	text     string
+	literals [][]byte
+}
+
+func dialIMAP(ctx context.Context, server IMAPServer) (*imapConn, error) {
+	address := server.Address
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		port := "993"
+		if server.Security != IMAPSecurityTLS {
+			port = "143"
+		}
+		address = net.JoinHostPort(address, port)
+	}
+	host, _, _ := net.SplitHostPort(address)
+	tlsConfig := &tls.Config{}
+	if server.TLSConfig != nil {
+		tlsConfig = server.TLSConfig.Clone()
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = host
+	}
+
+	var dialer net.Dialer
+	raw, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, newIOErrorWithContext(fmt.Sprintf("failed to connect to IMAP server %s", address), err, ErrorCodeIo, nil)
+	}
+	if server.Security == IMAPSecurityTLS {
+		tlsConn := tls.Client(raw, tlsConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			raw.Close()
+			return nil, newIOErrorWithContext("IMAP TLS handshake failed", err, ErrorCodeIo, nil)
+		}
+		raw = tlsConn
+	}
+	c := &imapConn{conn: raw, r: bufio.NewReader(raw), maxLiteral: imapMaxLiteralBytes}
+	if server.MaxMessageBytes > 0 {
+		c.maxLiteral = server.MaxMessageBytes
+	}
+
+	greeting, err := c.readResponse()
+	if err == nil && !strings.HasPrefix(greeting.text, "* OK") && !strings.HasPrefix(greeting.text, "* PREAUTH") {
+		err = newIOErrorWithContext("IMAP server refused the connection: "+greeting.text, nil, ErrorCodeIo, nil)
+	}
+	if err == nil && server.Security == IMAPSecurityStartTLS {
+		if _, err = c.command("STARTTLS"); err == nil {
+			tlsConn := tls.Client(raw, tlsConfig)
+			if err = tlsConn.HandshakeContext(ctx); err != nil {
+				err = newIOErrorWithContext("IMAP STARTTLS handshake failed", err, ErrorCodeIo, nil)
+			}
+			c.conn, c.r = tlsConn, bufio.NewReader(tlsConn)
+		}
+	}
+	if err != nil {
+		raw.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *imapConn) close() error {
+	return c.conn.Close()
+}
+
+// readResponse reads one response line, including any literals ({n} followed by n bytes) it
+// contains. Literals above maxLiteral are rejected, and the others are buffered as they arrive
+// rather than allocated at their announced size.
+func (c *imapConn) readResponse() (imapResponse, error) {
+	var resp imapResponse
+	var text strings.Builder
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return resp, newIOErrorWithContext("failed to read IMAP response", err, ErrorCodeIo, nil)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		text.WriteString(line)
+		size, ok := imapLiteralSize(line)
+		if !ok {
+			resp.text = text.String()
+			return resp, nil
+		}
+		if size > c.maxLiteral {
+			return resp, newIOErrorWithContext(fmt.Sprintf("IMAP literal of %d bytes exceeds the %d byte limit", size, c.maxLiteral), nil, ErrorCodeIo, nil)
+		}
+		var literal bytes.Buffer
+		if _, err := io.CopyN(&literal, c.r, size); err != nil {
+			return resp, newIOErrorWithContext("failed to read IMAP literal", err, ErrorCodeIo, nil)
+		}
+		resp.literals = append(resp.literals, literal.Bytes())
+	}
+}
+
+// imapLiteralSize reports whether line ends with a literal announcement such as "{1234}".
+func imapLiteralSize(line string) (int64, bool) {
+	if !strings.HasSuffix(line, "}") {
+		return 0, false
+	}
+	open := strings.LastIndexByte(line, '{')
+	if open < 0 {
+		return 0, false
+	}
+	size, err := strconv.ParseInt(line[open+1:len(line)-1], 10, 64)
+	return size, err == nil && size >= 0
+}
+
+// command sends a command and returns its untagged responses, failing unless the server
+// completes it with OK. Continuation requests are answered with an empty line, which cancels
+// SASL exchanges that would otherwise wait for more input.
+func (c *imapConn) command(format string, args ...any) ([]imapResponse, error) {
+	c.tag++
+	tag := "k" + strconv.Itoa(c.tag)
+	cmd := fmt.Sprintf(format, args...)
+	if _, err := io.WriteString(c.conn, tag+" "+cmd+"\r\n"); err != nil {
+		return nil, newIOErrorWithContext("failed to send IMAP command", err, ErrorCodeIo, nil)
+	}
+	verb, _, _ := strings.Cut(cmd, " ")
+
+	var untagged []imapResponse
+	for {
+		resp, err := c.readResponse()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case strings.HasPrefix(resp.text, "+"):
+			if _, err := io.WriteString(c.conn, "\r\n"); err != nil {
+				return nil, newIOErrorWithContext("failed to send IMAP continuation", err, ErrorCodeIo, nil)
+			}
+		case strings.HasPrefix(resp.text, tag+" "):
+			status := strings.TrimPrefix(resp.text, tag+" ")
+			if !strings.HasPrefix(strings.ToUpper(status), "OK") {
+				return nil, newIOErrorWithContext(fmt.Sprintf("IMAP %s failed: %s", verb, status), nil, ErrorCodeIo, nil)
+			}
+			return untagged, nil
+		default:
+			untagged = append(untagged, resp)
+		}
+	}
+}
+
+func (c *imapConn) login(creds IMAPCredentials) error {
+	if creds.AccessToken != "" {
+		token := "user=" + creds.Username + "\x01auth=Bearer " + creds.AccessToken + "\x01\x01"
+		_, err := c.command("AUTHENTICATE XOAUTH2 %s", base64.StdEncoding.EncodeToString([]byte(token)))
+		return err
+	}
+	user, err := imapQuote(creds.Username)
+	if err != nil {
+		return err
+	}
+	pass, err := imapQuote(creds.Password)
+	if err != nil {
+		return err
+	}
+	_, err = c.command("LOGIN %s %s", user, pass)
+	return err
+}
+
+var imapUIDValidity = regexp.MustCompile(`(?i)\[UIDVALIDITY (\d+)\]`)
+
+// examine opens folder read-only and returns its UIDVALIDITY.
+func (c *imapConn) examine(folder string) (uint32, error) {
+	name, err := imapQuote(imapUTF7(folder))
+	if err != nil {
+		return 0, err
+	}
+	responses, err := c.command("EXAMINE %s", name)
+	if err != nil {
+		return 0, err
+	}
+	for _, resp := range responses {
+		if m := imapUIDValidity.FindStringSubmatch(resp.text); m != nil {
+			v, _ := strconv.ParseUint(m[1], 10, 32)
+			return uint32(v), nil
+		}
+	}
+	return 0, nil
+}
+
+// searchSince returns the UIDs greater than since, in ascending order.
+func (c *imapConn) searchSince(since uint32) ([]uint32, error) {
+	responses, err := c.command("UID SEARCH UID %d:*", uint64(since)+1)
+	if err != nil {
+		return nil, err
+	}
+	var uids []uint32
+	for _, resp := range responses {
+		fields := strings.Fields(resp.text)
+		if len(fields) < 2 || !strings.EqualFold(fields[1], "SEARCH") {
+			continue
+		}
+		for _, f := range fields[2:] {
+			// "n:*" always matches the newest message, even when its UID is below n.
+			if uid, err := strconv.ParseUint(f, 10, 32); err == nil && uint32(uid) > since {
+				uids = append(uids, uint32(uid))
+			}
+		}
+	}
+	sort.Slice(uids, func(i, j int) bool { return uids[i] < uids[j] })
+	return uids, nil
+}
+
+var (
+	imapInternalDate = regexp.MustCompile(`(?i)INTERNALDATE "([^"]+)"`)
+	imapMessageSize  = regexp.MustCompile(`(?i)RFC822\.SIZE (\d+)`)
+)
+
+// fetch downloads a message without setting \Seen. found is false when the message no longer
+// exists. Messages larger than limit bytes are returned with Err set and no content.
+func (c *imapConn) fetch(uid uint32, limit int64) (IMAPMessage, []byte, bool, error) {
+	msg := IMAPMessage{UID: uid}
+	if limit > 0 {
+		responses, err := c.command("UID FETCH %d (UID RFC822.SIZE)", uid)
+		if err != nil {
+			return msg, nil, false, err
+		}
+		found := false
+		for _, resp := range responses {
+			if m := imapMessageSize.FindStringSubmatch(resp.text); m != nil {
+				found = true
+				msg.Size, _ = strconv.Atoi(m[1])
+			}
+		}
+		if !found {
+			return msg, nil, false, nil
+		}
+		if int64(msg.Size) > limit {
+			msg.Err = newValidationErrorWithContext(fmt.Sprintf("IMAP message %d is %d bytes, above the %d byte limit", uid, msg.Size, limit), nil, ErrorCodeValidation, nil)
+			return msg, nil, true, nil
+		}
+	}
+
+	responses, err := c.command("UID FETCH %d (UID INTERNALDATE BODY.PEEK[])", uid)
+	if err != nil {
+		return msg, nil, false, err
+	}
+	for _, resp := range responses {
+		if !strings.Contains(strings.ToUpper(resp.text), "FETCH") || len(resp.literals) == 0 {
+			continue
+		}
+		if m := imapInternalDate.FindStringSubmatch(resp.text); m != nil {
+			if t, err := time.Parse("_2-Jan-2006 15:04:05 -0700", m[1]); err == nil {
+				msg.InternalDate = t
+			}
+		}
+		raw := resp.literals[len(resp.literals)-1]
+		msg.Size = len(raw)
+		return msg, raw, true, nil
+	}
+	return msg, nil, false, nil
+}
+
+func (c *imapConn) logout() {
+	_, _ = c.command("LOGOUT")
+}
+
+// imapQuote returns s as an IMAP quoted string.
+func imapQuote(s string) (string, error) {
+	if strings.ContainsAny(s, "\r\n\x00") {
+		return "", newValidationErrorWithContext("IMAP strings cannot contain line breaks or NUL bytes", nil, ErrorCodeValidation, nil)
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`, nil
+}
+
+// imapUTF7 encodes a mailbox name in the modified UTF-7 of RFC 3501 section 5.1.3.
+func imapUTF7(name string) string {
+	var b strings.Builder
+	var run []rune
+	flush := func() {
+		if len(run) == 0 {
+			return
+		}
+		units := utf16.Encode(run)
+		buf := make([]byte, 2*len(units))
+		for i, u := range units {
+			binary.BigEndian.PutUint16(buf[2*i:], u)
+		}
+		b.WriteByte('&')
+		b.WriteString(strings.ReplaceAll(base64.RawStdEncoding.EncodeToString(buf), "/", ","))
+		b.WriteByte('-')
+		run = run[:0]
+	}
+	for _, r := range name {
+		if r < 0x20 || r > 0x7e {
+			run = append(run, r)
+			continue
+		}
+		flush()
+		if r == '&' {
+			b.WriteString("&-")
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	flush()
+	return b.String()
+}
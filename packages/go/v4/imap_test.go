@@ -0,0 +1,206 @@
+package kreuzberg
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeIMAP serves a read-only INBOX holding messages keyed by UID.
+func fakeIMAP(t *testing.T, messages map[uint32]string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	var uids []uint32
+	for uid := range messages {
+		uids = append(uids, uid)
+	}
+	sort.Slice(uids, func(i, j int) bool { return uids[i] < uids[j] })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveIMAP(t, conn, uids, messages)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func serveIMAP(t *testing.T, conn net.Conn, uids []uint32, messages map[uint32]string) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	fmt.Fprint(conn, "* OK fake IMAP ready\r\n")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		tag, cmd, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+		switch {
+		case cmd == `LOGIN "ann" "p\"w"`:
+			fmt.Fprintf(conn, "%s OK logged in\r\n", tag)
+		case strings.HasPrefix(cmd, "AUTHENTICATE XOAUTH2 "):
+			want := base64.StdEncoding.EncodeToString([]byte("user=ann\x01auth=Bearer tok\x01\x01"))
+			if strings.TrimPrefix(cmd, "AUTHENTICATE XOAUTH2 ") != want {
+				fmt.Fprint(conn, "+ eyJzdGF0dXMiOiI0MDAifQ==\r\n")
+				r.ReadString('\n')
+				fmt.Fprintf(conn, "%s NO [AUTHENTICATIONFAILED] invalid token\r\n", tag)
+				continue
+			}
+			fmt.Fprintf(conn, "%s OK authenticated\r\n", tag)
+		case strings.HasPrefix(cmd, "LOGIN "):
+			fmt.Fprintf(conn, "%s NO [AUTHENTICATIONFAILED] invalid credentials\r\n", tag)
+		case cmd == `EXAMINE "INBOX"`:
+			fmt.Fprintf(conn, "* %d EXISTS\r\n* OK [UIDVALIDITY 7] UIDs valid\r\n%s OK [READ-ONLY] done\r\n", len(uids), tag)
+		case strings.HasPrefix(cmd, "UID SEARCH UID "):
+			from, _ := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(cmd, "UID SEARCH UID "), ":*"), 10, 32)
+			var found []string
+			for _, uid := range uids {
+				if uint64(uid) >= from {
+					found = append(found, strconv.Itoa(int(uid)))
+				}
+			}
+			if len(found) == 0 {
+				found = []string{strconv.Itoa(int(uids[len(uids)-1]))}
+			}
+			fmt.Fprintf(conn, "* SEARCH %s\r\n%s OK search done\r\n", strings.Join(found, " "), tag)
+		case strings.HasPrefix(cmd, "UID FETCH "):
+			fields := strings.Fields(cmd)
+			uid, _ := strconv.ParseUint(fields[2], 10, 32)
+			body := messages[uint32(uid)]
+			if strings.Contains(cmd, "RFC822.SIZE") {
+				fmt.Fprintf(conn, "* 1 FETCH (UID %d RFC822.SIZE %d)\r\n", uid, len(body))
+			} else {
+				fmt.Fprintf(conn, "* 1 FETCH (UID %d INTERNALDATE \" 5-Mar-2025 09:30:00 +0100\" BODY[] {%d}\r\n%s)\r\n", uid, len(body), body)
+			}
+			fmt.Fprintf(conn, "%s OK fetch done\r\n", tag)
+		case cmd == "LOGOUT":
+			fmt.Fprintf(conn, "* BYE\r\n%s OK bye\r\n", tag)
+			return
+		default:
+			t.Errorf("unexpected IMAP command %q", cmd)
+			fmt.Fprintf(conn, "%s BAD unknown command\r\n", tag)
+		}
+	}
+}
+
+func testEmail(subject string) string {
+	return "From: Bob <bob@example.com>\r\nTo: ann@example.com\r\nSubject: " + subject + "\r\n" +
+		"MIME-Version: 1.0\r\nContent-Type: multipart/mixed; boundary=\"outer\"\r\n\r\n" +
+		"--outer\r\nContent-Type: multipart/alternative; boundary=\"inner\"\r\n\r\n" +
+		"--inner\r\nContent-Type: text/plain\r\n\r\nSee attached.\r\n--inner--\r\n" +
+		"--outer\r\nContent-Type: application/x-subrip; name=\"talk.srt\"\r\nContent-Disposition: attachment; filename=\"=?utf-8?q?t=C3=A4lk.srt?=\"\r\n" +
+		"Content-Transfer-Encoding: base64\r\n\r\n" + base64.StdEncoding.EncodeToString([]byte(testSRT)) + "\r\n" +
+		"--outer\r\nContent-Type: image/png\r\nContent-Disposition: inline; filename=\"logo.png\"\r\n\r\npng\r\n" +
+		"--outer--\r\n"
+}
+
+func TestExtractIMAPFolderResumesFromCheckpoint(t *testing.T) {
+	addr := fakeIMAP(t, map[uint32]string{3: testEmail("one"), 5: testEmail("two"), 9: testEmail("three")})
+	server := IMAPServer{Address: addr, Security: IMAPSecurityNone, MaxMessages: 2}
+	creds := IMAPCredentials{Username: "ann", Password: `p"w`}
+
+	result, err := ExtractIMAPFolder(context.Background(), server, creds, "INBOX", IMAPCheckpoint{})
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if result.UIDValidity != 7 || result.LastUID != 5 || result.Remaining != 1 || len(result.Messages) != 2 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	msg := result.Messages[0]
+	if msg.UID != 3 || msg.InternalDate.UTC().Format("2006-01-02T15:04") != "2025-03-05T08:30" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+	if len(msg.Attachments) != 1 {
+		t.Fatalf("expected one attachment, got %+v", msg.Attachments)
+	}
+	att := msg.Attachments[0]
+	if att.Filename != "tälk.srt" || att.MimeType != "application/x-subrip" || att.Err != nil {
+		t.Fatalf("unexpected attachment: %+v", att)
+	}
+	if att.Result.Content != "Hello there.\n\nGeneral Kenobi!\nYou are a bold one." {
+		t.Fatalf("unexpected attachment content: %q", att.Result.Content)
+	}
+
+	result, err = NewClient(nil).ExtractIMAPFolder(context.Background(), server, IMAPCredentials{Username: "ann", AccessToken: "tok"}, "INBOX", result.Checkpoint())
+	if err != nil || result.LastUID != 9 || len(result.Messages) != 1 || result.Remaining != 0 {
+		t.Fatalf("second run = %+v, %v", result, err)
+	}
+	result, err = ExtractIMAPFolder(context.Background(), server, creds, "INBOX", result.Checkpoint())
+	if err != nil || result.LastUID != 9 || len(result.Messages) != 0 || result.Reset {
+		t.Fatalf("third run = %+v, %v", result, err)
+	}
+
+	// A changed UIDVALIDITY invalidates the checkpoint's UIDs.
+	result, err = ExtractIMAPFolder(context.Background(), server, creds, "INBOX", IMAPCheckpoint{UIDValidity: 6, LastUID: 9})
+	if err != nil || !result.Reset || result.LastUID != 5 || len(result.Messages) != 2 || result.Messages[0].UID != 3 {
+		t.Fatalf("expected the folder to be re-read, got %+v, %v", result, err)
+	}
+
+	server.MaxMessageBytes = 10
+	result, err = ExtractIMAPFolder(context.Background(), server, creds, "INBOX", IMAPCheckpoint{UIDValidity: 7, LastUID: 5})
+	if err != nil || len(result.Messages) != 1 || result.Messages[0].Err == nil || result.LastUID != 9 {
+		t.Fatalf("expected oversized message to be skipped, got %+v, %v", result, err)
+	}
+}
+
+func TestExtractIMAPFolderReportsAuthenticationFailure(t *testing.T) {
+	addr := fakeIMAP(t, map[uint32]string{1: testEmail("one")})
+	server := IMAPServer{Address: addr, Security: IMAPSecurityNone}
+
+	for _, creds := range []IMAPCredentials{{Username: "ann", Password: "wrong"}, {Username: "ann", AccessToken: "expired"}} {
+		_, err := ExtractIMAPFolder(context.Background(), server, creds, "INBOX", IMAPCheckpoint{})
+		var ioErr *IOError
+		if !errors.As(err, &ioErr) || !strings.Contains(err.Error(), "AUTHENTICATIONFAILED") {
+			t.Fatalf("expected authentication IOError, got %v", err)
+		}
+	}
+	if _, err := ExtractIMAPFolder(context.Background(), server, IMAPCredentials{Username: "ann\r\nx"}, "INBOX", IMAPCheckpoint{}); err == nil {
+		t.Fatalf("expected validation error for line breaks in credentials")
+	}
+}
+
+func TestIMAPRejectsOversizedLiterals(t *testing.T) {
+	for _, tc := range []struct {
+		announced  string
+		maxLiteral int64
+	}{
+		{"{1099511627776}", imapMaxLiteralBytes},
+		{"{11}", 10},
+	} {
+		c := &imapConn{r: bufio.NewReader(strings.NewReader("* 1 FETCH (BODY[] " + tc.announced + "\r\n")), maxLiteral: tc.maxLiteral}
+		var ioErr *IOError
+		if _, err := c.readResponse(); !errors.As(err, &ioErr) || !strings.Contains(err.Error(), "exceeds") {
+			t.Fatalf("%s: expected the literal to be rejected, got %v", tc.announced, err)
+		}
+	}
+	c := &imapConn{r: bufio.NewReader(strings.NewReader("* 1 FETCH (BODY[] {5}\r\nhello)\r\n")), maxLiteral: 5}
+	if resp, err := c.readResponse(); err != nil || len(resp.literals) != 1 || string(resp.literals[0]) != "hello" {
+		t.Fatalf("unexpected response %+v, %v", resp, err)
+	}
+}
+
+func TestIMAPUTF7(t *testing.T) {
+	for in, want := range map[string]string{
+		"INBOX":         "INBOX",
+		"Entwürfe & Co": "Entw&APw-rfe &- Co",
+		"日本語":           "&ZeVnLIqe-",
+	} {
+		if got := imapUTF7(in); got != want {
+			t.Errorf("imapUTF7(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -125,6 +125,40 @@ typedef struct CBytesWithMime {
   const char *mime_type;
 } CBytesWithMime;
 
+/**
+ * A converted document returned by `kreuzberg_convert_file` and `kreuzberg_convert_bytes`.
+ *
+ * Free it with `kreuzberg_free_converted_document`.
+ */
+typedef struct CConvertedDocument {
+  /**
+   * The converted document bytes
+   */
+  uint8_t *data;
+  /**
+   * The number of bytes in `data`
+   */
+  uintptr_t len;
+} CConvertedDocument;
+
+/**
+ * Counters of the library's global allocator.
+ */
+typedef struct CNativeMemoryStats {
+  /**
+   * Bytes allocated and not yet freed
+   */
+  uint64_t allocated_bytes;
+  /**
+   * The highest `allocated_bytes` since the library was loaded
+   */
+  uint64_t peak_allocated_bytes;
+  /**
+   * Number of allocations since the library was loaded
+   */
+  uint64_t allocation_count;
+} CNativeMemoryStats;
+
 /**
  * Type alias for the OCR backend callback function.
  *
@@ -466,6 +500,204 @@ struct CBatchResult *kreuzberg_batch_extract_bytes_sync(const struct CBytesWithM
                                                         uintptr_t count,
                                                         const char *config_json);
 
+/**
+ * Converts the document at `file_path` to `target_mime` (e.g. "application/pdf") using
+ * LibreOffice in headless mode.
+ *
+ * # Arguments
+ *
+ * - `file_path`: Path of the document; LibreOffice detects its format
+ * - `target_mime`: MIME type to convert to
+ * - `timeout_seconds`: Time after which the LibreOffice process is killed (0 for the default
+ *   of 300 seconds)
+ *
+ * # Safety
+ *
+ * - `file_path` and `target_mime` must be valid null-terminated C strings
+ * - The returned pointer must be freed with `kreuzberg_free_converted_document`
+ * - Returns NULL on error (check `kreuzberg_last_error` for details)
+ *
+ * # C Signature
+ *
+ * ```c
+ * CConvertedDocument* kreuzberg_convert_file(const char* file_path, const char* target_mime, uint64_t timeout_seconds);
+ * ```
+ */
+struct CConvertedDocument *kreuzberg_convert_file(const char *file_path,
+                                                  const char *target_mime,
+                                                  uint64_t timeout_seconds);
+
+/**
+ * Converts an in-memory document of type `source_mime` to `target_mime` using LibreOffice in
+ * headless mode.
+ *
+ * # Arguments
+ *
+ * - `data`, `data_len`: The document bytes
+ * - `source_mime`: MIME type of the document
+ * - `target_mime`: MIME type to convert to
+ * - `timeout_seconds`: Time after which the LibreOffice process is killed (0 for the default
+ *   of 300 seconds)
+ *
+ * # Safety
+ *
+ * - `data` must be a valid pointer to a byte array of length `data_len`
+ * - `source_mime` and `target_mime` must be valid null-terminated C strings
+ * - The returned pointer must be freed with `kreuzberg_free_converted_document`
+ * - Returns NULL on error (check `kreuzberg_last_error` for details)
+ *
+ * # C Signature
+ *
+ * ```c
+ * CConvertedDocument* kreuzberg_convert_bytes(const uint8_t* data, uintptr_t data_len,
+ *                                             const char* source_mime, const char* target_mime,
+ *                                             uint64_t timeout_seconds);
+ * ```
+ */
+struct CConvertedDocument *kreuzberg_convert_bytes(const uint8_t *data,
+                                                   uintptr_t data_len,
+                                                   const char *source_mime,
+                                                   const char *target_mime,
+                                                   uint64_t timeout_seconds);
+
+/**
+ * Frees a document returned by `kreuzberg_convert_file` or `kreuzberg_convert_bytes`.
+ *
+ * # Safety
+ *
+ * - `document` must be NULL or a pointer returned by one of the conversion functions
+ * - `document` must not be used after this call
+ *
+ * # C Signature
+ *
+ * ```c
+ * void kreuzberg_free_converted_document(CConvertedDocument* document);
+ * ```
+ */
+void kreuzberg_free_converted_document(struct CConvertedDocument *document);
+
+/**
+ * Extracts the document at `file_path` and returns its statistics instead of its content.
+ *
+ * The result is a JSON object `{"success": bool, "statistics": {...}}` whose `statistics` has
+ * the MIME type, character, word, unique word, sentence, line, page, table and image counts,
+ * the detected languages, the pattern-detected entity counts by type, the Flesch reading ease
+ * and the lexical diversity. The result cache and the Tesseract OCR cache are disabled.
+ *
+ * # Safety
+ *
+ * - `file_path` must be a valid null-terminated C string
+ * - `config_json` must be NULL or a valid null-terminated JSON string
+ * - The returned string must be freed with `kreuzberg_free_string`
+ * - Returns NULL on error (check `kreuzberg_last_error` for details)
+ *
+ * # C Signature
+ *
+ * ```c
+ * char* kreuzberg_extract_statistics_file(const char* file_path, const char* config_json);
+ * ```
+ */
+char *kreuzberg_extract_statistics_file(const char *file_path, const char *config_json);
+
+/**
+ * Extracts an in-memory document and returns its statistics instead of its content.
+ *
+ * See `kreuzberg_extract_statistics_file` for the result.
+ *
+ * # Safety
+ *
+ * - `data` must point to `data_len` readable bytes
+ * - `mime_type` must be a valid null-terminated C string
+ * - `config_json` must be NULL or a valid null-terminated JSON string
+ * - The returned string must be freed with `kreuzberg_free_string`
+ * - Returns NULL on error (check `kreuzberg_last_error` for details)
+ *
+ * # C Signature
+ *
+ * ```c
+ * char* kreuzberg_extract_statistics_bytes(const uint8_t* data, uintptr_t data_len,
+ *                                          const char* mime_type, const char* config_json);
+ * ```
+ */
+char *kreuzberg_extract_statistics_bytes(const uint8_t *data,
+                                         uintptr_t data_len,
+                                         const char *mime_type,
+                                         const char *config_json);
+
+/**
+ * Extracts the documents at `file_paths` in parallel and returns their statistics.
+ *
+ * The result is a JSON array with one object per path, in order, shaped like the result of
+ * `kreuzberg_extract_statistics_file`. A document that failed to extract has `success` false
+ * and its `error_type`; the error message is dropped since parsers may quote the document in it.
+ *
+ * # Safety
+ *
+ * - `file_paths` must point to `count` valid null-terminated C strings
+ * - `config_json` must be NULL or a valid null-terminated JSON string
+ * - The returned string must be freed with `kreuzberg_free_string`
+ * - Returns NULL on error (check `kreuzberg_last_error` for details)
+ *
+ * # C Signature
+ *
+ * ```c
+ * char* kreuzberg_batch_extract_statistics_files(const char* const* file_paths, uintptr_t count,
+ *                                                const char* config_json);
+ * ```
+ */
+char *kreuzberg_batch_extract_statistics_files(const char *const *file_paths,
+                                               uintptr_t count,
+                                               const char *config_json);
+
+/**
+ * Count the pages of a PDF.
+ *
+ * # Safety
+ *
+ * - `data` must be a valid pointer to a byte array of length `data_len`
+ * - Returns -1 on error (check `kreuzberg_last_error` for details)
+ */
+int32_t kreuzberg_pdf_page_count(const uint8_t *data, uintptr_t data_len);
+
+/**
+ * Copy `page_count` pages of a PDF, starting at the 0-based page `first_page`, into a new PDF.
+ *
+ * # Safety
+ *
+ * - `data` must be a valid pointer to a byte array of length `data_len`
+ * - `out_len` must be a valid pointer; the length of the new PDF is written to it
+ * - The returned pointer must be freed with `kreuzberg_free_bytes`, passing that length
+ * - Returns NULL on error (check `kreuzberg_last_error` for details)
+ */
+uint8_t *kreuzberg_pdf_page_range(const uint8_t *data,
+                                  uintptr_t data_len,
+                                  uintptr_t first_page,
+                                  uintptr_t page_count,
+                                  uintptr_t *out_len);
+
+/**
+ * Free a byte buffer returned by `kreuzberg_pdf_page_range`.
+ *
+ * # Safety
+ *
+ * - `data` must have been returned by `kreuzberg_pdf_page_range` with length `len`, or be NULL
+ * - `data` must not be used after this call
+ */
+void kreuzberg_free_bytes(uint8_t *data, uintptr_t len);
+
+/**
+ * Read the counters of the library's global allocator.
+ *
+ * Only allocations made by Rust code are counted; the bundled C and C++ libraries (PDFium,
+ * Tesseract, ONNX Runtime) allocate outside of it.
+ *
+ * # Thread Safety
+ *
+ * This function is thread-safe. The counters are read one at a time, so under concurrent
+ * allocation they may be off by the allocations made while reading.
+ */
+struct CNativeMemoryStats kreuzberg_native_memory_stats(void);
+
 /**
  * Load an extraction configuration from a TOML/YAML/JSON file.
  *
@@ -920,6 +1152,28 @@ bool kreuzberg_clear_validators(void);
  */
 char *kreuzberg_list_validators(void);
 
+/**
+ * Set a hard timeout for every call into a plugin registered through a callback (OCR backends,
+ * post-processors, validators and document extractors).
+ *
+ * A callback that has not returned within `timeout_ms` milliseconds fails its extraction with
+ * a plugin error. The callback itself cannot be interrupted: it keeps running on its worker
+ * thread and its result is discarded, so the timeout is a backstop for plugins that ignore
+ * their own deadlines. `0` disables the timeout, which is the default.
+ *
+ * # Example (C)
+ *
+ * ```c
+ * kreuzberg_set_plugin_timeout_ms(30000);
+ * ```
+ */
+void kreuzberg_set_plugin_timeout_ms(uint64_t timeout_ms);
+
+/**
+ * Get the plugin callback timeout set with `kreuzberg_set_plugin_timeout_ms` (0 = none).
+ */
+uint64_t kreuzberg_plugin_timeout_ms(void);
+
 /**
  * Unregister an OCR backend by name.
  *
@@ -1606,6 +1860,33 @@ const char *kreuzberg_error_code_name(uint32_t code);
  */
 const char *kreuzberg_error_code_description(uint32_t code);
 
+/**
+ * Returns the stable `KZB-NNNN` code of an error kind as a C string.
+ *
+ * # Arguments
+ *
+ * - `kind`: Error kind, e.g. an error code name ("validation", "ocr") or one of "serialization",
+ *   "image_processing", "cache", "panic", "lock_poisoned", "cancelled", "deadline_exceeded"
+ *
+ * # Returns
+ *
+ * Pointer to a null-terminated C string with the stable code (e.g., "KZB-1000").
+ * Returns a pointer to "KZB-0000" if the kind is NULL or unknown.
+ *
+ * The returned pointer is valid for the lifetime of the program and should not be freed.
+ *
+ * # Safety
+ *
+ * - `kind` must be a valid null-terminated C string or NULL
+ *
+ * # C Signature
+ *
+ * ```c
+ * const char* kreuzberg_stable_error_code(const char* kind);
+ * ```
+ */
+const char *kreuzberg_stable_error_code(const char *kind);
+
 /**
  * Retrieves detailed error information from the thread-local error storage.
  *
@@ -0,0 +1,68 @@
+package kreuzberg
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"slices"
+)
+
+// ChunksSeq returns an iterator over the result's chunks.
+func (r *ExtractionResult) ChunksSeq() iter.Seq[Chunk] {
+	return slices.Values(r.Chunks)
+}
+
+// PagesSeq returns an iterator over the result's pages.
+func (r *ExtractionResult) PagesSeq() iter.Seq[PageContent] {
+	return slices.Values(r.Pages)
+}
+
+// BatchResult is a batch result yielded by the batch iterators, with the index of its input.
+type BatchResult struct {
+	Index  int
+	Result *ExtractionResult
+}
+
+// errStopIteration ends a batch stream when the consumer breaks out of a range loop.
+var errStopIteration = errors.New("iteration stopped")
+
+// BatchExtractFilesSeq returns an iterator over the results of extracting paths, in input order.
+// Documents are extracted lazily in chunks of opts.ChunkSize as the loop advances, so breaking
+// out of the loop skips the remaining documents and at most one chunk of results is held in
+// memory. Failed documents are yielded like in BatchExtractFilesSync; an error that stops the
+// batch (including ctx being done) is yielded once with a zero BatchResult and ends iteration.
+func BatchExtractFilesSeq(ctx context.Context, paths []string, config *ExtractionConfig, opts *BatchSinkOptions) iter.Seq2[BatchResult, error] {
+	return batchExtractFilesSeq(ctx, defaultPluginRegistry, paths, config, opts)
+}
+
+// BatchExtractBytesSeq is BatchExtractFilesSeq for in-memory documents.
+func BatchExtractBytesSeq(ctx context.Context, items []BytesWithMime, config *ExtractionConfig, opts *BatchSinkOptions) iter.Seq2[BatchResult, error] {
+	return batchExtractBytesSeq(ctx, defaultPluginRegistry, items, config, opts)
+}
+
+func batchExtractFilesSeq(ctx context.Context, plugins *pluginRegistry, paths []string, config *ExtractionConfig, opts *BatchSinkOptions) iter.Seq2[BatchResult, error] {
+	return batchSeq(func(sink ResultSink) error {
+		return batchExtractFilesToSink(ctx, plugins, paths, config, opts, sink)
+	})
+}
+
+func batchExtractBytesSeq(ctx context.Context, plugins *pluginRegistry, items []BytesWithMime, config *ExtractionConfig, opts *BatchSinkOptions) iter.Seq2[BatchResult, error] {
+	return batchSeq(func(sink ResultSink) error {
+		return batchExtractBytesToSink(ctx, plugins, items, config, opts, sink)
+	})
+}
+
+// batchSeq adapts a sink-based batch to an iterator.
+func batchSeq(run func(sink ResultSink) error) iter.Seq2[BatchResult, error] {
+	return func(yield func(BatchResult, error) bool) {
+		err := run(ResultSinkFunc(func(index int, result *ExtractionResult) error {
+			if !yield(BatchResult{Index: index, Result: result}, nil) {
+				return errStopIteration
+			}
+			return nil
+		}))
+		if err != nil && !errors.Is(err, errStopIteration) {
+			yield(BatchResult{}, err)
+		}
+	}
+}
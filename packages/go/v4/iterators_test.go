@@ -0,0 +1,71 @@
+package kreuzberg
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestResultSeqs(t *testing.T) {
+	result := &ExtractionResult{
+		Chunks: []Chunk{{Content: "a"}, {Content: "b"}},
+		Pages:  []PageContent{{PageNumber: 1}, {PageNumber: 2}, {PageNumber: 3}},
+	}
+	var chunks []string
+	for chunk := range result.ChunksSeq() {
+		chunks = append(chunks, chunk.Content)
+	}
+	if !slices.Equal(chunks, []string{"a", "b"}) {
+		t.Fatalf("unexpected chunks: %v", chunks)
+	}
+	if pages := slices.Collect(result.PagesSeq()); len(pages) != 3 || pages[2].PageNumber != 3 {
+		t.Fatalf("unexpected pages: %+v", pages)
+	}
+	for range (&ExtractionResult{}).ChunksSeq() {
+		t.Fatalf("expected no chunks")
+	}
+}
+
+func TestBatchExtractSeqIsLazy(t *testing.T) {
+	extracted := 0
+	client := NewClient(nil)
+	client.RegisterPostProcessor("count", 0, func(pc *PluginContext, result *ExtractionResult) error {
+		extracted++
+		return nil
+	})
+	items := make([]BytesWithMime, 5)
+	for i := range items {
+		items[i] = BytesWithMime{Data: []byte(testSRT), MimeType: mimeSRT}
+	}
+
+	var indices []int
+	for item, err := range client.BatchExtractBytesSeq(context.Background(), items, &BatchSinkOptions{ChunkSize: 2}) {
+		if err != nil {
+			t.Fatalf("batch: %v", err)
+		}
+		if item.Result.Content != wantSRTContent {
+			t.Fatalf("unexpected content for %d: %q", item.Index, item.Result.Content)
+		}
+		indices = append(indices, item.Index)
+		if item.Index == 2 {
+			break
+		}
+	}
+	if !slices.Equal(indices, []int{0, 1, 2}) || extracted != 4 {
+		t.Fatalf("expected extraction to stop after the second chunk, got %v with %d extracted", indices, extracted)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var errs []error
+	for item, err := range BatchExtractBytesSeq(ctx, items, nil, nil) {
+		if item.Result != nil {
+			t.Fatalf("expected no results after cancellation")
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], context.Canceled) {
+		t.Fatalf("expected a single cancellation error, got %v", errs)
+	}
+}
@@ -0,0 +1,489 @@
+package kreuzberg
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	mimePages   = "application/vnd.apple.pages"
+	mimeNumbers = "application/vnd.apple.numbers"
+	mimeKeynote = "application/vnd.apple.keynote"
+)
+
+// IWorkSlide describes one Keynote slide.
+type IWorkSlide struct {
+	Number int    `json:"number"`
+	Title  string `json:"title,omitempty"`
+	Notes  string `json:"notes,omitempty"`
+}
+
+// iworkPart is a unit of an iWork document: a Keynote slide, a Numbers sheet, or the whole
+// Pages document.
+type iworkPart struct {
+	title  string
+	text   []string
+	notes  []string
+	tables []iwaTable
+}
+
+// openIWorkBundle opens an iWork document, which is either a zip archive or (for older
+// documents saved as packages) a directory.
+func openIWorkBundle(src documentSource) (fs.FS, error) {
+	if src.data == nil && src.path != "" {
+		if info, err := os.Stat(src.path); err == nil && info.IsDir() {
+			return os.DirFS(src.path), nil
+		}
+	}
+	data, err := src.bytes()
+	if err != nil {
+		return nil, err
+	}
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, newParsingErrorWithContext("iWork document is not a zip archive", err, ErrorCodeParsing, nil)
+	}
+	return reader, nil
+}
+
+// iworkApplication identifies the iWork application from the MIME type, falling back to the
+// bundle layout.
+func iworkApplication(bundle fs.FS, mimeType string) string {
+	switch mimeType {
+	case mimePages:
+		return "pages"
+	case mimeNumbers:
+		return "numbers"
+	case mimeKeynote:
+		return "keynote"
+	}
+	if matches, _ := fs.Glob(bundle, "Index/Slide*.iwa"); len(matches) > 0 {
+		return "keynote"
+	}
+	if _, err := fs.Stat(bundle, "index.apxl.gz"); err == nil {
+		return "keynote"
+	}
+	if _, err := fs.Stat(bundle, "Index/CalculationEngine.iwa"); err == nil {
+		return "numbers"
+	}
+	return "pages"
+}
+
+var iworkSlideNumber = regexp.MustCompile(`(\d+)\.iwa$`)
+
+// iwaFileOrder sorts IWA files with Document.iwa first and numbered files (Slide-12.iwa) by
+// number, which follows the order slides were created in.
+func iwaFileOrder(names []string) {
+	number := func(name string) int {
+		if m := iworkSlideNumber.FindStringSubmatch(name); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			return n
+		}
+		return -1
+	}
+	sort.SliceStable(names, func(i, j int) bool {
+		di, dj := path.Base(names[i]) == "Document.iwa", path.Base(names[j]) == "Document.iwa"
+		if di != dj {
+			return di
+		}
+		if ni, nj := number(names[i]), number(names[j]); ni != nj {
+			return ni < nj
+		}
+		return names[i] < names[j]
+	})
+}
+
+// loadIWA reads every IWA file of a bundle.
+func loadIWA(bundle fs.FS) (*iwaDocument, []string, error) {
+	names, err := fs.Glob(bundle, "Index/*.iwa")
+	if err != nil {
+		return nil, nil, err
+	}
+	nested, _ := fs.Glob(bundle, "Index/*/*.iwa")
+	names = append(names, nested...)
+	iwaFileOrder(names)
+
+	doc := &iwaDocument{objects: map[uint64]iwaObject{}, files: map[string][]uint64{}}
+	for _, name := range names {
+		data, err := fs.ReadFile(bundle, name)
+		if err != nil {
+			return nil, nil, newIOErrorWithContext(fmt.Sprintf("failed to read %s", name), err, ErrorCodeIo, nil)
+		}
+		objects, err := readIWA(data)
+		if err != nil {
+			return nil, nil, newParsingErrorWithContext(fmt.Sprintf("failed to decode %s", name), err, ErrorCodeParsing, nil)
+		}
+		doc.add(name, objects)
+	}
+	return doc, names, nil
+}
+
+// collect adds the storages and tables of the objects in ids to part.
+func (d *iwaDocument) collect(part *iworkPart, ids []uint64, notes bool) {
+	var footnotes []string
+	for _, id := range ids {
+		switch d.objects[id].typ {
+		case iwaTypeStorage:
+			storage, _ := d.storage(id)
+			if storage.text == "" {
+				continue
+			}
+			switch storage.kind {
+			case iwaStorageBody, iwaStorageTextbox, iwaStorageUnclassified:
+				part.text = append(part.text, storage.text)
+			case iwaStorageFootnote:
+				footnotes = append(footnotes, storage.text)
+			case iwaStorageNote:
+				if notes {
+					part.notes = append(part.notes, storage.text)
+				}
+			}
+		case iwaTypeTableModel:
+			if table, ok := d.table(id); ok {
+				part.tables = append(part.tables, table)
+			}
+		}
+	}
+	part.text = append(part.text, footnotes...)
+}
+
+// iwaParts splits an IWA document into slides (Keynote), sheets (Numbers) or a single part.
+func iwaParts(doc *iwaDocument, names []string, app string) []iworkPart {
+	switch app {
+	case "keynote":
+		var parts []iworkPart
+		for _, name := range names {
+			if !strings.HasPrefix(path.Base(name), "Slide") {
+				continue
+			}
+			var part iworkPart
+			doc.collect(&part, doc.files[name], true)
+			if len(part.text) > 0 {
+				part.title = strings.SplitN(part.text[0], "\n", 2)[0]
+			}
+			parts = append(parts, part)
+		}
+		return parts
+	case "numbers":
+		if parts := numbersSheets(doc, names); len(parts) > 0 {
+			return parts
+		}
+	}
+	var part iworkPart
+	for _, name := range names {
+		if !strings.HasPrefix(path.Base(name), "TemplateSlide") {
+			doc.collect(&part, doc.files[name], false)
+		}
+	}
+	return []iworkPart{part}
+}
+
+// numbersSheets groups the tables of a Numbers document by sheet, in sheet order. Text boxes
+// and tables not placed on a sheet are added to the last sheet.
+func numbersSheets(doc *iwaDocument, names []string) []iworkPart {
+	var parts []iworkPart
+	placed := map[uint64]bool{}
+	for _, name := range names {
+		for _, id := range doc.files[name] {
+			sheet := doc.message(id, iwaTypeNumbersSheet)
+			if sheet == nil {
+				continue
+			}
+			part := iworkPart{title: string(sheet.bytes(1))}
+			var ids []uint64
+			for _, raw := range sheet[2] {
+				b, _ := raw.([]byte)
+				ref, _ := decodeProto(b)
+				info := doc.message(ref.uint(1), iwaTypeTableInfo)
+				if model := reference(info, 2); model != 0 {
+					ids = append(ids, model)
+					placed[model] = true
+				}
+			}
+			doc.collect(&part, ids, false)
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	last := &parts[len(parts)-1]
+	for _, name := range names {
+		var rest []uint64
+		for _, id := range doc.files[name] {
+			if !placed[id] {
+				rest = append(rest, id)
+			}
+		}
+		doc.collect(last, rest, false)
+	}
+	return parts
+}
+
+// openIWork09Index returns the XML index of an iWork '09 document, if the bundle has one.
+func openIWork09Index(bundle fs.FS) ([]byte, bool, error) {
+	for _, name := range []string{"index.xml.gz", "index.apxl.gz", "index.xml", "index.apxl"} {
+		data, err := fs.ReadFile(bundle, name)
+		if err != nil {
+			continue
+		}
+		if strings.HasSuffix(name, ".gz") {
+			zr, err := gzip.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, true, newParsingErrorWithContext("failed to decompress iWork '09 index", err, ErrorCodeParsing, nil)
+			}
+			if data, err = io.ReadAll(zr); err != nil {
+				return nil, true, newParsingErrorWithContext("failed to decompress iWork '09 index", err, ErrorCodeParsing, nil)
+			}
+		}
+		return data, true, nil
+	}
+	return nil, false, nil
+}
+
+// iwork09Paragraphs returns the text of the sf:p paragraphs below e, skipping style sheets and
+// the elements named in skip.
+func iwork09Paragraphs(e *xmlElem, skip ...string) []string {
+	var out []string
+	for _, c := range e.elements() {
+		switch {
+		case c.name == "stylesheet" || c.name == "metadata" || containsString(skip, c.name):
+		case c.name == "p":
+			if text := c.text(); text != "" {
+				out = append(out, text)
+			}
+		default:
+			out = append(out, iwork09Paragraphs(c, skip...)...)
+		}
+	}
+	return out
+}
+
+// iwork09Parts extracts the text of an iWork '09 document. Keynote slides keep their notes;
+// tables are not decoded for this legacy format.
+func iwork09Parts(root *xmlElem, app string) []iworkPart {
+	if app == "keynote" {
+		var parts []iworkPart
+		for _, slide := range root.descendants("slide") {
+			part := iworkPart{text: iwork09Paragraphs(slide, "notes")}
+			if notes := slide.child("notes"); notes != nil {
+				part.notes = iwork09Paragraphs(notes)
+			}
+			if len(part.text) > 0 {
+				part.title = part.text[0]
+			}
+			parts = append(parts, part)
+		}
+		return parts
+	}
+	return []iworkPart{{text: iwork09Paragraphs(root)}}
+}
+
+// iwork09Metadata reads the title and authors from the sf:metadata block.
+func iwork09Metadata(root *xmlElem) map[string]any {
+	meta := map[string]any{}
+	var block *xmlElem
+	if found := root.descendants("metadata"); len(found) > 0 {
+		block = found[0]
+	}
+	values := func(name string) []string {
+		var out []string
+		for _, s := range block.child(name).descendants("string") {
+			if v := s.attr("string"); v != "" {
+				out = append(out, v)
+			}
+		}
+		return out
+	}
+	if title := values("title"); len(title) > 0 {
+		meta["title"] = title[0]
+	}
+	if authors := values("authors"); len(authors) > 0 {
+		meta["authors"] = authors
+	}
+	if keywords := values("keywords"); len(keywords) > 0 {
+		meta["keywords"] = keywords
+	}
+	return meta
+}
+
+// iworkBuildVersions lists the application builds that saved the document, from
+// Metadata/BuildVersionHistory.plist when it is an XML property list.
+func iworkBuildVersions(bundle fs.FS) []string {
+	data, err := fs.ReadFile(bundle, "Metadata/BuildVersionHistory.plist")
+	if err != nil || !bytes.HasPrefix(bytes.TrimSpace(data), []byte("<?xml")) {
+		return nil
+	}
+	root, _, _, err := parseXMLTree(data)
+	if err != nil {
+		return nil
+	}
+	var versions []string
+	for _, s := range root.descendants("string") {
+		versions = append(versions, s.text())
+	}
+	return versions
+}
+
+// renderIWork renders parts as Markdown. Keynote parts become slides and Numbers parts sheets;
+// both are reported as pages.
+func renderIWork(parts []iworkPart, app string, result *ExtractionResult, config *ExtractionConfig) []IWorkSlide {
+	var content strings.Builder
+	var slides []IWorkSlide
+	unit := PageUnitTypePage
+	switch app {
+	case "keynote":
+		unit = PageUnitTypeSlide
+	case "numbers":
+		unit = PageUnitTypeSheet
+	}
+	paged := app != "pages"
+	structure := &PageStructure{TotalCount: uint64(len(parts)), UnitType: unit}
+	extractPages := config != nil && config.Pages != nil && config.Pages.ExtractPages != nil && *config.Pages.ExtractPages
+
+	for i, part := range parts {
+		if content.Len() > 0 {
+			content.WriteString("\n\n")
+		}
+		start := content.Len()
+		var blocks []string
+		switch app {
+		case "keynote":
+			blocks = append(blocks, fmt.Sprintf("## Slide %d", i+1))
+		case "numbers":
+			if part.title != "" {
+				blocks = append(blocks, "## "+part.title)
+			}
+		}
+		blocks = append(blocks, part.text...)
+		var pageTables []Table
+		for _, t := range part.tables {
+			if len(t.rows) == 0 {
+				continue
+			}
+			markdown := spreadsheetMarkdown(t.rows)
+			if t.name != "" && app != "pages" {
+				blocks = append(blocks, "### "+t.name)
+			}
+			blocks = append(blocks, strings.TrimRight(markdown, "\n"))
+			pageTables = append(pageTables, Table{Cells: t.rows, Markdown: markdown, PageNumber: i + 1})
+		}
+		if len(part.notes) > 0 {
+			blocks = append(blocks, "### Notes", strings.Join(part.notes, "\n\n"))
+		}
+		content.WriteString(strings.Join(blocks, "\n\n"))
+		result.Tables = append(result.Tables, pageTables...)
+
+		if app == "keynote" {
+			slides = append(slides, IWorkSlide{Number: i + 1, Title: part.title, Notes: strings.Join(part.notes, "\n\n")})
+		}
+		if paged {
+			info := PageInfo{Number: uint64(i + 1)}
+			if part.title != "" {
+				info.Title = stringPtr(part.title)
+			}
+			structure.Pages = append(structure.Pages, info)
+			structure.Boundaries = append(structure.Boundaries, PageBoundary{ByteStart: uint64(start), ByteEnd: uint64(content.Len()), PageNumber: uint64(i + 1)})
+			if extractPages {
+				result.Pages = append(result.Pages, PageContent{PageNumber: uint64(i + 1), Content: content.String()[start:], Tables: pageTables})
+			}
+		}
+	}
+	result.Content = content.String()
+	if paged {
+		result.Metadata.PageStructure = structure
+	}
+	return slides
+}
+
+// extractIWork extracts Pages, Numbers and Keynote documents: the IWA format used since iWork
+// 2013, and the XML format of iWork '09.
+func extractIWork(src documentSource, mimeType string, config *ExtractionConfig) (*ExtractionResult, error) {
+	bundle, err := openIWorkBundle(src)
+	if err != nil {
+		return nil, err
+	}
+	app := iworkApplication(bundle, mimeType)
+	additional := map[string]any{"iwork_application": app}
+
+	var parts []iworkPart
+	if index, ok, err := openIWork09Index(bundle); err != nil {
+		return nil, err
+	} else if ok {
+		root, _, _, err := parseXMLTree(index)
+		if err != nil {
+			return nil, newParsingErrorWithContext("failed to parse iWork '09 index", err, ErrorCodeParsing, nil)
+		}
+		parts = iwork09Parts(root, app)
+		additional["iwork_format"] = "xml"
+		for key, value := range iwork09Metadata(root) {
+			additional[key] = value
+		}
+	} else {
+		doc, names, err := loadIWA(bundle)
+		if err != nil {
+			return nil, err
+		}
+		if len(names) == 0 {
+			return nil, newUnsupportedFormatErrorWithContext(app, "iWork document has neither IWA archives nor an XML index", nil, ErrorCodeUnsupportedFormat, nil)
+		}
+		parts = iwaParts(doc, names, app)
+		additional["iwork_format"] = "iwa"
+	}
+
+	result := &ExtractionResult{MimeType: mimeType, Tables: []Table{}, Success: true}
+	slides := renderIWork(parts, app, result, config)
+	if app == "keynote" {
+		additional["slide_count"] = len(slides)
+		additional["slides"] = slides
+	}
+	if app == "numbers" {
+		var sheets []string
+		for _, part := range parts {
+			if part.title != "" {
+				sheets = append(sheets, part.title)
+			}
+		}
+		if len(sheets) > 0 {
+			additional["sheet_names"] = sheets
+		}
+	}
+	additional["table_count"] = len(result.Tables)
+	if versions := iworkBuildVersions(bundle); len(versions) > 0 {
+		additional["build_versions"] = versions
+	}
+
+	result.Metadata.Additional = make(map[string]json.RawMessage, len(additional))
+	for key, value := range additional {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, newSerializationErrorWithContext("failed to encode iWork metadata", err, ErrorCodeValidation, nil)
+		}
+		result.Metadata.Additional[key] = raw
+	}
+	return result, nil
+}
+
+func init() {
+	registerGoPrimaryExtractor(goPrimaryExtractor{
+		name:      "iwork",
+		mimeTypes: []string{mimePages, mimeNumbers, mimeKeynote, "application/x-iwork-pages-sffpages", "application/x-iwork-numbers-sffnumbers", "application/x-iwork-keynote-sffkey"},
+		extensions: map[string]string{
+			"pages":   mimePages,
+			"numbers": mimeNumbers,
+			"key":     mimeKeynote,
+		},
+		extract: extractIWork,
+	})
+}
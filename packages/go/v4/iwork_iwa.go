@@ -0,0 +1,440 @@
+package kreuzberg
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IWA message types used by the extractor. TSWP and TST types are shared by Pages, Numbers and
+// Keynote; type 2 is TN.SheetArchive in Numbers only.
+const (
+	iwaTypeNumbersSheet   = 2
+	iwaTypeStorage        = 2001
+	iwaTypeTableInfo      = 6000
+	iwaTypeTableModel     = 6001
+	iwaTypeTile           = 6002
+	iwaTypeTableDataList  = 6005
+	iwaTypeRichTextEntry  = 6218
+	iwaDefaultTileRows    = 256
+	iwaCellStorageVersion = 5
+)
+
+// TSWP.StorageArchive kinds.
+const (
+	iwaStorageBody         = 0
+	iwaStorageHeader       = 1
+	iwaStorageFootnote     = 2
+	iwaStorageTextbox      = 3
+	iwaStorageNote         = 4
+	iwaStorageCell         = 5
+	iwaStorageUnclassified = 6
+	iwaStorageTOC          = 7
+)
+
+// TST cell value types in cell storage version 5.
+const (
+	iwaCellNumber   = 2
+	iwaCellText     = 3
+	iwaCellDate     = 5
+	iwaCellBool     = 6
+	iwaCellDuration = 7
+	iwaCellError    = 8
+	iwaCellRichText = 9
+)
+
+// iwaEpoch is the reference date of iWork date cells.
+var iwaEpoch = time.Date(2001, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// decodeSnappy decodes a raw (unframed) Snappy block.
+func decodeSnappy(src []byte) ([]byte, error) {
+	size, n := binary.Uvarint(src)
+	if n <= 0 || size > uint64(len(src))*255+64 {
+		return nil, errors.New("invalid snappy length")
+	}
+	dst := make([]byte, 0, size)
+	for pos := n; pos < len(src); {
+		tag := src[pos]
+		pos++
+		var length, offset int
+		switch tag & 3 {
+		case 0:
+			length = int(tag>>2) + 1
+			if length > 60 {
+				extra := length - 60
+				if pos+extra > len(src) {
+					return nil, io.ErrUnexpectedEOF
+				}
+				length = 0
+				for i := extra - 1; i >= 0; i-- {
+					length = length<<8 | int(src[pos+i])
+				}
+				length++
+				pos += extra
+			}
+			if length > len(src)-pos {
+				return nil, io.ErrUnexpectedEOF
+			}
+			dst = append(dst, src[pos:pos+length]...)
+			pos += length
+			continue
+		case 1:
+			if pos >= len(src) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			length = 4 + int(tag>>2&7)
+			offset = int(tag&0xe0)<<3 | int(src[pos])
+			pos++
+		case 2:
+			if pos+2 > len(src) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint16(src[pos:]))
+			pos += 2
+		case 3:
+			if pos+4 > len(src) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint32(src[pos:]))
+			pos += 4
+		}
+		if offset <= 0 || offset > len(dst) {
+			return nil, fmt.Errorf("invalid snappy copy offset %d", offset)
+		}
+		// Copies may overlap their own output, so they run byte by byte.
+		for start := len(dst) - offset; length > 0; length-- {
+			dst = append(dst, dst[start])
+			start++
+		}
+	}
+	if uint64(len(dst)) != size {
+		return nil, errors.New("snappy length mismatch")
+	}
+	return dst, nil
+}
+
+// iwaObject is one archived object of an IWA file.
+type iwaObject struct {
+	id      uint64
+	typ     uint64
+	payload []byte
+}
+
+// readIWA decodes an .iwa file: Snappy chunks without checksums, concatenated into a stream of
+// length-prefixed ArchiveInfo headers, each followed by its message payloads.
+func readIWA(data []byte) ([]iwaObject, error) {
+	var stream []byte
+	for pos := 0; pos < len(data); {
+		if pos+4 > len(data) || data[pos] != 0 {
+			return nil, errors.New("invalid IWA chunk header")
+		}
+		length := int(data[pos+1]) | int(data[pos+2])<<8 | int(data[pos+3])<<16
+		pos += 4
+		if length > len(data)-pos {
+			return nil, io.ErrUnexpectedEOF
+		}
+		chunk, err := decodeSnappy(data[pos : pos+length])
+		if err != nil {
+			return nil, err
+		}
+		stream = append(stream, chunk...)
+		pos += length
+	}
+
+	var objects []iwaObject
+	for pos := 0; pos < len(stream); {
+		length, n := binary.Uvarint(stream[pos:])
+		if n <= 0 || length > uint64(len(stream)-pos-n) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		pos += n
+		info, err := decodeProto(stream[pos : pos+int(length)])
+		if err != nil {
+			return nil, err
+		}
+		pos += int(length)
+		for i, raw := range info[2] {
+			b, _ := raw.([]byte)
+			msgInfo, err := decodeProto(b)
+			if err != nil {
+				return nil, err
+			}
+			size := msgInfo.uint(3)
+			if size > uint64(len(stream)-pos) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			// Only the first message of an archive is the object itself; the rest are diffs.
+			if i == 0 {
+				objects = append(objects, iwaObject{id: info.uint(1), typ: msgInfo.uint(1), payload: stream[pos : pos+int(size)]})
+			}
+			pos += int(size)
+		}
+	}
+	return objects, nil
+}
+
+// iwaDocument indexes the objects of all IWA files in a bundle.
+type iwaDocument struct {
+	objects map[uint64]iwaObject
+	// files lists object IDs per IWA file, in archive order.
+	files map[string][]uint64
+}
+
+func (d *iwaDocument) add(name string, objects []iwaObject) {
+	for _, obj := range objects {
+		d.objects[obj.id] = obj
+		d.files[name] = append(d.files[name], obj.id)
+	}
+}
+
+// message decodes the object referenced by id if it has the given type.
+func (d *iwaDocument) message(id uint64, typ uint64) protoMessage {
+	obj, ok := d.objects[id]
+	if !ok || obj.typ != typ {
+		return nil
+	}
+	msg, err := decodeProto(obj.payload)
+	if err != nil {
+		return nil
+	}
+	return msg
+}
+
+// submessage decodes a nested message field.
+func submessage(m protoMessage, field uint64) protoMessage {
+	msg, err := decodeProto(m.bytes(field))
+	if err != nil {
+		return nil
+	}
+	return msg
+}
+
+// reference resolves a TSP.Reference field to an object ID.
+func reference(m protoMessage, field uint64) uint64 {
+	return submessage(m, field).uint(1)
+}
+
+// iwaStorage is the decoded text of a TSWP.StorageArchive.
+type iwaStorage struct {
+	kind uint64
+	text string
+}
+
+func (d *iwaDocument) storage(id uint64) (iwaStorage, bool) {
+	msg := d.message(id, iwaTypeStorage)
+	if msg == nil {
+		return iwaStorage{}, false
+	}
+	kind := uint64(iwaStorageTextbox)
+	if len(msg[1]) > 0 {
+		kind = msg.uint(1)
+	}
+	var parts []string
+	for _, raw := range msg[3] {
+		if b, ok := raw.([]byte); ok {
+			parts = append(parts, string(b))
+		}
+	}
+	return iwaStorage{kind: kind, text: cleanIWorkText(strings.Join(parts, ""))}, true
+}
+
+var iworkTextReplacer = strings.NewReplacer(
+	"\u2029", "\n", "\u2028", "\n", "\u000b", "\n", "\u000c", "\n", "\u0004", "\n", "\u0005", "\n",
+	"\ufffc", "", "\u200b", "",
+)
+
+// cleanIWorkText removes attachment placeholders and turns TSWP break characters into paragraph
+// breaks.
+func cleanIWorkText(text string) string {
+	var paragraphs []string
+	for _, line := range strings.Split(iworkTextReplacer.Replace(text), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			paragraphs = append(paragraphs, line)
+		}
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// iwaTable is a decoded TST.TableModelArchive.
+type iwaTable struct {
+	id   uint64
+	name string
+	rows [][]string
+}
+
+// dataList decodes a TST.TableDataList into its entries by key.
+func (d *iwaDocument) dataList(id uint64) map[uint64]protoMessage {
+	msg := d.message(id, iwaTypeTableDataList)
+	entries := map[uint64]protoMessage{}
+	for _, raw := range msg[3] {
+		b, _ := raw.([]byte)
+		if entry, err := decodeProto(b); err == nil {
+			entries[entry.uint(1)] = entry
+		}
+	}
+	return entries
+}
+
+// table decodes the cells of a table model. Tables whose tiles use a cell storage format other
+// than version 5 (iWork 2013 and later) are returned without rows.
+func (d *iwaDocument) table(id uint64) (iwaTable, bool) {
+	model := d.message(id, iwaTypeTableModel)
+	if model == nil {
+		return iwaTable{}, false
+	}
+	table := iwaTable{id: id, name: string(model.bytes(8))}
+	numRows, numCols := int(model.uint(6)), int(model.uint(7))
+	if numRows <= 0 || numCols <= 0 || numRows > 1<<20 || numCols > 1<<16 {
+		return table, true
+	}
+	store := submessage(model, 4)
+	stringTable := d.dataList(reference(store, 4))
+	richTable := d.dataList(reference(store, 17))
+
+	grid := make([][]string, numRows)
+	for i := range grid {
+		grid[i] = make([]string, numCols)
+	}
+	tiles := submessage(store, 3)
+	tileRows := tiles.uint(2)
+	if tileRows == 0 {
+		tileRows = iwaDefaultTileRows
+	}
+	used := 0
+	for _, raw := range tiles[1] {
+		b, _ := raw.([]byte)
+		ref, err := decodeProto(b)
+		if err != nil {
+			continue
+		}
+		tile := d.message(reference(ref, 2), iwaTypeTile)
+		for _, rowRaw := range tile[5] {
+			rb, _ := rowRaw.([]byte)
+			rowInfo, err := decodeProto(rb)
+			if err != nil {
+				continue
+			}
+			row := int(ref.uint(1)*tileRows + rowInfo.uint(1))
+			if row >= numRows {
+				continue
+			}
+			buffer, offsets := rowInfo.bytes(6), rowInfo.bytes(7)
+			wide := rowInfo.uint(8) != 0
+			for col := 0; col < numCols && 2*col+1 < len(offsets); col++ {
+				offset := int(binary.LittleEndian.Uint16(offsets[2*col:]))
+				if offset == 0xffff {
+					continue
+				}
+				if wide {
+					offset *= 4
+				}
+				if offset >= len(buffer) {
+					continue
+				}
+				if value, ok := d.cellValue(buffer[offset:], stringTable, richTable); ok {
+					grid[row][col] = value
+					used = max(used, row+1)
+				}
+			}
+		}
+	}
+	table.rows = grid[:used]
+	return table, true
+}
+
+// cellValue decodes a version 5 cell storage record.
+func (d *iwaDocument) cellValue(buf []byte, stringTable, richTable map[uint64]protoMessage) (string, bool) {
+	if len(buf) < 12 || buf[0] < iwaCellStorageVersion {
+		return "", false
+	}
+	cellType := buf[1]
+	flags := binary.LittleEndian.Uint32(buf[8:])
+	pos := 12
+	field := func(flag uint32, size int) []byte {
+		if flags&flag == 0 || pos+size > len(buf) {
+			return nil
+		}
+		b := buf[pos : pos+size]
+		pos += size
+		return b
+	}
+	d128, double, seconds := field(0x1, 16), field(0x2, 8), field(0x4, 8)
+	stringID, richID := field(0x8, 4), field(0x10, 4)
+
+	number := func(b []byte) float64 {
+		if b == nil {
+			return 0
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b))
+	}
+	switch cellType {
+	case iwaCellNumber:
+		if d128 != nil {
+			return decimal128String(d128), true
+		}
+		return strconv.FormatFloat(number(double), 'f', -1, 64), true
+	case iwaCellText:
+		if stringID == nil {
+			return "", false
+		}
+		return string(stringTable[uint64(binary.LittleEndian.Uint32(stringID))].bytes(3)), true
+	case iwaCellDate:
+		t := iwaEpoch.Add(time.Duration(number(seconds) * float64(time.Second)))
+		if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+			return t.Format("2006-01-02"), true
+		}
+		return t.Format(time.RFC3339), true
+	case iwaCellBool:
+		if number(double) != 0 {
+			return "TRUE", true
+		}
+		return "FALSE", true
+	case iwaCellDuration:
+		return (time.Duration(number(double) * float64(time.Second))).String(), true
+	case iwaCellError:
+		return "#ERROR", true
+	case iwaCellRichText:
+		if richID == nil {
+			return "", false
+		}
+		entry := richTable[uint64(binary.LittleEndian.Uint32(richID))]
+		payload := d.message(reference(entry, 4), iwaTypeRichTextEntry)
+		storage, ok := d.storage(reference(payload, 1))
+		return strings.Join(strings.Fields(storage.text), " "), ok
+	}
+	return "", false
+}
+
+// decimal128String formats an IEEE 754-2008 decimal128 value as stored by iWork.
+func decimal128String(b []byte) string {
+	exp := int(b[15]&0x7f)<<7 | int(b[14]>>1) - 0x1820
+	mantissa := new(big.Int).SetUint64(uint64(b[14] & 1))
+	for i := 13; i >= 0; i-- {
+		mantissa.Lsh(mantissa, 8)
+		mantissa.Or(mantissa, big.NewInt(int64(b[i])))
+	}
+	digits := mantissa.String()
+	switch {
+	case digits == "0":
+	case exp >= 0:
+		digits += strings.Repeat("0", exp)
+	default:
+		if pad := -exp - len(digits) + 1; pad > 0 {
+			digits = strings.Repeat("0", pad) + digits
+		}
+		point := len(digits) + exp
+		digits = strings.TrimRight(strings.TrimRight(digits[:point]+"."+digits[point:], "0"), ".")
+	}
+	if b[15]&0x80 != 0 && digits != "0" {
+		digits = "-" + digits
+	}
+	return digits
+}
@@ -0,0 +1,255 @@
+package kreuzberg
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+// iwaWriter builds an IWA file from (id, type, payload) objects, using literal-only Snappy chunks.
+type iwaWriter struct {
+	stream []byte
+}
+
+func (w *iwaWriter) object(id, typ uint64, fields ...[]byte) {
+	payload := bytes.Join(fields, nil)
+	info := append(protoField(1, id), protoField(2, append(protoField(1, typ), protoField(3, uint64(len(payload)))...))...)
+	w.stream = binary.AppendUvarint(w.stream, uint64(len(info)))
+	w.stream = append(w.stream, info...)
+	w.stream = append(w.stream, payload...)
+}
+
+func (w *iwaWriter) bytes() []byte {
+	block := binary.AppendUvarint(nil, uint64(len(w.stream)))
+	for rest := w.stream; len(rest) > 0; {
+		n := min(len(rest), 60)
+		block = append(block, byte(n-1)<<2)
+		block = append(block, rest[:n]...)
+		rest = rest[n:]
+	}
+	header := []byte{0, byte(len(block)), byte(len(block) >> 8), byte(len(block) >> 16)}
+	return append(header, block...)
+}
+
+func iwaRef(field, id uint64) []byte {
+	return protoField(field, protoField(1, id))
+}
+
+func iwaStorageFields(kind uint64, text string) [][]byte {
+	return [][]byte{protoField(1, kind), protoField(3, []byte(text))}
+}
+
+func buildBundle(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+		w.Write(data)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// iwaCell encodes a version 5 cell storage record with the given optional fields.
+func iwaCell(cellType byte, flags uint32, fields ...[]byte) []byte {
+	cell := []byte{5, cellType, 0, 0, 0, 0, 0, 0}
+	cell = binary.LittleEndian.AppendUint32(cell, flags)
+	return append(cell, bytes.Join(fields, nil)...)
+}
+
+func decimal128(mantissa uint64, exp int, negative bool) []byte {
+	b := make([]byte, 16)
+	binary.LittleEndian.PutUint64(b, mantissa)
+	biased := exp + 0x1820
+	b[14] = byte(biased << 1)
+	b[15] = byte(biased >> 7)
+	if negative {
+		b[15] |= 0x80
+	}
+	return b
+}
+
+func TestDecodeSnappy(t *testing.T) {
+	// "abc" as a literal, then an overlapping 1-byte-offset copy and a 2-byte-offset copy.
+	block := []byte{15, 0x08, 'a', 'b', 'c', 0x15, 3, (3-1)<<2 | 2, 12, 0}
+	got, err := decodeSnappy(block)
+	if err != nil || string(got) != "abcabcabcabcabc" {
+		t.Fatalf("decodeSnappy = %q, %v", got, err)
+	}
+	if _, err := decodeSnappy([]byte{4, 0x01, 9}); err == nil {
+		t.Fatalf("expected error for copy before any output")
+	}
+}
+
+func TestDecimal128String(t *testing.T) {
+	cases := []struct {
+		mantissa uint64
+		exp      int
+		negative bool
+		want     string
+	}{
+		{125, -1, false, "12.5"},
+		{5, -3, true, "-0.005"},
+		{42, 2, false, "4200"},
+		{1200, -2, false, "12"},
+		{0, 0, true, "0"},
+	}
+	for _, c := range cases {
+		if got := decimal128String(decimal128(c.mantissa, c.exp, c.negative)); got != c.want {
+			t.Fatalf("decimal128String(%d, %d) = %q, want %q", c.mantissa, c.exp, got, c.want)
+		}
+	}
+}
+
+func TestExtractKeynoteIWA(t *testing.T) {
+	var doc, slide2, slide10, template iwaWriter
+	doc.object(1, 1)
+	slide10.object(20, iwaTypeStorage, iwaStorageFields(iwaStorageTextbox, "Roadmap\u2029Q3 goals\ufffc")...)
+	slide2.object(10, iwaTypeStorage, iwaStorageFields(iwaStorageTextbox, "Welcome")...)
+	slide2.object(11, iwaTypeStorage, iwaStorageFields(iwaStorageNote, "Say hi\u2028to everyone")...)
+	template.object(30, iwaTypeStorage, iwaStorageFields(iwaStorageTextbox, "Title Text")...)
+	data := buildBundle(t, map[string][]byte{
+		"Index/Document.iwa":          doc.bytes(),
+		"Index/Slide-10.iwa":          slide10.bytes(),
+		"Index/Slide-2.iwa":           slide2.bytes(),
+		"Index/TemplateSlide-1.iwa":   template.bytes(),
+		"Metadata/Properties.plist":   []byte("bplist00"),
+		"Metadata/DocumentIdentifier": []byte("ABC"),
+	})
+
+	extractor, mimeType := selectGoPrimaryExtractor(documentSource{path: "talk.key"}, nil)
+	if extractor == nil || extractor.name != "iwork" || mimeType != mimeKeynote {
+		t.Fatalf("unexpected routing: %v %q", extractor, mimeType)
+	}
+	config := &ExtractionConfig{Pages: &PageConfig{ExtractPages: BoolPtr(true)}}
+	result, err := extractor.extract(documentSource{data: data}, mimeType, config)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	want := "## Slide 1\n\nWelcome\n\n### Notes\n\nSay hi\n\nto everyone\n\n## Slide 2\n\nRoadmap\n\nQ3 goals"
+	if result.Content != want {
+		t.Fatalf("unexpected content:\n%q", result.Content)
+	}
+	if len(result.Pages) != 2 || !strings.HasPrefix(result.Pages[1].Content, "## Slide 2") {
+		t.Fatalf("unexpected pages: %+v", result.Pages)
+	}
+	if result.Metadata.PageStructure == nil || result.Metadata.PageStructure.UnitType != PageUnitTypeSlide {
+		t.Fatalf("unexpected page structure: %+v", result.Metadata.PageStructure)
+	}
+	var slides []IWorkSlide
+	if err := json.Unmarshal(result.Metadata.Additional["slides"], &slides); err != nil || len(slides) != 2 {
+		t.Fatalf("unexpected slides: %s", result.Metadata.Additional["slides"])
+	}
+	if slides[0] != (IWorkSlide{Number: 1, Title: "Welcome", Notes: "Say hi\n\nto everyone"}) || slides[1].Title != "Roadmap" {
+		t.Fatalf("unexpected slides: %+v", slides)
+	}
+}
+
+func TestExtractNumbersIWA(t *testing.T) {
+	offsets := func(values ...uint16) []byte {
+		var out []byte
+		for _, v := range values {
+			out = binary.LittleEndian.AppendUint16(out, v)
+		}
+		return out
+	}
+	header := bytes.Join([][]byte{
+		iwaCell(iwaCellText, 0x8, binary.LittleEndian.AppendUint32(nil, 1)),
+		iwaCell(iwaCellText, 0x8, binary.LittleEndian.AppendUint32(nil, 2)),
+	}, nil)
+	headerOffsets := offsets(0, 16, 0xffff)
+	amount := iwaCell(iwaCellNumber, 0x1|0x2, decimal128(125, -1, false), binary.LittleEndian.AppendUint64(nil, math.Float64bits(12.5)))
+	flag := iwaCell(iwaCellBool, 0x2, binary.LittleEndian.AppendUint64(nil, math.Float64bits(1)))
+	body := append(append([]byte{}, amount...), flag...)
+	bodyOffsets := offsets(0, uint16(len(amount)))
+	rowInfo := func(index uint64, buffer, offsets []byte) []byte {
+		return protoField(5, bytes.Join([][]byte{protoField(1, index), protoField(2, uint64(2)), protoField(6, buffer), protoField(7, offsets)}, nil))
+	}
+
+	var doc, tiles iwaWriter
+	doc.object(1, iwaTypeNumbersSheet, protoField(1, []byte("Budget")), iwaRef(2, 2))
+	doc.object(2, iwaTypeTableInfo, iwaRef(2, 3))
+	dataStore := bytes.Join([][]byte{
+		protoField(3, protoField(1, bytes.Join([][]byte{protoField(1, uint64(0)), iwaRef(2, 4)}, nil))),
+		iwaRef(4, 5),
+	}, nil)
+	doc.object(3, iwaTypeTableModel, protoField(8, []byte("Costs")), protoField(6, uint64(3)), protoField(7, uint64(2)), protoField(4, dataStore))
+	doc.object(6, iwaTypeStorage, iwaStorageFields(iwaStorageCell, "Item")...)
+	doc.object(7, iwaTypeStorage, iwaStorageFields(iwaStorageTextbox, "Quarterly figures")...)
+	tiles.object(4, iwaTypeTile, rowInfo(0, header, headerOffsets), rowInfo(1, body, bodyOffsets))
+	tiles.object(5, iwaTypeTableDataList,
+		protoField(1, uint64(1)),
+		protoField(3, append(protoField(1, uint64(1)), protoField(3, []byte("Item"))...)),
+		protoField(3, append(protoField(1, uint64(2)), protoField(3, []byte("Paid"))...)),
+	)
+	data := buildBundle(t, map[string][]byte{
+		"Index/Document.iwa":          doc.bytes(),
+		"Index/CalculationEngine.iwa": (&iwaWriter{}).bytes(),
+		"Index/Tables/Tile.iwa":       tiles.bytes(),
+	})
+
+	result, err := extractIWork(documentSource{data: data}, "", nil)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if len(result.Tables) != 1 {
+		t.Fatalf("unexpected tables: %+v", result.Tables)
+	}
+	cells := result.Tables[0].Cells
+	if len(cells) != 2 || cells[0][0] != "Item" || cells[0][1] != "Paid" || cells[1][0] != "12.5" || cells[1][1] != "TRUE" {
+		t.Fatalf("unexpected cells: %q", cells)
+	}
+	for _, want := range []string{"## Budget", "### Costs", "Quarterly figures", "| 12.5 | TRUE |"} {
+		if !strings.Contains(result.Content, want) {
+			t.Fatalf("content missing %q:\n%s", want, result.Content)
+		}
+	}
+	if strings.Count(result.Content, "Item") != 1 {
+		t.Fatalf("cell storages should not be repeated as text:\n%s", result.Content)
+	}
+	if string(result.Metadata.Additional["iwork_application"]) != `"numbers"` || string(result.Metadata.Additional["sheet_names"]) != `["Budget"]` {
+		t.Fatalf("unexpected metadata: %v", result.Metadata.Additional)
+	}
+}
+
+func TestExtractPages09(t *testing.T) {
+	index := `<?xml version="1.0"?>
+<sl:document xmlns:sl="http://developer.apple.com/namespaces/sl" xmlns:sf="http://developer.apple.com/namespaces/sf" xmlns:sfa="http://developer.apple.com/namespaces/sfa">
+  <sf:metadata><sf:title><sf:string sfa:string="Minutes"/></sf:title><sf:authors><sf:string sfa:string="Alex"/></sf:authors></sf:metadata>
+  <sf:stylesheet><sf:p>Style sample</sf:p></sf:stylesheet>
+  <sf:text-storage><sf:text-body><sf:section><sf:layout><sf:p>First <sf:span>point</sf:span>.</sf:p><sf:p>Second point.</sf:p></sf:layout></sf:section></sf:text-body></sf:text-storage>
+</sl:document>`
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte(index))
+	zw.Close()
+	data := buildBundle(t, map[string][]byte{
+		"index.xml.gz":                       gz.Bytes(),
+		"Metadata/BuildVersionHistory.plist": []byte(`<?xml version="1.0"?><plist><array><string>Pages 4.3</string></array></plist>`),
+	})
+
+	result, err := extractIWork(documentSource{data: data}, mimePages, nil)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if result.Content != "First point.\n\nSecond point." || result.Metadata.PageStructure != nil {
+		t.Fatalf("unexpected result: %q %+v", result.Content, result.Metadata.PageStructure)
+	}
+	if string(result.Metadata.Additional["title"]) != `"Minutes"` || string(result.Metadata.Additional["iwork_format"]) != `"xml"` || string(result.Metadata.Additional["build_versions"]) != `["Pages 4.3"]` {
+		t.Fatalf("unexpected metadata: %v", result.Metadata.Additional)
+	}
+	if _, err := extractIWork(documentSource{data: []byte("not a zip")}, mimePages, nil); err == nil {
+		t.Fatalf("expected error for non-zip input")
+	}
+}
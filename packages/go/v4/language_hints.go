@@ -0,0 +1,187 @@
+package kreuzberg
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// LanguageHint tells indexing layers how to analyze and sort text in one of the document's
+// languages.
+type LanguageHint struct {
+	// Language is the language code as detected (ISO 639-1 or 639-3).
+	Language string `json:"language"`
+	// Locale is the BCP 47 tag for the language, suitable for choosing a collator
+	// (collate.New(language.Make(Locale))) or ICU sort rules.
+	Locale string `json:"locale"`
+	// Script is the ISO 15924 code of the script the language is usually written in.
+	Script string `json:"script,omitempty"`
+	// Direction is "ltr" or "rtl".
+	Direction string `json:"direction,omitempty"`
+	// Analyzer names the matching built-in Elasticsearch/OpenSearch language analyzer, or
+	// "standard" when there is none.
+	Analyzer string `json:"analyzer"`
+	// DictionarySegmentation reports that words are not separated by spaces, so tokenizers need
+	// dictionary-based segmentation (Chinese, Japanese, Thai, Lao, Khmer, Burmese).
+	DictionarySegmentation bool `json:"dictionary_segmentation,omitempty"`
+}
+
+// languageHintTable lists ISO 639-1 code, ISO 639-3 code, script and analyzer per language.
+const languageHintTable = `
+af afr Latn standard
+am amh Ethi standard
+ar ara Arab arabic
+az aze Latn standard
+be bel Cyrl standard
+bg bul Cyrl bulgarian
+bn ben Beng bengali
+ca cat Latn catalan
+cs ces Latn czech
+cy cym Latn standard
+da dan Latn danish
+de deu Latn german
+el ell Grek greek
+en eng Latn english
+eo epo Latn standard
+es spa Latn spanish
+et est Latn estonian
+eu eus Latn basque
+fa fas Arab persian
+fi fin Latn finnish
+fr fra Latn french
+ga gle Latn irish
+gl glg Latn galician
+gu guj Gujr standard
+he heb Hebr standard
+hi hin Deva hindi
+hr hrv Latn standard
+hu hun Latn hungarian
+hy hye Armn armenian
+id ind Latn indonesian
+is isl Latn standard
+it ita Latn italian
+ja jpn Jpan cjk
+ka kat Geor standard
+kk kaz Cyrl standard
+km khm Khmr standard
+kn kan Knda standard
+ko kor Kore cjk
+la lat Latn standard
+lo lao Laoo standard
+lt lit Latn lithuanian
+lv lav Latn latvian
+mk mkd Cyrl standard
+ml mal Mlym standard
+mr mar Deva standard
+ms msa Latn standard
+my mya Mymr standard
+nb nob Latn norwegian
+ne nep Deva standard
+nl nld Latn dutch
+nn nno Latn norwegian
+no nor Latn norwegian
+pa pan Guru standard
+pl pol Latn standard
+pt por Latn portuguese
+ro ron Latn romanian
+ru rus Cyrl russian
+si sin Sinh standard
+sk slk Latn standard
+sl slv Latn standard
+sq sqi Latn standard
+sr srp Cyrl standard
+sv swe Latn swedish
+sw swa Latn standard
+ta tam Taml standard
+te tel Telu standard
+th tha Thai thai
+tl tgl Latn standard
+tr tur Latn turkish
+uk ukr Cyrl standard
+ur urd Arab standard
+uz uzb Latn standard
+vi vie Latn standard
+yi yid Hebr standard
+zh zho Hans cjk
+`
+
+var (
+	// languageHints is keyed by ISO 639-1 code; languageAliases maps ISO 639-3 codes (including
+	// the individual-language codes language detectors report) to ISO 639-1.
+	languageHints   = map[string]LanguageHint{}
+	languageAliases = map[string]string{"cmn": "zh", "pes": "fa", "zsm": "ms", "arb": "ar", "ekk": "et", "lvs": "lv", "swh": "sw", "uzn": "uz"}
+	rtlScripts      = map[string]bool{"Arab": true, "Hebr": true, "Syrc": true, "Thaa": true}
+	unspacedScripts = map[string]bool{"Hans": true, "Hant": true, "Jpan": true, "Thai": true, "Laoo": true, "Khmr": true, "Mymr": true}
+)
+
+func init() {
+	for _, line := range strings.Split(strings.TrimSpace(languageHintTable), "\n") {
+		f := strings.Fields(line)
+		direction := "ltr"
+		if rtlScripts[f[2]] {
+			direction = "rtl"
+		}
+		languageHints[f[0]] = LanguageHint{Locale: f[0], Script: f[2], Direction: direction, Analyzer: f[3], DictionarySegmentation: unspacedScripts[f[2]]}
+		languageAliases[f[1]] = f[0]
+	}
+}
+
+// LanguageHintFor returns the hint for a language code: ISO 639-1, ISO 639-3 or a BCP 47 tag
+// with a region ("pt-BR"). Unknown languages get the standard analyzer and no script.
+func LanguageHintFor(code string) LanguageHint {
+	code = strings.TrimSpace(code)
+	base, region, _ := strings.Cut(strings.ReplaceAll(code, "_", "-"), "-")
+	base = strings.ToLower(base)
+	if alias, ok := languageAliases[base]; ok {
+		base = alias
+	}
+	hint, ok := languageHints[base]
+	if !ok {
+		hint = LanguageHint{Locale: base, Analyzer: "standard"}
+	}
+	hint.Language = code
+	if region != "" {
+		hint.Locale += "-" + region
+	}
+	return hint
+}
+
+// LanguageHints returns the hints recorded when ExtractionConfig.LanguageHints was set, primary
+// language first.
+func (m Metadata) LanguageHints() ([]LanguageHint, bool) {
+	var hints []LanguageHint
+	if found, err := m.Decode("language_hints", &hints); !found || err != nil {
+		return nil, false
+	}
+	return hints, true
+}
+
+// annotateLanguageHints stores a hint per language of the result, the metadata language first
+// and then the detected languages, in result.Metadata.Additional["language_hints"].
+func annotateLanguageHints(result *ExtractionResult) error {
+	codes := result.DetectedLanguages
+	if result.Metadata.Language != nil {
+		codes = append([]string{*result.Metadata.Language}, codes...)
+	}
+	var hints []LanguageHint
+	seen := map[string]bool{}
+	for _, code := range codes {
+		hint := LanguageHintFor(code)
+		if hint.Locale == "" || seen[hint.Locale] {
+			continue
+		}
+		seen[hint.Locale] = true
+		hints = append(hints, hint)
+	}
+	if len(hints) == 0 {
+		return nil
+	}
+	raw, err := json.Marshal(hints)
+	if err != nil {
+		return newSerializationErrorWithContext("failed to encode language hints", err, ErrorCodeValidation, nil)
+	}
+	if result.Metadata.Additional == nil {
+		result.Metadata.Additional = map[string]json.RawMessage{}
+	}
+	result.Metadata.Additional["language_hints"] = raw
+	return nil
+}
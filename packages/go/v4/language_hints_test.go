@@ -0,0 +1,54 @@
+package kreuzberg
+
+import "testing"
+
+func TestLanguageHintFor(t *testing.T) {
+	cases := []struct {
+		code string
+		want LanguageHint
+	}{
+		{"de", LanguageHint{Language: "de", Locale: "de", Script: "Latn", Direction: "ltr", Analyzer: "german"}},
+		{"eng", LanguageHint{Language: "eng", Locale: "en", Script: "Latn", Direction: "ltr", Analyzer: "english"}},
+		{"pt_BR", LanguageHint{Language: "pt_BR", Locale: "pt-BR", Script: "Latn", Direction: "ltr", Analyzer: "portuguese"}},
+		{"cmn", LanguageHint{Language: "cmn", Locale: "zh", Script: "Hans", Direction: "ltr", Analyzer: "cjk", DictionarySegmentation: true}},
+		{"heb", LanguageHint{Language: "heb", Locale: "he", Script: "Hebr", Direction: "rtl", Analyzer: "standard"}},
+		{"xx", LanguageHint{Language: "xx", Locale: "xx", Analyzer: "standard"}},
+	}
+	for _, tc := range cases {
+		if got := LanguageHintFor(tc.code); got != tc.want {
+			t.Errorf("LanguageHintFor(%q) = %+v, want %+v", tc.code, got, tc.want)
+		}
+	}
+}
+
+func TestAnnotateLanguageHints(t *testing.T) {
+	primary := "en"
+	result := &ExtractionResult{DetectedLanguages: []string{"eng", "ara", "fra"}, Metadata: Metadata{Language: &primary}}
+	if err := annotateLanguageHints(result); err != nil {
+		t.Fatalf("annotate: %v", err)
+	}
+	hints, ok := result.Metadata.LanguageHints()
+	if !ok || len(hints) != 3 {
+		t.Fatalf("expected one hint per distinct language, got %+v", hints)
+	}
+	if hints[0].Locale != "en" || hints[1].Analyzer != "arabic" || hints[1].Direction != "rtl" || hints[2].Locale != "fr" {
+		t.Fatalf("unexpected hints: %+v", hints)
+	}
+
+	result = &ExtractionResult{}
+	if err := annotateLanguageHints(result); err != nil || result.Metadata.Additional != nil {
+		t.Fatalf("expected no hints without languages, got %v, %v", result.Metadata.Additional, err)
+	}
+}
+
+func TestLanguageHintsDuringExtraction(t *testing.T) {
+	enabled := true
+	config := &ExtractionConfig{XMLProfile: &XMLProfileConfig{}, MetadataProvenance: &MetadataProvenanceConfig{}, LanguageHints: &enabled}
+	result, err := ExtractBytesSync([]byte(testDocBook), mimeXML, config)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if hints, ok := result.Metadata.LanguageHints(); !ok || hints[0].Analyzer != "english" {
+		t.Fatalf("expected a hint for the document language, got %+v", hints)
+	}
+}
@@ -0,0 +1,252 @@
+package kreuzberg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// LockfileName is the conventional name of a lockfile, kept next to the config file.
+const LockfileName = "kreuzberg.lock"
+
+// lockfileVersion versions the lockfile layout.
+const lockfileVersion = 1
+
+// tessdataSearchPaths are the directories the native library looks for tessdata in when
+// TESSDATA_PREFIX is unset, in order.
+var tessdataSearchPaths = []string{
+	"/opt/homebrew/share/tessdata",
+	"/opt/homebrew/opt/tesseract/share/tessdata",
+	"/usr/local/opt/tesseract/share/tessdata",
+	"/usr/share/tesseract-ocr/5/tessdata",
+	"/usr/share/tesseract-ocr/4/tessdata",
+	"/usr/share/tessdata",
+	"/usr/local/share/tessdata",
+	`C:\Program Files\Tesseract-OCR\tessdata`,
+	`C:\ProgramData\Tesseract-OCR\tessdata`,
+}
+
+// Lockfile pins the runtime artifacts that determine extraction output: the native library
+// version, the Tesseract language data and the embedding models. Record it with GenerateLock
+// where results are known to be good, commit it, and check deployments with Verify.
+type Lockfile struct {
+	Version        int    `json:"version"`
+	LibraryVersion string `json:"library_version"`
+	// Tessdata lists the *.traineddata files by name.
+	Tessdata []LockedFile `json:"tessdata,omitempty"`
+	// EmbeddingModels lists the files of the embedding model cache by slash-separated path
+	// relative to the cache directory.
+	EmbeddingModels []LockedFile `json:"embedding_models,omitempty"`
+}
+
+// LockedFile is a file pinned by a Lockfile.
+type LockedFile struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// LockOptions locates the artifacts recorded in and verified against a Lockfile.
+type LockOptions struct {
+	// TessdataDir is the Tesseract language data directory (default: TESSDATA_PREFIX, or the
+	// first standard location that exists, as searched by the native library).
+	TessdataDir string
+	// Languages restricts the tessdata files to these languages, e.g. "eng" (default: all).
+	Languages []string
+	// EmbeddingCacheDir is the embedding model cache (default: EmbeddingConfig.CacheDir's
+	// default, ".kreuzberg/embeddings" in the working directory).
+	EmbeddingCacheDir string
+}
+
+// LockMismatch is a difference between a Lockfile and the runtime.
+type LockMismatch struct {
+	// Kind is "library", "tessdata" or "embedding_model".
+	Kind string `json:"kind"`
+	// Name is the file name (empty for the library).
+	Name string `json:"name,omitempty"`
+	// Locked and Actual are the pinned and the found version or checksum; Actual is empty when
+	// the file is missing.
+	Locked string `json:"locked"`
+	Actual string `json:"actual"`
+}
+
+func (m LockMismatch) String() string {
+	subject := m.Kind
+	if m.Name != "" {
+		subject += " " + m.Name
+	}
+	if m.Actual == "" {
+		return fmt.Sprintf("%s: missing (locked %s)", subject, m.Locked)
+	}
+	return fmt.Sprintf("%s: %s, locked %s", subject, m.Actual, m.Locked)
+}
+
+// GenerateLock records the artifacts of the current runtime. Missing artifact directories are
+// recorded as empty. Embedding models are hashed in full, which takes a few seconds for large
+// models.
+func GenerateLock(opts LockOptions) (*Lockfile, error) {
+	lock := &Lockfile{Version: lockfileVersion, LibraryVersion: LibraryVersion()}
+	var err error
+	if lock.Tessdata, err = hashTessdata(opts); err != nil {
+		return nil, err
+	}
+	if lock.EmbeddingModels, err = hashTree(embeddingCacheDir(opts)); err != nil {
+		return nil, err
+	}
+	return lock, nil
+}
+
+// ReadLockfile reads a lockfile written by Lockfile.Write.
+func ReadLockfile(path string) (*Lockfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, newIOErrorWithContext("failed to read lockfile", err, ErrorCodeIo, nil)
+	}
+	var lock Lockfile
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, newSerializationErrorWithContext("failed to decode lockfile", err, ErrorCodeValidation, nil)
+	}
+	if lock.Version != lockfileVersion {
+		return nil, newValidationErrorWithContext(fmt.Sprintf("unsupported lockfile version %d", lock.Version), nil, ErrorCodeValidation, nil)
+	}
+	return &lock, nil
+}
+
+// Write writes the lockfile as indented JSON, for readable diffs under version control.
+func (l *Lockfile) Write(path string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return newSerializationErrorWithContext("failed to encode lockfile", err, ErrorCodeValidation, nil)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return newIOErrorWithContext("failed to write lockfile", err, ErrorCodeIo, nil)
+	}
+	return nil
+}
+
+// Verify compares the lockfile with the current runtime and returns the differences: a
+// different native library version, and pinned files that are missing or changed. Files that
+// are present but not pinned are not reported. An empty result means the runtime matches.
+func (l *Lockfile) Verify(opts LockOptions) ([]LockMismatch, error) {
+	var mismatches []LockMismatch
+	if actual := LibraryVersion(); actual != l.LibraryVersion {
+		mismatches = append(mismatches, LockMismatch{Kind: "library", Locked: l.LibraryVersion, Actual: actual})
+	}
+	tessdata, err := hashTessdata(opts)
+	if err != nil {
+		return nil, err
+	}
+	models, err := hashTree(embeddingCacheDir(opts))
+	if err != nil {
+		return nil, err
+	}
+	mismatches = append(mismatches, lockedFileMismatches("tessdata", l.Tessdata, tessdata)...)
+	mismatches = append(mismatches, lockedFileMismatches("embedding_model", l.EmbeddingModels, models)...)
+	return mismatches, nil
+}
+
+func lockedFileMismatches(kind string, locked, actual []LockedFile) []LockMismatch {
+	var mismatches []LockMismatch
+	for _, want := range locked {
+		i := slices.IndexFunc(actual, func(f LockedFile) bool { return f.Name == want.Name })
+		switch {
+		case i < 0:
+			mismatches = append(mismatches, LockMismatch{Kind: kind, Name: want.Name, Locked: want.SHA256})
+		case actual[i].SHA256 != want.SHA256:
+			mismatches = append(mismatches, LockMismatch{Kind: kind, Name: want.Name, Locked: want.SHA256, Actual: actual[i].SHA256})
+		}
+	}
+	return mismatches
+}
+
+// tessdataDir resolves the tessdata directory like the native library does.
+func tessdataDir(opts LockOptions) string {
+	if opts.TessdataDir != "" {
+		return opts.TessdataDir
+	}
+	if prefix := os.Getenv("TESSDATA_PREFIX"); prefix != "" {
+		return prefix
+	}
+	for _, dir := range tessdataSearchPaths {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+	}
+	return ""
+}
+
+func hashTessdata(opts LockOptions) ([]LockedFile, error) {
+	files, err := hashTree(tessdataDir(opts))
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(files, func(f LockedFile) bool {
+		lang, ok := strings.CutSuffix(f.Name, ".traineddata")
+		return !ok || strings.Contains(lang, "/") || (len(opts.Languages) > 0 && !slices.Contains(opts.Languages, lang))
+	}), nil
+}
+
+func embeddingCacheDir(opts LockOptions) string {
+	if opts.EmbeddingCacheDir != "" {
+		return opts.EmbeddingCacheDir
+	}
+	return filepath.Join(".kreuzberg", "embeddings")
+}
+
+// hashTree hashes the regular files below dir, sorted by path. Symlinks (e.g. model snapshot
+// links) and the download lock and partial files of the model hub are skipped. A missing dir
+// has no files.
+func hashTree(dir string) ([]LockedFile, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	var files []LockedFile
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipAll
+			}
+			return err
+		}
+		name := d.Name()
+		if !d.Type().IsRegular() || strings.HasSuffix(name, ".lock") || strings.HasSuffix(name, ".incomplete") {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		file := LockedFile{Name: filepath.ToSlash(rel)}
+		if file.SHA256, file.Size, err = hashFileSize(path); err != nil {
+			return err
+		}
+		files = append(files, file)
+		return nil
+	})
+	if err != nil {
+		return nil, newIOErrorWithContext(fmt.Sprintf("failed to hash %s", dir), err, ErrorCodeIo, nil)
+	}
+	return files, nil
+}
+
+func hashFileSize(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
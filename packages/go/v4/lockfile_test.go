@@ -0,0 +1,70 @@
+package kreuzberg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLockfileRoundTripAndVerify(t *testing.T) {
+	tessdata, models := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(tessdata, "eng.traineddata"), []byte("english"), 0o600)
+	os.WriteFile(filepath.Join(tessdata, "deu.traineddata"), []byte("german"), 0o600)
+	os.WriteFile(filepath.Join(tessdata, "README"), []byte("docs"), 0o600)
+	os.MkdirAll(filepath.Join(models, "model", "blobs"), 0o755)
+	blob := filepath.Join(models, "model", "blobs", "abc")
+	os.WriteFile(blob, []byte("weights"), 0o600)
+	os.WriteFile(filepath.Join(models, "model", "blobs", "abc.lock"), nil, 0o600)
+	os.Symlink(blob, filepath.Join(models, "model", "snapshot"))
+
+	opts := LockOptions{TessdataDir: tessdata, Languages: []string{"eng"}, EmbeddingCacheDir: models}
+	lock, err := GenerateLock(opts)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if len(lock.Tessdata) != 1 || lock.Tessdata[0].Name != "eng.traineddata" || lock.Tessdata[0].Size != 7 {
+		t.Fatalf("unexpected tessdata %+v", lock.Tessdata)
+	}
+	if len(lock.EmbeddingModels) != 1 || lock.EmbeddingModels[0].Name != "model/blobs/abc" {
+		t.Fatalf("unexpected embedding models %+v", lock.EmbeddingModels)
+	}
+
+	path := filepath.Join(t.TempDir(), LockfileName)
+	if err := lock.Write(path); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	read, err := ReadLockfile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if mismatches, err := read.Verify(opts); err != nil || len(mismatches) != 0 {
+		t.Fatalf("expected the runtime to match, got %v, %v", mismatches, err)
+	}
+
+	// Unpinned files do not matter; changed and missing pinned files do.
+	os.WriteFile(filepath.Join(tessdata, "deu.traineddata"), []byte("deutsch"), 0o600)
+	os.WriteFile(filepath.Join(tessdata, "eng.traineddata"), []byte("english v2"), 0o600)
+	os.Remove(blob)
+	read.LibraryVersion = "0.0.1"
+	mismatches, err := read.Verify(opts)
+	if err != nil || len(mismatches) != 3 {
+		t.Fatalf("expected three mismatches, got %v, %v", mismatches, err)
+	}
+	if m := mismatches[0]; m.Kind != "library" || m.Locked != "0.0.1" {
+		t.Fatalf("unexpected library mismatch %v", m)
+	}
+	if m := mismatches[1]; m.Kind != "tessdata" || m.Actual == "" || m.Actual == m.Locked {
+		t.Fatalf("unexpected tessdata mismatch %v", m)
+	}
+	if m := mismatches[2]; m.Kind != "embedding_model" || m.Actual != "" || m.String() != "embedding_model model/blobs/abc: missing (locked "+m.Locked+")" {
+		t.Fatalf("unexpected embedding model mismatch %v", m)
+	}
+
+	os.WriteFile(path, []byte(`{"version": 99}`), 0o600)
+	if _, err := ReadLockfile(path); err == nil {
+		t.Fatalf("expected an unsupported version to be rejected")
+	}
+	if lock, err := GenerateLock(LockOptions{TessdataDir: filepath.Join(tessdata, "missing"), EmbeddingCacheDir: filepath.Join(models, "missing")}); err != nil || len(lock.Tessdata)+len(lock.EmbeddingModels) != 0 {
+		t.Fatalf("expected missing directories to record nothing, got %+v, %v", lock, err)
+	}
+}
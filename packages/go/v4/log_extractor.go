@@ -0,0 +1,446 @@
+package kreuzberg
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultLogMaxRecords = 1000
+	defaultLogChunkChars = 1000
+	mimeLog              = "text/x-log"
+	mimeNDJSON           = "application/x-ndjson"
+)
+
+// LogFormat names a log line format.
+type LogFormat string
+
+const (
+	// LogFormatAuto detects the format from the first lines of the file.
+	LogFormatAuto LogFormat = ""
+	// LogFormatSyslog is RFC 3164 or RFC 5424 syslog.
+	LogFormatSyslog LogFormat = "syslog"
+	// LogFormatJSONLines is one JSON object per line, as written by structured loggers.
+	LogFormatJSONLines LogFormat = "jsonl"
+	// LogFormatAccess is the Common or Combined Log Format used by web server access logs.
+	LogFormatAccess LogFormat = "access"
+	// LogFormatGeneric is lines starting with a timestamp and optional level; lines without a
+	// timestamp (e.g. stack traces) continue the previous record.
+	LogFormatGeneric LogFormat = "generic"
+)
+
+// LogConfig enables line-structured extraction of log files. Each record is parsed into a
+// LogRecord reported in Metadata.Additional["log_records"], and when chunking is enabled chunks
+// end on record boundaries instead of splitting records.
+type LogConfig struct {
+	// Format forces a log format (default: detected).
+	Format LogFormat
+	// MaxRecords caps the records reported in metadata (default 1000). Content always holds the
+	// full log.
+	MaxRecords int
+	// Year is assumed for timestamps that carry none, such as RFC 3164 syslog (default: current year).
+	Year int
+}
+
+// LogRecord is a parsed log record, as reported in Metadata.Additional["log_records"].
+type LogRecord struct {
+	// Line is the 1-based line number where the record starts.
+	Line int `json:"line"`
+	// Timestamp is the record time in RFC 3339 format, when it could be parsed.
+	Timestamp string `json:"timestamp,omitempty"`
+	// Level is the normalized severity: trace, debug, info, warning, error or critical.
+	Level   string `json:"level,omitempty"`
+	Message string `json:"message"`
+	// Fields holds format-specific attributes such as host, status, or JSON keys.
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// logEntry is a record together with its byte span in the content.
+type logEntry struct {
+	LogRecord
+	start, end int
+}
+
+var (
+	syslog5424Pattern   = regexp.MustCompile(`^<(\d{1,3})>1 (\S+) (\S+) (\S+) (\S+) (\S+) (-|(?:\[.*?\])+) ?(.*)$`)
+	syslog3164Pattern   = regexp.MustCompile(`^(?:<(\d{1,3})>)?([A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2}) (\S+) ([^:\[\s]+)(?:\[(\d+)\])?: ?(.*)$`)
+	accessLogPattern    = regexp.MustCompile(`^(\S+) (\S+) (\S+) \[([^\]]+)\] "([^"]*)" (\d{3}|-) (\d+|-)(?: "([^"]*)" "([^"]*)")?`)
+	genericTimePattern  = regexp.MustCompile(`^\[?(\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?)\]?\s*`)
+	genericLevelPattern = regexp.MustCompile(`^\[?(?i:(TRACE|DEBUG|INFO|NOTICE|WARN|WARNING|ERROR|ERR|FATAL|CRITICAL|CRIT|SEVERE))\]?:?\s+`)
+)
+
+var syslogSeverities = []string{"critical", "critical", "critical", "error", "warning", "info", "info", "debug"}
+
+var logTimestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999Z0700",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z0700",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"02/Jan/2006:15:04:05 -0700",
+}
+
+// normalizeLogLevel maps level spellings from common loggers to a small fixed set.
+func normalizeLogLevel(level string) string {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "trace", "finest", "finer":
+		return "trace"
+	case "debug", "fine", "verbose":
+		return "debug"
+	case "info", "information", "notice", "informational":
+		return "info"
+	case "warn", "warning":
+		return "warning"
+	case "error", "err", "severe":
+		return "error"
+	case "fatal", "critical", "crit", "panic", "emerg", "emergency", "alert":
+		return "critical"
+	}
+	return ""
+}
+
+// parseLogTimestamp normalizes a timestamp to RFC 3339, or returns "" when it is not recognized.
+func parseLogTimestamp(value string) string {
+	value = strings.Replace(strings.TrimSpace(value), ",", ".", 1)
+	for _, layout := range logTimestampLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Format(time.RFC3339Nano)
+		}
+	}
+	return ""
+}
+
+func parseSyslogLine(line string, year int) (LogRecord, bool) {
+	if m := syslog5424Pattern.FindStringSubmatch(line); m != nil {
+		record := LogRecord{Timestamp: parseLogTimestamp(m[2]), Message: m[8], Fields: map[string]string{}}
+		if pri, err := strconv.Atoi(m[1]); err == nil {
+			record.Level = syslogSeverities[pri%8]
+			record.Fields["facility"] = strconv.Itoa(pri / 8)
+		}
+		for i, key := range []string{"", "", "", "host", "app", "pid", "msgid", "structured_data"} {
+			if key != "" && m[i] != "-" {
+				record.Fields[key] = m[i]
+			}
+		}
+		return record, true
+	}
+	if m := syslog3164Pattern.FindStringSubmatch(line); m != nil {
+		record := LogRecord{Message: m[6], Fields: map[string]string{"host": m[3], "app": m[4]}}
+		if t, err := time.Parse("Jan _2 15:04:05", m[2]); err == nil {
+			record.Timestamp = t.AddDate(year, 0, 0).Format(time.RFC3339)
+		}
+		if m[1] != "" {
+			pri, _ := strconv.Atoi(m[1])
+			record.Level = syslogSeverities[pri%8]
+			record.Fields["facility"] = strconv.Itoa(pri / 8)
+		}
+		if m[5] != "" {
+			record.Fields["pid"] = m[5]
+		}
+		return record, true
+	}
+	return LogRecord{}, false
+}
+
+var (
+	jsonLogTimeKeys    = []string{"timestamp", "time", "ts", "@timestamp", "datetime", "date"}
+	jsonLogLevelKeys   = []string{"level", "severity", "lvl", "log.level", "loglevel"}
+	jsonLogMessageKeys = []string{"message", "msg", "log", "text"}
+)
+
+func parseJSONLogLine(line string) (LogRecord, bool) {
+	if !strings.HasPrefix(line, "{") {
+		return LogRecord{}, false
+	}
+	var fields map[string]any
+	dec := json.NewDecoder(strings.NewReader(line))
+	dec.UseNumber()
+	if err := dec.Decode(&fields); err != nil {
+		return LogRecord{}, false
+	}
+
+	record := LogRecord{Fields: map[string]string{}}
+	take := func(keys []string) (any, bool) {
+		for _, key := range keys {
+			if value, ok := fields[key]; ok {
+				delete(fields, key)
+				return value, true
+			}
+		}
+		return nil, false
+	}
+	if value, ok := take(jsonLogTimeKeys); ok {
+		record.Timestamp = jsonLogTimestamp(value)
+	}
+	if value, ok := take(jsonLogLevelKeys); ok {
+		record.Level = normalizeLogLevel(fmt.Sprint(value))
+	}
+	if value, ok := take(jsonLogMessageKeys); ok {
+		record.Message = fmt.Sprint(value)
+	}
+	for key, value := range fields {
+		if s, ok := value.(string); ok {
+			record.Fields[key] = s
+			continue
+		}
+		raw, _ := json.Marshal(value)
+		record.Fields[key] = string(raw)
+	}
+	return record, true
+}
+
+// jsonLogTimestamp accepts RFC 3339 strings and Unix epochs in seconds or milliseconds.
+func jsonLogTimestamp(value any) string {
+	switch v := value.(type) {
+	case string:
+		return parseLogTimestamp(v)
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			if n > 1e11 {
+				return time.UnixMilli(n).UTC().Format(time.RFC3339Nano)
+			}
+			return time.Unix(n, 0).UTC().Format(time.RFC3339Nano)
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return ""
+		}
+		if f > 1e11 {
+			f /= 1000
+		}
+		sec := int64(f)
+		return time.Unix(sec, int64((f-float64(sec))*1e9)).UTC().Format(time.RFC3339Nano)
+	}
+	return ""
+}
+
+func parseAccessLogLine(line string) (LogRecord, bool) {
+	m := accessLogPattern.FindStringSubmatch(line)
+	if m == nil {
+		return LogRecord{}, false
+	}
+	record := LogRecord{Timestamp: parseLogTimestamp(m[4]), Message: m[5], Level: "info", Fields: map[string]string{}}
+	for i, key := range []string{"", "remote_host", "ident", "user", "", "", "status", "bytes", "referer", "user_agent"} {
+		if key != "" && m[i] != "-" && m[i] != "" {
+			record.Fields[key] = m[i]
+		}
+	}
+	if parts := strings.Fields(m[5]); len(parts) == 3 {
+		record.Fields["method"], record.Fields["path"], record.Fields["protocol"] = parts[0], parts[1], parts[2]
+	}
+	switch {
+	case strings.HasPrefix(m[6], "5"):
+		record.Level = "error"
+	case strings.HasPrefix(m[6], "4"):
+		record.Level = "warning"
+	}
+	return record, true
+}
+
+func parseGenericLogLine(line string) (LogRecord, bool) {
+	m := genericTimePattern.FindStringSubmatch(line)
+	if m == nil {
+		return LogRecord{}, false
+	}
+	record := LogRecord{Timestamp: parseLogTimestamp(m[1])}
+	rest := line[len(m[0]):]
+	if lm := genericLevelPattern.FindStringSubmatch(rest); lm != nil {
+		record.Level = normalizeLogLevel(lm[1])
+		rest = rest[len(lm[0]):]
+	}
+	record.Message = rest
+	return record, true
+}
+
+// logLineParser returns the parser for format.
+func logLineParser(format LogFormat, year int) func(string) (LogRecord, bool) {
+	switch format {
+	case LogFormatSyslog:
+		return func(line string) (LogRecord, bool) { return parseSyslogLine(line, year) }
+	case LogFormatJSONLines:
+		return parseJSONLogLine
+	case LogFormatAccess:
+		return parseAccessLogLine
+	}
+	return parseGenericLogLine
+}
+
+// detectLogFormat picks the format that parses the most of the first non-empty lines.
+func detectLogFormat(lines []string) LogFormat {
+	best, bestCount := LogFormatGeneric, 0
+	for _, format := range []LogFormat{LogFormatJSONLines, LogFormatSyslog, LogFormatAccess, LogFormatGeneric} {
+		parse := logLineParser(format, 2000)
+		count, sampled := 0, 0
+		for _, line := range lines {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			if _, ok := parse(line); ok {
+				count++
+			}
+			if sampled++; sampled == 20 {
+				break
+			}
+		}
+		if count > bestCount {
+			best, bestCount = format, count
+		}
+	}
+	return best
+}
+
+// parseLogRecords splits text into records. Lines that do not parse continue the previous record
+// (multi-line messages, stack traces); leading unparsed lines form a record of their own.
+func parseLogRecords(text string, format LogFormat, year int) []logEntry {
+	parse := logLineParser(format, year)
+	var entries []logEntry
+	offset := 0
+	for i, line := range strings.SplitAfter(text, "\n") {
+		start := offset
+		offset += len(line)
+		line = strings.TrimRight(line, "\r\n")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if record, ok := parse(line); ok {
+			record.Line = i + 1
+			if len(record.Fields) == 0 {
+				record.Fields = nil
+			}
+			entries = append(entries, logEntry{LogRecord: record, start: start, end: start + len(line)})
+			continue
+		}
+		if len(entries) == 0 {
+			entries = append(entries, logEntry{LogRecord: LogRecord{Line: i + 1, Message: line}, start: start, end: start + len(line)})
+			continue
+		}
+		last := &entries[len(entries)-1]
+		last.Message += "\n" + line
+		last.end = start + len(line)
+	}
+	return entries
+}
+
+// chunkOnBoundaries groups consecutive spans of content into chunks of at most maxChars bytes,
+// never splitting a span. A span longer than maxChars becomes a chunk of its own.
+func chunkOnBoundaries(content string, spans [][2]int, maxChars int) []Chunk {
+	var chunks []Chunk
+	for i := 0; i < len(spans); {
+		start, end := spans[i][0], spans[i][1]
+		for i++; i < len(spans) && spans[i][1]-start <= maxChars; i++ {
+			end = spans[i][1]
+		}
+		chunks = append(chunks, Chunk{
+			Content:  content[start:end],
+			Metadata: ChunkMetadata{ByteStart: uint64(start), ByteEnd: uint64(end), ChunkIndex: len(chunks)},
+		})
+	}
+	for i := range chunks {
+		chunks[i].Metadata.TotalChunks = len(chunks)
+	}
+	return chunks
+}
+
+// chunkingMaxChars returns the chunk size requested by config, or 0 when chunking is disabled.
+func chunkingMaxChars(config *ExtractionConfig, fallback int) int {
+	if config == nil || config.Chunking == nil || (config.Chunking.Enabled != nil && !*config.Chunking.Enabled) {
+		return 0
+	}
+	switch {
+	case config.Chunking.MaxChars != nil && *config.Chunking.MaxChars > 0:
+		return *config.Chunking.MaxChars
+	case config.Chunking.ChunkSize != nil && *config.Chunking.ChunkSize > 0:
+		return *config.Chunking.ChunkSize
+	}
+	return fallback
+}
+
+func extractLog(src documentSource, mimeType string, config *ExtractionConfig) (*ExtractionResult, error) {
+	data, err := src.bytes()
+	if err != nil {
+		return nil, err
+	}
+	text, _, err := decodeText(data, "")
+	if err != nil {
+		return nil, err
+	}
+	opts := *config.Logs
+	if opts.MaxRecords <= 0 {
+		opts.MaxRecords = defaultLogMaxRecords
+	}
+	if opts.Year == 0 {
+		opts.Year = time.Now().Year()
+	}
+	if opts.Format == LogFormatAuto {
+		if mimeType == mimeNDJSON {
+			opts.Format = LogFormatJSONLines
+		} else {
+			opts.Format = detectLogFormat(strings.SplitN(text, "\n", 50))
+		}
+	}
+
+	entries := parseLogRecords(text, opts.Format, opts.Year)
+	records := make([]LogRecord, 0, min(len(entries), opts.MaxRecords))
+	spans := make([][2]int, len(entries))
+	levels := map[string]int{}
+	var first, last string
+	for i, entry := range entries {
+		spans[i] = [2]int{entry.start, entry.end}
+		if entry.Level != "" {
+			levels[entry.Level]++
+		}
+		if ts := entry.Timestamp; ts != "" {
+			if first == "" || ts < first {
+				first = ts
+			}
+			if ts > last {
+				last = ts
+			}
+		}
+		if len(records) < opts.MaxRecords {
+			records = append(records, entry.LogRecord)
+		}
+	}
+
+	result := &ExtractionResult{MimeType: mimeType, Content: strings.TrimRight(text, "\r\n"), Tables: []Table{}, Success: true}
+	if maxChars := chunkingMaxChars(config, defaultLogChunkChars); maxChars > 0 {
+		result.Chunks = chunkOnBoundaries(result.Content, spans, maxChars)
+	}
+
+	additional := map[string]any{
+		"log_format":        opts.Format,
+		"record_count":      len(entries),
+		"log_records":       records,
+		"records_truncated": len(entries) > len(records),
+		"level_counts":      levels,
+	}
+	if first != "" {
+		additional["time_range"] = map[string]string{"start": first, "end": last}
+	}
+	result.Metadata.Additional = make(map[string]json.RawMessage, len(additional))
+	for key, value := range additional {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, newSerializationErrorWithContext("failed to encode log metadata", err, ErrorCodeValidation, nil)
+		}
+		result.Metadata.Additional[key] = raw
+	}
+	return result, nil
+}
+
+func init() {
+	registerGoPrimaryExtractor(goPrimaryExtractor{
+		name:       "logs",
+		mimeTypes:  []string{mimeLog, mimeNDJSON, "application/jsonl"},
+		extensions: map[string]string{"log": mimeLog, "jsonl": mimeNDJSON, "ndjson": mimeNDJSON},
+		enabled: func(config *ExtractionConfig) bool {
+			return config != nil && config.Logs != nil
+		},
+		extract: extractLog,
+	})
+}
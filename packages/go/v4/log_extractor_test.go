@@ -0,0 +1,112 @@
+package kreuzberg
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseSyslogLines(t *testing.T) {
+	record, ok := parseSyslogLine(`<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3"] An application event`, 2024)
+	if !ok || record.Timestamp != "2003-10-11T22:14:15.003Z" || record.Level != "info" || record.Message != "An application event" {
+		t.Fatalf("unexpected RFC 5424 record: %+v", record)
+	}
+	if record.Fields["host"] != "mymachine.example.com" || record.Fields["msgid"] != "ID47" || record.Fields["facility"] != "20" {
+		t.Fatalf("unexpected RFC 5424 fields: %v", record.Fields)
+	}
+
+	record, ok = parseSyslogLine("<11>Mar  7 09:15:02 web01 sshd[4721]: Failed password for root", 2024)
+	if !ok || record.Timestamp != "2024-03-07T09:15:02Z" || record.Level != "error" || record.Fields["pid"] != "4721" || record.Fields["app"] != "sshd" {
+		t.Fatalf("unexpected RFC 3164 record: %+v", record)
+	}
+}
+
+func TestParseJSONAndAccessLogLines(t *testing.T) {
+	record, ok := parseJSONLogLine(`{"ts": 1700000000123, "level": "WARN", "msg": "disk almost full", "disk": "/dev/sda1", "pct": 91}`)
+	if !ok || record.Timestamp != "2023-11-14T22:13:20.123Z" || record.Level != "warning" || record.Message != "disk almost full" {
+		t.Fatalf("unexpected JSON record: %+v", record)
+	}
+	if record.Fields["disk"] != "/dev/sda1" || record.Fields["pct"] != "91" {
+		t.Fatalf("unexpected JSON fields: %v", record.Fields)
+	}
+
+	record, ok = parseAccessLogLine(`127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 503 2326 "http://example.com/" "Mozilla/4.08"`)
+	if !ok || record.Timestamp != "2000-10-10T13:55:36-07:00" || record.Level != "error" {
+		t.Fatalf("unexpected access record: %+v", record)
+	}
+	if record.Fields["method"] != "GET" || record.Fields["path"] != "/apache_pb.gif" || record.Fields["user"] != "frank" || record.Fields["user_agent"] != "Mozilla/4.08" {
+		t.Fatalf("unexpected access fields: %v", record.Fields)
+	}
+}
+
+func TestParseLogRecordsJoinsContinuationLines(t *testing.T) {
+	text := "2024-05-01 10:00:00,123 INFO starting\n2024-05-01 10:00:01,000 ERROR boom\nTraceback (most recent call last):\n  File \"app.py\", line 1\n\n2024-05-01 10:00:02 [warn] slow\n"
+	if format := detectLogFormat(strings.Split(text, "\n")); format != LogFormatGeneric {
+		t.Fatalf("detected %q", format)
+	}
+	entries := parseLogRecords(text, LogFormatGeneric, 2024)
+	if len(entries) != 3 {
+		t.Fatalf("unexpected records: %+v", entries)
+	}
+	if entries[0].Timestamp != "2024-05-01T10:00:00.123Z" || entries[0].Level != "info" || entries[0].Message != "starting" {
+		t.Fatalf("unexpected first record: %+v", entries[0])
+	}
+	boom := entries[1]
+	if boom.Level != "error" || !strings.HasSuffix(boom.Message, `File "app.py", line 1`) || text[boom.start:boom.end] != "2024-05-01 10:00:01,000 ERROR boom\nTraceback (most recent call last):\n  File \"app.py\", line 1" {
+		t.Fatalf("stack trace not attached: %+v", boom)
+	}
+	if entries[2].Line != 6 || entries[2].Level != "warning" {
+		t.Fatalf("unexpected last record: %+v", entries[2])
+	}
+}
+
+func TestChunkOnBoundaries(t *testing.T) {
+	content := "aaaa\nbbbb\ncccccccccccc\ndd"
+	spans := [][2]int{{0, 4}, {5, 9}, {10, 22}, {23, 25}}
+	chunks := chunkOnBoundaries(content, spans, 10)
+	var got []string
+	for _, c := range chunks {
+		got = append(got, c.Content)
+		if c.Metadata.TotalChunks != 3 || content[c.Metadata.ByteStart:c.Metadata.ByteEnd] != c.Content {
+			t.Fatalf("bad chunk metadata: %+v", c.Metadata)
+		}
+	}
+	if strings.Join(got, "|") != "aaaa\nbbbb|cccccccccccc|dd" {
+		t.Fatalf("unexpected chunks: %q", got)
+	}
+}
+
+func TestExtractLog(t *testing.T) {
+	config := &ExtractionConfig{
+		Logs:     &LogConfig{MaxRecords: 2},
+		Chunking: &ChunkingConfig{MaxChars: IntPtr(120)},
+	}
+	extractor, mimeType := selectGoPrimaryExtractor(documentSource{path: "/var/log/app.jsonl"}, config)
+	if extractor == nil || extractor.name != "logs" {
+		t.Fatalf("unexpected routing: %v", extractor)
+	}
+	data := strings.Repeat(`{"time":"2024-01-01T00:00:00Z","level":"info","msg":"ok"}`+"\n", 3) + `{"time":"2024-01-02T00:00:00Z","level":"error","msg":"failed"}` + "\n"
+	result, err := extractor.extract(documentSource{data: []byte(data)}, mimeType, config)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+
+	var records []LogRecord
+	if err := json.Unmarshal(result.Metadata.Additional["log_records"], &records); err != nil || len(records) != 2 {
+		t.Fatalf("unexpected log_records: %s", result.Metadata.Additional["log_records"])
+	}
+	if string(result.Metadata.Additional["record_count"]) != "4" || string(result.Metadata.Additional["level_counts"]) != `{"error":1,"info":3}` {
+		t.Fatalf("unexpected metadata: %v", result.Metadata.Additional)
+	}
+	if string(result.Metadata.Additional["time_range"]) != `{"end":"2024-01-02T00:00:00Z","start":"2024-01-01T00:00:00Z"}` {
+		t.Fatalf("unexpected time_range: %s", result.Metadata.Additional["time_range"])
+	}
+	if len(result.Chunks) != 2 {
+		t.Fatalf("expected 2 chunks, got %d", len(result.Chunks))
+	}
+	for _, c := range result.Chunks {
+		if !strings.HasPrefix(c.Content, "{") || !strings.HasSuffix(c.Content, "}") {
+			t.Fatalf("chunk split a record: %q", c.Content)
+		}
+	}
+}
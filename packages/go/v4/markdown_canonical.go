@@ -0,0 +1,296 @@
+package kreuzberg
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	atxHeadingPattern     = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	setextUnderline       = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	thematicBreakPattern  = regexp.MustCompile(`^ {0,3}(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	bulletPattern         = regexp.MustCompile(`^(\s*)[*+]([ \t]+)`)
+	markdownImagePattern  = regexp.MustCompile(`!\[([^\]]*)\]\(([^)]*)\)`)
+	tableSeparatorPattern = regexp.MustCompile(`^\|?(?:[ \t]*:?-+:?[ \t]*\|)+(?:[ \t]*:?-+:?[ \t]*)?$`)
+)
+
+// canonicalLine is a rendered output line; verbatim lines (fenced code) are never rewritten.
+type canonicalLine struct {
+	text     string
+	verbatim bool
+}
+
+// CanonicalizeMarkdown rewrites Markdown into a canonical, stable form so that two renderings of
+// the same document compare equal byte-for-byte:
+//
+//   - line endings are normalized to "\n", trailing whitespace is removed, and runs of blank
+//     lines collapse to one;
+//   - setext headings become ATX headings, closing "#" sequences are dropped, and headings and
+//     thematic breaks ("---") are surrounded by blank lines;
+//   - pipe tables are re-rendered with padded columns and normalized separator rows;
+//   - "*" and "+" bullets become "-";
+//   - images become numbered placeholders (![alt](image-1), ![alt](image-2), ...) so renderer-
+//     specific paths do not leak into diffs.
+//
+// Fenced code blocks are copied verbatim. CanonicalizeMarkdown is idempotent.
+func CanonicalizeMarkdown(markdown string) string {
+	return (&markdownCanonicalizer{}).canonicalize(markdown)
+}
+
+// markdownCanonicalizer carries state that spans calls, so a document canonicalized block by
+// block numbers its images the same way as when canonicalized in one piece.
+type markdownCanonicalizer struct {
+	images int
+}
+
+func (c *markdownCanonicalizer) canonicalize(markdown string) string {
+	text := strings.ReplaceAll(markdown, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	lines := strings.Split(text, "\n")
+
+	var out []canonicalLine
+	emit := func(text string) { out = append(out, canonicalLine{text: text}) }
+	emitBlock := func(block ...string) {
+		emit("")
+		for _, l := range block {
+			emit(l)
+		}
+		emit("")
+	}
+
+	fence := ""
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if fence != "" {
+			out = append(out, canonicalLine{text: line, verbatim: true})
+			if strings.HasPrefix(strings.TrimSpace(line), fence) && strings.Trim(strings.TrimSpace(line), fence[:1]) == "" {
+				fence = ""
+			}
+			continue
+		}
+
+		line = strings.TrimRight(line, " \t")
+		trimmed := strings.TrimLeft(line, " ")
+		if marker := codeFenceMarker(trimmed); marker != "" {
+			fence = marker
+			out = append(out, canonicalLine{text: line, verbatim: true})
+			continue
+		}
+
+		switch {
+		case trimmed == "":
+			emit("")
+		case i+1 < len(lines) && isSetextHeadingText(trimmed) && setextUnderline.MatchString(strings.TrimRight(lines[i+1], " \t")):
+			level := 2
+			if strings.HasPrefix(strings.TrimSpace(lines[i+1]), "=") {
+				level = 1
+			}
+			heading := c.replaceImages(trimmed)
+			emitBlock(formatHeading(level, heading))
+			i++
+		case atxHeadingPattern.MatchString(line):
+			m := atxHeadingPattern.FindStringSubmatch(line)
+			heading := c.replaceImages(m[2])
+			emitBlock(formatHeading(len(m[1]), heading))
+		case thematicBreakPattern.MatchString(line):
+			emitBlock("---")
+		case strings.HasPrefix(trimmed, "|") && i+1 < len(lines) && tableSeparatorPattern.MatchString(strings.TrimSpace(lines[i+1])):
+			end := i + 2
+			for end < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[end]), "|") {
+				end++
+			}
+			rows := make([]string, 0, end-i)
+			for _, row := range lines[i:end] {
+				rows = append(rows, c.replaceImages(strings.TrimSpace(row)))
+			}
+			emitBlock(renderCanonicalTable(rows)...)
+			i = end - 1
+		default:
+			line = bulletPattern.ReplaceAllString(line, "$1- ")
+			emit(c.replaceImages(line))
+		}
+	}
+
+	var b strings.Builder
+	blank := true
+	for _, l := range out {
+		if !l.verbatim && l.text == "" {
+			blank = true
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+			if blank {
+				b.WriteByte('\n')
+			}
+		}
+		b.WriteString(l.text)
+		blank = false
+	}
+	return b.String()
+}
+
+func codeFenceMarker(trimmed string) string {
+	for _, marker := range []string{"```", "~~~"} {
+		if strings.HasPrefix(trimmed, marker) {
+			run := len(trimmed) - len(strings.TrimLeft(trimmed, marker[:1]))
+			return strings.Repeat(marker[:1], run)
+		}
+	}
+	return ""
+}
+
+// isSetextHeadingText reports whether a line may be the text of a setext heading, i.e. it does
+// not itself start another block construct.
+func isSetextHeadingText(trimmed string) bool {
+	if atxHeadingPattern.MatchString(trimmed) || thematicBreakPattern.MatchString(trimmed) {
+		return false
+	}
+	for _, prefix := range []string{"- ", "* ", "+ ", ">", "|"} {
+		if strings.HasPrefix(trimmed, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+func formatHeading(level int, text string) string {
+	prefix := strings.Repeat("#", level)
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return prefix
+	}
+	return prefix + " " + text
+}
+
+func (c *markdownCanonicalizer) replaceImages(line string) string {
+	return markdownImagePattern.ReplaceAllStringFunc(line, func(match string) string {
+		c.images++
+		alt := markdownImagePattern.FindStringSubmatch(match)[1]
+		return "![" + strings.Join(strings.Fields(alt), " ") + "](image-" + strconv.Itoa(c.images) + ")"
+	})
+}
+
+// renderCanonicalTable re-renders a pipe table (header, separator, body rows) with every column
+// padded to its widest cell.
+func renderCanonicalTable(rows []string) []string {
+	cells := make([][]string, len(rows))
+	columns := 0
+	for i, row := range rows {
+		cells[i] = splitTableRow(row)
+		if len(cells[i]) > columns {
+			columns = len(cells[i])
+		}
+	}
+
+	aligns := make([]string, columns)
+	widths := make([]int, columns)
+	for c := 0; c < columns; c++ {
+		widths[c] = 3
+		if c < len(cells[1]) {
+			sep := cells[1][c]
+			left, right := strings.HasPrefix(sep, ":"), strings.HasSuffix(sep, ":")
+			switch {
+			case left && right:
+				aligns[c] = "center"
+			case left:
+				aligns[c] = "left"
+			case right:
+				aligns[c] = "right"
+			}
+		}
+	}
+	for i, row := range cells {
+		if i == 1 {
+			continue
+		}
+		for c, cell := range row {
+			if w := utf8.RuneCountInString(cell); w > widths[c] {
+				widths[c] = w
+			}
+		}
+	}
+
+	out := make([]string, len(rows))
+	for i, row := range cells {
+		parts := make([]string, columns)
+		for c := 0; c < columns; c++ {
+			if i == 1 {
+				parts[c] = separatorCell(aligns[c], widths[c])
+				continue
+			}
+			cell := ""
+			if c < len(row) {
+				cell = row[c]
+			}
+			parts[c] = cell + strings.Repeat(" ", widths[c]-utf8.RuneCountInString(cell))
+		}
+		out[i] = "| " + strings.Join(parts, " | ") + " |"
+	}
+	return out
+}
+
+func separatorCell(align string, width int) string {
+	switch align {
+	case "center":
+		return ":" + strings.Repeat("-", width-2) + ":"
+	case "left":
+		return ":" + strings.Repeat("-", width-1)
+	case "right":
+		return strings.Repeat("-", width-1) + ":"
+	default:
+		return strings.Repeat("-", width)
+	}
+}
+
+// splitTableRow splits a pipe table row into trimmed cells, honoring escaped pipes.
+func splitTableRow(row string) []string {
+	row = strings.TrimSpace(row)
+	row = strings.TrimPrefix(row, "|")
+	if strings.HasSuffix(row, "|") && !strings.HasSuffix(row, `\|`) {
+		row = row[:len(row)-1]
+	}
+
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(row); i++ {
+		switch {
+		case row[i] == '\\' && i+1 < len(row) && row[i+1] == '|':
+			cell.WriteString(`\|`)
+			i++
+		case row[i] == '|':
+			cells = append(cells, strings.Join(strings.Fields(cell.String()), " "))
+			cell.Reset()
+		default:
+			cell.WriteByte(row[i])
+		}
+	}
+	return append(cells, strings.Join(strings.Fields(cell.String()), " "))
+}
+
+// canonicalizeResultMarkdown applies CanonicalizeMarkdown to the content, page content, and
+// table Markdown of result. Chunks are left alone because their offsets refer to the original
+// content.
+func canonicalizeResultMarkdown(result *ExtractionResult) {
+	if result == nil {
+		return
+	}
+	result.Content = CanonicalizeMarkdown(result.Content)
+	canonicalizePagesAndTables(result)
+}
+
+func canonicalizePagesAndTables(result *ExtractionResult) {
+	canonicalizeTables(result.Tables)
+	for i := range result.Pages {
+		result.Pages[i].Content = CanonicalizeMarkdown(result.Pages[i].Content)
+		canonicalizeTables(result.Pages[i].Tables)
+	}
+}
+
+func canonicalizeTables(tables []Table) {
+	for i := range tables {
+		tables[i].Markdown = CanonicalizeMarkdown(tables[i].Markdown)
+	}
+}
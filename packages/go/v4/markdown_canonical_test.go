@@ -0,0 +1,55 @@
+package kreuzberg
+
+import "testing"
+
+func TestCanonicalizeMarkdownNormalizesStructure(t *testing.T) {
+	input := "Title\r\n=====\r\nIntro text   \r\n\r\n\r\n\r\n##Not a heading\r\n### Section ###\r\n* one\r\n+ two\r\n" +
+		"![logo](/tmp/render-8f3a/img0.png \"Logo\")\r\n***\r\n|a|long header|\r\n|:-|--:|\r\n|x|y|\r\n"
+
+	want := "# Title\n\nIntro text\n\n##Not a heading\n\n### Section\n\n- one\n- two\n![logo](image-1)\n\n---\n\n" +
+		"| a   | long header |\n| :-- | ----------: |\n| x   | y           |"
+
+	if got := CanonicalizeMarkdown(input); got != want {
+		t.Fatalf("unexpected canonical form:\n%s\n--- want ---\n%s", got, want)
+	}
+}
+
+func TestCanonicalizeMarkdownKeepsCodeFencesVerbatim(t *testing.T) {
+	input := "```go\n* not a bullet   \n\n\n# not a heading\n```\n* bullet"
+	want := "```go\n* not a bullet   \n\n\n# not a heading\n```\n- bullet"
+	if got := CanonicalizeMarkdown(input); got != want {
+		t.Fatalf("unexpected canonical form:\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestCanonicalizeMarkdownIsIdempotent(t *testing.T) {
+	inputs := []string{
+		"Heading\n-------\ntext\n| h | i |\n| --- | :-: |\n| 1 | ![x](a.png) |\n",
+		"# A\ntext\n- - -\nmore ![one](1.png) and ![two](2.png)",
+		"plain paragraph\nwith two lines\n\n\n\nand another",
+	}
+	for _, input := range inputs {
+		once := CanonicalizeMarkdown(input)
+		if twice := CanonicalizeMarkdown(once); twice != once {
+			t.Fatalf("not idempotent:\n%q\n%q", once, twice)
+		}
+	}
+}
+
+func TestFinishResultAppliesCanonicalMarkdown(t *testing.T) {
+	result := &ExtractionResult{
+		Content: "Title\n===",
+		Tables:  []Table{{Markdown: "|a|b|\n|-|-|\n|1|2|"}},
+		Pages:   []PageContent{{Content: "* item"}},
+	}
+	cfg := &ExtractionConfig{CanonicalMarkdown: BoolPtr(true)}
+	if err := finishResult(&pluginRegistry{}, newPluginContext(t.Context(), "", []byte("x"), "text/markdown", cfg), result); err != nil {
+		t.Fatalf("finishResult: %v", err)
+	}
+	if result.Content != "# Title" || result.Pages[0].Content != "- item" {
+		t.Fatalf("content not canonicalized: %+v", result)
+	}
+	if result.Tables[0].Markdown != "| a   | b   |\n| --- | --- |\n| 1   | 2   |" {
+		t.Fatalf("table not canonicalized: %q", result.Tables[0].Markdown)
+	}
+}
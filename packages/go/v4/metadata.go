@@ -1,6 +1,12 @@
 package kreuzberg
 
-import "encoding/json"
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
 
 var metadataCoreKeys = map[string]struct{}{
 	"language":            {},
@@ -23,8 +29,8 @@ var formatFieldSets = map[FormatType][]string{
 	FormatPPTX:    {"title", "author", "description", "summary", "fonts"},
 	FormatArchive: {"format", "file_count", "file_list", "total_size", "compressed_size"},
 	FormatImage:   {"width", "height", "format", "exif"},
-	FormatXML:     {"element_count", "unique_elements"},
-	FormatText:    {"line_count", "word_count", "character_count", "headers", "links", "code_blocks"},
+	FormatXML:     {"element_count", "unique_elements", "xml_document"},
+	FormatText:    {"line_count", "word_count", "character_count", "headers", "links", "code_blocks", "statistics"},
 	FormatHTML: {
 		"title", "description", "keywords", "author", "canonical", "base_href",
 		"og_title", "og_description", "og_image", "og_url", "og_type", "og_site_name",
@@ -273,3 +279,61 @@ func encodeStructToRaw(value any) (map[string]json.RawMessage, error) {
 	}
 	return result, nil
 }
+
+// Decode unmarshals the additional metadata field key into target. It reports false when the
+// field is absent or null, and an error when the field cannot be decoded into target.
+func (m Metadata) Decode(key string, target any) (bool, error) {
+	raw, ok := m.Additional[key]
+	if !ok || bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, target); err != nil {
+		return true, newSerializationErrorWithContext(fmt.Sprintf("failed to decode metadata field %q", key), err, ErrorCodeValidation, nil)
+	}
+	return true, nil
+}
+
+// GetString returns the additional metadata field key if it is a JSON string.
+func (m Metadata) GetString(key string) (string, bool) {
+	var value string
+	found, err := m.Decode(key, &value)
+	return value, found && err == nil
+}
+
+// GetInt returns the additional metadata field key if it is a JSON number without a fractional
+// part that fits in an int.
+func (m Metadata) GetInt(key string) (int, bool) {
+	var number json.Number
+	if found, err := m.Decode(key, &number); !found || err != nil {
+		return 0, false
+	}
+	if value, err := strconv.ParseInt(number.String(), 10, strconv.IntSize); err == nil {
+		return int(value), true
+	}
+	value, err := number.Float64()
+	if err != nil || value != math.Trunc(value) || value < math.MinInt || value >= math.MaxInt {
+		return 0, false
+	}
+	return int(value), true
+}
+
+// GetFloat returns the additional metadata field key if it is a JSON number.
+func (m Metadata) GetFloat(key string) (float64, bool) {
+	var value float64
+	found, err := m.Decode(key, &value)
+	return value, found && err == nil
+}
+
+// GetBool returns the additional metadata field key if it is a JSON boolean.
+func (m Metadata) GetBool(key string) (bool, bool) {
+	var value bool
+	found, err := m.Decode(key, &value)
+	return value, found && err == nil
+}
+
+// GetStringSlice returns the additional metadata field key if it is a JSON array of strings.
+func (m Metadata) GetStringSlice(key string) ([]string, bool) {
+	var value []string
+	found, err := m.Decode(key, &value)
+	return value, found && err == nil
+}
@@ -0,0 +1,70 @@
+package kreuzberg
+
+// MetadataBlock is the set of typed metadata blocks MetadataAs can return: the format-specific
+// metadata and the blocks recorded by Go-side features in Metadata.Additional.
+type MetadataBlock interface {
+	*PdfMetadata | *ExcelMetadata | *EmailMetadata | *PptxMetadata | *ArchiveMetadata |
+		*ImageMetadata | *XMLMetadata | *TextMetadata | *HtmlMetadata | *OcrMetadata |
+		*TextStatistics | *XMPPacket | *OfficeStats |
+		map[string]CustomProperty | map[string]FieldProvenance | []LanguageHint
+}
+
+// MetadataAs returns the metadata block of type T, for example MetadataAs[*PdfMetadata](m) or
+// MetadataAs[*OfficeStats](m). It is equivalent to the matching accessor method
+// (m.PdfMetadata(), m.OfficeStats()), and requesting a type that is not a metadata block fails
+// to compile.
+func MetadataAs[T MetadataBlock](m Metadata) (T, bool) {
+	var zero T
+	var block any
+	var ok bool
+	switch any(zero).(type) {
+	case *PdfMetadata:
+		block, ok = m.PdfMetadata()
+	case *ExcelMetadata:
+		block, ok = m.ExcelMetadata()
+	case *EmailMetadata:
+		block, ok = m.EmailMetadata()
+	case *PptxMetadata:
+		block, ok = m.PptxMetadata()
+	case *ArchiveMetadata:
+		block, ok = m.ArchiveMetadata()
+	case *ImageMetadata:
+		block, ok = m.ImageMetadata()
+	case *XMLMetadata:
+		block, ok = m.XMLMetadata()
+	case *TextMetadata:
+		block, ok = m.TextMetadata()
+	case *HtmlMetadata:
+		block, ok = m.HTMLMetadata()
+	case *OcrMetadata:
+		block, ok = m.OcrMetadata()
+	case *TextStatistics:
+		block, ok = m.TextStatistics()
+	case *XMPPacket:
+		block, ok = m.XMP()
+	case *OfficeStats:
+		block, ok = m.OfficeStats()
+	case map[string]CustomProperty:
+		block, ok = m.CustomProperties()
+	case map[string]FieldProvenance:
+		block, ok = m.Provenance()
+	case []LanguageHint:
+		block, ok = m.LanguageHints()
+	}
+	if !ok {
+		return zero, false
+	}
+	return block.(T), true
+}
+
+// AdditionalAs decodes the additional metadata field key into a T. It reports false when the
+// field is absent, null or not a valid T.
+func AdditionalAs[T any](m Metadata, key string) (T, bool) {
+	var value T
+	found, err := m.Decode(key, &value)
+	if !found || err != nil {
+		var zero T
+		return zero, false
+	}
+	return value, true
+}
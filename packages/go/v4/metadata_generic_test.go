@@ -0,0 +1,54 @@
+package kreuzberg
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMetadataAs(t *testing.T) {
+	title := "Report"
+	meta := Metadata{
+		Format: FormatMetadata{Type: FormatPDF, Pdf: &PdfMetadata{Title: &title}},
+		Additional: map[string]json.RawMessage{
+			"office_stats":   json.RawMessage(`{"last_modified_by":"Ada","revision":3}`),
+			"language_hints": json.RawMessage(`[{"language":"en","locale":"en","analyzer":"english"}]`),
+		},
+	}
+	if pdf, ok := MetadataAs[*PdfMetadata](meta); !ok || pdf != meta.Format.Pdf {
+		t.Fatalf("expected the PDF metadata block, got %+v", pdf)
+	}
+	if excel, ok := MetadataAs[*ExcelMetadata](meta); ok || excel != nil {
+		t.Fatalf("expected no Excel metadata, got %+v", excel)
+	}
+	if stats, ok := MetadataAs[*OfficeStats](meta); !ok || stats.LastModifiedBy != "Ada" || *stats.Revision != 3 {
+		t.Fatalf("unexpected office statistics: %+v", stats)
+	}
+	if hints, ok := MetadataAs[[]LanguageHint](meta); !ok || hints[0].Analyzer != "english" {
+		t.Fatalf("unexpected language hints: %+v", hints)
+	}
+	if props, ok := MetadataAs[map[string]CustomProperty](meta); ok || props != nil {
+		t.Fatalf("expected no custom properties, got %v", props)
+	}
+}
+
+func TestAdditionalAs(t *testing.T) {
+	meta := Metadata{Additional: map[string]json.RawMessage{
+		"sheet_row_counts": json.RawMessage(`{"Data":3}`),
+		"truncated":        json.RawMessage(`true`),
+		"missing":          json.RawMessage(`null`),
+	}}
+	if counts, ok := AdditionalAs[map[string]int](meta, "sheet_row_counts"); !ok || counts["Data"] != 3 {
+		t.Fatalf("unexpected counts: %v", counts)
+	}
+	if truncated, ok := AdditionalAs[bool](meta, "truncated"); !ok || !truncated {
+		t.Fatalf("unexpected flag: %v", truncated)
+	}
+	if _, ok := AdditionalAs[string](meta, "truncated"); ok {
+		t.Fatalf("expected a type mismatch to report false")
+	}
+	for _, key := range []string{"missing", "absent"} {
+		if _, ok := AdditionalAs[int](meta, key); ok {
+			t.Fatalf("expected %q to be reported as absent", key)
+		}
+	}
+}
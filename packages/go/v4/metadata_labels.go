@@ -0,0 +1,201 @@
+package kreuzberg
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// metadataLabelFiles holds the shipped label catalogs, one JSON file per locale.
+//
+//go:embed metadata_labels/*.json
+var metadataLabelFiles embed.FS
+
+// defaultLabelLocale is the catalog every lookup falls back to.
+const defaultLabelLocale = "en"
+
+// MetadataLabels maps metadata keys and enum values to display labels in one locale. Value
+// labels are grouped by enum: "format_type", "page_unit_type", "metadata_source",
+// "custom_property_type", "diagnostic_severity", "direction" (LanguageHint.Direction) and "bool".
+type MetadataLabels struct {
+	Locale string                       `json:"locale"`
+	Keys   map[string]string            `json:"keys"`
+	Values map[string]map[string]string `json:"values"`
+}
+
+// LabeledField is a metadata entry with its display labels.
+type LabeledField struct {
+	Key   string          `json:"key"`
+	Label string          `json:"label"`
+	Value json.RawMessage `json:"value"`
+	// ValueLabel is the label of an enum or boolean value; empty for other values.
+	ValueLabel string `json:"value_label,omitempty"`
+}
+
+var metadataLabelCatalogs struct {
+	once     sync.Once
+	mu       sync.RWMutex
+	byLocale map[string]*MetadataLabels
+	err      error
+}
+
+// loadMetadataLabelCatalogs parses the shipped catalogs once.
+func loadMetadataLabelCatalogs() error {
+	c := &metadataLabelCatalogs
+	c.once.Do(func() {
+		c.byLocale = map[string]*MetadataLabels{}
+		files, err := metadataLabelFiles.ReadDir("metadata_labels")
+		if err != nil {
+			c.err = err
+			return
+		}
+		for _, file := range files {
+			data, err := metadataLabelFiles.ReadFile("metadata_labels/" + file.Name())
+			if err != nil {
+				c.err = err
+				return
+			}
+			var labels MetadataLabels
+			if err := json.Unmarshal(data, &labels); err != nil {
+				c.err = newParsingErrorWithContext(fmt.Sprintf("invalid label catalog %s", file.Name()), err, ErrorCodeParsing, nil)
+				return
+			}
+			c.byLocale[normalizeLabelLocale(labels.Locale)] = &labels
+		}
+	})
+	return c.err
+}
+
+// normalizeLabelLocale lower-cases a locale and uses "-" as separator, e.g. "pt_BR" to "pt-br".
+func normalizeLabelLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// MetadataLabelLocales returns the locales with a label catalog, shipped or registered.
+func MetadataLabelLocales() []string {
+	if err := loadMetadataLabelCatalogs(); err != nil {
+		return nil
+	}
+	metadataLabelCatalogs.mu.RLock()
+	defer metadataLabelCatalogs.mu.RUnlock()
+	return slices.Sorted(maps.Keys(metadataLabelCatalogs.byLocale))
+}
+
+// RegisterMetadataLabels adds a catalog for labels.Locale, or overrides entries of an existing
+// one, e.g. to add a locale or to adjust wording for a product.
+func RegisterMetadataLabels(labels *MetadataLabels) error {
+	if labels == nil || normalizeLabelLocale(labels.Locale) == "" {
+		return newValidationErrorWithContext("label catalog needs a locale", nil, ErrorCodeValidation, nil)
+	}
+	if err := loadMetadataLabelCatalogs(); err != nil {
+		return err
+	}
+	locale := normalizeLabelLocale(labels.Locale)
+	c := &metadataLabelCatalogs
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	merged := &MetadataLabels{Locale: locale}
+	if existing := c.byLocale[locale]; existing != nil {
+		merged.Locale = existing.Locale
+		merged.merge(existing)
+	}
+	merged.merge(labels)
+	c.byLocale[locale] = merged
+	return nil
+}
+
+// merge copies the entries of other into l.
+func (l *MetadataLabels) merge(other *MetadataLabels) {
+	if l.Keys == nil {
+		l.Keys = map[string]string{}
+	}
+	if l.Values == nil {
+		l.Values = map[string]map[string]string{}
+	}
+	maps.Copy(l.Keys, other.Keys)
+	for enum, values := range other.Values {
+		if l.Values[enum] == nil {
+			l.Values[enum] = map[string]string{}
+		}
+		maps.Copy(l.Values[enum], values)
+	}
+}
+
+// LoadMetadataLabels returns the labels for locale, a BCP 47 tag such as "de" or "de-AT".
+// Entries missing from the exact locale fall back to its language ("de"), then to English, so
+// the result is never nil and Locale reports the best matching catalog.
+func LoadMetadataLabels(locale string) *MetadataLabels {
+	labels := &MetadataLabels{Locale: defaultLabelLocale, Keys: map[string]string{}, Values: map[string]map[string]string{}}
+	if loadMetadataLabelCatalogs() != nil {
+		return labels
+	}
+	chain := []string{defaultLabelLocale}
+	if locale = normalizeLabelLocale(locale); locale != "" {
+		if language, _, ok := strings.Cut(locale, "-"); ok {
+			chain = append(chain, language)
+		}
+		chain = append(chain, locale)
+	}
+	c := &metadataLabelCatalogs
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, name := range chain {
+		if catalog := c.byLocale[name]; catalog != nil {
+			labels.merge(catalog)
+			labels.Locale = catalog.Locale
+		}
+	}
+	return labels
+}
+
+// Key returns the label of a metadata key, or the key in sentence case ("page_count" becomes
+// "Page count") when the catalog has none.
+func (l *MetadataLabels) Key(key string) string {
+	if label, ok := l.Keys[key]; ok {
+		return label
+	}
+	words := strings.ReplaceAll(key, "_", " ")
+	if words == "" {
+		return ""
+	}
+	return strings.ToUpper(words[:1]) + words[1:]
+}
+
+// Value returns the label of an enum value, or the value itself when the catalog has none.
+func (l *MetadataLabels) Value(enum, value string) string {
+	if label, ok := l.Values[enum][value]; ok {
+		return label
+	}
+	return value
+}
+
+// Fields returns the entries of m in its flattened JSON form, sorted by key, with their labels.
+func (l *MetadataLabels) Fields(m Metadata) ([]LabeledField, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, newSerializationErrorWithContext("failed to encode metadata", err, ErrorCodeValidation, nil)
+	}
+	var flat map[string]json.RawMessage
+	if err := json.Unmarshal(data, &flat); err != nil {
+		return nil, newSerializationErrorWithContext("failed to decode metadata", err, ErrorCodeValidation, nil)
+	}
+	fields := make([]LabeledField, 0, len(flat))
+	for _, key := range slices.Sorted(maps.Keys(flat)) {
+		field := LabeledField{Key: key, Label: l.Key(key), Value: flat[key]}
+		switch raw := string(flat[key]); {
+		case raw == "true" || raw == "false":
+			field.ValueLabel = l.Value("bool", raw)
+		case key == "format_type":
+			var value string
+			if json.Unmarshal(flat[key], &value) == nil {
+				field.ValueLabel = l.Value(key, value)
+			}
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
@@ -0,0 +1,94 @@
+package kreuzberg
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoadMetadataLabels(t *testing.T) {
+	if locales := MetadataLabelLocales(); !slices.Equal(locales, []string{"de", "en", "es", "fr"}) {
+		t.Fatalf("unexpected shipped locales: %v", locales)
+	}
+	de := LoadMetadataLabels("de_AT")
+	if de.Locale != "de" || de.Key("page_count") != "Seiten" || de.Value("format_type", "pdf") != "PDF-Dokument" {
+		t.Fatalf("unexpected German labels: %s %q %q", de.Locale, de.Key("page_count"), de.Value("format_type", "pdf"))
+	}
+	if de.Key("unknown_key") != "Unknown key" || de.Value("format_type", "zip") != "zip" {
+		t.Fatalf("unexpected fallbacks: %q %q", de.Key("unknown_key"), de.Value("format_type", "zip"))
+	}
+	if ja := LoadMetadataLabels("ja-JP"); ja.Locale != "en" || ja.Key("title") != "Title" {
+		t.Fatalf("expected English for a locale without a catalog, got %s %q", ja.Locale, ja.Key("title"))
+	}
+
+	// Every shipped catalog covers the English keys and values.
+	en := LoadMetadataLabels("en")
+	for _, locale := range []string{"de", "es", "fr"} {
+		c := metadataLabelCatalogs.byLocale[locale]
+		for key := range en.Keys {
+			if _, ok := c.Keys[key]; !ok {
+				t.Errorf("%s catalog misses key %q", locale, key)
+			}
+		}
+		for enum, values := range en.Values {
+			for value := range values {
+				if _, ok := c.Values[enum][value]; !ok {
+					t.Errorf("%s catalog misses value %s/%s", locale, enum, value)
+				}
+			}
+		}
+	}
+}
+
+func TestRegisterMetadataLabels(t *testing.T) {
+	if err := RegisterMetadataLabels(&MetadataLabels{Locale: "de-CH", Keys: map[string]string{"subject": "Betreff (CH)"}}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if err := RegisterMetadataLabels(&MetadataLabels{Locale: "de-ch", Values: map[string]map[string]string{"bool": {"true": "Jo"}}}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	t.Cleanup(func() {
+		metadataLabelCatalogs.mu.Lock()
+		delete(metadataLabelCatalogs.byLocale, "de-ch")
+		metadataLabelCatalogs.mu.Unlock()
+	})
+	labels := LoadMetadataLabels("de-CH")
+	if labels.Locale != "de-ch" || labels.Key("subject") != "Betreff (CH)" || labels.Value("bool", "true") != "Jo" || labels.Key("title") != "Titel" {
+		t.Fatalf("unexpected merged labels: %s %q %q %q", labels.Locale, labels.Key("subject"), labels.Value("bool", "true"), labels.Key("title"))
+	}
+	if err := RegisterMetadataLabels(&MetadataLabels{}); err == nil {
+		t.Fatalf("expected an error without a locale")
+	}
+}
+
+func TestMetadataLabelsFields(t *testing.T) {
+	pages, encrypted := 3, true
+	meta := Metadata{
+		Format:     FormatMetadata{Type: FormatPDF, Pdf: &PdfMetadata{PageCount: &pages, IsEncrypted: &encrypted}},
+		Additional: map[string]json.RawMessage{"acme_id": json.RawMessage(`"A-1"`)},
+	}
+	fields, err := LoadMetadataLabels("fr").Fields(meta)
+	if err != nil {
+		t.Fatalf("fields: %v", err)
+	}
+	byKey := map[string]LabeledField{}
+	for _, f := range fields {
+		byKey[f.Key] = f
+	}
+	if f := byKey["format_type"]; f.Label != "Format" || f.ValueLabel != "Document PDF" {
+		t.Fatalf("unexpected format field: %+v", f)
+	}
+	if f := byKey["is_encrypted"]; f.Label != "Chiffré" || f.ValueLabel != "Oui" {
+		t.Fatalf("unexpected boolean field: %+v", f)
+	}
+	if f := byKey["page_count"]; f.Label != "Pages" || string(f.Value) != "3" || f.ValueLabel != "" {
+		t.Fatalf("unexpected count field: %+v", f)
+	}
+	if f := byKey["acme_id"]; f.Label != "Acme id" {
+		t.Fatalf("unexpected custom field: %+v", f)
+	}
+	if !slices.IsSortedFunc(fields, func(a, b LabeledField) int { return strings.Compare(a.Key, b.Key) }) {
+		t.Fatalf("expected fields sorted by key")
+	}
+}
@@ -0,0 +1,486 @@
+package kreuzberg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// MetadataSource names where a metadata value was read from.
+type MetadataSource string
+
+const (
+	// MetadataSourceExtractor is the value reported by the extractor that produced the result.
+	MetadataSourceExtractor MetadataSource = "extractor"
+	// MetadataSourcePDFInfo is the PDF document information dictionary.
+	MetadataSourcePDFInfo MetadataSource = "pdf_info"
+	// MetadataSourceXMP is an XMP packet embedded in the document.
+	MetadataSourceXMP MetadataSource = "xmp"
+	// MetadataSourceHeuristic is a value derived from the content, e.g. the first heading as title.
+	MetadataSourceHeuristic MetadataSource = "heuristic"
+)
+
+// defaultMetadataPrecedence prefers XMP, which PDF 2.0 makes the primary metadata store over the
+// deprecated information dictionary.
+var defaultMetadataPrecedence = []MetadataSource{MetadataSourceXMP, MetadataSourcePDFInfo, MetadataSourceExtractor, MetadataSourceHeuristic}
+
+// provenanceFields are the metadata fields whose sources are tracked, in report order.
+var provenanceFields = []string{"title", "subject", "authors", "keywords", "created_at", "modified_at", "created_by", "producer", "language"}
+
+// MetadataProvenanceConfig records which source each common metadata field (title, subject,
+// authors, keywords, created_at, modified_at, created_by, producer, language) came from and
+// resolves conflicting values deterministically. The chosen values are written back to the PDF
+// metadata and to Metadata.Subject/Language; the full picture is available from
+// Metadata.Provenance.
+type MetadataProvenanceConfig struct {
+	// Precedence orders sources from most to least trusted (default: xmp, pdf_info, extractor,
+	// heuristic). Sources left out are ranked after the listed ones in the default order.
+	Precedence []MetadataSource
+}
+
+// FieldCandidate is a value one source reported for a metadata field.
+type FieldCandidate struct {
+	Source MetadataSource  `json:"source"`
+	Value  json.RawMessage `json:"value"`
+}
+
+// FieldProvenance describes how a metadata field was resolved.
+type FieldProvenance struct {
+	// Source is the source of the chosen value.
+	Source MetadataSource `json:"source"`
+	// Value is the chosen value: a string, or an array of strings for authors and keywords.
+	// Dates are normalized to RFC 3339 where they can be parsed.
+	Value json.RawMessage `json:"value"`
+	// Candidates lists every source that reported the field, in precedence order.
+	Candidates []FieldCandidate `json:"candidates"`
+	// Conflict reports whether the sources disagree.
+	Conflict bool `json:"conflict"`
+}
+
+// Provenance returns the field provenance recorded when ExtractionConfig.MetadataProvenance was
+// set, keyed by field name.
+func (m Metadata) Provenance() (map[string]FieldProvenance, bool) {
+	var provenance map[string]FieldProvenance
+	if found, err := m.Decode("metadata_provenance", &provenance); !found || err != nil {
+		return nil, false
+	}
+	return provenance, true
+}
+
+func metadataPrecedence(cfg *MetadataProvenanceConfig) ([]MetadataSource, error) {
+	var order []MetadataSource
+	for _, source := range cfg.Precedence {
+		if !slices.Contains(defaultMetadataPrecedence, source) {
+			return nil, newValidationErrorWithContext(fmt.Sprintf("unknown metadata source %q", source), nil, ErrorCodeValidation, nil)
+		}
+		if !slices.Contains(order, source) {
+			order = append(order, source)
+		}
+	}
+	for _, source := range defaultMetadataPrecedence {
+		if !slices.Contains(order, source) {
+			order = append(order, source)
+		}
+	}
+	return order, nil
+}
+
+// annotateMetadataProvenance gathers the metadata fields of every source, resolves them by
+// precedence, writes the chosen values back and records the provenance in the result.
+func annotateMetadataProvenance(result *ExtractionResult, data []byte, cfg *MetadataProvenanceConfig) error {
+	order, err := metadataPrecedence(cfg)
+	if err != nil {
+		return err
+	}
+	sources := map[MetadataSource]map[string]any{
+		MetadataSourceExtractor: extractorMetadataValues(result),
+		MetadataSourceHeuristic: heuristicMetadataValues(result),
+	}
+	if data != nil {
+		sources[MetadataSourcePDFInfo] = pdfInfoValues(data)
+		sources[MetadataSourceXMP] = xmpValues(data)
+	}
+
+	provenance := map[string]FieldProvenance{}
+	chosen := map[string]any{}
+	for _, field := range provenanceFields {
+		var fp FieldProvenance
+		var first string
+		for _, source := range order {
+			value, ok := sources[source][field]
+			if !ok {
+				continue
+			}
+			raw, err := json.Marshal(value)
+			if err != nil {
+				return newSerializationErrorWithContext("failed to encode metadata provenance", err, ErrorCodeValidation, nil)
+			}
+			if fp.Candidates == nil {
+				fp.Source, fp.Value, first = source, raw, string(raw)
+				chosen[field] = value
+			} else if string(raw) != first {
+				fp.Conflict = true
+			}
+			fp.Candidates = append(fp.Candidates, FieldCandidate{Source: source, Value: raw})
+		}
+		if fp.Candidates != nil {
+			provenance[field] = fp
+		}
+	}
+	if len(provenance) == 0 {
+		return nil
+	}
+	applyChosenMetadata(&result.Metadata, chosen)
+
+	raw, err := json.Marshal(provenance)
+	if err != nil {
+		return newSerializationErrorWithContext("failed to encode metadata provenance", err, ErrorCodeValidation, nil)
+	}
+	if result.Metadata.Additional == nil {
+		result.Metadata.Additional = map[string]json.RawMessage{}
+	}
+	result.Metadata.Additional["metadata_provenance"] = raw
+	return nil
+}
+
+// setMetadataValue records a non-empty value, normalizing dates and trimming strings.
+func setMetadataValue(values map[string]any, field string, value any) {
+	switch v := value.(type) {
+	case *string:
+		if v != nil {
+			setMetadataValue(values, field, *v)
+		}
+	case string:
+		v = strings.TrimSpace(v)
+		if v == "" {
+			return
+		}
+		if field == "created_at" || field == "modified_at" {
+			v = normalizeMetadataDate(v)
+		}
+		values[field] = v
+	case []string:
+		var list []string
+		for _, item := range v {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		if len(list) > 0 {
+			values[field] = list
+		}
+	}
+}
+
+func extractorMetadataValues(result *ExtractionResult) map[string]any {
+	values := map[string]any{}
+	meta := &result.Metadata
+	if pdf, ok := meta.PdfMetadata(); ok {
+		setMetadataValue(values, "title", pdf.Title)
+		setMetadataValue(values, "subject", pdf.Subject)
+		setMetadataValue(values, "authors", pdf.Authors)
+		setMetadataValue(values, "keywords", pdf.Keywords)
+		setMetadataValue(values, "created_at", pdf.CreatedAt)
+		setMetadataValue(values, "modified_at", pdf.ModifiedAt)
+		setMetadataValue(values, "created_by", pdf.CreatedBy)
+		setMetadataValue(values, "producer", pdf.Producer)
+	}
+	if pptx, ok := meta.PptxMetadata(); ok {
+		setMetadataValue(values, "title", pptx.Title)
+		if pptx.Author != nil {
+			setMetadataValue(values, "authors", []string{*pptx.Author})
+		}
+	}
+	if html, ok := meta.HTMLMetadata(); ok {
+		setMetadataValue(values, "title", html.Title)
+		if html.Author != nil {
+			setMetadataValue(values, "authors", []string{*html.Author})
+		}
+		if html.Keywords != nil {
+			setMetadataValue(values, "keywords", splitMetadataList(*html.Keywords, ",;"))
+		}
+	}
+	if xmlMeta, ok := meta.XMLMetadata(); ok && xmlMeta.Document != nil {
+		doc := xmlMeta.Document
+		setMetadataValue(values, "title", doc.Title)
+		authors := make([]string, len(doc.Authors))
+		for i, author := range doc.Authors {
+			authors[i] = author.Name
+		}
+		setMetadataValue(values, "authors", authors)
+		setMetadataValue(values, "keywords", doc.Keywords)
+	}
+	if _, ok := values["subject"]; !ok {
+		setMetadataValue(values, "subject", meta.Subject)
+	}
+	if _, ok := values["created_at"]; !ok {
+		setMetadataValue(values, "created_at", meta.Date)
+	}
+	setMetadataValue(values, "language", meta.Language)
+	return values
+}
+
+// heuristicMetadataValues takes the first Markdown heading of the content as title.
+func heuristicMetadataValues(result *ExtractionResult) map[string]any {
+	values := map[string]any{}
+	for line := range strings.Lines(result.Content) {
+		line = strings.TrimSpace(line)
+		if heading := strings.TrimLeft(line, "#"); heading != line && strings.HasPrefix(heading, " ") {
+			setMetadataValue(values, "title", heading)
+			break
+		}
+	}
+	return values
+}
+
+func applyChosenMetadata(meta *Metadata, chosen map[string]any) {
+	str := func(field string) *string {
+		if v, ok := chosen[field].(string); ok {
+			return &v
+		}
+		return nil
+	}
+	list := func(field string) []string {
+		v, _ := chosen[field].([]string)
+		return v
+	}
+	if pdf, ok := meta.PdfMetadata(); ok {
+		pdf.Title = orStringPtr(str("title"), pdf.Title)
+		pdf.Subject = orStringPtr(str("subject"), pdf.Subject)
+		pdf.CreatedAt = orStringPtr(str("created_at"), pdf.CreatedAt)
+		pdf.ModifiedAt = orStringPtr(str("modified_at"), pdf.ModifiedAt)
+		pdf.CreatedBy = orStringPtr(str("created_by"), pdf.CreatedBy)
+		pdf.Producer = orStringPtr(str("producer"), pdf.Producer)
+		if authors := list("authors"); authors != nil {
+			pdf.Authors = authors
+		}
+		if keywords := list("keywords"); keywords != nil {
+			pdf.Keywords = keywords
+		}
+	}
+	meta.Subject = orStringPtr(str("subject"), meta.Subject)
+	meta.Language = orStringPtr(str("language"), meta.Language)
+}
+
+// orStringPtr returns value unless it is nil, then fallback.
+func orStringPtr(value, fallback *string) *string {
+	if value != nil {
+		return value
+	}
+	return fallback
+}
+
+func splitMetadataList(value, separators string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool { return strings.ContainsRune(separators, r) })
+}
+
+var pdfInfoRefPattern = regexp.MustCompile(`/Info\s+(\d+)\s+(\d+)\s+R`)
+
+// pdfInfoValues reads the document information dictionary of a PDF. Only dictionaries stored
+// as plain objects are found; those inside compressed object streams are skipped.
+func pdfInfoValues(data []byte) map[string]any {
+	values := map[string]any{}
+	if !bytes.HasPrefix(bytes.TrimLeft(data[:min(len(data), 1024)], "\x00\r\n\t "), []byte("%PDF-")) {
+		return values
+	}
+	refs := pdfInfoRefPattern.FindAllSubmatch(data, -1)
+	if len(refs) == 0 {
+		return values
+	}
+	// The last trailer wins after incremental updates, as does the last definition of the object.
+	ref := refs[len(refs)-1]
+	objPattern := regexp.MustCompile(`(?:^|\s)` + string(ref[1]) + `\s+` + string(ref[2]) + `\s+obj\s*<<`)
+	locs := objPattern.FindAllIndex(data, -1)
+	if len(locs) == 0 {
+		return values
+	}
+	dict := data[locs[len(locs)-1][1]:]
+	if end := bytes.Index(dict, []byte("endobj")); end >= 0 {
+		dict = dict[:end]
+	}
+	entries := parsePDFInfoDict(dict)
+	setMetadataValue(values, "title", entries["Title"])
+	setMetadataValue(values, "subject", entries["Subject"])
+	setMetadataValue(values, "authors", splitMetadataList(entries["Author"], ";"))
+	setMetadataValue(values, "keywords", splitMetadataList(entries["Keywords"], ",;"))
+	setMetadataValue(values, "created_at", entries["CreationDate"])
+	setMetadataValue(values, "modified_at", entries["ModDate"])
+	setMetadataValue(values, "created_by", entries["Creator"])
+	setMetadataValue(values, "producer", entries["Producer"])
+	return values
+}
+
+// parsePDFInfoDict returns the string entries of an information dictionary body.
+func parsePDFInfoDict(dict []byte) map[string]string {
+	entries := map[string]string{}
+	for i := 0; i < len(dict); i++ {
+		if dict[i] == '>' && i+1 < len(dict) && dict[i+1] == '>' {
+			break
+		}
+		if dict[i] != '/' {
+			continue
+		}
+		j := i + 1
+		for j < len(dict) && !bytes.ContainsRune([]byte(" \t\r\n/()<>[]"), rune(dict[j])) {
+			j++
+		}
+		key := string(dict[i+1 : j])
+		for j < len(dict) && bytes.ContainsRune([]byte(" \t\r\n"), rune(dict[j])) {
+			j++
+		}
+		var value []byte
+		var end int
+		switch {
+		case j < len(dict) && dict[j] == '(':
+			value, end = parsePDFLiteralString(dict, j)
+		case j+1 < len(dict) && dict[j] == '<' && dict[j+1] != '<':
+			value, end = parsePDFHexString(dict, j)
+		default:
+			i = j - 1
+			continue
+		}
+		entries[key] = decodePDFTextString(value)
+		i = end
+	}
+	return entries
+}
+
+// parsePDFLiteralString parses the literal string starting at data[start] == '(' and returns its
+// bytes and the index of the closing parenthesis.
+func parsePDFLiteralString(data []byte, start int) ([]byte, int) {
+	var out []byte
+	depth := 0
+	for i := start; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case c == '\\' && i+1 < len(data):
+			i++
+			switch e := data[i]; e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\r':
+				if i+1 < len(data) && data[i+1] == '\n' {
+					i++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					n := 0
+					for k := 0; k < 3 && i < len(data) && data[i] >= '0' && data[i] <= '7'; k++ {
+						n = n*8 + int(data[i]-'0')
+						i++
+					}
+					i--
+					out = append(out, byte(n))
+				} else {
+					out = append(out, e)
+				}
+			}
+		case c == '(':
+			if depth > 0 {
+				out = append(out, c)
+			}
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return out, i
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+	return out, len(data)
+}
+
+// parsePDFHexString parses the hex string starting at data[start] == '<'.
+func parsePDFHexString(data []byte, start int) ([]byte, int) {
+	end := bytes.IndexByte(data[start:], '>')
+	if end < 0 {
+		return nil, len(data)
+	}
+	var digits []byte
+	for _, c := range data[start+1 : start+end] {
+		if (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F') {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	for i := range out {
+		v, _ := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+		out[i] = byte(v)
+	}
+	return out, start + end
+}
+
+// decodePDFTextString decodes a PDF text string: UTF-16BE or UTF-8 with a byte order mark,
+// otherwise PDFDocEncoding, approximated by Latin-1.
+func decodePDFTextString(value []byte) string {
+	switch {
+	case len(value) >= 2 && value[0] == 0xFE && value[1] == 0xFF:
+		units := make([]uint16, 0, len(value)/2)
+		for i := 2; i+1 < len(value); i += 2 {
+			units = append(units, uint16(value[i])<<8|uint16(value[i+1]))
+		}
+		return string(utf16.Decode(units))
+	case bytes.HasPrefix(value, []byte("\xEF\xBB\xBF")):
+		return string(value[3:])
+	}
+	runes := make([]rune, len(value))
+	for i, b := range value {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+// xmpValues reads the provenance fields from the last XMP packet in data, which after
+// incremental PDF updates is the most recent one.
+func xmpValues(data []byte) map[string]any {
+	values := map[string]any{}
+	packets := ExtractXMP(data)
+	if len(packets) == 0 {
+		return values
+	}
+	packet := packets[len(packets)-1]
+	get := func(namespace, name string) XMPValue {
+		value, _ := packet.Get(namespace, name)
+		return value
+	}
+	setMetadataValue(values, "title", get(XMPNamespaceDC, "title").Text())
+	setMetadataValue(values, "subject", get(XMPNamespaceDC, "description").Text())
+	setMetadataValue(values, "authors", get(XMPNamespaceDC, "creator").Strings())
+	var keywords []string
+	for _, item := range append(get(XMPNamespaceDC, "subject").Strings(), get(XMPNamespacePDF, "Keywords").Strings()...) {
+		for _, keyword := range splitMetadataList(item, ",;") {
+			if keyword = strings.TrimSpace(keyword); !slices.Contains(keywords, keyword) {
+				keywords = append(keywords, keyword)
+			}
+		}
+	}
+	setMetadataValue(values, "keywords", keywords)
+	if languages := get(XMPNamespaceDC, "language").Strings(); len(languages) > 0 {
+		setMetadataValue(values, "language", languages[0])
+	}
+	setMetadataValue(values, "created_at", get(XMPNamespaceXMP, "CreateDate").Text())
+	setMetadataValue(values, "modified_at", get(XMPNamespaceXMP, "ModifyDate").Text())
+	setMetadataValue(values, "created_by", get(XMPNamespaceXMP, "CreatorTool").Text())
+	setMetadataValue(values, "producer", get(XMPNamespacePDF, "Producer").Text())
+	return values
+}
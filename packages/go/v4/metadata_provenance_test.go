@@ -0,0 +1,99 @@
+package kreuzberg
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const testXMP = `<?xpacket begin="" id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+<rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:xmp="http://ns.adobe.com/xap/1.0/" xmlns:pdf="http://ns.adobe.com/pdf/1.3/" pdf:Producer="Distiller 9">
+<dc:title><rdf:Alt><rdf:li xml:lang="x-default">Annual Report 2024</rdf:li></rdf:Alt></dc:title>
+<dc:creator><rdf:Seq><rdf:li>Ada Lovelace</rdf:li><rdf:li>Charles Babbage</rdf:li></rdf:Seq></dc:creator>
+<dc:subject><rdf:Bag><rdf:li>finance</rdf:li><rdf:li>annual</rdf:li></rdf:Bag></dc:subject>
+<xmp:CreateDate>2024-01-02T03:04:05+01:00</xmp:CreateDate>
+</rdf:Description></rdf:RDF></x:xmpmeta>
+<?xpacket end="w"?>`
+
+func testProvenancePDF() []byte {
+	return []byte("%PDF-1.7\n1 0 obj\n<< /Type /Catalog >>\nendobj\n" +
+		"7 0 obj\n<< /Title <FEFF0044007200610066007400200052006500700030007200740020> /Author (Ada Lovelace; Charles Babbage) " +
+		"/Subject (Results \\(audited\\)) /CreationDate (D:20240102030405+01'00') /Producer (Writer\\0401.0) >>\nendobj\n" +
+		"8 0 obj\n<< /Type /Metadata /Subtype /XML >>\nstream\n" + testXMP + "\nendstream\nendobj\n" +
+		"trailer\n<< /Root 1 0 R /Info 7 0 R >>\n%%EOF\n")
+}
+
+func TestMetadataProvenanceResolvesByPrecedence(t *testing.T) {
+	nativeTitle := "untitled.docx"
+	result := &ExtractionResult{
+		Content:  "# Annual Report\n\nRevenue grew.",
+		Metadata: Metadata{Format: FormatMetadata{Type: FormatPDF, Pdf: &PdfMetadata{Title: &nativeTitle}}},
+	}
+	if err := annotateMetadataProvenance(result, testProvenancePDF(), &MetadataProvenanceConfig{}); err != nil {
+		t.Fatalf("annotate: %v", err)
+	}
+	provenance, ok := result.Metadata.Provenance()
+	if !ok {
+		t.Fatalf("missing provenance: %v", result.Metadata.Additional)
+	}
+
+	title := provenance["title"]
+	if title.Source != MetadataSourceXMP || string(title.Value) != `"Annual Report 2024"` || !title.Conflict || len(title.Candidates) != 4 {
+		t.Fatalf("unexpected title provenance: %+v", title)
+	}
+	wantTitles := []string{`"Annual Report 2024"`, `"Draft Rep0rt"`, `"untitled.docx"`, `"Annual Report"`}
+	for i, c := range title.Candidates {
+		if c.Source != defaultMetadataPrecedence[i] || string(c.Value) != wantTitles[i] {
+			t.Fatalf("unexpected title candidate %d: %s %s", i, c.Source, c.Value)
+		}
+	}
+	if pdf, _ := result.Metadata.PdfMetadata(); *pdf.Title != "Annual Report 2024" || len(pdf.Authors) != 2 || *pdf.Producer != "Distiller 9" {
+		t.Fatalf("chosen values not applied: %+v", pdf)
+	}
+
+	if authors := provenance["authors"]; authors.Conflict || len(authors.Candidates) != 2 {
+		t.Fatalf("expected XMP and info authors to agree: %+v", authors)
+	}
+	if created := provenance["created_at"]; created.Conflict || string(created.Value) != `"2024-01-02T03:04:05+01:00"` {
+		t.Fatalf("expected dates to be normalized before comparison: %+v", created)
+	}
+	if subject := provenance["subject"]; subject.Source != MetadataSourcePDFInfo || string(subject.Value) != `"Results (audited)"` || *result.Metadata.Subject != "Results (audited)" {
+		t.Fatalf("unexpected subject: %+v", subject)
+	}
+	var keywords []string
+	json.Unmarshal(provenance["keywords"].Value, &keywords)
+	if len(keywords) != 2 || keywords[0] != "finance" {
+		t.Fatalf("unexpected keywords: %v", keywords)
+	}
+
+	// A custom precedence trusts the extractor first.
+	result.Metadata.Additional = nil
+	result.Metadata.Format.Pdf = &PdfMetadata{Title: &nativeTitle}
+	if err := annotateMetadataProvenance(result, testProvenancePDF(), &MetadataProvenanceConfig{Precedence: []MetadataSource{MetadataSourceExtractor}}); err != nil {
+		t.Fatalf("annotate: %v", err)
+	}
+	if provenance, _ := result.Metadata.Provenance(); provenance["title"].Source != MetadataSourceExtractor || provenance["producer"].Source != MetadataSourceXMP {
+		t.Fatalf("unexpected precedence: %+v", provenance)
+	}
+	if err := annotateMetadataProvenance(result, nil, &MetadataProvenanceConfig{Precedence: []MetadataSource{"exif"}}); err == nil {
+		t.Fatalf("expected an error for an unknown source")
+	}
+}
+
+func TestMetadataProvenanceDuringExtraction(t *testing.T) {
+	config := &ExtractionConfig{XMLProfile: &XMLProfileConfig{}, MetadataProvenance: &MetadataProvenanceConfig{}}
+	result, err := ExtractBytesSync([]byte(testDocBook), mimeXML, config)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	provenance, ok := result.Metadata.Provenance()
+	if !ok {
+		t.Fatalf("missing provenance")
+	}
+	if title := provenance["title"]; title.Source != MetadataSourceExtractor || title.Conflict || len(title.Candidates) != 2 {
+		t.Fatalf("expected the extractor and the first heading to agree: %+v", title)
+	}
+	if created := provenance["created_at"]; string(created.Value) != `"2024-03-01T00:00:00Z"` {
+		t.Fatalf("unexpected creation date: %+v", created)
+	}
+}
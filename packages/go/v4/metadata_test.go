@@ -114,3 +114,54 @@ func TestMetadataRoundTripHandlesTextFormats(t *testing.T) {
 		t.Fatalf("metadata mismatch: want %#v, got %#v", want, got)
 	}
 }
+
+func TestMetadataAdditionalAccessors(t *testing.T) {
+	var meta Metadata
+	if err := json.Unmarshal([]byte(`{"producer":"scanner","pages":12,"big":9007199254740993,"exp":1e3,"ratio":0.5,"draft":true,"tags":["a","b"],"missing":null,"info":{"n":1}}`), &meta); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	if v, ok := meta.GetString("producer"); !ok || v != "scanner" {
+		t.Fatalf("GetString: %q, %v", v, ok)
+	}
+	if _, ok := meta.GetString("pages"); ok {
+		t.Fatalf("expected GetString to reject a number")
+	}
+	if v, ok := meta.GetInt("pages"); !ok || v != 12 {
+		t.Fatalf("GetInt: %d, %v", v, ok)
+	}
+	if v, ok := meta.GetInt("big"); !ok || v != 9007199254740993 {
+		t.Fatalf("expected GetInt to keep precision, got %d, %v", v, ok)
+	}
+	if v, ok := meta.GetInt("exp"); !ok || v != 1000 {
+		t.Fatalf("GetInt with exponent: %d, %v", v, ok)
+	}
+	if _, ok := meta.GetInt("ratio"); ok {
+		t.Fatalf("expected GetInt to reject a fraction")
+	}
+	if v, ok := meta.GetFloat("ratio"); !ok || v != 0.5 {
+		t.Fatalf("GetFloat: %v, %v", v, ok)
+	}
+	if v, ok := meta.GetBool("draft"); !ok || !v {
+		t.Fatalf("GetBool: %v, %v", v, ok)
+	}
+	if v, ok := meta.GetStringSlice("tags"); !ok || len(v) != 2 || v[1] != "b" {
+		t.Fatalf("GetStringSlice: %v, %v", v, ok)
+	}
+	for _, key := range []string{"missing", "absent"} {
+		if _, ok := meta.GetString(key); ok {
+			t.Fatalf("expected %q to be reported absent", key)
+		}
+	}
+
+	var info struct{ N int }
+	if found, err := meta.Decode("info", &info); !found || err != nil || info.N != 1 {
+		t.Fatalf("Decode: %+v, %v, %v", info, found, err)
+	}
+	if found, err := meta.Decode("tags", &info); !found || err == nil {
+		t.Fatalf("expected a decode error, got %v, %v", found, err)
+	}
+	if found, err := meta.Decode("absent", &info); found || err != nil {
+		t.Fatalf("expected an absent field, got %v, %v", found, err)
+	}
+}
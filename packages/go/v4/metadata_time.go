@@ -0,0 +1,154 @@
+package kreuzberg
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Timestamp is a date from document metadata, parsed and normalized to UTC, with the value as
+// the document stored it.
+type Timestamp struct {
+	// Time is the parsed instant in UTC. Values without a time zone are read as UTC, and values
+	// without a time of day as midnight.
+	Time time.Time
+	// Raw is the original metadata value.
+	Raw string
+}
+
+var (
+	pdfDatePattern = regexp.MustCompile(`^(?:D:)?(\d{4})(\d{2})?(\d{2})?(\d{2})?(\d{2})?(\d{2})?(Z|[+-]\d{2}'?\d{2}'?)?`)
+	// mailZoneComment matches the trailing zone comment of RFC 5322 dates ("(PST)").
+	mailZoneComment = regexp.MustCompile(`\s*\([A-Za-z ]+\)$`)
+)
+
+// metadataTimeLayouts are the textual date layouts found in document metadata, tried in order.
+var metadataTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"2006-01",
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	time.RFC850,
+	time.ANSIC,
+}
+
+// ParseMetadataTime parses a metadata date in any of the formats documents use: ISO 8601 and
+// RFC 3339, PDF dates ("D:20240102030405+01'00'"), RFC 5322 mail dates and C asctime. The
+// result is in UTC.
+func ParseMetadataTime(value string) (time.Time, error) {
+	t, ok := parseMetadataTime(value)
+	if !ok {
+		return time.Time{}, newParsingErrorWithContext(fmt.Sprintf("unrecognized date %q", value), nil, ErrorCodeParsing, nil)
+	}
+	return t.UTC(), nil
+}
+
+// parseMetadataTime is ParseMetadataTime keeping the time zone of the value.
+func parseMetadataTime(value string) (time.Time, bool) {
+	value = mailZoneComment.ReplaceAllString(strings.TrimSpace(value), "")
+	for _, layout := range metadataTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	m := pdfDatePattern.FindStringSubmatch(value)
+	if m == nil || m[0] != value {
+		return time.Time{}, false
+	}
+	part := func(i, fallback int) int {
+		if m[i] == "" {
+			return fallback
+		}
+		n, _ := strconv.Atoi(m[i])
+		return n
+	}
+	loc := time.UTC
+	if tz := strings.ReplaceAll(m[7], "'", ""); tz != "" && tz != "Z" {
+		offset := atoiOr(tz[1:3])*3600 + atoiOr(tz[3:])*60
+		if tz[0] == '-' {
+			offset = -offset
+		}
+		loc = time.FixedZone("", offset)
+	}
+	return time.Date(part(1, 0), time.Month(part(2, 1)), part(3, 1), part(4, 0), part(5, 0), part(6, 0), 0, loc), true
+}
+
+// normalizeMetadataDate converts a metadata date to RFC 3339, keeping its time zone, and
+// returns values it cannot parse unchanged.
+func normalizeMetadataDate(value string) string {
+	if t, ok := parseMetadataTime(value); ok {
+		return t.Format(time.RFC3339)
+	}
+	return value
+}
+
+func atoiOr(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// firstTimestamp returns the first candidate that parses as a date.
+func firstTimestamp(candidates ...string) (Timestamp, bool) {
+	for _, raw := range candidates {
+		if t, err := ParseMetadataTime(raw); err == nil {
+			return Timestamp{Time: t, Raw: raw}, true
+		}
+	}
+	return Timestamp{}, false
+}
+
+// CreatedAt returns the document creation date from the format metadata, the
+// "created_at"/"creation_date" metadata fields, or the document date.
+func (m Metadata) CreatedAt() (Timestamp, bool) {
+	var candidates []string
+	if pdf, ok := m.PdfMetadata(); ok && pdf.CreatedAt != nil {
+		candidates = append(candidates, *pdf.CreatedAt)
+	}
+	for _, key := range []string{"created_at", "creation_date"} {
+		if value, ok := m.GetString(key); ok {
+			candidates = append(candidates, value)
+		}
+	}
+	if m.Date != nil {
+		candidates = append(candidates, *m.Date)
+	}
+	if xmlMeta, ok := m.XMLMetadata(); ok && xmlMeta.Document != nil {
+		candidates = append(candidates, xmlMeta.Document.Date)
+	}
+	return firstTimestamp(candidates...)
+}
+
+// ModifiedAt returns the document modification date from the format metadata or the
+// "modified_at"/"modification_date" metadata fields.
+func (m Metadata) ModifiedAt() (Timestamp, bool) {
+	var candidates []string
+	if pdf, ok := m.PdfMetadata(); ok && pdf.ModifiedAt != nil {
+		candidates = append(candidates, *pdf.ModifiedAt)
+	}
+	for _, key := range []string{"modified_at", "modification_date"} {
+		if value, ok := m.GetString(key); ok {
+			candidates = append(candidates, value)
+		}
+	}
+	return firstTimestamp(candidates...)
+}
+
+// Timestamp parses the string metadata field key as a date.
+func (m Metadata) Timestamp(key string) (Timestamp, bool) {
+	value, ok := m.GetString(key)
+	if !ok {
+		return Timestamp{}, false
+	}
+	return firstTimestamp(value)
+}
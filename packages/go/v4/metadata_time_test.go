@@ -0,0 +1,70 @@
+package kreuzberg
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestNormalizeMetadataDate(t *testing.T) {
+	for in, want := range map[string]string{
+		"D:20240102030405+01'00'": "2024-01-02T03:04:05+01:00",
+		"D:20240102030405Z":       "2024-01-02T03:04:05Z",
+		"D:2024":                  "2024-01-01T00:00:00Z",
+		"2024-01-02":              "2024-01-02T00:00:00Z",
+		"last Tuesday":            "last Tuesday",
+	} {
+		if got := normalizeMetadataDate(in); got != want {
+			t.Errorf("normalizeMetadataDate(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseMetadataTimeFormats(t *testing.T) {
+	want := time.Date(2024, 1, 2, 2, 4, 5, 0, time.UTC)
+	for _, value := range []string{
+		"2024-01-02T03:04:05+01:00",
+		"2024-01-02T02:04:05Z",
+		"2024-01-02 02:04:05",
+		"D:20240102030405+01'00'",
+		"Tue, 2 Jan 2024 03:04:05 +0100",
+		"Tue, 02 Jan 2024 02:04:05 GMT",
+		"Mon, 1 Jan 2024 18:04:05 -0800 (PST)",
+		"Tue Jan  2 02:04:05 2024",
+	} {
+		got, err := ParseMetadataTime(value)
+		if err != nil || !got.Equal(want) || got.Location() != time.UTC {
+			t.Errorf("ParseMetadataTime(%q) = %v, %v", value, got, err)
+		}
+	}
+	if _, err := ParseMetadataTime("sometime in spring"); err == nil {
+		t.Fatalf("expected an error for an unrecognized date")
+	}
+}
+
+func TestMetadataTimestampAccessors(t *testing.T) {
+	created, modified := "D:20240102030405Z", "2024-02-03T04:05:06-05:00"
+	meta := Metadata{Format: FormatMetadata{Type: FormatPDF, Pdf: &PdfMetadata{CreatedAt: &created, ModifiedAt: &modified}}}
+	if ts, ok := meta.CreatedAt(); !ok || ts.Raw != created || !ts.Time.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Fatalf("unexpected creation date: %+v", ts)
+	}
+	if ts, ok := meta.ModifiedAt(); !ok || ts.Time != time.Date(2024, 2, 3, 9, 5, 6, 0, time.UTC) {
+		t.Fatalf("expected the modification date in UTC, got %+v", ts)
+	}
+
+	unparsable, date := "n/a", "2023-11-30"
+	meta = Metadata{
+		Date:       &date,
+		Format:     FormatMetadata{Type: FormatPDF, Pdf: &PdfMetadata{CreatedAt: &unparsable}},
+		Additional: map[string]json.RawMessage{"internal_date": json.RawMessage(`"Thu, 30 Nov 2023 10:00:00 +0000"`)},
+	}
+	if ts, ok := meta.CreatedAt(); !ok || ts.Raw != date {
+		t.Fatalf("expected the document date when the PDF date does not parse, got %+v", ts)
+	}
+	if _, ok := meta.ModifiedAt(); ok {
+		t.Fatalf("expected no modification date")
+	}
+	if ts, ok := meta.Timestamp("internal_date"); !ok || ts.Time.Hour() != 10 {
+		t.Fatalf("unexpected timestamp: %+v", ts)
+	}
+}
@@ -0,0 +1,178 @@
+package kreuzberg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// mimeOctetStream is the type overrides are looked up as for paths whose type cannot be
+// detected.
+const mimeOctetStream = "application/octet-stream"
+
+// mimeOverrides holds the MIME type overrides of a plugin scope. The zero value has none.
+//
+// The native library maps extensions and magic bytes to MIME types with fixed tables and caches
+// no detections, and the result cache keys entries by the MIME type a document is extracted
+// as, so overrides take effect for the next extraction without anything to flush.
+type mimeOverrides struct {
+	mu sync.RWMutex
+	// extensions maps lower-case extensions, without the dot, to MIME types.
+	extensions map[string]string
+	// types maps detected or caller-supplied MIME types to the types to extract as.
+	types map[string]string
+}
+
+// SetExtensionMimeType makes package-level extractions treat files with extension (with or
+// without the leading dot, matched case-insensitively) as mimeType, ahead of detection. An empty
+// mimeType removes the mapping. Client.SetExtensionMimeType scopes a mapping to a Client.
+func SetExtensionMimeType(extension, mimeType string) error {
+	return defaultPluginRegistry.mime.setExtension(extension, mimeType)
+}
+
+// SetMimeTypeOverride makes package-level extractions treat documents detected or passed as
+// detected as mimeType, e.g. application/octet-stream from a source known to deliver PDFs as
+// application/pdf. Files whose type cannot be detected from their path count as
+// application/octet-stream. An empty mimeType removes the override.
+// Client.SetMimeTypeOverride scopes an override to a Client.
+func SetMimeTypeOverride(detected, mimeType string) error {
+	return defaultPluginRegistry.mime.setType(detected, mimeType)
+}
+
+// ClearMimeOverrides removes the package-level MIME type overrides and extension mappings.
+func ClearMimeOverrides() {
+	defaultPluginRegistry.mime.clear()
+}
+
+func (o *mimeOverrides) setExtension(extension, mimeType string) error {
+	extension = strings.ToLower(strings.TrimPrefix(extension, "."))
+	if extension == "" {
+		return newValidationErrorWithContext("extension cannot be empty", nil, ErrorCodeValidation, nil)
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.extensions = setOverride(o.extensions, extension, mimeType)
+	return nil
+}
+
+func (o *mimeOverrides) setType(detected, mimeType string) error {
+	if detected == "" {
+		return newValidationErrorWithContext("detected MIME type cannot be empty", nil, ErrorCodeValidation, nil)
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.types = setOverride(o.types, detected, mimeType)
+	return nil
+}
+
+// setOverride sets or, for an empty value, deletes key in m, allocating m as needed.
+func setOverride(m map[string]string, key, value string) map[string]string {
+	if value == "" {
+		delete(m, key)
+		return m
+	}
+	if m == nil {
+		m = make(map[string]string)
+	}
+	m[key] = value
+	return m
+}
+
+func (o *mimeOverrides) clear() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.extensions, o.types = nil, nil
+}
+
+func (o *mimeOverrides) empty() bool {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return len(o.extensions) == 0 && len(o.types) == 0
+}
+
+func (o *mimeOverrides) lookup(extension, detected string) (string, bool) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	if extension != "" {
+		if mimeType, ok := o.extensions[extension]; ok {
+			return mimeType, true
+		}
+	}
+	mimeType, ok := o.types[detected]
+	return mimeType, ok
+}
+
+// detectPath detects the MIME type of the file at path and applies the overrides, reporting
+// whether one applied.
+func (o *mimeOverrides) detectPath(path string) (string, bool, error) {
+	if path == "" {
+		return "", false, newValidationErrorWithContext("path cannot be empty", nil, ErrorCodeValidation, nil)
+	}
+	extension := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	if mimeType, ok := o.lookup(extension, ""); ok {
+		return mimeType, true, nil
+	}
+	detected, err := detectMimeTypeFromPathNative(path)
+	if err != nil {
+		if _, statErr := os.Stat(path); statErr != nil {
+			return "", false, err
+		}
+		mimeType, ok := o.lookup("", mimeOctetStream)
+		if !ok {
+			return "", false, err
+		}
+		return mimeType, true, nil
+	}
+	if mimeType, ok := o.lookup("", detected); ok {
+		return mimeType, true, nil
+	}
+	return detected, false, nil
+}
+
+// detectBytes detects the MIME type of data and applies the overrides.
+func (o *mimeOverrides) detectBytes(data []byte) (string, error) {
+	detected, err := detectMimeTypeNative(data)
+	if err != nil {
+		return "", err
+	}
+	if mimeType, ok := o.lookup("", detected); ok {
+		return mimeType, nil
+	}
+	return detected, nil
+}
+
+// source returns the documentSource extracting the file at path, carrying the overridden MIME
+// type when an override applies.
+func (o *mimeOverrides) source(path string) documentSource {
+	src := documentSource{path: path}
+	if o.empty() {
+		return src
+	}
+	if mimeType, ok, err := o.detectPath(path); err == nil && ok {
+		src.mimeType = mimeType
+	}
+	return src
+}
+
+// resolveBytes returns the MIME type to extract data as, given the caller's mimeType, which may
+// be empty to detect it.
+func (o *mimeOverrides) resolveBytes(data []byte, mimeType string) string {
+	if o.empty() {
+		return mimeType
+	}
+	if mimeType == "" {
+		detected, err := detectMimeTypeNative(data)
+		if err != nil {
+			return ""
+		}
+		if override, ok := o.lookup("", detected); ok {
+			return override
+		}
+		return ""
+	}
+	if override, ok := o.lookup("", mimeType); ok {
+		return override
+	}
+	return mimeType
+}
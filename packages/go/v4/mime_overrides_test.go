@@ -0,0 +1,88 @@
+package kreuzberg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClientMimeOverrides(t *testing.T) {
+	dir := t.TempDir()
+	subs := filepath.Join(dir, "talk.SUBS")
+	blob := filepath.Join(dir, "talk.dat")
+	for _, path := range []string{subs, blob} {
+		os.WriteFile(path, []byte(testSRT), 0o600)
+	}
+
+	client := NewClient(nil)
+	if err := client.SetExtensionMimeType(".subs", mimeSRT); err != nil {
+		t.Fatalf("set extension: %v", err)
+	}
+	if err := client.SetMimeTypeOverride(mimeOctetStream, mimeSRT); err != nil {
+		t.Fatalf("set override: %v", err)
+	}
+	if mimeType, err := client.DetectMimeTypeFromPath(subs); err != nil || mimeType != mimeSRT {
+		t.Fatalf("unexpected detection %q, %v", mimeType, err)
+	}
+	for _, path := range []string{subs, blob} {
+		result, err := client.ExtractFile(t.Context(), path)
+		if err != nil || result.Content != wantSRTContent {
+			t.Fatalf("%s: unexpected result %+v, %v", path, result, err)
+		}
+	}
+	if result, err := client.ExtractBytes(t.Context(), []byte(testSRT), mimeOctetStream); err != nil || result.Content != wantSRTContent {
+		t.Fatalf("unexpected bytes result %+v, %v", result, err)
+	}
+
+	results, err := client.BatchExtractFiles(t.Context(), []string{subs, blob})
+	if err != nil {
+		t.Fatalf("batch files: %v", err)
+	}
+	items := []BytesWithMime{{Data: []byte(testSRT), MimeType: mimeOctetStream}}
+	bytesResults, err := client.BatchExtractBytes(t.Context(), items)
+	if err != nil {
+		t.Fatalf("batch bytes: %v", err)
+	}
+	for _, result := range append(results, bytesResults...) {
+		if result.Content != wantSRTContent {
+			t.Fatalf("unexpected batch result %+v", result)
+		}
+	}
+	if items[0].MimeType != mimeOctetStream {
+		t.Fatalf("expected the caller's items to be left alone")
+	}
+
+	// The overrides are scoped to the client.
+	if result, err := ExtractFileSync(subs, nil); err == nil && result.Content == wantSRTContent {
+		t.Fatalf("expected package-level extractions to ignore the client's overrides")
+	}
+	client.ClearMimeOverrides()
+	if _, err := client.ExtractFile(t.Context(), subs); err == nil {
+		t.Fatalf("expected the cleared overrides to stop applying")
+	}
+}
+
+func TestMimeOverrideValidation(t *testing.T) {
+	defer ClearMimeOverrides()
+	if err := SetExtensionMimeType(".", mimeSRT); err == nil {
+		t.Fatalf("expected an empty extension to be rejected")
+	}
+	if err := SetMimeTypeOverride("", mimeSRT); err == nil {
+		t.Fatalf("expected an empty MIME type to be rejected")
+	}
+	SetExtensionMimeType("subs", mimeSRT)
+	SetExtensionMimeType("subs", "")
+	if !defaultPluginRegistry.mime.empty() {
+		t.Fatalf("expected an empty MIME type to remove the mapping")
+	}
+
+	path := filepath.Join(t.TempDir(), "talk.subs")
+	os.WriteFile(path, []byte(testSRT), 0o600)
+	SetExtensionMimeType("subs", mimeSRT)
+	if result, err := ExtractFileSync(path, nil); err != nil || result.Content != wantSRTContent {
+		t.Fatalf("expected the package-level mapping to apply, got %+v, %v", result, err)
+	}
+	if mimeType, err := DetectMimeTypeFromPath(path); err != nil || mimeType != mimeSRT {
+		t.Fatalf("unexpected detection %q, %v", mimeType, err)
+	}
+}
@@ -0,0 +1,153 @@
+package kreuzberg
+
+/*
+#include "internal/ffi/kreuzberg.h"
+*/
+import "C"
+
+import (
+	"context"
+	"math"
+	"runtime/debug"
+	"runtime/metrics"
+	"slices"
+	"time"
+)
+
+// NativeMemoryUsage splits the resident memory of the process into the part the Go runtime
+// accounts for and the rest, which is mostly native extraction memory (the native library, its
+// parsers, OCR and embedding models) that GOMEMLIMIT does not see.
+type NativeMemoryUsage struct {
+	// Resident is the resident set size of the process.
+	Resident uint64 `json:"resident"`
+	// GoRuntime is the memory mapped by the Go runtime, excluding heap memory returned to the OS.
+	GoRuntime uint64 `json:"go_runtime"`
+	// Native is Resident minus GoRuntime, or 0 when the Go runtime maps more than is resident.
+	// It is an estimate: memory of other cgo libraries counts as native too.
+	Native uint64 `json:"native"`
+	// NativeHeap is the memory the native library's allocator holds. Unlike Native it is exact,
+	// but it only covers the library's own allocations, not those of the C and C++ libraries it
+	// bundles (PDFium, Tesseract, ONNX Runtime).
+	NativeHeap uint64 `json:"native_heap"`
+	// NativeHeapPeak is the highest NativeHeap since the native library was loaded.
+	NativeHeapPeak uint64 `json:"native_heap_peak"`
+}
+
+// NativeMemoryEvent reports native memory usage crossing a threshold.
+type NativeMemoryEvent struct {
+	Threshold uint64            `json:"threshold"`
+	Usage     NativeMemoryUsage `json:"usage"`
+	// Rising is true when usage rose to the threshold and false when it fell below it again.
+	Rising bool `json:"rising"`
+	// Err reports a sample that failed, with the other fields unset. Thresholds are checked
+	// again with the next sample that succeeds.
+	Err error `json:"-"`
+}
+
+// NativeMemoryOptions configures WatchNativeMemory.
+type NativeMemoryOptions struct {
+	// Interval is how often usage is sampled (default 1 second).
+	Interval time.Duration
+	// AdjustGoLimit lowers Go's soft memory limit by the native usage, so the limit in effect
+	// when watching starts (usually GOMEMLIMIT) bounds the whole process instead of the Go heap
+	// alone: the garbage collector works harder while extractions hold native memory. It has no
+	// effect without a limit.
+	AdjustGoLimit bool
+	// MinGoLimit is the lowest limit AdjustGoLimit sets (default a quarter of the starting
+	// limit), so native usage alone cannot drive the collector into running continuously.
+	MinGoLimit int64
+	// Thresholds are native usage levels, in bytes, at which OnThreshold is called.
+	Thresholds []uint64
+	// OnThreshold is called from the watching goroutine each time native usage crosses one of
+	// Thresholds, upwards or downwards, e.g. to shed load or alert, and each time a sample fails
+	// (see NativeMemoryEvent.Err).
+	OnThreshold func(NativeMemoryEvent)
+}
+
+// ReadNativeMemoryUsage samples the memory usage of the process. It fails where the resident
+// set size cannot be read; it is supported on Linux.
+func ReadNativeMemoryUsage() (NativeMemoryUsage, error) {
+	resident, err := residentMemory()
+	if err != nil {
+		return NativeMemoryUsage{}, err
+	}
+	samples := []metrics.Sample{{Name: "/memory/classes/total:bytes"}, {Name: "/memory/classes/heap/released:bytes"}}
+	metrics.Read(samples)
+	heap := C.kreuzberg_native_memory_stats()
+	usage := NativeMemoryUsage{
+		Resident:       resident,
+		GoRuntime:      samples[0].Value.Uint64() - samples[1].Value.Uint64(),
+		NativeHeap:     uint64(heap.allocated_bytes),
+		NativeHeapPeak: uint64(heap.peak_allocated_bytes),
+	}
+	if usage.Resident > usage.GoRuntime {
+		usage.Native = usage.Resident - usage.GoRuntime
+	}
+	return usage, nil
+}
+
+// readNativeMemory samples usage for WatchNativeMemory; tests replace it.
+var readNativeMemory = ReadNativeMemoryUsage
+
+// WatchNativeMemory samples native memory usage until ctx is cancelled, adjusting Go's memory
+// limit and calling opts.OnThreshold as configured, then restores the limit it started with and
+// returns nil. It fails at once where usage cannot be read. Run it in its own goroutine, or let
+// RunService run it (see ServiceOptions.NativeMemory); run one at a time, since each sets the
+// process-wide limit.
+func WatchNativeMemory(ctx context.Context, opts NativeMemoryOptions) error {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	thresholds := slices.Clone(opts.Thresholds)
+	slices.Sort(thresholds)
+	if _, err := readNativeMemory(); err != nil {
+		return err
+	}
+
+	baseLimit := debug.SetMemoryLimit(-1)
+	adjust := opts.AdjustGoLimit && baseLimit != math.MaxInt64
+	if adjust {
+		defer debug.SetMemoryLimit(baseLimit)
+	}
+	minLimit := opts.MinGoLimit
+	if minLimit <= 0 {
+		minLimit = baseLimit / 4
+	}
+
+	var level int // number of thresholds at or below the last sampled usage
+	sample := func() {
+		usage, err := readNativeMemory()
+		if err != nil {
+			if opts.OnThreshold != nil {
+				opts.OnThreshold(NativeMemoryEvent{Err: err})
+			}
+			return
+		}
+		if adjust {
+			limit := baseLimit - int64(min(usage.Native, uint64(baseLimit)))
+			debug.SetMemoryLimit(max(limit, minLimit))
+		}
+		next, _ := slices.BinarySearch(thresholds, usage.Native+1)
+		if opts.OnThreshold == nil {
+			level = next
+			return
+		}
+		for ; level < next; level++ {
+			opts.OnThreshold(NativeMemoryEvent{Threshold: thresholds[level], Usage: usage, Rising: true})
+		}
+		for ; level > next; level-- {
+			opts.OnThreshold(NativeMemoryEvent{Threshold: thresholds[level-1], Usage: usage})
+		}
+	}
+
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+	for {
+		sample()
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,26 @@
+//go:build linux
+
+package kreuzberg
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+)
+
+// residentMemory reads the resident set size of the process from /proc/self/statm.
+func residentMemory() (uint64, error) {
+	data, err := os.ReadFile("/proc/self/statm")
+	if err != nil {
+		return 0, newIOErrorWithContext("failed to read process memory usage", err, ErrorCodeIo, nil)
+	}
+	fields := bytes.Fields(data)
+	if len(fields) < 2 {
+		return 0, newIOErrorWithContext("unexpected format of /proc/self/statm", nil, ErrorCodeIo, nil)
+	}
+	pages, err := strconv.ParseUint(string(fields[1]), 10, 64)
+	if err != nil {
+		return 0, newIOErrorWithContext("unexpected format of /proc/self/statm", err, ErrorCodeIo, nil)
+	}
+	return pages * uint64(os.Getpagesize()), nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package kreuzberg
+
+import "runtime"
+
+// residentMemory is not supported on this platform.
+func residentMemory() (uint64, error) {
+	return 0, newRuntimeErrorWithContext("native memory usage cannot be read on "+runtime.GOOS, nil, ErrorCodeInternal, nil)
+}
@@ -0,0 +1,115 @@
+package kreuzberg
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReadNativeMemoryUsage(t *testing.T) {
+	usage, err := ReadNativeMemoryUsage()
+	if runtime.GOOS != "linux" {
+		if err == nil {
+			t.Fatalf("expected an error on %s", runtime.GOOS)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if usage.Resident == 0 || usage.GoRuntime == 0 || usage.Native > usage.Resident || usage.NativeHeap > usage.NativeHeapPeak {
+		t.Fatalf("unexpected usage %+v", usage)
+	}
+}
+
+func TestWatchNativeMemory(t *testing.T) {
+	const mib = 1 << 20
+	var mu sync.Mutex
+	native := []uint64{10 * mib}
+	var sampleErr error
+	readNativeMemory = func() (NativeMemoryUsage, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return NativeMemoryUsage{Native: native[0]}, sampleErr
+	}
+	defer func() { readNativeMemory = ReadNativeMemoryUsage }()
+	setNative := func(n uint64) {
+		mu.Lock()
+		native[0] = n
+		mu.Unlock()
+	}
+	base := debug.SetMemoryLimit(1000 * mib)
+	defer debug.SetMemoryLimit(base)
+
+	events := make(chan NativeMemoryEvent, 16)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- WatchNativeMemory(ctx, NativeMemoryOptions{
+			Interval:      5 * time.Millisecond,
+			AdjustGoLimit: true,
+			MinGoLimit:    300 * mib,
+			Thresholds:    []uint64{500 * mib, 100 * mib},
+			OnThreshold:   func(e NativeMemoryEvent) { events <- e },
+		})
+	}()
+	waitLimit := func(want int64) {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+			if debug.SetMemoryLimit(-1) == want {
+				return
+			}
+		}
+		t.Fatalf("expected the limit %d, got %d", want, debug.SetMemoryLimit(-1))
+	}
+	waitLimit(990 * mib)
+
+	setNative(600 * mib)
+	for _, want := range []uint64{100 * mib, 500 * mib} {
+		if e := <-events; e.Threshold != want || !e.Rising {
+			t.Fatalf("unexpected event %+v", e)
+		}
+	}
+	waitLimit(400 * mib)
+
+	setNative(900 * mib)
+	waitLimit(300 * mib)
+
+	setNative(200 * mib)
+	if e := <-events; e.Threshold != 500*mib || e.Rising {
+		t.Fatalf("unexpected event %+v", e)
+	}
+	waitLimit(800 * mib)
+
+	// A failed sample is reported and leaves the limit as it was.
+	unreadable := errors.New("unreadable")
+	mu.Lock()
+	sampleErr = unreadable
+	mu.Unlock()
+	if e := <-events; !errors.Is(e.Err, unreadable) || e.Threshold != 0 {
+		t.Fatalf("expected the failed sample to be reported, got %+v", e)
+	}
+	if limit := debug.SetMemoryLimit(-1); limit != 800*mib {
+		t.Fatalf("expected the limit to be kept, got %d", limit)
+	}
+
+	cancel()
+	// Failed samples keep being reported until the watcher stops.
+	for stopped := false; !stopped; {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("watch: %v", err)
+			}
+			stopped = true
+		case <-events:
+		}
+	}
+	if limit := debug.SetMemoryLimit(-1); limit != 1000*mib {
+		t.Fatalf("expected the limit to be restored, got %d", limit)
+	}
+}
@@ -0,0 +1,220 @@
+package kreuzberg
+
+/*
+#define _GNU_SOURCE
+#include <stdint.h>
+#include <pthread.h>
+#include <signal.h>
+#include <ucontext.h>
+
+// kreuzbergTracebackArg mirrors the argument of runtime.SetCgoTraceback's traceback function.
+struct kreuzbergTracebackArg {
+	uintptr_t context;
+	uintptr_t sigContext;
+	uintptr_t *buf;
+	uintptr_t max;
+};
+
+static volatile int kreuzbergWalkFramePointers;
+
+// The stack bounds of the current thread, recorded by kreuzbergRecordStackBounds because they
+// cannot be looked up inside a signal handler. The initial-exec model keeps the handler's access
+// free of allocation.
+static __thread volatile uintptr_t kreuzbergStackLo __attribute__((tls_model("initial-exec")));
+static __thread volatile uintptr_t kreuzbergStackHi __attribute__((tls_model("initial-exec")));
+
+// kreuzbergRecordStackBounds records the stack bounds of the calling thread once. It is not
+// static because native_profiling.go calls it before native calls.
+void kreuzbergRecordStackBounds(void) {
+	if (kreuzbergStackHi != 0) {
+		return;
+	}
+#if defined(__APPLE__)
+	pthread_t self = pthread_self();
+	uintptr_t hi = (uintptr_t)pthread_get_stackaddr_np(self);
+	kreuzbergStackLo = hi - pthread_get_stacksize_np(self);
+	kreuzbergStackHi = hi;
+#elif defined(__linux__)
+	pthread_attr_t attr;
+	void *addr;
+	size_t size;
+	if (pthread_getattr_np(pthread_self(), &attr) != 0) {
+		return;
+	}
+	if (pthread_attr_getstack(&attr, &addr, &size) == 0) {
+		kreuzbergStackLo = (uintptr_t)addr;
+		kreuzbergStackHi = (uintptr_t)addr + size;
+	}
+	pthread_attr_destroy(&attr);
+#endif
+}
+
+// kreuzbergRegisters reads the interrupted PC, frame pointer and stack pointer from a signal
+// context. It returns 0 on platforms without support.
+static int kreuzbergRegisters(void *sigContext, uintptr_t *pc, uintptr_t *fp, uintptr_t *sp) {
+	ucontext_t *uc = (ucontext_t *)sigContext;
+#if defined(__linux__) && defined(__x86_64__)
+	*pc = (uintptr_t)uc->uc_mcontext.gregs[REG_RIP];
+	*fp = (uintptr_t)uc->uc_mcontext.gregs[REG_RBP];
+	*sp = (uintptr_t)uc->uc_mcontext.gregs[REG_RSP];
+	return 1;
+#elif defined(__linux__) && defined(__aarch64__)
+	*pc = (uintptr_t)uc->uc_mcontext.pc;
+	*fp = (uintptr_t)uc->uc_mcontext.regs[29];
+	*sp = (uintptr_t)uc->uc_mcontext.sp;
+	return 1;
+#elif defined(__APPLE__) && defined(__x86_64__)
+	*pc = (uintptr_t)uc->uc_mcontext->__ss.__rip;
+	*fp = (uintptr_t)uc->uc_mcontext->__ss.__rbp;
+	*sp = (uintptr_t)uc->uc_mcontext->__ss.__rsp;
+	return 1;
+#elif defined(__APPLE__) && defined(__aarch64__)
+	*pc = (uintptr_t)uc->uc_mcontext->__ss.__pc;
+	*fp = (uintptr_t)uc->uc_mcontext->__ss.__fp;
+	*sp = (uintptr_t)uc->uc_mcontext->__ss.__sp;
+	return 1;
+#else
+	(void)uc; (void)pc; (void)fp; (void)sp;
+	return 0;
+#endif
+}
+
+static int kreuzbergNativeProfilingSupported(void) {
+	uintptr_t pc, fp, sp;
+	ucontext_t uc = {0};
+#if defined(__APPLE__)
+	struct __darwin_mcontext64 mc = {0};
+	uc.uc_mcontext = &mc;
+#endif
+	return kreuzbergRegisters(&uc, &pc, &fp, &sp);
+}
+
+// kreuzbergCgoTraceback runs inside the profiling signal handler, so it only reads registers
+// and, when enabled, walks the frame-pointer chain between the stack pointer and the top of the
+// thread's recorded stack. Threads whose stack was not recorded, or samples taken off that stack,
+// carry only the interrupted PC. It is not static because Go takes its address.
+void kreuzbergCgoTraceback(void *p) {
+	struct kreuzbergTracebackArg *arg = (struct kreuzbergTracebackArg *)p;
+	uintptr_t n = 0, pc = 0, fp = 0, sp = 0;
+	if (arg->sigContext != 0 && kreuzbergRegisters((void *)arg->sigContext, &pc, &fp, &sp) && pc != 0) {
+		arg->buf[n++] = pc;
+		uintptr_t lo = kreuzbergStackLo, hi = kreuzbergStackHi;
+		if (kreuzbergWalkFramePointers && hi != 0 && sp >= lo && sp < hi) {
+			// A frame record is the saved frame pointer followed by the return address.
+			uintptr_t limit = hi - 2 * sizeof(uintptr_t);
+			while (n < arg->max && fp >= sp && fp <= limit && (fp & (sizeof(uintptr_t) - 1)) == 0) {
+				uintptr_t next = ((uintptr_t *)fp)[0];
+				uintptr_t ret = ((uintptr_t *)fp)[1];
+				if (ret == 0) {
+					break;
+				}
+				arg->buf[n++] = ret;
+				if (next <= fp) {
+					break;
+				}
+				fp = next;
+			}
+		}
+	}
+	if (n < arg->max) {
+		arg->buf[n] = 0;
+	}
+}
+
+static void kreuzbergSetWalkFramePointers(int enabled) {
+	kreuzbergWalkFramePointers = enabled;
+}
+*/
+import "C"
+
+import (
+	"io"
+	"runtime"
+	"runtime/pprof"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// NativeProfilingOptions configures EnableNativeProfiling.
+type NativeProfilingOptions struct {
+	// FramePointers walks native call stacks through frame pointers. Enable it only when
+	// kreuzberg-ffi is built with frame pointers (RUSTFLAGS="-C force-frame-pointers=yes");
+	// otherwise samples taken in native code carry only the innermost native frame. Stacks are
+	// walked on the threads making extraction and conversion calls; samples taken on the
+	// library's own worker threads also carry only the innermost frame.
+	FramePointers bool
+}
+
+var nativeProfiling struct {
+	once          sync.Once
+	err           error
+	framePointers atomic.Bool
+}
+
+// EnableNativeProfiling makes Go CPU profiles include the native frames of samples taken while a
+// goroutine is inside the native library, instead of stopping at the cgo call. It applies to
+// every profile collected afterwards, including runtime/pprof and net/http/pprof sessions.
+// Native frames are recorded as addresses in the kreuzberg-ffi mapping; `go tool pprof`
+// symbolizes them from the library's symbol table.
+//
+// The traceback hook is installed once per process with runtime.SetCgoTraceback; later calls
+// only update opts.
+func EnableNativeProfiling(opts NativeProfilingOptions) error {
+	if err := installNativeProfiling(); err != nil {
+		return err
+	}
+	setNativeProfilingOptions(opts)
+	return nil
+}
+
+func installNativeProfiling() error {
+	nativeProfiling.once.Do(func() {
+		if C.kreuzbergNativeProfilingSupported() == 0 {
+			nativeProfiling.err = newRuntimeErrorWithContext("native profiling is not supported on "+runtime.GOOS+"/"+runtime.GOARCH, nil, ErrorCodeInternal, nil)
+			return
+		}
+		runtime.SetCgoTraceback(0, unsafe.Pointer(C.kreuzbergCgoTraceback), nil, nil)
+	})
+	return nativeProfiling.err
+}
+
+func setNativeProfilingOptions(opts NativeProfilingOptions) {
+	walk := C.int(0)
+	if opts.FramePointers {
+		walk = 1
+	}
+	C.kreuzbergSetWalkFramePointers(walk)
+	nativeProfiling.framePointers.Store(opts.FramePointers)
+}
+
+// pinNativeStack prepares the current thread for a long native call while frame pointers are
+// walked: it locks the goroutine to the thread and records the thread's stack bounds, which
+// bound the walk. Call the returned function once the native call returns.
+func pinNativeStack() (unpin func()) {
+	if !nativeProfiling.framePointers.Load() {
+		return func() {}
+	}
+	runtime.LockOSThread()
+	C.kreuzbergRecordStackBounds()
+	return runtime.UnlockOSThread
+}
+
+// StartCPUProfile enables native profiling with opts and starts a Go CPU profile written to w in
+// pprof format (see pprof.StartCPUProfile). Stop it with StopCPUProfile. When a profile is
+// already running, it returns an error and leaves that profile's options alone.
+func StartCPUProfile(w io.Writer, opts NativeProfilingOptions) error {
+	if err := installNativeProfiling(); err != nil {
+		return err
+	}
+	if err := pprof.StartCPUProfile(w); err != nil {
+		return newRuntimeErrorWithContext("failed to start CPU profile", err, ErrorCodeInternal, nil)
+	}
+	setNativeProfilingOptions(opts)
+	return nil
+}
+
+// StopCPUProfile stops the CPU profile started by StartCPUProfile and flushes it.
+func StopCPUProfile() {
+	pprof.StopCPUProfile()
+}
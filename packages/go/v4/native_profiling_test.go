@@ -0,0 +1,148 @@
+package kreuzberg
+
+import (
+	"bytes"
+	"compress/gzip"
+	"debug/elf"
+	"io"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStartCPUProfileCoversNativeCalls(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("native frames are symbolized from the ELF symbol table")
+	}
+	html := []byte("<html><body>" + strings.Repeat("<h2>Section</h2><p>Some <b>bold</b> text and a <a href=\"#x\">link</a>.</p>", 2000) + "</body></html>")
+
+	var profile bytes.Buffer
+	if err := StartCPUProfile(&profile, NativeProfilingOptions{FramePointers: true}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if err := StartCPUProfile(&bytes.Buffer{}, NativeProfilingOptions{}); err == nil {
+		StopCPUProfile()
+		t.Fatalf("expected an error while a profile is running")
+	}
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); {
+		// Only the time spent in the native library matters here, not the result.
+		ExtractBytesSync(html, "text/html", nil)
+	}
+	StopCPUProfile()
+
+	stacks := nativeProfileStacks(t, profile.Bytes())
+	deepest := []string{}
+	for _, stack := range stacks {
+		if len(stack) > len(deepest) {
+			deepest = stack
+		}
+	}
+	if len(deepest) == 0 {
+		t.Fatalf("expected samples with symbolized native frames, got %d native stacks", len(stacks))
+	}
+	t.Logf("deepest native stack: %s", strings.Join(deepest, " <- "))
+
+	// The hook stays installed; the options can be changed for later sessions.
+	if err := EnableNativeProfiling(NativeProfilingOptions{}); err != nil {
+		t.Fatalf("enable: %v", err)
+	}
+}
+
+// nativeProfileStacks decodes a gzipped pprof profile and returns, for each sample, the names of
+// its frames in the kreuzberg-ffi library, symbolized from the library's ELF symbol table.
+func nativeProfileStacks(t *testing.T, data []byte) [][]string {
+	t.Helper()
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("profile: %v", err)
+	}
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("profile: %v", err)
+	}
+	decode := func(b []byte) protoMessage {
+		msg, err := decodeProto(b)
+		if err != nil {
+			t.Fatalf("profile: %v", err)
+		}
+		return msg
+	}
+	prof := decode(raw)
+	var strs []string
+	for _, s := range prof[6] {
+		b, _ := s.([]byte)
+		strs = append(strs, string(b))
+	}
+
+	type mapping struct {
+		start, offset uint64
+		symbolize     func(fileOffset uint64) string
+	}
+	mappings := map[uint64]mapping{}
+	for _, m := range prof[3] {
+		b, _ := m.([]byte)
+		msg := decode(b)
+		file := strs[msg.uint(5)]
+		if !strings.Contains(filepath.Base(file), "kreuzberg_ffi") {
+			continue
+		}
+		mappings[msg.uint(1)] = mapping{start: msg.uint(2), offset: msg.uint(4), symbolize: elfSymbolizer(t, file)}
+	}
+	locations := map[uint64]string{}
+	for _, l := range prof[4] {
+		b, _ := l.([]byte)
+		msg := decode(b)
+		if m, ok := mappings[msg.uint(2)]; ok {
+			if name := m.symbolize(msg.uint(3) - m.start + m.offset); name != "" {
+				locations[msg.uint(1)] = name
+			}
+		}
+	}
+
+	var stacks [][]string
+	for _, s := range prof[2] {
+		b, _ := s.([]byte)
+		var stack []string
+		for _, id := range decode(b).uints(1) {
+			if name, ok := locations[id]; ok {
+				stack = append(stack, name)
+			}
+		}
+		if len(stack) > 0 {
+			stacks = append(stacks, stack)
+		}
+	}
+	return stacks
+}
+
+// elfSymbolizer returns the function symbol of the library at path covering a file offset.
+func elfSymbolizer(t *testing.T, path string) func(uint64) string {
+	t.Helper()
+	f, err := elf.Open(path)
+	if err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	defer f.Close()
+	syms, _ := f.Symbols()
+	dynamic, _ := f.DynamicSymbols()
+	syms = append(syms, dynamic...)
+	progs := f.Progs
+	return func(fileOffset uint64) string {
+		for _, p := range progs {
+			if p.Type != elf.PT_LOAD || fileOffset < p.Off || fileOffset >= p.Off+p.Filesz {
+				continue
+			}
+			// Return addresses point past the call, so look up the byte before them too.
+			for _, addr := range []uint64{fileOffset - p.Off + p.Vaddr, fileOffset - p.Off + p.Vaddr - 1} {
+				for _, sym := range syms {
+					if elf.ST_TYPE(sym.Info) == elf.STT_FUNC && addr >= sym.Value && addr < sym.Value+sym.Size {
+						return sym.Name
+					}
+				}
+			}
+		}
+		return ""
+	}
+}
@@ -0,0 +1,217 @@
+// Package nativeloader installs the kreuzberg native library for the current platform from a
+// bundle holding builds for several platforms.
+//
+// The kreuzberg package links the native library when the process starts, so a process cannot
+// switch libraries once it runs. This package has no cgo and does not import kreuzberg: build a
+// small launcher with CGO_ENABLED=0 that embeds the bundle, installs the right library and
+// starts the program that uses the binding with Env set:
+//
+//	//go:embed native
+//	var native embed.FS
+//
+//	sub, _ := fs.Sub(native, "native")
+//	bundle, err := nativeloader.Load(sub)
+//	dir := filepath.Join(cacheDir, "kreuzberg-native")
+//	if _, err := bundle.Install(dir); err != nil { ... }
+//	key, value := nativeloader.Env(dir)
+//	cmd := exec.Command(serverPath, os.Args[1:]...)
+//	cmd.Env = append(os.Environ(), key+"="+value)
+package nativeloader
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"debug/elf"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Platform identifies the OS, architecture and, on Linux, C library a native library build is
+// for.
+type Platform struct {
+	OS   string // runtime.GOOS, e.g. "linux"
+	Arch string // runtime.GOARCH, e.g. "arm64"
+	// Libc is "glibc" or "musl" on Linux and empty elsewhere.
+	Libc string
+}
+
+// String returns the platform key used in bundle manifests, e.g. "linux-amd64-musl" or
+// "darwin-arm64".
+func (p Platform) String() string {
+	key := p.OS + "-" + p.Arch
+	if p.Libc != "" {
+		key += "-" + p.Libc
+	}
+	return key
+}
+
+// Current returns the platform of the host. On Linux the C library is that of the host's
+// dynamic loader, since a CGO_ENABLED=0 launcher is statically linked and has none of its own;
+// a dynamically linked executable goes by its program interpreter instead.
+func Current() Platform {
+	p := Platform{OS: runtime.GOOS, Arch: runtime.GOARCH}
+	if p.OS == "linux" {
+		p.Libc = detectLibc()
+	}
+	return p
+}
+
+func detectLibc() string {
+	if exe, err := os.Executable(); err == nil {
+		if f, err := elf.Open(exe); err == nil {
+			defer f.Close()
+			for _, prog := range f.Progs {
+				if prog.Type != elf.PT_INTERP {
+					continue
+				}
+				interp := make([]byte, prog.Filesz)
+				if _, err := prog.ReadAt(interp, 0); err == nil {
+					if bytes.Contains(interp, []byte("musl")) {
+						return "musl"
+					}
+					return "glibc"
+				}
+			}
+		}
+	}
+	if matches, _ := filepath.Glob("/lib/ld-musl-*.so.1"); len(matches) > 0 {
+		return "musl"
+	}
+	return "glibc"
+}
+
+// Library is one entry of a bundle manifest.
+type Library struct {
+	// Platform is the Platform key the library was built for. Linux entries without a libc
+	// suffix are taken to be glibc builds.
+	Platform string `json:"platform"`
+	// Path is the slash-separated path of the library in the bundle, e.g.
+	// "linux-amd64-musl/libkreuzberg_ffi.so".
+	Path string `json:"path"`
+	// SHA256 is the hex SHA-256 digest of the library.
+	SHA256 string `json:"sha256"`
+}
+
+// Bundle holds native libraries for several platforms, typically embedded into a launcher
+// binary with go:embed, so that one artifact can be shipped to a heterogeneous fleet.
+type Bundle struct {
+	FS        fs.FS
+	Libraries []Library
+}
+
+// ManifestName is the manifest file at the root of a bundle.
+const ManifestName = "manifest.json"
+
+// Load reads the bundle in fsys, whose manifest.json lists its libraries as a JSON array of
+// Library entries.
+func Load(fsys fs.FS) (*Bundle, error) {
+	data, err := fs.ReadFile(fsys, ManifestName)
+	if err != nil {
+		return nil, fmt.Errorf("nativeloader: read manifest: %w", err)
+	}
+	bundle := &Bundle{FS: fsys}
+	if err := json.Unmarshal(data, &bundle.Libraries); err != nil {
+		return nil, fmt.Errorf("nativeloader: decode manifest: %w", err)
+	}
+	for _, lib := range bundle.Libraries {
+		if lib.Platform == "" || !fs.ValidPath(lib.Path) || len(lib.SHA256) != sha256.Size*2 {
+			return nil, fmt.Errorf("nativeloader: invalid manifest entry %+v", lib)
+		}
+	}
+	return bundle, nil
+}
+
+// Select returns the library built for platform.
+func (b *Bundle) Select(platform Platform) (Library, error) {
+	keys := []string{platform.String()}
+	if platform.Libc == "glibc" {
+		keys = append(keys, Platform{OS: platform.OS, Arch: platform.Arch}.String())
+	}
+	for _, key := range keys {
+		for _, lib := range b.Libraries {
+			if lib.Platform == key {
+				return lib, nil
+			}
+		}
+	}
+	return Library{}, fmt.Errorf("nativeloader: no library bundled for %s", platform)
+}
+
+// Install selects the library for the current platform, verifies its checksum and writes it to
+// dir under its base name, returning the path written. A library already installed with the
+// same checksum is left in place; otherwise the file is replaced atomically, so a concurrent
+// launcher never sees a partial library.
+func (b *Bundle) Install(dir string) (string, error) {
+	lib, err := b.Select(Current())
+	if err != nil {
+		return "", err
+	}
+	data, err := fs.ReadFile(b.FS, lib.Path)
+	if err != nil {
+		return "", fmt.Errorf("nativeloader: read bundled library %s: %w", lib.Path, err)
+	}
+	if err := verifySHA256(data, lib.SHA256); err != nil {
+		return "", fmt.Errorf("nativeloader: bundled library %s failed verification: %w", lib.Path, err)
+	}
+
+	target := filepath.Join(dir, path.Base(lib.Path))
+	if existing, err := os.ReadFile(target); err == nil && verifySHA256(existing, lib.SHA256) == nil {
+		return target, nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("nativeloader: create library directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return "", fmt.Errorf("nativeloader: install library: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0o755)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), target)
+	}
+	if err != nil {
+		return "", fmt.Errorf("nativeloader: install library: %w", err)
+	}
+	return target, nil
+}
+
+func verifySHA256(data []byte, want string) error {
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, want) {
+		return fmt.Errorf("checksum %s, expected %s", got, want)
+	}
+	return nil
+}
+
+// Env returns the environment variable through which the dynamic linker of the current OS
+// finds libraries in dir, with dir prepended to its current value: LD_LIBRARY_PATH on Linux,
+// DYLD_FALLBACK_LIBRARY_PATH on macOS and PATH on Windows.
+func Env(dir string) (key, value string) {
+	switch runtime.GOOS {
+	case "darwin":
+		key = "DYLD_FALLBACK_LIBRARY_PATH"
+	case "windows":
+		key = "PATH"
+	default:
+		key = "LD_LIBRARY_PATH"
+	}
+	value = dir
+	if current := os.Getenv(key); current != "" {
+		value += string(os.PathListSeparator) + current
+	}
+	return key, value
+}
@@ -0,0 +1,96 @@
+package nativeloader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func nativeBundle(t *testing.T, libs map[string][]byte) fstest.MapFS {
+	t.Helper()
+	fsys := fstest.MapFS{}
+	var manifest []Library
+	for platform, data := range libs {
+		path := platform + "/libkreuzberg_ffi.so"
+		sum := sha256.Sum256(data)
+		manifest = append(manifest, Library{Platform: platform, Path: path, SHA256: hex.EncodeToString(sum[:])})
+		fsys[path] = &fstest.MapFile{Data: data}
+	}
+	raw, _ := json.Marshal(manifest)
+	fsys[ManifestName] = &fstest.MapFile{Data: raw}
+	return fsys
+}
+
+func TestCurrent(t *testing.T) {
+	p := Current()
+	if p.OS != runtime.GOOS || p.Arch != runtime.GOARCH {
+		t.Fatalf("unexpected platform %v", p)
+	}
+	if (runtime.GOOS == "linux") != (p.Libc == "glibc" || p.Libc == "musl") {
+		t.Fatalf("unexpected libc %q", p.Libc)
+	}
+}
+
+func TestBundleSelect(t *testing.T) {
+	bundle, err := Load(nativeBundle(t, map[string][]byte{
+		"linux-amd64":      []byte("glibc"),
+		"linux-amd64-musl": []byte("musl"),
+		"darwin-arm64":     []byte("mac"),
+	}))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	for platform, want := range map[Platform]string{
+		{OS: "linux", Arch: "amd64", Libc: "glibc"}: "linux-amd64",
+		{OS: "linux", Arch: "amd64", Libc: "musl"}:  "linux-amd64-musl",
+		{OS: "darwin", Arch: "arm64"}:               "darwin-arm64",
+	} {
+		if lib, err := bundle.Select(platform); err != nil || lib.Platform != want {
+			t.Fatalf("%v: expected %s, got %+v, %v", platform, want, lib, err)
+		}
+	}
+	if _, err := bundle.Select(Platform{OS: "linux", Arch: "arm64", Libc: "musl"}); err == nil {
+		t.Fatalf("expected an error for an unbundled platform")
+	}
+
+	bad := nativeBundle(t, nil)
+	bad[ManifestName] = &fstest.MapFile{Data: []byte(`[{"platform":"linux-amd64","path":"../lib.so","sha256":"00"}]`)}
+	if _, err := Load(bad); err == nil {
+		t.Fatalf("expected an invalid manifest to be rejected")
+	}
+}
+
+func TestBundleInstall(t *testing.T) {
+	fsys := nativeBundle(t, map[string][]byte{Current().String(): []byte("native library")})
+	bundle, err := Load(fsys)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	dir := filepath.Join(t.TempDir(), "lib")
+	path, err := bundle.Install(dir)
+	if err != nil {
+		t.Fatalf("install: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "native library" || filepath.Dir(path) != dir {
+		t.Fatalf("unexpected installed library %s: %q", path, data)
+	}
+	if again, err := bundle.Install(dir); err != nil || again != path {
+		t.Fatalf("expected a repeated install to succeed, got %v", err)
+	}
+
+	fsys[bundle.Libraries[0].Path].Data = []byte("tampered library")
+	if _, err := bundle.Install(t.TempDir()); err == nil || !strings.Contains(err.Error(), "verification") {
+		t.Fatalf("expected a checksum failure, got %v", err)
+	}
+
+	key, value := Env(dir)
+	if key == "" || !strings.HasPrefix(value, dir) {
+		t.Fatalf("unexpected environment %s=%s", key, value)
+	}
+}
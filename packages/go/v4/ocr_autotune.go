@@ -0,0 +1,365 @@
+package kreuzberg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// OCRAutoTuneConfig enables automatic OCR parameter tuning. The first document of each class is
+// extracted with every candidate of a small grid of Tesseract page segmentation modes, target
+// DPIs and preprocessing steps; the best-scoring candidate's result is returned and its
+// parameters are reused for later documents of the class, which are extracted once.
+//
+// Tuning applies to images and, when ForceOCR is set, to every document that no built-in Go
+// extractor claims. The chosen parameters are reported in Metadata.Additional["ocr_autotune"].
+type OCRAutoTuneConfig struct {
+	// PSMs are the page segmentation modes to try (default 3, 4, 6 and 11).
+	PSMs []int
+	// DPIs are the target DPIs to try (default 300 and 400).
+	DPIs []int
+	// Preprocessing lists the preprocessing variants to try; TargetDPI is overridden by DPIs
+	// (default: none, and deskew with denoising and contrast enhancement).
+	Preprocessing []ImagePreprocessingConfig
+	// Score rates a candidate's result; the highest score wins (default: OCRTextScore of the
+	// content). Scores are averaged over the samples passed to TuneOCR.
+	Score func(*ExtractionResult) float64
+	// Class names the document class a choice is cached for, e.g. a scanner or form type
+	// (default: the MIME type). Returning "" tunes the document without caching the choice.
+	Class func(mimeType, path string) string
+	// Cache stores the choice per class (default: a process-wide cache).
+	Cache *OCRTuningCache
+}
+
+// OCRCandidate is one combination of OCR parameters.
+type OCRCandidate struct {
+	PSM           int                      `json:"psm"`
+	DPI           int                      `json:"dpi"`
+	Preprocessing ImagePreprocessingConfig `json:"preprocessing"`
+}
+
+// OCRCandidateScore is the score of a candidate, or the error that disqualified it.
+type OCRCandidateScore struct {
+	Candidate OCRCandidate `json:"candidate"`
+	Score     float64      `json:"score"`
+	Err       error        `json:"-"`
+}
+
+// OCRTuning is the outcome of tuning.
+type OCRTuning struct {
+	Best      OCRCandidate `json:"best"`
+	BestScore float64      `json:"best_score"`
+	// Candidates lists every candidate in grid order.
+	Candidates []OCRCandidateScore `json:"candidates"`
+}
+
+// OCRTuningCache remembers the tuned candidate per document class. It is safe for concurrent
+// use; concurrent extractions of an untuned class wait for a single tuning run.
+type OCRTuningCache struct {
+	mu       sync.Mutex
+	choices  map[string]OCRCandidate
+	inflight map[string]chan struct{}
+}
+
+// NewOCRTuningCache returns an empty cache.
+func NewOCRTuningCache() *OCRTuningCache {
+	return &OCRTuningCache{choices: map[string]OCRCandidate{}, inflight: map[string]chan struct{}{}}
+}
+
+var defaultOCRTuningCache = NewOCRTuningCache()
+
+// Choice returns the candidate chosen for class.
+func (c *OCRTuningCache) Choice(class string) (OCRCandidate, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	candidate, ok := c.choices[class]
+	return candidate, ok
+}
+
+// Set records the candidate for class, e.g. to restore choices persisted by an earlier run.
+func (c *OCRTuningCache) Set(class string, candidate OCRCandidate) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.choices[class] = candidate
+}
+
+// Forget drops the choice for class, so the next document of the class is tuned again.
+func (c *OCRTuningCache) Forget(class string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.choices, class)
+}
+
+// acquire returns the choice for class, or reports that the caller must tune the class and
+// call release afterwards. Callers arriving while the class is tuned wait for the result.
+func (c *OCRTuningCache) acquire(ctx context.Context, class string) (OCRCandidate, bool, error) {
+	for {
+		c.mu.Lock()
+		if candidate, ok := c.choices[class]; ok {
+			c.mu.Unlock()
+			return candidate, true, nil
+		}
+		wait, busy := c.inflight[class]
+		if !busy {
+			c.inflight[class] = make(chan struct{})
+			c.mu.Unlock()
+			return OCRCandidate{}, false, nil
+		}
+		c.mu.Unlock()
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return OCRCandidate{}, false, ctx.Err()
+		}
+	}
+}
+
+func (c *OCRTuningCache) release(class string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	close(c.inflight[class])
+	delete(c.inflight, class)
+}
+
+// OCRTextScore rates OCR output by how much of it looks like real text: the number of
+// characters in plausible tokens (words in a consistent case, numbers) minus the number of
+// characters in implausible ones (mixed letters and symbols, erratic case). Longer clean output
+// scores higher; noise lowers the score.
+func OCRTextScore(text string) float64 {
+	score := 0
+	for _, token := range strings.Fields(text) {
+		token = strings.TrimFunc(token, unicode.IsPunct)
+		if token == "" {
+			continue
+		}
+		n := len([]rune(token))
+		if plausibleOCRToken(token) {
+			score += n
+		} else {
+			score -= n
+		}
+	}
+	return float64(score)
+}
+
+func plausibleOCRToken(token string) bool {
+	letters, digits, upper, other := 0, 0, 0, 0
+	for _, r := range token {
+		switch {
+		case unicode.IsLetter(r):
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		case unicode.IsDigit(r):
+			digits++
+		case strings.ContainsRune(".,:/-'%", r):
+		default:
+			other++
+		}
+	}
+	if other > 0 || (letters > 0 && digits > 0) {
+		return false
+	}
+	if letters == 0 {
+		return digits > 0
+	}
+	// Lower case, upper case, or capitalized.
+	first := []rune(token)[0]
+	return upper == 0 || upper == letters || (upper == 1 && unicode.IsUpper(first))
+}
+
+func (t *OCRAutoTuneConfig) candidates() []OCRCandidate {
+	psms, dpis, preprocessing := t.PSMs, t.DPIs, t.Preprocessing
+	if len(psms) == 0 {
+		psms = []int{3, 4, 6, 11}
+	}
+	if len(dpis) == 0 {
+		dpis = []int{300, 400}
+	}
+	if len(preprocessing) == 0 {
+		preprocessing = []ImagePreprocessingConfig{{}, {Deskew: BoolPtr(true), Denoise: BoolPtr(true), ContrastEnhance: BoolPtr(true)}}
+	}
+	var out []OCRCandidate
+	for _, pre := range preprocessing {
+		for _, dpi := range dpis {
+			for _, psm := range psms {
+				out = append(out, OCRCandidate{PSM: psm, DPI: dpi, Preprocessing: pre})
+			}
+		}
+	}
+	return out
+}
+
+func (t *OCRAutoTuneConfig) score(result *ExtractionResult) float64 {
+	if t.Score != nil {
+		return t.Score(result)
+	}
+	return OCRTextScore(result.Content)
+}
+
+func (t *OCRAutoTuneConfig) cache() *OCRTuningCache {
+	if t.Cache != nil {
+		return t.Cache
+	}
+	return defaultOCRTuningCache
+}
+
+// apply returns a copy of config that extracts with the candidate's parameters and without
+// auto-tuning.
+func (c OCRCandidate) apply(config *ExtractionConfig) *ExtractionConfig {
+	out := *config
+	out.OCRAutoTune = nil
+	ocr := OCRConfig{Backend: OCRBackendTesseract}
+	if config.OCR != nil {
+		ocr = *config.OCR
+	}
+	tesseract := TesseractConfig{}
+	if ocr.Tesseract != nil {
+		tesseract = *ocr.Tesseract
+	}
+	preprocessing := c.Preprocessing
+	preprocessing.TargetDPI = IntPtr(c.DPI)
+	tesseract.PSM = IntPtr(c.PSM)
+	tesseract.Preprocessing = &preprocessing
+	ocr.Tesseract = &tesseract
+	out.OCR = &ocr
+	return &out
+}
+
+// ocrCandidateExtract extracts a document with a candidate's config. Candidate configs have no
+// OCRAutoTune, so extractPrimaryUncached does not recurse.
+var ocrCandidateExtract func(documentSource, *ExtractionConfig) (*ExtractionResult, error)
+
+func init() {
+	ocrCandidateExtract = extractPrimaryUncached
+}
+
+// ocrAutoTuneApplies reports whether src is extracted with auto-tuned OCR parameters.
+func ocrAutoTuneApplies(src documentSource, config *ExtractionConfig) bool {
+	if config == nil || config.OCRAutoTune == nil {
+		return false
+	}
+	if config.ForceOCR != nil && *config.ForceOCR {
+		return true
+	}
+	return strings.HasPrefix(src.detectMimeType(), "image/")
+}
+
+// TuneOCR scores every candidate of the grid in config.OCRAutoTune (defaults when nil) on the
+// samples, e.g. representative page images, and returns the best candidate. It does not touch
+// the tuning cache; record the choice with OCRTuningCache.Set to reuse it.
+func TuneOCR(ctx context.Context, samples []BytesWithMime, config *ExtractionConfig) (*OCRTuning, error) {
+	if len(samples) == 0 {
+		return nil, newValidationErrorWithContext("at least one sample is required", nil, ErrorCodeValidation, nil)
+	}
+	if config == nil {
+		config = &ExtractionConfig{}
+	}
+	tune := config.OCRAutoTune
+	if tune == nil {
+		tune = &OCRAutoTuneConfig{}
+	}
+	sources := make([]documentSource, len(samples))
+	for i, sample := range samples {
+		sources[i] = documentSource{data: sample.Data, mimeType: sample.MimeType}
+	}
+	tuning, _, err := tuneOCR(ctx, sources, config, tune)
+	return tuning, err
+}
+
+// tuneOCR extracts sources with every candidate and returns the tuning along with the results
+// of the best candidate.
+func tuneOCR(ctx context.Context, sources []documentSource, config *ExtractionConfig, tune *OCRAutoTuneConfig) (*OCRTuning, []*ExtractionResult, error) {
+	tuning := &OCRTuning{}
+	var bestResults []*ExtractionResult
+	var errs []error
+	for _, candidate := range tune.candidates() {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		entry := OCRCandidateScore{Candidate: candidate}
+		candidateConfig := candidate.apply(config)
+		results := make([]*ExtractionResult, 0, len(sources))
+		for _, src := range sources {
+			result, err := ocrCandidateExtract(src, candidateConfig)
+			if err != nil {
+				entry.Err = err
+				errs = append(errs, fmt.Errorf("psm %d, dpi %d: %w", candidate.PSM, candidate.DPI, err))
+				break
+			}
+			entry.Score += tune.score(result) / float64(len(sources))
+			results = append(results, result)
+		}
+		tuning.Candidates = append(tuning.Candidates, entry)
+		if entry.Err == nil && (bestResults == nil || entry.Score > tuning.BestScore) {
+			tuning.Best, tuning.BestScore, bestResults = candidate, entry.Score, results
+		}
+	}
+	if bestResults == nil {
+		return nil, nil, newOCRErrorWithContext("OCR auto-tuning failed for every candidate", errors.Join(errs...), ErrorCodeOcr, nil)
+	}
+	return tuning, bestResults, nil
+}
+
+// ocrAutoTuneInfo is reported in Metadata.Additional["ocr_autotune"].
+type ocrAutoTuneInfo struct {
+	Class     string       `json:"class"`
+	Candidate OCRCandidate `json:"candidate"`
+	// Tuned is true when the document was used to tune the class.
+	Tuned bool     `json:"tuned"`
+	Score *float64 `json:"score,omitempty"`
+}
+
+// extractAutoTuned extracts src with the parameters chosen for its class, tuning the class on
+// src first when it has no choice yet.
+func extractAutoTuned(src documentSource, config *ExtractionConfig) (*ExtractionResult, error) {
+	tune := config.OCRAutoTune
+	mimeType := src.detectMimeType()
+	class := mimeType
+	if tune.Class != nil {
+		class = tune.Class(mimeType, src.path)
+	}
+	cache := tune.cache()
+
+	if class != "" {
+		candidate, ok, err := cache.acquire(context.Background(), class)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result, err := ocrCandidateExtract(src, candidate.apply(config))
+			if err != nil {
+				return nil, err
+			}
+			return result, setOCRAutoTuneInfo(result, ocrAutoTuneInfo{Class: class, Candidate: candidate})
+		}
+		defer cache.release(class)
+	}
+
+	tuning, results, err := tuneOCR(context.Background(), []documentSource{src}, config, tune)
+	if err != nil {
+		return nil, err
+	}
+	if class != "" {
+		cache.Set(class, tuning.Best)
+	}
+	result := results[0]
+	return result, setOCRAutoTuneInfo(result, ocrAutoTuneInfo{Class: class, Candidate: tuning.Best, Tuned: true, Score: &tuning.BestScore})
+}
+
+func setOCRAutoTuneInfo(result *ExtractionResult, info ocrAutoTuneInfo) error {
+	raw, err := json.Marshal(info)
+	if err != nil {
+		return newSerializationErrorWithContext("failed to encode OCR auto-tune metadata", err, ErrorCodeValidation, nil)
+	}
+	if result.Metadata.Additional == nil {
+		result.Metadata.Additional = map[string]json.RawMessage{}
+	}
+	result.Metadata.Additional["ocr_autotune"] = raw
+	return nil
+}
@@ -0,0 +1,127 @@
+package kreuzberg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeOCR replaces candidate extraction with output that is clean only for PSM 6 at 400 DPI.
+func fakeOCR(t *testing.T, fail bool) *atomic.Int32 {
+	t.Helper()
+	var calls atomic.Int32
+	saved := ocrCandidateExtract
+	t.Cleanup(func() { ocrCandidateExtract = saved })
+	ocrCandidateExtract = func(src documentSource, config *ExtractionConfig) (*ExtractionResult, error) {
+		calls.Add(1)
+		if config.OCRAutoTune != nil {
+			t.Errorf("candidate config must not auto-tune")
+		}
+		if fail {
+			return nil, newOCRErrorWithContext("tesseract unavailable", nil, ErrorCodeOcr, nil)
+		}
+		tess := config.OCR.Tesseract
+		content := "Inv0ice t@tal 4Z.5O"
+		if *tess.PSM == 6 && *tess.Preprocessing.TargetDPI == 400 {
+			content = "Invoice total 42.50"
+		}
+		return &ExtractionResult{Content: content, MimeType: src.mimeType}, nil
+	}
+	return &calls
+}
+
+func autoTuneInfo(t *testing.T, result *ExtractionResult) ocrAutoTuneInfo {
+	t.Helper()
+	var info ocrAutoTuneInfo
+	if err := json.Unmarshal(result.Metadata.Additional["ocr_autotune"], &info); err != nil {
+		t.Fatalf("decode ocr_autotune metadata: %v", err)
+	}
+	return info
+}
+
+func TestOCRAutoTuneCachesChoicePerClass(t *testing.T) {
+	calls := fakeOCR(t, false)
+	cache := NewOCRTuningCache()
+	config := &ExtractionConfig{OCRAutoTune: &OCRAutoTuneConfig{Cache: cache}}
+
+	result, err := ExtractBytesSync([]byte("png"), "image/png", config)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if result.Content != "Invoice total 42.50" || calls.Load() != 16 {
+		t.Fatalf("expected the best of 16 candidates, got %q after %d calls", result.Content, calls.Load())
+	}
+	info := autoTuneInfo(t, result)
+	if !info.Tuned || info.Class != "image/png" || info.Candidate.PSM != 6 || info.Candidate.DPI != 400 || info.Score == nil {
+		t.Fatalf("unexpected tuning metadata: %+v", info)
+	}
+	if choice, ok := cache.Choice("image/png"); !ok || choice.PSM != 6 {
+		t.Fatalf("expected the choice to be cached, got %+v", choice)
+	}
+
+	result, err = ExtractBytesSync([]byte("png"), "image/png", config)
+	if err != nil || result.Content != "Invoice total 42.50" || calls.Load() != 17 || autoTuneInfo(t, result).Tuned {
+		t.Fatalf("expected a single extraction with the cached choice, got %v, %v after %d calls", result, err, calls.Load())
+	}
+	results, err := BatchExtractBytesSync([]BytesWithMime{{Data: []byte("a"), MimeType: "image/png"}, {Data: []byte("b"), MimeType: "image/png"}}, config)
+	if err != nil || len(results) != 2 || results[1].Content != "Invoice total 42.50" || calls.Load() != 19 {
+		t.Fatalf("unexpected batch: %v, %v after %d calls", results, err, calls.Load())
+	}
+
+	cache.Forget("image/png")
+	if _, err := ExtractBytesSync([]byte("png"), "image/png", config); err != nil || calls.Load() != 35 {
+		t.Fatalf("expected the class to be tuned again, got %v after %d calls", err, calls.Load())
+	}
+}
+
+func TestOCRAutoTuneScope(t *testing.T) {
+	calls := fakeOCR(t, false)
+	config := &ExtractionConfig{OCRAutoTune: &OCRAutoTuneConfig{Cache: NewOCRTuningCache()}}
+	if result, err := ExtractBytesSync([]byte(testSRT), mimeSRT, config); err != nil || result.Content != wantSRTContent {
+		t.Fatalf("expected the Go extractor to handle subtitles, got %v, %v", result, err)
+	}
+	ExtractBytesSync([]byte("%PDF-1.7"), "application/pdf", config)
+	if calls.Load() != 0 {
+		t.Fatalf("expected no tuning without ForceOCR, got %d calls", calls.Load())
+	}
+	config.ForceOCR = BoolPtr(true)
+	if _, err := ExtractBytesSync([]byte("%PDF-1.7"), "application/pdf", config); err != nil || calls.Load() != 16 {
+		t.Fatalf("expected tuning with ForceOCR, got %v after %d calls", err, calls.Load())
+	}
+}
+
+func TestTuneOCR(t *testing.T) {
+	fakeOCR(t, false)
+	config := &ExtractionConfig{OCRAutoTune: &OCRAutoTuneConfig{PSMs: []int{3, 6}, DPIs: []int{400}}}
+	tuning, err := TuneOCR(context.Background(), []BytesWithMime{{Data: []byte("a"), MimeType: "image/tiff"}, {Data: []byte("b"), MimeType: "image/tiff"}}, config)
+	if err != nil {
+		t.Fatalf("tune: %v", err)
+	}
+	if len(tuning.Candidates) != 4 || tuning.Best.PSM != 6 || tuning.BestScore != OCRTextScore("Invoice total 42.50") {
+		t.Fatalf("unexpected tuning: %+v", tuning)
+	}
+	if _, ok := defaultOCRTuningCache.Choice("image/tiff"); ok {
+		t.Fatalf("TuneOCR must not record a choice")
+	}
+	if _, err := TuneOCR(context.Background(), nil, config); err == nil {
+		t.Fatalf("expected an error without samples")
+	}
+
+	fakeOCR(t, true)
+	_, err = TuneOCR(context.Background(), []BytesWithMime{{Data: []byte("a"), MimeType: "image/tiff"}}, config)
+	var ocrErr *OCRError
+	if !errors.As(err, &ocrErr) {
+		t.Fatalf("expected an OCRError when every candidate fails, got %v", err)
+	}
+}
+
+func TestOCRTextScore(t *testing.T) {
+	if clean, noisy := OCRTextScore("Invoice total: 42.50 EUR"), OCRTextScore("Inv0ice t@tal: 4Z.5O eUR"); clean <= noisy {
+		t.Fatalf("expected clean text to score higher: %v <= %v", clean, noisy)
+	}
+	if OCRTextScore("") != 0 {
+		t.Fatalf("expected zero for empty text")
+	}
+}
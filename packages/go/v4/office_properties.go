@@ -0,0 +1,186 @@
+package kreuzberg
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CustomPropertyType is the type of an Office custom document property.
+type CustomPropertyType string
+
+const (
+	// CustomPropertyString is a text property; unknown variant types are also kept as text.
+	CustomPropertyString CustomPropertyType = "string"
+	// CustomPropertyNumber is an integer, real or currency property, decoded as float64.
+	CustomPropertyNumber CustomPropertyType = "number"
+	// CustomPropertyBool is a yes/no property.
+	CustomPropertyBool CustomPropertyType = "bool"
+	// CustomPropertyDate is a date property, decoded as time.Time.
+	CustomPropertyDate CustomPropertyType = "date"
+)
+
+// CustomProperty is a custom document property of a DOCX, XLSX or PPTX file (File > Properties >
+// Custom). Value is a string, a float64, a bool or a time.Time according to Type.
+type CustomProperty struct {
+	Type  CustomPropertyType `json:"type"`
+	Value any                `json:"value"`
+}
+
+// UnmarshalJSON restores Value to the Go type matching Type.
+func (p *CustomProperty) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Type  CustomPropertyType `json:"type"`
+		Value json.RawMessage    `json:"value"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	p.Type = raw.Type
+	switch raw.Type {
+	case CustomPropertyNumber:
+		var f float64
+		if err := json.Unmarshal(raw.Value, &f); err != nil {
+			return err
+		}
+		p.Value = f
+	case CustomPropertyBool:
+		var b bool
+		if err := json.Unmarshal(raw.Value, &b); err != nil {
+			return err
+		}
+		p.Value = b
+	case CustomPropertyDate:
+		var t time.Time
+		if err := json.Unmarshal(raw.Value, &t); err != nil {
+			return err
+		}
+		p.Value = t
+	default:
+		var s string
+		if err := json.Unmarshal(raw.Value, &s); err != nil {
+			return err
+		}
+		p.Value = s
+	}
+	return nil
+}
+
+// CustomProperties returns the custom document properties recorded when
+// ExtractionConfig.OfficeCustomProperties was set, keyed by property name.
+func (m Metadata) CustomProperties() (map[string]CustomProperty, bool) {
+	var props map[string]CustomProperty
+	if found, err := m.Decode("custom_properties", &props); !found || err != nil {
+		return nil, false
+	}
+	return props, true
+}
+
+// ooxmlCustomProperties is docProps/custom.xml. Each property holds one vt: value element.
+type ooxmlCustomProperties struct {
+	Properties []struct {
+		Name  string `xml:"name,attr"`
+		Value struct {
+			XMLName xml.Name
+			Text    string `xml:",chardata"`
+		} `xml:",any"`
+	} `xml:"property"`
+}
+
+// isOOXMLPackage reports whether data starts like a zip archive, as Office Open XML packages do.
+func isOOXMLPackage(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("PK\x03\x04"))
+}
+
+// openOOXML opens an Office Open XML package.
+func openOOXML(data []byte) (*zip.Reader, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, newParsingErrorWithContext("document is not an Office Open XML archive", err, ErrorCodeParsing, nil)
+	}
+	return reader, nil
+}
+
+// decodeOOXMLPart decodes the named part into target and reports whether the part exists.
+func decodeOOXMLPart(reader *zip.Reader, name string, target any) (bool, error) {
+	part, err := reader.Open(name)
+	if err != nil {
+		return false, nil
+	}
+	defer part.Close()
+	if err := xml.NewDecoder(part).Decode(target); err != nil {
+		return true, newParsingErrorWithContext(fmt.Sprintf("failed to parse %s", name), err, ErrorCodeParsing, nil)
+	}
+	return true, nil
+}
+
+// ParseCustomProperties reads the custom document properties of an Office Open XML document.
+// It returns nil without error when the document has none.
+func ParseCustomProperties(data []byte) (map[string]CustomProperty, error) {
+	reader, err := openOOXML(data)
+	if err != nil {
+		return nil, err
+	}
+	var doc ooxmlCustomProperties
+	if found, err := decodeOOXMLPart(reader, "docProps/custom.xml", &doc); !found || err != nil {
+		return nil, err
+	}
+	props := make(map[string]CustomProperty, len(doc.Properties))
+	for _, p := range doc.Properties {
+		if p.Name != "" {
+			props[p.Name] = customPropertyValue(p.Value.XMLName.Local, strings.TrimSpace(p.Value.Text))
+		}
+	}
+	return props, nil
+}
+
+// customPropertyValue converts the text of a vt: variant element. Values that do not parse as
+// their declared type are kept as strings.
+func customPropertyValue(variant, text string) CustomProperty {
+	switch variant {
+	case "i1", "i2", "i4", "i8", "int", "ui1", "ui2", "ui4", "ui8", "uint", "r4", "r8", "decimal", "cy":
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			return CustomProperty{Type: CustomPropertyNumber, Value: f}
+		}
+	case "bool":
+		if b, err := strconv.ParseBool(text); err == nil {
+			return CustomProperty{Type: CustomPropertyBool, Value: b}
+		}
+	case "filetime", "date":
+		if t, err := time.Parse(time.RFC3339, text); err == nil {
+			return CustomProperty{Type: CustomPropertyDate, Value: t}
+		}
+	}
+	return CustomProperty{Type: CustomPropertyString, Value: text}
+}
+
+// annotateCustomProperties stores the custom document properties of data in
+// result.Metadata.Additional["custom_properties"]. Documents that are not Office Open XML are
+// skipped, and a malformed properties part is reported as a warning diagnostic.
+func annotateCustomProperties(result *ExtractionResult, data []byte) error {
+	if !isOOXMLPackage(data) {
+		return nil
+	}
+	props, err := ParseCustomProperties(data)
+	if err != nil {
+		result.addDiagnostic("custom_properties", DiagnosticSeverityWarning, fmt.Sprintf("custom document properties skipped: %v", err))
+		return nil
+	}
+	if len(props) == 0 {
+		return nil
+	}
+	raw, err := json.Marshal(props)
+	if err != nil {
+		return newSerializationErrorWithContext("failed to encode custom document properties", err, ErrorCodeValidation, nil)
+	}
+	if result.Metadata.Additional == nil {
+		result.Metadata.Additional = map[string]json.RawMessage{}
+	}
+	result.Metadata.Additional["custom_properties"] = raw
+	return nil
+}
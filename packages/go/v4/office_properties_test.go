@@ -0,0 +1,103 @@
+package kreuzberg
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+const testCustomPropertiesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Properties xmlns="http://schemas.openxmlformats.org/officeDocument/2006/custom-properties" xmlns:vt="http://schemas.openxmlformats.org/officeDocument/2006/docPropsVTypes">
+<property fmtid="{D5CDD505-2E9C-101B-9397-08002B2CF9AE}" pid="2" name="RoutingCode"><vt:lpwstr>AP-7731</vt:lpwstr></property>
+<property fmtid="{D5CDD505-2E9C-101B-9397-08002B2CF9AE}" pid="3" name="Priority"><vt:i4>2</vt:i4></property>
+<property fmtid="{D5CDD505-2E9C-101B-9397-08002B2CF9AE}" pid="4" name="Amount"><vt:r8>1250.5</vt:r8></property>
+<property fmtid="{D5CDD505-2E9C-101B-9397-08002B2CF9AE}" pid="5" name="Approved"><vt:bool>true</vt:bool></property>
+<property fmtid="{D5CDD505-2E9C-101B-9397-08002B2CF9AE}" pid="6" name="Due"><vt:filetime>2024-06-30T00:00:00Z</vt:filetime></property>
+<property fmtid="{D5CDD505-2E9C-101B-9397-08002B2CF9AE}" pid="7" name="Broken"><vt:i4>n/a</vt:i4></property>
+</Properties>`
+
+// withZipPart returns a copy of the zip archive data with an extra part.
+func withZipPart(t *testing.T, data []byte, name, body string) []byte {
+	t.Helper()
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range reader.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		w, _ := zw.Create(f.Name)
+		io.Copy(w, rc)
+		rc.Close()
+	}
+	w, _ := zw.Create(name)
+	w.Write([]byte(body))
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestParseCustomPropertiesTypes(t *testing.T) {
+	data := withZipPart(t, buildXLSX(t, nil, map[string]string{"Data": ""}, []string{"Data"}), "docProps/custom.xml", testCustomPropertiesXML)
+	props, err := ParseCustomProperties(data)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := map[string]CustomProperty{
+		"RoutingCode": {Type: CustomPropertyString, Value: "AP-7731"},
+		"Priority":    {Type: CustomPropertyNumber, Value: 2.0},
+		"Amount":      {Type: CustomPropertyNumber, Value: 1250.5},
+		"Approved":    {Type: CustomPropertyBool, Value: true},
+		"Due":         {Type: CustomPropertyDate, Value: time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)},
+		"Broken":      {Type: CustomPropertyString, Value: "n/a"},
+	}
+	if len(props) != len(want) {
+		t.Fatalf("unexpected properties: %+v", props)
+	}
+	for name, w := range want {
+		if got := props[name]; got.Type != w.Type || got.Value != w.Value {
+			t.Errorf("%s: got %+v, want %+v", name, got, w)
+		}
+	}
+
+	if props, err := ParseCustomProperties(buildXLSX(t, nil, map[string]string{"Data": ""}, []string{"Data"})); err != nil || props != nil {
+		t.Fatalf("expected no properties for a document without custom.xml, got %v, %v", props, err)
+	}
+}
+
+func TestCustomPropertiesDuringExtraction(t *testing.T) {
+	data := withZipPart(t, buildXLSX(t, []string{"h"}, map[string]string{"Data": `<row r="1"><c t="s"><v>0</v></c></row>`}, []string{"Data"}),
+		"docProps/custom.xml", testCustomPropertiesXML)
+	enabled := true
+	config := &ExtractionConfig{Spreadsheet: &SpreadsheetConfig{MaxRows: 10}, OfficeCustomProperties: &enabled}
+	result, err := ExtractBytesSync(data, mimeXLSX, config)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	props, ok := result.Metadata.CustomProperties()
+	if !ok {
+		t.Fatalf("missing custom properties: %v", result.Metadata.Additional)
+	}
+	if due := props["Due"]; due.Value != time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC) {
+		t.Fatalf("expected the date to survive a JSON round trip, got %+v", due)
+	}
+	if props["Priority"].Value != 2.0 || props["Approved"].Value != true || props["RoutingCode"].Value != "AP-7731" {
+		t.Fatalf("unexpected properties: %+v", props)
+	}
+
+	data = withZipPart(t, buildXLSX(t, nil, map[string]string{"Data": ""}, []string{"Data"}), "docProps/custom.xml", "<Properties><property")
+	result, err = ExtractBytesSync(data, mimeXLSX, config)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if _, ok := result.Metadata.CustomProperties(); ok || len(result.DiagnosticsBySeverity(DiagnosticSeverityWarning)) != 1 {
+		t.Fatalf("expected a warning for malformed properties, got %+v", result.Diagnostics)
+	}
+}
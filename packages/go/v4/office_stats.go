@@ -0,0 +1,150 @@
+package kreuzberg
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// OfficeStats holds the application statistics and revision details that Office stores in
+// docProps/app.xml and docProps/core.xml. Counts are as last saved by the editing application
+// and are absent when it did not record them.
+type OfficeStats struct {
+	// Application is the name of the application that last saved the document.
+	Application string `json:"application,omitempty"`
+	// AppVersion is its version ("16.0000").
+	AppVersion string `json:"app_version,omitempty"`
+	// Template is the template the document was based on ("Normal.dotm").
+	Template string `json:"template,omitempty"`
+	// Company and Manager are the organization properties.
+	Company string `json:"company,omitempty"`
+	Manager string `json:"manager,omitempty"`
+	// LastModifiedBy is the user who last saved the document.
+	LastModifiedBy string `json:"last_modified_by,omitempty"`
+	// Revision is the number of times the document was saved.
+	Revision *int `json:"revision,omitempty"`
+	// TotalEditingMinutes is the total time the document was open for editing.
+	TotalEditingMinutes *int `json:"total_editing_minutes,omitempty"`
+
+	// Document counts; Slides, Notes and HiddenSlides are recorded by presentations.
+	Pages                *int `json:"pages,omitempty"`
+	Words                *int `json:"words,omitempty"`
+	Characters           *int `json:"characters,omitempty"`
+	CharactersWithSpaces *int `json:"characters_with_spaces,omitempty"`
+	Lines                *int `json:"lines,omitempty"`
+	Paragraphs           *int `json:"paragraphs,omitempty"`
+	Slides               *int `json:"slides,omitempty"`
+	Notes                *int `json:"notes,omitempty"`
+	HiddenSlides         *int `json:"hidden_slides,omitempty"`
+}
+
+// OfficeStats returns the statistics recorded when ExtractionConfig.OfficeStats was set.
+func (m Metadata) OfficeStats() (*OfficeStats, bool) {
+	var stats OfficeStats
+	if found, err := m.Decode("office_stats", &stats); !found || err != nil {
+		return nil, false
+	}
+	return &stats, true
+}
+
+// ooxmlAppProperties is docProps/app.xml (extended properties).
+type ooxmlAppProperties struct {
+	Application          string `xml:"Application"`
+	AppVersion           string `xml:"AppVersion"`
+	Template             string `xml:"Template"`
+	Company              string `xml:"Company"`
+	Manager              string `xml:"Manager"`
+	TotalTime            string `xml:"TotalTime"`
+	Pages                string `xml:"Pages"`
+	Words                string `xml:"Words"`
+	Characters           string `xml:"Characters"`
+	CharactersWithSpaces string `xml:"CharactersWithSpaces"`
+	Lines                string `xml:"Lines"`
+	Paragraphs           string `xml:"Paragraphs"`
+	Slides               string `xml:"Slides"`
+	Notes                string `xml:"Notes"`
+	HiddenSlides         string `xml:"HiddenSlides"`
+}
+
+// ooxmlCoreProperties holds the revision fields of docProps/core.xml.
+type ooxmlCoreProperties struct {
+	LastModifiedBy string `xml:"lastModifiedBy"`
+	Revision       string `xml:"revision"`
+}
+
+// ParseOfficeStats reads the application statistics and revision details of an Office Open XML
+// document. It returns nil without error when the document has neither properties part.
+func ParseOfficeStats(data []byte) (*OfficeStats, error) {
+	reader, err := openOOXML(data)
+	if err != nil {
+		return nil, err
+	}
+	var app ooxmlAppProperties
+	foundApp, err := decodeOOXMLPart(reader, "docProps/app.xml", &app)
+	if err != nil {
+		return nil, err
+	}
+	var core ooxmlCoreProperties
+	foundCore, err := decodeOOXMLPart(reader, "docProps/core.xml", &core)
+	if err != nil {
+		return nil, err
+	}
+	if !foundApp && !foundCore {
+		return nil, nil
+	}
+	return &OfficeStats{
+		Application:          strings.TrimSpace(app.Application),
+		AppVersion:           strings.TrimSpace(app.AppVersion),
+		Template:             strings.TrimSpace(app.Template),
+		Company:              strings.TrimSpace(app.Company),
+		Manager:              strings.TrimSpace(app.Manager),
+		LastModifiedBy:       strings.TrimSpace(core.LastModifiedBy),
+		Revision:             parseOfficeCount(core.Revision),
+		TotalEditingMinutes:  parseOfficeCount(app.TotalTime),
+		Pages:                parseOfficeCount(app.Pages),
+		Words:                parseOfficeCount(app.Words),
+		Characters:           parseOfficeCount(app.Characters),
+		CharactersWithSpaces: parseOfficeCount(app.CharactersWithSpaces),
+		Lines:                parseOfficeCount(app.Lines),
+		Paragraphs:           parseOfficeCount(app.Paragraphs),
+		Slides:               parseOfficeCount(app.Slides),
+		Notes:                parseOfficeCount(app.Notes),
+		HiddenSlides:         parseOfficeCount(app.HiddenSlides),
+	}, nil
+}
+
+// parseOfficeCount parses a non-negative count, returning nil for absent or invalid values.
+func parseOfficeCount(text string) *int {
+	n, err := strconv.Atoi(strings.TrimSpace(text))
+	if err != nil || n < 0 {
+		return nil
+	}
+	return &n
+}
+
+// annotateOfficeStats stores the statistics of data in result.Metadata.Additional["office_stats"].
+// Documents that are not Office Open XML are skipped, and malformed properties parts are
+// reported as a warning diagnostic.
+func annotateOfficeStats(result *ExtractionResult, data []byte) error {
+	if !isOOXMLPackage(data) {
+		return nil
+	}
+	stats, err := ParseOfficeStats(data)
+	if err != nil {
+		result.addDiagnostic("office_stats", DiagnosticSeverityWarning, fmt.Sprintf("office statistics skipped: %v", err))
+		return nil
+	}
+	if stats == nil {
+		return nil
+	}
+	raw, err := json.Marshal(stats)
+	if err != nil {
+		return newSerializationErrorWithContext("failed to encode office statistics", err, ErrorCodeValidation, nil)
+	}
+	if result.Metadata.Additional == nil {
+		result.Metadata.Additional = map[string]json.RawMessage{}
+	}
+	result.Metadata.Additional["office_stats"] = raw
+	return nil
+}
@@ -0,0 +1,51 @@
+package kreuzberg
+
+import "testing"
+
+const testAppXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Properties xmlns="http://schemas.openxmlformats.org/officeDocument/2006/extended-properties" xmlns:vt="http://schemas.openxmlformats.org/officeDocument/2006/docPropsVTypes">
+<Template>Quarterly.dotm</Template><TotalTime>95</TotalTime><Pages>3</Pages><Words>812</Words><Characters>4630</Characters>
+<Application>Microsoft Office Word</Application><Lines>38</Lines><Paragraphs>10</Paragraphs><Company>Acme Ltd</Company>
+<CharactersWithSpaces>5432</CharactersWithSpaces><AppVersion>16.0000</AppVersion>
+</Properties>`
+
+const testCoreXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<dc:creator>Ada Lovelace</dc:creator><cp:lastModifiedBy>Charles Babbage</cp:lastModifiedBy><cp:revision>14</cp:revision>
+</cp:coreProperties>`
+
+func TestParseOfficeStats(t *testing.T) {
+	data := buildXLSX(t, nil, map[string]string{"Data": ""}, []string{"Data"})
+	data = withZipPart(t, withZipPart(t, data, "docProps/app.xml", testAppXML), "docProps/core.xml", testCoreXML)
+	stats, err := ParseOfficeStats(data)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if stats.Application != "Microsoft Office Word" || stats.Template != "Quarterly.dotm" || stats.Company != "Acme Ltd" || stats.LastModifiedBy != "Charles Babbage" {
+		t.Fatalf("unexpected text fields: %+v", stats)
+	}
+	if *stats.Revision != 14 || *stats.TotalEditingMinutes != 95 || *stats.Words != 812 || *stats.CharactersWithSpaces != 5432 {
+		t.Fatalf("unexpected counts: %+v", stats)
+	}
+	if stats.Slides != nil || stats.Manager != "" {
+		t.Fatalf("expected absent fields to stay empty: %+v", stats)
+	}
+
+	if stats, err := ParseOfficeStats(buildXLSX(t, nil, map[string]string{"Data": ""}, []string{"Data"})); err != nil || stats != nil {
+		t.Fatalf("expected no statistics without properties parts, got %+v, %v", stats, err)
+	}
+}
+
+func TestOfficeStatsDuringExtraction(t *testing.T) {
+	data := buildXLSX(t, []string{"h"}, map[string]string{"Data": `<row r="1"><c t="s"><v>0</v></c></row>`}, []string{"Data"})
+	data = withZipPart(t, data, "docProps/core.xml", testCoreXML)
+	enabled := true
+	result, err := ExtractBytesSync(data, mimeXLSX, &ExtractionConfig{Spreadsheet: &SpreadsheetConfig{MaxRows: 10}, OfficeStats: &enabled})
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	stats, ok := result.Metadata.OfficeStats()
+	if !ok || stats.LastModifiedBy != "Charles Babbage" || stats.Revision == nil || *stats.Revision != 14 || stats.Words != nil {
+		t.Fatalf("unexpected statistics: %+v (%v)", stats, result.Metadata.Additional)
+	}
+}
@@ -0,0 +1,90 @@
+package kreuzberg
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strings"
+)
+
+// PasswordRequest identifies the document a PasswordProvider is asked to unlock.
+type PasswordRequest struct {
+	// DocumentID is the path of the document, or empty for in-memory extractions.
+	DocumentID string
+	// SHA256 is the hex SHA-256 digest of the document, for looking up in-memory documents.
+	SHA256 string
+	// MimeType is the MIME type of the document, when known.
+	MimeType string
+	// Attempt is 1 on the first request for a document and counts up each time the passwords
+	// returned before were all rejected.
+	Attempt int
+}
+
+// PasswordProvider returns the passwords to try for an encrypted document, in order. It is
+// called only when the native library reports that a document needs a password, so credentials
+// can be fetched lazily, e.g. from a vault, instead of being stored in the config. Returning no
+// passwords gives up and the extraction fails with the original error; returning an error
+// fails it with a PluginError. The passwords are used for the one extraction and are never
+// logged, recorded in diagnostics or replay bundles, or kept by the binding.
+//
+// Providers are called concurrently by concurrent extractions.
+type PasswordProvider func(ctx context.Context, req PasswordRequest) ([]string, error)
+
+// maxPasswordAttempts bounds how often a provider is asked for the same document.
+const maxPasswordAttempts = 3
+
+// passwordProviderName names the provider in the PluginErrors it causes.
+const passwordProviderName = "password_provider"
+
+// isPasswordError reports whether err is the native library rejecting an encrypted document for
+// a missing or wrong password.
+func isPasswordError(err error) bool {
+	return err != nil && strings.Contains(strings.ToLower(err.Error()), "password")
+}
+
+// unlockDocument retries an extraction that failed with err with the passwords from
+// config.PasswordProvider. It returns err unchanged when err is not a password error or no
+// provider is configured.
+func unlockDocument(ctx context.Context, src documentSource, config *ExtractionConfig, err error) (*ExtractionResult, error) {
+	if config == nil || config.PasswordProvider == nil || !isPasswordError(err) {
+		return nil, err
+	}
+	req := PasswordRequest{DocumentID: src.path, MimeType: src.detectMimeType()}
+	if data, readErr := src.bytes(); readErr == nil {
+		sum := sha256.Sum256(data)
+		req.SHA256 = hex.EncodeToString(sum[:])
+	}
+	for attempt := 1; attempt <= maxPasswordAttempts; attempt++ {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		req.Attempt = attempt
+		passwords, providerErr := config.PasswordProvider(ctx, req)
+		if providerErr != nil {
+			return nil, newPluginErrorWithContext(passwordProviderName, "password provider failed", providerErr, ErrorCodePlugin, nil)
+		}
+		if len(passwords) == 0 {
+			return nil, err
+		}
+		result, extractErr := extractPrimary(src, withPasswords(config, passwords))
+		if !isPasswordError(extractErr) {
+			return result, extractErr
+		}
+		err = extractErr
+	}
+	return nil, err
+}
+
+// withPasswords returns a copy of config that tries passwords after the configured ones.
+func withPasswords(config *ExtractionConfig, passwords []string) *ExtractionConfig {
+	unlocked := *config
+	unlocked.PasswordProvider = nil
+	pdf := PdfConfig{}
+	if config.PdfOptions != nil {
+		pdf = *config.PdfOptions
+	}
+	pdf.Passwords = append(slices.Clip(pdf.Passwords), passwords...)
+	unlocked.PdfOptions = &pdf
+	return &unlocked
+}
@@ -0,0 +1,62 @@
+package kreuzberg
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestUnlockDocument(t *testing.T) {
+	locked := newParsingErrorWithContext("PDF is password-protected", nil, ErrorCodeParsing, nil)
+	src := documentSource{data: []byte(testSRT), mimeType: mimeSRT}
+	var requests []PasswordRequest
+	config := &ExtractionConfig{
+		PdfOptions: &PdfConfig{Passwords: []string{"static"}},
+		PasswordProvider: func(_ context.Context, req PasswordRequest) ([]string, error) {
+			requests = append(requests, req)
+			return []string{"fetched"}, nil
+		},
+	}
+
+	result, err := unlockDocument(t.Context(), src, config, locked)
+	if err != nil || result.Content != wantSRTContent {
+		t.Fatalf("unexpected result %+v, %v", result, err)
+	}
+	if len(requests) != 1 || requests[0].Attempt != 1 || requests[0].MimeType != mimeSRT || len(requests[0].SHA256) != 64 {
+		t.Fatalf("unexpected requests %+v", requests)
+	}
+	if !slices.Equal(config.PdfOptions.Passwords, []string{"static"}) {
+		t.Fatalf("expected the config to be left alone, got %v", config.PdfOptions.Passwords)
+	}
+
+	other := errors.New("unsupported format")
+	if _, err := unlockDocument(t.Context(), src, config, other); err != other || len(requests) != 1 {
+		t.Fatalf("expected other errors to skip the provider, got %v", err)
+	}
+
+	config.PasswordProvider = func(context.Context, PasswordRequest) ([]string, error) { return nil, nil }
+	if _, err := unlockDocument(t.Context(), src, config, locked); err != locked {
+		t.Fatalf("expected the original error when the provider gives up, got %v", err)
+	}
+
+	config.PasswordProvider = func(context.Context, PasswordRequest) ([]string, error) { return nil, errors.New("vault sealed") }
+	_, err = unlockDocument(t.Context(), src, config, locked)
+	var pluginErr *PluginError
+	if !errors.As(err, &pluginErr) || pluginErr.PluginName != passwordProviderName {
+		t.Fatalf("expected a plugin error, got %v", err)
+	}
+}
+
+func TestWithPasswords(t *testing.T) {
+	unlocked := withPasswords(&ExtractionConfig{PasswordProvider: func(context.Context, PasswordRequest) ([]string, error) { return nil, nil }}, []string{"a", "b"})
+	if unlocked.PasswordProvider != nil || !slices.Equal(unlocked.PdfOptions.Passwords, []string{"a", "b"}) {
+		t.Fatalf("unexpected config %+v", unlocked)
+	}
+
+	base := &ExtractionConfig{PdfOptions: &PdfConfig{Passwords: make([]string, 1, 4)}}
+	withPasswords(base, []string{"c"})
+	if len(base.PdfOptions.Passwords[:2][1]) != 0 {
+		t.Fatalf("expected the configured passwords to be left alone")
+	}
+}
@@ -0,0 +1,979 @@
+package kreuzberg
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// HiddenTextReason names why text in a PDF is invisible to a reader.
+type HiddenTextReason string
+
+const (
+	// HiddenTextRenderMode is text drawn with an invisible or clip-only rendering mode (Tr 3 or 7).
+	HiddenTextRenderMode HiddenTextReason = "render_mode"
+	// HiddenTextTransparent is text filled with zero opacity.
+	HiddenTextTransparent HiddenTextReason = "transparent"
+	// HiddenTextOffPage is text whose origin lies outside the page's media box.
+	HiddenTextOffPage HiddenTextReason = "off_page"
+	// HiddenTextWhite is text filled in white, which disappears on the usual white page.
+	HiddenTextWhite HiddenTextReason = "white"
+	// HiddenTextTinyFont is text smaller than HiddenTextConfig.MinFontSize.
+	HiddenTextTinyFont HiddenTextReason = "tiny_font"
+)
+
+// HiddenTextConfig enables the detection of text PDFs render invisibly: with an invisible
+// rendering mode, zero opacity, outside the page, in white or in a tiny font. The native library
+// extracts such text along with the visible text; the detection reports it separately, for
+// security review, and can exclude it from the content, e.g. for search indexing.
+//
+// The page content streams are interpreted on the Go side. White text is reported regardless
+// of what it is drawn on, and text in fonts without a ToUnicode map that use two-byte codes
+// cannot be decoded and is skipped.
+type HiddenTextConfig struct {
+	// Exclude removes the hidden text from the content, the pages and the chunks. Text is only
+	// removed from its own page of the content, which for documents of several pages requires
+	// Metadata.PageStructure boundaries. By default it is kept and only reported.
+	Exclude bool
+	// MinFontSize is the effective font size, in points, below which text counts as hidden
+	// (default 2).
+	MinFontSize float64
+}
+
+// HiddenTextSpan is a run of hidden text on one page.
+type HiddenTextSpan struct {
+	// Page is the 1-based page number.
+	Page   int              `json:"page"`
+	Reason HiddenTextReason `json:"reason"`
+	Text   string           `json:"text"`
+	// Excluded reports whether the text was found in, and removed from, the content.
+	Excluded bool `json:"excluded"`
+}
+
+// HiddenText returns the hidden text spans recorded when ExtractionConfig.HiddenText was set
+// and the PDF has any.
+func (m Metadata) HiddenText() ([]HiddenTextSpan, bool) {
+	var spans []HiddenTextSpan
+	if found, err := m.Decode("hidden_text", &spans); !found || err != nil {
+		return nil, false
+	}
+	return spans, true
+}
+
+const defaultHiddenTextMinFontSize = 2
+
+// annotateHiddenText records the hidden text of the PDF data in result and, when configured,
+// removes it from the content.
+func annotateHiddenText(result *ExtractionResult, data []byte, cfg *HiddenTextConfig) error {
+	if cfg.MinFontSize < 0 {
+		return newValidationErrorWithContext(fmt.Sprintf("hidden text minimum font size must not be negative, got %g", cfg.MinFontSize), nil, ErrorCodeValidation, nil)
+	}
+	if result.MimeType != "application/pdf" || !bytes.HasPrefix(bytes.TrimLeft(data[:min(len(data), 1024)], "\x00\r\n\t "), []byte("%PDF-")) {
+		return nil
+	}
+	minFontSize := cfg.MinFontSize
+	if minFontSize == 0 {
+		minFontSize = defaultHiddenTextMinFontSize
+	}
+	spans, skipped, err := findHiddenText(data, minFontSize)
+	if err != nil {
+		result.addDiagnostic("hidden_text", DiagnosticSeverityWarning, err.Error()+", so hidden text was not detected")
+		return nil
+	}
+	if skipped > 0 {
+		result.addDiagnostic("hidden_text", DiagnosticSeverityWarning, fmt.Sprintf("%d streams exceeded the decompression limit and were not scanned for hidden text", skipped))
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+	excluded := 0
+	if cfg.Exclude {
+		for i := range spans {
+			if spans[i].Excluded = excludeHiddenText(result, spans[i]); spans[i].Excluded {
+				excluded++
+			}
+		}
+	}
+	raw, err := json.Marshal(spans)
+	if err != nil {
+		return newSerializationErrorWithContext("failed to encode hidden text", err, ErrorCodeValidation, nil)
+	}
+	if result.Metadata.Additional == nil {
+		result.Metadata.Additional = map[string]json.RawMessage{}
+	}
+	result.Metadata.Additional["hidden_text"] = raw
+	result.addDiagnostic("hidden_text", DiagnosticSeverityInfo, fmt.Sprintf("%d hidden text spans, %d excluded from the content", len(spans), excluded))
+	return nil
+}
+
+// excludeHiddenText removes span from its page of the content, from that page and from the
+// chunk covering it, reporting whether the content had it. The page is located through
+// Metadata.PageStructure, which a document of several pages needs, and the page boundaries and
+// chunk offsets after the removed text are shifted to stay aligned with the content.
+func excludeHiddenText(result *ExtractionResult, span HiddenTextSpan) bool {
+	pageStart, pageEnd, ok := pageByteRange(result, span.Page)
+	if !ok {
+		return false
+	}
+	start, end, ok := findCollapsed(result.Content[pageStart:pageEnd], span.Text)
+	if !ok {
+		return false
+	}
+	start, end = start+pageStart, end+pageStart
+	removed := result.Content[start:end]
+	result.Content = result.Content[:start] + result.Content[end:]
+	shift := func(offset uint64) uint64 {
+		switch {
+		case offset >= uint64(end):
+			return offset - uint64(end-start)
+		case offset > uint64(start):
+			return uint64(start)
+		}
+		return offset
+	}
+	if ps := result.Metadata.PageStructure; ps != nil {
+		for i := range ps.Boundaries {
+			ps.Boundaries[i].ByteStart, ps.Boundaries[i].ByteEnd = shift(ps.Boundaries[i].ByteStart), shift(ps.Boundaries[i].ByteEnd)
+		}
+	}
+	for i := range result.Pages {
+		if result.Pages[i].PageNumber == uint64(span.Page) {
+			if from, to, ok := findCollapsed(result.Pages[i].Content, span.Text); ok {
+				result.Pages[i].Content = result.Pages[i].Content[:from] + result.Pages[i].Content[to:]
+			}
+		}
+	}
+	covered := false
+	for i := range result.Chunks {
+		chunk := &result.Chunks[i]
+		if !covered && chunk.Metadata.ByteStart <= uint64(start) && uint64(end) <= chunk.Metadata.ByteEnd {
+			covered = true
+			from := int(uint64(start) - chunk.Metadata.ByteStart)
+			if from+len(removed) <= len(chunk.Content) && chunk.Content[from:from+len(removed)] == removed {
+				chunk.Content = chunk.Content[:from] + chunk.Content[from+len(removed):]
+			} else if from, to, ok := findCollapsed(chunk.Content, span.Text); ok {
+				chunk.Content = chunk.Content[:from] + chunk.Content[to:]
+			}
+		}
+		chunk.Metadata.ByteStart, chunk.Metadata.ByteEnd = shift(chunk.Metadata.ByteStart), shift(chunk.Metadata.ByteEnd)
+	}
+	return true
+}
+
+// pageByteRange returns the byte range of page in result.Content: from the page boundaries, or
+// the whole content for a document of one page.
+func pageByteRange(result *ExtractionResult, page int) (int, int, bool) {
+	ps := result.Metadata.PageStructure
+	if ps != nil {
+		for _, b := range ps.Boundaries {
+			if b.PageNumber == uint64(page) && b.ByteStart <= b.ByteEnd && b.ByteEnd <= uint64(len(result.Content)) {
+				return int(b.ByteStart), int(b.ByteEnd), true
+			}
+		}
+	}
+	if page == 1 && len(result.Pages) <= 1 && (ps == nil || ps.TotalCount <= 1) {
+		return 0, len(result.Content), true
+	}
+	return 0, 0, false
+}
+
+// findCollapsed returns the byte range of the first occurrence of phrase in s, allowing any
+// whitespace between its words, that neither starts nor ends inside a word of s.
+func findCollapsed(s, phrase string) (int, int, bool) {
+	words := strings.Fields(phrase)
+	if len(words) == 0 {
+		return 0, 0, false
+	}
+	for i, word := range words {
+		words[i] = regexp.QuoteMeta(word)
+	}
+	isWord := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }
+	for _, loc := range regexp.MustCompile(strings.Join(words, `\s+`)).FindAllStringIndex(s, -1) {
+		before, _ := utf8.DecodeLastRuneInString(s[:loc[0]])
+		first, _ := utf8.DecodeRuneInString(s[loc[0]:])
+		last, _ := utf8.DecodeLastRuneInString(s[:loc[1]])
+		after, _ := utf8.DecodeRuneInString(s[loc[1]:])
+		if isWord(before) && isWord(first) || isWord(last) && isWord(after) {
+			continue
+		}
+		return loc[0], loc[1], true
+	}
+	return 0, 0, false
+}
+
+// findHiddenText interprets the content streams of every page of the PDF data and returns the
+// hidden text in page order, with the number of streams skipped for the decompression limits.
+// It returns an error when the PDF cannot be scanned: it is encrypted, its page tree cannot be
+// found, or it is malformed beyond what the parser tolerates.
+func findHiddenText(data []byte, minFontSize float64) (spans []HiddenTextSpan, skipped int, err error) {
+	defer func() {
+		// The parser reads untrusted input; a case it does not handle must not take the process
+		// down with it.
+		if r := recover(); r != nil {
+			spans, skipped, err = nil, 0, fmt.Errorf("the PDF could not be parsed (%v)", r)
+		}
+	}()
+	if lastMatch(pdfEncryptPattern, data) != nil {
+		return nil, 0, errors.New("the PDF is encrypted")
+	}
+	doc := loadPDFObjects(data)
+	catalog := doc.catalog(data)
+	if catalog == nil || catalog["Pages"] == nil {
+		return nil, 0, errors.New("the page tree could not be read")
+	}
+	s := &hiddenTextScanner{doc: doc, minFontSize: minFontSize, fonts: map[pdfRef]*pdfFont{}}
+	s.walkPages(catalog["Pages"], nil, pdfLetterBox, map[pdfRef]bool{}, 0)
+	return s.spans, doc.skipped, nil
+}
+
+// Limits on the work findHiddenText does for one PDF, so a crafted file cannot exhaust memory
+// or the stack: the decompressed size of a stream and of all streams, and the nesting depth of
+// arrays and dictionaries.
+var (
+	pdfMaxStreamBytes  = 64 << 20
+	pdfMaxDecodedBytes = 256 << 20
+	pdfMaxNesting      = 64
+)
+
+// pdfLetterBox is the media box assumed for pages that declare none.
+var pdfLetterBox = [4]float64{0, 0, 612, 792}
+
+type (
+	pdfName    string
+	pdfRef     int
+	pdfKeyword string
+)
+
+type pdfStream struct {
+	dict map[string]any
+	data []byte
+	// decoded caches the result of pdfDocument.decode, so forms drawn many times are
+	// decompressed once.
+	decoded     []byte
+	decodedDone bool
+}
+
+// pdfParser reads PDF objects: numbers as float64, strings as []byte, arrays as []any,
+// dictionaries as map[string]any, plus names, references and keywords, which include content
+// stream operators.
+type pdfParser struct {
+	data []byte
+	pos  int
+	// refs enables references, which content streams do not have.
+	refs bool
+	// depth is the number of arrays and dictionaries being read.
+	depth int
+}
+
+func newPDFParser(data []byte) *pdfParser {
+	return &pdfParser{data: data, refs: true}
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func (p *pdfParser) skipSpace() {
+	for p.pos < len(p.data) {
+		switch c := p.data[p.pos]; {
+		case c == '%':
+			for p.pos < len(p.data) && p.data[p.pos] != '\n' && p.data[p.pos] != '\r' {
+				p.pos++
+			}
+		case isPDFSpace(c):
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+// next returns the next object, or nil at the end of the data.
+func (p *pdfParser) next() any {
+	p.skipSpace()
+	if p.pos >= len(p.data) {
+		return nil
+	}
+	switch c := p.data[p.pos]; {
+	case c == '(':
+		s, end := parsePDFLiteralString(p.data, p.pos)
+		p.pos = min(end+1, len(p.data))
+		return s
+	case (c == '[' || c == '<' && p.pos+1 < len(p.data) && p.data[p.pos+1] == '<') && p.depth >= pdfMaxNesting:
+		// Nesting this deep is not a real document; stop reading the data.
+		p.pos = len(p.data)
+		return nil
+	case c == '<' && p.pos+1 < len(p.data) && p.data[p.pos+1] == '<':
+		p.pos += 2
+		p.depth++
+		defer func() { p.depth-- }()
+		dict := map[string]any{}
+		for {
+			name, ok := p.next().(pdfName)
+			if !ok {
+				return dict
+			}
+			dict[string(name)] = p.next()
+		}
+	case c == '<':
+		s, end := parsePDFHexString(p.data, p.pos)
+		p.pos = min(end+1, len(p.data))
+		return s
+	case c == '>' && p.pos+1 < len(p.data) && p.data[p.pos+1] == '>':
+		p.pos += 2
+		return pdfKeyword(">>")
+	case c == '[':
+		p.pos++
+		p.depth++
+		defer func() { p.depth-- }()
+		var array []any
+		for {
+			v := p.next()
+			if v == nil || v == pdfKeyword("]") {
+				return array
+			}
+			array = append(array, v)
+		}
+	case c == '/':
+		p.pos++
+		start := p.pos
+		p.skipToken()
+		return pdfName(p.data[start:p.pos])
+	case isPDFDelimiter(c):
+		p.pos++
+		return pdfKeyword(p.data[p.pos-1 : p.pos])
+	}
+	start := p.pos
+	p.skipToken()
+	token := string(p.data[start:p.pos])
+	if c := token[0]; c == '+' || c == '-' || c == '.' || c >= '0' && c <= '9' {
+		if n, err := strconv.ParseFloat(token, 64); err == nil {
+			if ref, ok := p.reference(token); ok {
+				return ref
+			}
+			return n
+		}
+	}
+	return pdfKeyword(token)
+}
+
+func (p *pdfParser) skipToken() {
+	for p.pos < len(p.data) && !isPDFSpace(p.data[p.pos]) && !isPDFDelimiter(p.data[p.pos]) {
+		p.pos++
+	}
+}
+
+// reference completes "number generation R" after number, restoring the position otherwise.
+func (p *pdfParser) reference(number string) (pdfRef, bool) {
+	n, err := strconv.Atoi(number)
+	if !p.refs || err != nil {
+		return 0, false
+	}
+	save := p.pos
+	p.skipSpace()
+	start := p.pos
+	p.skipToken()
+	if _, err := strconv.Atoi(string(p.data[start:p.pos])); err == nil && p.pos > start {
+		p.skipSpace()
+		if p.pos < len(p.data) && p.data[p.pos] == 'R' && (p.pos+1 == len(p.data) || isPDFSpace(p.data[p.pos+1]) || isPDFDelimiter(p.data[p.pos+1])) {
+			p.pos++
+			return pdfRef(n), true
+		}
+	}
+	p.pos = save
+	return 0, false
+}
+
+// pdfDocument gives access to the objects of a PDF found by scanning for their headers, like
+// repairPDF does, so damaged cross-reference data does not matter. Objects stored in object
+// streams are unpacked.
+type pdfDocument struct {
+	raw     map[int][]byte
+	objects map[int]any
+	// decodedBytes is the decompressed size of the streams decoded so far, and skipped the
+	// number of streams not decoded because of pdfMaxStreamBytes and pdfMaxDecodedBytes.
+	decodedBytes int
+	skipped      int
+}
+
+func loadPDFObjects(data []byte) *pdfDocument {
+	d := &pdfDocument{raw: map[int][]byte{}, objects: map[int]any{}}
+	matches := pdfObjectPattern.FindAllSubmatchIndex(data, -1)
+	for i, m := range matches {
+		number, err := strconv.Atoi(string(data[m[2]:m[3]]))
+		if err != nil {
+			continue
+		}
+		end := len(data)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		body := data[m[1]:end]
+		if j := bytes.LastIndex(body, []byte("endobj")); j >= 0 {
+			body = body[:j]
+		}
+		// Later definitions override earlier ones, as in incremental updates.
+		d.raw[number] = body
+	}
+	for number, body := range d.raw {
+		if !bytes.Contains(body, []byte("/ObjStm")) {
+			continue
+		}
+		if s, ok := d.object(number).(*pdfStream); ok && s.dict["Type"] == pdfName("ObjStm") {
+			d.unpack(s)
+		}
+	}
+	return d
+}
+
+// catalog returns the document catalog the last trailer of data points to, or nil.
+func (d *pdfDocument) catalog(data []byte) map[string]any {
+	root := lastMatch(pdfRootPattern, data)
+	if root == nil {
+		return nil
+	}
+	catalog, _ := d.resolve(newPDFParser(root[len("/Root"):]).next()).(map[string]any)
+	return catalog
+}
+
+// object returns the object with the given number, or nil.
+func (d *pdfDocument) object(number int) any {
+	if obj, ok := d.objects[number]; ok {
+		return obj
+	}
+	body, ok := d.raw[number]
+	if !ok {
+		return nil
+	}
+	// A reference cycle, e.g. through a stream's Length, resolves to nil.
+	d.objects[number] = nil
+	p := newPDFParser(body)
+	obj := p.next()
+	if dict, ok := obj.(map[string]any); ok && p.next() == pdfKeyword("stream") {
+		start := p.pos
+		if start < len(body) && body[start] == '\r' {
+			start++
+		}
+		if start < len(body) && body[start] == '\n' {
+			start++
+		}
+		end := max(bytes.LastIndex(body, []byte("endstream")), start)
+		if length, ok := d.resolve(dict["Length"]).(float64); ok && length >= 0 && start+int(length) <= len(body) {
+			end = start + int(length)
+		}
+		obj = &pdfStream{dict: dict, data: body[start:end]}
+	}
+	d.objects[number] = obj
+	return obj
+}
+
+// resolve follows references to the object they point to.
+func (d *pdfDocument) resolve(v any) any {
+	for range 8 {
+		ref, ok := v.(pdfRef)
+		if !ok {
+			return v
+		}
+		v = d.object(int(ref))
+	}
+	return nil
+}
+
+func (d *pdfDocument) dict(v any) map[string]any {
+	switch v := d.resolve(v).(type) {
+	case map[string]any:
+		return v
+	case *pdfStream:
+		return v.dict
+	}
+	return nil
+}
+
+// decode returns the decoded data of s, or nil for filters other than FlateDecode and for
+// streams over the decompression limits.
+func (d *pdfDocument) decode(s *pdfStream) []byte {
+	if !s.decodedDone {
+		s.decoded, s.decodedDone = d.decodeStream(s), true
+	}
+	return s.decoded
+}
+
+func (d *pdfDocument) decodeStream(s *pdfStream) []byte {
+	var filters []any
+	switch f := d.resolve(s.dict["Filter"]).(type) {
+	case nil:
+		return s.data
+	case pdfName:
+		filters = []any{f}
+	case []any:
+		filters = f
+	}
+	data := s.data
+	for _, filter := range filters {
+		if filter != pdfName("FlateDecode") {
+			return nil
+		}
+		zr, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil
+		}
+		limit := min(pdfMaxStreamBytes, pdfMaxDecodedBytes-d.decodedBytes)
+		// Streams cut short still yield the text before the damage.
+		if data, err = io.ReadAll(io.LimitReader(zr, int64(limit)+1)); err != nil && len(data) == 0 {
+			return nil
+		}
+		if len(data) > limit {
+			d.skipped++
+			return nil
+		}
+		d.decodedBytes += len(data)
+	}
+	return data
+}
+
+// unpack adds the objects stored in the object stream s that are not defined directly.
+func (d *pdfDocument) unpack(s *pdfStream) {
+	data := d.decode(s)
+	n, _ := d.resolve(s.dict["N"]).(float64)
+	first, _ := d.resolve(s.dict["First"]).(float64)
+	if data == nil || !(first >= 0 && first <= float64(len(data))) {
+		return
+	}
+	header := &pdfParser{data: data[:int(first)]}
+	for range int(n) {
+		number, ok1 := header.next().(float64)
+		offset, ok2 := header.next().(float64)
+		at := int(first) + int(offset)
+		if !ok1 || !ok2 || at < 0 || at >= len(data) {
+			return
+		}
+		if _, defined := d.raw[int(number)]; !defined {
+			d.objects[int(number)] = newPDFParser(data[at:]).next()
+		}
+	}
+}
+
+// pdfMatrix is a PDF transformation matrix [a b c d e f].
+type pdfMatrix [6]float64
+
+var pdfIdentity = pdfMatrix{1, 0, 0, 1, 0, 0}
+
+// times returns m × n, which applies m first.
+func (m pdfMatrix) times(n pdfMatrix) pdfMatrix {
+	return pdfMatrix{
+		m[0]*n[0] + m[1]*n[2], m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2], m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4], m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+// pdfFont decodes the strings of text shown in a font.
+type pdfFont struct {
+	// twoByte marks composite fonts, whose codes cannot be read without toUnicode.
+	twoByte   bool
+	toUnicode map[string]string
+	codeLen   int
+}
+
+func (f *pdfFont) decode(b []byte) string {
+	if f == nil || f.toUnicode == nil {
+		if f != nil && f.twoByte {
+			return ""
+		}
+		runes := make([]rune, len(b))
+		for i, c := range b {
+			runes[i] = rune(c)
+		}
+		return string(runes)
+	}
+	var out strings.Builder
+	for i := 0; i+f.codeLen <= len(b); i += f.codeLen {
+		out.WriteString(f.toUnicode[string(b[i:i+f.codeLen])])
+	}
+	return out.String()
+}
+
+var (
+	cmapBfcharPattern  = regexp.MustCompile(`(?s)beginbfchar(.*?)endbfchar`)
+	cmapBfrangePattern = regexp.MustCompile(`(?s)beginbfrange(.*?)endbfrange`)
+	cmapCharPattern    = regexp.MustCompile(`<([0-9A-Fa-f]+)>\s*<([0-9A-Fa-f]*)>`)
+	cmapRangePattern   = regexp.MustCompile(`<([0-9A-Fa-f]+)>\s*<([0-9A-Fa-f]+)>\s*(<[0-9A-Fa-f]*>|\[[^\]]*\])`)
+	cmapTargetPattern  = regexp.MustCompile(`<([0-9A-Fa-f]*)>`)
+)
+
+// parseToUnicode reads the bfchar and bfrange mappings of a ToUnicode CMap into f.
+func (f *pdfFont) parseToUnicode(cmap []byte) {
+	hex := func(s []byte) []byte {
+		b, _ := parsePDFHexString([]byte("<"+string(s)+">"), 0)
+		return b
+	}
+	f.toUnicode = map[string]string{}
+	set := func(code []byte, unicode []byte) {
+		f.codeLen = len(code)
+		f.toUnicode[string(code)] = utf16BEString(unicode)
+	}
+	for _, block := range cmapBfcharPattern.FindAllSubmatch(cmap, -1) {
+		for _, m := range cmapCharPattern.FindAllSubmatch(block[1], -1) {
+			set(hex(m[1]), hex(m[2]))
+		}
+	}
+	for _, block := range cmapBfrangePattern.FindAllSubmatch(cmap, -1) {
+		for _, m := range cmapRangePattern.FindAllSubmatch(block[1], -1) {
+			lo, hi := hex(m[1]), hex(m[2])
+			if len(lo) != len(hi) || len(lo) == 0 || len(lo) > 4 {
+				continue
+			}
+			var targets [][]byte
+			if m[3][0] == '[' {
+				for _, t := range cmapTargetPattern.FindAllSubmatch(m[3], -1) {
+					targets = append(targets, hex(t[1]))
+				}
+			}
+			start, end := codeValue(lo), codeValue(hi)
+			for i, code := 0, start; code <= end && i < 1<<16; i, code = i+1, code+1 {
+				var unicode []byte
+				if targets != nil {
+					if i >= len(targets) {
+						break
+					}
+					unicode = targets[i]
+				} else {
+					unicode = bytes.Clone(hex(m[3][1 : len(m[3])-1]))
+					if len(unicode) >= 2 {
+						last := uint32(unicode[len(unicode)-2])<<8 | uint32(unicode[len(unicode)-1]) + uint32(i)
+						unicode[len(unicode)-2], unicode[len(unicode)-1] = byte(last>>8), byte(last)
+					}
+				}
+				codeBytes := make([]byte, len(lo))
+				for k := range codeBytes {
+					codeBytes[k] = byte(code >> (8 * (len(lo) - 1 - k)))
+				}
+				set(codeBytes, unicode)
+			}
+		}
+	}
+	if len(f.toUnicode) == 0 {
+		f.toUnicode = nil
+	}
+}
+
+func codeValue(b []byte) uint32 {
+	var v uint32
+	for _, c := range b {
+		v = v<<8 | uint32(c)
+	}
+	return v
+}
+
+func utf16BEString(b []byte) string {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+	return string(utf16.Decode(units))
+}
+
+// pdfGraphicsState is the part of the graphics state that decides whether text is visible.
+type pdfGraphicsState struct {
+	ctm        pdfMatrix
+	fillWhite  bool
+	fillAlpha  float64
+	renderMode int
+	font       *pdfFont
+	fontSize   float64
+	leading    float64
+}
+
+// hiddenTextScanner interprets page content streams and collects the hidden text.
+type hiddenTextScanner struct {
+	doc         *pdfDocument
+	minFontSize float64
+	fonts       map[pdfRef]*pdfFont
+
+	page    int
+	box     [4]float64
+	spans   []HiddenTextSpan
+	current *HiddenTextSpan
+	// moved records a text positioning operator since the last text shown.
+	moved bool
+}
+
+// walkPages visits the page tree below node, passing on the inheritable resources and media box.
+func (s *hiddenTextScanner) walkPages(node any, resources map[string]any, box [4]float64, visited map[pdfRef]bool, depth int) {
+	if ref, ok := node.(pdfRef); ok {
+		if visited[ref] {
+			return
+		}
+		visited[ref] = true
+	}
+	dict := s.doc.dict(node)
+	if dict == nil || depth > 32 {
+		return
+	}
+	if r := s.doc.dict(dict["Resources"]); r != nil {
+		resources = r
+	}
+	if b, ok := s.doc.resolve(dict["MediaBox"]).([]any); ok && len(b) == 4 {
+		for i, v := range b {
+			n, _ := s.doc.resolve(v).(float64)
+			box[i] = n
+		}
+		box = [4]float64{min(box[0], box[2]), min(box[1], box[3]), max(box[0], box[2]), max(box[1], box[3])}
+	}
+	if kids, ok := s.doc.resolve(dict["Kids"]).([]any); ok {
+		for _, kid := range kids {
+			s.walkPages(kid, resources, box, visited, depth+1)
+		}
+		return
+	}
+	s.page++
+	s.box = box
+	var content []byte
+	switch c := s.doc.resolve(dict["Contents"]).(type) {
+	case *pdfStream:
+		content = s.doc.decode(c)
+	case []any:
+		for _, part := range c {
+			if stream, ok := s.doc.resolve(part).(*pdfStream); ok {
+				content = append(append(content, s.doc.decode(stream)...), '\n')
+			}
+		}
+	}
+	s.run(content, resources, &pdfGraphicsState{ctm: pdfIdentity, fillAlpha: 1}, 0)
+	s.flush()
+}
+
+// run interprets a content stream starting from gs.
+func (s *hiddenTextScanner) run(content []byte, resources map[string]any, gs *pdfGraphicsState, depth int) {
+	p := &pdfParser{data: content}
+	var stack []pdfGraphicsState
+	var operands []any
+	tm, tlm := pdfIdentity, pdfIdentity
+	num := func(i int) float64 {
+		if i < len(operands) {
+			n, _ := operands[i].(float64)
+			return n
+		}
+		return 0
+	}
+	resource := func(category string) any {
+		name, _ := operands[0].(pdfName)
+		return s.doc.resolve(s.doc.dict(resources[category])[string(name)])
+	}
+	moveText := func(tx, ty float64) {
+		tlm = pdfMatrix{1, 0, 0, 1, tx, ty}.times(tlm)
+		tm = tlm
+		s.moved = true
+	}
+	for {
+		obj := p.next()
+		if obj == nil {
+			return
+		}
+		op, ok := obj.(pdfKeyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+		n := len(operands)
+		switch op {
+		case "q":
+			stack = append(stack, *gs)
+		case "Q":
+			if len(stack) > 0 {
+				*gs, stack = stack[len(stack)-1], stack[:len(stack)-1]
+			}
+		case "cm":
+			if n == 6 {
+				gs.ctm = pdfMatrix{num(0), num(1), num(2), num(3), num(4), num(5)}.times(gs.ctm)
+			}
+		case "gs":
+			if n == 1 {
+				if alpha, ok := s.doc.resolve(s.doc.dict(resource("ExtGState"))["ca"]).(float64); ok {
+					gs.fillAlpha = alpha
+				}
+			}
+		case "g":
+			gs.fillWhite = n == 1 && num(0) >= 1
+		case "rg":
+			gs.fillWhite = n == 3 && num(0) >= 1 && num(1) >= 1 && num(2) >= 1
+		case "k":
+			gs.fillWhite = n == 4 && num(0) <= 0 && num(1) <= 0 && num(2) <= 0 && num(3) <= 0
+		case "sc", "scn":
+			// The color space is not tracked; the operand count implies gray, RGB or CMYK.
+			switch n {
+			case 1:
+				gs.fillWhite = num(0) >= 1
+			case 3:
+				gs.fillWhite = num(0) >= 1 && num(1) >= 1 && num(2) >= 1
+			case 4:
+				gs.fillWhite = num(0) <= 0 && num(1) <= 0 && num(2) <= 0 && num(3) <= 0
+			default:
+				gs.fillWhite = false
+			}
+		case "cs":
+			gs.fillWhite = false
+		case "BT":
+			tm, tlm = pdfIdentity, pdfIdentity
+			s.moved = true
+		case "Tf":
+			if n == 2 {
+				gs.font = s.font(resources, operands[0])
+				gs.fontSize = num(1)
+			}
+		case "Tr":
+			gs.renderMode = int(num(0))
+		case "TL":
+			gs.leading = num(0)
+		case "Td":
+			moveText(num(0), num(1))
+		case "TD":
+			gs.leading = -num(1)
+			moveText(num(0), num(1))
+		case "Tm":
+			if n == 6 {
+				tm = pdfMatrix{num(0), num(1), num(2), num(3), num(4), num(5)}
+				tlm = tm
+				s.moved = true
+			}
+		case "T*":
+			moveText(0, -gs.leading)
+		case "Tj", "'", "\"":
+			if op != "Tj" {
+				moveText(0, -gs.leading)
+			}
+			if n > 0 {
+				if text, ok := operands[n-1].([]byte); ok {
+					s.show(gs.font.decode(text), s.hiddenReason(gs, tm))
+				}
+			}
+		case "TJ":
+			if n == 1 {
+				if parts, ok := operands[0].([]any); ok {
+					var text strings.Builder
+					for _, part := range parts {
+						switch part := part.(type) {
+						case []byte:
+							text.WriteString(gs.font.decode(part))
+						case float64:
+							// Large negative adjustments, in thousandths of the font size, are
+							// word gaps.
+							if part < -250 {
+								text.WriteByte(' ')
+							}
+						}
+					}
+					s.show(text.String(), s.hiddenReason(gs, tm))
+				}
+			}
+		case "Do":
+			if n == 1 && depth < 8 {
+				if form, ok := resource("XObject").(*pdfStream); ok && form.dict["Subtype"] == pdfName("Form") {
+					formResources := s.doc.dict(form.dict["Resources"])
+					if formResources == nil {
+						formResources = resources
+					}
+					inner := *gs
+					if m, ok := s.doc.resolve(form.dict["Matrix"]).([]any); ok && len(m) == 6 {
+						var matrix pdfMatrix
+						for i, v := range m {
+							matrix[i], _ = s.doc.resolve(v).(float64)
+						}
+						inner.ctm = matrix.times(gs.ctm)
+					}
+					s.run(s.doc.decode(form), formResources, &inner, depth+1)
+				}
+			}
+		case "ID":
+			// Skip the binary data of inline images up to the EI operator.
+			if end := bytes.Index(content[p.pos:], []byte("EI")); end >= 0 {
+				p.pos += end + 2
+			} else {
+				p.pos = len(content)
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+// font returns the font the resource name refers to.
+func (s *hiddenTextScanner) font(resources map[string]any, name any) *pdfFont {
+	n, _ := name.(pdfName)
+	entry := s.doc.dict(resources["Font"])[string(n)]
+	ref, isRef := entry.(pdfRef)
+	if f, ok := s.fonts[ref]; ok && isRef {
+		return f
+	}
+	dict := s.doc.dict(entry)
+	if dict == nil {
+		return nil
+	}
+	f := &pdfFont{twoByte: dict["Subtype"] == pdfName("Type0")}
+	if cmap, ok := s.doc.resolve(dict["ToUnicode"]).(*pdfStream); ok {
+		f.parseToUnicode(s.doc.decode(cmap))
+	}
+	if isRef {
+		s.fonts[ref] = f
+	}
+	return f
+}
+
+// hiddenReason classifies text shown at the text matrix tm, returning "" for visible text.
+func (s *hiddenTextScanner) hiddenReason(gs *pdfGraphicsState, tm pdfMatrix) HiddenTextReason {
+	m := tm.times(gs.ctm)
+	switch {
+	case gs.renderMode == 3 || gs.renderMode == 7:
+		return HiddenTextRenderMode
+	case gs.fillAlpha <= 0:
+		return HiddenTextTransparent
+	case m[4] < s.box[0] || m[4] > s.box[2] || m[5] < s.box[1] || m[5] > s.box[3]:
+		return HiddenTextOffPage
+	case gs.fillWhite && gs.renderMode%2 == 0:
+		return HiddenTextWhite
+	case math.Abs(gs.fontSize)*math.Hypot(m[2], m[3]) < s.minFontSize:
+		return HiddenTextTinyFont
+	}
+	return ""
+}
+
+// show adds text to the current span when it is hidden for the same reason, and otherwise ends
+// the span.
+func (s *hiddenTextScanner) show(text string, reason HiddenTextReason) {
+	switch {
+	case reason == "":
+		s.flush()
+	case s.current != nil && s.current.Reason == reason:
+		if s.moved {
+			s.current.Text += " "
+		}
+		s.current.Text += text
+	default:
+		s.flush()
+		s.current = &HiddenTextSpan{Page: s.page, Reason: reason, Text: text}
+	}
+	s.moved = false
+}
+
+func (s *hiddenTextScanner) flush() {
+	if s.current == nil {
+		return
+	}
+	if s.current.Text = collapseSpace(s.current.Text); s.current.Text != "" {
+		s.spans = append(s.spans, *s.current)
+	}
+	s.current = nil
+}
@@ -0,0 +1,198 @@
+package kreuzberg
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func zlibCompress(data string) string {
+	var b bytes.Buffer
+	zw := zlib.NewWriter(&b)
+	zw.Write([]byte(data))
+	zw.Close()
+	return b.String()
+}
+
+// testHiddenTextPDF builds a two-page PDF. The first page shows visible text between text hidden
+// in every supported way; the second, whose dictionary sits in an object stream, shows white
+// text in a composite font through a ToUnicode map and invisible text from a form XObject.
+func testHiddenTextPDF() []byte {
+	page1 := `BT /F1 12 Tf 72 700 Td (Visible intro.) Tj ET
+q BT /F1 12 Tf 3 Tr 72 680 Td (Invisible render mode) Tj ET Q
+q BT /F1 12 Tf 1 1 1 rg 72 660 Td (White words) Tj 0 -14 Td (on two lines) Tj ET Q
+BT /F1 0.5 Tf 72 640 Td (Tiny print text) Tj ET
+BT /F1 12 Tf 72 620 Td (Middle part.) Tj ET
+BT /F1 12 Tf -500 640 Td (Off page text) Tj ET
+q /GS1 gs BT /F1 12 Tf 72 600 Td [(Transparent) -300 (text)] TJ ET Q
+BT /F1 12 Tf 72 580 Td [(Clos) 20 (ing) -300 (remark.)] TJ ET`
+	page2 := "q BT /F2 12 Tf 1 g 72 700 Td <00010002> Tj ET Q\nq 0.5 0 0 0.5 0 0 cm /Fm1 Do Q\nBT /F2 12 Tf 72 650 Td <0002> Tj ET"
+	form := "BT /F1 12 Tf 7 Tr 72 600 Td (Form secret) Tj ET"
+	cmap := "/CIDInit /ProcSet findresource begin 12 dict begin begincmap\n1 begincodespacerange <0000> <FFFF> endcodespacerange\n" +
+		"1 beginbfchar <0001> <0048> endbfchar\n1 beginbfrange <0002> <0003> <0069> endbfrange\nendcmap end end"
+	page2Dict := "<< /Type /Page /Parent 2 0 R /Contents 7 0 R /Resources << /Font << /F1 5 0 R /F2 8 0 R >> /XObject << /Fm1 10 0 R >> >> >>"
+	objStm := "11 0 " + page2Dict
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 11 0 R] /Count 2 /MediaBox [0 0 612 792] >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> /ExtGState << /GS1 6 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(page1), page1),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		"<< /Type /ExtGState /ca 0 >>",
+		fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", len(zlibCompress(page2)), zlibCompress(page2)),
+		"<< /Type /Font /Subtype /Type0 /BaseFont /Foo /Encoding /Identity-H /ToUnicode 9 0 R >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(cmap), cmap),
+		fmt.Sprintf("<< /Type /XObject /Subtype /Form /BBox [0 0 612 792] /Length %d >>\nstream\n%s\nendstream", len(form), form),
+		fmt.Sprintf("<< /Type /ObjStm /N 1 /First 5 /Filter /FlateDecode /Length %d >>\nstream\n%s\nendstream", len(zlibCompress(objStm)), zlibCompress(objStm)),
+	}
+	var pdf strings.Builder
+	pdf.WriteString("%PDF-1.7\n")
+	for i, obj := range objects {
+		number := i + 1
+		if number == 11 {
+			number = 12
+		}
+		fmt.Fprintf(&pdf, "%d 0 obj\n%s\nendobj\n", number, obj)
+	}
+	pdf.WriteString("trailer\n<< /Root 1 0 R /Size 13 >>\n%%EOF\n")
+	return []byte(pdf.String())
+}
+
+func TestFindHiddenText(t *testing.T) {
+	spans, skipped, err := findHiddenText(testHiddenTextPDF(), defaultHiddenTextMinFontSize)
+	if err != nil || skipped != 0 {
+		t.Fatalf("find: %d skipped, %v", skipped, err)
+	}
+	want := []HiddenTextSpan{
+		{Page: 1, Reason: HiddenTextRenderMode, Text: "Invisible render mode"},
+		{Page: 1, Reason: HiddenTextWhite, Text: "White words on two lines"},
+		{Page: 1, Reason: HiddenTextTinyFont, Text: "Tiny print text"},
+		{Page: 1, Reason: HiddenTextOffPage, Text: "Off page text"},
+		{Page: 1, Reason: HiddenTextTransparent, Text: "Transparent text"},
+		{Page: 2, Reason: HiddenTextWhite, Text: "Hi"},
+		{Page: 2, Reason: HiddenTextRenderMode, Text: "Form secret"},
+	}
+	if !slices.Equal(spans, want) {
+		t.Fatalf("unexpected spans\n got %+v\nwant %+v", spans, want)
+	}
+}
+
+// hostilePDF builds a one-page PDF whose page draws the content stream built from extra objects.
+func hostilePDF(contents string, extra ...string) []byte {
+	var pdf strings.Builder
+	pdf.WriteString("%PDF-1.7\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	pdf.WriteString("2 0 obj\n<< /Type /Pages /Kids [3 0 R] /Count 1 >>\nendobj\n")
+	fmt.Fprintf(&pdf, "3 0 obj\n<< /Type /Page /Parent 2 0 R /Contents %s >>\nendobj\n", contents)
+	for i, obj := range extra {
+		fmt.Fprintf(&pdf, "%d 0 obj\n%s\nendobj\n", i+4, obj)
+	}
+	pdf.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return []byte(pdf.String())
+}
+
+func TestFindHiddenTextResistsHostilePDFs(t *testing.T) {
+	objStm := "<< /Type /ObjStm /N 1 /First -5 /Length 9 >>\nstream\n9 0 (x) \nendstream"
+	if _, _, err := findHiddenText(hostilePDF("4 0 R", objStm), 2); err != nil {
+		t.Fatalf("a negative First should be ignored, got %v", err)
+	}
+
+	deep := strings.Repeat("[", 100000) + strings.Repeat("<<", 100000)
+	if _, _, err := findHiddenText(hostilePDF("4 0 R", fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(deep), deep)), 2); err != nil {
+		t.Fatalf("deep nesting should be cut off, got %v", err)
+	}
+
+	defer func(limit int) { pdfMaxStreamBytes = limit }(pdfMaxStreamBytes)
+	pdfMaxStreamBytes = 1 << 10
+	bomb := zlibCompress(strings.Repeat("0", 1<<20))
+	spans, skipped, err := findHiddenText(hostilePDF("4 0 R", fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", len(bomb), bomb)), 2)
+	if err != nil || skipped != 1 || len(spans) != 0 {
+		t.Fatalf("expected the oversized stream to be skipped, got %v, %d, %v", spans, skipped, err)
+	}
+
+	encrypted := append(hostilePDF("[]"), "trailer\n<< /Root 1 0 R /Encrypt 9 0 R >>\n"...)
+	result := &ExtractionResult{MimeType: "application/pdf"}
+	if err := annotateHiddenText(result, encrypted, &HiddenTextConfig{}); err != nil {
+		t.Fatalf("annotate: %v", err)
+	}
+	if d := result.DiagnosticsBySeverity(DiagnosticSeverityWarning); len(d) != 1 || !strings.Contains(d[0].Message, "encrypted") {
+		t.Fatalf("expected a warning for an encrypted PDF, got %+v", result.Diagnostics)
+	}
+}
+
+func TestAnnotateHiddenText(t *testing.T) {
+	// The first page quotes the second page's hidden text visibly, and the second has a word
+	// starting with its other hidden text.
+	page1 := "Visible intro.\nInvisible render mode\nWhite words\non two lines\nTiny print text\nMiddle part.\nOff page text\nTransparent text\nClosing remark.\nForm secret stays."
+	page2 := "History\nHi\nForm secret\ni"
+	newResult := func() *ExtractionResult {
+		split := len(page1) + 2
+		return &ExtractionResult{
+			MimeType: "application/pdf",
+			Content:  page1 + "\n\f" + page2,
+			Pages:    []PageContent{{PageNumber: 1, Content: page1}, {PageNumber: 2, Content: page2}},
+			Chunks: []Chunk{
+				{Content: page1 + "\n\f", Metadata: ChunkMetadata{ByteStart: 0, ByteEnd: uint64(split)}},
+				{Content: page2, Metadata: ChunkMetadata{ByteStart: uint64(split), ByteEnd: uint64(split + len(page2))}},
+			},
+			Metadata: Metadata{PageStructure: &PageStructure{TotalCount: 2, Boundaries: []PageBoundary{
+				{ByteStart: 0, ByteEnd: uint64(split), PageNumber: 1},
+				{ByteStart: uint64(split), ByteEnd: uint64(split + len(page2)), PageNumber: 2},
+			}}},
+		}
+	}
+
+	kept := newResult()
+	if err := annotateHiddenText(kept, testHiddenTextPDF(), &HiddenTextConfig{}); err != nil {
+		t.Fatalf("annotate: %v", err)
+	}
+	spans, ok := kept.Metadata.HiddenText()
+	if !ok || len(spans) != 7 || spans[0].Excluded || kept.Content != newResult().Content {
+		t.Fatalf("expected the hidden text to be reported and kept, got %+v in %q", spans, kept.Content)
+	}
+
+	excluded := newResult()
+	if err := annotateHiddenText(excluded, testHiddenTextPDF(), &HiddenTextConfig{Exclude: true}); err != nil {
+		t.Fatalf("annotate: %v", err)
+	}
+	if got := collapseSpace(excluded.Content); got != "Visible intro. Middle part. Closing remark. Form secret stays. History i" {
+		t.Fatalf("unexpected content %q", got)
+	}
+	if got := collapseSpace(excluded.Pages[1].Content); got != "History i" {
+		t.Fatalf("unexpected page content %q", got)
+	}
+	// The boundaries and chunks still address the content.
+	for i, b := range excluded.Metadata.PageStructure.Boundaries {
+		chunk := excluded.Chunks[i]
+		if got := excluded.Content[b.ByteStart:b.ByteEnd]; got != chunk.Content || chunk.Metadata.ByteStart != b.ByteStart || chunk.Metadata.ByteEnd != b.ByteEnd {
+			t.Fatalf("page %d: %q is out of line with chunk %+v", b.PageNumber, got, chunk)
+		}
+	}
+	if spans, _ := excluded.Metadata.HiddenText(); !spans[6].Excluded {
+		t.Fatalf("expected the spans to be marked excluded, got %+v", spans)
+	}
+	if d := excluded.DiagnosticsBySeverity(DiagnosticSeverityInfo); len(d) != 1 || d[0].Message != "7 hidden text spans, 7 excluded from the content" {
+		t.Fatalf("unexpected diagnostics %+v", excluded.Diagnostics)
+	}
+
+	// Raising the threshold hides the visible 12pt text too.
+	large := newResult()
+	annotateHiddenText(large, testHiddenTextPDF(), &HiddenTextConfig{MinFontSize: 20})
+	if spans, _ := large.Metadata.HiddenText(); spans[0].Reason != HiddenTextTinyFont || spans[0].Text != "Visible intro." {
+		t.Fatalf("unexpected spans %+v", spans)
+	}
+
+	if err := annotateHiddenText(newResult(), testHiddenTextPDF(), &HiddenTextConfig{MinFontSize: -1}); err == nil {
+		t.Fatalf("expected a negative font size to be rejected")
+	}
+	broken := newResult()
+	if err := annotateHiddenText(broken, []byte("%PDF-1.7\n1 0 obj\n<< >>\nendobj\n"), &HiddenTextConfig{}); err != nil {
+		t.Fatalf("annotate: %v", err)
+	}
+	if d := broken.DiagnosticsBySeverity(DiagnosticSeverityWarning); len(d) != 1 {
+		t.Fatalf("expected a warning for a PDF without page tree, got %+v", broken.Diagnostics)
+	}
+}
@@ -0,0 +1,58 @@
+package kreuzberg
+
+/*
+#include "internal/ffi/kreuzberg.h"
+#include <stdlib.h>
+#include <stdint.h>
+*/
+import "C"
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"unsafe"
+)
+
+// PDFPages returns a PageSource over the pages of a PDF, so that ExtractPagesResumable can
+// checkpoint it page by page. The native library counts the pages up front and copies each page
+// into a PDF of its own when it is extracted. Its ID is a digest of the PDF.
+func PDFPages(data []byte) (PageSource, error) {
+	if len(data) == 0 {
+		return nil, newValidationErrorWithContext("data cannot be empty", nil, ErrorCodeValidation, nil)
+	}
+	buf := newCBytes(data)
+	defer freeCBuffer(buf)
+	count := int(C.kreuzberg_pdf_page_count((*C.uint8_t)(buf), C.uintptr_t(len(data))))
+	if count < 0 {
+		return nil, lastError()
+	}
+	return &pdfPages{data: data, pages: count}, nil
+}
+
+type pdfPages struct {
+	data  []byte
+	pages int
+}
+
+func (p *pdfPages) ID() (string, error) {
+	digest := sha256.Sum256(p.data)
+	return "pdf:" + hex.EncodeToString(digest[:]), nil
+}
+
+func (p *pdfPages) Len() int { return p.pages }
+
+func (p *pdfPages) Page(i int) (BytesWithMime, error) {
+	if i < 0 || i >= p.pages {
+		return BytesWithMime{}, newValidationErrorWithContext("page index out of range", nil, ErrorCodeValidation, nil)
+	}
+	defer pinNativeStack()()
+	buf := newCBytes(p.data)
+	defer freeCBuffer(buf)
+	var pageLen C.uintptr_t
+	page := C.kreuzberg_pdf_page_range((*C.uint8_t)(buf), C.uintptr_t(len(p.data)), C.uintptr_t(i), 1, &pageLen)
+	if page == nil {
+		return BytesWithMime{}, lastError()
+	}
+	defer C.kreuzberg_free_bytes(page, pageLen)
+	return BytesWithMime{Data: C.GoBytes(unsafe.Pointer(page), C.int(pageLen)), MimeType: "application/pdf"}, nil
+}
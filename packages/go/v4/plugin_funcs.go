@@ -0,0 +1,399 @@
+package kreuzberg
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// PluginContext describes the document a Go plugin is being invoked for.
+//
+// Native (C callback) plugins only ever see the result JSON. Go plugins registered via
+// RegisterValidatorFunc/RegisterPostProcessorFunc additionally receive this context so
+// they can apply per-source rules without encoding that information into the content.
+type PluginContext struct {
+	// DocumentPath is the path of the extracted file (empty for in-memory documents).
+	DocumentPath string
+	// MimeType is the MIME type the document was extracted as.
+	MimeType string
+	// Config is the ExtractionConfig supplied to the extraction call (nil when defaults are used).
+	Config *ExtractionConfig
+	// Labels carries the caller-supplied labels from ExtractionConfig.Labels (tenant, source system, etc.).
+	Labels map[string]string
+
+	ctx      context.Context
+	data     []byte
+	hashOnce sync.Once
+	hash     string
+	hashErr  error
+}
+
+// NewPluginContext builds a PluginContext for invoking Go plugins outside of an extraction,
+// e.g. from tests or custom pipelines. Pass either path or data as the document source.
+func NewPluginContext(path string, data []byte, mimeType string, config *ExtractionConfig) *PluginContext {
+	return newPluginContext(context.Background(), path, data, mimeType, config)
+}
+
+func newPluginContext(ctx context.Context, path string, data []byte, mimeType string, config *ExtractionConfig) *PluginContext {
+	pc := &PluginContext{
+		DocumentPath: path,
+		MimeType:     mimeType,
+		Config:       config,
+		ctx:          ctx,
+		data:         data,
+	}
+	if config != nil {
+		pc.Labels = config.Labels
+	}
+	return pc
+}
+
+// DocumentHash returns the hex-encoded SHA-256 digest of the source document.
+// The digest is computed lazily on first use, reading the file from disk for path-based extractions.
+// Under CryptoProfileFIPS it fails unless Go's FIPS 140-3 mode is enabled.
+func (pc *PluginContext) DocumentHash() (string, error) {
+	pc.hashOnce.Do(func() {
+		if pc.hashErr = checkCryptoProfile(); pc.hashErr != nil {
+			return
+		}
+		h := sha256.New()
+		switch {
+		case pc.data != nil:
+			h.Write(pc.data)
+		case pc.DocumentPath != "":
+			// #nosec G304 -- path was supplied by the caller for extraction
+			f, err := os.Open(pc.DocumentPath)
+			if err != nil {
+				pc.hashErr = newIOErrorWithContext("failed to open document for hashing", err, ErrorCodeIo, nil)
+				return
+			}
+			defer f.Close()
+			if _, err := io.Copy(h, f); err != nil {
+				pc.hashErr = newIOErrorWithContext("failed to hash document", err, ErrorCodeIo, nil)
+				return
+			}
+		default:
+			pc.hashErr = newValidationErrorWithContext("no document source available for hashing", nil, ErrorCodeValidation, nil)
+			return
+		}
+		pc.hash = hex.EncodeToString(h.Sum(nil))
+	})
+	return pc.hash, pc.hashErr
+}
+
+// Context returns the context of the extraction: the ctx passed to the Client methods and the
+// other context-aware entry points (context.Background for the Sync functions), bounded by
+// ExtractionConfig.PluginTimeout while a plugin runs. Plugins that call remote services should
+// pass it on so they stop when the deadline passes or the caller gives up.
+func (pc *PluginContext) Context() context.Context {
+	if pc.ctx == nil {
+		return context.Background()
+	}
+	return pc.ctx
+}
+
+// Label returns the caller-supplied label for key, if present.
+func (pc *PluginContext) Label(key string) (string, bool) {
+	value, ok := pc.Labels[key]
+	return value, ok
+}
+
+// PostProcessorFunc is a Go-native post processor. It may modify result in place;
+// returning an error aborts the extraction.
+type PostProcessorFunc func(ctx *PluginContext, result *ExtractionResult) error
+
+// ValidatorFunc is a Go-native validator. Returning nil passes; returning WarnValidation records
+// a warning in result.Diagnostics; any other error (including FailValidation) fails the extraction.
+type ValidatorFunc func(ctx *PluginContext, result *ExtractionResult) error
+
+// ValidationOutcome is a structured validator outcome. Validators return it as an error via
+// WarnValidation or FailValidation.
+type ValidationOutcome struct {
+	// Severity is DiagnosticSeverityWarning for warnings and DiagnosticSeverityError for failures.
+	Severity DiagnosticSeverity
+	// Message describes the outcome.
+	Message string
+}
+
+func (o *ValidationOutcome) Error() string {
+	return o.Message
+}
+
+// WarnValidation returns an outcome that is recorded as a warning without failing the result,
+// unless ValidationPolicy.WarningsBlock is set.
+func WarnValidation(message string) error {
+	return &ValidationOutcome{Severity: DiagnosticSeverityWarning, Message: message}
+}
+
+// FailValidation returns an outcome that fails the extraction.
+func FailValidation(message string) error {
+	return &ValidationOutcome{Severity: DiagnosticSeverityError, Message: message}
+}
+
+// ValidationPolicy controls how Go validator outcomes affect the result.
+type ValidationPolicy struct {
+	// WarningsBlock treats warnings as failures.
+	WarningsBlock bool
+	// ContinueOnFailure runs every validator and reports all failures instead of
+	// short-circuiting at the first one.
+	ContinueOnFailure bool
+}
+
+type registeredPostProcessor struct {
+	name     string
+	priority int32
+	fn       PostProcessorFunc
+}
+
+type registeredValidator struct {
+	name     string
+	priority int32
+	fn       ValidatorFunc
+}
+
+// pluginRegistry holds Go-native plugins. Post-processors run before validators, mirroring
+// the native pipeline; within each stage higher priorities run first.
+type pluginRegistry struct {
+	mu             sync.RWMutex
+	postProcessors []registeredPostProcessor
+	validators     []registeredValidator
+	// mime holds the MIME type overrides applied to the scope's extractions.
+	mime mimeOverrides
+	// formats holds the format features disabled for the scope's extractions.
+	formats formatFlags
+	// events delivers the lifecycle events of the scope's extractions.
+	events eventBus
+}
+
+var defaultPluginRegistry = &pluginRegistry{}
+
+func (r *pluginRegistry) addPostProcessor(name string, priority int32, fn PostProcessorFunc) error {
+	if name == "" {
+		return newValidationErrorWithContext("post processor name cannot be empty", nil, ErrorCodeValidation, nil)
+	}
+	if fn == nil {
+		return newValidationErrorWithContext("post processor func cannot be nil", nil, ErrorCodeValidation, nil)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, p := range r.postProcessors {
+		if p.name == name {
+			return newPluginErrorWithContext(name, fmt.Sprintf("post processor '%s' is already registered", name), nil, ErrorCodePlugin, nil)
+		}
+	}
+	r.postProcessors = append(r.postProcessors, registeredPostProcessor{name: name, priority: priority, fn: fn})
+	sort.SliceStable(r.postProcessors, func(i, j int) bool {
+		return r.postProcessors[i].priority > r.postProcessors[j].priority
+	})
+	return nil
+}
+
+func (r *pluginRegistry) addValidator(name string, priority int32, fn ValidatorFunc) error {
+	if name == "" {
+		return newValidationErrorWithContext("validator name cannot be empty", nil, ErrorCodeValidation, nil)
+	}
+	if fn == nil {
+		return newValidationErrorWithContext("validator func cannot be nil", nil, ErrorCodeValidation, nil)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, v := range r.validators {
+		if v.name == name {
+			return newPluginErrorWithContext(name, fmt.Sprintf("validator '%s' is already registered", name), nil, ErrorCodePlugin, nil)
+		}
+	}
+	r.validators = append(r.validators, registeredValidator{name: name, priority: priority, fn: fn})
+	sort.SliceStable(r.validators, func(i, j int) bool {
+		return r.validators[i].priority > r.validators[j].priority
+	})
+	return nil
+}
+
+func (r *pluginRegistry) removePostProcessor(name string) error {
+	if name == "" {
+		return newValidationErrorWithContext("post processor name cannot be empty", nil, ErrorCodeValidation, nil)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, p := range r.postProcessors {
+		if p.name == name {
+			r.postProcessors = append(r.postProcessors[:i], r.postProcessors[i+1:]...)
+			return nil
+		}
+	}
+	return newPluginErrorWithContext(name, fmt.Sprintf("post processor '%s' is not registered", name), nil, ErrorCodePlugin, nil)
+}
+
+func (r *pluginRegistry) removeValidator(name string) error {
+	if name == "" {
+		return newValidationErrorWithContext("validator name cannot be empty", nil, ErrorCodeValidation, nil)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, v := range r.validators {
+		if v.name == name {
+			r.validators = append(r.validators[:i], r.validators[i+1:]...)
+			return nil
+		}
+	}
+	return newPluginErrorWithContext(name, fmt.Sprintf("validator '%s' is not registered", name), nil, ErrorCodePlugin, nil)
+}
+
+func (r *pluginRegistry) snapshot() ([]registeredPostProcessor, []registeredValidator) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]registeredPostProcessor(nil), r.postProcessors...), append([]registeredValidator(nil), r.validators...)
+}
+
+// apply runs all registered Go plugins against result.
+func (r *pluginRegistry) apply(pc *PluginContext, result *ExtractionResult) error {
+	if result == nil {
+		return nil
+	}
+	postProcessors, validators := r.snapshot()
+	if len(postProcessors) == 0 && len(validators) == 0 {
+		return nil
+	}
+	if pc.MimeType == "" {
+		pc.MimeType = result.MimeType
+	}
+
+	observe := r.events.wants(EventPluginModified)
+	for _, p := range postProcessors {
+		var before [sha256.Size]byte
+		if observe {
+			before = resultFingerprint(result)
+		}
+		if err := callPlugin(pc, func() error { return p.fn(pc, result) }); err != nil {
+			return newPluginErrorWithContext(p.name, fmt.Sprintf("post processor '%s' failed", p.name), err, ErrorCodePlugin, nil)
+		}
+		if observe {
+			r.events.emitPluginModified(pc, p.name, before, result)
+		}
+	}
+	return runValidators(validators, pc, result)
+}
+
+func runValidators(validators []registeredValidator, pc *PluginContext, result *ExtractionResult) error {
+	var policy ValidationPolicy
+	if pc.Config != nil && pc.Config.ValidationPolicy != nil {
+		policy = *pc.Config.ValidationPolicy
+	}
+
+	var firstFailure error
+	for _, v := range validators {
+		err := callPlugin(pc, func() error { return v.fn(pc, result) })
+		if err == nil {
+			continue
+		}
+		if errors.Is(err, errPluginAbandoned) {
+			return newPluginErrorWithContext(v.name, fmt.Sprintf("validator '%s' failed", v.name), err, ErrorCodePlugin, nil)
+		}
+
+		severity := DiagnosticSeverityError
+		var outcome *ValidationOutcome
+		if errors.As(err, &outcome) && outcome.Severity != "" {
+			severity = outcome.Severity
+		}
+		if severity != DiagnosticSeverityError && !policy.WarningsBlock {
+			result.addDiagnostic(v.name, severity, err.Error())
+			continue
+		}
+
+		failure := newPluginErrorWithContext(v.name, fmt.Sprintf("validator '%s' failed", v.name), err, ErrorCodePlugin, nil)
+		if !policy.ContinueOnFailure {
+			return failure
+		}
+		result.addDiagnostic(v.name, DiagnosticSeverityError, err.Error())
+		if firstFailure == nil {
+			firstFailure = failure
+		}
+	}
+	return firstFailure
+}
+
+// errPluginAbandoned marks a plugin call that had not returned when its context ended. The
+// plugin keeps running in the background, so the result it was given must be discarded.
+var errPluginAbandoned = errors.New("plugin did not return before its deadline")
+
+// pluginTimeoutGrace is how long a plugin may take to return after its context ends, so
+// plugins that honor cancellation report their own error.
+const pluginTimeoutGrace = 100 * time.Millisecond
+
+// callPlugin runs fn with pc.Context bounded by ExtractionConfig.PluginTimeout. Without a
+// timeout fn runs inline. With one, callPlugin stops waiting pluginTimeoutGrace after the
+// context ends and reports errPluginAbandoned; a panic in fn is re-raised on the calling
+// goroutine.
+func callPlugin(pc *PluginContext, fn func() error) error {
+	if pc.Config == nil || pc.Config.PluginTimeout <= 0 {
+		return fn()
+	}
+	parent := pc.Context()
+	ctx, cancel := context.WithTimeout(parent, pc.Config.PluginTimeout)
+	defer cancel()
+	pc.ctx = ctx
+
+	type outcome struct {
+		err      error
+		panicked bool
+		value    any
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- outcome{panicked: true, value: r}
+			}
+		}()
+		done <- outcome{err: fn()}
+	}()
+	var out outcome
+	select {
+	case out = <-done:
+	case <-ctx.Done():
+		select {
+		case out = <-done:
+		case <-time.After(pluginTimeoutGrace):
+			// pc.ctx is left cancelled: the abandoned plugin may still read it.
+			return fmt.Errorf("%w: %w", errPluginAbandoned, ctx.Err())
+		}
+	}
+	pc.ctx = parent
+	if out.panicked {
+		panic(out.value)
+	}
+	return out.err
+}
+
+// RegisterPostProcessorFunc registers a Go-native post processor that receives a PluginContext.
+// Unlike RegisterPostProcessor it does not require a cgo-exported callback.
+func RegisterPostProcessorFunc(name string, priority int32, fn PostProcessorFunc) error {
+	return defaultPluginRegistry.addPostProcessor(name, priority, fn)
+}
+
+// UnregisterPostProcessorFunc removes a Go-native post processor by name.
+func UnregisterPostProcessorFunc(name string) error {
+	return defaultPluginRegistry.removePostProcessor(name)
+}
+
+// RegisterValidatorFunc registers a Go-native validator that receives a PluginContext.
+// Unlike RegisterValidator it does not require a cgo-exported callback.
+func RegisterValidatorFunc(name string, priority int32, fn ValidatorFunc) error {
+	return defaultPluginRegistry.addValidator(name, priority, fn)
+}
+
+// UnregisterValidatorFunc removes a Go-native validator by name.
+func UnregisterValidatorFunc(name string) error {
+	return defaultPluginRegistry.removeValidator(name)
+}
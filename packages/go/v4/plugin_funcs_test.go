@@ -0,0 +1,299 @@
+package kreuzberg
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPluginRegistryRunsPostProcessorsThenValidatorsByPriority(t *testing.T) {
+	registry := &pluginRegistry{}
+	var order []string
+
+	record := func(name string) PostProcessorFunc {
+		return func(ctx *PluginContext, result *ExtractionResult) error {
+			order = append(order, name)
+			return nil
+		}
+	}
+	if err := registry.addPostProcessor("low", 1, record("low")); err != nil {
+		t.Fatalf("add low: %v", err)
+	}
+	if err := registry.addPostProcessor("high", 100, record("high")); err != nil {
+		t.Fatalf("add high: %v", err)
+	}
+	if err := registry.addValidator("validator", 1000, func(ctx *PluginContext, result *ExtractionResult) error {
+		order = append(order, "validator")
+		return nil
+	}); err != nil {
+		t.Fatalf("add validator: %v", err)
+	}
+
+	if err := registry.apply(newPluginContext(t.Context(), "", []byte("x"), "text/plain", nil), &ExtractionResult{}); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	want := []string{"high", "low", "validator"}
+	if len(order) != len(want) {
+		t.Fatalf("unexpected order: %v", order)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("unexpected order: %v", order)
+		}
+	}
+}
+
+func TestPluginRegistryPassesContext(t *testing.T) {
+	registry := &pluginRegistry{}
+	cfg := &ExtractionConfig{Labels: map[string]string{"tenant": "acme"}}
+
+	err := registry.addValidator("tenant-check", 0, func(ctx *PluginContext, result *ExtractionResult) error {
+		if ctx.Config != cfg {
+			return errors.New("config not forwarded")
+		}
+		if tenant, ok := ctx.Label("tenant"); !ok || tenant != "acme" {
+			return errors.New("tenant label missing")
+		}
+		if ctx.MimeType != "application/pdf" {
+			return errors.New("mime type not forwarded: " + ctx.MimeType)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("add validator: %v", err)
+	}
+
+	result := &ExtractionResult{MimeType: "application/pdf"}
+	if err := registry.apply(newPluginContext(t.Context(), "doc.pdf", nil, "", cfg), result); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+}
+
+func TestPluginRegistryWrapsFailuresAsPluginError(t *testing.T) {
+	registry := &pluginRegistry{}
+	if err := registry.addValidator("reject", 0, func(ctx *PluginContext, result *ExtractionResult) error {
+		return errors.New("content rejected")
+	}); err != nil {
+		t.Fatalf("add validator: %v", err)
+	}
+
+	err := registry.apply(newPluginContext(t.Context(), "", []byte("x"), "text/plain", nil), &ExtractionResult{})
+	var pluginErr *PluginError
+	if !errors.As(err, &pluginErr) {
+		t.Fatalf("expected PluginError, got %T", err)
+	}
+	if pluginErr.PluginName != "reject" {
+		t.Fatalf("unexpected plugin name: %s", pluginErr.PluginName)
+	}
+}
+
+func TestPluginRegistryRejectsDuplicatesAndUnknownRemovals(t *testing.T) {
+	registry := &pluginRegistry{}
+	noop := func(ctx *PluginContext, result *ExtractionResult) error { return nil }
+
+	if err := registry.addValidator("dup", 0, noop); err != nil {
+		t.Fatalf("add validator: %v", err)
+	}
+	if err := registry.addValidator("dup", 0, noop); err == nil {
+		t.Fatalf("expected duplicate registration error")
+	}
+	if err := registry.removeValidator("dup"); err != nil {
+		t.Fatalf("remove validator: %v", err)
+	}
+	if err := registry.removeValidator("dup"); err == nil {
+		t.Fatalf("expected error removing unknown validator")
+	}
+	if err := registry.addPostProcessor("", 0, noop); err == nil {
+		t.Fatalf("expected validation error for empty name")
+	}
+}
+
+func TestPluginContextDocumentHash(t *testing.T) {
+	data := []byte("hello kreuzberg")
+	sum := sha256.Sum256(data)
+	want := hex.EncodeToString(sum[:])
+
+	fromBytes := newPluginContext(t.Context(), "", data, "text/plain", nil)
+	got, err := fromBytes.DocumentHash()
+	if err != nil || got != want {
+		t.Fatalf("bytes hash = %q, %v; want %q", got, err, want)
+	}
+
+	path := filepath.Join(t.TempDir(), "doc.txt")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+	fromPath := newPluginContext(t.Context(), path, nil, "text/plain", nil)
+	got, err = fromPath.DocumentHash()
+	if err != nil || got != want {
+		t.Fatalf("path hash = %q, %v; want %q", got, err, want)
+	}
+}
+
+func TestValidatorWarningsAreRecordedAsDiagnostics(t *testing.T) {
+	registry := &pluginRegistry{}
+	if err := registry.addValidator("short-content", 0, func(ctx *PluginContext, result *ExtractionResult) error {
+		return WarnValidation("content is suspiciously short")
+	}); err != nil {
+		t.Fatalf("add validator: %v", err)
+	}
+	if err := registry.addValidator("wrapped", 0, func(ctx *PluginContext, result *ExtractionResult) error {
+		return fmt.Errorf("language check: %w", WarnValidation("no language detected"))
+	}); err != nil {
+		t.Fatalf("add validator: %v", err)
+	}
+
+	result := &ExtractionResult{Content: "x"}
+	if err := registry.apply(newPluginContext(t.Context(), "", []byte("x"), "text/plain", nil), result); err != nil {
+		t.Fatalf("warning should not fail the result: %v", err)
+	}
+	warnings := result.DiagnosticsBySeverity(DiagnosticSeverityWarning)
+	if len(warnings) != 2 || warnings[0].Source != "short-content" || warnings[1].Source != "wrapped" {
+		t.Fatalf("unexpected diagnostics: %+v", result.Diagnostics)
+	}
+}
+
+func TestValidationPolicyWarningsBlock(t *testing.T) {
+	registry := &pluginRegistry{}
+	if err := registry.addValidator("warn", 0, func(ctx *PluginContext, result *ExtractionResult) error {
+		return WarnValidation("heads up")
+	}); err != nil {
+		t.Fatalf("add validator: %v", err)
+	}
+
+	cfg := &ExtractionConfig{ValidationPolicy: &ValidationPolicy{WarningsBlock: true}}
+	if err := registry.apply(newPluginContext(t.Context(), "", []byte("x"), "text/plain", cfg), &ExtractionResult{}); err == nil {
+		t.Fatalf("expected warning to block the result")
+	}
+}
+
+func TestValidatorFailureShortCircuitsByDefault(t *testing.T) {
+	registry := &pluginRegistry{}
+	laterRan := false
+	if err := registry.addValidator("fail", 10, func(ctx *PluginContext, result *ExtractionResult) error {
+		return FailValidation("broken")
+	}); err != nil {
+		t.Fatalf("add validator: %v", err)
+	}
+	if err := registry.addValidator("later", 0, func(ctx *PluginContext, result *ExtractionResult) error {
+		laterRan = true
+		return FailValidation("also broken")
+	}); err != nil {
+		t.Fatalf("add validator: %v", err)
+	}
+
+	if err := registry.apply(newPluginContext(t.Context(), "", []byte("x"), "text/plain", nil), &ExtractionResult{}); err == nil {
+		t.Fatalf("expected failure")
+	}
+	if laterRan {
+		t.Fatalf("validators after a failure should not run by default")
+	}
+
+	laterRan = false
+	cfg := &ExtractionConfig{ValidationPolicy: &ValidationPolicy{ContinueOnFailure: true}}
+	result := &ExtractionResult{}
+	err := registry.apply(newPluginContext(t.Context(), "", []byte("x"), "text/plain", cfg), result)
+	var pluginErr *PluginError
+	if !errors.As(err, &pluginErr) || pluginErr.PluginName != "fail" {
+		t.Fatalf("expected first failure to be reported, got %v", err)
+	}
+	if !laterRan || len(result.DiagnosticsBySeverity(DiagnosticSeverityError)) != 2 {
+		t.Fatalf("expected all failures recorded, got %+v", result.Diagnostics)
+	}
+}
+
+type pluginCtxKey struct{}
+
+func TestPluginContextCarriesCallerContext(t *testing.T) {
+	client := NewClient(nil)
+	var got any
+	client.RegisterPostProcessor("ctx", 0, func(pc *PluginContext, result *ExtractionResult) error {
+		got = pc.Context().Value(pluginCtxKey{})
+		return nil
+	})
+	ctx := context.WithValue(t.Context(), pluginCtxKey{}, "tenant-a")
+	if _, err := client.ExtractBytes(ctx, []byte(testSRT), mimeSRT); err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if got != "tenant-a" {
+		t.Fatalf("expected the caller's context in the plugin, got %v", got)
+	}
+
+	var batchGot []any
+	if err := RegisterPostProcessorFunc("ctx-batch", 0, func(pc *PluginContext, result *ExtractionResult) error {
+		batchGot = append(batchGot, pc.Context().Value(pluginCtxKey{}))
+		return nil
+	}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	defer UnregisterPostProcessorFunc("ctx-batch")
+	if _, err := BatchExtractBytesWithContext(ctx, []BytesWithMime{{Data: []byte(testSRT), MimeType: mimeSRT}}, nil); err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+	if len(batchGot) != 1 || batchGot[0] != "tenant-a" {
+		t.Fatalf("expected the caller's context in batch plugins, got %v", batchGot)
+	}
+	if ctx := NewPluginContext("", nil, "", nil).Context(); ctx == nil || ctx.Err() != nil {
+		t.Fatalf("expected a background context outside of extractions")
+	}
+}
+
+func TestPluginTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	newClient := func(labels map[string]string) *Client {
+		client := NewClient(&ExtractionConfig{PluginTimeout: 20 * time.Millisecond, Labels: labels})
+		client.RegisterPostProcessor("remote", 0, func(pc *PluginContext, result *ExtractionResult) error {
+			if _, ok := pc.Context().Deadline(); !ok {
+				return errors.New("expected a deadline")
+			}
+			switch mode, _ := pc.Label("mode"); mode {
+			case "cooperative":
+				<-pc.Context().Done()
+				return pc.Context().Err()
+			case "stuck":
+				<-release
+			}
+			return nil
+		})
+		return client
+	}
+	var pluginErr *PluginError
+
+	if _, err := newClient(nil).ExtractBytes(t.Context(), []byte(testSRT), mimeSRT); err != nil {
+		t.Fatalf("expected a fast plugin to pass, got %v", err)
+	}
+	_, err := newClient(map[string]string{"mode": "cooperative"}).ExtractBytes(t.Context(), []byte(testSRT), mimeSRT)
+	if !errors.As(err, &pluginErr) || !errors.Is(err, context.DeadlineExceeded) || errors.Is(err, errPluginAbandoned) {
+		t.Fatalf("expected the cooperative plugin to report the deadline, got %v", err)
+	}
+
+	stuck := newClient(map[string]string{"mode": "stuck"})
+	_, err = stuck.ExtractBytes(t.Context(), []byte(testSRT), mimeSRT)
+	if !errors.As(err, &pluginErr) || !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, errPluginAbandoned) {
+		t.Fatalf("expected the stuck plugin to be abandoned, got %v", err)
+	}
+	results, err := stuck.BatchExtractBytes(t.Context(), []BytesWithMime{{Data: []byte(testSRT), MimeType: mimeSRT}})
+	if err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+	if r := results[0]; r.Success || r.Content != "" || r.MimeType != mimeSRT || r.Metadata.Error == nil {
+		t.Fatalf("expected the abandoned result to be replaced by a failed one, got %+v", r)
+	}
+}
+
+func TestPluginTimeoutRepanics(t *testing.T) {
+	pc := newPluginContext(t.Context(), "", nil, "", &ExtractionConfig{PluginTimeout: time.Second})
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Fatalf("expected the plugin panic on the caller, got %v", r)
+		}
+	}()
+	callPlugin(pc, func() error { panic("boom") })
+}
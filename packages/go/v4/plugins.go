@@ -23,12 +23,15 @@ bool kreuzberg_register_document_extractor(const char *name, DocumentExtractorCa
 bool kreuzberg_unregister_document_extractor(const char *name);
 char *kreuzberg_list_document_extractors(void);
 bool kreuzberg_clear_document_extractors(void);
+void kreuzberg_set_plugin_timeout_ms(uint64_t timeout_ms);
+uint64_t kreuzberg_plugin_timeout_ms(void);
 void kreuzberg_free_string(char *ptr);
 */
 import "C"
 
 import (
 	"encoding/json"
+	"time"
 	"unsafe"
 )
 
@@ -44,8 +47,8 @@ func RegisterOCRBackend(name string, callback C.OcrBackendCallback) error {
 		return newValidationErrorWithContext("ocr backend callback cannot be nil", nil, ErrorCodeValidation, nil)
 	}
 
-	cName := C.CString(name)
-	defer C.free(unsafe.Pointer(cName))
+	cName := newCString(name)
+	defer freeCBuffer(unsafe.Pointer(cName))
 
 	if ok := C.kreuzberg_register_ocr_backend(cName, callback); !bool(ok) {
 		return lastError()
@@ -65,8 +68,8 @@ func RegisterPostProcessor(name string, priority int32, callback C.PostProcessor
 		return newValidationErrorWithContext("post processor callback cannot be nil", nil, ErrorCodeValidation, nil)
 	}
 
-	cName := C.CString(name)
-	defer C.free(unsafe.Pointer(cName))
+	cName := newCString(name)
+	defer freeCBuffer(unsafe.Pointer(cName))
 
 	if ok := C.kreuzberg_register_post_processor(cName, callback, C.int32_t(priority)); !bool(ok) {
 		return lastError()
@@ -80,8 +83,8 @@ func UnregisterPostProcessor(name string) error {
 		return newValidationErrorWithContext("post processor name cannot be empty", nil, ErrorCodeValidation, nil)
 	}
 
-	cName := C.CString(name)
-	defer C.free(unsafe.Pointer(cName))
+	cName := newCString(name)
+	defer freeCBuffer(unsafe.Pointer(cName))
 
 	if ok := C.kreuzberg_unregister_post_processor(cName); !bool(ok) {
 		return lastError()
@@ -98,8 +101,8 @@ func RegisterValidator(name string, priority int32, callback C.ValidatorCallback
 		return newValidationErrorWithContext("validator callback cannot be nil", nil, ErrorCodeValidation, nil)
 	}
 
-	cName := C.CString(name)
-	defer C.free(unsafe.Pointer(cName))
+	cName := newCString(name)
+	defer freeCBuffer(unsafe.Pointer(cName))
 
 	if ok := C.kreuzberg_register_validator(cName, callback, C.int32_t(priority)); !bool(ok) {
 		return lastError()
@@ -113,8 +116,8 @@ func UnregisterValidator(name string) error {
 		return newValidationErrorWithContext("validator name cannot be empty", nil, ErrorCodeValidation, nil)
 	}
 
-	cName := C.CString(name)
-	defer C.free(unsafe.Pointer(cName))
+	cName := newCString(name)
+	defer freeCBuffer(unsafe.Pointer(cName))
 
 	if ok := C.kreuzberg_unregister_validator(cName); !bool(ok) {
 		return lastError()
@@ -124,11 +127,11 @@ func UnregisterValidator(name string) error {
 
 // ListValidators returns names of all registered validators.
 func ListValidators() ([]string, error) {
-	listPtr := C.kreuzberg_list_validators()
+	listPtr := trackFFIAlloc(FFIResourceString, C.kreuzberg_list_validators())
 	if listPtr == nil {
 		return []string{}, nil
 	}
-	defer C.kreuzberg_free_string(listPtr)
+	defer freeNativeString(listPtr)
 
 	jsonStr := C.GoString(listPtr)
 	var validators []string
@@ -148,11 +151,11 @@ func ClearValidators() error {
 
 // ListPostProcessors returns names of all registered post-processors.
 func ListPostProcessors() ([]string, error) {
-	listPtr := C.kreuzberg_list_post_processors()
+	listPtr := trackFFIAlloc(FFIResourceString, C.kreuzberg_list_post_processors())
 	if listPtr == nil {
 		return []string{}, nil
 	}
-	defer C.kreuzberg_free_string(listPtr)
+	defer freeNativeString(listPtr)
 
 	jsonStr := C.GoString(listPtr)
 	var processors []string
@@ -176,8 +179,8 @@ func UnregisterOCRBackend(name string) error {
 		return newValidationErrorWithContext("ocr backend name cannot be empty", nil, ErrorCodeValidation, nil)
 	}
 
-	cName := C.CString(name)
-	defer C.free(unsafe.Pointer(cName))
+	cName := newCString(name)
+	defer freeCBuffer(unsafe.Pointer(cName))
 
 	if ok := C.kreuzberg_unregister_ocr_backend(cName); !bool(ok) {
 		return lastError()
@@ -187,11 +190,11 @@ func UnregisterOCRBackend(name string) error {
 
 // ListOCRBackends returns names of all registered OCR backends.
 func ListOCRBackends() ([]string, error) {
-	listPtr := C.kreuzberg_list_ocr_backends()
+	listPtr := trackFFIAlloc(FFIResourceString, C.kreuzberg_list_ocr_backends())
 	if listPtr == nil {
 		return []string{}, nil
 	}
-	defer C.kreuzberg_free_string(listPtr)
+	defer freeNativeString(listPtr)
 
 	jsonStr := C.GoString(listPtr)
 	var backends []string
@@ -211,11 +214,11 @@ func ClearOCRBackends() error {
 
 // ListDocumentExtractors returns names of all registered document extractors.
 func ListDocumentExtractors() ([]string, error) {
-	listPtr := C.kreuzberg_list_document_extractors()
+	listPtr := trackFFIAlloc(FFIResourceString, C.kreuzberg_list_document_extractors())
 	if listPtr == nil {
 		return []string{}, nil
 	}
-	defer C.kreuzberg_free_string(listPtr)
+	defer freeNativeString(listPtr)
 
 	jsonStr := C.GoString(listPtr)
 	var extractors []string
@@ -231,12 +234,13 @@ func UnregisterDocumentExtractor(name string) error {
 		return newValidationErrorWithContext("document extractor name cannot be empty", nil, ErrorCodeValidation, nil)
 	}
 
-	cName := C.CString(name)
-	defer C.free(unsafe.Pointer(cName))
+	cName := newCString(name)
+	defer freeCBuffer(unsafe.Pointer(cName))
 
 	if ok := C.kreuzberg_unregister_document_extractor(cName); !bool(ok) {
 		return lastError()
 	}
+	forgetGoExtractor(name)
 	return nil
 }
 
@@ -245,5 +249,25 @@ func ClearDocumentExtractors() error {
 	if ok := C.kreuzberg_clear_document_extractors(); !bool(ok) {
 		return lastError()
 	}
+	forgetAllGoExtractors()
 	return nil
 }
+
+// SetNativePluginTimeout sets a hard timeout for every call into a plugin registered with a C
+// callback (OCR backends, post processors, validators and document extractors). A callback
+// that has not returned in time fails its extraction with a PluginError. The native library
+// cannot interrupt the callback: it keeps running and its result is discarded, so the timeout
+// is a backstop for plugins that ignore ExtractionConfig.PluginTimeout. The timeout is
+// process-wide and rounded up to whole milliseconds; zero disables it, which is the default.
+func SetNativePluginTimeout(timeout time.Duration) error {
+	if timeout < 0 {
+		return newValidationErrorWithContext("native plugin timeout must not be negative", nil, ErrorCodeValidation, nil)
+	}
+	C.kreuzberg_set_plugin_timeout_ms(C.uint64_t((timeout + time.Millisecond - 1) / time.Millisecond))
+	return nil
+}
+
+// NativePluginTimeout returns the timeout set with SetNativePluginTimeout.
+func NativePluginTimeout() time.Duration {
+	return time.Duration(C.kreuzberg_plugin_timeout_ms()) * time.Millisecond
+}
@@ -26,6 +26,19 @@ func TestRegisterValidatorLifecycle(t *testing.T) {
 	}
 }
 
+func TestSetNativePluginTimeout(t *testing.T) {
+	defer SetNativePluginTimeout(0)
+	if err := SetNativePluginTimeout(-time.Second); err == nil {
+		t.Fatalf("expected a negative timeout to be rejected")
+	}
+	if err := SetNativePluginTimeout(1500 * time.Microsecond); err != nil {
+		t.Fatalf("set timeout: %v", err)
+	}
+	if got := NativePluginTimeout(); got != 2*time.Millisecond {
+		t.Fatalf("expected the timeout to be rounded up to 2ms, got %v", got)
+	}
+}
+
 func TestRegisterOCRBackend(t *testing.T) {
 	name := fmt.Sprintf("go-ocr-%d", time.Now().UnixNano())
 	if err := RegisterOCRBackend(name, testOcrBackendCallback); err != nil {
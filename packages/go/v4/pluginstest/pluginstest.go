@@ -0,0 +1,127 @@
+// Package pluginstest provides harnesses for unit testing Go-native Kreuzberg plugins.
+//
+// A Harness invokes validators and post-processors against fixture results exactly as the
+// extraction pipeline does: results are round-tripped through the same JSON shape the native
+// library produces, and plugins run in pipeline order (post-processors, then validators, by
+// descending priority). No extraction is performed, so fixtures can be built by hand or loaded
+// from JSON captured with kreuzberg.ResultToJSON.
+//
+//	func TestRejectsEmptyContent(t *testing.T) {
+//		h := pluginstest.New(nil)
+//		h.MustRegisterValidator(t, "non-empty", 0, myValidator)
+//		_, err := h.Run(pluginstest.Document{MimeType: "text/plain"}, pluginstest.TextResult("", "text/plain"))
+//		if err == nil {
+//			t.Fatal("expected empty content to be rejected")
+//		}
+//	}
+package pluginstest
+
+import (
+	"os"
+	"testing"
+
+	kreuzberg "github.com/kreuzberg-dev/kreuzberg/packages/go/v4"
+)
+
+// Document describes the source document a fixture result was extracted from.
+type Document struct {
+	// Path is the document path reported to plugins (optional).
+	Path string
+	// Data is the raw document content used for PluginContext.DocumentHash (optional).
+	Data []byte
+	// MimeType is the MIME type reported to plugins. Defaults to the fixture result's MIME type.
+	MimeType string
+}
+
+// Harness runs Go plugins registered on it against fixture results.
+type Harness struct {
+	client *kreuzberg.Client
+}
+
+// New creates a Harness whose plugins see config as PluginContext.Config.
+func New(config *kreuzberg.ExtractionConfig) *Harness {
+	return &Harness{client: kreuzberg.NewClient(config)}
+}
+
+// Client returns the underlying client so plugins can be registered exactly as in production code.
+func (h *Harness) Client() *kreuzberg.Client {
+	return h.client
+}
+
+// MustRegisterValidator registers a validator on the harness, failing the test on error.
+func (h *Harness) MustRegisterValidator(t testing.TB, name string, priority int32, fn kreuzberg.ValidatorFunc) {
+	t.Helper()
+	if err := h.client.RegisterValidator(name, priority, fn); err != nil {
+		t.Fatalf("register validator %q: %v", name, err)
+	}
+}
+
+// MustRegisterPostProcessor registers a post processor on the harness, failing the test on error.
+func (h *Harness) MustRegisterPostProcessor(t testing.TB, name string, priority int32, fn kreuzberg.PostProcessorFunc) {
+	t.Helper()
+	if err := h.client.RegisterPostProcessor(name, priority, fn); err != nil {
+		t.Fatalf("register post processor %q: %v", name, err)
+	}
+}
+
+// Run normalizes fixture through the pipeline's JSON representation and applies the registered
+// plugins to it. The fixture itself is never modified; the processed copy is returned.
+func (h *Harness) Run(doc Document, fixture *kreuzberg.ExtractionResult) (*kreuzberg.ExtractionResult, error) {
+	result, err := normalize(fixture)
+	if err != nil {
+		return nil, err
+	}
+
+	mimeType := doc.MimeType
+	if mimeType == "" {
+		mimeType = result.MimeType
+	}
+	pc := kreuzberg.NewPluginContext(doc.Path, doc.Data, mimeType, h.client.Config())
+	if err := h.client.ApplyPlugins(pc, result); err != nil {
+		return result, err
+	}
+	return normalize(result)
+}
+
+// MustRun is like Run but fails the test if a plugin returns an error.
+func (h *Harness) MustRun(t testing.TB, doc Document, fixture *kreuzberg.ExtractionResult) *kreuzberg.ExtractionResult {
+	t.Helper()
+	result, err := h.Run(doc, fixture)
+	if err != nil {
+		t.Fatalf("run plugins: %v", err)
+	}
+	return result
+}
+
+// TextResult builds a minimal successful fixture result with the given content and MIME type.
+func TextResult(content string, mimeType string) *kreuzberg.ExtractionResult {
+	return &kreuzberg.ExtractionResult{
+		Content:  content,
+		MimeType: mimeType,
+		Tables:   []kreuzberg.Table{},
+		Success:  true,
+	}
+}
+
+// LoadResult reads a JSON fixture (as produced by kreuzberg.ResultToJSON) from path.
+func LoadResult(t testing.TB, path string) *kreuzberg.ExtractionResult {
+	t.Helper()
+	// #nosec G304 -- test fixture path supplied by the test author
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read fixture %s: %v", path, err)
+	}
+	result, err := kreuzberg.ResultFromJSON(string(data))
+	if err != nil {
+		t.Fatalf("decode fixture %s: %v", path, err)
+	}
+	return result
+}
+
+func normalize(result *kreuzberg.ExtractionResult) (*kreuzberg.ExtractionResult, error) {
+	raw, err := kreuzberg.ResultToJSON(result)
+	if err != nil {
+		return nil, err
+	}
+	return kreuzberg.ResultFromJSON(raw)
+}
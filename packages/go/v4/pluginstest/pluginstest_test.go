@@ -0,0 +1,75 @@
+package pluginstest_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	kreuzberg "github.com/kreuzberg-dev/kreuzberg/packages/go/v4"
+	"github.com/kreuzberg-dev/kreuzberg/packages/go/v4/pluginstest"
+)
+
+func TestHarnessRunsPluginsInPipelineOrder(t *testing.T) {
+	h := pluginstest.New(nil)
+	var order []string
+
+	h.MustRegisterValidator(t, "validator", 100, func(ctx *kreuzberg.PluginContext, r *kreuzberg.ExtractionResult) error {
+		order = append(order, "validator")
+		if r.Content != "HELLO" {
+			return errors.New("validator ran before post processor")
+		}
+		return nil
+	})
+	h.MustRegisterPostProcessor(t, "upper", 0, func(ctx *kreuzberg.PluginContext, r *kreuzberg.ExtractionResult) error {
+		order = append(order, "upper")
+		r.Content = "HELLO"
+		return nil
+	})
+
+	fixture := pluginstest.TextResult("hello", "text/plain")
+	result := h.MustRun(t, pluginstest.Document{}, fixture)
+
+	if result.Content != "HELLO" {
+		t.Fatalf("post processor output missing: %q", result.Content)
+	}
+	if fixture.Content != "hello" {
+		t.Fatalf("fixture was modified")
+	}
+	if len(order) != 2 || order[0] != "upper" || order[1] != "validator" {
+		t.Fatalf("unexpected order: %v", order)
+	}
+}
+
+func TestHarnessSurfacesValidatorFailures(t *testing.T) {
+	h := pluginstest.New(&kreuzberg.ExtractionConfig{Labels: map[string]string{"tenant": "acme"}})
+	h.MustRegisterValidator(t, "tenant", 0, func(ctx *kreuzberg.PluginContext, r *kreuzberg.ExtractionResult) error {
+		if tenant, _ := ctx.Label("tenant"); tenant == "acme" {
+			return kreuzberg.FailValidation("acme rejects everything")
+		}
+		return nil
+	})
+
+	_, err := h.Run(pluginstest.Document{MimeType: "application/pdf"}, pluginstest.TextResult("x", "application/pdf"))
+	var pluginErr *kreuzberg.PluginError
+	if !errors.As(err, &pluginErr) || pluginErr.PluginName != "tenant" {
+		t.Fatalf("expected PluginError from tenant validator, got %v", err)
+	}
+}
+
+func TestLoadResultNormalizesAdditionalMetadata(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixture.json")
+	fixture := `{"content":"x","mime_type":"text/plain","metadata":{"custom_field":"v"},"tables":[],"success":true}`
+	if err := os.WriteFile(path, []byte(fixture), 0o600); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+
+	h := pluginstest.New(nil)
+	h.MustRegisterValidator(t, "custom", 0, func(ctx *kreuzberg.PluginContext, r *kreuzberg.ExtractionResult) error {
+		if _, ok := r.Metadata.Additional["custom_field"]; !ok {
+			return errors.New("additional metadata not decoded")
+		}
+		return nil
+	})
+	h.MustRun(t, pluginstest.Document{}, pluginstest.LoadResult(t, path))
+}
@@ -0,0 +1,134 @@
+package kreuzberg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// QAConfig generates candidate question/answer pairs over the sections of extracted content,
+// e.g. as synthetic training data for retrieval models. The pairs are stored in the metadata of
+// the chunk holding each answer (see ChunkMetadata.QAPairs), so chunking must be enabled;
+// results without chunks are left unchanged and the generator is not called.
+type QAConfig struct {
+	// Generate produces the pairs for one section, typically by prompting an LLM. It is called
+	// once per non-empty section, in document order.
+	Generate QAGenerator `json:"-"`
+	// MaxPairsPerSection keeps at most this many pairs per section (0 keeps all).
+	MaxPairsPerSection int `json:"max_pairs_per_section,omitempty"`
+}
+
+// QAGenerator produces candidate question/answer pairs for a section of a document. The
+// context is cancelled when the extraction is.
+type QAGenerator func(ctx context.Context, section QASection) ([]QAPair, error)
+
+// QASection is a section of the content: the text between a Markdown heading and the next one.
+// Text before the first heading, or the whole content when there are no headings, forms a
+// section with an empty HeadingPath.
+type QASection struct {
+	// HeadingPath lists the headings the section is nested under, outermost first; the last one
+	// is the section's own heading.
+	HeadingPath []string
+	// Text is the section body, without its heading.
+	Text string
+	// ByteStart and ByteEnd locate Text in the result's Content.
+	ByteStart uint64
+	ByteEnd   uint64
+	// Page is the 1-indexed page the section starts on (0 when unknown).
+	Page uint64
+}
+
+// QAPair is a generated question with its answer.
+type QAPair struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+	// HeadingPath is the HeadingPath of the section the pair was generated from.
+	HeadingPath []string `json:"heading_path,omitempty"`
+}
+
+// GenerateQAPairs runs cfg.Generate over the sections of result.Content and stores the pairs in
+// the chunk metadata, for results extracted without ExtractionConfig.QA. A pair goes to the
+// chunk containing its answer when the answer is quoted verbatim from the section, and to the
+// chunk the section starts in otherwise.
+func GenerateQAPairs(ctx context.Context, result *ExtractionResult, cfg *QAConfig) error {
+	if result == nil || cfg == nil || cfg.Generate == nil || len(result.Chunks) == 0 {
+		return nil
+	}
+	var boundaries []PageBoundary
+	if result.Metadata.PageStructure != nil {
+		boundaries = result.Metadata.PageStructure.Boundaries
+	}
+	for _, section := range qaSections(result.Content) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		section.Page = pageForOffset(boundaries, int(section.ByteStart))
+		pairs, err := cfg.Generate(ctx, section)
+		if err != nil {
+			return newPluginErrorWithContext("qa_generator", fmt.Sprintf("question-answer generation failed for section %q", strings.Join(section.HeadingPath, " > ")), err, ErrorCodePlugin, nil)
+		}
+		if cfg.MaxPairsPerSection > 0 && len(pairs) > cfg.MaxPairsPerSection {
+			pairs = pairs[:cfg.MaxPairsPerSection]
+		}
+		for _, pair := range pairs {
+			pair.HeadingPath = section.HeadingPath
+			offset := int(section.ByteStart)
+			if i := strings.Index(section.Text, strings.TrimSpace(pair.Answer)); pair.Answer != "" && i >= 0 {
+				offset += i
+			}
+			if chunk := chunkForOffset(result.Chunks, offset); chunk != nil {
+				chunk.Metadata.QAPairs = append(chunk.Metadata.QAPairs, pair)
+			}
+		}
+	}
+	return nil
+}
+
+// qaSections splits content at its ATX headings.
+func qaSections(content string) []QASection {
+	headings := markdownHeadings(content)
+	var sections []QASection
+	add := func(start, end int, path []string) {
+		text := content[start:end]
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" {
+			return
+		}
+		start += strings.Index(text, trimmed)
+		sections = append(sections, QASection{HeadingPath: path, Text: trimmed, ByteStart: uint64(start), ByteEnd: uint64(start + len(trimmed))})
+	}
+	start := 0
+	var path []string
+	for _, h := range headings {
+		add(start, h.offset, path)
+		path = headingPathAt(headings, h.offset)
+		start = h.offset
+		if eol := strings.IndexByte(content[start:], '\n'); eol >= 0 {
+			start += eol + 1
+		} else {
+			start = len(content)
+		}
+	}
+	add(start, len(content), path)
+	return sections
+}
+
+// chunkForOffset returns the chunk containing offset, or the last chunk starting before it when
+// offset falls between chunks.
+func chunkForOffset(chunks []Chunk, offset int) *Chunk {
+	var found *Chunk
+	for i := range chunks {
+		c := &chunks[i]
+		if c.Metadata.ByteStart > uint64(offset) {
+			break
+		}
+		found = c
+		if uint64(offset) < c.Metadata.ByteEnd {
+			break
+		}
+	}
+	if found == nil && len(chunks) > 0 {
+		found = &chunks[0]
+	}
+	return found
+}
@@ -0,0 +1,78 @@
+package kreuzberg
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGenerateQAPairs(t *testing.T) {
+	content := "Preface text.\n\n# Guide\n\n## Install\n\nRun the installer. Then reboot the machine.\n\n## Empty\n\n## Use\n\nOpen the app."
+	install := strings.Index(content, "Run the")
+	reboot := strings.Index(content, "Then reboot")
+	result := &ExtractionResult{Content: content, Chunks: []Chunk{
+		{Metadata: ChunkMetadata{ByteStart: 0, ByteEnd: uint64(reboot)}},
+		{Metadata: ChunkMetadata{ByteStart: uint64(reboot), ByteEnd: uint64(len(content))}},
+	}}
+
+	var sections []QASection
+	cfg := &QAConfig{MaxPairsPerSection: 2, Generate: func(ctx context.Context, section QASection) ([]QAPair, error) {
+		sections = append(sections, section)
+		if section.Text == "Run the installer. Then reboot the machine." {
+			return []QAPair{
+				{Question: "What comes after installing?", Answer: "reboot the machine"},
+				{Question: "What is run first?", Answer: "the installer, as the guide says"},
+				{Question: "Dropped by the cap?", Answer: "yes"},
+			}, nil
+		}
+		return nil, nil
+	}}
+	if err := GenerateQAPairs(context.Background(), result, cfg); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if len(sections) != 3 || sections[0].HeadingPath != nil || sections[0].Text != "Preface text." {
+		t.Fatalf("unexpected sections %+v", sections)
+	}
+	if s := sections[1]; strings.Join(s.HeadingPath, " > ") != "Guide > Install" || s.ByteStart != uint64(install) || content[s.ByteStart:s.ByteEnd] != s.Text {
+		t.Fatalf("unexpected install section %+v", s)
+	}
+	if s := sections[2]; strings.Join(s.HeadingPath, " > ") != "Guide > Use" || s.Text != "Open the app." {
+		t.Fatalf("unexpected use section %+v", s)
+	}
+
+	// The verbatim answer lands in the chunk holding it; the paraphrased one where the section starts.
+	first, second := result.Chunks[0].Metadata.QAPairs, result.Chunks[1].Metadata.QAPairs
+	if len(first) != 1 || first[0].Question != "What is run first?" || strings.Join(first[0].HeadingPath, " > ") != "Guide > Install" {
+		t.Fatalf("unexpected first chunk pairs %+v", first)
+	}
+	if len(second) != 1 || second[0].Answer != "reboot the machine" {
+		t.Fatalf("unexpected second chunk pairs %+v", second)
+	}
+
+	boom := errors.New("model unavailable")
+	cfg.Generate = func(context.Context, QASection) ([]QAPair, error) { return nil, boom }
+	var pluginErr *PluginError
+	if err := GenerateQAPairs(context.Background(), result, cfg); !errors.As(err, &pluginErr) || !errors.Is(err, boom) {
+		t.Fatalf("expected a plugin error wrapping the generator failure, got %v", err)
+	}
+	if err := GenerateQAPairs(context.Background(), &ExtractionResult{Content: content}, cfg); err != nil {
+		t.Fatalf("expected results without chunks to be skipped, got %v", err)
+	}
+}
+
+func TestExtractWithQAConfig(t *testing.T) {
+	config := &ExtractionConfig{
+		Chunking: &ChunkingConfig{MaxChars: IntPtr(20)},
+		QA: &QAConfig{Generate: func(ctx context.Context, section QASection) ([]QAPair, error) {
+			return []QAPair{{Question: "Who is addressed?", Answer: "General Kenobi"}}, nil
+		}},
+	}
+	result, err := ExtractBytesSync([]byte(testSRT), mimeSRT, config)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if len(result.Chunks) != 2 || len(result.Chunks[0].Metadata.QAPairs) != 0 || len(result.Chunks[1].Metadata.QAPairs) != 1 {
+		t.Fatalf("expected the pair on the chunk holding the answer, got %+v", result.Chunks)
+	}
+}
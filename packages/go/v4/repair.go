@@ -0,0 +1,287 @@
+package kreuzberg
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// FallbackRepair repairs mildly corrupted PDFs and ZIP-based documents (Office Open XML,
+// OpenDocument, EPUB, iWork) and extracts the repaired copy; see RepairDocument. Each repair is
+// recorded as a diagnostic. Configure it ahead of costlier strategies, e.g.
+// {"*": {FallbackRepair, FallbackForceOCR}}.
+const FallbackRepair FallbackStrategy = "repair"
+
+// RepairDocument makes a best-effort repair of a PDF or ZIP-based document and returns the
+// repaired copy with a description of each repair. mimeType, if given, selects the format;
+// otherwise it is detected from the leading bytes.
+//
+// PDFs get a new cross-reference table built by scanning for objects, which recovers files with
+// truncated or missing xref tables, trailers and end markers; objects cut off by truncation are
+// left out. Objects stored inside object streams cannot be recovered this way. ZIP archives get
+// a new central directory built from the local file headers; entries that are truncated or fail
+// their checksum are dropped. A document without recoverable content returns a ParsingError.
+func RepairDocument(data []byte, mimeType string) ([]byte, []string, error) {
+	report := triage(data, expectedContainer(mimeType, ""))
+	switch report.Format {
+	case "pdf":
+		return repairPDF(data, report)
+	case "zip":
+		return repairZIP(data)
+	}
+	return nil, nil, newParsingErrorWithContext(fmt.Sprintf("cannot repair %s documents", report.Format), nil, ErrorCodeParsing, nil)
+}
+
+var (
+	pdfObjectPattern  = regexp.MustCompile(`(?:^|[\r\n\s])(\d+)\s+(\d+)\s+obj\b`)
+	pdfCatalogPattern = regexp.MustCompile(`/Type\s*/Catalog\b`)
+	pdfRootPattern    = regexp.MustCompile(`/Root\s+\d+\s+\d+\s+R`)
+	pdfInfoPattern    = regexp.MustCompile(`/Info\s+\d+\s+\d+\s+R`)
+	pdfEncryptPattern = regexp.MustCompile(`/Encrypt\s+\d+\s+\d+\s+R`)
+	pdfIDPattern      = regexp.MustCompile(`/ID\s*\[[^\]]*\]`)
+)
+
+type pdfObjectRef struct {
+	offset     int64
+	generation int
+}
+
+// repairPDF appends a cross-reference section covering every complete object in data, with a
+// trailer carrying over the references of the last one found.
+func repairPDF(data []byte, report *TriageReport) ([]byte, []string, error) {
+	objects := map[int]pdfObjectRef{}
+	matches := pdfObjectPattern.FindAllSubmatchIndex(data, -1)
+	var repairs []string
+	for i, m := range matches {
+		number, err1 := strconv.Atoi(string(data[m[2]:m[3]]))
+		generation, err2 := strconv.Atoi(string(data[m[4]:m[5]]))
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		end := len(data)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		if !bytes.Contains(data[m[1]:end], []byte("endobj")) {
+			repairs = append(repairs, fmt.Sprintf("dropped object %d, which is cut off at byte %d", number, m[2]))
+			continue
+		}
+		// Later definitions override earlier ones, as in incremental updates.
+		objects[number] = pdfObjectRef{offset: int64(m[2]), generation: generation}
+	}
+	if len(objects) == 0 {
+		return nil, nil, newParsingErrorWithContext("no PDF objects to rebuild the cross-reference table from", nil, ErrorCodeParsing, nil)
+	}
+
+	numbers := slices.Sorted(maps.Keys(objects))
+	root := lastMatch(pdfRootPattern, data)
+	if root == nil {
+		for _, number := range numbers {
+			ref := objects[number]
+			if loc := pdfCatalogPattern.FindIndex(data[ref.offset:]); loc != nil && !bytes.Contains(data[ref.offset:ref.offset+int64(loc[0])], []byte("endobj")) {
+				root = fmt.Appendf(nil, "/Root %d %d R", number, ref.generation)
+				break
+			}
+		}
+	}
+	if root == nil {
+		return nil, nil, newParsingErrorWithContext("the PDF catalog cannot be located", nil, ErrorCodeParsing, nil)
+	}
+
+	var out bytes.Buffer
+	out.Write(data)
+	if data[len(data)-1] != '\n' {
+		out.WriteByte('\n')
+	}
+	xref := out.Len()
+	size := numbers[len(numbers)-1] + 1
+	fmt.Fprintf(&out, "xref\n0 %d\n", size)
+	for number := range size {
+		if ref, ok := objects[number]; ok {
+			fmt.Fprintf(&out, "%010d %05d n \n", ref.offset, ref.generation)
+		} else {
+			out.WriteString("0000000000 65535 f \n")
+		}
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d %s", size, root)
+	for _, p := range []*regexp.Regexp{pdfInfoPattern, pdfEncryptPattern, pdfIDPattern} {
+		if ref := lastMatch(p, data); ref != nil {
+			out.WriteByte(' ')
+			out.Write(ref)
+		}
+	}
+	fmt.Fprintf(&out, " >>\nstartxref\n%d\n%%%%EOF\n", xref)
+
+	for _, a := range report.Anomalies {
+		repairs = append(repairs, "found: "+a.Message)
+	}
+	repairs = append(repairs, fmt.Sprintf("rebuilt the cross-reference table from %d objects", len(objects)))
+	return out.Bytes(), repairs, nil
+}
+
+func lastMatch(p *regexp.Regexp, data []byte) []byte {
+	all := p.FindAll(data, -1)
+	if len(all) == 0 {
+		return nil
+	}
+	return all[len(all)-1]
+}
+
+// repairZIP rewrites the archive from its local file headers, keeping the entries that are
+// complete and pass their checksum.
+func repairZIP(data []byte) ([]byte, []string, error) {
+	var (
+		out     bytes.Buffer
+		repairs []string
+		kept    int
+	)
+	zw := zip.NewWriter(&out)
+	size := int64(len(data))
+	pos := int64(bytes.Index(data, zipMagic))
+	for pos >= 0 && pos+30 <= size && bytes.HasPrefix(data[pos:], zipMagic) {
+		flags := binary.LittleEndian.Uint16(data[pos+6:])
+		fh := &zip.FileHeader{
+			Method:             binary.LittleEndian.Uint16(data[pos+8:]),
+			Modified:           msdosTime(binary.LittleEndian.Uint16(data[pos+12:]), binary.LittleEndian.Uint16(data[pos+10:])),
+			CRC32:              binary.LittleEndian.Uint32(data[pos+14:]),
+			CompressedSize64:   uint64(binary.LittleEndian.Uint32(data[pos+18:])),
+			UncompressedSize64: uint64(binary.LittleEndian.Uint32(data[pos+22:])),
+		}
+		nameLen := int64(binary.LittleEndian.Uint16(data[pos+26:]))
+		start := pos + 30 + nameLen + int64(binary.LittleEndian.Uint16(data[pos+28:]))
+		if start > size {
+			repairs = append(repairs, fmt.Sprintf("dropped the entry at byte %d, whose header is cut off", pos))
+			break
+		}
+		fh.Name = string(data[pos+30 : pos+30+nameLen])
+		end := start + int64(fh.CompressedSize64)
+		next := end
+		if flags&0x8 != 0 {
+			var ok bool
+			if end, next, ok = findDataDescriptor(data, start, fh); !ok {
+				repairs = append(repairs, fmt.Sprintf("dropped entry %q, which is cut off", fh.Name))
+				break
+			}
+		}
+		if end > size {
+			repairs = append(repairs, fmt.Sprintf("dropped entry %q, which is cut off", fh.Name))
+			break
+		}
+		raw := data[start:end]
+		if err := checkZIPEntry(fh, raw); err != nil {
+			repairs = append(repairs, fmt.Sprintf("dropped corrupt entry %q: %v", fh.Name, err))
+		} else if w, err := zw.CreateRaw(fh); err == nil {
+			w.Write(raw)
+			kept++
+		}
+		pos = next
+	}
+	if err := zw.Close(); err != nil || kept == 0 {
+		return nil, nil, newParsingErrorWithContext("no ZIP entries could be recovered", err, ErrorCodeParsing, nil)
+	}
+	repairs = append(repairs, fmt.Sprintf("rebuilt the central directory from %d local file headers", kept))
+	return out.Bytes(), repairs, nil
+}
+
+// findDataDescriptor locates the data descriptor of an entry whose sizes follow its data,
+// checking the recorded size against the signature's position to skip signatures that occur in
+// the data itself. It fills in fh and returns where the data ends and the next header starts.
+func findDataDescriptor(data []byte, start int64, fh *zip.FileHeader) (end, next int64, ok bool) {
+	for from := start; ; {
+		i := bytes.Index(data[from:], []byte("PK\x07\x08"))
+		if i < 0 {
+			return 0, 0, false
+		}
+		end = from + int64(i)
+		if end+16 <= int64(len(data)) && int64(binary.LittleEndian.Uint32(data[end+8:])) == end-start {
+			fh.CRC32 = binary.LittleEndian.Uint32(data[end+4:])
+			fh.CompressedSize64 = uint64(end - start)
+			fh.UncompressedSize64 = uint64(binary.LittleEndian.Uint32(data[end+12:]))
+			return end, end + 16, true
+		}
+		from = end + 4
+	}
+}
+
+// checkZIPEntry decompresses raw and verifies its size and checksum.
+func checkZIPEntry(fh *zip.FileHeader, raw []byte) error {
+	var r io.Reader
+	switch fh.Method {
+	case zip.Store:
+		r = bytes.NewReader(raw)
+	case zip.Deflate:
+		fr := flate.NewReader(bytes.NewReader(raw))
+		defer fr.Close()
+		r = fr
+	default:
+		return fmt.Errorf("unsupported compression method %d", fh.Method)
+	}
+	h := crc32.NewIEEE()
+	n, err := io.Copy(h, io.LimitReader(r, int64(fh.UncompressedSize64)+1))
+	switch {
+	case err != nil:
+		return err
+	case uint64(n) != fh.UncompressedSize64:
+		return fmt.Errorf("size %d, expected %d", n, fh.UncompressedSize64)
+	case h.Sum32() != fh.CRC32:
+		return fmt.Errorf("checksum mismatch")
+	}
+	return nil
+}
+
+func msdosTime(date, clock uint16) time.Time {
+	return time.Date(int(date>>9)+1980, time.Month(date>>5&0xf), int(date&0x1f),
+		int(clock>>11), int(clock>>5&0x3f), int(clock&0x1f)*2, 0, time.UTC)
+}
+
+func fallbackRepair(src documentSource, mimeType string, config *ExtractionConfig) (*ExtractionResult, error) {
+	data, err := src.bytes()
+	if err != nil {
+		return nil, err
+	}
+	repaired, repairs, err := RepairDocument(data, mimeType)
+	if err != nil {
+		return nil, err
+	}
+
+	fixed := documentSource{data: repaired, mimeType: mimeType}
+	if mimeType == "" && src.path != "" {
+		// Without a MIME type, extractors rely on the file extension.
+		tmp, err := os.CreateTemp("", "kreuzberg-repair-*"+filepath.Ext(src.path))
+		if err != nil {
+			return nil, newIOErrorWithContext("failed to write repaired document", err, ErrorCodeIo, nil)
+		}
+		defer os.Remove(tmp.Name())
+		_, err = tmp.Write(repaired)
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, newIOErrorWithContext("failed to write repaired document", err, ErrorCodeIo, nil)
+		}
+		fixed = documentSource{path: tmp.Name()}
+	}
+	result, err := extractPrimaryUncached(fixed, config)
+	if err != nil {
+		return nil, err
+	}
+	for _, repair := range repairs {
+		result.addDiagnostic("fallback:repair", DiagnosticSeverityInfo, repair)
+	}
+	return result, nil
+}
+
+func init() {
+	fallbackStrategies[FallbackRepair] = fallbackRepair
+}
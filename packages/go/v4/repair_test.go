@@ -0,0 +1,120 @@
+package kreuzberg
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRepairDocumentRebuildsPDFCrossReferences(t *testing.T) {
+	pdf := minimalPDF(2)
+	truncated := pdf[:bytes.Index(pdf, []byte("xref"))+12]
+	repaired, repairs, err := RepairDocument(truncated, "application/pdf")
+	if err != nil {
+		t.Fatalf("repair: %v", err)
+	}
+	if report, _ := TriageBytes(repaired, "application/pdf"); len(report.Anomalies) != 0 {
+		t.Fatalf("expected a structurally intact PDF, got %v", report)
+	}
+	if !bytes.HasPrefix(repaired, truncated) || !strings.Contains(string(repaired), "/Root 1 0 R") {
+		t.Fatalf("expected the catalog to become the root:\n%s", repaired)
+	}
+	if last := repairs[len(repairs)-1]; last != "rebuilt the cross-reference table from 1 objects" {
+		t.Fatalf("unexpected repairs: %q", repairs)
+	}
+
+	// An object cut off by truncation is left out.
+	cut := append(bytes.Clone(truncated[:bytes.Index(truncated, []byte("xref"))]), "2 0 obj\n<< /Length 100 >>\nstream\nabc"...)
+	_, repairs, err = RepairDocument(cut, "")
+	if err != nil || !strings.HasPrefix(repairs[0], "dropped object 2") {
+		t.Fatalf("expected the truncated object to be dropped, got %q, %v", repairs, err)
+	}
+
+	if _, _, err := RepairDocument([]byte("%PDF-1.4\n1 0 obj\n<< >>\nendobj\n"), ""); err == nil {
+		t.Fatalf("expected an error without a catalog")
+	}
+	if _, _, err := RepairDocument([]byte("plain text"), ""); err == nil {
+		t.Fatalf("expected an error for an unsupported format")
+	}
+}
+
+// orderedZip builds an archive with entries in the given order.
+func orderedZip(t *testing.T, names []string, contents [][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for i, name := range names {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+		w.Write(contents[i])
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func randomBytes(n int) []byte {
+	rng := rand.New(rand.NewPCG(3, 4))
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(rng.Uint32())
+	}
+	return data
+}
+
+func TestRepairDocumentRebuildsZIPCentralDirectory(t *testing.T) {
+	archive := orderedZip(t, []string{"a.txt", "b.txt", "c.bin"}, [][]byte{[]byte("first"), []byte("second"), randomBytes(4096)})
+	second := 4 + bytes.Index(archive[4:], zipMagic)
+	corrupt := bytes.Clone(archive[:len(archive)-2000])
+	corrupt[second+30+len("b.txt")] ^= 0xff
+
+	repaired, repairs, err := RepairDocument(corrupt, "application/zip")
+	if err != nil {
+		t.Fatalf("repair: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(repaired), int64(len(repaired)))
+	if err != nil || len(zr.File) != 1 || zr.File[0].Name != "a.txt" {
+		t.Fatalf("expected only the intact entry, got %v", err)
+	}
+	if len(repairs) != 3 || !strings.HasPrefix(repairs[0], `dropped corrupt entry "b.txt"`) || !strings.HasPrefix(repairs[1], `dropped entry "c.bin"`) {
+		t.Fatalf("unexpected repairs: %q", repairs)
+	}
+}
+
+func TestFallbackRepairExtractsRepairedDocument(t *testing.T) {
+	var index bytes.Buffer
+	gz := gzip.NewWriter(&index)
+	gz.Write([]byte(`<sl:document xmlns:sl="http://developer.apple.com/namespaces/sl" xmlns:sf="http://developer.apple.com/namespaces/sf"><sf:text-storage><sf:text-body><sf:p>Recovered text.</sf:p></sf:text-body></sf:text-storage></sl:document>`))
+	gz.Close()
+	archive := orderedZip(t, []string{"index.xml.gz", "Data/preview.bin"}, [][]byte{index.Bytes(), randomBytes(4096)})
+	path := filepath.Join(t.TempDir(), "notes.pages")
+	if err := os.WriteFile(path, archive[:len(archive)/2], 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	config := &ExtractionConfig{Fallback: &FallbackConfig{Chains: map[string][]FallbackStrategy{"*": {FallbackRepair}}}}
+	result, err := ExtractFileSync(path, config)
+	if err != nil {
+		t.Fatalf("expected the repaired document to extract: %v", err)
+	}
+	if result.Content != "Recovered text." {
+		t.Fatalf("unexpected content %q", result.Content)
+	}
+	var repairs []string
+	for _, d := range result.Diagnostics {
+		if d.Source == "fallback:repair" {
+			repairs = append(repairs, d.Message)
+		}
+	}
+	if len(repairs) != 3 || !strings.Contains(repairs[1], "rebuilt the central directory from 1") {
+		t.Fatalf("expected repairs in diagnostics, got %+v", result.Diagnostics)
+	}
+}
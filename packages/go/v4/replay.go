@@ -0,0 +1,353 @@
+package kreuzberg
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// replayBundleVersion versions the layout of replay bundles.
+const replayBundleVersion = 1
+
+// Entries of a replay bundle archive.
+const (
+	replayManifestEntry = "manifest.json"
+	replayConfigEntry   = "config.json"
+	replayInputDir      = "input/"
+)
+
+// replaySecretFields are Go-only config fields never written to a bundle.
+var replaySecretFields = []string{"CacheEncryption"}
+
+// ReplayOptions configures CaptureReplayBundle.
+type ReplayOptions struct {
+	// OmitInput leaves the document out of the bundle; only its name, size, SHA-256 digest and
+	// structural triage report are recorded.
+	OmitInput bool
+	// Redact rewrites the document before it is added to the bundle, e.g. to blank out personal
+	// data while keeping the structure that triggers the bug. The extraction is still captured
+	// from the original document; check that the redacted one reproduces it.
+	Redact func(data []byte, mimeType string) ([]byte, error)
+}
+
+// ReplayInput describes the document of a replay bundle.
+type ReplayInput struct {
+	Name     string `json:"name"`
+	MimeType string `json:"mime_type,omitempty"`
+	Size     int64  `json:"size"`
+	// SHA256 is the digest of the original document.
+	SHA256 string `json:"sha256"`
+	// Included reports whether the bundle holds the document, under "input/<Name>".
+	Included bool `json:"included"`
+	// Redacted reports whether the included document was rewritten by ReplayOptions.Redact;
+	// RedactedSHA256 is then its digest.
+	Redacted       bool   `json:"redacted,omitempty"`
+	RedactedSHA256 string `json:"redacted_sha256,omitempty"`
+}
+
+// ReplayError is the error of the captured extraction.
+type ReplayError struct {
+	Type       string          `json:"type"`
+	Message    string          `json:"message"`
+	Code       StableErrorCode `json:"code"`
+	NativeCode *ErrorCode      `json:"native_code,omitempty"`
+}
+
+// ReplayOutcome summarizes the captured extraction. Extracted content is not recorded.
+type ReplayOutcome struct {
+	Error        *ReplayError  `json:"error,omitempty"`
+	MimeType     string        `json:"mime_type,omitempty"`
+	ContentChars int           `json:"content_chars"`
+	Pages        int           `json:"pages"`
+	Tables       int           `json:"tables"`
+	Chunks       int           `json:"chunks"`
+	Diagnostics  []Diagnostic  `json:"diagnostics,omitempty"`
+	Duration     time.Duration `json:"duration"`
+}
+
+// ReplayManifest is the "manifest.json" entry of a replay bundle.
+type ReplayManifest struct {
+	Version  int         `json:"version"`
+	Captured time.Time   `json:"captured"`
+	System   SystemInfo  `json:"system"`
+	Input    ReplayInput `json:"input"`
+	// Triage is the structural report of the original document.
+	Triage  *TriageReport `json:"triage,omitempty"`
+	Outcome ReplayOutcome `json:"outcome"`
+	// OmittedConfig lists the Go-only config fields that were set but not recorded, because they
+	// hold secrets or callbacks; a replay runs without them.
+	OmittedConfig []string `json:"omitted_config,omitempty"`
+}
+
+// replayConfig is the "config.json" entry of a replay bundle: the native config in its JSON form
+// and the recorded Go-only fields by name.
+type replayConfig struct {
+	Native *ExtractionConfig          `json:"native"`
+	GoOnly map[string]json.RawMessage `json:"go_only,omitempty"`
+}
+
+// ReplayBundle packages everything needed to reproduce an extraction: the input document, the
+// effective config, the library versions and the outcome with its diagnostics. Write it with
+// WriteTo or WriteFile and attach the archive to the issue.
+type ReplayBundle struct {
+	Manifest ReplayManifest
+	config   replayConfig
+	input    []byte
+}
+
+// CaptureReplayBundle extracts the file at path with config (nil for defaults) and captures the
+// outcome in a bundle; a failed extraction is recorded, not returned. opts may be nil. PDF
+// passwords and the cache encryption settings are never recorded.
+func CaptureReplayBundle(path string, config *ExtractionConfig, opts *ReplayOptions) (*ReplayBundle, error) {
+	var o ReplayOptions
+	if opts != nil {
+		o = *opts
+	}
+	// #nosec G304 -- path was supplied by the caller for extraction
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, newIOErrorWithContext("failed to read document for the replay bundle", err, ErrorCodeIo, nil)
+	}
+	src := documentSource{path: path}
+	digest := sha256.Sum256(data)
+	bundle := &ReplayBundle{Manifest: ReplayManifest{
+		Version:  replayBundleVersion,
+		Captured: time.Now().UTC(),
+		System:   SystemStatus(),
+		Input: ReplayInput{
+			Name:     filepath.Base(path),
+			MimeType: src.detectMimeType(),
+			Size:     int64(len(data)),
+			SHA256:   hex.EncodeToString(digest[:]),
+		},
+	}}
+	bundle.Manifest.Triage, _ = TriageBytes(data, bundle.Manifest.Input.MimeType)
+	bundle.config, bundle.Manifest.OmittedConfig = newReplayConfig(config)
+
+	start := time.Now()
+	result, extractErr := ExtractFileSync(path, config)
+	bundle.Manifest.Outcome = replayOutcome(result, extractErr)
+	bundle.Manifest.Outcome.Duration = time.Since(start)
+
+	if !o.OmitInput {
+		bundle.input = data
+		bundle.Manifest.Input.Included = true
+		if o.Redact != nil {
+			redacted, err := o.Redact(data, bundle.Manifest.Input.MimeType)
+			if err != nil {
+				return nil, newPluginErrorWithContext("replay_redact", "failed to redact the replay input", err, ErrorCodePlugin, nil)
+			}
+			sum := sha256.Sum256(redacted)
+			bundle.input = redacted
+			bundle.Manifest.Input.Redacted = true
+			bundle.Manifest.Input.RedactedSHA256 = hex.EncodeToString(sum[:])
+		}
+	}
+	return bundle, nil
+}
+
+func replayOutcome(result *ExtractionResult, err error) ReplayOutcome {
+	var outcome ReplayOutcome
+	if err != nil {
+		outcome.Error = &ReplayError{Type: errorTypeName(err), Message: err.Error(), Code: StableErrorCodeOf(err)}
+		var kerr KreuzbergError
+		if errors.As(err, &kerr) {
+			code := kerr.Code()
+			outcome.Error.NativeCode = &code
+		}
+		if result == nil {
+			return outcome
+		}
+	}
+	outcome.MimeType = result.MimeType
+	outcome.ContentChars = utf8.RuneCountInString(result.Content)
+	outcome.Pages = len(result.Pages)
+	outcome.Tables = len(result.Tables)
+	outcome.Chunks = len(result.Chunks)
+	outcome.Diagnostics = result.Diagnostics
+	if result.Metadata.Error != nil && outcome.Error == nil {
+		outcome.Error = &ReplayError{Type: result.Metadata.Error.ErrorType, Message: result.Metadata.Error.Message, Code: result.Metadata.Error.StableCode()}
+	}
+	return outcome
+}
+
+// newReplayConfig records config without its secrets. Go-only fields are recorded when their
+// JSON form decodes back to the same value; the names of the others are returned.
+func newReplayConfig(config *ExtractionConfig) (replayConfig, []string) {
+	if config == nil {
+		return replayConfig{}, nil
+	}
+	native := *config
+	if native.PdfOptions != nil && len(native.PdfOptions.Passwords) > 0 {
+		pdf := *native.PdfOptions
+		pdf.Passwords = nil
+		native.PdfOptions = &pdf
+	}
+	rc := replayConfig{Native: &native, GoOnly: map[string]json.RawMessage{}}
+	var omitted []string
+	v := reflect.ValueOf(config).Elem()
+	for i := range v.NumField() {
+		sf, field := v.Type().Field(i), v.Field(i)
+		if sf.Tag.Get("json") != "-" || field.IsZero() {
+			continue
+		}
+		raw, err := json.Marshal(field.Interface())
+		decoded := reflect.New(sf.Type)
+		if slices.Contains(replaySecretFields, sf.Name) || err != nil || json.Unmarshal(raw, decoded.Interface()) != nil || !reflect.DeepEqual(decoded.Elem().Interface(), field.Interface()) {
+			omitted = append(omitted, sf.Name)
+			continue
+		}
+		rc.GoOnly[sf.Name] = raw
+	}
+	if native.PdfOptions != config.PdfOptions {
+		omitted = append(omitted, "PdfOptions.Passwords")
+	}
+	return rc, omitted
+}
+
+// Config returns the recorded config, with the Go-only fields that could be recorded.
+func (b *ReplayBundle) Config() (*ExtractionConfig, error) {
+	if b.config.Native == nil {
+		return nil, nil
+	}
+	config := *b.config.Native
+	v := reflect.ValueOf(&config).Elem()
+	for name, raw := range b.config.GoOnly {
+		field := v.FieldByName(name)
+		if !field.IsValid() {
+			continue
+		}
+		if err := json.Unmarshal(raw, field.Addr().Interface()); err != nil {
+			return nil, newSerializationErrorWithContext(fmt.Sprintf("failed to decode replay config field %s", name), err, ErrorCodeValidation, nil)
+		}
+	}
+	return &config, nil
+}
+
+// Input returns the document held by the bundle, or nil when it was omitted.
+func (b *ReplayBundle) Input() []byte {
+	return b.input
+}
+
+// WriteTo writes the bundle as a zip archive.
+func (b *ReplayBundle) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	add := func(name string, data []byte) error {
+		entry, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: b.Manifest.Captured})
+		if err != nil {
+			return err
+		}
+		_, err = entry.Write(data)
+		return err
+	}
+	manifest, err := json.MarshalIndent(b.Manifest, "", "  ")
+	if err != nil {
+		return 0, newSerializationErrorWithContext("failed to encode replay manifest", err, ErrorCodeValidation, nil)
+	}
+	config, err := json.MarshalIndent(b.config, "", "  ")
+	if err != nil {
+		return 0, newSerializationErrorWithContext("failed to encode replay config", err, ErrorCodeValidation, nil)
+	}
+	err = errors.Join(add(replayManifestEntry, manifest), add(replayConfigEntry, config))
+	if err == nil && b.input != nil {
+		err = add(replayInputDir+b.Manifest.Input.Name, b.input)
+	}
+	if err = errors.Join(err, zw.Close()); err != nil {
+		return 0, newIOErrorWithContext("failed to write replay bundle", err, ErrorCodeIo, nil)
+	}
+	return buf.WriteTo(w)
+}
+
+// WriteFile writes the bundle as a zip archive to path.
+func (b *ReplayBundle) WriteFile(path string) error {
+	var buf bytes.Buffer
+	if _, err := b.WriteTo(&buf); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		return newIOErrorWithContext("failed to write replay bundle", err, ErrorCodeIo, nil)
+	}
+	return nil
+}
+
+// ReadReplayBundle reads a bundle written by ReplayBundle.WriteFile.
+func ReadReplayBundle(path string) (*ReplayBundle, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, newIOErrorWithContext("failed to open replay bundle", err, ErrorCodeIo, nil)
+	}
+	defer zr.Close()
+
+	bundle := &ReplayBundle{}
+	var sawManifest bool
+	for _, f := range zr.File {
+		data, err := readZipEntry(f)
+		if err != nil {
+			return nil, newIOErrorWithContext("failed to read replay bundle entry "+f.Name, err, ErrorCodeIo, nil)
+		}
+		switch {
+		case f.Name == replayManifestEntry:
+			err, sawManifest = json.Unmarshal(data, &bundle.Manifest), true
+		case f.Name == replayConfigEntry:
+			err = json.Unmarshal(data, &bundle.config)
+		case strings.HasPrefix(f.Name, replayInputDir):
+			bundle.input = data
+		}
+		if err != nil {
+			return nil, newSerializationErrorWithContext("failed to decode replay bundle entry "+f.Name, err, ErrorCodeValidation, nil)
+		}
+	}
+	if !sawManifest {
+		return nil, newValidationErrorWithContext("replay bundle has no manifest", nil, ErrorCodeValidation, nil)
+	}
+	if bundle.Manifest.Version != replayBundleVersion {
+		return nil, newValidationErrorWithContext(fmt.Sprintf("unsupported replay bundle version %d", bundle.Manifest.Version), nil, ErrorCodeValidation, nil)
+	}
+	return bundle, nil
+}
+
+func readZipEntry(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// Replay extracts the bundled document again with the recorded config, under its original file
+// name so detection by extension behaves the same, and returns the new outcome for comparison
+// with Manifest.Outcome.
+func (b *ReplayBundle) Replay(ctx context.Context) (*ExtractionResult, error) {
+	if b.input == nil {
+		return nil, newValidationErrorWithContext("replay bundle does not include the input document", nil, ErrorCodeValidation, nil)
+	}
+	config, err := b.Config()
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "kreuzberg-replay-*")
+	if err != nil {
+		return nil, newIOErrorWithContext("failed to create replay directory", err, ErrorCodeIo, nil)
+	}
+	defer os.RemoveAll(dir)
+	inputPath := filepath.Join(dir, filepath.Base(b.Manifest.Input.Name))
+	if err := os.WriteFile(inputPath, b.input, 0o600); err != nil {
+		return nil, newIOErrorWithContext("failed to write replay input", err, ErrorCodeIo, nil)
+	}
+	return ExtractFileWithContext(ctx, inputPath, config)
+}
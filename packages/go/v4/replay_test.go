@@ -0,0 +1,91 @@
+package kreuzberg
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCaptureReplayBundle(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "talk.srt")
+	os.WriteFile(input, []byte(testSRT), 0o600)
+	config := &ExtractionConfig{
+		Chunking:        &ChunkingConfig{MaxChars: IntPtr(40)},
+		PdfOptions:      &PdfConfig{Passwords: []string{"s3cret"}},
+		CSV:             &CSVConfig{Delimiter: ';'},
+		CacheEncryption: &CacheEncryptionConfig{Key: []byte(strings.Repeat("k", 32)), Dir: filepath.Join(dir, "cache")},
+		QA:              &QAConfig{Generate: func(context.Context, QASection) ([]QAPair, error) { return nil, nil }},
+	}
+
+	bundle, err := CaptureReplayBundle(input, config, nil)
+	if err != nil {
+		t.Fatalf("capture: %v", err)
+	}
+	m := bundle.Manifest
+	if m.Input.Name != "talk.srt" || !m.Input.Included || m.Input.Size != int64(len(testSRT)) || len(m.Input.SHA256) != 64 {
+		t.Fatalf("unexpected input %+v", m.Input)
+	}
+	if m.Outcome.Error != nil || m.Outcome.Chunks == 0 || m.Outcome.ContentChars == 0 || m.System.GoVersion == "" {
+		t.Fatalf("unexpected outcome %+v", m.Outcome)
+	}
+	if !slices.Equal(m.OmittedConfig, []string{"CacheEncryption", "QA", "PdfOptions.Passwords"}) {
+		t.Fatalf("unexpected omitted config %v", m.OmittedConfig)
+	}
+
+	path := filepath.Join(dir, "bundle.zip")
+	if err := bundle.WriteFile(path); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	archive, _ := os.ReadFile(path)
+	if bytes.Contains(archive, []byte("s3cret")) {
+		t.Fatalf("the bundle leaks the PDF password")
+	}
+
+	read, err := ReadReplayBundle(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	replayConfig, err := read.Config()
+	if err != nil || replayConfig.CSV == nil || replayConfig.CSV.Delimiter != ';' || replayConfig.CacheEncryption != nil || replayConfig.PdfOptions.Passwords != nil {
+		t.Fatalf("unexpected replay config %+v, %v", replayConfig, err)
+	}
+	result, err := read.Replay(t.Context())
+	if err != nil || len(result.Chunks) != m.Outcome.Chunks {
+		t.Fatalf("unexpected replay %+v, %v", result, err)
+	}
+}
+
+func TestCaptureReplayBundleInputOptions(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "talk.srt")
+	os.WriteFile(input, []byte(testSRT), 0o600)
+
+	bundle, err := CaptureReplayBundle(input, nil, &ReplayOptions{OmitInput: true})
+	if err != nil || bundle.Input() != nil || bundle.Manifest.Input.Included {
+		t.Fatalf("expected the input to be omitted, got %v", err)
+	}
+	if _, err := bundle.Replay(t.Context()); err == nil {
+		t.Fatalf("expected a replay without input to fail")
+	}
+
+	redact := func(data []byte, _ string) ([]byte, error) {
+		return bytes.ReplaceAll(data, []byte("Hello"), []byte("XXXXX")), nil
+	}
+	bundle, err = CaptureReplayBundle(input, nil, &ReplayOptions{Redact: redact})
+	if err != nil || !bundle.Manifest.Input.Redacted || bytes.Contains(bundle.Input(), []byte("Hello")) || bundle.Manifest.Input.RedactedSHA256 == bundle.Manifest.Input.SHA256 {
+		t.Fatalf("expected a redacted input, got %+v, %v", bundle.Manifest.Input, err)
+	}
+
+	// A failing extraction is recorded in the bundle.
+	broken := filepath.Join(dir, "broken.pdf")
+	os.WriteFile(broken, []byte("%PDF-1.7\nnot really"), 0o600)
+	bundle, err = CaptureReplayBundle(broken, nil, nil)
+	if err != nil || bundle.Manifest.Outcome.Error == nil || bundle.Manifest.Outcome.Error.Code == "" || bundle.Manifest.Triage == nil {
+		t.Fatalf("expected the failure to be recorded, got %+v, %v", bundle.Manifest, err)
+	}
+}
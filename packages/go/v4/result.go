@@ -136,6 +136,37 @@ func ResultFromJSON(jsonStr string) (*ExtractionResult, error) {
 	return &result, nil
 }
 
+// CellBoundsAt returns the bounding box of the cell at row, col, if the extractor located it.
+func (t *Table) CellBoundsAt(row, col int) (BoundingBox, bool) {
+	if row < 0 || row >= len(t.CellBounds) || col < 0 || col >= len(t.CellBounds[row]) || t.CellBounds[row][col] == nil {
+		return BoundingBox{}, false
+	}
+	return *t.CellBounds[row][col], true
+}
+
+// Bounds returns the union of the cell bounding boxes, for drawing the table outline in review
+// overlays. It returns false when no cell was located.
+func (t *Table) Bounds() (BoundingBox, bool) {
+	var bounds BoundingBox
+	found := false
+	for _, row := range t.CellBounds {
+		for _, cell := range row {
+			if cell == nil {
+				continue
+			}
+			if !found {
+				bounds, found = *cell, true
+				continue
+			}
+			bounds.Left = min(bounds.Left, cell.Left)
+			bounds.Top = min(bounds.Top, cell.Top)
+			bounds.Right = max(bounds.Right, cell.Right)
+			bounds.Bottom = max(bounds.Bottom, cell.Bottom)
+		}
+	}
+	return bounds, found
+}
+
 // String implements fmt.Stringer for ExtractionResult, showing a summary.
 func (r *ExtractionResult) String() string {
 	if r == nil {
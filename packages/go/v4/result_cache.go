@@ -0,0 +1,295 @@
+package kreuzberg
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// resultCacheMagic prefixes every encrypted cache entry and versions its layout:
+// magic | key ID length (uint16) | key ID | nonce | AES-GCM ciphertext.
+const resultCacheMagic = "KZC1"
+
+// CacheEncryptionConfig configures encryption at rest for cached extraction results. Entries are
+// JSON-encoded results sealed with AES-GCM; each entry records the ID of the key it was sealed
+// with, so keys can be rotated without invalidating the cache at once.
+type CacheEncryptionConfig struct {
+	// Key is the AES key (16, 24 or 32 bytes) used when KeyProvider is nil.
+	Key []byte
+	// KeyID names the key new entries are sealed with. It is stored in clear text with each entry.
+	KeyID string
+	// KeyProvider resolves a key by ID, e.g. by unwrapping a data key with a KMS. It is called with
+	// KeyID when writing and with an entry's key ID when reading, on every cached extraction, so it
+	// should memoize expensive lookups. Entries whose key cannot be resolved are treated as misses.
+	KeyProvider func(keyID string) ([]byte, error)
+	// Dir is the directory holding encrypted entries (default: "kreuzberg/results" in
+	// os.UserCacheDir).
+	Dir string
+	// RetainVersions caps the cached versions kept per document, one per library version and
+	// config; storing a new version removes the oldest ones (0 = keep all). See ResultCache.
+	RetainVersions int
+}
+
+// cacheEncryptionActive reports whether config routes caching through the encrypted Go cache.
+// Statistics-only extractions are never cached, since entries hold the full result.
+func cacheEncryptionActive(config *ExtractionConfig) bool {
+	return config != nil && config.CacheEncryption != nil && (config.UseCache == nil || *config.UseCache) && !statisticsOnly(config)
+}
+
+// resultCache is the encrypted result cache for one extraction config.
+type resultCache struct {
+	cfg       *CacheEncryptionConfig
+	dir       string
+	aead      cipher.AEAD
+	configKey []byte
+	// configHash identifies the config independently of the library version.
+	configHash string
+}
+
+// openResultCache returns the encrypted cache configured by config, or nil when it is disabled.
+func openResultCache(config *ExtractionConfig) (*resultCache, error) {
+	if !cacheEncryptionActive(config) {
+		return nil, nil
+	}
+	if err := checkCryptoProfile(); err != nil {
+		return nil, err
+	}
+	cfg := config.CacheEncryption
+	cache := &resultCache{cfg: cfg, dir: cfg.Dir}
+	if cache.dir == "" {
+		base, err := os.UserCacheDir()
+		if err != nil {
+			return nil, newCacheErrorWithContext("cannot determine the cache directory; set CacheEncryption.Dir", err, ErrorCodeIo, nil)
+		}
+		cache.dir = filepath.Join(base, "kreuzberg", "results")
+	}
+	aead, err := cache.cipher(cfg.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	cache.aead = aead
+
+	if cache.configKey, err = configDigest(resultCacheMagic, config); err != nil {
+		return nil, err
+	}
+	configHash, err := configDigestWithVersion(resultCacheMagic, "", config)
+	if err != nil {
+		return nil, err
+	}
+	cache.configHash = hex.EncodeToString(configHash)
+	return cache, nil
+}
+
+// configDigest hashes everything about config that affects results, prefixed with domain: the
+// library version and every option, including the Go-only ones that select and tune the
+// built-in Go extractors.
+func configDigest(domain string, config *ExtractionConfig) ([]byte, error) {
+	return configDigestWithVersion(domain, LibraryVersion(), config)
+}
+
+// configDigestWithVersion is configDigest for the given library version.
+func configDigestWithVersion(domain, libraryVersion string, config *ExtractionConfig) ([]byte, error) {
+	nativeConfig, err := json.Marshal(config)
+	if err != nil {
+		return nil, newSerializationErrorWithContext("failed to encode config for the cache key", err, ErrorCodeValidation, nil)
+	}
+	var goConfig []byte
+	if config != nil {
+		if goConfig, err = json.Marshal([]any{config.Spreadsheet, config.CSV, config.DataFiles, config.Database, config.StructuredData, config.Logs, config.XMLProfile, config.TextEncoding}); err != nil {
+			return nil, newSerializationErrorWithContext("failed to encode config for the cache key", err, ErrorCodeValidation, nil)
+		}
+	}
+	h := sha256.New()
+	for _, part := range [][]byte{[]byte(domain), []byte(libraryVersion), nativeConfig, goConfig} {
+		binary.Write(h, binary.BigEndian, uint64(len(part)))
+		h.Write(part)
+	}
+	return h.Sum(nil), nil
+}
+
+// cipher resolves the key with the given ID and returns its AES-GCM cipher.
+func (c *resultCache) cipher(keyID string) (cipher.AEAD, error) {
+	key := c.cfg.Key
+	if c.cfg.KeyProvider != nil {
+		var err error
+		if key, err = c.cfg.KeyProvider(keyID); err != nil {
+			return nil, newCacheErrorWithContext(fmt.Sprintf("failed to resolve cache encryption key %q", keyID), err, ErrorCodeInternal, nil)
+		}
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, newValidationErrorWithContext("cache encryption key must be 16, 24 or 32 bytes", err, ErrorCodeValidation, nil)
+	}
+	aead, err := newCacheAEAD(block)
+	if err != nil {
+		return nil, newCacheErrorWithContext("failed to initialize AES-GCM", err, ErrorCodeInternal, nil)
+	}
+	return aead, nil
+}
+
+// key returns the cache key for src: a digest of the document, its MIME type or file extension,
+// and the extraction config.
+func (c *resultCache) key(src documentSource) (string, error) {
+	key, _, err := c.keys(src)
+	return key, err
+}
+
+// keys returns the cache key for src and the ID of its version index, which leaves out the
+// config so every cached version of the document shares it.
+func (c *resultCache) keys(src documentSource) (key, document string, err error) {
+	data, err := src.bytes()
+	if err != nil {
+		return "", "", err
+	}
+	digest := sha256.Sum256(data)
+	parts := []string{src.mimeType, strings.ToLower(filepath.Ext(src.path)), string(digest[:])}
+	entry, index := sha256.New(), sha256.New()
+	entry.Write(c.configKey)
+	index.Write([]byte(resultVersionsMagic))
+	for _, part := range parts {
+		binary.Write(entry, binary.BigEndian, uint64(len(part)))
+		entry.Write([]byte(part))
+		binary.Write(index, binary.BigEndian, uint64(len(part)))
+		index.Write([]byte(part))
+	}
+	return hex.EncodeToString(entry.Sum(nil)), hex.EncodeToString(index.Sum(nil)), nil
+}
+
+func (c *resultCache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".kzc")
+}
+
+// load returns the cached result for key, or nil on a miss. Entries that cannot be read,
+// authenticated or decoded are treated as misses and overwritten by the next store.
+func (c *resultCache) load(key string) *ExtractionResult {
+	plaintext := c.open(c.path(key), key)
+	if plaintext == nil {
+		return nil
+	}
+	var result ExtractionResult
+	if err := json.Unmarshal(plaintext, &result); err != nil {
+		return nil
+	}
+	result.cacheHit = true
+	return &result
+}
+
+// open reads and authenticates the sealed file at path, bound to key. It returns nil when the
+// file is missing, sealed with an unknown key or has been tampered with.
+func (c *resultCache) open(path, key string) []byte {
+	entry, err := os.ReadFile(path)
+	if err != nil || len(entry) < len(resultCacheMagic)+2 || string(entry[:len(resultCacheMagic)]) != resultCacheMagic {
+		return nil
+	}
+	rest := entry[len(resultCacheMagic):]
+	idLen := int(binary.BigEndian.Uint16(rest))
+	rest = rest[2:]
+	if len(rest) < idLen {
+		return nil
+	}
+	keyID, sealed := string(rest[:idLen]), rest[idLen:]
+
+	aead := c.aead
+	if keyID != c.cfg.KeyID {
+		if aead, err = c.cipher(keyID); err != nil {
+			return nil
+		}
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, resultCacheAAD(entry[:len(resultCacheMagic)+2+idLen], key))
+	if err != nil {
+		return nil
+	}
+	return plaintext
+}
+
+// store seals result under key. The entry is written to a temporary file and renamed into
+// place, so concurrent readers never see a partial entry.
+func (c *resultCache) store(key string, result *ExtractionResult) error {
+	plaintext, err := json.Marshal(result)
+	if err != nil {
+		return newSerializationErrorWithContext("failed to encode result for the cache", err, ErrorCodeValidation, nil)
+	}
+	return c.seal(c.path(key), key, plaintext)
+}
+
+// seal encrypts plaintext bound to key and writes it to path atomically.
+func (c *resultCache) seal(path, key string, plaintext []byte) error {
+	if len(c.cfg.KeyID) > 0xffff {
+		return newValidationErrorWithContext("cache encryption key ID is too long", nil, ErrorCodeValidation, nil)
+	}
+	var header bytes.Buffer
+	header.WriteString(resultCacheMagic)
+	binary.Write(&header, binary.BigEndian, uint16(len(c.cfg.KeyID)))
+	header.WriteString(c.cfg.KeyID)
+
+	// Under the FIPS profile NonceSize is zero and Seal generates and prepends the nonce itself.
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return newCacheErrorWithContext("failed to generate nonce", err, ErrorCodeInternal, nil)
+	}
+	entry := append(header.Bytes(), nonce...)
+	entry = c.aead.Seal(entry, nonce, plaintext, resultCacheAAD(header.Bytes(), key))
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return newCacheErrorWithContext("failed to create cache directory", err, ErrorCodeIo, nil)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return newCacheErrorWithContext("failed to write cache entry", err, ErrorCodeIo, nil)
+	}
+	_, err = tmp.Write(entry)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return newCacheErrorWithContext("failed to write cache entry", err, ErrorCodeIo, nil)
+	}
+	return nil
+}
+
+// resultCacheAAD binds an entry's ciphertext to its header and cache key, so entries cannot be
+// swapped between keys undetected.
+func resultCacheAAD(header []byte, key string) []byte {
+	return append(append([]byte(nil), header...), key...)
+}
+
+// cachedExtract serves src from the encrypted cache, running extract and caching its result on
+// a miss. A failure to write the entry is reported as a warning diagnostic on the result; a
+// result a DataFlowInspector withholds from the cache is returned uncached.
+func cachedExtract(cache *resultCache, src documentSource, config *ExtractionConfig, extract func() (*ExtractionResult, error)) (*ExtractionResult, error) {
+	key, document, err := cache.keys(src)
+	if err != nil {
+		return nil, err
+	}
+	if result := cache.load(key); result != nil {
+		return result, nil
+	}
+	result, err := extract()
+	if err != nil {
+		return nil, err
+	}
+	if !cacheable(config, src, result) {
+		return result, nil
+	}
+	if err := cache.storeVersion(key, document, result); err != nil {
+		result.addDiagnostic("cache", DiagnosticSeverityWarning, err.Error())
+	}
+	return result, nil
+}
@@ -0,0 +1,151 @@
+package kreuzberg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const wantSRTContent = "Hello there.\n\nGeneral Kenobi!\nYou are a bold one."
+
+// cacheEntries returns the paths of the entries in an encrypted cache directory.
+func cacheEntries(t *testing.T, dir string) []string {
+	t.Helper()
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && filepath.Ext(path) == ".kzc" {
+			paths = append(paths, path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("walk cache: %v", err)
+	}
+	return paths
+}
+
+func TestEncryptedResultCacheSealsAndServesEntries(t *testing.T) {
+	dir := t.TempDir()
+	k1 := bytes.Repeat([]byte{1}, 32)
+	config := &ExtractionConfig{CacheEncryption: &CacheEncryptionConfig{Key: k1, KeyID: "k1", Dir: dir}}
+
+	result, err := ExtractBytesSync([]byte(testSRT), mimeSRT, config)
+	if err != nil || result.Content != wantSRTContent {
+		t.Fatalf("extract = %v, %v", result, err)
+	}
+	entries := cacheEntries(t, dir)
+	if len(entries) != 1 {
+		t.Fatalf("expected one cache entry, got %v", entries)
+	}
+	sealed, err := os.ReadFile(entries[0])
+	if err != nil {
+		t.Fatalf("read entry: %v", err)
+	}
+	if bytes.Contains(sealed, []byte("Kenobi")) {
+		t.Fatalf("cache entry holds plaintext content")
+	}
+
+	// Replace the entry with a marker result to observe cache hits.
+	cache, err := openResultCache(config)
+	if err != nil {
+		t.Fatalf("open cache: %v", err)
+	}
+	key, err := cache.key(documentSource{data: []byte(testSRT), mimeType: mimeSRT})
+	if err != nil {
+		t.Fatalf("cache key: %v", err)
+	}
+	if err := cache.store(key, &ExtractionResult{Content: "cached", MimeType: mimeSRT}); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	if result, _ := ExtractBytesSync([]byte(testSRT), mimeSRT, config); result.Content != "cached" {
+		t.Fatalf("expected a cache hit, got %q", result.Content)
+	}
+	results, err := BatchExtractBytesSync([]BytesWithMime{{Data: []byte(testSRT), MimeType: mimeSRT}}, config)
+	if err != nil || results[0].Content != "cached" {
+		t.Fatalf("expected a batch cache hit, got %v, %v", results, err)
+	}
+
+	// After rotating to k2, entries sealed with k1 stay readable while the provider knows k1.
+	keys := map[string][]byte{"k1": k1, "k2": bytes.Repeat([]byte{2}, 32)}
+	rotated := &ExtractionConfig{CacheEncryption: &CacheEncryptionConfig{KeyID: "k2", Dir: dir, KeyProvider: func(id string) ([]byte, error) {
+		if key, ok := keys[id]; ok {
+			return key, nil
+		}
+		return nil, errors.New("unknown key")
+	}}}
+	if result, _ := ExtractBytesSync([]byte(testSRT), mimeSRT, rotated); result.Content != "cached" {
+		t.Fatalf("expected a cache hit with a rotated key, got %q", result.Content)
+	}
+	delete(keys, "k1")
+	if result, _ := ExtractBytesSync([]byte(testSRT), mimeSRT, rotated); result.Content != wantSRTContent {
+		t.Fatalf("expected a miss once k1 is retired, got %q", result.Content)
+	}
+}
+
+func TestEncryptedResultCacheRejectsTamperedEntries(t *testing.T) {
+	dir := t.TempDir()
+	config := &ExtractionConfig{CacheEncryption: &CacheEncryptionConfig{Key: bytes.Repeat([]byte{7}, 16), Dir: dir}}
+	cache, err := openResultCache(config)
+	if err != nil {
+		t.Fatalf("open cache: %v", err)
+	}
+	key, _ := cache.key(documentSource{data: []byte(testSRT), mimeType: mimeSRT})
+	if err := cache.store(key, &ExtractionResult{Content: "cached"}); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	path := cache.path(key)
+	sealed, _ := os.ReadFile(path)
+	sealed[len(sealed)-1] ^= 1
+	if err := os.WriteFile(path, sealed, 0o600); err != nil {
+		t.Fatalf("tamper: %v", err)
+	}
+	if result, _ := ExtractBytesSync([]byte(testSRT), mimeSRT, config); result.Content != wantSRTContent {
+		t.Fatalf("expected tampered entry to be ignored, got %q", result.Content)
+	}
+}
+
+func TestEncryptedResultCacheConfiguration(t *testing.T) {
+	_, err := ExtractBytesSync([]byte(testSRT), mimeSRT, &ExtractionConfig{CacheEncryption: &CacheEncryptionConfig{Key: []byte("short"), Dir: t.TempDir()}})
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected ValidationError for a short key, got %v", err)
+	}
+
+	disabled := &ExtractionConfig{UseCache: BoolPtr(false), CacheEncryption: &CacheEncryptionConfig{Key: []byte("short")}}
+	if cache, err := openResultCache(disabled); cache != nil || err != nil {
+		t.Fatalf("expected the cache to be off when UseCache is false, got %v, %v", cache, err)
+	}
+}
+
+func TestEncryptedResultCacheDisablesNativeCaches(t *testing.T) {
+	config := &ExtractionConfig{
+		UseCache:        BoolPtr(true),
+		CacheEncryption: &CacheEncryptionConfig{Key: bytes.Repeat([]byte{1}, 32), Dir: t.TempDir()},
+		OCR:             &OCRConfig{Tesseract: &TesseractConfig{UseCache: BoolPtr(true)}},
+	}
+	data, err := nativeConfigJSON(config)
+	if err != nil {
+		t.Fatalf("nativeConfigJSON: %v", err)
+	}
+	var native struct {
+		UseCache *bool `json:"use_cache"`
+		OCR      struct {
+			Tesseract struct {
+				UseCache *bool `json:"use_cache"`
+			} `json:"tesseract_config"`
+		} `json:"ocr"`
+	}
+	if err := json.Unmarshal(data, &native); err != nil {
+		t.Fatalf("decode native config: %v", err)
+	}
+	if native.UseCache == nil || *native.UseCache || native.OCR.Tesseract.UseCache == nil || *native.OCR.Tesseract.UseCache {
+		t.Fatalf("expected the native result and OCR caches to be off, got %s", data)
+	}
+	if !*config.UseCache || !*config.OCR.Tesseract.UseCache {
+		t.Fatal("expected the caller's config to be left unchanged")
+	}
+}
@@ -0,0 +1,275 @@
+package kreuzberg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fingerprintHeadSize is the number of leading bytes kept in DocumentFingerprint.Head.
+const fingerprintHeadSize = 8 << 10
+
+// DocumentFingerprint holds properties of a document that are cheap to probe, used to route it
+// to a config profile.
+type DocumentFingerprint struct {
+	// Path is the document path, or "" for in-memory documents.
+	Path string
+	// MimeType is the declared MIME type, or the type detected from the content or extension.
+	MimeType string
+	// Extension is the lower-case file extension including the dot, or "".
+	Extension string
+	// Size is the document size in bytes.
+	Size int64
+	// Head holds the first 8 KiB of the document, for magic numbers and markers.
+	Head []byte
+}
+
+// FingerprintFile probes the file at path without extracting it.
+func FingerprintFile(path string) (DocumentFingerprint, error) {
+	return fingerprint(documentSource{path: path})
+}
+
+// FingerprintBytes probes an in-memory document; mimeType may be empty.
+func FingerprintBytes(data []byte, mimeType string) DocumentFingerprint {
+	fp, _ := fingerprint(documentSource{data: data, mimeType: mimeType})
+	return fp
+}
+
+func fingerprint(src documentSource) (DocumentFingerprint, error) {
+	fp := DocumentFingerprint{Path: src.path, MimeType: src.mimeType}
+	if src.path != "" {
+		fp.Extension = strings.ToLower(filepath.Ext(src.path))
+		// #nosec G304 -- path was supplied by the caller for extraction
+		f, err := os.Open(src.path)
+		if err != nil {
+			return fp, newIOErrorWithContext("failed to open document for fingerprinting", err, ErrorCodeIo, nil)
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil {
+			return fp, newIOErrorWithContext("failed to stat document for fingerprinting", err, ErrorCodeIo, nil)
+		}
+		fp.Size = info.Size()
+		if fp.Head, err = io.ReadAll(io.LimitReader(f, fingerprintHeadSize)); err != nil {
+			return fp, newIOErrorWithContext("failed to read document for fingerprinting", err, ErrorCodeIo, nil)
+		}
+	} else {
+		fp.Size = int64(len(src.data))
+		fp.Head = src.data[:min(len(src.data), fingerprintHeadSize)]
+	}
+	if fp.MimeType == "" {
+		fp.MimeType = src.detectMimeType()
+	}
+	if fp.MimeType == "" && fp.Extension != "" {
+		fp.MimeType, _, _ = strings.Cut(mime.TypeByExtension(fp.Extension), ";")
+	}
+	if fp.MimeType == "" && len(fp.Head) > 0 {
+		if sniffed, _, _ := strings.Cut(http.DetectContentType(fp.Head), ";"); sniffed != "application/octet-stream" {
+			fp.MimeType = sniffed
+		}
+	}
+	return fp, nil
+}
+
+// Matcher reports whether a document belongs to a route.
+type Matcher func(DocumentFingerprint) bool
+
+// MatchMIME matches documents whose MIME type equals one of the patterns. Patterns may be
+// type wildcards ("image/*") or "*".
+func MatchMIME(patterns ...string) Matcher {
+	return func(fp DocumentFingerprint) bool {
+		mimeType := strings.ToLower(fp.MimeType)
+		for _, pattern := range patterns {
+			pattern = strings.ToLower(pattern)
+			if pattern == "*" || pattern == mimeType ||
+				(strings.HasSuffix(pattern, "/*") && strings.HasPrefix(mimeType, strings.TrimSuffix(pattern, "*"))) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MatchExtension matches documents with one of the file extensions, given with or without the
+// leading dot and compared case-insensitively.
+func MatchExtension(extensions ...string) Matcher {
+	return func(fp DocumentFingerprint) bool {
+		for _, ext := range extensions {
+			if fp.Extension != "" && strings.TrimPrefix(fp.Extension, ".") == strings.ToLower(strings.TrimPrefix(ext, ".")) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MatchSizeAtLeast matches documents of at least size bytes.
+func MatchSizeAtLeast(size int64) Matcher {
+	return func(fp DocumentFingerprint) bool {
+		return fp.Size >= size
+	}
+}
+
+// MatchHead matches documents whose first 8 KiB contain marker, e.g. a producer string.
+func MatchHead(marker []byte) Matcher {
+	return func(fp DocumentFingerprint) bool {
+		return bytes.Contains(fp.Head, marker)
+	}
+}
+
+// MatchAll matches documents that every matcher matches.
+func MatchAll(matchers ...Matcher) Matcher {
+	return func(fp DocumentFingerprint) bool {
+		for _, match := range matchers {
+			if !match(fp) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Route sends the documents Match accepts to a profile.
+type Route struct {
+	Profile string
+	Match   Matcher
+}
+
+// Router picks a named config profile for each document from its fingerprint. The profile is
+// merged over the config the Router is set on (as by ConfigMerge), so profiles only hold the
+// settings that differ. The chosen profile is reported in Metadata.Additional["config_profile"].
+//
+// Batch extractions are split by profile, so each profile's documents still share one native
+// batch call.
+type Router struct {
+	// Profiles maps profile names to their settings.
+	Profiles map[string]*ExtractionConfig
+	// Classify, when set, is consulted first; it returns a profile name or "" to fall through
+	// to Routes.
+	Classify func(DocumentFingerprint) string
+	// Routes are tried in order; the first match wins.
+	Routes []Route
+	// Default is the profile of documents no route matches. When empty, they use the config
+	// the Router is set on as is.
+	Default string
+}
+
+// Route returns the profile name for a document, or "" when the base config applies.
+func (r *Router) Route(fp DocumentFingerprint) string {
+	if r.Classify != nil {
+		if profile := r.Classify(fp); profile != "" {
+			return profile
+		}
+	}
+	for _, route := range r.Routes {
+		if route.Match != nil && route.Match(fp) {
+			return route.Profile
+		}
+	}
+	return r.Default
+}
+
+// configFor returns the config for a profile: base with the profile merged over it and no
+// router, so routed extractions are not routed again.
+func (r *Router) configFor(base *ExtractionConfig, profile string) (*ExtractionConfig, error) {
+	routed := *base
+	routed.Router = nil
+	if profile == "" {
+		return &routed, nil
+	}
+	settings, ok := r.Profiles[profile]
+	if !ok {
+		return nil, newValidationErrorWithContext(fmt.Sprintf("router selected unknown config profile %q", profile), nil, ErrorCodeValidation, nil)
+	}
+	if settings != nil {
+		if err := ConfigMerge(&routed, settings); err != nil {
+			return nil, err
+		}
+		routed.Router = nil
+	}
+	return &routed, nil
+}
+
+func routingEnabled(config *ExtractionConfig) bool {
+	return config != nil && config.Router != nil
+}
+
+// extractRouted routes src and runs extract with the profile's config.
+func extractRouted(src documentSource, config *ExtractionConfig, extract func(*ExtractionConfig) (*ExtractionResult, error)) (*ExtractionResult, error) {
+	fp, err := fingerprint(src)
+	if err != nil {
+		return nil, err
+	}
+	profile := config.Router.Route(fp)
+	routed, err := config.Router.configFor(config, profile)
+	if err != nil {
+		return nil, err
+	}
+	result, err := extract(routed)
+	if err != nil {
+		return nil, err
+	}
+	return result, setConfigProfile(result, profile)
+}
+
+// batchExtractRouted routes every source and runs extract once per profile with the indices of
+// its sources. Results keep the input order.
+func batchExtractRouted(sources []documentSource, config *ExtractionConfig, extract func(indices []int, routed *ExtractionConfig) ([]*ExtractionResult, error)) ([]*ExtractionResult, error) {
+	var profiles []string
+	groups := map[string][]int{}
+	for i, src := range sources {
+		fp, err := fingerprint(src)
+		if err != nil {
+			return nil, err
+		}
+		profile := config.Router.Route(fp)
+		if _, ok := groups[profile]; !ok {
+			profiles = append(profiles, profile)
+		}
+		groups[profile] = append(groups[profile], i)
+	}
+
+	results := make([]*ExtractionResult, len(sources))
+	for _, profile := range profiles {
+		routed, err := config.Router.configFor(config, profile)
+		if err != nil {
+			return nil, err
+		}
+		indices := groups[profile]
+		groupResults, err := extract(indices, routed)
+		if err != nil {
+			return nil, err
+		}
+		for j, i := range indices {
+			if j >= len(groupResults) || groupResults[j] == nil {
+				continue
+			}
+			results[i] = groupResults[j]
+			if err := setConfigProfile(results[i], profile); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return results, nil
+}
+
+func setConfigProfile(result *ExtractionResult, profile string) error {
+	if profile == "" {
+		return nil
+	}
+	raw, err := json.Marshal(profile)
+	if err != nil {
+		return newSerializationErrorWithContext("failed to encode config profile", err, ErrorCodeValidation, nil)
+	}
+	if result.Metadata.Additional == nil {
+		result.Metadata.Additional = map[string]json.RawMessage{}
+	}
+	result.Metadata.Additional["config_profile"] = raw
+	return nil
+}
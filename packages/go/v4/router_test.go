@@ -0,0 +1,112 @@
+package kreuzberg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func configProfile(result *ExtractionResult) string {
+	var profile string
+	json.Unmarshal(result.Metadata.Additional["config_profile"], &profile)
+	return profile
+}
+
+// labelClient returns a client whose post processor appends the "tier" label to the content.
+func labelClient(t *testing.T, config *ExtractionConfig) *Client {
+	t.Helper()
+	client := NewClient(config)
+	err := client.RegisterPostProcessor("tier", 0, func(pc *PluginContext, result *ExtractionResult) error {
+		if tier, ok := pc.Label("tier"); ok {
+			result.Content += "|" + tier
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	return client
+}
+
+func TestRouterSelectsProfilePerDocument(t *testing.T) {
+	config := &ExtractionConfig{
+		Labels: map[string]string{"tier": "base"},
+		Router: &Router{
+			Profiles: map[string]*ExtractionConfig{
+				"subtitles": {Labels: map[string]string{"tier": "subtitles"}},
+				"webvtt":    {Labels: map[string]string{"tier": "webvtt"}},
+			},
+			Classify: func(fp DocumentFingerprint) string {
+				if fp.Extension == ".vtt" {
+					return "webvtt"
+				}
+				return ""
+			},
+			Routes: []Route{{Profile: "subtitles", Match: MatchAll(MatchMIME(mimeSRT), MatchHead([]byte("-->")))}},
+		},
+	}
+	client := labelClient(t, config)
+	ctx := context.Background()
+
+	result, err := client.ExtractBytes(ctx, []byte(testSRT), mimeSRT)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if result.Content != wantSRTContent+"|subtitles" || configProfile(result) != "subtitles" {
+		t.Fatalf("expected the subtitles profile, got %q (%q)", result.Content, configProfile(result))
+	}
+
+	dir := t.TempDir()
+	vtt := filepath.Join(dir, "talk.vtt")
+	srt := filepath.Join(dir, "talk.srt")
+	os.WriteFile(vtt, []byte(testVTT), 0o600)
+	os.WriteFile(srt, []byte(testSRT), 0o600)
+	results, err := client.BatchExtractFiles(ctx, []string{vtt, srt, vtt})
+	if err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+	for i, want := range []string{"webvtt", "subtitles", "webvtt"} {
+		if configProfile(results[i]) != want || results[i].Content[len(results[i].Content)-len(want):] != want {
+			t.Fatalf("item %d: expected profile %q, got %q (%q)", i, want, configProfile(results[i]), results[i].Content)
+		}
+	}
+
+	// Without a match and without a default, the base config applies.
+	config.Router.Routes = nil
+	result, err = client.ExtractBytes(ctx, []byte(testSRT), mimeSRT)
+	if err != nil || result.Content != wantSRTContent+"|base" || configProfile(result) != "" {
+		t.Fatalf("expected the base config, got %v, %v", result, err)
+	}
+
+	config.Router.Default = "missing"
+	_, err = client.ExtractBytes(ctx, []byte(testSRT), mimeSRT)
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a ValidationError for an unknown profile, got %v", err)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	fp := FingerprintBytes([]byte("%PDF-1.7\n"), "")
+	if fp.MimeType != "application/pdf" || fp.Size != 9 || fp.Extension != "" {
+		t.Fatalf("unexpected fingerprint: %+v", fp)
+	}
+	path := filepath.Join(t.TempDir(), "Notes.MD")
+	os.WriteFile(path, []byte("# Notes"), 0o600)
+	fp, err := FingerprintFile(path)
+	if err != nil || fp.Extension != ".md" || fp.Size != 7 || string(fp.Head) != "# Notes" {
+		t.Fatalf("unexpected fingerprint: %+v, %v", fp, err)
+	}
+	if !MatchExtension("md")(fp) || MatchExtension(".txt")(fp) || !MatchSizeAtLeast(7)(fp) || MatchSizeAtLeast(8)(fp) {
+		t.Fatalf("unexpected matcher results for %+v", fp)
+	}
+	if !MatchMIME("image/*")(DocumentFingerprint{MimeType: "image/png"}) || MatchMIME("image/*")(DocumentFingerprint{MimeType: "imagery/png"}) {
+		t.Fatalf("unexpected MIME wildcard matching")
+	}
+	if _, err := FingerprintFile(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Fatalf("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,390 @@
+package kreuzberg
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// SanitizeAction selects what the sanitization pass does with suspicious content.
+type SanitizeAction string
+
+const (
+	// SanitizeFlag reports findings and leaves the result unchanged.
+	SanitizeFlag SanitizeAction = "flag"
+	// SanitizeStrip reports findings and removes them: invisible characters, hidden text and
+	// sentences with instructions from the content, chunks and pages, and suspicious metadata
+	// fields. Chunk offsets are not adjusted.
+	SanitizeStrip SanitizeAction = "strip"
+)
+
+var sanitizeActions = []SanitizeAction{SanitizeFlag, SanitizeStrip}
+
+// Validate reports an error for an unknown action. The empty action flags.
+func (a SanitizeAction) Validate() error {
+	if a == "" || slices.Contains(sanitizeActions, a) {
+		return nil
+	}
+	return invalidConfigValue("sanitize action", string(a), stringValues(sanitizeActions))
+}
+
+// SanitizeConfig enables a pass that looks for likely prompt-injection content in extracted
+// text, for results fed directly into LLM agents. It is a heuristic defense: it catches common
+// phrasings and hiding tricks, not every attack, and may flag legitimate text that discusses
+// prompts.
+type SanitizeConfig struct {
+	// Action selects whether findings are only reported or also removed (default SanitizeFlag).
+	Action SanitizeAction
+	// Patterns are matched, in addition to the built-in ones, against the content and metadata
+	// to find instructions, e.g. phrasings specific to the agents the output is fed to.
+	Patterns []*regexp.Regexp
+}
+
+// Kinds of SanitizationFinding.
+const (
+	// SanitizeFindingHiddenCharacters is invisible Unicode, such as zero-width, bidirectional
+	// control or tag characters; tag characters can spell out text no reader sees.
+	SanitizeFindingHiddenCharacters = "hidden_characters"
+	// SanitizeFindingHiddenText is text of HTML elements styled to be invisible (display:none,
+	// visibility:hidden, zero font size or opacity, white text) that made it into the content.
+	SanitizeFindingHiddenText = "hidden_text"
+	// SanitizeFindingInstruction is text addressing a language model, such as "ignore previous
+	// instructions" or chat template tokens.
+	SanitizeFindingInstruction = "instruction"
+)
+
+// SanitizationFinding is one piece of suspicious content.
+type SanitizationFinding struct {
+	Kind string `json:"kind"`
+	// Location is "content" or "metadata.<field>".
+	Location string `json:"location"`
+	// Excerpt is the suspicious text, shortened, with invisible characters spelled out.
+	Excerpt string `json:"excerpt"`
+}
+
+// SanitizationReport lists the findings of the sanitization pass.
+type SanitizationReport struct {
+	Action   SanitizeAction        `json:"action"`
+	Findings []SanitizationFinding `json:"findings"`
+}
+
+// Sanitization returns the report of the sanitization pass, stored in
+// Additional["sanitization"] when ExtractionConfig.Sanitize is set and something was found.
+func (m Metadata) Sanitization() (*SanitizationReport, bool) {
+	raw, ok := m.Additional["sanitization"]
+	if !ok {
+		return nil, false
+	}
+	var report SanitizationReport
+	if err := json.Unmarshal(raw, &report); err != nil {
+		return nil, false
+	}
+	return &report, true
+}
+
+// sanitizeExcerptLength bounds the length of finding excerpts, in runes.
+const sanitizeExcerptLength = 120
+
+// instructionPatterns match common prompt-injection phrasings.
+var instructionPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(ignore|disregard|forget|override)\s+(all\s+|any\s+)?(of\s+)?(the\s+|your\s+)?(previous|prior|above|earlier|preceding|original)\s+(instructions|prompts?|messages|context|rules)`),
+	regexp.MustCompile(`(?i)\byou\s+are\s+now\s+(a|an|in|the)\b`),
+	regexp.MustCompile(`(?i)\b(new|updated|real)\s+(system\s+)?instructions?\s*:`),
+	regexp.MustCompile(`(?i)\b(reveal|print|show|repeat|output)\s+(your|the)\s+(system\s+prompt|hidden\s+instructions|instructions\s+above)`),
+	regexp.MustCompile(`(?i)\bdo\s+not\s+(tell|inform|mention\s+(this\s+)?to)\s+the\s+user\b`),
+	regexp.MustCompile(`<\|(im_start|im_end|system|user|assistant|endoftext)\|>|\[/?INST\]|<</?SYS>>`),
+}
+
+// hiddenElementPattern matches HTML elements whose style hides them. Nested elements of the
+// same name end the match early, which only shortens the hidden text found.
+var hiddenElementPattern = regexp.MustCompile(`(?is)<([a-z][a-z0-9]*)\b[^>]*\bstyle\s*=\s*["']([^"']*)["'][^>]*>(.*?)</([a-z][a-z0-9]*)\s*>`)
+
+var (
+	hiddenStylePattern = regexp.MustCompile(`(?i)display\s*:\s*none|visibility\s*:\s*hidden|font-size\s*:\s*0(\.0*)?(px|pt|em|rem|%)?\s*(;|$)|opacity\s*:\s*0(\.0*)?\s*(;|$)|(^|[;\s])color\s*:\s*(#fff\b|#ffffff\b|white\b|rgb\(\s*255\s*,\s*255\s*,\s*255\s*\))`)
+	htmlTagPattern     = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// hiddenRune reports whether r is an invisible character used to hide text.
+func hiddenRune(r rune) bool {
+	switch {
+	case r >= 0x200B && r <= 0x200F, r >= 0x202A && r <= 0x202E, r >= 0x2060 && r <= 0x2064,
+		r >= 0x2066 && r <= 0x2069, r == 0xFEFF, r >= 0xE0000 && r <= 0xE007F:
+		return true
+	}
+	return false
+}
+
+// sanitizer runs the sanitization pass over one result.
+type sanitizer struct {
+	strip    bool
+	patterns []*regexp.Regexp
+	findings []SanitizationFinding
+}
+
+// sanitizeResult looks for prompt-injection content in result, source being the document the
+// result was extracted from when available, and records a report when something was found.
+func sanitizeResult(result *ExtractionResult, source []byte, cfg *SanitizeConfig) error {
+	if err := cfg.Action.Validate(); err != nil {
+		return err
+	}
+	s := &sanitizer{strip: cfg.Action == SanitizeStrip, patterns: append(slices.Clip(instructionPatterns), cfg.Patterns...)}
+
+	texts := []*string{&result.Content}
+	for i := range result.Chunks {
+		texts = append(texts, &result.Chunks[i].Content)
+	}
+	for i := range result.Pages {
+		texts = append(texts, &result.Pages[i].Content)
+	}
+	// Findings are reported for the content; chunks and pages repeat it and are only cleaned.
+	s.hiddenCharacters("content", texts)
+	if isHTMLResult(result) && source != nil {
+		s.hiddenText(texts, source)
+	}
+	s.instructions("content", texts)
+	s.metadata(&result.Metadata)
+
+	if len(s.findings) == 0 {
+		return nil
+	}
+	report := SanitizationReport{Action: SanitizeFlag, Findings: s.findings}
+	if s.strip {
+		report.Action = SanitizeStrip
+	}
+	raw, err := json.Marshal(report)
+	if err != nil {
+		return newSerializationErrorWithContext("failed to encode sanitization report", err, ErrorCodeValidation, nil)
+	}
+	if result.Metadata.Additional == nil {
+		result.Metadata.Additional = map[string]json.RawMessage{}
+	}
+	result.Metadata.Additional["sanitization"] = raw
+	result.addDiagnostic("sanitize", DiagnosticSeverityWarning, fmt.Sprintf("%d possible prompt-injection findings (%s)", len(s.findings), report.Action))
+	return nil
+}
+
+func (s *sanitizer) report(kind, location, excerpt string) {
+	s.findings = append(s.findings, SanitizationFinding{Kind: kind, Location: location, Excerpt: sanitizeExcerpt(excerpt)})
+}
+
+// hiddenCharacters reports runs of invisible characters in texts[0] and strips them from all
+// texts.
+func (s *sanitizer) hiddenCharacters(location string, texts []*string) {
+	text := *texts[0]
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if !hiddenRune(r) || (i == 0 && r == 0xFEFF) {
+			i += size
+			continue
+		}
+		start := i
+		for i < len(text) {
+			r, size := utf8.DecodeRuneInString(text[i:])
+			if !hiddenRune(r) {
+				break
+			}
+			i += size
+		}
+		s.report(SanitizeFindingHiddenCharacters, location, text[start:i])
+	}
+	if s.strip {
+		for _, t := range texts {
+			*t = strings.Map(func(r rune) rune {
+				if hiddenRune(r) {
+					return -1
+				}
+				return r
+			}, *t)
+		}
+	}
+}
+
+// hiddenText reports the text of hidden HTML elements in source that appears in texts[0], and
+// strips it from all texts.
+func (s *sanitizer) hiddenText(texts []*string, source []byte) {
+	for _, m := range hiddenElementPattern.FindAllSubmatch(source, -1) {
+		if !strings.EqualFold(string(m[1]), string(m[4])) || !hiddenStylePattern.Match(m[2]) {
+			continue
+		}
+		hidden := strings.Join(strings.Fields(html.UnescapeString(htmlTagPattern.ReplaceAllString(string(m[3]), " "))), " ")
+		if len(strings.Fields(hidden)) < 2 || !strings.Contains(collapseSpace(*texts[0]), hidden) {
+			continue
+		}
+		s.report(SanitizeFindingHiddenText, "content", hidden)
+		if s.strip {
+			for _, t := range texts {
+				*t = removeCollapsed(*t, hidden)
+			}
+		}
+	}
+}
+
+// instructions reports the sentences of texts[0] matching an instruction pattern and strips
+// matching sentences from all texts.
+func (s *sanitizer) instructions(location string, texts []*string) {
+	for _, sentence := range s.instructionSentences(*texts[0]) {
+		s.report(SanitizeFindingInstruction, location, sentence)
+	}
+	if s.strip {
+		for _, t := range texts {
+			for _, sentence := range s.instructionSentences(*t) {
+				*t = strings.Replace(*t, sentence, "", 1)
+			}
+		}
+	}
+}
+
+// instructionSentences returns the distinct sentences of text containing an instruction.
+func (s *sanitizer) instructionSentences(text string) []string {
+	var sentences []string
+	for _, pattern := range s.patterns {
+		for _, loc := range pattern.FindAllStringIndex(text, -1) {
+			start, end := sentenceBounds(text, loc[0], loc[1])
+			if sentence := text[start:end]; !slices.Contains(sentences, sentence) {
+				sentences = append(sentences, sentence)
+			}
+		}
+	}
+	return sentences
+}
+
+// sentenceBounds widens [start, end) to the sentence around it: from after the previous
+// sentence end or line break to the next one, inclusive.
+func sentenceBounds(text string, start, end int) (int, int) {
+	if i := strings.LastIndexAny(text[:start], ".!?\n"); i >= 0 {
+		start = i + 1
+	} else {
+		start = 0
+	}
+	if i := strings.IndexAny(text[end:], ".!?\n"); i >= 0 {
+		end += i + 1
+	} else {
+		end = len(text)
+	}
+	return start, end
+}
+
+// metadata reports and, when stripping, clears metadata fields with hidden characters or
+// instructions.
+func (s *sanitizer) metadata(m *Metadata) {
+	// finding returns the kind of suspicious content in value, or "".
+	finding := func(value string) string {
+		if strings.IndexFunc(value, hiddenRune) >= 0 {
+			return SanitizeFindingHiddenCharacters
+		}
+		if slices.ContainsFunc(s.patterns, func(p *regexp.Regexp) bool { return p.MatchString(value) }) {
+			return SanitizeFindingInstruction
+		}
+		return ""
+	}
+	check := func(name string, field **string) {
+		if *field == nil {
+			return
+		}
+		if kind := finding(**field); kind != "" {
+			s.report(kind, "metadata."+name, **field)
+			if s.strip {
+				*field = nil
+			}
+		}
+	}
+	checkList := func(name string, list *[]string) {
+		*list = slices.DeleteFunc(*list, func(value string) bool {
+			kind := finding(value)
+			if kind == "" {
+				return false
+			}
+			s.report(kind, "metadata."+name, value)
+			return s.strip
+		})
+	}
+
+	check("subject", &m.Subject)
+	if pdf := m.Format.Pdf; pdf != nil {
+		check("title", &pdf.Title)
+		check("subject", &pdf.Subject)
+		check("producer", &pdf.Producer)
+		check("created_by", &pdf.CreatedBy)
+		checkList("authors", &pdf.Authors)
+		checkList("keywords", &pdf.Keywords)
+	}
+	if h := m.Format.HTML; h != nil {
+		check("title", &h.Title)
+		check("description", &h.Description)
+		check("keywords", &h.Keywords)
+		check("author", &h.Author)
+		check("og_title", &h.OGTitle)
+		check("og_description", &h.OGDescription)
+		check("twitter_title", &h.TwitterTitle)
+		check("twitter_description", &h.TwitterDescription)
+	}
+	keys := make([]string, 0, len(m.Additional))
+	for key := range m.Additional {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		var value string
+		if json.Unmarshal(m.Additional[key], &value) != nil {
+			continue
+		}
+		if kind := finding(value); kind != "" {
+			s.report(kind, "metadata."+key, value)
+			if s.strip {
+				delete(m.Additional, key)
+			}
+		}
+	}
+}
+
+// isHTMLResult reports whether result was extracted from HTML.
+func isHTMLResult(result *ExtractionResult) bool {
+	return strings.HasPrefix(result.MimeType, "text/html") || strings.HasPrefix(result.MimeType, "application/xhtml")
+}
+
+// collapseSpace replaces runs of whitespace in s with single spaces.
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// removeCollapsed removes the first occurrence of phrase from s, matching any run of whitespace
+// in s against the single spaces of phrase.
+func removeCollapsed(s, phrase string) string {
+	words := strings.Fields(phrase)
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = regexp.QuoteMeta(word)
+	}
+	pattern := regexp.MustCompile(strings.Join(quoted, `\s+`))
+	if loc := pattern.FindStringIndex(s); loc != nil {
+		return s[:loc[0]] + s[loc[1]:]
+	}
+	return s
+}
+
+// sanitizeExcerpt shortens text for a finding and spells out invisible characters, decoding
+// tag characters to the ASCII they hide.
+func sanitizeExcerpt(text string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range strings.TrimSpace(text) {
+		if n == sanitizeExcerptLength {
+			b.WriteString("…")
+			break
+		}
+		switch {
+		case r >= 0xE0020 && r <= 0xE007E:
+			b.WriteRune(r - 0xE0000)
+		case hiddenRune(r):
+			fmt.Fprintf(&b, "<U+%04X>", r)
+		default:
+			b.WriteRune(r)
+		}
+		n++
+	}
+	return b.String()
+}
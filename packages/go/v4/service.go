@@ -0,0 +1,350 @@
+package kreuzberg
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// ServiceOptions configures RunService.
+type ServiceOptions struct {
+	// Handler builds the HTTP handler served with config, e.g. a Client's endpoints, a
+	// Coordinator or health handlers. It is called again with the new config on every reload;
+	// when it fails, the previous handler stays in place.
+	Handler func(config *ExtractionConfig) (http.Handler, error)
+	// ConfigPath is the config file loaded at startup and on every reload (see
+	// LoadExtractionConfigFromFile). When empty and LoadConfig is nil, the handler gets a nil
+	// config (library defaults).
+	ConfigPath string
+	// LoadConfig, when set, loads the config instead of ConfigPath.
+	LoadConfig func() (*ExtractionConfig, error)
+	// Addr is the TCP address to listen on (default ":8000"). It is ignored when Listener is
+	// set, the service runs under a Supervisor that shares a listener, or the service is
+	// socket-activated by systemd.
+	Addr string
+	// Listener, when set, is served instead of listening on Addr.
+	Listener net.Listener
+	// Reload triggers a reload in addition to SIGHUP, e.g. from an admin endpoint.
+	Reload <-chan struct{}
+	// OnReload, when set, is called after each reload with its error (nil on success).
+	OnReload func(error)
+	// ShutdownTimeout bounds how long in-flight requests may take to finish on shutdown
+	// (default 30s).
+	ShutdownTimeout time.Duration
+	// NativeMemory, when set, watches native memory usage while the service runs (see
+	// WatchNativeMemory), e.g. to fold it into GOMEMLIMIT. Where usage cannot be read, the
+	// service runs without it.
+	NativeMemory *NativeMemoryOptions
+	// Name is the Windows service name RunService reports to the service control manager
+	// (default "kreuzberg"). It is ignored on other platforms.
+	Name string
+}
+
+// RunService runs an HTTP server as a long-lived OS service until ctx is cancelled or the
+// process receives SIGINT or SIGTERM, then shuts it down gracefully and returns nil. On SIGHUP
+// (or opts.Reload) it reloads the config and swaps in a new handler; requests in flight finish
+// on the old one, and a config that fails to load or build keeps the service running as it was.
+//
+// Under systemd, RunService reports its state over NOTIFY_SOCKET (READY, RELOADING, STOPPING),
+// pings the watchdog when WatchdogSec is set, and accepts a socket-activated listener. Pair it
+// with a unit from SystemdUnit.
+//
+// Started by the Windows service control manager, RunService reports its state to it, stops on
+// a stop or shutdown request and reloads on a parameter change ("sc control <name>
+// paramchange"), since SIGHUP is not delivered on Windows. Register the service with
+// InstallWindowsService.
+func RunService(ctx context.Context, opts ServiceOptions) error {
+	if opts.Handler == nil {
+		return newValidationErrorWithContext("ServiceOptions.Handler is required", nil, ErrorCodeValidation, nil)
+	}
+	if handled, err := runPlatformService(ctx, opts); handled {
+		return err
+	}
+	return runService(ctx, opts, sdNotify, nil)
+}
+
+// runService runs the service, reporting its state (as systemd notification strings) to notify
+// and reloading on SIGHUP, opts.Reload and reload.
+func runService(ctx context.Context, opts ServiceOptions, notify func(state string), reload <-chan struct{}) error {
+	var current atomic.Pointer[http.Handler]
+	build := func() error {
+		config, err := loadServiceConfig(opts)
+		if err != nil {
+			return err
+		}
+		handler, err := opts.Handler(config)
+		if err != nil {
+			return err
+		}
+		current.Store(&handler)
+		return nil
+	}
+	if err := build(); err != nil {
+		return err
+	}
+
+	inner, err := serviceListener(opts)
+	if err != nil {
+		return err
+	}
+	if opts.NativeMemory != nil {
+		watchCtx, stopWatch := context.WithCancel(ctx)
+		watched := make(chan struct{})
+		go func() {
+			defer close(watched)
+			WatchNativeMemory(watchCtx, *opts.NativeMemory)
+		}()
+		defer func() {
+			stopWatch()
+			<-watched
+		}()
+	}
+	listener := &drainingListener{Listener: inner}
+	conns := &newConnTracker{conns: map[net.Conn]struct{}{}}
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			(*current.Load()).ServeHTTP(w, r)
+		}),
+		ReadHeaderTimeout: 30 * time.Second,
+		ConnState:         conns.track,
+	}
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(listener) }()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+	var watchdog <-chan time.Time
+	if interval := systemdWatchdogInterval(); interval > 0 {
+		ticker := time.NewTicker(interval / 2)
+		defer ticker.Stop()
+		watchdog = ticker.C
+	}
+	notify("READY=1")
+
+	rebuild := func() {
+		notify("RELOADING=1")
+		err := build()
+		if opts.OnReload != nil {
+			opts.OnReload(err)
+		}
+		notify("READY=1")
+	}
+	for {
+		select {
+		case err := <-served:
+			return newIOErrorWithContext("service stopped serving", err, ErrorCodeIo, nil)
+		case <-watchdog:
+			sdNotify("WATCHDOG=1")
+		case <-opts.Reload:
+			rebuild()
+		case <-reload:
+			rebuild()
+		case sig := <-signals:
+			if sig == syscall.SIGHUP {
+				rebuild()
+				continue
+			}
+			return shutdownService(server, listener, conns, opts.ShutdownTimeout, notify)
+		case <-ctx.Done():
+			return shutdownService(server, listener, conns, opts.ShutdownTimeout, notify)
+		}
+	}
+}
+
+func loadServiceConfig(opts ServiceOptions) (*ExtractionConfig, error) {
+	switch {
+	case opts.LoadConfig != nil:
+		return opts.LoadConfig()
+	case opts.ConfigPath != "":
+		return LoadExtractionConfigFromFile(opts.ConfigPath)
+	}
+	return nil, nil
+}
+
+// shutdownService stops accepting connections, gives connections that were already accepted a
+// moment to send their request, and then shuts the server down gracefully. http.Server.Shutdown
+// alone closes a connection whose request arrives after shutdown began without a response, which
+// would drop requests while a Supervisor replaces the process.
+func shutdownService(server *http.Server, listener *drainingListener, conns *newConnTracker, timeout time.Duration, notify func(state string)) error {
+	notify("STOPPING=1")
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	listener.Close()
+	for grace := time.Now().Add(min(time.Second, timeout/2)); conns.pending() && time.Now().Before(grace); {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := server.Shutdown(ctx); err != nil {
+		server.Close()
+		return newIOErrorWithContext("service did not shut down gracefully", err, ErrorCodeIo, nil)
+	}
+	return nil
+}
+
+// drainingListener makes Close idempotent, so the listener can be closed before Server.Shutdown
+// closes it again.
+type drainingListener struct {
+	net.Listener
+	once sync.Once
+	err  error
+}
+
+func (l *drainingListener) Close() error {
+	l.once.Do(func() { l.err = l.Listener.Close() })
+	return l.err
+}
+
+// newConnTracker records the connections that have not sent a request yet.
+type newConnTracker struct {
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+}
+
+func (t *newConnTracker) track(conn net.Conn, state http.ConnState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if state == http.StateNew {
+		t.conns[conn] = struct{}{}
+	} else {
+		delete(t.conns, conn)
+	}
+}
+
+func (t *newConnTracker) pending() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.conns) > 0
+}
+
+// serviceListener returns opts.Listener, the listener shared by a Supervisor, the first socket
+// passed by systemd socket activation (LISTEN_PID/LISTEN_FDS), or a new listener on opts.Addr.
+func serviceListener(opts ServiceOptions) (net.Listener, error) {
+	if opts.Listener != nil {
+		return opts.Listener, nil
+	}
+	if listener, err := supervisorListener(); listener != nil || err != nil {
+		return listener, err
+	}
+	if pid, _ := strconv.Atoi(os.Getenv("LISTEN_PID")); pid == os.Getpid() {
+		if fds, _ := strconv.Atoi(os.Getenv("LISTEN_FDS")); fds > 0 {
+			// Activated sockets start at file descriptor 3 (SD_LISTEN_FDS_START).
+			file := os.NewFile(3, "LISTEN_FD_3")
+			defer file.Close()
+			listener, err := net.FileListener(file)
+			if err != nil {
+				return nil, newIOErrorWithContext("failed to use the socket passed by systemd", err, ErrorCodeIo, nil)
+			}
+			return listener, nil
+		}
+	}
+	addr := opts.Addr
+	if addr == "" {
+		addr = ":8000"
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, newIOErrorWithContext(fmt.Sprintf("failed to listen on %s", addr), err, ErrorCodeIo, nil)
+	}
+	return listener, nil
+}
+
+// sdNotify sends a state update to the systemd service manager, if the process runs under one.
+// Errors are ignored: notifications are advisory.
+func sdNotify(state string) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return
+	}
+	conn, err := net.Dial("unixgram", socket)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	conn.Write([]byte(state))
+}
+
+// systemdWatchdogInterval returns the WatchdogSec of the unit, or 0 when the watchdog is off or
+// meant for another process.
+func systemdWatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// SystemdUnitOptions describes the service unit written by SystemdUnit.
+type SystemdUnitOptions struct {
+	// Description is the unit description (default "Kreuzberg extraction service").
+	Description string
+	// ExecStart is the command line that starts the service (required).
+	ExecStart string
+	// User and Group run the service under an unprivileged account, when set.
+	User  string
+	Group string
+	// WorkingDirectory is the service's working directory, when set.
+	WorkingDirectory string
+	// Environment lists KEY=value pairs set for the service.
+	Environment []string
+	// Watchdog enables the systemd watchdog with this timeout; RunService pings it.
+	Watchdog time.Duration
+}
+
+// SystemdUnit renders a systemd service unit for a binary that calls RunService: the service
+// notifies readiness (Type=notify), reloads with SIGHUP (systemctl reload) and is restarted on
+// failure. Install it as /etc/systemd/system/<name>.service and run
+// "systemctl daemon-reload && systemctl enable --now <name>".
+func SystemdUnit(opts SystemdUnitOptions) (string, error) {
+	if opts.ExecStart == "" {
+		return "", newValidationErrorWithContext("SystemdUnitOptions.ExecStart is required", nil, ErrorCodeValidation, nil)
+	}
+	for _, value := range append([]string{opts.Description, opts.ExecStart, opts.User, opts.Group, opts.WorkingDirectory}, opts.Environment...) {
+		if strings.ContainsAny(value, "\r\n") {
+			return "", newValidationErrorWithContext(fmt.Sprintf("unit value %q contains a line break", value), nil, ErrorCodeValidation, nil)
+		}
+	}
+	description := opts.Description
+	if description == "" {
+		description = "Kreuzberg extraction service"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "[Unit]\nDescription=%s\nWants=network-online.target\nAfter=network-online.target\n\n", description)
+	fmt.Fprintf(&b, "[Service]\nType=notify\nExecStart=%s\nExecReload=/bin/kill -HUP $MAINPID\n", opts.ExecStart)
+	b.WriteString("Restart=on-failure\nRestartSec=5s\nKillSignal=SIGTERM\n")
+	if opts.Watchdog > 0 {
+		fmt.Fprintf(&b, "WatchdogSec=%ds\n", max(1, int(opts.Watchdog/time.Second)))
+	}
+	if opts.User != "" {
+		fmt.Fprintf(&b, "User=%s\n", opts.User)
+	}
+	if opts.Group != "" {
+		fmt.Fprintf(&b, "Group=%s\n", opts.Group)
+	}
+	if opts.WorkingDirectory != "" {
+		fmt.Fprintf(&b, "WorkingDirectory=%s\n", opts.WorkingDirectory)
+	}
+	for _, env := range opts.Environment {
+		if !strings.Contains(env, "=") {
+			return "", newValidationErrorWithContext(fmt.Sprintf("environment entry %q is not KEY=value", env), nil, ErrorCodeValidation, nil)
+		}
+		fmt.Fprintf(&b, "Environment=%s\n", strconv.Quote(env))
+	}
+	b.WriteString("\n[Install]\nWantedBy=multi-user.target\n")
+	return b.String(), nil
+}
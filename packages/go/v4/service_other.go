@@ -0,0 +1,11 @@
+//go:build !windows
+
+package kreuzberg
+
+import "context"
+
+// runPlatformService reports that RunService needs no platform service manager glue here:
+// systemd is driven through NOTIFY_SOCKET by runService itself.
+func runPlatformService(context.Context, ServiceOptions) (bool, error) {
+	return false, nil
+}
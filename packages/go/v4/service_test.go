@@ -0,0 +1,178 @@
+package kreuzberg
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunServiceReloadsAndShutsDown(t *testing.T) {
+	dir, err := os.MkdirTemp("", "sd")
+	if err != nil {
+		t.Fatalf("temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	notify, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: filepath.Join(dir, "notify"), Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unix datagram sockets unavailable: %v", err)
+	}
+	defer notify.Close()
+	t.Setenv("NOTIFY_SOCKET", filepath.Join(dir, "notify"))
+	expectState := func(want string) {
+		t.Helper()
+		notify.SetReadDeadline(time.Now().Add(5 * time.Second))
+		buf := make([]byte, 64)
+		n, err := notify.Read(buf)
+		if err != nil || string(buf[:n]) != want {
+			t.Fatalf("expected notification %q, got %q, %v", want, buf[:n], err)
+		}
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	loads := 0
+	reload := make(chan struct{})
+	reloaded := make(chan error, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- RunService(ctx, ServiceOptions{
+			Listener: listener,
+			Reload:   reload,
+			OnReload: func(err error) { reloaded <- err },
+			LoadConfig: func() (*ExtractionConfig, error) {
+				loads++
+				if loads == 3 {
+					return nil, errors.New("broken config")
+				}
+				return &ExtractionConfig{Labels: map[string]string{"version": strconv.Itoa(loads)}}, nil
+			},
+			Handler: func(config *ExtractionConfig) (http.Handler, error) {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					io.WriteString(w, config.Labels["version"])
+				}), nil
+			},
+		})
+	}()
+	get := func() string {
+		t.Helper()
+		resp, err := http.Get("http://" + listener.Addr().String())
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	expectState("READY=1")
+	if v := get(); v != "1" {
+		t.Fatalf("expected the first config, got %q", v)
+	}
+	reload <- struct{}{}
+	expectState("RELOADING=1")
+	expectState("READY=1")
+	if err := <-reloaded; err != nil || get() != "2" {
+		t.Fatalf("expected the reloaded config, got %v", err)
+	}
+	reload <- struct{}{}
+	expectState("RELOADING=1")
+	expectState("READY=1")
+	if err := <-reloaded; err == nil || get() != "2" {
+		t.Fatalf("expected a failed reload to keep the running config, got %v", err)
+	}
+
+	cancel()
+	expectState("STOPPING=1")
+	if err := <-done; err != nil {
+		t.Fatalf("expected a graceful shutdown, got %v", err)
+	}
+}
+
+func TestRunServiceReportsToServiceManager(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	states := make(chan string, 8)
+	reload := make(chan struct{})
+	reloaded := make(chan error, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- runService(ctx, ServiceOptions{
+			Listener: listener,
+			OnReload: func(err error) { reloaded <- err },
+			Handler: func(*ExtractionConfig) (http.Handler, error) {
+				return http.NotFoundHandler(), nil
+			},
+		}, func(state string) { states <- state }, reload)
+	}()
+
+	expectState := func(want string) {
+		t.Helper()
+		select {
+		case got := <-states:
+			if got != want {
+				t.Fatalf("expected state %q, got %q", want, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected state %q", want)
+		}
+	}
+	expectState("READY=1")
+	reload <- struct{}{}
+	expectState("RELOADING=1")
+	expectState("READY=1")
+	if err := <-reloaded; err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	cancel()
+	expectState("STOPPING=1")
+	if err := <-done; err != nil {
+		t.Fatalf("expected a graceful shutdown, got %v", err)
+	}
+}
+
+func TestSystemdUnit(t *testing.T) {
+	unit, err := SystemdUnit(SystemdUnitOptions{
+		ExecStart:   "/usr/local/bin/extractd --config /etc/kreuzberg.toml",
+		User:        "kreuzberg",
+		Environment: []string{"RUST_LOG=info"},
+		Watchdog:    30 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("unit: %v", err)
+	}
+	for _, line := range []string{
+		"Type=notify",
+		"ExecStart=/usr/local/bin/extractd --config /etc/kreuzberg.toml",
+		"ExecReload=/bin/kill -HUP $MAINPID",
+		"WatchdogSec=30s",
+		"User=kreuzberg",
+		`Environment="RUST_LOG=info"`,
+		"WantedBy=multi-user.target",
+	} {
+		if !strings.Contains(unit, line+"\n") {
+			t.Fatalf("expected %q in unit:\n%s", line, unit)
+		}
+	}
+	if _, err := SystemdUnit(SystemdUnitOptions{ExecStart: "a\nExecStartPre=evil"}); err == nil {
+		t.Fatalf("expected line breaks to be rejected")
+	}
+	if _, err := SystemdUnit(SystemdUnitOptions{}); err == nil {
+		t.Fatalf("expected ExecStart to be required")
+	}
+}
@@ -0,0 +1,179 @@
+//go:build windows
+
+package kreuzberg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+const defaultWindowsServiceName = "kreuzberg"
+
+// runPlatformService runs the service under the Windows service control manager when the
+// process was started by it, and reports false for an interactive process.
+func runPlatformService(ctx context.Context, opts ServiceOptions) (bool, error) {
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		return true, newRuntimeErrorWithContext("failed to detect the Windows service control manager", err, ErrorCodeInternal, nil)
+	}
+	if !isService {
+		return false, nil
+	}
+	name := opts.Name
+	if name == "" {
+		name = defaultWindowsServiceName
+	}
+	handler := &windowsService{ctx: ctx, opts: opts}
+	if err := svc.Run(name, handler); err != nil {
+		return true, newRuntimeErrorWithContext(fmt.Sprintf("failed to run Windows service %s", name), err, ErrorCodeInternal, nil)
+	}
+	return true, handler.err
+}
+
+// windowsService adapts runService to the service control manager: stop and shutdown requests
+// cancel the service, and a parameter change reloads it.
+type windowsService struct {
+	ctx  context.Context
+	opts ServiceOptions
+	err  error
+}
+
+const windowsServiceAccepts = svc.AcceptStop | svc.AcceptShutdown | svc.AcceptParamChange
+
+func (s *windowsService) Execute(_ []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+
+	reload := make(chan struct{}, 1)
+	notify := func(state string) {
+		switch state {
+		case "READY=1":
+			status <- svc.Status{State: svc.Running, Accepts: windowsServiceAccepts}
+		case "STOPPING=1":
+			status <- svc.Status{State: svc.StopPending}
+		}
+	}
+	done := make(chan error, 1)
+	go func() { done <- runService(ctx, s.opts, notify, reload) }()
+
+	for {
+		select {
+		case err := <-done:
+			s.err = err
+			if err != nil {
+				// A service-specific exit code makes the service control manager apply the
+				// recovery actions set by InstallWindowsService.
+				return true, 1
+			}
+			return false, 0
+		case req := <-requests:
+			switch req.Cmd {
+			case svc.Interrogate:
+				status <- req.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				cancel()
+			case svc.ParamChange:
+				select {
+				case reload <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}
+}
+
+// WindowsServiceOptions describes the service registered by InstallWindowsService.
+type WindowsServiceOptions struct {
+	// Name is the service name (default "kreuzberg"); pass the same name as ServiceOptions.Name.
+	Name string
+	// DisplayName is the name shown in the Services console (default "Kreuzberg extraction
+	// service").
+	DisplayName string
+	// Description is the service description, when set.
+	Description string
+	// ExecPath is the binary that calls RunService (required).
+	ExecPath string
+	// Args are passed to the binary on every start.
+	Args []string
+	// Account and Password run the service under that account instead of LocalSystem, when set.
+	Account  string
+	Password string
+	// Manual leaves the service to be started by hand instead of at boot.
+	Manual bool
+}
+
+// InstallWindowsService registers a service with the Windows service control manager for a
+// binary that calls RunService. The service starts at boot unless opts.Manual is set and is
+// restarted after 5 seconds when it fails. Start it with "sc start <name>" and reload its
+// config with "sc control <name> paramchange". It needs administrator rights.
+func InstallWindowsService(opts WindowsServiceOptions) error {
+	if opts.ExecPath == "" {
+		return newValidationErrorWithContext("WindowsServiceOptions.ExecPath is required", nil, ErrorCodeValidation, nil)
+	}
+	name := opts.Name
+	if name == "" {
+		name = defaultWindowsServiceName
+	}
+	displayName := opts.DisplayName
+	if displayName == "" {
+		displayName = "Kreuzberg extraction service"
+	}
+	startType := uint32(mgr.StartAutomatic)
+	if opts.Manual {
+		startType = mgr.StartManual
+	}
+
+	manager, err := mgr.Connect()
+	if err != nil {
+		return newIOErrorWithContext("failed to connect to the Windows service control manager", err, ErrorCodeIo, nil)
+	}
+	defer manager.Disconnect()
+	service, err := manager.CreateService(name, opts.ExecPath, mgr.Config{
+		DisplayName:      displayName,
+		Description:      opts.Description,
+		StartType:        startType,
+		ServiceStartName: opts.Account,
+		Password:         opts.Password,
+	}, opts.Args...)
+	if err != nil {
+		return newIOErrorWithContext(fmt.Sprintf("failed to install Windows service %s", name), err, ErrorCodeIo, nil)
+	}
+	defer service.Close()
+	restart := mgr.RecoveryAction{Type: mgr.ServiceRestart, Delay: 5 * time.Second}
+	if err := service.SetRecoveryActions([]mgr.RecoveryAction{restart, restart, restart}, uint32((24 * time.Hour).Seconds())); err != nil {
+		return newIOErrorWithContext(fmt.Sprintf("failed to set recovery actions of Windows service %s", name), err, ErrorCodeIo, nil)
+	}
+	return nil
+}
+
+// RemoveWindowsService unregisters the named service (default "kreuzberg"). A running service
+// is removed once it stops. It needs administrator rights.
+func RemoveWindowsService(name string) error {
+	if name == "" {
+		name = defaultWindowsServiceName
+	}
+	manager, err := mgr.Connect()
+	if err != nil {
+		return newIOErrorWithContext("failed to connect to the Windows service control manager", err, ErrorCodeIo, nil)
+	}
+	defer manager.Disconnect()
+	service, err := manager.OpenService(name)
+	if err != nil {
+		if errors.Is(err, windows.ERROR_SERVICE_DOES_NOT_EXIST) {
+			return newValidationErrorWithContext(fmt.Sprintf("Windows service %s is not installed", name), err, ErrorCodeValidation, nil)
+		}
+		return newIOErrorWithContext(fmt.Sprintf("failed to open Windows service %s", name), err, ErrorCodeIo, nil)
+	}
+	defer service.Close()
+	if err := service.Delete(); err != nil {
+		return newIOErrorWithContext(fmt.Sprintf("failed to remove Windows service %s", name), err, ErrorCodeIo, nil)
+	}
+	return nil
+}
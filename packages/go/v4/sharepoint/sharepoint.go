@@ -0,0 +1,490 @@
+// Package sharepoint extracts documents stored in SharePoint document libraries and OneDrive
+// through Microsoft Graph.
+//
+// A Connector enumerates a drive folder, downloads its files and extracts them with a bounded
+// number of concurrent extractions. Sync uses Graph delta queries, so an ingestion job can store
+// the returned token and only process files that changed since its previous run:
+//
+//	conn := &sharepoint.Connector{AccessToken: token, Concurrency: 4}
+//	driveID, err := conn.SiteDrive(ctx, "https://contoso.sharepoint.com/sites/Legal")
+//	if err != nil {
+//		return err
+//	}
+//	next, err := conn.Sync(ctx, sharepoint.Folder{DriveID: driveID, Path: "Contracts"}, lastToken,
+//		func(r sharepoint.Result) error {
+//			if r.Item.Deleted {
+//				return index.Delete(r.Item.ID)
+//			}
+//			if r.Err != nil {
+//				log.Printf("%s: %v", r.Item.Path, r.Err)
+//				return nil
+//			}
+//			return index.Put(r.Item.ID, r.Extraction)
+//		})
+//
+// The package does not acquire tokens itself: pass an access token with the Files.Read.All or
+// Sites.Read.All scope, or an HTTPClient that authorizes its requests.
+package sharepoint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	kreuzberg "github.com/kreuzberg-dev/kreuzberg/packages/go/v4"
+)
+
+const (
+	// DefaultBaseURL is the Microsoft Graph v1.0 endpoint.
+	DefaultBaseURL     = "https://graph.microsoft.com/v1.0"
+	defaultConcurrency = 4
+	maxAttempts        = 3
+	// Dependency names the Graph API for kreuzberg.SetDependencyLimit; every request, including
+	// downloads and retries, is throttled by its limit.
+	Dependency = "sharepoint"
+)
+
+// Connector lists, downloads and extracts drive items. The zero value is usable once an
+// AccessToken or an authorizing HTTPClient is set. A Connector is safe for concurrent use.
+type Connector struct {
+	// HTTPClient sends Graph requests (default http.DefaultClient).
+	HTTPClient *http.Client
+	// AccessToken is sent as an OAuth 2.0 bearer token when set.
+	AccessToken string
+	// BaseURL overrides the Graph endpoint (default DefaultBaseURL).
+	BaseURL string
+	// Client extracts downloaded files with its config and plugins (default kreuzberg.NewClient(nil)).
+	Client *kreuzberg.Client
+	// Concurrency limits concurrent downloads and extractions (default 4).
+	Concurrency int
+	// MaxFileSize skips files larger than this many bytes (0 = no limit). Skipped files are
+	// reported with ErrFileTooLarge.
+	MaxFileSize int64
+}
+
+// Folder identifies a folder in a drive.
+type Folder struct {
+	// DriveID is the ID of the document library or OneDrive. Empty means the signed-in user's
+	// OneDrive (/me/drive).
+	DriveID string
+	// Path is the folder path relative to the drive root; empty means the whole drive.
+	Path string
+}
+
+// Item is a file in a drive.
+type Item struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Path is the file's path relative to the drive root, e.g. "Contracts/2024/nda.docx".
+	Path         string    `json:"path"`
+	MimeType     string    `json:"mime_type,omitempty"`
+	Size         int64     `json:"size"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified time.Time `json:"last_modified"`
+	WebURL       string    `json:"web_url,omitempty"`
+	// Deleted is set for items removed since the previous Sync; only ID is populated.
+	Deleted bool `json:"deleted,omitempty"`
+
+	driveID     string
+	downloadURL string
+}
+
+// Result is the outcome of processing one item.
+type Result struct {
+	Item Item
+	// Extraction is the extraction result; nil for deleted items and failures.
+	Extraction *kreuzberg.ExtractionResult
+	// Err reports a download or extraction failure for this item.
+	Err error
+}
+
+// ErrFileTooLarge is reported in Result.Err for files above Connector.MaxFileSize.
+var ErrFileTooLarge = fmt.Errorf("sharepoint: file exceeds MaxFileSize")
+
+// GraphError is an error response from Microsoft Graph.
+type GraphError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *GraphError) Error() string {
+	return fmt.Sprintf("sharepoint: graph request failed (HTTP %d %s): %s", e.StatusCode, e.Code, e.Message)
+}
+
+// driveItem is the subset of the Graph driveItem resource the connector reads.
+type driveItem struct {
+	ID                   string    `json:"id"`
+	Name                 string    `json:"name"`
+	Size                 int64     `json:"size"`
+	ETag                 string    `json:"eTag"`
+	LastModifiedDateTime time.Time `json:"lastModifiedDateTime"`
+	WebURL               string    `json:"webUrl"`
+	DownloadURL          string    `json:"@microsoft.graph.downloadUrl"`
+	File                 *struct {
+		MimeType string `json:"mimeType"`
+	} `json:"file"`
+	Folder          *struct{} `json:"folder"`
+	Deleted         *struct{} `json:"deleted"`
+	ParentReference struct {
+		DriveID string `json:"driveId"`
+		Path    string `json:"path"`
+	} `json:"parentReference"`
+}
+
+type itemPage struct {
+	Value     []driveItem `json:"value"`
+	NextLink  string      `json:"@odata.nextLink"`
+	DeltaLink string      `json:"@odata.deltaLink"`
+}
+
+func (c *Connector) baseURL() string {
+	if c.BaseURL != "" {
+		return strings.TrimRight(c.BaseURL, "/")
+	}
+	return DefaultBaseURL
+}
+
+func (c *Connector) driveURL(driveID string) string {
+	if driveID == "" {
+		return c.baseURL() + "/me/drive"
+	}
+	return c.baseURL() + "/drives/" + url.PathEscape(driveID)
+}
+
+// folderURL addresses a folder by path, e.g. /drives/{id}/root:/Contracts/2024:.
+func (c *Connector) folderURL(folder Folder) string {
+	p := strings.Trim(folder.Path, "/")
+	if p == "" {
+		return c.driveURL(folder.DriveID) + "/root"
+	}
+	segments := strings.Split(p, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return c.driveURL(folder.DriveID) + "/root:/" + strings.Join(segments, "/") + ":"
+}
+
+// get performs a GET request and returns the response body, retrying throttled requests.
+// Pre-authenticated download URLs are fetched without the bearer token.
+func (c *Connector) get(ctx context.Context, rawURL string, authorize bool, limit int64) ([]byte, error) {
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		if authorize && c.AccessToken != "" {
+			req.Header.Set("Authorization", "Bearer "+c.AccessToken)
+		}
+		release, err := kreuzberg.AcquireDependency(ctx, Dependency)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		body, err := readBody(resp.Body, limit)
+		resp.Body.Close()
+		release()
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode < 300 {
+			return body, nil
+		}
+
+		if (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) && attempt < maxAttempts {
+			delay := time.Duration(attempt) * time.Second
+			if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+				delay = time.Duration(seconds) * time.Second
+			}
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(delay):
+			}
+			continue
+		}
+		graphErr := &GraphError{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+		var envelope struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(body, &envelope) == nil && envelope.Error.Code != "" {
+			graphErr.Code, graphErr.Message = envelope.Error.Code, envelope.Error.Message
+		}
+		return nil, graphErr
+	}
+}
+
+func readBody(r io.Reader, limit int64) ([]byte, error) {
+	if limit > 0 {
+		r = io.LimitReader(r, limit+1)
+	}
+	data, err := io.ReadAll(r)
+	if err == nil && limit > 0 && int64(len(data)) > limit {
+		return nil, ErrFileTooLarge
+	}
+	return data, err
+}
+
+func (c *Connector) getJSON(ctx context.Context, rawURL string, out any) error {
+	body, err := c.get(ctx, rawURL, true, 0)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("sharepoint: decode graph response: %w", err)
+	}
+	return nil
+}
+
+// SiteDrive returns the ID of the default document library of a SharePoint site, given its URL
+// (e.g. https://contoso.sharepoint.com/sites/Legal).
+func (c *Connector) SiteDrive(ctx context.Context, siteURL string) (string, error) {
+	u, err := url.Parse(siteURL)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("sharepoint: invalid site URL %q", siteURL)
+	}
+	site := c.baseURL() + "/sites/" + u.Host
+	if p := strings.Trim(u.Path, "/"); p != "" {
+		site += ":/" + p + ":"
+	}
+	var drive struct {
+		ID string `json:"id"`
+	}
+	if err := c.getJSON(ctx, site+"/drive", &drive); err != nil {
+		return "", err
+	}
+	return drive.ID, nil
+}
+
+// itemPath returns the path of a driveItem relative to the drive root, from its parent
+// reference ("/drive/root:/Contracts" or "/drives/{id}/root:/Contracts").
+func itemPath(item driveItem) string {
+	parent := item.ParentReference.Path
+	if i := strings.Index(parent, "root:"); i >= 0 {
+		parent = parent[i+len("root:"):]
+	}
+	parent, _ = url.PathUnescape(parent)
+	return strings.TrimPrefix(path.Join(parent, item.Name), "/")
+}
+
+// toItem converts a driveItem listed from driveID.
+func toItem(item driveItem, itemPath, driveID string) Item {
+	out := Item{
+		ID:           item.ID,
+		Name:         item.Name,
+		Path:         itemPath,
+		Size:         item.Size,
+		ETag:         item.ETag,
+		LastModified: item.LastModifiedDateTime,
+		WebURL:       item.WebURL,
+		Deleted:      item.Deleted != nil,
+		driveID:      driveID,
+		downloadURL:  item.DownloadURL,
+	}
+	if item.ParentReference.DriveID != "" {
+		out.driveID = item.ParentReference.DriveID
+	}
+	if item.File != nil {
+		out.MimeType = item.File.MimeType
+	}
+	return out
+}
+
+// List returns the files below folder, recursing into subfolders.
+func (c *Connector) List(ctx context.Context, folder Folder) ([]Item, error) {
+	var items []Item
+	var walk func(folderURL, prefix string) error
+	walk = func(folderURL, prefix string) error {
+		next := folderURL + "/children"
+		for next != "" {
+			var page itemPage
+			if err := c.getJSON(ctx, next, &page); err != nil {
+				return err
+			}
+			for _, child := range page.Value {
+				childPath := path.Join(prefix, child.Name)
+				switch {
+				case child.Folder != nil:
+					if err := walk(c.driveURL(folder.DriveID)+"/items/"+url.PathEscape(child.ID), childPath); err != nil {
+						return err
+					}
+				case child.File != nil:
+					items = append(items, toItem(child, childPath, folder.DriveID))
+				}
+			}
+			next = page.NextLink
+		}
+		return nil
+	}
+	if err := walk(c.folderURL(folder), strings.Trim(folder.Path, "/")); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Extract downloads and extracts items, calling fn with each result as it completes. At most
+// Concurrency items are processed at once; fn is never called concurrently. Processing stops at
+// the first error returned by fn, which Extract returns.
+func (c *Connector) Extract(ctx context.Context, items []Item, fn func(Result) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	concurrency := c.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+	client := c.Client
+	if client == nil {
+		client = kreuzberg.NewClient(nil)
+	}
+
+	var (
+		mu      sync.Mutex
+		fnErr   error
+		wg      sync.WaitGroup
+		pending = make(chan struct{}, concurrency)
+	)
+	deliver := func(r Result) {
+		mu.Lock()
+		defer mu.Unlock()
+		if fnErr != nil {
+			return
+		}
+		if err := fn(r); err != nil {
+			fnErr = err
+			cancel()
+		}
+	}
+
+	for _, item := range items {
+		if item.Deleted {
+			deliver(Result{Item: item})
+			continue
+		}
+		select {
+		case pending <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(item Item) {
+			defer wg.Done()
+			defer func() { <-pending }()
+			result, err := c.extractItem(ctx, client, item)
+			deliver(Result{Item: item, Extraction: result, Err: err})
+		}(item)
+	}
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if fnErr != nil {
+		return fnErr
+	}
+	return ctx.Err()
+}
+
+func (c *Connector) extractItem(ctx context.Context, client *kreuzberg.Client, item Item) (*kreuzberg.ExtractionResult, error) {
+	if c.MaxFileSize > 0 && item.Size > c.MaxFileSize {
+		return nil, ErrFileTooLarge
+	}
+	var data []byte
+	var err error
+	if item.downloadURL != "" {
+		data, err = c.get(ctx, item.downloadURL, false, c.MaxFileSize)
+	} else {
+		// The content endpoint redirects to a pre-authenticated URL; net/http drops the
+		// Authorization header when the redirect leaves the Graph host.
+		data, err = c.get(ctx, c.driveURL(item.driveID)+"/items/"+url.PathEscape(item.ID)+"/content", true, c.MaxFileSize)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	mimeType := item.MimeType
+	if mimeType == "" || mimeType == "application/octet-stream" {
+		if byExt := mime.TypeByExtension(path.Ext(item.Name)); byExt != "" {
+			mimeType, _, _ = mime.ParseMediaType(byExt)
+		}
+	}
+	return client.ExtractBytes(ctx, data, mimeType)
+}
+
+// Sync processes the files below folder that changed since token, a value returned by an earlier
+// Sync (empty for a full run). Deleted items are passed to fn with Item.Deleted set and are not
+// filtered by folder, since Graph omits their paths. Sync returns the token for the next run;
+// it is only returned when every item was delivered, so a failed run is retried from the old
+// token.
+func (c *Connector) Sync(ctx context.Context, folder Folder, token string, fn func(Result) error) (string, error) {
+	// Delta queries are only supported on the drive root for SharePoint and OneDrive for
+	// Business, so the folder is applied as a path filter.
+	next := token
+	if next == "" {
+		next = c.driveURL(folder.DriveID) + "/root/delta"
+	}
+	prefix := strings.Trim(folder.Path, "/")
+
+	var changed []Item
+	seen := map[string]int{}
+	for {
+		var page itemPage
+		if err := c.getJSON(ctx, next, &page); err != nil {
+			return "", err
+		}
+		for _, entry := range page.Value {
+			if entry.Folder != nil && entry.Deleted == nil {
+				continue
+			}
+			item := toItem(entry, itemPath(entry), folder.DriveID)
+			if !item.Deleted && (entry.File == nil || !withinFolder(item.Path, prefix)) {
+				continue
+			}
+			// An item can appear more than once in a delta; the last state wins.
+			if i, ok := seen[item.ID]; ok {
+				changed[i] = item
+				continue
+			}
+			seen[item.ID] = len(changed)
+			changed = append(changed, item)
+		}
+		if page.DeltaLink != "" {
+			next = page.DeltaLink
+			break
+		}
+		if page.NextLink == "" {
+			return "", fmt.Errorf("sharepoint: delta response has neither a next nor a delta link")
+		}
+		next = page.NextLink
+	}
+
+	if err := c.Extract(ctx, changed, fn); err != nil {
+		return "", err
+	}
+	return next, nil
+}
+
+func withinFolder(itemPath, prefix string) bool {
+	return prefix == "" || itemPath == prefix || strings.HasPrefix(itemPath, prefix+"/")
+}
@@ -0,0 +1,166 @@
+package sharepoint_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/kreuzberg-dev/kreuzberg/packages/go/v4/sharepoint"
+)
+
+const srt = "1\n00:00:01,000 --> 00:00:02,000\nHello there.\n\n"
+
+// fakeGraph serves a drive with a Docs folder and a delta feed that changes after the first sync.
+func fakeGraph(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var inflight, peak atomic.Int32
+	throttled := false
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/download/") {
+			if r.Header.Get("Authorization") != "" {
+				t.Errorf("download URL received the bearer token")
+			}
+			if n := inflight.Add(1); n > peak.Load() {
+				peak.Store(n)
+			}
+			defer inflight.Add(-1)
+			w.Write([]byte(srt))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"code":"InvalidAuthenticationToken","message":"Access token is empty."}}`))
+			return
+		}
+		file := func(id, name, parent string) string {
+			return `{"id":"` + id + `","name":"` + name + `","size":60,"file":{"mimeType":"application/x-subrip"},` +
+				`"parentReference":{"driveId":"d1","path":"/drives/d1/root:` + parent + `"},` +
+				`"@microsoft.graph.downloadUrl":"` + server.URL + `/download/` + id + `"}`
+		}
+		switch r.URL.Path + "?" + r.URL.RawQuery {
+		case "/sites/contoso.sharepoint.com:/sites/Legal:/drive?":
+			w.Write([]byte(`{"id":"d1"}`))
+		case "/drives/d1/root:/Docs:/children?":
+			w.Write([]byte(`{"value":[` + file("a", "a.srt", "/Docs") + `,{"id":"sub","name":"Sub","folder":{}}],"@odata.nextLink":"` + server.URL + `/drives/d1/root:/Docs:/children?page=2"}`))
+		case "/drives/d1/root:/Docs:/children?page=2":
+			w.Write([]byte(`{"value":[` + file("b", "b.srt", "/Docs") + `]}`))
+		case "/drives/d1/items/sub/children?":
+			w.Write([]byte(`{"value":[` + file("c", "c.srt", "/Docs/Sub") + `]}`))
+		case "/drives/d1/root/delta?":
+			if !throttled {
+				throttled = true
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.Write([]byte(`{"value":[{"id":"docs","name":"Docs","folder":{}},` + file("a", "a.srt", "/Docs") + `,` + file("c", "c.srt", "/Docs/Sub") + `,` +
+				file("x", "x.srt", "/Other") + `],"@odata.nextLink":"` + server.URL + `/drives/d1/root/delta?token=p2"}`))
+		case "/drives/d1/root/delta?token=p2":
+			w.Write([]byte(`{"value":[` + file("b", "b.srt", "/Docs") + `],"@odata.deltaLink":"` + server.URL + `/drives/d1/root/delta?token=t1"}`))
+		case "/drives/d1/root/delta?token=t1":
+			w.Write([]byte(`{"value":[{"id":"a","deleted":{"state":"deleted"}},` + file("b", "b.srt", "/Docs") + `],"@odata.deltaLink":"` + server.URL + `/drives/d1/root/delta?token=t2"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"code":"itemNotFound","message":"The resource could not be found."}}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &peak
+}
+
+func TestListRecursesAndFollowsPages(t *testing.T) {
+	server, _ := fakeGraph(t)
+	conn := &sharepoint.Connector{AccessToken: "secret", BaseURL: server.URL}
+
+	driveID, err := conn.SiteDrive(context.Background(), "https://contoso.sharepoint.com/sites/Legal")
+	if err != nil || driveID != "d1" {
+		t.Fatalf("SiteDrive = %q, %v", driveID, err)
+	}
+	items, err := conn.List(context.Background(), sharepoint.Folder{DriveID: driveID, Path: "/Docs/"})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	var paths []string
+	for _, item := range items {
+		paths = append(paths, item.Path)
+	}
+	if strings.Join(paths, ",") != "Docs/a.srt,Docs/Sub/c.srt,Docs/b.srt" {
+		t.Fatalf("unexpected items: %v", paths)
+	}
+}
+
+func TestSyncExtractsChangesIncrementally(t *testing.T) {
+	server, peak := fakeGraph(t)
+	conn := &sharepoint.Connector{AccessToken: "secret", BaseURL: server.URL, Concurrency: 2}
+	folder := sharepoint.Folder{DriveID: "d1", Path: "Docs"}
+
+	var got []string
+	token, err := conn.Sync(context.Background(), folder, "", func(r sharepoint.Result) error {
+		if r.Err != nil {
+			t.Errorf("%s: %v", r.Item.Path, r.Err)
+			return nil
+		}
+		if r.Extraction.Content != "Hello there." {
+			t.Errorf("%s: unexpected content %q", r.Item.Path, r.Extraction.Content)
+		}
+		got = append(got, r.Item.Path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	sort.Strings(got)
+	if strings.Join(got, ",") != "Docs/Sub/c.srt,Docs/a.srt,Docs/b.srt" {
+		t.Fatalf("unexpected items: %v", got)
+	}
+	if !strings.HasSuffix(token, "token=t1") {
+		t.Fatalf("unexpected token %q", token)
+	}
+	if peak.Load() > 2 {
+		t.Fatalf("concurrency limit exceeded: %d downloads in flight", peak.Load())
+	}
+
+	var deleted, updated []string
+	token, err = conn.Sync(context.Background(), folder, token, func(r sharepoint.Result) error {
+		if r.Item.Deleted {
+			deleted = append(deleted, r.Item.ID)
+		} else {
+			updated = append(updated, r.Item.ID)
+		}
+		return nil
+	})
+	if err != nil || !strings.HasSuffix(token, "token=t2") {
+		t.Fatalf("second sync = %q, %v", token, err)
+	}
+	if strings.Join(deleted, ",") != "a" || strings.Join(updated, ",") != "b" {
+		t.Fatalf("deleted %v, updated %v", deleted, updated)
+	}
+}
+
+func TestSyncStopsOnCallbackErrorAndReportsGraphErrors(t *testing.T) {
+	server, _ := fakeGraph(t)
+	conn := &sharepoint.Connector{AccessToken: "secret", BaseURL: server.URL, MaxFileSize: 10}
+
+	stop := errors.New("stop")
+	token, err := conn.Sync(context.Background(), sharepoint.Folder{DriveID: "d1"}, "", func(r sharepoint.Result) error {
+		if !errors.Is(r.Err, sharepoint.ErrFileTooLarge) {
+			t.Errorf("expected ErrFileTooLarge, got %v", r.Err)
+		}
+		return stop
+	})
+	if !errors.Is(err, stop) || token != "" {
+		t.Fatalf("Sync = %q, %v; want callback error and no token", token, err)
+	}
+
+	_, err = (&sharepoint.Connector{BaseURL: server.URL}).List(context.Background(), sharepoint.Folder{DriveID: "d1"})
+	var graphErr *sharepoint.GraphError
+	if !errors.As(err, &graphErr) || graphErr.StatusCode != http.StatusUnauthorized || graphErr.Code != "InvalidAuthenticationToken" {
+		t.Fatalf("expected GraphError, got %v", err)
+	}
+}
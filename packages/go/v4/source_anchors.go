@@ -0,0 +1,141 @@
+package kreuzberg
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var sourceAnchorPattern = regexp.MustCompile(`^<!-- kreuzberg:source(?: page=(\d+))? bytes=(\d+)-(\d+) -->$`)
+
+// SourceAnchor maps a block of annotated Markdown back to its location in the extracted content.
+type SourceAnchor struct {
+	// Page is the 1-indexed page the block starts on (0 when the document has no page boundaries).
+	Page uint64 `json:"page,omitempty"`
+	// ByteStart is the offset of the block in the unannotated content.
+	ByteStart uint64 `json:"byte_start"`
+	// ByteEnd is the end offset (exclusive) of the block in the unannotated content.
+	ByteEnd uint64 `json:"byte_end"`
+	// Line is the 1-indexed line of the annotated Markdown where the block starts.
+	Line int `json:"line"`
+}
+
+// sourceBlock is a run of non-blank lines (or a whole fenced code block) in the content.
+type sourceBlock struct {
+	start, end int
+}
+
+// splitSourceBlocks splits content into Markdown blocks separated by blank lines, keeping fenced
+// code blocks whole even when they contain blank lines.
+func splitSourceBlocks(content string) []sourceBlock {
+	var blocks []sourceBlock
+	start := -1
+	fence := ""
+	offset := 0
+	for offset < len(content) {
+		end := strings.IndexByte(content[offset:], '\n')
+		if end < 0 {
+			end = len(content)
+		} else {
+			end += offset
+		}
+		line := strings.TrimRight(content[offset:end], "\r")
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+			}
+		case trimmed == "":
+			if start >= 0 {
+				blocks = append(blocks, sourceBlock{start: start, end: offset})
+				start = -1
+			}
+		default:
+			if start < 0 {
+				start = offset
+			}
+			fence = codeFenceMarker(trimmed)
+		}
+		offset = end + 1
+	}
+	if start >= 0 {
+		blocks = append(blocks, sourceBlock{start: start, end: len(content)})
+	}
+	for i := range blocks {
+		blocks[i].end = blocks[i].start + len(strings.TrimRight(content[blocks[i].start:blocks[i].end], "\r\n"))
+	}
+	return blocks
+}
+
+func pageForOffset(boundaries []PageBoundary, offset int) uint64 {
+	for _, b := range boundaries {
+		if uint64(offset) >= b.ByteStart && uint64(offset) < b.ByteEnd {
+			return b.PageNumber
+		}
+	}
+	return 0
+}
+
+// annotateSourceSpans prefixes every block of content with a source anchor comment. When
+// canonical is set, each block is also canonicalized (see CanonicalizeMarkdown); the anchors
+// still refer to offsets in the original content.
+func annotateSourceSpans(content string, boundaries []PageBoundary, canonical bool) string {
+	canonicalizer := &markdownCanonicalizer{}
+	var b strings.Builder
+	for _, block := range splitSourceBlocks(content) {
+		text := content[block.start:block.end]
+		if canonical {
+			text = canonicalizer.canonicalize(text)
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+		}
+		b.WriteString("<!-- kreuzberg:source")
+		if page := pageForOffset(boundaries, block.start); page > 0 {
+			fmt.Fprintf(&b, " page=%d", page)
+		}
+		fmt.Fprintf(&b, " bytes=%d-%d -->\n", block.start, block.end)
+		b.WriteString(text)
+	}
+	return b.String()
+}
+
+// annotateResultSourceSpans renders the anchored Markdown of result.Content into
+// AnnotatedContent, leaving Content, chunk offsets and page boundaries untouched. Page numbers
+// come from Metadata.PageStructure when the extractor reports page boundaries.
+func annotateResultSourceSpans(result *ExtractionResult, canonical bool) {
+	if result == nil {
+		return
+	}
+	var boundaries []PageBoundary
+	if result.Metadata.PageStructure != nil {
+		boundaries = result.Metadata.PageStructure.Boundaries
+	}
+	result.AnnotatedContent = annotateSourceSpans(result.Content, boundaries, canonical)
+	if canonical {
+		canonicalizeResultMarkdown(result)
+	}
+}
+
+// ParseSourceAnchors returns the source anchors embedded in Markdown produced with
+// ExtractionConfig.SourceAnchors (ExtractionResult.AnnotatedContent), in document order.
+func ParseSourceAnchors(markdown string) []SourceAnchor {
+	var anchors []SourceAnchor
+	for i, line := range strings.Split(markdown, "\n") {
+		m := sourceAnchorPattern.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		anchor := SourceAnchor{Line: i + 2}
+		if m[1] != "" {
+			anchor.Page, _ = strconv.ParseUint(m[1], 10, 64)
+		}
+		anchor.ByteStart, _ = strconv.ParseUint(m[2], 10, 64)
+		anchor.ByteEnd, _ = strconv.ParseUint(m[3], 10, 64)
+		anchors = append(anchors, anchor)
+	}
+	return anchors
+}
@@ -0,0 +1,88 @@
+package kreuzberg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAnnotateSourceSpansMapsBlocksToPages(t *testing.T) {
+	content := "# Title\n\nFirst paragraph.\n\n```\ncode\n\nmore code\n```\n\nSecond page text."
+	secondPage := strings.Index(content, "Second")
+	boundaries := []PageBoundary{
+		{ByteStart: 0, ByteEnd: uint64(secondPage), PageNumber: 1},
+		{ByteStart: uint64(secondPage), ByteEnd: uint64(len(content)), PageNumber: 2},
+	}
+
+	annotated := annotateSourceSpans(content, boundaries, false)
+	anchors := ParseSourceAnchors(annotated)
+	if len(anchors) != 4 {
+		t.Fatalf("expected 4 anchors, got %d:\n%s", len(anchors), annotated)
+	}
+
+	lines := strings.Split(annotated, "\n")
+	for _, anchor := range anchors {
+		block := content[anchor.ByteStart:anchor.ByteEnd]
+		if first := strings.SplitN(block, "\n", 2)[0]; lines[anchor.Line-1] != first {
+			t.Fatalf("anchor %+v points at %q, want %q", anchor, lines[anchor.Line-1], first)
+		}
+	}
+	if anchors[2].ByteEnd-anchors[2].ByteStart != uint64(len("```\ncode\n\nmore code\n```")) {
+		t.Fatalf("fenced block should be a single span: %+v", anchors[2])
+	}
+	if anchors[0].Page != 1 || anchors[3].Page != 2 {
+		t.Fatalf("unexpected pages: %+v", anchors)
+	}
+}
+
+func TestAnnotateSourceSpansWithoutPages(t *testing.T) {
+	annotated := annotateSourceSpans("one\n\n\ntwo", nil, false)
+	want := "<!-- kreuzberg:source bytes=0-3 -->\none\n\n<!-- kreuzberg:source bytes=6-9 -->\ntwo"
+	if annotated != want {
+		t.Fatalf("unexpected annotation:\n%q\nwant\n%q", annotated, want)
+	}
+}
+
+func TestFinishResultCombinesAnchorsWithCanonicalMarkdown(t *testing.T) {
+	result := &ExtractionResult{Content: "Title\n===\n\n* ![a](x.png)\n\n* ![b](y.png)"}
+	cfg := &ExtractionConfig{SourceAnchors: BoolPtr(true), CanonicalMarkdown: BoolPtr(true)}
+	if err := finishResult(&pluginRegistry{}, newPluginContext(t.Context(), "", []byte("x"), "text/markdown", cfg), result); err != nil {
+		t.Fatalf("finishResult: %v", err)
+	}
+	want := "<!-- kreuzberg:source bytes=0-9 -->\n# Title\n\n" +
+		"<!-- kreuzberg:source bytes=11-24 -->\n- ![a](image-1)\n\n" +
+		"<!-- kreuzberg:source bytes=26-39 -->\n- ![b](image-2)"
+	if result.AnnotatedContent != want {
+		t.Fatalf("unexpected annotated content:\n%q\nwant\n%q", result.AnnotatedContent, want)
+	}
+	if want := "# Title\n\n- ![a](image-1)\n\n- ![b](image-2)"; result.Content != want {
+		t.Fatalf("expected Content to hold only the canonical Markdown, got %q", result.Content)
+	}
+}
+
+func TestFinishResultKeepsOffsetsWithSourceAnchors(t *testing.T) {
+	content := "First page.\n\nSecond page."
+	second := strings.Index(content, "Second")
+	result := &ExtractionResult{
+		Content: content,
+		Chunks:  []Chunk{{Content: "Second page.", Metadata: ChunkMetadata{ByteStart: uint64(second), ByteEnd: uint64(len(content))}}},
+		Metadata: Metadata{PageStructure: &PageStructure{Boundaries: []PageBoundary{
+			{ByteStart: 0, ByteEnd: uint64(second), PageNumber: 1},
+			{ByteStart: uint64(second), ByteEnd: uint64(len(content)), PageNumber: 2},
+		}}},
+	}
+	cfg := &ExtractionConfig{SourceAnchors: BoolPtr(true)}
+	if err := finishResult(&pluginRegistry{}, newPluginContext(t.Context(), "", []byte("x"), "text/plain", cfg), result); err != nil {
+		t.Fatalf("finishResult: %v", err)
+	}
+	if result.Content != content {
+		t.Fatalf("expected Content to be left as is, got %q", result.Content)
+	}
+	chunk := result.Chunks[0].Metadata
+	if got := result.Content[chunk.ByteStart:chunk.ByteEnd]; got != "Second page." {
+		t.Fatalf("chunk offsets point at %q", got)
+	}
+	anchors := ParseSourceAnchors(result.AnnotatedContent)
+	if len(anchors) != 2 || anchors[1].Page != 2 || result.Content[anchors[1].ByteStart:anchors[1].ByteEnd] != "Second page." {
+		t.Fatalf("unexpected anchors %+v in %q", anchors, result.AnnotatedContent)
+	}
+}
@@ -0,0 +1,592 @@
+package kreuzberg
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	mimeXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	mimeXLSM = "application/vnd.ms-excel.sheet.macroEnabled.12"
+)
+
+// SpreadsheetConfig enables the Go streaming XLSX reader for extraction. The native extractor
+// materializes every sheet in memory; with MaxRows set, XLSX/XLSM documents are instead streamed
+// and only the first MaxRows rows of each sheet are kept.
+type SpreadsheetConfig struct {
+	// MaxRows caps the rows kept per sheet (including the header row). Rows past the cap are
+	// counted but dropped, and the result reports the truncation in Metadata.Additional
+	// ("truncated", "sheet_row_counts").
+	MaxRows int
+	// Sheets restricts extraction to the named sheets (default: all sheets).
+	Sheets []string
+}
+
+// SpreadsheetRow is a worksheet row delivered by StreamSpreadsheetFile/StreamSpreadsheetBytes.
+type SpreadsheetRow struct {
+	// Sheet is the worksheet name.
+	Sheet string
+	// SheetIndex is the 0-based position of the sheet in the workbook.
+	SheetIndex int
+	// Number is the 1-based row number in the sheet. Empty rows are not delivered, so numbers
+	// may skip.
+	Number int
+	// Cells holds the formatted cell values, starting at column A; missing cells are "".
+	Cells []string
+}
+
+// SpreadsheetStreamOptions controls StreamSpreadsheetFile/StreamSpreadsheetBytes.
+type SpreadsheetStreamOptions struct {
+	// Sheets restricts streaming to the named sheets (default: all sheets).
+	Sheets []string
+	// MaxRows caps the rows delivered per sheet (0 = unlimited). Remaining rows are still counted.
+	MaxRows int
+}
+
+// SheetStreamSummary reports how much of a sheet was streamed.
+type SheetStreamSummary struct {
+	// Name is the worksheet name.
+	Name string `json:"name"`
+	// RowsDelivered is the number of rows passed to the callback.
+	RowsDelivered int `json:"rows_delivered"`
+	// TotalRows is the number of non-empty rows in the sheet.
+	TotalRows int `json:"total_rows"`
+	// Truncated reports whether MaxRows cut the sheet short.
+	Truncated bool `json:"truncated"`
+}
+
+// StreamSpreadsheetFile streams the rows of an XLSX/XLSM file to fn one at a time, so memory
+// use does not grow with the number of rows (shared strings are still loaded up front).
+// An error returned by fn stops streaming and is returned as-is.
+func StreamSpreadsheetFile(filePath string, opts *SpreadsheetStreamOptions, fn func(row SpreadsheetRow) error) ([]SheetStreamSummary, error) {
+	if filePath == "" {
+		return nil, newValidationErrorWithContext("path cannot be empty", nil, ErrorCodeValidation, nil)
+	}
+	zr, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, newParsingErrorWithContext("failed to open XLSX archive", err, ErrorCodeParsing, nil)
+	}
+	defer zr.Close()
+	return streamSpreadsheet(&zr.Reader, opts, fn)
+}
+
+// StreamSpreadsheetBytes is StreamSpreadsheetFile for an in-memory XLSX/XLSM document.
+func StreamSpreadsheetBytes(data []byte, opts *SpreadsheetStreamOptions, fn func(row SpreadsheetRow) error) ([]SheetStreamSummary, error) {
+	if len(data) == 0 {
+		return nil, newValidationErrorWithContext("data cannot be empty", nil, ErrorCodeValidation, nil)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, newParsingErrorWithContext("failed to open XLSX archive", err, ErrorCodeParsing, nil)
+	}
+	return streamSpreadsheet(zr, opts, fn)
+}
+
+func streamSpreadsheet(zr *zip.Reader, opts *SpreadsheetStreamOptions, fn func(row SpreadsheetRow) error) ([]SheetStreamSummary, error) {
+	if fn == nil {
+		return nil, newValidationErrorWithContext("row callback cannot be nil", nil, ErrorCodeValidation, nil)
+	}
+	var o SpreadsheetStreamOptions
+	if opts != nil {
+		o = *opts
+	}
+
+	wb, err := openXLSXWorkbook(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	var summaries []SheetStreamSummary
+	for index, sheet := range wb.sheets {
+		if len(o.Sheets) > 0 && !containsString(o.Sheets, sheet.name) {
+			continue
+		}
+		summary, err := wb.streamSheet(index, sheet, o.MaxRows, fn)
+		if err != nil {
+			return summaries, err
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+type xlsxSheetRef struct {
+	name string
+	part string
+}
+
+type xlsxWorkbook struct {
+	zr            *zip.Reader
+	sheets        []xlsxSheetRef
+	sharedStrings []string
+	dateStyles    map[int]bool
+	date1904      bool
+}
+
+func openXLSXWorkbook(zr *zip.Reader) (*xlsxWorkbook, error) {
+	wb := &xlsxWorkbook{zr: zr}
+
+	var workbook struct {
+		Properties struct {
+			Date1904 bool `xml:"date1904,attr"`
+		} `xml:"workbookPr"`
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			RID  string `xml:"id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := wb.decodePart("xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	wb.date1904 = workbook.Properties.Date1904
+
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := wb.decodePart("xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	targets := make(map[string]string, len(rels.Relationships))
+	for _, rel := range rels.Relationships {
+		if strings.HasPrefix(rel.Target, "/") {
+			targets[rel.ID] = strings.TrimPrefix(rel.Target, "/")
+		} else {
+			targets[rel.ID] = path.Clean(path.Join("xl", rel.Target))
+		}
+	}
+	for _, sheet := range workbook.Sheets {
+		if part, ok := targets[sheet.RID]; ok {
+			wb.sheets = append(wb.sheets, xlsxSheetRef{name: sheet.Name, part: part})
+		}
+	}
+
+	if err := wb.loadSharedStrings(); err != nil {
+		return nil, err
+	}
+	if err := wb.loadDateStyles(); err != nil {
+		return nil, err
+	}
+	return wb, nil
+}
+
+func (wb *xlsxWorkbook) openPart(name string) (io.ReadCloser, error) {
+	for _, f := range wb.zr.File {
+		if f.Name == name {
+			rc, err := f.Open()
+			if err != nil {
+				return nil, newParsingErrorWithContext(fmt.Sprintf("failed to open %s", name), err, ErrorCodeParsing, nil)
+			}
+			return rc, nil
+		}
+	}
+	return nil, nil
+}
+
+func (wb *xlsxWorkbook) decodePart(name string, target any) error {
+	rc, err := wb.openPart(name)
+	if err != nil {
+		return err
+	}
+	if rc == nil {
+		return newParsingErrorWithContext(fmt.Sprintf("XLSX archive is missing %s", name), nil, ErrorCodeParsing, nil)
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(rc).Decode(target); err != nil {
+		return newParsingErrorWithContext(fmt.Sprintf("failed to parse %s", name), err, ErrorCodeParsing, nil)
+	}
+	return nil
+}
+
+// loadSharedStrings reads the shared string table, skipping phonetic (rPh) runs.
+func (wb *xlsxWorkbook) loadSharedStrings() error {
+	rc, err := wb.openPart("xl/sharedStrings.xml")
+	if err != nil || rc == nil {
+		return err
+	}
+	defer rc.Close()
+
+	dec := xml.NewDecoder(rc)
+	var current strings.Builder
+	inText, inPhonetic := false, false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return newParsingErrorWithContext("failed to parse shared strings", err, ErrorCodeParsing, nil)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				current.Reset()
+			case "rPh":
+				inPhonetic = true
+			case "t":
+				inText = !inPhonetic
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "si":
+				wb.sharedStrings = append(wb.sharedStrings, current.String())
+			case "rPh":
+				inPhonetic = false
+			case "t":
+				inText = false
+			}
+		case xml.CharData:
+			if inText {
+				current.Write(t)
+			}
+		}
+	}
+}
+
+// loadDateStyles records which cell style indexes format numbers as dates.
+func (wb *xlsxWorkbook) loadDateStyles() error {
+	wb.dateStyles = map[int]bool{}
+	rc, err := wb.openPart("xl/styles.xml")
+	if err != nil || rc == nil {
+		return err
+	}
+	defer rc.Close()
+
+	var styles struct {
+		NumFmts []struct {
+			ID   int    `xml:"numFmtId,attr"`
+			Code string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		CellXfs []struct {
+			NumFmtID int `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+	if err := xml.NewDecoder(rc).Decode(&styles); err != nil {
+		return newParsingErrorWithContext("failed to parse styles", err, ErrorCodeParsing, nil)
+	}
+
+	custom := make(map[int]bool, len(styles.NumFmts))
+	for _, f := range styles.NumFmts {
+		custom[f.ID] = isDateFormatCode(f.Code)
+	}
+	for i, xf := range styles.CellXfs {
+		id := xf.NumFmtID
+		if (id >= 14 && id <= 22) || (id >= 45 && id <= 47) || custom[id] {
+			wb.dateStyles[i] = true
+		}
+	}
+	return nil
+}
+
+func isDateFormatCode(code string) bool {
+	var b strings.Builder
+	inQuote, inBracket := false, false
+	for _, r := range code {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+		case inQuote:
+		case r == '[':
+			inBracket = true
+		case r == ']':
+			inBracket = false
+		case !inBracket:
+			b.WriteRune(r)
+		}
+	}
+	return strings.ContainsAny(strings.ToLower(b.String()), "ymdhs")
+}
+
+func (wb *xlsxWorkbook) streamSheet(index int, sheet xlsxSheetRef, maxRows int, fn func(row SpreadsheetRow) error) (SheetStreamSummary, error) {
+	summary := SheetStreamSummary{Name: sheet.name}
+	rc, err := wb.openPart(sheet.part)
+	if err != nil {
+		return summary, err
+	}
+	if rc == nil {
+		return summary, newParsingErrorWithContext(fmt.Sprintf("XLSX archive is missing %s", sheet.part), nil, ErrorCodeParsing, nil)
+	}
+	defer rc.Close()
+
+	dec := xml.NewDecoder(rc)
+	var (
+		row                    SpreadsheetRow
+		cellType, cellStyle    string
+		cellColumn, lastColumn int
+		value                  strings.Builder
+		capture, empty         bool
+	)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return summary, nil
+		}
+		if err != nil {
+			return summary, newParsingErrorWithContext(fmt.Sprintf("failed to parse sheet %q", sheet.name), err, ErrorCodeParsing, nil)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "row":
+				number := row.Number + 1
+				if r := xmlAttr(t, "r"); r != "" {
+					if n, err := strconv.Atoi(r); err == nil {
+						number = n
+					}
+				}
+				row = SpreadsheetRow{Sheet: sheet.name, SheetIndex: index, Number: number}
+				lastColumn = -1
+				empty = true
+			case "c":
+				cellType, cellStyle = xmlAttr(t, "t"), xmlAttr(t, "s")
+				cellColumn = lastColumn + 1
+				if ref := xmlAttr(t, "r"); ref != "" {
+					cellColumn = columnIndex(ref)
+				}
+				value.Reset()
+			case "v", "t":
+				capture = true
+			}
+		case xml.CharData:
+			if capture {
+				value.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "v", "t":
+				capture = false
+			case "c":
+				for len(row.Cells) < cellColumn {
+					row.Cells = append(row.Cells, "")
+				}
+				cell := wb.formatCell(cellType, cellStyle, value.String())
+				row.Cells = append(row.Cells, cell)
+				lastColumn = cellColumn
+				empty = empty && cell == ""
+			case "row":
+				// Rows without a value, e.g. rows that only carry formatting, are skipped.
+				if empty {
+					continue
+				}
+				summary.TotalRows++
+				if maxRows > 0 && summary.RowsDelivered >= maxRows {
+					summary.Truncated = true
+					continue
+				}
+				summary.RowsDelivered++
+				if err := fn(row); err != nil {
+					return summary, err
+				}
+			}
+		}
+	}
+}
+
+// cellErrorNames maps the error values stored in XLSX cells to the names the native Excel
+// extractor renders.
+var cellErrorNames = map[string]string{
+	"#DIV/0!":       "Div0",
+	"#N/A":          "NA",
+	"#NAME?":        "Name",
+	"#NULL!":        "Null",
+	"#NUM!":         "Num",
+	"#REF!":         "Ref",
+	"#VALUE!":       "Value",
+	"#GETTING_DATA": "GettingData",
+}
+
+// formatCell renders a cell value the same way the native Excel extractor does.
+func (wb *xlsxWorkbook) formatCell(cellType, style, raw string) string {
+	switch cellType {
+	case "s":
+		i, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil || i < 0 || i >= len(wb.sharedStrings) {
+			return ""
+		}
+		return wb.sharedStrings[i]
+	case "str", "inlineStr":
+		return raw
+	case "b":
+		if strings.TrimSpace(raw) == "1" {
+			return "true"
+		}
+		return "false"
+	case "e":
+		raw = strings.TrimSpace(raw)
+		if name, ok := cellErrorNames[raw]; ok {
+			return "#ERR: " + name
+		}
+		return "#ERR: " + raw
+	}
+
+	f, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+	if err != nil {
+		return raw
+	}
+	if s, err := strconv.Atoi(style); err == nil && wb.dateStyles[s] {
+		return excelSerialToTime(f, wb.date1904).Format("2006-01-02 15:04:05")
+	}
+	if f == math.Trunc(f) && !math.IsInf(f, 0) {
+		return strconv.FormatFloat(f, 'f', 1, 64)
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func excelSerialToTime(serial float64, date1904 bool) time.Time {
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	if date1904 {
+		epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return epoch.Add(time.Duration(math.Round(serial*86400)) * time.Second)
+}
+
+// columnIndex converts the column letters of a cell reference ("AB12") to a 0-based index.
+func columnIndex(ref string) int {
+	index := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		index = index*26 + int(r-'A'+1)
+	}
+	return index - 1
+}
+
+func xmlAttr(el xml.StartElement, name string) string {
+	for _, attr := range el.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// extractSpreadsheetStreaming builds an ExtractionResult from the streamed XLSX rows, keeping at
+// most SpreadsheetConfig.MaxRows rows per sheet.
+func extractSpreadsheetStreaming(src documentSource, mimeType string, config *ExtractionConfig) (*ExtractionResult, error) {
+	opts := &SpreadsheetStreamOptions{MaxRows: config.Spreadsheet.MaxRows, Sheets: config.Spreadsheet.Sheets}
+
+	tables := map[string]*Table{}
+	collect := func(row SpreadsheetRow) error {
+		table, ok := tables[row.Sheet]
+		if !ok {
+			table = &Table{PageNumber: row.SheetIndex + 1}
+			tables[row.Sheet] = table
+		}
+		table.Cells = append(table.Cells, row.Cells)
+		return nil
+	}
+
+	var summaries []SheetStreamSummary
+	var err error
+	if src.path != "" {
+		summaries, err = StreamSpreadsheetFile(src.path, opts, collect)
+	} else {
+		summaries, err = StreamSpreadsheetBytes(src.data, opts, collect)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ExtractionResult{MimeType: mimeType, Tables: []Table{}, Success: true}
+	sections := make([]string, 0, len(summaries))
+	names := make([]string, 0, len(summaries))
+	rowCounts := make(map[string]int, len(summaries))
+	truncated := false
+	for _, summary := range summaries {
+		names = append(names, summary.Name)
+		rowCounts[summary.Name] = summary.TotalRows
+		truncated = truncated || summary.Truncated
+
+		table, ok := tables[summary.Name]
+		if !ok {
+			sections = append(sections, fmt.Sprintf("## %s\n\n*Empty sheet*", summary.Name))
+			continue
+		}
+		table.Markdown = spreadsheetMarkdown(table.Cells)
+		result.Tables = append(result.Tables, *table)
+		sections = append(sections, fmt.Sprintf("## %s\n\n%s", summary.Name, strings.TrimRight(table.Markdown, "\n")))
+	}
+	result.Content = strings.Join(sections, "\n\n")
+	result.Metadata.Format = FormatMetadata{Type: FormatExcel, Excel: &ExcelMetadata{SheetCount: len(names), SheetNames: names}}
+
+	additional := map[string]any{"truncated": truncated, "sheet_row_counts": rowCounts}
+	result.Metadata.Additional = make(map[string]json.RawMessage, len(additional))
+	for key, value := range additional {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, newSerializationErrorWithContext("failed to encode spreadsheet metadata", err, ErrorCodeValidation, nil)
+		}
+		result.Metadata.Additional[key] = raw
+	}
+	return result, nil
+}
+
+var markdownCellEscaper = strings.NewReplacer("\\", "\\\\", "|", "\\|")
+
+// spreadsheetMarkdown renders rows as a Markdown table with the first row as header, padding
+// short rows to the header width like the native Excel extractor.
+func spreadsheetMarkdown(rows [][]string) string {
+	width := len(rows[0])
+	var b strings.Builder
+	writeRow := func(cells []string) {
+		b.WriteString("| ")
+		for i := 0; i < width; i++ {
+			if i > 0 {
+				b.WriteString(" | ")
+			}
+			if i < len(cells) {
+				b.WriteString(markdownCellEscaper.Replace(cells[i]))
+			}
+		}
+		b.WriteString(" |\n")
+	}
+
+	writeRow(rows[0])
+	b.WriteString("| ")
+	for i := 0; i < width; i++ {
+		if i > 0 {
+			b.WriteString(" | ")
+		}
+		b.WriteString("---")
+	}
+	b.WriteString(" |\n")
+	for _, row := range rows[1:] {
+		writeRow(row)
+	}
+	return b.String()
+}
+
+func init() {
+	registerGoPrimaryExtractor(goPrimaryExtractor{
+		name:       "xlsx-streaming",
+		mimeTypes:  []string{mimeXLSX, mimeXLSM},
+		extensions: map[string]string{"xlsx": mimeXLSX, "xlsm": mimeXLSM},
+		enabled: func(config *ExtractionConfig) bool {
+			return config != nil && config.Spreadsheet != nil && config.Spreadsheet.MaxRows > 0
+		},
+		extract: extractSpreadsheetStreaming,
+	})
+}
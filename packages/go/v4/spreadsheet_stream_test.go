@@ -0,0 +1,173 @@
+package kreuzberg
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// buildXLSX assembles a minimal workbook; each sheet body is the inner XML of <sheetData>.
+func buildXLSX(t *testing.T, shared []string, sheets map[string]string, order []string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	write := func(name, body string) {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	var sheetEls, rels strings.Builder
+	for i, name := range order {
+		fmt.Fprintf(&sheetEls, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, name, i+1, i+1)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+		write(fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1),
+			`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`+sheets[name]+`</sheetData></worksheet>`)
+	}
+	write("xl/workbook.xml", `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" `+
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`+sheetEls.String()+`</sheets></workbook>`)
+	write("xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`+rels.String()+`</Relationships>`)
+
+	var sst strings.Builder
+	for _, s := range shared {
+		fmt.Fprintf(&sst, "<si><t>%s</t></si>", s)
+	}
+	write("xl/sharedStrings.xml", `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`+sst.String()+`</sst>`)
+	write("xl/styles.xml", `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`+
+		`<numFmts><numFmt numFmtId="164" formatCode="yyyy-mm-dd"/><numFmt numFmtId="165" formatCode="&quot;days&quot; 0"/></numFmts>`+
+		`<cellXfs><xf numFmtId="0"/><xf numFmtId="164"/><xf numFmtId="165"/></cellXfs></styleSheet>`)
+
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestStreamSpreadsheetBytesFormatsCells(t *testing.T) {
+	data := buildXLSX(t, []string{"name", "score"}, map[string]string{
+		"Scores": `<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>` +
+			`<row r="3"><c r="A3" t="inlineStr"><is><t>a|b</t></is></c><c r="C3"><v>2.5</v></c></row>` +
+			`<row r="4"><c r="A4" t="b"><v>1</v></c><c r="B4" s="1"><v>45292</v></c><c r="C4" s="2"><v>7</v></c></row>`,
+	}, []string{"Scores"})
+
+	var rows []SpreadsheetRow
+	summaries, err := StreamSpreadsheetBytes(data, nil, func(row SpreadsheetRow) error {
+		rows = append(rows, row)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	if len(summaries) != 1 || summaries[0].TotalRows != 3 || summaries[0].Truncated {
+		t.Fatalf("unexpected summaries: %+v", summaries)
+	}
+	want := [][]string{{"name", "score"}, {"a|b", "", "2.5"}, {"true", "2024-01-01 00:00:00", "7.0"}}
+	for i, row := range rows {
+		if strings.Join(row.Cells, ",") != strings.Join(want[i], ",") {
+			t.Fatalf("row %d = %q, want %q", i, row.Cells, want[i])
+		}
+	}
+	if rows[1].Number != 3 {
+		t.Fatalf("row numbers should follow the sheet, got %d", rows[1].Number)
+	}
+}
+
+func TestStreamSpreadsheetSkipsEmptyRowsAndNamesErrors(t *testing.T) {
+	data := buildXLSX(t, []string{"", "total"}, map[string]string{
+		"Sheet1": `<row r="1"><c r="A1" t="s"><v>1</v></c></row>` +
+			`<row r="2" ht="30" customHeight="1"/>` +
+			`<row r="3"><c r="A3" s="1"/><c r="B3" t="s"><v>0</v></c></row>` +
+			`<row r="4"><c r="A4" t="e"><v>#DIV/0!</v></c><c r="B4" t="e"><v>#BOGUS</v></c></row>`,
+	}, []string{"Sheet1"})
+
+	var rows []SpreadsheetRow
+	summaries, err := StreamSpreadsheetBytes(data, &SpreadsheetStreamOptions{MaxRows: 1}, func(row SpreadsheetRow) error {
+		rows = append(rows, row)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	if len(summaries) != 1 || summaries[0].TotalRows != 2 || summaries[0].RowsDelivered != 1 || !summaries[0].Truncated {
+		t.Fatalf("empty rows should not be counted: %+v", summaries)
+	}
+
+	rows = nil
+	if _, err := StreamSpreadsheetBytes(data, nil, func(row SpreadsheetRow) error {
+		rows = append(rows, row)
+		return nil
+	}); err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	if len(rows) != 2 || rows[0].Number != 1 || rows[1].Number != 4 {
+		t.Fatalf("expected rows 1 and 4 only, got %+v", rows)
+	}
+	if got := strings.Join(rows[1].Cells, ","); got != "#ERR: Div0,#ERR: #BOGUS" {
+		t.Fatalf("error cells should render like the native extractor, got %q", got)
+	}
+}
+
+func TestStreamSpreadsheetCapsRowsAndFiltersSheets(t *testing.T) {
+	var body strings.Builder
+	for i := 1; i <= 50; i++ {
+		fmt.Fprintf(&body, `<row r="%d"><c r="A%d"><v>%d</v></c></row>`, i, i, i)
+	}
+	data := buildXLSX(t, nil, map[string]string{"Big": body.String(), "Other": `<row r="1"><c><v>1</v></c></row>`}, []string{"Other", "Big"})
+
+	delivered := 0
+	summaries, err := StreamSpreadsheetBytes(data, &SpreadsheetStreamOptions{Sheets: []string{"Big"}, MaxRows: 10}, func(row SpreadsheetRow) error {
+		if row.Sheet != "Big" || row.SheetIndex != 1 {
+			t.Fatalf("unexpected sheet: %+v", row)
+		}
+		delivered++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	if delivered != 10 || len(summaries) != 1 || summaries[0].TotalRows != 50 || !summaries[0].Truncated {
+		t.Fatalf("delivered %d, summaries %+v", delivered, summaries)
+	}
+
+	stop := errors.New("stop")
+	if _, err := StreamSpreadsheetBytes(data, nil, func(row SpreadsheetRow) error { return stop }); !errors.Is(err, stop) {
+		t.Fatalf("expected callback error to stop streaming, got %v", err)
+	}
+}
+
+func TestExtractSpreadsheetStreamingReportsTruncation(t *testing.T) {
+	data := buildXLSX(t, []string{"h"}, map[string]string{
+		"Data":  `<row r="1"><c t="s"><v>0</v></c></row><row r="2"><c><v>1</v></c></row><row r="3"><c><v>2</v></c></row>`,
+		"Empty": ``,
+	}, []string{"Data", "Empty"})
+	cfg := &ExtractionConfig{Spreadsheet: &SpreadsheetConfig{MaxRows: 2}}
+
+	extractor, mime := selectGoPrimaryExtractor(documentSource{data: data, mimeType: mimeXLSX}, cfg)
+	if extractor == nil || mime != mimeXLSX {
+		t.Fatalf("expected streaming extractor to be selected")
+	}
+	result, err := extractor.extract(documentSource{data: data, mimeType: mimeXLSX}, mime, cfg)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if result.Content != "## Data\n\n| h |\n| --- |\n| 1.0 |\n\n## Empty\n\n*Empty sheet*" {
+		t.Fatalf("unexpected content: %q", result.Content)
+	}
+	if string(result.Metadata.Additional["truncated"]) != "true" || string(result.Metadata.Additional["sheet_row_counts"]) != `{"Data":3,"Empty":0}` {
+		t.Fatalf("unexpected metadata: %s %s", result.Metadata.Additional["truncated"], result.Metadata.Additional["sheet_row_counts"])
+	}
+	if excel, ok := result.Metadata.ExcelMetadata(); !ok || excel.SheetCount != 2 {
+		t.Fatalf("missing excel metadata")
+	}
+
+	if extractor, _ := selectGoPrimaryExtractor(documentSource{data: data, mimeType: mimeXLSX}, nil); extractor != nil {
+		t.Fatalf("streaming extractor should be opt-in")
+	}
+}
@@ -0,0 +1,101 @@
+package kreuzberg
+
+/*
+#include "internal/ffi/kreuzberg.h"
+#include <stdlib.h>
+#include <stdint.h>
+*/
+import "C"
+
+import (
+	"encoding/json"
+	"unsafe"
+)
+
+// nativeStatisticsResult is a result of the native statistics-only extraction, which reduces
+// documents to their DocumentStatistics before anything crosses the FFI boundary.
+type nativeStatisticsResult struct {
+	Success    bool               `json:"success"`
+	ErrorType  string             `json:"error_type,omitempty"`
+	Statistics DocumentStatistics `json:"statistics"`
+}
+
+// extractStatisticsNative runs a native statistics-only extraction of one document.
+func extractStatisticsNative(config *ExtractionConfig, extract func(cfg *C.char) *C.char) (*ExtractionResult, error) {
+	cfgPtr, cfgCleanup, err := newConfigJSON(config)
+	if err != nil {
+		return nil, err
+	}
+	if cfgCleanup != nil {
+		defer cfgCleanup()
+	}
+	var native nativeStatisticsResult
+	if err := decodeNativeStatistics(extract(cfgPtr), &native); err != nil {
+		return nil, err
+	}
+	return statisticsResult(&native.Statistics, native.Success, native.ErrorType)
+}
+
+// extractStatisticsBytesNative is the statistics-only form of extractBytesNative; data and
+// mimeType are validated by the caller.
+func extractStatisticsBytesNative(data []byte, mimeType string, config *ExtractionConfig) (*ExtractionResult, error) {
+	buf := newCBytes(data)
+	defer freeCBuffer(buf)
+	cMime := newCString(mimeType)
+	defer freeCBuffer(unsafe.Pointer(cMime))
+	return extractStatisticsNative(config, func(cfg *C.char) *C.char {
+		return C.kreuzberg_extract_statistics_bytes((*C.uint8_t)(buf), C.uintptr_t(len(data)), cMime, cfg)
+	})
+}
+
+// batchExtractStatisticsFilesNative runs a native statistics-only extraction of paths. Failed
+// documents are reported per item, with their error type only.
+func batchExtractStatisticsFilesNative(cPaths **C.char, count int, config *ExtractionConfig) ([]*ExtractionResult, error) {
+	cfgPtr, cfgCleanup, err := newConfigJSON(config)
+	if err != nil {
+		return nil, err
+	}
+	if cfgCleanup != nil {
+		defer cfgCleanup()
+	}
+	var native []nativeStatisticsResult
+	if err := decodeNativeStatistics(C.kreuzberg_batch_extract_statistics_files(cPaths, C.uintptr_t(count), cfgPtr), &native); err != nil {
+		return nil, err
+	}
+	results := make([]*ExtractionResult, len(native))
+	for i := range native {
+		if results[i], err = statisticsResult(&native[i].Statistics, native[i].Success, native[i].ErrorType); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+func decodeNativeStatistics[T any](ptr *C.char, target *T) error {
+	ptr = trackFFIAlloc(FFIResourceString, ptr)
+	if ptr == nil {
+		return lastError()
+	}
+	defer freeNativeString(ptr)
+	if err := json.Unmarshal([]byte(C.GoString(ptr)), target); err != nil {
+		return newSerializationErrorWithContext("failed to decode document statistics", err, ErrorCodeValidation, nil)
+	}
+	return nil
+}
+
+// batchExtractStatisticsBytesNative extracts items one by one in statistics-only mode, reporting
+// failures per item like the native batch.
+func batchExtractStatisticsBytesNative(items []BytesWithMime, config *ExtractionConfig) ([]*ExtractionResult, error) {
+	results := make([]*ExtractionResult, len(items))
+	for i, item := range items {
+		result, err := extractBytesNative(item.Data, item.MimeType, config)
+		if err != nil {
+			// Only the error type: parsers may quote the document in the message.
+			if result, err = statisticsResult(&DocumentStatistics{MimeType: item.MimeType}, false, errorTypeName(err)); err != nil {
+				return nil, err
+			}
+		}
+		results[i] = result
+	}
+	return results, nil
+}
@@ -0,0 +1,295 @@
+package kreuzberg
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"math/rand/v2"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// EntityType classifies a pattern-detected entity counted in DocumentStatistics.
+type EntityType string
+
+const (
+	EntityEmail     EntityType = "email"
+	EntityURL       EntityType = "url"
+	EntityPhone     EntityType = "phone"
+	EntityIPAddress EntityType = "ip_address"
+	EntityDate      EntityType = "date"
+	EntityMoney     EntityType = "money"
+	EntityPercent   EntityType = "percent"
+)
+
+// entityPatterns detect entities by shape only; they are deliberately conservative so counts
+// err towards missing entities rather than inventing them. Matches are removed from the text
+// before later patterns run, so e.g. the digits of a URL are not also counted as a phone number.
+var entityPatterns = []struct {
+	entity  EntityType
+	pattern *regexp.Regexp
+}{
+	{EntityEmail, regexp.MustCompile(`[\pL\pN._%+-]+@[\pL\pN-]+(?:\.[\pL\pN-]+)*\.\pL{2,}`)},
+	{EntityURL, regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"]+`)},
+	{EntityIPAddress, regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`)},
+	{EntityDate, regexp.MustCompile(`\b(?:\d{4}-\d{2}-\d{2}|\d{1,2}[./]\d{1,2}[./]\d{2,4})\b`)},
+	{EntityMoney, regexp.MustCompile(`(?:[$€£¥]\s?\d[\d,.]*\d|\b\d[\d,.]*\d?\s?(?:USD|EUR|GBP|JPY|CHF)\b)`)},
+	{EntityPercent, regexp.MustCompile(`\b\d+(?:[.,]\d+)?\s?%`)},
+	{EntityPhone, regexp.MustCompile(`(?:\+\d{1,3}[\s.-]?)?(?:\(\d{1,4}\)[\s.-]?)?\d{2,4}[\s.-]\d{2,4}[\s.-]\d{2,6}\b`)},
+}
+
+// CountEntities counts pattern-detected entities in text by type.
+func CountEntities(text string) map[EntityType]int {
+	counts := map[EntityType]int{}
+	for _, p := range entityPatterns {
+		matches := p.pattern.FindAllStringIndex(text, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		counts[p.entity] += len(matches)
+		text = p.pattern.ReplaceAllString(text, " ")
+	}
+	return counts
+}
+
+// DocumentStatistics is what a statistics-only extraction reports about a document. None of
+// its fields quote content.
+type DocumentStatistics struct {
+	MimeType       string `json:"mime_type"`
+	CharacterCount int    `json:"character_count"`
+	WordCount      int    `json:"word_count"`
+	// UniqueWordCount is the number of distinct lower-cased words.
+	UniqueWordCount int `json:"unique_word_count"`
+	SentenceCount   int `json:"sentence_count"`
+	LineCount       int `json:"line_count"`
+	PageCount       int `json:"page_count"`
+	TableCount      int `json:"table_count"`
+	ImageCount      int `json:"image_count"`
+	// Languages are the detected languages, when language detection is enabled.
+	Languages []string `json:"languages,omitempty"`
+	// Entities counts pattern-detected entities by type (see CountEntities).
+	Entities map[EntityType]int `json:"entities,omitempty"`
+	// FleschReadingEase is only meaningful for English text (see TextStatistics).
+	FleschReadingEase float64 `json:"flesch_reading_ease"`
+	LexicalDiversity  float64 `json:"lexical_diversity"`
+}
+
+// DocumentStatistics returns the statistics of a result extracted with
+// ExtractionConfig.StatisticsOnly.
+func (m Metadata) DocumentStatistics() (*DocumentStatistics, bool) {
+	var stats DocumentStatistics
+	if found, err := m.Decode("document_statistics", &stats); !found || err != nil {
+		return nil, false
+	}
+	return &stats, true
+}
+
+func statisticsOnly(config *ExtractionConfig) bool {
+	return config != nil && config.StatisticsOnly != nil && *config.StatisticsOnly
+}
+
+// reduceToStatistics replaces everything in result that could expose content with its
+// DocumentStatistics. Success, the MIME type, the detected languages and a failure's error
+// type are kept; error messages are dropped since parsers may quote the document in them.
+// Results of the native statistics-only extraction are already reduced and left alone.
+func reduceToStatistics(result *ExtractionResult) error {
+	if result.statisticsOnly {
+		return nil
+	}
+	text, err := ComputeTextStatistics(result.Content, &TextStatisticsConfig{NgramSizes: []int{1}, TopNgrams: 1})
+	if err != nil {
+		return err
+	}
+	stats := DocumentStatistics{
+		MimeType:          result.MimeType,
+		CharacterCount:    len([]rune(result.Content)),
+		WordCount:         text.WordCount,
+		UniqueWordCount:   text.UniqueWordCount,
+		SentenceCount:     text.SentenceCount,
+		PageCount:         len(result.Pages),
+		TableCount:        len(result.Tables),
+		ImageCount:        len(result.Images),
+		Languages:         result.DetectedLanguages,
+		Entities:          CountEntities(result.Content),
+		FleschReadingEase: text.FleschReadingEase,
+		LexicalDiversity:  text.LexicalDiversity,
+	}
+	if result.Content != "" {
+		stats.LineCount = strings.Count(strings.TrimRight(result.Content, "\n"), "\n") + 1
+	}
+	if stats.PageCount == 0 {
+		if count, ok := result.Metadata.GetInt("page_count"); ok {
+			stats.PageCount = count
+		}
+	}
+	errorType := ""
+	if result.Metadata.Error != nil {
+		errorType = result.Metadata.Error.ErrorType
+	}
+	reduced, err := statisticsResult(&stats, result.Success, errorType)
+	if err != nil {
+		return err
+	}
+	*result = *reduced
+	return nil
+}
+
+// statisticsResult returns the statistics-only result carrying stats.
+func statisticsResult(stats *DocumentStatistics, success bool, errorType string) (*ExtractionResult, error) {
+	raw, err := json.Marshal(stats)
+	if err != nil {
+		return nil, newSerializationErrorWithContext("failed to encode document statistics", err, ErrorCodeValidation, nil)
+	}
+	var metaErr *ErrorMetadata
+	if errorType != "" {
+		metaErr = &ErrorMetadata{ErrorType: errorType}
+	}
+	return &ExtractionResult{
+		MimeType:          stats.MimeType,
+		Success:           success,
+		DetectedLanguages: stats.Languages,
+		Tables:            []Table{},
+		Metadata: Metadata{
+			Error:      metaErr,
+			Additional: map[string]json.RawMessage{"document_statistics": raw},
+		},
+		statisticsOnly: true,
+	}, nil
+}
+
+// maxCorpusLanguages caps the languages a document contributes to CorpusStatistics.Languages,
+// which bounds its influence on the noisy counts.
+const maxCorpusLanguages = 3
+
+// CorpusStatistics aggregates DocumentStatistics over a document set. The histograms count
+// documents, not occurrences, so each document changes each bin by at most one.
+type CorpusStatistics struct {
+	Documents int `json:"documents"`
+	Failed    int `json:"failed"`
+	// MimeTypes counts documents per MIME type.
+	MimeTypes map[string]int `json:"mime_types"`
+	// Languages counts documents per detected language (up to three languages per document).
+	Languages map[string]int `json:"languages"`
+	// EntityTypes counts documents mentioning each entity type.
+	EntityTypes map[EntityType]int `json:"entity_types"`
+	// The totals are exact sums and are dropped by WithLaplaceNoise.
+	Words      int `json:"words"`
+	Characters int `json:"characters"`
+	Pages      int `json:"pages"`
+	Entities   int `json:"entities"`
+}
+
+// NewCorpusStatistics returns empty corpus statistics.
+func NewCorpusStatistics() *CorpusStatistics {
+	return &CorpusStatistics{MimeTypes: map[string]int{}, Languages: map[string]int{}, EntityTypes: map[EntityType]int{}}
+}
+
+// Add counts a result extracted with ExtractionConfig.StatisticsOnly. Other results are
+// reduced to their statistics first, without modifying result.
+func (c *CorpusStatistics) Add(result *ExtractionResult) error {
+	stats, ok := result.Metadata.DocumentStatistics()
+	if !ok {
+		reduced := *result
+		if err := reduceToStatistics(&reduced); err != nil {
+			return err
+		}
+		stats, _ = reduced.Metadata.DocumentStatistics()
+	}
+	c.Documents++
+	if !result.Success {
+		c.Failed++
+	}
+	c.MimeTypes[stats.MimeType]++
+	for _, language := range stats.Languages[:min(len(stats.Languages), maxCorpusLanguages)] {
+		c.Languages[language]++
+	}
+	for entity, count := range stats.Entities {
+		c.EntityTypes[entity]++
+		c.Entities += count
+	}
+	c.Words += stats.WordCount
+	c.Characters += stats.CharacterCount
+	c.Pages += stats.PageCount
+	return nil
+}
+
+// CorpusDomain lists the public histogram keys WithLaplaceNoise publishes. It must be chosen
+// without looking at the data, e.g. the MIME types and languages a pipeline supports: publishing
+// the keys that occur would reveal that some document has them.
+type CorpusDomain struct {
+	MimeTypes []string
+	Languages []string
+}
+
+// WithLaplaceNoise returns a copy for publication under epsilon-differential privacy with
+// respect to adding or removing one document. Every document count gets Laplace noise scaled
+// to the most one document can change all of them together; results are rounded and clamped at
+// zero. The MIME type and language bins are published for exactly the keys of domain, including
+// keys no document has, and dropped when domain is nil. The totals, which a single document can
+// change without bound, are zeroed. Smaller epsilon means more noise; epsilon must be positive.
+func (c *CorpusStatistics) WithLaplaceNoise(epsilon float64, domain *CorpusDomain, rng *rand.Rand) (*CorpusStatistics, error) {
+	if epsilon <= 0 || math.IsInf(epsilon, 0) || math.IsNaN(epsilon) {
+		return nil, newValidationErrorWithContext("epsilon must be a positive number", nil, ErrorCodeValidation, nil)
+	}
+	if rng == nil {
+		rng = rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	}
+	// Documents, Failed and one MIME type bin, plus the language and entity type bins.
+	sensitivity := float64(3 + maxCorpusLanguages + len(entityPatterns))
+	scale := sensitivity / epsilon
+	noisy := func(n int) int {
+		// u is drawn from (0, 1); a zero would make the logarithm infinite.
+		u := rng.Float64()
+		for u == 0 {
+			u = rng.Float64()
+		}
+		u -= 0.5
+		noise := -scale * math.Copysign(1, u) * math.Log(1-2*math.Abs(u))
+		return max(int(math.Round(float64(n)+noise)), 0)
+	}
+	out := NewCorpusStatistics()
+	out.Documents, out.Failed = noisy(c.Documents), noisy(c.Failed)
+	if domain != nil {
+		for _, key := range slices.Compact(slices.Sorted(slices.Values(domain.MimeTypes))) {
+			out.MimeTypes[key] = noisy(c.MimeTypes[key])
+		}
+		for _, key := range slices.Compact(slices.Sorted(slices.Values(domain.Languages))) {
+			out.Languages[key] = noisy(c.Languages[key])
+		}
+	}
+	for _, p := range entityPatterns {
+		out.EntityTypes[p.entity] = noisy(c.EntityTypes[p.entity])
+	}
+	return out, nil
+}
+
+// BatchExtractStatistics extracts paths in statistics-only mode and aggregates the results.
+// config may be nil; StatisticsOnly is forced on a copy.
+func BatchExtractStatistics(ctx context.Context, paths []string, config *ExtractionConfig) (*CorpusStatistics, error) {
+	return batchExtractStatistics(ctx, defaultPluginRegistry, paths, config)
+}
+
+// BatchExtractStatistics is the package-level BatchExtractStatistics with the client's config.
+// The client's plugins do not run in statistics-only mode.
+func (c *Client) BatchExtractStatistics(ctx context.Context, paths []string) (*CorpusStatistics, error) {
+	return batchExtractStatistics(ctx, c.plugins, paths, c.config)
+}
+
+func batchExtractStatistics(ctx context.Context, plugins *pluginRegistry, paths []string, config *ExtractionConfig) (*CorpusStatistics, error) {
+	enabled := true
+	cfg := &ExtractionConfig{}
+	if config != nil {
+		copied := *config
+		cfg = &copied
+	}
+	cfg.StatisticsOnly = &enabled
+	corpus := NewCorpusStatistics()
+	err := batchExtractFilesToSink(ctx, plugins, paths, cfg, nil, ResultSinkFunc(func(_ int, result *ExtractionResult) error {
+		return corpus.Add(result)
+	}))
+	if err != nil {
+		return nil, err
+	}
+	return corpus, nil
+}
@@ -0,0 +1,128 @@
+package kreuzberg
+
+import (
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCountEntities(t *testing.T) {
+	text := `Contact ada@example.com or see https://example.com/a/2024-01-02 before 2024-03-15.
+Call +44 20 7946 0958, budget $1,200.50 or 300 EUR (up 12.5 %), server 10.0.0.1.`
+	got := CountEntities(text)
+	want := map[EntityType]int{EntityEmail: 1, EntityURL: 1, EntityDate: 1, EntityPhone: 1, EntityMoney: 2, EntityPercent: 1, EntityIPAddress: 1}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected entities: %v", got)
+	}
+	for entity, n := range want {
+		if got[entity] != n {
+			t.Fatalf("unexpected %s count %d: %v", entity, got[entity], got)
+		}
+	}
+	if got := CountEntities("Nothing to see here."); len(got) != 0 {
+		t.Fatalf("expected no entities, got %v", got)
+	}
+}
+
+func TestStatisticsOnlyExtraction(t *testing.T) {
+	enabled := true
+	client := NewClient(&ExtractionConfig{StatisticsOnly: &enabled})
+	ran := false
+	client.RegisterPostProcessor("observer", 0, func(pc *PluginContext, result *ExtractionResult) error {
+		ran = true
+		return nil
+	})
+	result, err := client.ExtractBytes(t.Context(), []byte(testSRT), mimeSRT)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if result.Content != "" || len(result.Chunks) != 0 || len(result.Metadata.Additional) != 1 || ran {
+		t.Fatalf("expected only statistics, got %+v (plugin ran: %v)", result, ran)
+	}
+	stats, ok := result.Metadata.DocumentStatistics()
+	if !ok || stats.MimeType != mimeSRT || stats.WordCount != 9 || stats.LineCount != 4 || stats.CharacterCount != len(wantSRTContent) {
+		t.Fatalf("unexpected statistics: %+v", stats)
+	}
+
+	// The native caches must not store the content of statistics-only extractions.
+	native, err := nativeConfigJSON(&ExtractionConfig{StatisticsOnly: &enabled, UseCache: BoolPtr(true)})
+	if err != nil || !strings.Contains(string(native), `"use_cache":false`) {
+		t.Fatalf("expected the native cache to be disabled, got %s, %v", native, err)
+	}
+}
+
+func TestReduceToStatisticsKeepsNativeStatistics(t *testing.T) {
+	// Results of the native statistics-only extraction carry no content to recompute them from.
+	result, err := statisticsResult(&DocumentStatistics{MimeType: "application/pdf", WordCount: 12, PageCount: 2}, false, "ParsingError")
+	if err != nil {
+		t.Fatalf("statistics result: %v", err)
+	}
+	if err := reduceToStatistics(result); err != nil {
+		t.Fatalf("reduce: %v", err)
+	}
+	stats, ok := result.Metadata.DocumentStatistics()
+	if !ok || stats.WordCount != 12 || stats.PageCount != 2 || result.Success || result.Metadata.Error.ErrorType != "ParsingError" || result.Metadata.Error.Message != "" {
+		t.Fatalf("unexpected result %+v with statistics %+v", result, stats)
+	}
+}
+
+func TestBatchExtractStatistics(t *testing.T) {
+	dir := t.TempDir()
+	paths := []string{filepath.Join(dir, "a.srt"), filepath.Join(dir, "b.srt"), filepath.Join(dir, "missing.srt")}
+	for _, path := range paths[:2] {
+		if err := os.WriteFile(path, []byte(testSRT), 0o600); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	corpus, err := BatchExtractStatistics(t.Context(), paths, nil)
+	if err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+	if corpus.Documents != 3 || corpus.Failed != 1 || corpus.MimeTypes[mimeSRT] != 3 || corpus.Words != 18 {
+		t.Fatalf("unexpected corpus statistics: %+v", corpus)
+	}
+}
+
+func TestCorpusStatisticsWithLaplaceNoise(t *testing.T) {
+	corpus := NewCorpusStatistics()
+	for i := range 200 {
+		result := &ExtractionResult{Content: "Mail ada@example.com today.", MimeType: "text/plain", Success: i%4 != 0, DetectedLanguages: []string{"en", "de", "fr", "es"}}
+		if err := corpus.Add(result); err != nil {
+			t.Fatalf("add: %v", err)
+		}
+		if result.Content == "" {
+			t.Fatalf("Add must not modify a full result")
+		}
+	}
+	if corpus.Documents != 200 || corpus.Failed != 50 || corpus.EntityTypes[EntityEmail] != 200 || corpus.Languages["en"] != 200 || corpus.Languages["es"] != 0 {
+		t.Fatalf("unexpected exact statistics: %+v", corpus)
+	}
+
+	domain := &CorpusDomain{MimeTypes: []string{"text/plain", "application/pdf"}, Languages: []string{"en", "it", "en"}}
+	noisy, err := corpus.WithLaplaceNoise(1000, domain, rand.New(rand.NewPCG(1, 2)))
+	if err != nil {
+		t.Fatalf("noise: %v", err)
+	}
+	if d := noisy.Documents - 200; d < -5 || d > 5 || noisy.Words != 0 || noisy.Entities != 0 {
+		t.Fatalf("expected small noise on counts and no totals, got %+v", noisy)
+	}
+	// Only the public domain's bins are published, whether or not documents have them.
+	if len(noisy.MimeTypes) != 2 || noisy.MimeTypes["application/pdf"] > 5 || len(noisy.Languages) != 2 || noisy.Languages["de"] != 0 {
+		t.Fatalf("expected the bins of the domain, got %v and %v", noisy.MimeTypes, noisy.Languages)
+	}
+	if undisclosed, _ := corpus.WithLaplaceNoise(1000, nil, nil); len(undisclosed.MimeTypes) != 0 || len(undisclosed.Languages) != 0 {
+		t.Fatalf("expected no histogram bins without a domain, got %+v", undisclosed)
+	}
+	if _, ok := noisy.EntityTypes[EntityPhone]; !ok {
+		t.Fatalf("expected every entity type bin to be published, got %v", noisy.EntityTypes)
+	}
+	strong, _ := corpus.WithLaplaceNoise(0.01, domain, rand.New(rand.NewPCG(1, 2)))
+	if strong.Documents == 200 && strong.Failed == 50 {
+		t.Fatalf("expected visible noise for a small epsilon")
+	}
+	if _, err := corpus.WithLaplaceNoise(0, nil, nil); err == nil {
+		t.Fatalf("expected an error for a zero epsilon")
+	}
+}
@@ -0,0 +1,261 @@
+package kreuzberg
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// FallbackStrings extracts printable strings from the raw bytes, like the Unix strings tool.
+// It is a last resort for unknown or unsupported binaries: configure it under the "*" chain so
+// triage pipelines get some signal instead of an UnsupportedFormatError.
+const FallbackStrings FallbackStrategy = "strings"
+
+// StringEncoding identifies the encoding a printable string was found in.
+type StringEncoding string
+
+const (
+	// StringEncodingUTF8 covers ASCII and valid UTF-8 sequences.
+	StringEncodingUTF8 StringEncoding = "utf-8"
+	// StringEncodingUTF16LE covers little-endian 16-bit Latin-1 text (strings -e l).
+	StringEncodingUTF16LE StringEncoding = "utf-16le"
+	// StringEncodingUTF16BE covers big-endian 16-bit Latin-1 text (strings -e b).
+	StringEncodingUTF16BE StringEncoding = "utf-16be"
+)
+
+const defaultMinStringLength = 4
+
+// StringsOptions tunes printable-string extraction.
+type StringsOptions struct {
+	// MinLength is the minimum number of characters in a string (default 4).
+	MinLength int
+	// Encodings lists the encodings to scan (default: all).
+	Encodings []StringEncoding
+	// MaxStrings caps the number of strings returned (0 = unlimited).
+	MaxStrings int
+	// KeepNoise disables the sanity filter that drops strings made mostly of symbols
+	// or a single repeated character.
+	KeepNoise bool
+}
+
+// FoundString is a printable string located in binary data.
+type FoundString struct {
+	// Offset is the byte offset of the string within the input.
+	Offset int `json:"offset"`
+	// Encoding is the encoding the string was decoded from.
+	Encoding StringEncoding `json:"encoding"`
+	// Text is the decoded string.
+	Text string `json:"text"`
+}
+
+// ExtractStrings returns the printable strings found in data, ordered by offset.
+func ExtractStrings(data []byte, opts *StringsOptions) []FoundString {
+	var o StringsOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.MinLength <= 0 {
+		o.MinLength = defaultMinStringLength
+	}
+	encodings := o.Encodings
+	if len(encodings) == 0 {
+		encodings = []StringEncoding{StringEncodingUTF8, StringEncodingUTF16LE, StringEncodingUTF16BE}
+	}
+
+	var found []FoundString
+	for _, enc := range encodings {
+		switch enc {
+		case StringEncodingUTF8:
+			found = append(found, scanUTF8Strings(data, o)...)
+		case StringEncodingUTF16LE:
+			found = append(found, scanUTF16Strings(data, o, false)...)
+		case StringEncodingUTF16BE:
+			found = append(found, scanUTF16Strings(data, o, true)...)
+		}
+	}
+
+	sort.SliceStable(found, func(i, j int) bool { return found[i].Offset < found[j].Offset })
+	found = dropOverlappingUTF16(found)
+	if o.MaxStrings > 0 && len(found) > o.MaxStrings {
+		found = found[:o.MaxStrings]
+	}
+	return found
+}
+
+func isPrintableStringRune(r rune) bool {
+	return r == '\t' || (r != utf8.RuneError && unicode.IsPrint(r))
+}
+
+func scanUTF8Strings(data []byte, o StringsOptions) []FoundString {
+	var out []FoundString
+	var b strings.Builder
+	start, count := 0, 0
+
+	flush := func() {
+		if count >= o.MinLength {
+			out = appendFoundString(out, start, 1, StringEncodingUTF8, b.String(), o)
+		}
+		b.Reset()
+		count = 0
+	}
+
+	for i := 0; i < len(data); {
+		r, size := utf8.DecodeRune(data[i:])
+		if !isPrintableStringRune(r) {
+			flush()
+			i += size
+			continue
+		}
+		if count == 0 {
+			start = i
+		}
+		b.WriteRune(r)
+		count++
+		i += size
+	}
+	flush()
+	return out
+}
+
+// scanUTF16Strings scans both byte alignments for 16-bit characters in the Latin-1 range.
+func scanUTF16Strings(data []byte, o StringsOptions, bigEndian bool) []FoundString {
+	encoding := StringEncodingUTF16LE
+	if bigEndian {
+		encoding = StringEncodingUTF16BE
+	}
+
+	var out []FoundString
+	for align := 0; align < 2; align++ {
+		var b strings.Builder
+		start, count := 0, 0
+
+		flush := func() {
+			if count >= o.MinLength {
+				out = appendFoundString(out, start, 2, encoding, b.String(), o)
+			}
+			b.Reset()
+			count = 0
+		}
+
+		for i := align; i+1 < len(data); i += 2 {
+			lo, hi := data[i], data[i+1]
+			if bigEndian {
+				lo, hi = hi, lo
+			}
+			r := rune(lo)
+			if hi != 0 || !isPrintableStringRune(r) {
+				flush()
+				continue
+			}
+			if count == 0 {
+				start = i
+			}
+			b.WriteRune(r)
+			count++
+		}
+		flush()
+	}
+	return out
+}
+
+// dropOverlappingUTF16 resolves the ambiguity between little-endian text and the same bytes
+// read big-endian one byte later, keeping the longer of two overlapping UTF-16 strings.
+func dropOverlappingUTF16(found []FoundString) []FoundString {
+	span := func(s FoundString) int { return 2 * utf8.RuneCountInString(s.Text) }
+
+	out := found[:0]
+	lastWide := -1
+	for _, s := range found {
+		if s.Encoding == StringEncodingUTF8 {
+			out = append(out, s)
+			continue
+		}
+		if lastWide >= 0 {
+			prev := out[lastWide]
+			if s.Offset < prev.Offset+span(prev) {
+				if span(s) > span(prev) {
+					out[lastWide] = s
+				}
+				continue
+			}
+		}
+		out = append(out, s)
+		lastWide = len(out) - 1
+	}
+	return out
+}
+
+func appendFoundString(out []FoundString, offset, width int, encoding StringEncoding, text string, o StringsOptions) []FoundString {
+	trimmed := strings.TrimLeft(text, " \t")
+	offset += width * (len(text) - len(trimmed))
+	text = strings.TrimRight(trimmed, " \t")
+	if utf8.RuneCountInString(text) < o.MinLength {
+		return out
+	}
+	if !o.KeepNoise && looksLikeNoise(text) {
+		return out
+	}
+	return append(out, FoundString{Offset: offset, Encoding: encoding, Text: text})
+}
+
+// looksLikeNoise rejects runs that are mostly punctuation/symbols or one repeated character,
+// which dominate the output of naive strings scans over compressed or padded data.
+func looksLikeNoise(text string) bool {
+	total, alnum := 0, 0
+	var first rune
+	repeated := true
+	for i, r := range text {
+		if i == 0 {
+			first = r
+		} else if r != first {
+			repeated = false
+		}
+		total++
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			alnum++
+		}
+	}
+	if total == 0 || repeated {
+		return true
+	}
+	return alnum*2 < total
+}
+
+func fallbackExtractStrings(src documentSource, mimeType string, config *ExtractionConfig) (*ExtractionResult, error) {
+	data, err := src.bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	var opts *StringsOptions
+	if config != nil && config.Fallback != nil {
+		opts = config.Fallback.Strings
+	}
+	found := ExtractStrings(data, opts)
+
+	lines := make([]string, len(found))
+	for i, s := range found {
+		lines[i] = s.Text
+	}
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+
+	count, err := json.Marshal(len(found))
+	if err != nil {
+		return nil, newSerializationErrorWithContext("failed to encode strings metadata", err, ErrorCodeValidation, nil)
+	}
+	return &ExtractionResult{
+		Content:  strings.Join(lines, "\n"),
+		MimeType: mimeType,
+		Tables:   []Table{},
+		Metadata: Metadata{Additional: map[string]json.RawMessage{"string_count": count}},
+		Success:  true,
+	}, nil
+}
+
+func init() {
+	fallbackStrategies[FallbackStrings] = fallbackExtractStrings
+}
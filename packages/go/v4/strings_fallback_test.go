@@ -0,0 +1,70 @@
+package kreuzberg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractStringsFindsASCIIAndUTF16(t *testing.T) {
+	data := []byte("\x00\x01\x02Hello world\x00\xff\xfe")
+	data = append(data, []byte("W\x00i\x00d\x00e\x00 \x00t\x00e\x00x\x00t\x00\x00\x00")...)
+	data = append(data, []byte("\x00B\x00i\x00g\x00E\x00n\x00d\x00i\x00a\x00n")...)
+
+	found := ExtractStrings(data, nil)
+	got := map[StringEncoding]string{}
+	for _, s := range found {
+		got[s.Encoding] = s.Text
+	}
+	if got[StringEncodingUTF8] != "Hello world" {
+		t.Fatalf("missing utf-8 string: %+v", found)
+	}
+	if got[StringEncodingUTF16LE] != "Wide text" {
+		t.Fatalf("missing utf-16le string: %+v", found)
+	}
+	if got[StringEncodingUTF16BE] != "BigEndian" {
+		t.Fatalf("missing utf-16be string: %+v", found)
+	}
+	for i := 1; i < len(found); i++ {
+		if found[i].Offset < found[i-1].Offset {
+			t.Fatalf("strings not ordered by offset: %+v", found)
+		}
+	}
+}
+
+func TestExtractStringsFiltersNoise(t *testing.T) {
+	data := []byte("\x00AAAAAAAA\x00!@#$%^&*\x00héllo wörld\x00ab\x00")
+
+	found := ExtractStrings(data, &StringsOptions{Encodings: []StringEncoding{StringEncodingUTF8}})
+	if len(found) != 1 || found[0].Text != "héllo wörld" {
+		t.Fatalf("unexpected strings: %+v", found)
+	}
+
+	noisy := ExtractStrings(data, &StringsOptions{Encodings: []StringEncoding{StringEncodingUTF8}, KeepNoise: true})
+	if len(noisy) != 3 {
+		t.Fatalf("expected noise to be kept, got %+v", noisy)
+	}
+
+	capped := ExtractStrings(data, &StringsOptions{KeepNoise: true, MaxStrings: 1})
+	if len(capped) != 1 {
+		t.Fatalf("expected MaxStrings to cap output, got %+v", capped)
+	}
+}
+
+func TestFallbackStringsStrategy(t *testing.T) {
+	src := documentSource{data: []byte("\x7fELF\x02\x01\x00\x00usage: tool [options]\x00\x00")}
+	cfg := &ExtractionConfig{Fallback: &FallbackConfig{Strings: &StringsOptions{MinLength: 6}}}
+
+	result, err := fallbackStrategies[FallbackStrings](src, "", cfg)
+	if err != nil {
+		t.Fatalf("strings fallback: %v", err)
+	}
+	if !result.Success || result.MimeType != "application/octet-stream" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if !strings.Contains(result.Content, "usage: tool [options]") || strings.Contains(result.Content, "ELF") {
+		t.Fatalf("unexpected content: %q", result.Content)
+	}
+	if string(result.Metadata.Additional["string_count"]) != "1" {
+		t.Fatalf("unexpected string_count: %s", result.Metadata.Additional["string_count"])
+	}
+}
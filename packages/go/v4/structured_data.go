@@ -0,0 +1,415 @@
+package kreuzberg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const defaultStructuredMaxPaths = 1000
+
+const (
+	mimeJSON = "application/json"
+	mimeYAML = "application/x-yaml"
+)
+
+// StructuredDataConfig enables structure-aware extraction of JSON and YAML documents. Instead of
+// a plain text blob, the content lists every leaf as a "path: value" line (or Markdown when
+// Markdown is set), and the metadata carries key paths, depth statistics and an inferred schema.
+type StructuredDataConfig struct {
+	// Markdown renders the document as Markdown (headings, nested lists, and tables for arrays
+	// of flat objects) instead of flattened path/value lines.
+	Markdown bool
+	// MaxPaths caps the entries reported in Metadata.Additional["paths"] (default 1000).
+	MaxPaths int
+}
+
+// StructuredPath is a flattened leaf of a JSON or YAML document, as reported in
+// Metadata.Additional["paths"].
+type StructuredPath struct {
+	// Path addresses the leaf in JSONPath notation, e.g. "$.servers[0].host".
+	Path string `json:"path"`
+	// Type is "string", "integer", "number", "boolean", "null", or "object"/"array" for empty containers.
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// StructureStats summarizes the shape of a JSON or YAML document, as reported in
+// Metadata.Additional["structure"].
+type StructureStats struct {
+	// MaxDepth is the deepest container nesting; a scalar document has depth 0.
+	MaxDepth      int     `json:"max_depth"`
+	MeanLeafDepth float64 `json:"mean_leaf_depth"`
+	LeafCount     int     `json:"leaf_count"`
+	ObjectCount   int     `json:"object_count"`
+	ArrayCount    int     `json:"array_count"`
+}
+
+// structNode is a parsed JSON or YAML value. Objects keep their key order.
+type structNode struct {
+	// kind is "object", "array", "string", "integer", "number", "boolean" or "null".
+	kind     string
+	keys     []string
+	children []*structNode
+	scalar   string
+}
+
+func (n *structNode) isContainer() bool {
+	return n.kind == "object" || n.kind == "array"
+}
+
+// parseJSONTree decodes JSON into a structNode, preserving object key order.
+func parseJSONTree(text string) (*structNode, error) {
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	var parse func() (*structNode, error)
+	parse = func() (*structNode, error) {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch v := tok.(type) {
+		case json.Delim:
+			node := &structNode{kind: "object"}
+			if v == '[' {
+				node.kind = "array"
+			}
+			for dec.More() {
+				if node.kind == "object" {
+					keyTok, err := dec.Token()
+					if err != nil {
+						return nil, err
+					}
+					node.keys = append(node.keys, keyTok.(string))
+				}
+				child, err := parse()
+				if err != nil {
+					return nil, err
+				}
+				node.children = append(node.children, child)
+			}
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+			return node, nil
+		case string:
+			return &structNode{kind: "string", scalar: v}, nil
+		case json.Number:
+			if _, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+				return &structNode{kind: "integer", scalar: string(v)}, nil
+			}
+			return &structNode{kind: "number", scalar: string(v)}, nil
+		case bool:
+			return &structNode{kind: "boolean", scalar: strconv.FormatBool(v)}, nil
+		case nil:
+			return &structNode{kind: "null", scalar: "null"}, nil
+		}
+		return nil, fmt.Errorf("unexpected JSON token %v", tok)
+	}
+
+	root, err := parse()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("unexpected data after top-level JSON value")
+	}
+	return root, nil
+}
+
+var plainPathKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+func childPath(parent, key string) string {
+	if plainPathKey.MatchString(key) {
+		return parent + "." + key
+	}
+	quoted, _ := json.Marshal(key)
+	return parent + "[" + string(quoted) + "]"
+}
+
+// walkStructure visits the leaves of root (scalars and empty containers) with their paths and
+// depths, and returns the document statistics.
+func walkStructure(root *structNode, visit func(path string, depth int, leaf *structNode)) StructureStats {
+	var stats StructureStats
+	depthSum := 0
+	var walk func(n *structNode, path string, depth int)
+	walk = func(n *structNode, path string, depth int) {
+		switch n.kind {
+		case "object":
+			stats.ObjectCount++
+		case "array":
+			stats.ArrayCount++
+		}
+		if n.isContainer() {
+			stats.MaxDepth = max(stats.MaxDepth, depth+1)
+		}
+		if !n.isContainer() || len(n.children) == 0 {
+			stats.LeafCount++
+			depthSum += depth
+			visit(path, depth, n)
+			return
+		}
+		for i, child := range n.children {
+			if n.kind == "object" {
+				walk(child, childPath(path, n.keys[i]), depth+1)
+			} else {
+				walk(child, fmt.Sprintf("%s[%d]", path, i), depth+1)
+			}
+		}
+	}
+	walk(root, "$", 0)
+	if stats.LeafCount > 0 {
+		stats.MeanLeafDepth = float64(depthSum) / float64(stats.LeafCount)
+	}
+	return stats
+}
+
+func leafValue(n *structNode) string {
+	switch {
+	case n.kind == "object":
+		return "{}"
+	case n.kind == "array":
+		return "[]"
+	}
+	return n.scalar
+}
+
+// inferredSchema is a JSON Schema fragment merged across all values seen at one position.
+type inferredSchema struct {
+	types      map[string]bool
+	properties map[string]*inferredSchema
+	// keyCounts counts the objects each property appeared in; objects counts all objects merged.
+	keyCounts map[string]int
+	objects   int
+	items     *inferredSchema
+}
+
+func inferSchema(n *structNode) *inferredSchema {
+	s := &inferredSchema{}
+	s.merge(n)
+	return s
+}
+
+func (s *inferredSchema) merge(n *structNode) {
+	if s.types == nil {
+		s.types = map[string]bool{}
+	}
+	s.types[n.kind] = true
+	switch n.kind {
+	case "object":
+		if s.properties == nil {
+			s.properties = map[string]*inferredSchema{}
+			s.keyCounts = map[string]int{}
+		}
+		s.objects++
+		for i, key := range n.keys {
+			child := s.properties[key]
+			if child == nil {
+				child = &inferredSchema{}
+				s.properties[key] = child
+			}
+			child.merge(n.children[i])
+			s.keyCounts[key]++
+		}
+	case "array":
+		for _, child := range n.children {
+			if s.items == nil {
+				s.items = &inferredSchema{}
+			}
+			s.items.merge(child)
+		}
+	}
+}
+
+func (s *inferredSchema) MarshalJSON() ([]byte, error) {
+	types := make([]string, 0, len(s.types))
+	for t := range s.types {
+		// Integers widen to number when both occur at the same position.
+		if t != "integer" || !s.types["number"] {
+			types = append(types, t)
+		}
+	}
+	sort.Strings(types)
+
+	out := map[string]any{}
+	if len(types) == 1 {
+		out["type"] = types[0]
+	} else {
+		out["type"] = types
+	}
+	if s.properties != nil {
+		out["properties"] = s.properties
+		required := []string{}
+		for key, count := range s.keyCounts {
+			if count == s.objects {
+				required = append(required, key)
+			}
+		}
+		sort.Strings(required)
+		out["required"] = required
+	}
+	if s.items != nil {
+		out["items"] = s.items
+	}
+	return json.Marshal(out)
+}
+
+// flatTableRows renders an array of objects whose values are all scalars as table rows, with a
+// header of the keys in first-seen order. It reports false for other values.
+func flatTableRows(n *structNode) ([][]string, bool) {
+	if n.kind != "array" || len(n.children) == 0 {
+		return nil, false
+	}
+	var header []string
+	index := map[string]int{}
+	for _, item := range n.children {
+		if item.kind != "object" {
+			return nil, false
+		}
+		for i, key := range item.keys {
+			if item.children[i].isContainer() {
+				return nil, false
+			}
+			if _, ok := index[key]; !ok {
+				index[key] = len(header)
+				header = append(header, key)
+			}
+		}
+	}
+	rows := [][]string{header}
+	for _, item := range n.children {
+		row := make([]string, len(header))
+		for i, key := range item.keys {
+			row[index[key]] = item.children[i].scalar
+		}
+		rows = append(rows, row)
+	}
+	return rows, true
+}
+
+// structMarkdown renders a structured document as Markdown. Top-level object keys become
+// headings, arrays of flat objects become tables (also returned as Tables), and other nesting
+// becomes nested bullet lists.
+func structMarkdown(root *structNode) (string, []Table) {
+	var tables []Table
+	block := func(n *structNode) string {
+		if rows, ok := flatTableRows(n); ok {
+			markdown := spreadsheetMarkdown(rows)
+			tables = append(tables, Table{Cells: rows, Markdown: markdown, PageNumber: 1})
+			return strings.TrimRight(markdown, "\n")
+		}
+		if !n.isContainer() {
+			return n.scalar
+		}
+		if len(n.children) == 0 {
+			return leafValue(n)
+		}
+		var b strings.Builder
+		writeStructList(&b, n, 0)
+		return strings.TrimRight(b.String(), "\n")
+	}
+
+	if root.kind != "object" {
+		return block(root), tables
+	}
+	sections := make([]string, len(root.keys))
+	for i, key := range root.keys {
+		sections[i] = fmt.Sprintf("## %s\n\n%s", key, block(root.children[i]))
+	}
+	return strings.Join(sections, "\n\n"), tables
+}
+
+func writeStructList(b *strings.Builder, n *structNode, indent int) {
+	pad := strings.Repeat("  ", indent)
+	for i, child := range n.children {
+		label := "-"
+		if n.kind == "object" {
+			label = fmt.Sprintf("- **%s**:", n.keys[i])
+		}
+		if child.isContainer() && len(child.children) > 0 {
+			fmt.Fprintf(b, "%s%s\n", pad, label)
+			writeStructList(b, child, indent+1)
+			continue
+		}
+		fmt.Fprintf(b, "%s%s %s\n", pad, label, leafValue(child))
+	}
+}
+
+func extractStructuredData(src documentSource, mimeType string, config *ExtractionConfig) (*ExtractionResult, error) {
+	data, err := src.bytes()
+	if err != nil {
+		return nil, err
+	}
+	text, _, err := decodeText(data, "")
+	if err != nil {
+		return nil, err
+	}
+
+	format := "yaml"
+	var root *structNode
+	if strings.Contains(mimeType, "json") {
+		format = "json"
+		root, err = parseJSONTree(text)
+	} else {
+		root, err = parseYAMLTree(text)
+	}
+	if err != nil {
+		return nil, newParsingErrorWithContext(fmt.Sprintf("failed to parse %s document", strings.ToUpper(format)), err, ErrorCodeParsing, nil)
+	}
+
+	opts := *config.StructuredData
+	if opts.MaxPaths <= 0 {
+		opts.MaxPaths = defaultStructuredMaxPaths
+	}
+	var lines []string
+	paths := []StructuredPath{}
+	stats := walkStructure(root, func(path string, depth int, leaf *structNode) {
+		lines = append(lines, path+": "+leafValue(leaf))
+		if len(paths) < opts.MaxPaths {
+			paths = append(paths, StructuredPath{Path: path, Type: leaf.kind, Value: leafValue(leaf)})
+		}
+	})
+
+	result := &ExtractionResult{MimeType: mimeType, Tables: []Table{}, Success: true}
+	if opts.Markdown {
+		var tables []Table
+		result.Content, tables = structMarkdown(root)
+		result.Tables = append(result.Tables, tables...)
+	} else {
+		result.Content = strings.Join(lines, "\n")
+	}
+
+	additional := map[string]any{
+		"structured_format": format,
+		"paths":             paths,
+		"paths_truncated":   stats.LeafCount > len(paths),
+		"structure":         stats,
+		"schema":            inferSchema(root),
+	}
+	result.Metadata.Additional = make(map[string]json.RawMessage, len(additional))
+	for key, value := range additional {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, newSerializationErrorWithContext("failed to encode structured data metadata", err, ErrorCodeValidation, nil)
+		}
+		result.Metadata.Additional[key] = raw
+	}
+	return result, nil
+}
+
+func init() {
+	registerGoPrimaryExtractor(goPrimaryExtractor{
+		name:       "structured-data",
+		mimeTypes:  []string{mimeJSON, "text/json", mimeYAML, "application/yaml", "text/yaml", "text/x-yaml"},
+		extensions: map[string]string{"json": mimeJSON, "yaml": mimeYAML, "yml": mimeYAML},
+		enabled: func(config *ExtractionConfig) bool {
+			return config != nil && config.StructuredData != nil
+		},
+		extract: extractStructuredData,
+	})
+}
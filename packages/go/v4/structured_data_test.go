@@ -0,0 +1,214 @@
+package kreuzberg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func structLeaves(root *structNode) []string {
+	var out []string
+	walkStructure(root, func(path string, depth int, leaf *structNode) {
+		out = append(out, path+"="+leafValue(leaf))
+	})
+	return out
+}
+
+func TestParseJSONTreeKeepsKeyOrder(t *testing.T) {
+	root, err := parseJSONTree(`{"zeta": 1, "alpha": {"list": [true, null, 2.5]}, "odd key": "x", "empty": []}`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	got := strings.Join(structLeaves(root), "\n")
+	want := strings.Join([]string{
+		"$.zeta=1",
+		"$.alpha.list[0]=true",
+		"$.alpha.list[1]=null",
+		"$.alpha.list[2]=2.5",
+		`$["odd key"]=x`,
+		"$.empty=[]",
+	}, "\n")
+	if got != want {
+		t.Fatalf("unexpected leaves:\n%s\nwant:\n%s", got, want)
+	}
+	if _, err := parseJSONTree(`{"a": 1} trailing`); err == nil {
+		t.Fatalf("expected error for trailing data")
+	}
+}
+
+func TestParseYAMLTree(t *testing.T) {
+	doc := `# service config
+defaults: &defaults
+  retries: 3
+  timeout: 1.5
+service:
+  <<: *defaults
+  name: "api # not a comment"
+  enabled: yes
+  tags: [web, 'internal', {tier: 1}]
+  hosts:
+  - name: a.example.com
+    port: 8080
+  - name: b.example.com
+    port: 8081
+  description: |
+    first line
+    second line
+  summary: >-
+    folded
+    text
+  nothing: ~
+  matrix:
+    - - 1
+      - 2
+    - []
+`
+	root, err := parseYAMLTree(doc)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	got := strings.Join(structLeaves(root), "\n")
+	want := strings.Join([]string{
+		"$.defaults.retries=3",
+		"$.defaults.timeout=1.5",
+		"$.service.retries=3",
+		"$.service.timeout=1.5",
+		"$.service.name=api # not a comment",
+		"$.service.enabled=yes",
+		"$.service.tags[0]=web",
+		"$.service.tags[1]=internal",
+		"$.service.tags[2].tier=1",
+		"$.service.hosts[0].name=a.example.com",
+		"$.service.hosts[0].port=8080",
+		"$.service.hosts[1].name=b.example.com",
+		"$.service.hosts[1].port=8081",
+		"$.service.description=first line\nsecond line\n",
+		"$.service.summary=folded text",
+		"$.service.nothing=null",
+		"$.service.matrix[0][0]=1",
+		"$.service.matrix[0][1]=2",
+		"$.service.matrix[1]=[]",
+	}, "\n")
+	if got != want {
+		t.Fatalf("unexpected leaves:\n%s\nwant:\n%s", got, want)
+	}
+
+	// "yes" is a string under the YAML 1.2 core schema; ports are integers.
+	service := root.children[1]
+	if service.children[2].kind != "string" || service.children[5].children[0].children[1].kind != "integer" {
+		t.Fatalf("unexpected scalar resolution: %+v", service.children)
+	}
+}
+
+func TestParseYAMLMultipleDocuments(t *testing.T) {
+	root, err := parseYAMLTree("---\na: 1\n---\n- x\n...\n")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got := strings.Join(structLeaves(root), ","); got != "$[0].a=1,$[1][0]=x" {
+		t.Fatalf("unexpected leaves: %s", got)
+	}
+	if _, err := parseYAMLTree("a: 1\n b: [unterminated\n"); err == nil {
+		t.Fatalf("expected error for malformed YAML")
+	}
+}
+
+func TestParseYAMLFlowAliases(t *testing.T) {
+	root, err := parseYAMLTree("base: &b {host: db, port: 5432}\nreplicas: [*b, {host: &h db2}, *h]\n")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := "$.base.host=db,$.base.port=5432,$.replicas[0].host=db,$.replicas[0].port=5432,$.replicas[1].host=db2,$.replicas[2]=db2"
+	if got := strings.Join(structLeaves(root), ","); got != want {
+		t.Fatalf("unexpected leaves: %s", got)
+	}
+	if _, err := parseYAMLTree("a: [*missing]\n"); err == nil {
+		t.Fatalf("expected error for an unknown alias")
+	}
+}
+
+func TestParseYAMLRejectsAliasBombs(t *testing.T) {
+	var doc strings.Builder
+	doc.WriteString("a0: &a0 [lol, lol, lol, lol, lol, lol, lol, lol, lol, lol]\n")
+	for i := 1; i <= 9; i++ {
+		fmt.Fprintf(&doc, "a%d: &a%d [*a%[3]d, *a%[3]d, *a%[3]d, *a%[3]d, *a%[3]d, *a%[3]d, *a%[3]d, *a%[3]d, *a%[3]d, *a%[3]d]\n", i, i, i-1)
+	}
+	_, err := ExtractBytesSync([]byte(doc.String()), mimeYAML, &ExtractionConfig{StructuredData: &StructuredDataConfig{}})
+	var parseErr *ParsingError
+	if !errors.As(err, &parseErr) || !strings.Contains(err.Error(), "aliases expand") {
+		t.Fatalf("expected the alias bomb to be rejected, got %v", err)
+	}
+
+	block := "base: &b\n  k: v\nlist:\n" + strings.Repeat("  - *b\n", 1000)
+	if _, err := parseYAMLTree(block); err != nil {
+		t.Fatalf("expected modest alias use to parse, got %v", err)
+	}
+}
+
+func TestStructureStatsAndSchema(t *testing.T) {
+	root, err := parseJSONTree(`{"items": [{"id": 1, "name": "a"}, {"id": 2.5}], "ok": true}`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	stats := walkStructure(root, func(string, int, *structNode) {})
+	if stats.MaxDepth != 3 || stats.LeafCount != 4 || stats.ObjectCount != 3 || stats.ArrayCount != 1 || stats.MeanLeafDepth != 2.5 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	raw, err := json.Marshal(inferSchema(root))
+	if err != nil {
+		t.Fatalf("marshal schema: %v", err)
+	}
+	want := `{"properties":{"items":{"items":{"properties":{"id":{"type":"number"},"name":{"type":"string"}},"required":["id"],"type":"object"},"type":"array"},"ok":{"type":"boolean"}},"required":["items","ok"],"type":"object"}`
+	if string(raw) != want {
+		t.Fatalf("unexpected schema:\n%s\nwant:\n%s", raw, want)
+	}
+}
+
+func TestStructMarkdown(t *testing.T) {
+	root, err := parseYAMLTree("title: Report\nrows:\n  - id: 1\n    name: a\n  - id: 2\nowner:\n  name: ops\n  contacts: [a@x, b@x]\n")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	markdown, tables := structMarkdown(root)
+	for _, want := range []string{"## title\n\nReport", "## rows\n\n| id | name |", "## owner\n\n- **name**: ops\n- **contacts**:\n  - a@x\n  - b@x"} {
+		if !strings.Contains(markdown, want) {
+			t.Fatalf("markdown missing %q:\n%s", want, markdown)
+		}
+	}
+	if len(tables) != 1 || len(tables[0].Cells) != 3 || tables[0].Cells[2][1] != "" {
+		t.Fatalf("unexpected tables: %+v", tables)
+	}
+}
+
+func TestExtractStructuredData(t *testing.T) {
+	if extractor, _ := selectGoPrimaryExtractor(documentSource{path: "config.yaml"}, nil); extractor != nil {
+		t.Fatalf("structured extraction must be opt-in")
+	}
+	config := &ExtractionConfig{StructuredData: &StructuredDataConfig{MaxPaths: 1}}
+	extractor, mimeType := selectGoPrimaryExtractor(documentSource{path: "config.yml"}, config)
+	if extractor == nil || extractor.name != "structured-data" || mimeType != mimeYAML {
+		t.Fatalf("unexpected routing: %v %q", extractor, mimeType)
+	}
+
+	result, err := extractor.extract(documentSource{data: []byte("a: 1\nb: [x, y]\n")}, mimeType, config)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if result.Content != "$.a: 1\n$.b[0]: x\n$.b[1]: y" {
+		t.Fatalf("unexpected content: %q", result.Content)
+	}
+	var paths []StructuredPath
+	if err := json.Unmarshal(result.Metadata.Additional["paths"], &paths); err != nil || len(paths) != 1 || paths[0] != (StructuredPath{Path: "$.a", Type: "integer", Value: "1"}) {
+		t.Fatalf("unexpected paths: %s", result.Metadata.Additional["paths"])
+	}
+	if string(result.Metadata.Additional["paths_truncated"]) != "true" || string(result.Metadata.Additional["structured_format"]) != `"yaml"` {
+		t.Fatalf("unexpected metadata: %v", result.Metadata.Additional)
+	}
+
+	if _, err := extractor.extract(documentSource{data: []byte("{")}, mimeJSON, config); err == nil {
+		t.Fatalf("expected parse error for malformed JSON")
+	}
+}
@@ -0,0 +1,658 @@
+package kreuzberg
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// yamlLine is one physical line of a YAML document.
+type yamlLine struct {
+	num    int
+	indent int
+	raw    string
+	// text is the line without indentation and trailing comment ("" for blank/comment lines).
+	text string
+}
+
+// yamlParser parses the block-structured subset of YAML found in configuration and data files:
+// block mappings and sequences, flow collections, plain/quoted/block scalars, comments,
+// anchors/aliases and multiple documents. Tags are accepted and ignored.
+type yamlParser struct {
+	lines   []yamlLine
+	pos     int
+	anchors map[string]*structNode
+	aliases *yamlAliasBudget
+}
+
+// yamlMaxAliasNodes caps the nodes aliases may expand to across a YAML stream. Anchored
+// subtrees are shared rather than copied, but every consumer walks them once per alias, so
+// nested aliases ("billion laughs") would otherwise turn a tiny file into billions of nodes.
+const yamlMaxAliasNodes = 1_000_000
+
+// yamlAliasBudget accounts the nodes that aliases expand to.
+type yamlAliasBudget struct {
+	remaining int
+	// sizes memoizes the expanded size of anchored subtrees, saturating above remaining.
+	sizes map[*structNode]int
+}
+
+// expand charges an alias to target against the budget.
+func (b *yamlAliasBudget) expand(target *structNode) error {
+	size := b.size(target)
+	if size > b.remaining {
+		return fmt.Errorf("aliases expand to more than %d nodes", yamlMaxAliasNodes)
+	}
+	b.remaining -= size
+	return nil
+}
+
+func (b *yamlAliasBudget) size(n *structNode) int {
+	if size, ok := b.sizes[n]; ok {
+		return size
+	}
+	size := 1
+	for _, child := range n.children {
+		size = min(size+b.size(child), b.remaining+1)
+	}
+	b.sizes[n] = size
+	return size
+}
+
+var (
+	yamlIntPattern   = regexp.MustCompile(`^[-+]?(0|[1-9][0-9]*|0x[0-9a-fA-F]+|0o[0-7]+)$`)
+	yamlFloatPattern = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$|^[-+]?\.(inf|Inf|INF)$|^\.(nan|NaN|NAN)$`)
+)
+
+// parseYAMLTree parses a YAML stream. A stream with several documents becomes an array of them.
+func parseYAMLTree(text string) (*structNode, error) {
+	var docs []*structNode
+	var current []yamlLine
+	started := false
+	aliases := &yamlAliasBudget{remaining: yamlMaxAliasNodes, sizes: map[*structNode]int{}}
+	flush := func() error {
+		if !started && !hasYAMLContent(current) {
+			return nil
+		}
+		p := &yamlParser{lines: current, anchors: map[string]*structNode{}, aliases: aliases}
+		doc, err := p.document()
+		if err != nil {
+			return err
+		}
+		docs = append(docs, doc)
+		current = nil
+		return nil
+	}
+
+	for i, raw := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimRight(raw, " \t")
+		switch {
+		case strings.HasPrefix(trimmed, "%"):
+			continue
+		case trimmed == "---" || strings.HasPrefix(trimmed, "--- "):
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			started = true
+			if rest := strings.TrimSpace(trimmed[3:]); rest != "" {
+				current = append(current, newYAMLLine(i+1, rest))
+			}
+			continue
+		case trimmed == "...":
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			started = false
+			continue
+		}
+		current = append(current, newYAMLLine(i+1, raw))
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	switch len(docs) {
+	case 0:
+		return &structNode{kind: "null", scalar: "null"}, nil
+	case 1:
+		return docs[0], nil
+	}
+	return &structNode{kind: "array", children: docs}, nil
+}
+
+func hasYAMLContent(lines []yamlLine) bool {
+	for _, line := range lines {
+		if line.text != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func newYAMLLine(num int, raw string) yamlLine {
+	body := strings.TrimLeft(raw, " ")
+	return yamlLine{num: num, indent: len(raw) - len(body), raw: raw, text: stripYAMLComment(body)}
+}
+
+// stripYAMLComment removes a trailing "# comment" that is outside quotes.
+func stripYAMLComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote == '\'' && c == '\'':
+			quote = 0
+		case quote == '"' && c == '\\':
+			i++
+		case quote == '"' && c == '"':
+			quote = 0
+		case quote != 0:
+		case (c == '"' || c == '\'') && (i == 0 || strings.ContainsRune(" \t[{,:-", rune(s[i-1]))):
+			quote = c
+		case c == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return strings.TrimRight(s[:i], " \t")
+		}
+	}
+	return strings.TrimRight(s, " \t")
+}
+
+func (p *yamlParser) errorf(format string, args ...any) error {
+	num := 0
+	if p.pos < len(p.lines) {
+		num = p.lines[p.pos].num
+	} else if len(p.lines) > 0 {
+		num = p.lines[len(p.lines)-1].num
+	}
+	return fmt.Errorf("yaml line %d: %s", num, fmt.Sprintf(format, args...))
+}
+
+// peek returns the next non-blank line without consuming it.
+func (p *yamlParser) peek() (yamlLine, bool) {
+	for p.pos < len(p.lines) && p.lines[p.pos].text == "" {
+		p.pos++
+	}
+	if p.pos >= len(p.lines) {
+		return yamlLine{}, false
+	}
+	return p.lines[p.pos], true
+}
+
+func (p *yamlParser) document() (*structNode, error) {
+	line, ok := p.peek()
+	if !ok {
+		return &structNode{kind: "null", scalar: "null"}, nil
+	}
+	node, err := p.block(line.indent)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.peek(); ok {
+		return nil, p.errorf("unexpected content")
+	}
+	return node, nil
+}
+
+// block parses the node starting at the next line, which must be indented at least minIndent.
+func (p *yamlParser) block(minIndent int) (*structNode, error) {
+	line, ok := p.peek()
+	if !ok || line.indent < minIndent {
+		return &structNode{kind: "null", scalar: "null"}, nil
+	}
+	switch {
+	case isYAMLSequenceItem(line.text):
+		return p.sequence(line.indent)
+	case yamlKeySplit(line.text) >= 0:
+		return p.mapping(line.indent)
+	}
+	p.pos++
+	return p.inline(line.text, line.indent-1)
+}
+
+func isYAMLSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// yamlKeySplit returns the index of the ": " (or trailing ":") separating a mapping key from its
+// value, or -1 when text is not a mapping entry.
+func yamlKeySplit(text string) int {
+	if text == "" || strings.ContainsRune("[{|>*&!%@`", rune(text[0])) {
+		return -1
+	}
+	start := 0
+	if text[0] == '"' || text[0] == '\'' {
+		end := closingQuote(text, text[0])
+		if end < 0 {
+			return -1
+		}
+		start = end + 1
+	}
+	for i := start; i < len(text); i++ {
+		if text[i] == ':' && (i == len(text)-1 || text[i+1] == ' ' || text[i+1] == '\t') {
+			return i
+		}
+	}
+	return -1
+}
+
+func closingQuote(s string, quote byte) int {
+	for i := 1; i < len(s); i++ {
+		switch {
+		case quote == '"' && s[i] == '\\':
+			i++
+		case s[i] == quote && quote == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++
+		case s[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+func (p *yamlParser) sequence(indent int) (*structNode, error) {
+	node := &structNode{kind: "array"}
+	for {
+		line, ok := p.peek()
+		if !ok || line.indent != indent || !isYAMLSequenceItem(line.text) {
+			return node, nil
+		}
+		rest := strings.TrimLeft(line.text[1:], " ")
+		var item *structNode
+		var err error
+		if rest == "" {
+			p.pos++
+			item, err = p.block(indent + 1)
+		} else {
+			// Re-read the item body as a line of its own, so "- key: value" starts a mapping
+			// indented at the column of "key".
+			p.lines[p.pos].indent = indent + len(line.text) - len(rest)
+			p.lines[p.pos].text = rest
+			item, err = p.block(indent + 1)
+		}
+		if err != nil {
+			return nil, err
+		}
+		node.children = append(node.children, item)
+	}
+}
+
+func (p *yamlParser) mapping(indent int) (*structNode, error) {
+	node := &structNode{kind: "object"}
+	for {
+		line, ok := p.peek()
+		if !ok || line.indent != indent {
+			return node, nil
+		}
+		split := yamlKeySplit(line.text)
+		if split < 0 {
+			return nil, p.errorf("expected a mapping key")
+		}
+		key, err := yamlScalarText(strings.TrimSpace(line.text[:split]))
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		rest := strings.TrimSpace(line.text[split+1:])
+		p.pos++
+
+		var value *structNode
+		if rest == "" {
+			next, ok := p.peek()
+			switch {
+			case ok && next.indent > indent:
+				value, err = p.block(indent + 1)
+			case ok && next.indent == indent && isYAMLSequenceItem(next.text):
+				value, err = p.sequence(indent)
+			default:
+				value = &structNode{kind: "null", scalar: "null"}
+			}
+		} else {
+			value, err = p.inline(rest, indent)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if key == "<<" && value.kind == "object" {
+			for i, k := range value.keys {
+				if !containsString(node.keys, k) {
+					node.keys = append(node.keys, k)
+					node.children = append(node.children, value.children[i])
+				}
+			}
+			continue
+		}
+		node.keys = append(node.keys, key)
+		node.children = append(node.children, value)
+	}
+}
+
+// inline parses a value that starts on the current line after a key or sequence dash. Lines
+// indented deeper than parentIndent may continue it.
+func (p *yamlParser) inline(text string, parentIndent int) (*structNode, error) {
+	var anchor string
+	for len(text) > 0 && (text[0] == '&' || text[0] == '!') {
+		end := strings.IndexAny(text, " \t")
+		if end < 0 {
+			end = len(text)
+		}
+		if text[0] == '&' {
+			anchor = text[1:end]
+		}
+		text = strings.TrimSpace(text[end:])
+	}
+
+	var node *structNode
+	var err error
+	switch {
+	case text == "":
+		node, err = p.block(parentIndent + 1)
+	case text[0] == '*':
+		if node, err = p.alias(text[1:]); err != nil {
+			return nil, p.errorf("%v", err)
+		}
+	case text[0] == '|' || text[0] == '>':
+		node = p.blockScalar(text, parentIndent)
+	case text[0] == '[' || text[0] == '{':
+		for !flowBalanced(text) {
+			line, ok := p.peek()
+			if !ok || line.indent <= parentIndent {
+				return nil, p.errorf("unterminated flow collection")
+			}
+			text += " " + line.text
+			p.pos++
+		}
+		f := &yamlFlow{s: text, parser: p}
+		node, err = f.value()
+		if err == nil && strings.TrimSpace(f.s[f.pos:]) != "" {
+			err = errors.New("unexpected content after flow collection")
+		}
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+	default:
+		for {
+			line, ok := p.peek()
+			if !ok || line.indent <= parentIndent || text[0] == '"' || text[0] == '\'' {
+				break
+			}
+			if yamlKeySplit(line.text) >= 0 {
+				return nil, p.errorf("mapping values are not allowed in a plain scalar")
+			}
+			text += " " + line.text
+			p.pos++
+		}
+		node, err = resolveYAMLScalar(text)
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if anchor != "" {
+		p.anchors[anchor] = node
+	}
+	return node, nil
+}
+
+// alias resolves an alias to the node anchored as name.
+func (p *yamlParser) alias(name string) (*structNode, error) {
+	target, ok := p.anchors[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown alias %q", name)
+	}
+	if err := p.aliases.expand(target); err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+// blockScalar reads a literal (|) or folded (>) scalar from the lines following the header.
+func (p *yamlParser) blockScalar(header string, parentIndent int) *structNode {
+	folded := header[0] == '>'
+	chomp := byte(0)
+	if strings.ContainsAny(header, "-+") {
+		chomp = header[strings.IndexAny(header, "-+")]
+	}
+
+	var lines []string
+	indent := -1
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		blank := strings.TrimSpace(line.raw) == ""
+		if !blank && line.indent <= parentIndent {
+			break
+		}
+		if !blank && indent < 0 {
+			indent = line.indent
+		}
+		if blank {
+			lines = append(lines, "")
+		} else {
+			lines = append(lines, line.raw[min(indent, line.indent):])
+		}
+		p.pos++
+	}
+
+	trailing := 0
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+		trailing++
+	}
+	var value string
+	if folded {
+		var b strings.Builder
+		for i, line := range lines {
+			switch {
+			case i == 0:
+			case line == "" || lines[i-1] == "" || strings.HasPrefix(line, " "):
+				b.WriteByte('\n')
+			default:
+				b.WriteByte(' ')
+			}
+			b.WriteString(line)
+		}
+		value = b.String()
+	} else {
+		value = strings.Join(lines, "\n")
+	}
+	switch chomp {
+	case '+':
+		value += strings.Repeat("\n", trailing+1)
+	case 0:
+		if len(lines) > 0 {
+			value += "\n"
+		}
+	}
+	return &structNode{kind: "string", scalar: value}
+}
+
+func flowBalanced(s string) bool {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		}
+	}
+	return depth <= 0
+}
+
+// yamlScalarText returns the string value of a quoted or plain scalar.
+func yamlScalarText(s string) (string, error) {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return strconv.Unquote(s)
+	}
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	}
+	return s, nil
+}
+
+// resolveYAMLScalar applies the YAML 1.2 core schema to a scalar: quoted scalars are strings,
+// plain scalars may be null, booleans or numbers.
+func resolveYAMLScalar(s string) (*structNode, error) {
+	if s != "" && (s[0] == '"' || s[0] == '\'') {
+		if closingQuote(s, s[0]) != len(s)-1 {
+			return nil, fmt.Errorf("malformed quoted scalar %s", s)
+		}
+		text, err := yamlScalarText(s)
+		if err != nil {
+			return nil, fmt.Errorf("malformed quoted scalar %s", s)
+		}
+		return &structNode{kind: "string", scalar: text}, nil
+	}
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return &structNode{kind: "null", scalar: "null"}, nil
+	case "true", "True", "TRUE":
+		return &structNode{kind: "boolean", scalar: "true"}, nil
+	case "false", "False", "FALSE":
+		return &structNode{kind: "boolean", scalar: "false"}, nil
+	}
+	switch {
+	case yamlIntPattern.MatchString(s):
+		return &structNode{kind: "integer", scalar: s}, nil
+	case yamlFloatPattern.MatchString(s):
+		return &structNode{kind: "number", scalar: s}, nil
+	}
+	return &structNode{kind: "string", scalar: s}, nil
+}
+
+// yamlFlow parses a flow collection such as "[a, {b: 1}]".
+type yamlFlow struct {
+	s   string
+	pos int
+	// parser holds the anchors that aliases in the collection refer to.
+	parser *yamlParser
+}
+
+// name reads an anchor or alias name, which ends at whitespace or a flow indicator.
+func (f *yamlFlow) name() string {
+	start := f.pos
+	for f.pos < len(f.s) && !strings.ContainsRune(" \t,[]{}", rune(f.s[f.pos])) {
+		f.pos++
+	}
+	return f.s[start:f.pos]
+}
+
+func (f *yamlFlow) skipSpace() {
+	for f.pos < len(f.s) && (f.s[f.pos] == ' ' || f.s[f.pos] == '\t') {
+		f.pos++
+	}
+}
+
+func (f *yamlFlow) value() (*structNode, error) {
+	f.skipSpace()
+	var anchor string
+	for f.pos < len(f.s) && (f.s[f.pos] == '&' || f.s[f.pos] == '!') {
+		indicator := f.s[f.pos]
+		f.pos++
+		if name := f.name(); indicator == '&' {
+			anchor = name
+		}
+		f.skipSpace()
+	}
+	node, err := f.node()
+	if err == nil && anchor != "" {
+		f.parser.anchors[anchor] = node
+	}
+	return node, err
+}
+
+func (f *yamlFlow) node() (*structNode, error) {
+	if f.pos >= len(f.s) {
+		return nil, errors.New("unexpected end of flow collection")
+	}
+	switch c := f.s[f.pos]; c {
+	case '*':
+		f.pos++
+		return f.parser.alias(f.name())
+	case '[', '{':
+		f.pos++
+		closing := byte(']')
+		node := &structNode{kind: "array"}
+		if c == '{' {
+			closing = '}'
+			node.kind = "object"
+		}
+		for {
+			f.skipSpace()
+			if f.pos < len(f.s) && f.s[f.pos] == closing {
+				f.pos++
+				return node, nil
+			}
+			if node.kind == "object" {
+				key, err := f.scalar(true)
+				if err != nil {
+					return nil, err
+				}
+				f.skipSpace()
+				value := &structNode{kind: "null", scalar: "null"}
+				if f.pos < len(f.s) && f.s[f.pos] == ':' {
+					f.pos++
+					if value, err = f.value(); err != nil {
+						return nil, err
+					}
+				}
+				node.keys = append(node.keys, key.scalar)
+				node.children = append(node.children, value)
+			} else {
+				item, err := f.value()
+				if err != nil {
+					return nil, err
+				}
+				node.children = append(node.children, item)
+			}
+			f.skipSpace()
+			switch {
+			case f.pos < len(f.s) && f.s[f.pos] == ',':
+				f.pos++
+			case f.pos < len(f.s) && f.s[f.pos] == closing:
+			default:
+				return nil, errors.New("expected ',' in flow collection")
+			}
+		}
+	}
+	return f.scalar(false)
+}
+
+// scalar reads a flow scalar; keys end at ':' as well as at ',', ']' and '}'.
+func (f *yamlFlow) scalar(key bool) (*structNode, error) {
+	start := f.pos
+	if c := f.s[f.pos]; c == '"' || c == '\'' {
+		end := closingQuote(f.s[start:], c)
+		if end < 0 {
+			return nil, errors.New("unterminated quoted scalar")
+		}
+		f.pos = start + end + 1
+		return resolveYAMLScalar(f.s[start:f.pos])
+	}
+	for f.pos < len(f.s) && !strings.ContainsRune(",]}", rune(f.s[f.pos])) {
+		if key && f.s[f.pos] == ':' {
+			break
+		}
+		if f.s[f.pos] == ':' && (f.pos+1 == len(f.s) || strings.ContainsRune(" ,]}", rune(f.s[f.pos+1]))) {
+			break
+		}
+		f.pos++
+	}
+	node, err := resolveYAMLScalar(strings.TrimSpace(f.s[start:f.pos]))
+	if err == nil && key {
+		node = &structNode{kind: "string", scalar: node.scalar}
+	}
+	return node, err
+}
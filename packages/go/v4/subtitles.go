@@ -0,0 +1,216 @@
+package kreuzberg
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	mimeSRT = "application/x-subrip"
+	mimeVTT = "text/vtt"
+)
+
+// SubtitleCue is a timed cue of an SRT or WebVTT file, as reported in Metadata.Additional["cues"].
+type SubtitleCue struct {
+	// Index is the 1-based position of the cue in the file.
+	Index int `json:"index"`
+	// Identifier is the SRT sequence number or WebVTT cue identifier, when present.
+	Identifier string `json:"identifier,omitempty"`
+	// Start and End are the playback range as "HH:MM:SS.mmm".
+	Start   string `json:"start"`
+	End     string `json:"end"`
+	StartMs int64  `json:"start_ms"`
+	EndMs   int64  `json:"end_ms"`
+	// Speaker is the WebVTT voice (<v Name>) of the cue, when present.
+	Speaker string `json:"speaker,omitempty"`
+	// Text is the cue text with markup removed.
+	Text string `json:"text"`
+}
+
+var (
+	subtitleTimingPattern = regexp.MustCompile(`^((?:\d+:)?\d{1,2}:\d{2}[.,]\d{1,3})\s+-->\s+((?:\d+:)?\d{1,2}:\d{2}[.,]\d{1,3})`)
+	subtitleVoicePattern  = regexp.MustCompile(`<v(?:\.[^\s>]*)?\s+([^>]+)>`)
+	subtitleTagPattern    = regexp.MustCompile(`</?[^>]+>|\{\\[^}]*\}`)
+	subtitleBlankLine     = regexp.MustCompile(`\n[ \t]*\n`)
+)
+
+// parseSubtitleTime parses "HH:MM:SS,mmm", "HH:MM:SS.mmm" or "MM:SS.mmm" into milliseconds.
+func parseSubtitleTime(value string) (int64, error) {
+	value = strings.Replace(value, ",", ".", 1)
+	clock, fraction, _ := strings.Cut(value, ".")
+	parts := strings.Split(clock, ":")
+	var ms int64
+	for _, part := range parts {
+		n, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		ms = ms*60 + n
+	}
+	ms *= 1000
+	if fraction != "" {
+		n, err := strconv.ParseInt((fraction + "00")[:3], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		ms += n
+	}
+	return ms, nil
+}
+
+func formatSubtitleTime(ms int64) string {
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+// cleanSubtitleText removes SRT/WebVTT markup and decodes entities.
+func cleanSubtitleText(lines []string) string {
+	text := subtitleTagPattern.ReplaceAllString(strings.Join(lines, "\n"), "")
+	return strings.TrimSpace(html.UnescapeString(text))
+}
+
+// parseSubtitles parses SRT or WebVTT text into cues. For WebVTT it also returns the header
+// settings (e.g. "Kind", "Language").
+func parseSubtitles(text string, vtt bool) ([]SubtitleCue, map[string]string, error) {
+	text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\r", "\n")
+	blocks := subtitleBlankLine.Split(strings.TrimSpace(text), -1)
+
+	header := map[string]string{}
+	if vtt {
+		if len(blocks) == 0 || !strings.HasPrefix(blocks[0], "WEBVTT") {
+			return nil, nil, newParsingErrorWithContext("WebVTT file must start with WEBVTT", nil, ErrorCodeParsing, nil)
+		}
+		for _, line := range strings.Split(blocks[0], "\n")[1:] {
+			if key, value, ok := strings.Cut(line, ":"); ok {
+				header[strings.TrimSpace(key)] = strings.TrimSpace(value)
+			}
+		}
+		blocks = blocks[1:]
+	}
+
+	var cues []SubtitleCue
+	for _, block := range blocks {
+		lines := strings.Split(block, "\n")
+		if vtt && (strings.HasPrefix(lines[0], "NOTE") || lines[0] == "STYLE" || lines[0] == "REGION") {
+			continue
+		}
+		timing := 0
+		for timing < len(lines) && !subtitleTimingPattern.MatchString(lines[timing]) {
+			timing++
+		}
+		if timing == len(lines) || timing > 1 {
+			// Not a cue; SRT files in the wild contain stray text blocks, so skip rather than fail.
+			continue
+		}
+		m := subtitleTimingPattern.FindStringSubmatch(lines[timing])
+		start, err := parseSubtitleTime(m[1])
+		if err != nil {
+			return nil, nil, newParsingErrorWithContext(fmt.Sprintf("invalid cue timestamp %q", m[1]), err, ErrorCodeParsing, nil)
+		}
+		end, err := parseSubtitleTime(m[2])
+		if err != nil {
+			return nil, nil, newParsingErrorWithContext(fmt.Sprintf("invalid cue timestamp %q", m[2]), err, ErrorCodeParsing, nil)
+		}
+
+		cue := SubtitleCue{
+			Index:   len(cues) + 1,
+			Start:   formatSubtitleTime(start),
+			End:     formatSubtitleTime(end),
+			StartMs: start,
+			EndMs:   end,
+		}
+		if timing == 1 {
+			cue.Identifier = strings.TrimSpace(lines[0])
+		}
+		body := lines[timing+1:]
+		if vm := subtitleVoicePattern.FindStringSubmatch(strings.Join(body, "\n")); vm != nil {
+			cue.Speaker = strings.TrimSpace(vm[1])
+		}
+		cue.Text = cleanSubtitleText(body)
+		cues = append(cues, cue)
+	}
+	return cues, header, nil
+}
+
+func extractSubtitles(src documentSource, mimeType string, config *ExtractionConfig) (*ExtractionResult, error) {
+	data, err := src.bytes()
+	if err != nil {
+		return nil, err
+	}
+	text, _, err := decodeText(data, "")
+	if err != nil {
+		return nil, err
+	}
+	vtt := mimeType == mimeVTT || strings.HasPrefix(text, "WEBVTT")
+	cues, header, err := parseSubtitles(text, vtt)
+	if err != nil {
+		return nil, err
+	}
+
+	var content strings.Builder
+	spans := make([][2]int, len(cues))
+	var duration int64
+	for i, cue := range cues {
+		if i > 0 {
+			content.WriteString("\n\n")
+		}
+		spans[i] = [2]int{content.Len(), content.Len() + len(cue.Text)}
+		content.WriteString(cue.Text)
+		duration = max(duration, cue.EndMs)
+	}
+
+	result := &ExtractionResult{MimeType: mimeType, Content: content.String(), Tables: []Table{}, Success: true}
+	if maxChars := chunkingMaxChars(config, defaultLogChunkChars); maxChars > 0 {
+		result.Chunks = chunkOnBoundaries(result.Content, spans, maxChars)
+		cue := 0
+		for i := range result.Chunks {
+			meta := &result.Chunks[i].Metadata
+			for cue < len(cues) && spans[cue][0] < int(meta.ByteStart) {
+				cue++
+			}
+			first := cue
+			for cue < len(cues) && spans[cue][1] <= int(meta.ByteEnd) {
+				cue++
+			}
+			if cue > first {
+				start, end := cues[first].StartMs, cues[cue-1].EndMs
+				meta.StartTimeMs, meta.EndTimeMs = &start, &end
+			}
+		}
+	}
+
+	format := "srt"
+	if vtt {
+		format = "vtt"
+	}
+	additional := map[string]any{
+		"subtitle_format": format,
+		"cues":            cues,
+		"cue_count":       len(cues),
+		"duration_ms":     duration,
+	}
+	if len(header) > 0 {
+		additional["subtitle_header"] = header
+	}
+	result.Metadata.Additional = make(map[string]json.RawMessage, len(additional))
+	for key, value := range additional {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, newSerializationErrorWithContext("failed to encode subtitle metadata", err, ErrorCodeValidation, nil)
+		}
+		result.Metadata.Additional[key] = raw
+	}
+	return result, nil
+}
+
+func init() {
+	registerGoPrimaryExtractor(goPrimaryExtractor{
+		name:       "subtitles",
+		mimeTypes:  []string{mimeSRT, "text/srt", "application/srt", mimeVTT},
+		extensions: map[string]string{"srt": mimeSRT, "vtt": mimeVTT},
+		extract:    extractSubtitles,
+	})
+}
@@ -0,0 +1,92 @@
+package kreuzberg
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const testSRT = "1\r\n00:00:01,600 --> 00:00:04,200\r\n<i>Hello</i> there.\r\n\r\n2\r\n00:00:05,000 --> 00:00:07,250 X1:40 X2:600\r\n{\\an8}General Kenobi!\r\nYou are a bold one.\r\n\r\n"
+
+const testVTT = `WEBVTT
+Kind: captions
+Language: en
+
+NOTE This is a comment
+spanning two lines
+
+intro
+01:02.500 --> 01:04.000 align:start
+<v Roger Bingham>We are in New York City &amp; it's cold
+
+01:05.000 --> 01:07.000
+<c.loud>Really</c> cold
+`
+
+func TestParseSRT(t *testing.T) {
+	cues, _, err := parseSubtitles(testSRT, false)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(cues) != 2 {
+		t.Fatalf("unexpected cues: %+v", cues)
+	}
+	want := SubtitleCue{Index: 2, Identifier: "2", Start: "00:00:05.000", End: "00:00:07.250", StartMs: 5000, EndMs: 7250, Text: "General Kenobi!\nYou are a bold one."}
+	if cues[1] != want {
+		t.Fatalf("cue = %+v, want %+v", cues[1], want)
+	}
+	if cues[0].Text != "Hello there." || cues[0].StartMs != 1600 {
+		t.Fatalf("unexpected first cue: %+v", cues[0])
+	}
+}
+
+func TestParseVTT(t *testing.T) {
+	cues, header, err := parseSubtitles(testVTT, true)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if header["Kind"] != "captions" || header["Language"] != "en" {
+		t.Fatalf("unexpected header: %v", header)
+	}
+	if len(cues) != 2 {
+		t.Fatalf("unexpected cues: %+v", cues)
+	}
+	first := cues[0]
+	if first.Identifier != "intro" || first.StartMs != 62500 || first.Speaker != "Roger Bingham" || first.Text != "We are in New York City & it's cold" {
+		t.Fatalf("unexpected first cue: %+v", first)
+	}
+	if cues[1].Text != "Really cold" || cues[1].Identifier != "" {
+		t.Fatalf("unexpected second cue: %+v", cues[1])
+	}
+	if _, _, err := parseSubtitles("00:01.000 --> 00:02.000\nhi", true); err == nil {
+		t.Fatalf("expected error for missing WEBVTT header")
+	}
+}
+
+func TestExtractSubtitlesChunksOnCues(t *testing.T) {
+	extractor, mimeType := selectGoPrimaryExtractor(documentSource{path: "movie.srt"}, nil)
+	if extractor == nil || extractor.name != "subtitles" || mimeType != mimeSRT {
+		t.Fatalf("unexpected routing: %v %q", extractor, mimeType)
+	}
+	config := &ExtractionConfig{Chunking: &ChunkingConfig{MaxChars: IntPtr(20)}}
+	result, err := extractor.extract(documentSource{data: []byte(testSRT)}, mimeType, config)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if result.Content != "Hello there.\n\nGeneral Kenobi!\nYou are a bold one." {
+		t.Fatalf("unexpected content: %q", result.Content)
+	}
+	if len(result.Chunks) != 2 {
+		t.Fatalf("expected one chunk per cue, got %+v", result.Chunks)
+	}
+	second := result.Chunks[1]
+	if second.Content != "General Kenobi!\nYou are a bold one." || *second.Metadata.StartTimeMs != 5000 || *second.Metadata.EndTimeMs != 7250 {
+		t.Fatalf("unexpected chunk: %+v", second)
+	}
+	if string(result.Metadata.Additional["duration_ms"]) != "7250" || string(result.Metadata.Additional["subtitle_format"]) != `"srt"` {
+		t.Fatalf("unexpected metadata: %v", result.Metadata.Additional)
+	}
+	var cues []SubtitleCue
+	if err := json.Unmarshal(result.Metadata.Additional["cues"], &cues); err != nil || len(cues) != 2 {
+		t.Fatalf("unexpected cues: %s", result.Metadata.Additional["cues"])
+	}
+}
@@ -0,0 +1,472 @@
+package kreuzberg
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/kreuzberg-dev/kreuzberg/packages/go/v4/nativeloader"
+)
+
+// supervisorListenFDEnv names the file descriptor of the listener a Supervisor shares with its
+// workers. RunService serves it when set.
+const supervisorListenFDEnv = "KREUZBERG_LISTEN_FD"
+
+const (
+	defaultSupervisorStartTimeout = time.Minute
+	defaultSupervisorDrainTimeout = time.Minute
+	supervisorRestartDelay        = time.Second
+)
+
+// SupervisorOptions configures a Supervisor.
+type SupervisorOptions struct {
+	// Command returns the command of a new worker process, e.g. the service binary running
+	// RunService or RunWorker. It is called for every worker start; the supervisor adds the
+	// native library path, the readiness socket and the shared listener to its environment.
+	Command func() *exec.Cmd
+	// Workers is the number of worker processes (default 1).
+	Workers int
+	// LibraryDir is the directory holding the native library the workers load (see
+	// nativeloader.Bundle.Install). Empty leaves the library path of the environment as is.
+	LibraryDir string
+	// Listener, when set, is shared with every worker, so that old and new workers accept
+	// connections on the same address while they are rolled. RunService serves it
+	// automatically.
+	Listener net.Listener
+	// StartTimeout bounds how long a new worker may take to report readiness (default 1 minute).
+	StartTimeout time.Duration
+	// DrainTimeout bounds how long a stopping worker may take to finish its in-flight work
+	// before it is killed (default 1 minute). Keep it above the service's ShutdownTimeout.
+	DrainTimeout time.Duration
+}
+
+// SupervisorStatus describes the workers of a Supervisor.
+type SupervisorStatus struct {
+	// LibraryDir is the native library directory new workers start with.
+	LibraryDir string             `json:"library_dir"`
+	Workers    []SupervisedWorker `json:"workers"`
+}
+
+// SupervisedWorker is a worker process of a Supervisor.
+type SupervisedWorker struct {
+	PID        int       `json:"pid"`
+	LibraryDir string    `json:"library_dir"`
+	Ready      bool      `json:"ready"`
+	Started    time.Time `json:"started"`
+}
+
+// Supervisor runs extraction workers as child processes and rolls them onto a new native library
+// without dropping requests. Because the native library is linked when a process starts, an
+// upgrade starts a worker on the new library, waits until it reports readiness, and only then
+// stops an old worker gracefully, one worker at a time:
+//
+//	sup := kreuzberg.NewSupervisor(kreuzberg.SupervisorOptions{
+//		Command:    func() *exec.Cmd { return exec.Command("/usr/bin/extract-server") },
+//		Workers:    4,
+//		LibraryDir: "/var/lib/kreuzberg/4.2.0",
+//		Listener:   listener,
+//	})
+//	go sup.Run(ctx)
+//	...
+//	err := sup.Upgrade(ctx, "/var/lib/kreuzberg/4.3.0")
+//
+// Workers report readiness by sending READY=1 to NOTIFY_SOCKET, as RunService does, and RunWorker
+// once it reaches its coordinator. They are stopped with SIGTERM, on which RunService finishes
+// the requests in flight; coordinator workers drain through WorkerOptions.Drain. Workers that
+// exit on their own are restarted. Under systemd the supervisor itself reports READY=1 once its
+// first workers are ready.
+//
+// Readiness is reported over Unix datagram sockets, so the Supervisor needs a Unix-like OS.
+type Supervisor struct {
+	opts      SupervisorOptions
+	listen    *os.File
+	upgradeMu sync.Mutex
+
+	mu      sync.Mutex
+	libDir  string
+	workers []*supervisedProcess
+	started bool
+	running bool
+	exits   chan *supervisedProcess
+	stopped chan struct{}
+}
+
+type supervisedProcess struct {
+	cmd     *exec.Cmd
+	libDir  string
+	started time.Time
+	ready   chan struct{}
+	done    chan struct{}
+	err     error
+
+	mu       sync.Mutex
+	isReady  bool
+	draining bool
+}
+
+// NewSupervisor returns a Supervisor; start its workers with Run, which may be called once.
+func NewSupervisor(opts SupervisorOptions) *Supervisor {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.StartTimeout <= 0 {
+		opts.StartTimeout = defaultSupervisorStartTimeout
+	}
+	if opts.DrainTimeout <= 0 {
+		opts.DrainTimeout = defaultSupervisorDrainTimeout
+	}
+	return &Supervisor{
+		opts:    opts,
+		libDir:  opts.LibraryDir,
+		exits:   make(chan *supervisedProcess, opts.Workers),
+		stopped: make(chan struct{}),
+	}
+}
+
+// Run starts the workers, waits until they are ready, and then keeps them running until ctx is
+// done, when it stops them gracefully and returns nil. It fails when the first workers do not
+// become ready.
+func (s *Supervisor) Run(ctx context.Context) error {
+	if s.opts.Command == nil {
+		return newValidationErrorWithContext("SupervisorOptions.Command is required", nil, ErrorCodeValidation, nil)
+	}
+	s.mu.Lock()
+	if s.started {
+		s.mu.Unlock()
+		return newValidationErrorWithContext("supervisor has already been run", nil, ErrorCodeValidation, nil)
+	}
+	s.started, s.running = true, true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+		close(s.stopped)
+	}()
+
+	if s.opts.Listener != nil {
+		filer, ok := s.opts.Listener.(interface{ File() (*os.File, error) })
+		if !ok {
+			return newValidationErrorWithContext(fmt.Sprintf("listener %T cannot be shared with worker processes", s.opts.Listener), nil, ErrorCodeValidation, nil)
+		}
+		file, err := filer.File()
+		if err != nil {
+			return newIOErrorWithContext("failed to share the listener with worker processes", err, ErrorCodeIo, nil)
+		}
+		defer file.Close()
+		s.listen = file
+	}
+
+	defer s.stopAll()
+	for range s.opts.Workers {
+		p, err := s.start(s.libDir)
+		if err != nil {
+			return err
+		}
+		s.mu.Lock()
+		s.workers = append(s.workers, p)
+		s.mu.Unlock()
+	}
+	for _, p := range s.snapshot() {
+		if err := s.awaitReady(ctx, p); err != nil {
+			return err
+		}
+	}
+	sdNotify("READY=1")
+
+	for {
+		select {
+		case <-ctx.Done():
+			sdNotify("STOPPING=1")
+			return nil
+		case exited := <-s.exits:
+			select {
+			case <-ctx.Done():
+				continue
+			case <-time.After(supervisorRestartDelay):
+			}
+			s.restart(exited)
+		}
+	}
+}
+
+// Upgrade rolls every worker onto the native library in libDir, one at a time: it starts a
+// replacement, waits until it is ready and then drains the worker it replaces. When the first
+// replacement fails to become ready it is stopped and all workers stay on the old library; a
+// later failure leaves the remaining workers on the old library, and Upgrade can be called
+// again. Workers restarted after a successful first replacement use libDir.
+func (s *Supervisor) Upgrade(ctx context.Context, libDir string) error {
+	s.upgradeMu.Lock()
+	defer s.upgradeMu.Unlock()
+	s.mu.Lock()
+	running := s.running
+	s.mu.Unlock()
+	if !running {
+		return newValidationErrorWithContext("supervisor is not running", nil, ErrorCodeValidation, nil)
+	}
+
+	switched := false
+	for {
+		old := s.outdated(libDir)
+		if old == nil {
+			return nil
+		}
+		next, err := s.start(libDir)
+		if err != nil {
+			return err
+		}
+		if err := s.awaitReady(ctx, next); err != nil {
+			s.stop(next)
+			return err
+		}
+		s.mu.Lock()
+		replaced := s.replace(old, next)
+		if replaced && !switched {
+			s.libDir, switched = libDir, true
+		}
+		s.mu.Unlock()
+		if !replaced {
+			// old exited meanwhile and was restarted in its place; roll that one instead.
+			s.stop(next)
+			continue
+		}
+		s.stop(old)
+	}
+}
+
+// Status reports the current workers.
+func (s *Supervisor) Status() SupervisorStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := SupervisorStatus{LibraryDir: s.libDir, Workers: make([]SupervisedWorker, 0, len(s.workers))}
+	for _, p := range s.workers {
+		p.mu.Lock()
+		status.Workers = append(status.Workers, SupervisedWorker{PID: p.cmd.Process.Pid, LibraryDir: p.libDir, Ready: p.isReady, Started: p.started})
+		p.mu.Unlock()
+	}
+	return status
+}
+
+func (s *Supervisor) snapshot() []*supervisedProcess {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*supervisedProcess(nil), s.workers...)
+}
+
+// replace swaps old for next in the worker list; it reports false when old is no longer listed.
+func (s *Supervisor) replace(old, next *supervisedProcess) bool {
+	for i, p := range s.workers {
+		if p == old {
+			s.workers[i] = next
+			return true
+		}
+	}
+	return false
+}
+
+// outdated returns the oldest worker not running on libDir.
+func (s *Supervisor) outdated(libDir string) *supervisedProcess {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range s.workers {
+		if p.libDir != libDir {
+			return p
+		}
+	}
+	return nil
+}
+
+// restart replaces a worker that exited on its own.
+func (s *Supervisor) restart(exited *supervisedProcess) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := -1
+	for j, p := range s.workers {
+		if p == exited {
+			i = j
+		}
+	}
+	if i < 0 {
+		return
+	}
+	next, err := s.start(s.libDir)
+	if err != nil {
+		// Try again on the next exit check.
+		go s.notifyExit(exited)
+		return
+	}
+	s.workers[i] = next
+}
+
+func (s *Supervisor) notifyExit(p *supervisedProcess) {
+	select {
+	case s.exits <- p:
+	case <-s.stopped:
+	}
+}
+
+// start launches a worker with libDir on the native library path.
+func (s *Supervisor) start(libDir string) (*supervisedProcess, error) {
+	dir, err := os.MkdirTemp("", "kreuzberg-notify-")
+	if err != nil {
+		return nil, newIOErrorWithContext("failed to create worker notify socket", err, ErrorCodeIo, nil)
+	}
+	socketPath := filepath.Join(dir, "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, newIOErrorWithContext("failed to create worker notify socket", err, ErrorCodeIo, nil)
+	}
+
+	cmd := s.opts.Command()
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	env = withoutEnv(env, "LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES", "WATCHDOG_PID", "WATCHDOG_USEC")
+	if libDir != "" {
+		key, value := nativeloader.Env(libDir)
+		env = append(env, key+"="+value)
+	}
+	env = append(env, "NOTIFY_SOCKET="+socketPath)
+	if s.listen != nil {
+		cmd.ExtraFiles = append(cmd.ExtraFiles, s.listen)
+		env = append(env, fmt.Sprintf("%s=%d", supervisorListenFDEnv, 2+len(cmd.ExtraFiles)))
+	}
+	cmd.Env = env
+
+	p := &supervisedProcess{cmd: cmd, libDir: libDir, started: time.Now(), ready: make(chan struct{}), done: make(chan struct{})}
+	if err := cmd.Start(); err != nil {
+		conn.Close()
+		os.RemoveAll(dir)
+		return nil, newIOErrorWithContext("failed to start worker process", err, ErrorCodeIo, nil)
+	}
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			n, _, err := conn.ReadFromUnix(buf)
+			if err != nil {
+				return
+			}
+			for _, line := range strings.Split(string(buf[:n]), "\n") {
+				if line == "READY=1" {
+					p.mu.Lock()
+					if !p.isReady {
+						p.isReady = true
+						close(p.ready)
+					}
+					p.mu.Unlock()
+				}
+			}
+		}
+	}()
+	go func() {
+		p.err = cmd.Wait()
+		conn.Close()
+		os.RemoveAll(dir)
+		close(p.done)
+		p.mu.Lock()
+		draining := p.draining
+		p.mu.Unlock()
+		if !draining {
+			s.notifyExit(p)
+		}
+	}()
+	return p, nil
+}
+
+// awaitReady waits until p reports readiness.
+func (s *Supervisor) awaitReady(ctx context.Context, p *supervisedProcess) error {
+	timer := time.NewTimer(s.opts.StartTimeout)
+	defer timer.Stop()
+	select {
+	case <-p.ready:
+		return nil
+	case <-p.done:
+		return newRuntimeErrorWithContext(fmt.Sprintf("worker process %d exited before it was ready", p.cmd.Process.Pid), p.err, ErrorCodeInternal, nil)
+	case <-timer.C:
+		return newRuntimeErrorWithContext(fmt.Sprintf("worker process %d was not ready after %s", p.cmd.Process.Pid, s.opts.StartTimeout), nil, ErrorCodeInternal, nil)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// stop asks p to finish its work and exit, killing it after the drain timeout.
+func (s *Supervisor) stop(p *supervisedProcess) {
+	p.mu.Lock()
+	p.draining = true
+	p.mu.Unlock()
+	if err := p.cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		p.cmd.Process.Kill()
+	}
+	timer := time.NewTimer(s.opts.DrainTimeout)
+	defer timer.Stop()
+	select {
+	case <-p.done:
+	case <-timer.C:
+		p.cmd.Process.Kill()
+		<-p.done
+	}
+}
+
+func (s *Supervisor) stopAll() {
+	s.mu.Lock()
+	workers := s.workers
+	s.workers = nil
+	s.mu.Unlock()
+	var wg sync.WaitGroup
+	for _, p := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.stop(p)
+		}()
+	}
+	wg.Wait()
+}
+
+// withoutEnv drops the variables named keys from env.
+func withoutEnv(env []string, keys ...string) []string {
+	kept := env[:0:0]
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		drop := false
+		for _, key := range keys {
+			if name == key {
+				drop = true
+			}
+		}
+		if !drop {
+			kept = append(kept, kv)
+		}
+	}
+	return kept
+}
+
+// supervisorListener returns the listener shared by a Supervisor, if the process is one of its
+// workers.
+func supervisorListener() (net.Listener, error) {
+	value := os.Getenv(supervisorListenFDEnv)
+	if value == "" {
+		return nil, nil
+	}
+	fd, err := strconv.Atoi(value)
+	if err != nil || fd < 3 {
+		return nil, newValidationErrorWithContext(fmt.Sprintf("invalid %s %q", supervisorListenFDEnv, value), err, ErrorCodeValidation, nil)
+	}
+	file := os.NewFile(uintptr(fd), "supervisor-listener")
+	defer file.Close()
+	listener, err := net.FileListener(file)
+	if err != nil {
+		return nil, newIOErrorWithContext("failed to use the listener shared by the supervisor", err, ErrorCodeIo, nil)
+	}
+	return listener, nil
+}
@@ -0,0 +1,172 @@
+package kreuzberg
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kreuzberg-dev/kreuzberg/packages/go/v4/nativeloader"
+)
+
+// TestSupervisorHelperProcess is the worker started by TestSupervisorRollsWorkers. It serves the
+// native library directory it was started with.
+func TestSupervisorHelperProcess(t *testing.T) {
+	if os.Getenv("KREUZBERG_SUPERVISOR_HELPER") != "1" {
+		t.Skip("helper process")
+	}
+	key, _ := nativeloader.Env("")
+	libDir, _, _ := strings.Cut(os.Getenv(key), string(os.PathListSeparator))
+	if libDir == "/broken" {
+		os.Exit(1)
+	}
+	err := RunService(context.Background(), ServiceOptions{Handler: func(*ExtractionConfig) (http.Handler, error) {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/slow" {
+				time.Sleep(500 * time.Millisecond)
+			}
+			io.WriteString(w, libDir)
+		}), nil
+	}})
+	if err != nil {
+		os.Exit(2)
+	}
+	os.Exit(0)
+}
+
+func TestSupervisorRollsWorkers(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the supervisor needs Unix datagram sockets")
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+	sup := NewSupervisor(SupervisorOptions{
+		Command: func() *exec.Cmd {
+			cmd := exec.Command(os.Args[0], "-test.run=^TestSupervisorHelperProcess$")
+			cmd.Env = append(os.Environ(), "KREUZBERG_SUPERVISOR_HELPER=1")
+			return cmd
+		},
+		Workers:      2,
+		LibraryDir:   "/opt/kreuzberg/4.0",
+		Listener:     listener,
+		StartTimeout: 30 * time.Second,
+		DrainTimeout: 30 * time.Second,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stopped := make(chan error, 1)
+	go func() { stopped <- sup.Run(ctx) }()
+
+	waitReady := func() SupervisorStatus {
+		t.Helper()
+		for deadline := time.Now().Add(30 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+			status := sup.Status()
+			ready := len(status.Workers) == 2
+			for _, w := range status.Workers {
+				ready = ready && w.Ready
+			}
+			if ready {
+				return status
+			}
+		}
+		t.Fatalf("workers did not become ready: %+v", sup.Status())
+		return SupervisorStatus{}
+	}
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}, Timeout: 10 * time.Second}
+	get := func(path string) (string, error) {
+		resp, err := client.Get("http://" + listener.Addr().String() + path)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	before := waitReady()
+	if body, err := get("/"); err != nil || body != "/opt/kreuzberg/4.0" {
+		t.Fatalf("unexpected response %q, %v", body, err)
+	}
+
+	// Requests in flight and arriving during the upgrade all succeed.
+	slow := make(chan string, 1)
+	go func() {
+		body, err := get("/slow")
+		if err != nil {
+			body = err.Error()
+		}
+		slow <- body
+	}()
+	var failures, served atomic.Int64
+	trafficDone := make(chan struct{})
+	stopTraffic := make(chan struct{})
+	go func() {
+		defer close(trafficDone)
+		for {
+			select {
+			case <-stopTraffic:
+				return
+			default:
+			}
+			if _, err := get("/"); err != nil {
+				t.Log(err)
+				failures.Add(1)
+			}
+			served.Add(1)
+		}
+	}()
+	time.Sleep(50 * time.Millisecond)
+	if err := sup.Upgrade(ctx, "/opt/kreuzberg/4.1"); err != nil {
+		t.Fatalf("upgrade: %v", err)
+	}
+	close(stopTraffic)
+	<-trafficDone
+	if failures.Load() != 0 || served.Load() == 0 {
+		t.Fatalf("expected no failed requests, got %d of %d", failures.Load(), served.Load())
+	}
+	if body := <-slow; body != "/opt/kreuzberg/4.0" {
+		t.Fatalf("expected the in-flight request to finish on the old worker, got %q", body)
+	}
+
+	after := waitReady()
+	if after.LibraryDir != "/opt/kreuzberg/4.1" {
+		t.Fatalf("unexpected library dir %q", after.LibraryDir)
+	}
+	for i, w := range after.Workers {
+		if w.LibraryDir != "/opt/kreuzberg/4.1" || w.PID == before.Workers[i].PID {
+			t.Fatalf("worker %d was not rolled: %+v", i, w)
+		}
+	}
+	if body, err := get("/"); err != nil || body != "/opt/kreuzberg/4.1" {
+		t.Fatalf("unexpected response %q, %v", body, err)
+	}
+
+	// A library the workers cannot start on leaves the running workers in place.
+	if err := sup.Upgrade(ctx, "/broken"); err == nil {
+		t.Fatalf("expected the upgrade to fail")
+	}
+	if status := sup.Status(); status.LibraryDir != "/opt/kreuzberg/4.1" || status.Workers[0].PID != after.Workers[0].PID || status.Workers[1].PID != after.Workers[1].PID {
+		t.Fatalf("expected the workers to be kept, got %+v", status)
+	}
+
+	cancel()
+	if err := <-stopped; err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if status := sup.Status(); len(status.Workers) != 0 {
+		t.Fatalf("expected the workers to be stopped, got %+v", status)
+	}
+	if err := sup.Upgrade(context.Background(), "/opt/kreuzberg/4.2"); err == nil {
+		t.Fatalf("expected an upgrade of a stopped supervisor to fail")
+	}
+}
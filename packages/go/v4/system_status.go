@@ -0,0 +1,36 @@
+package kreuzberg
+
+import (
+	"crypto/fips140"
+	"runtime"
+)
+
+// SystemInfo describes the runtime environment of the binding, e.g. for health checks and
+// support bundles.
+type SystemInfo struct {
+	// LibraryVersion is the version of the native Kreuzberg library.
+	LibraryVersion string `json:"library_version"`
+	// GoVersion is the Go release the binary was built with.
+	GoVersion string `json:"go_version"`
+	// CryptoProfile is the active crypto profile (see ActiveCryptoProfile).
+	CryptoProfile CryptoProfile `json:"crypto_profile"`
+	// FIPS140Mode reports whether Go's FIPS 140-3 mode is enabled.
+	FIPS140Mode bool `json:"fips140_mode"`
+	// GoExtractors lists the built-in Go extractors, in routing order.
+	GoExtractors []string `json:"go_extractors"`
+}
+
+// SystemStatus reports the binding's runtime environment.
+func SystemStatus() SystemInfo {
+	info := SystemInfo{
+		LibraryVersion: LibraryVersion(),
+		GoVersion:      runtime.Version(),
+		CryptoProfile:  ActiveCryptoProfile(),
+		FIPS140Mode:    fips140.Enabled(),
+		GoExtractors:   make([]string, 0, len(goPrimaryExtractors)),
+	}
+	for _, extractor := range goPrimaryExtractors {
+		info.GoExtractors = append(info.GoExtractors, extractor.name)
+	}
+	return info
+}
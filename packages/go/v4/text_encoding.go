@@ -0,0 +1,297 @@
+package kreuzberg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// windows1252High maps bytes 0x80-0x9F of Windows-1252; the rest of the code page matches Latin-1.
+var windows1252High = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8D, 'Ž', 0x8F,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9D, 'ž', 'Ÿ',
+}
+
+// iso885915 lists the code points where ISO-8859-15 (Latin-9) differs from Latin-1.
+var iso885915 = map[byte]rune{0xA4: '€', 0xA6: 'Š', 0xA8: 'š', 0xB4: 'Ž', 0xB8: 'ž', 0xBC: 'Œ', 0xBD: 'œ', 0xBE: 'Ÿ'}
+
+// cp437High and cp850High map bytes 0x80-0xFF of the DOS code pages still found in exports
+// from legacy ERP and accounting systems; the lower half matches ASCII.
+var (
+	cp437High = []rune("ÇüéâäàåçêëèïîìÄÅÉæÆôöòûùÿÖÜ¢£¥₧ƒáíóúñÑªº¿⌐¬½¼¡«»░▒▓│┤╡╢╖╕╣║╗╝╜╛┐" +
+		"└┴┬├─┼╞╟╚╔╩╦╠═╬╧╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀αßΓπΣσµτΦΘΩδ∞φε∩≡±≥≤⌠⌡÷≈°∙·√ⁿ²■\u00A0")
+	cp850High = []rune("ÇüéâäàåçêëèïîìÄÅÉæÆôöòûùÿÖÜø£Ø×ƒáíóúñÑªº¿®¬½¼¡«»░▒▓│┤ÁÂÀ©╣║╗╝¢¥┐" +
+		"└┴┬├─┼ãÃ╚╔╩╦╠═╬¤ðÐÊËÈıÍÎÏ┘┌█▄¦Ì▀ÓßÔÒõÕµþÞÚÛÙýÝ¯´\u00AD±‗¾¶§÷¸°¨·¹³²■\u00A0")
+)
+
+// charsetAliases maps accepted encoding names, lowercased with "_" replaced by "-", to the
+// canonical names reported in metadata.
+var charsetAliases = map[string]string{
+	"utf-8": "utf-8", "utf8": "utf-8",
+	"utf-16le": "utf-16le", "utf-16": "utf-16le",
+	"utf-16be":   "utf-16be",
+	"iso-8859-1": "iso-8859-1", "latin-1": "iso-8859-1", "latin1": "iso-8859-1",
+	"iso-8859-15": "iso-8859-15", "latin-9": "iso-8859-15", "latin9": "iso-8859-15",
+	"windows-1252": "windows-1252", "cp1252": "windows-1252",
+	"cp437": "cp437", "ibm437": "cp437",
+	"cp850": "cp850", "ibm850": "cp850",
+}
+
+var byteOrderMarks = []struct {
+	charset string
+	mark    []byte
+}{
+	{"utf-8", []byte{0xEF, 0xBB, 0xBF}},
+	{"utf-16le", []byte{0xFF, 0xFE}},
+	{"utf-16be", []byte{0xFE, 0xFF}},
+}
+
+// canonicalCharset returns the canonical name of encoding, or "" for an empty name.
+func canonicalCharset(encoding string) (string, error) {
+	name := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(encoding), "_", "-"))
+	if name == "" {
+		return "", nil
+	}
+	if canonical, ok := charsetAliases[name]; ok {
+		return canonical, nil
+	}
+	return "", newValidationErrorWithContext(fmt.Sprintf("unsupported text encoding %q", encoding), nil, ErrorCodeValidation, nil)
+}
+
+// detectBOM returns the encoding and length of the byte order mark data starts with, if any.
+func detectBOM(data []byte) (string, int) {
+	for _, bom := range byteOrderMarks {
+		if bytes.HasPrefix(data, bom.mark) {
+			return bom.charset, len(bom.mark)
+		}
+	}
+	return "", 0
+}
+
+// decodeText converts data in the named encoding to a Go string. An empty encoding detects
+// UTF-8/UTF-16 byte order marks, then falls back to UTF-8 when valid and Windows-1252 otherwise.
+// Named encodings skip a byte order mark of their own. It returns the encoding that was used.
+func decodeText(data []byte, encoding string) (string, string, error) {
+	charset, err := canonicalCharset(encoding)
+	if err != nil {
+		return "", "", err
+	}
+	bom, n := detectBOM(data)
+	switch {
+	case charset == "" && bom != "":
+		charset = bom
+	case charset == "" && utf8.Valid(data):
+		charset = "utf-8"
+	case charset == "":
+		charset = "windows-1252"
+	}
+	if bom == charset {
+		data = data[n:]
+	}
+	return decodeCharset(data, charset), charset, nil
+}
+
+// decodeCharset decodes data in a canonical encoding, byte order marks included.
+func decodeCharset(data []byte, charset string) string {
+	switch charset {
+	case "utf-16le":
+		return decodeUTF16(data, binary.LittleEndian)
+	case "utf-16be":
+		return decodeUTF16(data, binary.BigEndian)
+	case "iso-8859-1", "iso-8859-15":
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+			if r, ok := iso885915[b]; ok && charset == "iso-8859-15" {
+				runes[i] = r
+			}
+		}
+		return string(runes)
+	case "windows-1252":
+		return decodeWindows1252(data)
+	case "cp437":
+		return decodeSingleByte(data, cp437High)
+	case "cp850":
+		return decodeSingleByte(data, cp850High)
+	}
+	return string(data)
+}
+
+func decodeUTF16(data []byte, order binary.ByteOrder) string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2*i:])
+	}
+	return string(utf16.Decode(units))
+}
+
+func decodeWindows1252(data []byte) string {
+	var b strings.Builder
+	b.Grow(len(data))
+	for _, c := range data {
+		if c >= 0x80 && c <= 0x9F {
+			b.WriteRune(windows1252High[c-0x80])
+		} else {
+			b.WriteRune(rune(c))
+		}
+	}
+	return b.String()
+}
+
+// decodeSingleByte decodes a code page whose lower half is ASCII and whose upper half is high.
+func decodeSingleByte(data []byte, high []rune) string {
+	var b strings.Builder
+	b.Grow(len(data))
+	for _, c := range data {
+		if c >= 0x80 {
+			b.WriteRune(high[c-0x80])
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// BOMPolicy controls how byte order marks are handled by TextEncodingConfig.
+type BOMPolicy string
+
+const (
+	// BOMAuto lets a byte order mark override Charset and removes it (the default).
+	BOMAuto BOMPolicy = "auto"
+	// BOMStrip decodes with Charset even when a byte order mark names another encoding, and
+	// removes the mark.
+	BOMStrip BOMPolicy = "strip"
+	// BOMKeep decodes with Charset and keeps a byte order mark as U+FEFF at the start of the
+	// text. The built-in CSV extractor still drops it from the first header.
+	BOMKeep BOMPolicy = "keep"
+)
+
+// TextEncodingConfig decodes text inputs in Go before extraction, for exports whose encoding is
+// known but not declared, e.g. from legacy ERP systems. Matching documents are transcoded to
+// UTF-8 and the decision is recorded in Metadata.Additional["text_encoding"] (see
+// Metadata.TextEncoding). It overrides CSVConfig.Encoding and charsets declared in HTML.
+type TextEncodingConfig struct {
+	// Charset forces the encoding: "utf-8", "utf-16le", "utf-16be", "iso-8859-1",
+	// "iso-8859-15", "windows-1252", "cp437" or "cp850". Empty detects byte order marks and
+	// falls back to UTF-8, then Windows-1252.
+	Charset string
+	// BOM controls byte order marks (default BOMAuto).
+	BOM BOMPolicy
+	// MimeTypes lists the MIME types decoded (default: plain text, CSV, TSV and HTML).
+	MimeTypes []string
+}
+
+var defaultTextEncodingMimeTypes = []string{"text/plain", "text/csv", "text/tab-separated-values", "text/html"}
+
+// TextEncodingDecision records how a document was decoded, as reported in
+// Metadata.Additional["text_encoding"].
+type TextEncodingDecision struct {
+	// Charset is the encoding the document was decoded with.
+	Charset string `json:"charset"`
+	// Source is "forced" (TextEncodingConfig.Charset), "bom" or "detected".
+	Source string `json:"source"`
+	// BOM is the encoding named by the document's byte order mark, if it has one.
+	BOM string `json:"bom,omitempty"`
+	// BOMStripped reports whether the byte order mark was removed.
+	BOMStripped bool `json:"bom_stripped"`
+}
+
+// TextEncoding returns how a document was decoded when ExtractionConfig.TextEncoding applied.
+func (m Metadata) TextEncoding() (*TextEncodingDecision, bool) {
+	var decision TextEncodingDecision
+	if found, err := m.Decode("text_encoding", &decision); !found || err != nil {
+		return nil, false
+	}
+	return &decision, true
+}
+
+// decodeTextWithPolicy decodes data as configured by cfg.
+func decodeTextWithPolicy(data []byte, cfg *TextEncodingConfig) (string, TextEncodingDecision, error) {
+	charset, err := canonicalCharset(cfg.Charset)
+	if err != nil {
+		return "", TextEncodingDecision{}, err
+	}
+	switch cfg.BOM {
+	case "", BOMAuto, BOMStrip, BOMKeep:
+	default:
+		return "", TextEncodingDecision{}, newValidationErrorWithContext(fmt.Sprintf("unsupported BOM policy %q", cfg.BOM), nil, ErrorCodeValidation, nil)
+	}
+	bom, n := detectBOM(data)
+	decision := TextEncodingDecision{Charset: charset, Source: "forced", BOM: bom}
+	switch {
+	case bom != "" && (charset == "" || cfg.BOM == "" || cfg.BOM == BOMAuto):
+		decision.Charset, decision.Source, decision.BOMStripped = bom, "bom", true
+		return decodeCharset(data[n:], bom), decision, nil
+	case charset == "":
+		text, detected, _ := decodeText(data, "")
+		decision.Charset, decision.Source = detected, "detected"
+		return text, decision, nil
+	case bom != "" && cfg.BOM == BOMStrip:
+		data, decision.BOMStripped = data[n:], true
+	}
+	return decodeCharset(data, charset), decision, nil
+}
+
+// textEncodingMimeType returns the MIME type of src when config.TextEncoding applies to it.
+func textEncodingMimeType(src documentSource, config *ExtractionConfig) (string, bool) {
+	if config == nil || config.TextEncoding == nil {
+		return "", false
+	}
+	mimeType := src.mimeType
+	if mimeType == "" && src.path != "" {
+		mimeType, _, _ = strings.Cut(mime.TypeByExtension(strings.ToLower(filepath.Ext(src.path))), ";")
+	}
+	if mimeType == "" {
+		mimeType = src.detectMimeType()
+	}
+	mimeTypes := config.TextEncoding.MimeTypes
+	if mimeTypes == nil {
+		mimeTypes = defaultTextEncodingMimeTypes
+	}
+	mimeType = strings.ToLower(mimeType)
+	return mimeType, mimeType != "" && slices.Contains(mimeTypes, mimeType)
+}
+
+// extractTranscoded decodes src as configured by config.TextEncoding and extracts the UTF-8
+// text with the primary extractor.
+func extractTranscoded(src documentSource, mimeType string, config *ExtractionConfig) (*ExtractionResult, error) {
+	data, err := src.bytes()
+	if err != nil {
+		return nil, err
+	}
+	text, decision, err := decodeTextWithPolicy(data, config.TextEncoding)
+	if err != nil {
+		return nil, err
+	}
+	if mimeType == "text/html" && !strings.HasPrefix(text, "\uFEFF") {
+		// HTML parsers let a byte order mark take precedence over <meta charset> declarations.
+		text = "\uFEFF" + text
+	}
+	cfg := *config
+	cfg.TextEncoding = nil
+	if cfg.CSV != nil {
+		csv := *cfg.CSV
+		csv.Encoding = "utf-8"
+		cfg.CSV = &csv
+	}
+	result, err := extractPrimaryUncached(documentSource{data: []byte(text), mimeType: mimeType}, &cfg)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(decision)
+	if err != nil {
+		return nil, newSerializationErrorWithContext("failed to encode text encoding metadata", err, ErrorCodeValidation, nil)
+	}
+	if result.Metadata.Additional == nil {
+		result.Metadata.Additional = map[string]json.RawMessage{}
+	}
+	result.Metadata.Additional["text_encoding"] = raw
+	return result, nil
+}
@@ -0,0 +1,73 @@
+package kreuzberg
+
+import "testing"
+
+func TestDecodeTextLegacyCodePages(t *testing.T) {
+	for _, tc := range []struct {
+		data     []byte
+		encoding string
+		want     string
+	}{
+		{[]byte{'M', 0x81, 'l', 'l', 'e', 'r', ' ', 0x9B}, "cp850", "Müller ø"},
+		{[]byte{0x84, 0xE1, 0xB3}, "IBM437", "äß│"},
+		{[]byte{0xA4, ' ', 0xE9}, "latin_9", "€ é"},
+		{[]byte{0xA4}, "iso-8859-1", "¤"},
+	} {
+		got, _, err := decodeText(tc.data, tc.encoding)
+		if err != nil || got != tc.want {
+			t.Fatalf("decodeText(%x, %q) = %q, %v; want %q", tc.data, tc.encoding, got, err, tc.want)
+		}
+	}
+}
+
+func TestDecodeTextWithPolicy(t *testing.T) {
+	data := append([]byte{0xEF, 0xBB, 0xBF}, 'a', 0x81)
+	for _, tc := range []struct {
+		cfg  TextEncodingConfig
+		want string
+		dec  TextEncodingDecision
+	}{
+		{TextEncodingConfig{Charset: "cp850"}, "a\x81", TextEncodingDecision{Charset: "utf-8", Source: "bom", BOM: "utf-8", BOMStripped: true}},
+		{TextEncodingConfig{Charset: "cp850", BOM: BOMStrip}, "aü", TextEncodingDecision{Charset: "cp850", Source: "forced", BOM: "utf-8", BOMStripped: true}},
+		{TextEncodingConfig{Charset: "cp850", BOM: BOMKeep}, "´╗┐aü", TextEncodingDecision{Charset: "cp850", Source: "forced", BOM: "utf-8"}},
+		{TextEncodingConfig{Charset: "utf-8", BOM: BOMKeep}, "\uFEFFa\x81", TextEncodingDecision{Charset: "utf-8", Source: "forced", BOM: "utf-8"}},
+		{TextEncodingConfig{}, "a\x81", TextEncodingDecision{Charset: "utf-8", Source: "bom", BOM: "utf-8", BOMStripped: true}},
+	} {
+		got, dec, err := decodeTextWithPolicy(data, &tc.cfg)
+		if err != nil || got != tc.want || dec != tc.dec {
+			t.Fatalf("%+v: got %q %+v %v; want %q %+v", tc.cfg, got, dec, err, tc.want, tc.dec)
+		}
+	}
+	if _, dec, _ := decodeTextWithPolicy([]byte{'a', 0x80}, &TextEncodingConfig{}); dec.Charset != "windows-1252" || dec.Source != "detected" {
+		t.Fatalf("unexpected detection: %+v", dec)
+	}
+	if _, _, err := decodeTextWithPolicy(data, &TextEncodingConfig{BOM: "drop"}); err == nil {
+		t.Fatalf("expected an error for an unknown BOM policy")
+	}
+}
+
+func TestTextEncodingOverridesCSVEncoding(t *testing.T) {
+	config := &ExtractionConfig{
+		CSV:          &CSVConfig{Encoding: "windows-1252"},
+		TextEncoding: &TextEncodingConfig{Charset: "cp850"},
+	}
+	result, err := ExtractBytesSync([]byte("name;city\nM\x81ller;K\x94ln\n"), "text/csv", config)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if cells := result.Tables[0].Cells; cells[1][0] != "Müller" || cells[1][1] != "Köln" {
+		t.Fatalf("unexpected cells: %q", cells)
+	}
+	decision, ok := result.Metadata.TextEncoding()
+	if !ok || decision.Charset != "cp850" || decision.Source != "forced" {
+		t.Fatalf("unexpected decision: %+v", decision)
+	}
+
+	result, err = ExtractBytesSync([]byte(testSRT), mimeSRT, &ExtractionConfig{TextEncoding: &TextEncodingConfig{Charset: "cp850"}})
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if _, ok := result.Metadata.TextEncoding(); ok {
+		t.Fatalf("expected formats outside MimeTypes to be left alone")
+	}
+}
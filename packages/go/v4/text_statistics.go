@@ -0,0 +1,229 @@
+package kreuzberg
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TextStatisticsConfig configures the statistics computed over extracted content.
+type TextStatisticsConfig struct {
+	// NgramSizes lists the n-gram lengths counted, from 1 to 5 (default 1, 2 and 3).
+	NgramSizes []int
+	// TopNgrams is the number of most frequent n-grams kept per size (default 10).
+	TopNgrams int
+}
+
+// NgramCount is a frequent n-gram of lower-cased words.
+type NgramCount struct {
+	// N is the number of words in the n-gram.
+	N int `json:"n"`
+	// Ngram is the words joined by single spaces.
+	Ngram string `json:"ngram"`
+	// Count is the number of occurrences.
+	Count int `json:"count"`
+}
+
+// TextStatistics summarizes extracted content for analytics. Readability scores use the English
+// Flesch formulas with estimated syllable counts, so they are only meaningful for English text.
+type TextStatistics struct {
+	// SentenceCount is the number of sentences; paragraph breaks also end sentences.
+	SentenceCount int `json:"sentence_count"`
+	// WordCount is the number of words.
+	WordCount int `json:"word_count"`
+	// UniqueWordCount is the number of distinct lower-cased words.
+	UniqueWordCount int `json:"unique_word_count"`
+	// AverageSentenceLength is the mean number of words per sentence.
+	AverageSentenceLength float64 `json:"average_sentence_length"`
+	// AverageWordLength is the mean number of characters per word.
+	AverageWordLength float64 `json:"average_word_length"`
+	// LexicalDiversity is UniqueWordCount divided by WordCount.
+	LexicalDiversity float64 `json:"lexical_diversity"`
+	// FleschReadingEase is higher for easier text (roughly 0-100).
+	FleschReadingEase float64 `json:"flesch_reading_ease"`
+	// FleschKincaidGrade approximates the US school grade needed to read the text.
+	FleschKincaidGrade float64 `json:"flesch_kincaid_grade"`
+	// TopNgrams lists the most frequent n-grams per size, most frequent first. N-grams that
+	// start or end with an English stopword, and n-grams spanning sentences, are not counted.
+	TopNgrams []NgramCount `json:"top_ngrams,omitempty"`
+}
+
+// TextStatistics returns the statistics computed when ExtractionConfig.TextStatistics was set.
+// They are stored in TextMetadata for text documents and in Additional["text_statistics"] for
+// every other format.
+func (m Metadata) TextStatistics() (*TextStatistics, bool) {
+	if text, ok := m.TextMetadata(); ok && text.Statistics != nil {
+		return text.Statistics, true
+	}
+	var stats TextStatistics
+	if found, err := m.Decode("text_statistics", &stats); !found || err != nil {
+		return nil, false
+	}
+	return &stats, true
+}
+
+// ngramStopwords are skipped at the edges of n-grams so the top lists show content words.
+var ngramStopwords = map[string]bool{}
+
+func init() {
+	for _, word := range strings.Fields(`a an and are as at be but by for from had has have he her his i if in
+		into is it its not of on or our she so that the their them there they this to was we were
+		what which who will with you your`) {
+		ngramStopwords[word] = true
+	}
+}
+
+// ComputeTextStatistics computes statistics over text; cfg may be nil for the defaults.
+func ComputeTextStatistics(text string, cfg *TextStatisticsConfig) (*TextStatistics, error) {
+	sizes := []int{1, 2, 3}
+	top := 10
+	if cfg != nil {
+		if len(cfg.NgramSizes) > 0 {
+			sizes = cfg.NgramSizes
+		}
+		if cfg.TopNgrams > 0 {
+			top = cfg.TopNgrams
+		}
+	}
+	for _, n := range sizes {
+		if n < 1 || n > 5 {
+			return nil, newValidationErrorWithContext(fmt.Sprintf("n-gram size %d is out of range 1-5", n), nil, ErrorCodeValidation, nil)
+		}
+	}
+
+	stats := &TextStatistics{}
+	counts := make(map[int]map[string]int, len(sizes))
+	for _, n := range sizes {
+		counts[n] = map[string]int{}
+	}
+	unique := map[string]bool{}
+	letters, syllables := 0, 0
+	for _, sentence := range splitSentences(text) {
+		words := sentenceWords(sentence)
+		if len(words) == 0 {
+			continue
+		}
+		stats.SentenceCount++
+		stats.WordCount += len(words)
+		for i, word := range words {
+			letters += utf8.RuneCountInString(word)
+			syllables += estimateSyllables(word)
+			words[i] = strings.ToLower(word)
+			unique[words[i]] = true
+		}
+		for _, n := range sizes {
+			for i := 0; i+n <= len(words); i++ {
+				if ngramStopwords[words[i]] || ngramStopwords[words[i+n-1]] {
+					continue
+				}
+				counts[n][strings.Join(words[i:i+n], " ")]++
+			}
+		}
+	}
+	if stats.WordCount == 0 {
+		return stats, nil
+	}
+
+	words, sentences := float64(stats.WordCount), float64(stats.SentenceCount)
+	stats.UniqueWordCount = len(unique)
+	stats.AverageSentenceLength = words / sentences
+	stats.AverageWordLength = float64(letters) / words
+	stats.LexicalDiversity = float64(stats.UniqueWordCount) / words
+	stats.FleschReadingEase = 206.835 - 1.015*stats.AverageSentenceLength - 84.6*float64(syllables)/words
+	stats.FleschKincaidGrade = 0.39*stats.AverageSentenceLength + 11.8*float64(syllables)/words - 15.59
+	for _, n := range sizes {
+		ngrams := make([]NgramCount, 0, len(counts[n]))
+		for ngram, count := range counts[n] {
+			ngrams = append(ngrams, NgramCount{N: n, Ngram: ngram, Count: count})
+		}
+		slices.SortFunc(ngrams, func(a, b NgramCount) int {
+			return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Ngram, b.Ngram))
+		})
+		stats.TopNgrams = append(stats.TopNgrams, ngrams[:min(top, len(ngrams))]...)
+	}
+	return stats, nil
+}
+
+// splitSentences splits text after sentence-ending punctuation followed by whitespace, and at
+// blank lines so headings and list items without punctuation count as sentences.
+func splitSentences(text string) []string {
+	var sentences []string
+	start := 0
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		end := false
+		switch r {
+		case '.', '!', '?', '…':
+			end = i+1 == len(runes) || unicode.IsSpace(runes[i+1])
+		case '。', '！', '？':
+			end = true
+		case '\n':
+			end = i+1 < len(runes) && runes[i+1] == '\n'
+		}
+		if end {
+			sentences = append(sentences, string(runes[start:i+1]))
+			start = i + 1
+		}
+	}
+	return append(sentences, string(runes[start:]))
+}
+
+// sentenceWords returns the words of a sentence: runs of letters and digits, with inner
+// apostrophes and hyphens kept ("don't", "e-mail").
+func sentenceWords(sentence string) []string {
+	fields := strings.FieldsFunc(sentence, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\'' && r != '’' && r != '-'
+	})
+	words := fields[:0]
+	for _, field := range fields {
+		if word := strings.Trim(field, "'’-"); word != "" {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// estimateSyllables counts vowel groups, ignoring a silent final "e"; words without vowels
+// (numbers, abbreviations) count as one syllable.
+func estimateSyllables(word string) int {
+	word = strings.ToLower(word)
+	count, previousVowel := 0, false
+	for _, r := range word {
+		vowel := strings.ContainsRune("aeiouyàáâäèéêëìíîïòóôöùúûü", r)
+		if vowel && !previousVowel {
+			count++
+		}
+		previousVowel = vowel
+	}
+	if count > 1 && strings.HasSuffix(word, "e") && !strings.HasSuffix(word, "le") {
+		count--
+	}
+	return max(count, 1)
+}
+
+// annotateTextStatistics computes statistics over result.Content and stores them in the
+// result metadata.
+func annotateTextStatistics(result *ExtractionResult, cfg *TextStatisticsConfig) error {
+	stats, err := ComputeTextStatistics(result.Content, cfg)
+	if err != nil {
+		return err
+	}
+	if text, ok := result.Metadata.TextMetadata(); ok {
+		text.Statistics = stats
+		return nil
+	}
+	raw, err := json.Marshal(stats)
+	if err != nil {
+		return newSerializationErrorWithContext("failed to encode text statistics", err, ErrorCodeValidation, nil)
+	}
+	if result.Metadata.Additional == nil {
+		result.Metadata.Additional = map[string]json.RawMessage{}
+	}
+	result.Metadata.Additional["text_statistics"] = raw
+	return nil
+}
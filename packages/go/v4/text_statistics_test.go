@@ -0,0 +1,71 @@
+package kreuzberg
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestComputeTextStatistics(t *testing.T) {
+	text := "The quick brown fox jumps over the lazy dog. The quick brown fox sleeps!\n\nLazy dogs' days\n\nIs it 'quick'?"
+	stats, err := ComputeTextStatistics(text, &TextStatisticsConfig{NgramSizes: []int{1, 3}, TopNgrams: 2})
+	if err != nil {
+		t.Fatalf("compute: %v", err)
+	}
+	if stats.SentenceCount != 4 || stats.WordCount != 20 || stats.UniqueWordCount != 13 {
+		t.Fatalf("unexpected counts: %+v", stats)
+	}
+	if math.Abs(stats.AverageSentenceLength-5) > 1e-9 || math.Abs(stats.LexicalDiversity-0.65) > 1e-9 {
+		t.Fatalf("unexpected averages: %+v", stats)
+	}
+	want := []NgramCount{{1, "quick", 3}, {1, "brown", 2}, {3, "quick brown fox", 2}, {3, "brown fox jumps", 1}}
+	if len(stats.TopNgrams) != len(want) {
+		t.Fatalf("expected %v, got %v", want, stats.TopNgrams)
+	}
+	for i := range want {
+		if stats.TopNgrams[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, stats.TopNgrams)
+		}
+	}
+	if stats.FleschReadingEase < 80 || stats.FleschKincaidGrade > 5 {
+		t.Fatalf("expected simple text to read easily: %+v", stats)
+	}
+
+	if hard, _ := ComputeTextStatistics("Institutional interoperability necessitates comprehensive organizational standardization initiatives.", nil); hard.FleschReadingEase >= stats.FleschReadingEase {
+		t.Fatalf("expected long words to lower readability: %v", hard.FleschReadingEase)
+	}
+	if empty, err := ComputeTextStatistics(" \n ", nil); err != nil || empty.WordCount != 0 || empty.SentenceCount != 0 {
+		t.Fatalf("unexpected statistics for empty text: %+v, %v", empty, err)
+	}
+	if _, err := ComputeTextStatistics(text, &TextStatisticsConfig{NgramSizes: []int{6}}); err == nil {
+		t.Fatalf("expected an error for an unsupported n-gram size")
+	}
+}
+
+func TestTextStatisticsInMetadata(t *testing.T) {
+	config := &ExtractionConfig{TextStatistics: &TextStatisticsConfig{}}
+	result, err := ExtractBytesSync([]byte(testSRT), mimeSRT, config)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	stats, ok := result.Metadata.TextStatistics()
+	if !ok || stats.SentenceCount != 3 || stats.WordCount != 9 {
+		t.Fatalf("unexpected statistics: %+v", stats)
+	}
+
+	text := &ExtractionResult{Content: "One. Two.", Metadata: Metadata{Format: FormatMetadata{Type: FormatText, Text: &TextMetadata{}}}}
+	if err := annotateTextStatistics(text, nil); err != nil {
+		t.Fatalf("annotate: %v", err)
+	}
+	data, err := json.Marshal(text.Metadata)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var decoded Metadata
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if stats, ok := decoded.TextStatistics(); !ok || stats.SentenceCount != 2 || decoded.Additional["statistics"] != nil {
+		t.Fatalf("expected statistics to round-trip in TextMetadata, got %+v from %s", stats, data)
+	}
+}
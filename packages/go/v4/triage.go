@@ -0,0 +1,379 @@
+package kreuzberg
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// TriageAnomaly codes name the structural problems a TriageReport can record.
+const (
+	AnomalyEmptyFile               = "empty_file"
+	AnomalyFormatMismatch          = "format_mismatch"
+	AnomalyLeadingGarbage          = "leading_garbage"
+	AnomalyMissingEOF              = "missing_eof"
+	AnomalyMissingStartXref        = "missing_startxref"
+	AnomalyInvalidStartXref        = "invalid_startxref"
+	AnomalyTruncatedXref           = "truncated_xref"
+	AnomalyMissingTrailer          = "missing_trailer"
+	AnomalyMissingCentralDirectory = "missing_central_directory"
+	AnomalyInvalidCentralDirectory = "invalid_central_directory"
+	AnomalyTruncatedEntry          = "truncated_entry"
+	AnomalyCorruptEntry            = "corrupt_entry"
+	AnomalyInvalidHeader           = "invalid_header"
+	AnomalyTruncated               = "truncated"
+)
+
+// TriageAnomaly is one structural problem found in a document.
+type TriageAnomaly struct {
+	Code string `json:"code"`
+	// Offset is the byte offset of the problem, or -1 when it has none (e.g. a missing marker).
+	Offset  int64  `json:"offset"`
+	Message string `json:"message"`
+}
+
+// TriageReport is a forensic summary of why a document could not be parsed, meant for support
+// teams rather than programs: which container format the bytes are, how far its structure holds
+// up and what is broken. It inspects the container only (PDF cross-reference data, the ZIP
+// central directory and entries of Office Open XML, OpenDocument, EPUB and iWork files, and the
+// OLE2 header of legacy Office files), so a report without anomalies means the damage is inside
+// the content the parser reads.
+type TriageReport struct {
+	// Format is the container detected from the leading bytes: "pdf", "zip", "ole2" or "unknown".
+	Format string `json:"format"`
+	// ExpectedFormat is the container implied by the MIME type or file extension, if known.
+	ExpectedFormat string `json:"expected_format,omitempty"`
+	Size           int64  `json:"size"`
+	// StoppedAt is the offset of the first anomaly with an offset, i.e. where a parser reading
+	// the structure would stop, or -1 when no anomaly has one.
+	StoppedAt int64           `json:"stopped_at"`
+	Anomalies []TriageAnomaly `json:"anomalies"`
+}
+
+// String renders the report as one line, e.g. for support tickets.
+func (r *TriageReport) String() string {
+	if len(r.Anomalies) == 0 {
+		return fmt.Sprintf("%s, %d bytes: no structural anomalies", r.Format, r.Size)
+	}
+	parts := make([]string, len(r.Anomalies))
+	for i, a := range r.Anomalies {
+		parts[i] = a.Message
+		if a.Offset >= 0 {
+			parts[i] += fmt.Sprintf(" (at byte %d)", a.Offset)
+		}
+	}
+	return fmt.Sprintf("%s, %d bytes: %s", r.Format, r.Size, strings.Join(parts, "; "))
+}
+
+func (r *TriageReport) add(code string, offset int64, format string, args ...any) {
+	r.Anomalies = append(r.Anomalies, TriageAnomaly{Code: code, Offset: offset, Message: fmt.Sprintf(format, args...)})
+	if offset >= 0 && (r.StoppedAt < 0 || offset < r.StoppedAt) {
+		r.StoppedAt = offset
+	}
+}
+
+// Triage returns the forensic report attached to a parsing error when ExtractionConfig.Triage is
+// enabled, or nil.
+func (e *baseError) Triage() *TriageReport {
+	return e.triage
+}
+
+// TriageOf returns the triage report attached to err or any error it wraps, or nil.
+func TriageOf(err error) *TriageReport {
+	var triaged interface{ Triage() *TriageReport }
+	if errors.As(err, &triaged) {
+		return triaged.Triage()
+	}
+	return nil
+}
+
+// TriageFile inspects the structure of the file at path.
+func TriageFile(path string) (*TriageReport, error) {
+	return triageSource(documentSource{path: path})
+}
+
+// TriageBytes inspects the structure of an in-memory document. mimeType may be empty.
+func TriageBytes(data []byte, mimeType string) (*TriageReport, error) {
+	return triageSource(documentSource{data: data, mimeType: mimeType})
+}
+
+func triageEnabled(config *ExtractionConfig) bool {
+	return config != nil && config.Triage != nil && *config.Triage
+}
+
+// triageError attaches a triage report of src to err when err is a parsing error and triage is
+// enabled. Other errors, and documents that cannot be read, are returned unchanged.
+func triageError(src documentSource, config *ExtractionConfig, err error) error {
+	var parsingErr *ParsingError
+	if !triageEnabled(config) || !errors.As(err, &parsingErr) {
+		return err
+	}
+	if report, readErr := triageSource(src); readErr == nil {
+		parsingErr.triage = report
+	}
+	return err
+}
+
+// triageBatchItems attaches triage reports to the ErrorMetadata of batch items that failed to
+// parse.
+func triageBatchItems(sources []documentSource, results []*ExtractionResult, config *ExtractionConfig) {
+	if !triageEnabled(config) {
+		return
+	}
+	for i, result := range results {
+		if result == nil || result.Metadata.Error == nil || result.Metadata.Error.ErrorType != "ParsingError" {
+			continue
+		}
+		if report, err := triageSource(sources[i]); err == nil {
+			result.Metadata.Error.Triage = report
+		}
+	}
+}
+
+func triageSource(src documentSource) (*TriageReport, error) {
+	data, err := src.bytes()
+	if err != nil {
+		return nil, err
+	}
+	return triage(data, expectedContainer(src.mimeType, src.path)), nil
+}
+
+// expectedContainer maps a MIME type or file extension to the container format it implies.
+func expectedContainer(mimeType, path string) string {
+	switch mimeType = strings.ToLower(mimeType); {
+	case mimeType == "application/pdf":
+		return "pdf"
+	case strings.Contains(mimeType, "openxmlformats"), strings.Contains(mimeType, "opendocument"),
+		mimeType == "application/epub+zip", mimeType == "application/zip", strings.Contains(mimeType, "iwork"):
+		return "zip"
+	case mimeType == "application/msword", mimeType == "application/vnd.ms-excel",
+		mimeType == "application/vnd.ms-powerpoint", mimeType == "application/vnd.ms-outlook":
+		return "ole2"
+	case mimeType != "":
+		return ""
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pdf":
+		return "pdf"
+	case ".docx", ".xlsx", ".pptx", ".odt", ".ods", ".odp", ".epub", ".zip", ".pages", ".numbers", ".key":
+		return "zip"
+	case ".doc", ".xls", ".ppt", ".msg":
+		return "ole2"
+	}
+	return ""
+}
+
+var (
+	pdfMagic  = []byte("%PDF-")
+	zipMagic  = []byte("PK\x03\x04")
+	eocdMagic = []byte("PK\x05\x06")
+	ole2Magic = []byte("\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1")
+)
+
+func triage(data []byte, expected string) *TriageReport {
+	r := &TriageReport{Format: "unknown", ExpectedFormat: expected, Size: int64(len(data)), StoppedAt: -1, Anomalies: []TriageAnomaly{}}
+	if len(data) == 0 {
+		r.add(AnomalyEmptyFile, 0, "the file is empty")
+		return r
+	}
+	// PDF readers accept a header within the first KiB; ZIP archives may be prefixed, e.g. by
+	// a self-extractor stub, and are recognized by their end record.
+	pdfAt := bytes.Index(data[:min(len(data), 1024)], pdfMagic)
+	switch {
+	case pdfAt >= 0:
+		r.Format = "pdf"
+	case bytes.HasPrefix(data, zipMagic) || bytes.HasPrefix(data, eocdMagic) || (expected == "zip" && bytes.LastIndex(data, eocdMagic) >= 0):
+		r.Format = "zip"
+	case bytes.HasPrefix(data, ole2Magic):
+		r.Format = "ole2"
+	}
+	if expected != "" && r.Format != expected {
+		r.add(AnomalyFormatMismatch, 0, "the file is declared as %s but its leading bytes are not a %s header", expected, expected)
+		if r.Format == "unknown" {
+			r.Format = expected
+		}
+	}
+	switch r.Format {
+	case "pdf":
+		triagePDF(r, data, max(pdfAt, 0))
+	case "zip":
+		triageZIP(r, data)
+	case "ole2":
+		triageOLE2(r, data)
+	}
+	return r
+}
+
+var (
+	startxrefPattern = regexp.MustCompile(`startxref\s+(\d+)`)
+	xrefObjPattern   = regexp.MustCompile(`^\s*\d+\s+\d+\s+obj`)
+	xrefSubsection   = regexp.MustCompile(`^(\d+)\s+(\d+)[ \t]*\r?\n?`)
+	xrefEntryPattern = regexp.MustCompile(`^\d{10} \d{5} [nf][ \r\n]{2}$`)
+)
+
+// triagePDF checks the header, the end-of-file marker and the cross-reference data startxref
+// points to.
+func triagePDF(r *TriageReport, data []byte, headerAt int) {
+	if headerAt > 0 {
+		r.add(AnomalyLeadingGarbage, 0, "%d bytes precede the %%PDF header", headerAt)
+	}
+	size := int64(len(data))
+	if bytes.LastIndex(data, []byte("%%EOF")) < 0 {
+		r.add(AnomalyMissingEOF, size, "the %%%%EOF marker is missing; the file is probably truncated")
+	}
+	locs := startxrefPattern.FindAllSubmatchIndex(data, -1)
+	if len(locs) == 0 {
+		r.add(AnomalyMissingStartXref, -1, "no startxref pointer to the cross-reference table")
+		return
+	}
+	loc := locs[len(locs)-1]
+	xref, err := strconv.ParseInt(string(data[loc[2]:loc[3]]), 10, 64)
+	if err != nil || xref >= size {
+		r.add(AnomalyInvalidStartXref, int64(loc[0]), "startxref points beyond the end of the file (offset %s)", data[loc[2]:loc[3]])
+		return
+	}
+	rest := data[xref:]
+	switch {
+	case bytes.HasPrefix(rest, []byte("xref")):
+		triageXrefTable(r, data, xref)
+	case xrefObjPattern.Match(rest[:min(len(rest), 64)]):
+		// A cross-reference stream; its entries are compressed and left to the parser.
+	default:
+		r.add(AnomalyInvalidStartXref, xref, "startxref does not point to a cross-reference table or stream")
+	}
+}
+
+// triageXrefTable walks the subsections of a classic cross-reference table at offset, whose
+// entries are 20 bytes each, and checks the trailer that follows it.
+func triageXrefTable(r *TriageReport, data []byte, offset int64) {
+	pos := offset + int64(len("xref"))
+	for pos < int64(len(data)) && (data[pos] == '\r' || data[pos] == '\n' || data[pos] == ' ') {
+		pos++
+	}
+	for {
+		m := xrefSubsection.FindSubmatchIndex(data[pos:])
+		if m == nil {
+			break
+		}
+		count, _ := strconv.ParseInt(string(data[pos+int64(m[4]):pos+int64(m[5])]), 10, 64)
+		pos += int64(m[1])
+		for i := range count {
+			if pos+20 > int64(len(data)) || !xrefEntryPattern.Match(data[pos:pos+20]) {
+				r.add(AnomalyTruncatedXref, pos, "the cross-reference table declares %d entries but entry %d is cut off or malformed", count, i)
+				return
+			}
+			pos += 20
+		}
+	}
+	if !bytes.HasPrefix(bytes.TrimLeft(data[pos:], " \r\n"), []byte("trailer")) {
+		r.add(AnomalyMissingTrailer, pos, "the cross-reference table is not followed by a trailer")
+	}
+}
+
+// triageZIP checks the end of central directory record, the central directory it points to
+// and the entries themselves.
+func triageZIP(r *TriageReport, data []byte) {
+	size := int64(len(data))
+	tail := max(0, len(data)-(22+0xffff))
+	eocd := bytes.LastIndex(data[tail:], eocdMagic)
+	if eocd >= 0 {
+		eocd += tail
+	}
+	if eocd < 0 || len(data)-eocd < 22 {
+		r.add(AnomalyMissingCentralDirectory, size, "the end of central directory record is missing; the archive is probably truncated")
+		triageLocalHeaders(r, data)
+		return
+	}
+	cdSize := int64(binary.LittleEndian.Uint32(data[eocd+12:]))
+	cdOffset := int64(binary.LittleEndian.Uint32(data[eocd+16:]))
+	if cdOffset != 0xffffffff {
+		if cdOffset+cdSize > int64(eocd) {
+			r.add(AnomalyInvalidCentralDirectory, int64(eocd), "the central directory (offset %d, %d bytes) overlaps the end record or lies beyond the file", cdOffset, cdSize)
+			triageLocalHeaders(r, data)
+			return
+		}
+		// Prefixed archives shift every offset by the prefix length.
+		shift := int64(eocd) - cdSize - cdOffset
+		if !bytes.HasPrefix(data[cdOffset+shift:], []byte("PK\x01\x02")) {
+			r.add(AnomalyInvalidCentralDirectory, cdOffset+shift, "no central directory header where the end record points")
+			return
+		}
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), size)
+	if err != nil {
+		r.add(AnomalyInvalidCentralDirectory, -1, "the central directory cannot be read: %v", err)
+		return
+	}
+	for _, f := range zr.File {
+		offset, _ := f.DataOffset()
+		rc, err := f.Open()
+		if err == nil {
+			// Reading at most the declared size bounds the work on decompression bombs.
+			_, err = io.CopyN(io.Discard, rc, int64(min(f.UncompressedSize64, 1<<30))+1)
+			if err == io.EOF {
+				err = nil
+			}
+			rc.Close()
+		}
+		if err != nil {
+			r.add(AnomalyCorruptEntry, offset, "entry %q is corrupt: %v", f.Name, err)
+		}
+	}
+}
+
+// triageLocalHeaders walks the local file headers from the start of an archive without a
+// usable central directory and records where the last complete entry ends.
+func triageLocalHeaders(r *TriageReport, data []byte) {
+	var pos int64
+	size := int64(len(data))
+	for pos+30 <= size && bytes.HasPrefix(data[pos:], zipMagic) {
+		flags := binary.LittleEndian.Uint16(data[pos+6:])
+		compressed := int64(binary.LittleEndian.Uint32(data[pos+18:]))
+		nameLen := int64(binary.LittleEndian.Uint16(data[pos+26:]))
+		extraLen := int64(binary.LittleEndian.Uint16(data[pos+28:]))
+		name := string(data[pos+30 : min(size, pos+30+nameLen)])
+		end := pos + 30 + nameLen + extraLen + compressed
+		if flags&0x8 != 0 && end <= size {
+			// The sizes follow the data in a descriptor, located by its signature.
+			next := bytes.Index(data[end:], []byte("PK\x07\x08"))
+			if next < 0 {
+				r.add(AnomalyTruncatedEntry, pos, "entry %q is cut off before its data descriptor", name)
+				return
+			}
+			end += int64(next) + 16
+		}
+		if end > size {
+			r.add(AnomalyTruncatedEntry, pos, "entry %q needs %d bytes but the file ends after %d", name, end-pos, size-pos)
+			return
+		}
+		pos = end
+	}
+	if pos+30 > size && pos < size {
+		r.add(AnomalyTruncated, pos, "the file ends inside a local file header")
+	}
+}
+
+// triageOLE2 checks the compound file header of legacy Office documents.
+func triageOLE2(r *TriageReport, data []byte) {
+	if len(data) < 512 {
+		r.add(AnomalyTruncated, int64(len(data)), "the file ends inside the 512-byte compound file header")
+		return
+	}
+	shift := binary.LittleEndian.Uint16(data[30:])
+	if shift != 9 && shift != 12 {
+		r.add(AnomalyInvalidHeader, 30, "invalid sector size exponent %d", shift)
+		return
+	}
+	sector := int64(1) << shift
+	if body := int64(len(data)) - sector; body%sector != 0 {
+		r.add(AnomalyTruncated, int64(len(data))-body%sector, "the file ends inside a %d-byte sector", sector)
+	}
+}
@@ -0,0 +1,109 @@
+package kreuzberg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// minimalPDF builds a PDF with a classic cross-reference table of n entries.
+func minimalPDF(n int) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n1 0 obj\n<< /Type /Catalog >>\nendobj\n")
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n", n)
+	for i := range n {
+		fmt.Fprintf(&b, "%010d 00000 n \n", i*10)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", n, xref)
+	return b.Bytes()
+}
+
+func anomalyCodes(r *TriageReport) []string {
+	codes := make([]string, len(r.Anomalies))
+	for i, a := range r.Anomalies {
+		codes[i] = a.Code
+	}
+	return codes
+}
+
+func TestTriagePDF(t *testing.T) {
+	pdf := minimalPDF(3)
+	report, err := TriageBytes(pdf, "application/pdf")
+	if err != nil || report.Format != "pdf" || len(report.Anomalies) != 0 || report.StoppedAt != -1 {
+		t.Fatalf("expected an intact PDF, got %+v, %v", report, err)
+	}
+
+	// Cut the file inside the second cross-reference entry.
+	xref := bytes.Index(pdf, []byte("xref\n"))
+	truncated := append(bytes.Clone(pdf[:xref+len("xref\n0 3\n")+30]), "\nstartxref\n"...)
+	truncated = append(truncated, fmt.Sprint(xref)...)
+	report, _ = TriageBytes(truncated, "application/pdf")
+	if codes := anomalyCodes(report); len(codes) != 2 || codes[0] != AnomalyMissingEOF || codes[1] != AnomalyTruncatedXref {
+		t.Fatalf("unexpected anomalies: %v", report)
+	}
+	if want := int64(xref + len("xref\n0 3\n") + 20); report.StoppedAt != want {
+		t.Fatalf("expected parsing to stop at %d, got %d", want, report.StoppedAt)
+	}
+
+	report, _ = TriageBytes(bytes.Replace(pdf, []byte("startxref\n"), []byte("startxref\n9"), 1), "")
+	if codes := anomalyCodes(report); len(codes) != 1 || codes[0] != AnomalyInvalidStartXref {
+		t.Fatalf("unexpected anomalies: %v", report)
+	}
+	report, _ = TriageBytes([]byte("<html>"), "application/pdf")
+	if codes := anomalyCodes(report); len(codes) < 2 || codes[0] != AnomalyFormatMismatch || codes[1] != AnomalyMissingEOF {
+		t.Fatalf("unexpected anomalies: %v", report)
+	}
+}
+
+func TestTriageZIPAndOLE2(t *testing.T) {
+	archive := buildBundle(t, map[string][]byte{"a.txt": bytes.Repeat([]byte("hello "), 100)})
+	if report, _ := TriageBytes(archive, "application/zip"); len(report.Anomalies) != 0 {
+		t.Fatalf("expected an intact archive, got %v", report)
+	}
+
+	corrupt := bytes.Clone(archive)
+	corrupt[40] ^= 0xff
+	if report, _ := TriageBytes(corrupt, "application/zip"); len(report.Anomalies) != 1 || report.Anomalies[0].Code != AnomalyCorruptEntry {
+		t.Fatalf("expected a corrupt entry, got %v", report)
+	}
+
+	ole := append([]byte("\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1"), make([]byte, 600)...)
+	ole[30] = 9
+	report, _ := TriageBytes(ole, "")
+	if report.Format != "ole2" || len(report.Anomalies) != 1 || report.Anomalies[0].Code != AnomalyTruncated || report.StoppedAt != 512 {
+		t.Fatalf("unexpected OLE2 report: %v", report)
+	}
+}
+
+func TestTriageAttachesReportsToParsingErrors(t *testing.T) {
+	archive := buildBundle(t, map[string][]byte{"Index/Document.iwa": bytes.Repeat([]byte{1}, 200)})
+	path := filepath.Join(t.TempDir(), "broken.pages")
+	if err := os.WriteFile(path, archive[:60], 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	_, err := ExtractFileSync(path, nil)
+	var parsingErr *ParsingError
+	if !errors.As(err, &parsingErr) || TriageOf(err) != nil {
+		t.Fatalf("expected a ParsingError without triage, got %v", err)
+	}
+
+	enabled := true
+	_, err = ExtractFileSync(path, &ExtractionConfig{Triage: &enabled})
+	report := TriageOf(err)
+	if report == nil || report.Format != "zip" || report.StoppedAt != 0 {
+		t.Fatalf("expected a triage report, got %v (%v)", report, err)
+	}
+	if codes := anomalyCodes(report); len(codes) != 2 || codes[0] != AnomalyMissingCentralDirectory || codes[1] != AnomalyTruncatedEntry {
+		t.Fatalf("unexpected anomalies: %v", report)
+	}
+
+	results, err := BatchExtractFilesSync([]string{path}, &ExtractionConfig{Triage: &enabled})
+	if err != nil || results[0].Metadata.Error == nil || results[0].Metadata.Error.Triage == nil {
+		t.Fatalf("expected a triage report on the failed batch item, got %+v, %v", results, err)
+	}
+}
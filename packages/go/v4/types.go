@@ -22,6 +22,16 @@ type ExtractionResult struct {
 	Pages []PageContent `json:"pages,omitempty"`
 	// Success indicates whether extraction completed successfully.
 	Success bool `json:"success"`
+	// Diagnostics lists non-fatal observations (e.g., validator warnings) recorded by the Go pipeline.
+	Diagnostics []Diagnostic `json:"diagnostics,omitempty"`
+	// AnnotatedContent is Content with a source anchor before each Markdown block, set when
+	// ExtractionConfig.SourceAnchors is enabled (see ParseSourceAnchors).
+	AnnotatedContent string `json:"annotated_content,omitempty"`
+
+	// cacheHit is set on results served from the result cache.
+	cacheHit bool
+	// statisticsOnly is set on results already reduced to their DocumentStatistics.
+	statisticsOnly bool
 }
 
 // Table represents a detected table in the source document.
@@ -32,6 +42,18 @@ type Table struct {
 	Markdown string `json:"markdown"`
 	// PageNumber is the page number where the table was found (1-indexed).
 	PageNumber int `json:"page_number"`
+	// CellBounds parallels Cells with the bounding box of each cell on page PageNumber. Only the
+	// PDF and OCR table extractors report it, in PDF points for digital PDFs and in pixels of the
+	// OCR input image for OCR; cells that are empty or could not be located are nil.
+	CellBounds [][]*BoundingBox `json:"cell_bounds,omitempty"`
+}
+
+// BoundingBox is an axis-aligned rectangle on a page, with the origin at the top-left corner.
+type BoundingBox struct {
+	Left   float64 `json:"left"`
+	Top    float64 `json:"top"`
+	Right  float64 `json:"right"`
+	Bottom float64 `json:"bottom"`
 }
 
 // Chunk contains chunked content plus optional embeddings and metadata.
@@ -60,6 +82,14 @@ type ChunkMetadata struct {
 	FirstPage *uint64 `json:"first_page,omitempty"`
 	// LastPage is the last page number containing this chunk (1-indexed, if available).
 	LastPage *uint64 `json:"last_page,omitempty"`
+	// StartTimeMs is the media playback position where this chunk begins, for timed formats
+	// such as subtitles (if available).
+	StartTimeMs *int64 `json:"start_time_ms,omitempty"`
+	// EndTimeMs is the media playback position where this chunk ends (if available).
+	EndTimeMs *int64 `json:"end_time_ms,omitempty"`
+	// QAPairs holds the question/answer pairs generated for this chunk when ExtractionConfig.QA
+	// is set.
+	QAPairs []QAPair `json:"qa_pairs,omitempty"`
 }
 
 // ExtractedImage represents an extracted image, optionally with nested OCR results.
@@ -298,6 +328,54 @@ type XMLMetadata struct {
 	ElementCount int `json:"element_count"`
 	// UniqueElements lists all unique XML element tag names.
 	UniqueElements []string `json:"unique_elements"`
+	// Document is the semantic structure recognized by an XML profile (DocBook, TEI, JATS,
+	// PubMed), when ExtractionConfig.XMLProfile is set and the document matches a profile.
+	Document *XMLDocument `json:"xml_document,omitempty"`
+}
+
+// XMLDocument describes a document written in an XML documentation standard.
+type XMLDocument struct {
+	// Profile names the standard the document was read as.
+	Profile  XMLProfile  `json:"profile"`
+	Title    string      `json:"title,omitempty"`
+	Authors  []XMLAuthor `json:"authors,omitempty"`
+	Date     string      `json:"date,omitempty"`
+	Language string      `json:"language,omitempty"`
+	// Source is the journal, series or publication the document appeared in.
+	Source   string   `json:"source,omitempty"`
+	Abstract string   `json:"abstract,omitempty"`
+	Keywords []string `json:"keywords,omitempty"`
+	// Identifiers maps identifier types (e.g., "doi", "pmid", "isbn") to values.
+	Identifiers map[string]string `json:"identifiers,omitempty"`
+	// Sections lists the document headings in reading order.
+	Sections   []XMLSection   `json:"sections,omitempty"`
+	References []XMLReference `json:"references,omitempty"`
+}
+
+// XMLAuthor is an author of an XMLDocument.
+type XMLAuthor struct {
+	Name        string `json:"name"`
+	Affiliation string `json:"affiliation,omitempty"`
+	Email       string `json:"email,omitempty"`
+}
+
+// XMLSection is a heading of an XMLDocument.
+type XMLSection struct {
+	// Level is the heading depth, 1 for top-level sections.
+	Level int    `json:"level"`
+	Title string `json:"title"`
+	// ID is the section's id or xml:id attribute, when present.
+	ID string `json:"id,omitempty"`
+}
+
+// XMLReference is a bibliography entry of an XMLDocument.
+type XMLReference struct {
+	ID    string `json:"id,omitempty"`
+	Label string `json:"label,omitempty"`
+	// Text is the citation as written, with markup removed.
+	Text string `json:"text"`
+	DOI  string `json:"doi,omitempty"`
+	PMID string `json:"pmid,omitempty"`
 }
 
 // TextMetadata contains counts for plain text and Markdown documents.
@@ -314,6 +392,9 @@ type TextMetadata struct {
 	Links [][2]string `json:"links,omitempty"`
 	// CodeBlocks is a list of [language, code] pairs for all code blocks.
 	CodeBlocks [][2]string `json:"code_blocks,omitempty"`
+	// Statistics holds sentence, n-gram and readability statistics when
+	// ExtractionConfig.TextStatistics is set.
+	Statistics *TextStatistics `json:"statistics,omitempty"`
 }
 
 //revive:disable-next-line var-naming
@@ -426,6 +507,9 @@ type ErrorMetadata struct {
 	ErrorType string `json:"error_type"`
 	// Message is the error message.
 	Message string `json:"message"`
+	// Triage is the forensic report of a document that failed to parse, recorded by the Go
+	// binding when ExtractionConfig.Triage is enabled.
+	Triage *TriageReport `json:"triage,omitempty"`
 }
 
 // PageUnitType enumerates the types of paginated units in documents.
@@ -0,0 +1,233 @@
+package kreuzberg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// defaultUsageTenantLabel is the default ExtractionConfig.Labels key usage is metered by.
+const defaultUsageTenantLabel = "tenant"
+
+// Usage is the metered usage of a tenant.
+type Usage struct {
+	// Documents is the number of documents extracted successfully.
+	Documents int64 `json:"documents"`
+	// FailedDocuments is the number of documents whose extraction failed.
+	FailedDocuments int64 `json:"failed_documents"`
+	// CacheHits is the number of documents served from the result cache.
+	CacheHits int64 `json:"cache_hits"`
+	// Pages is the number of pages, slides or sheets extracted; a document without pages
+	// counts as one.
+	Pages int64 `json:"pages"`
+	// OCRPages is the number of pages extracted with OCR, counting each OCRed embedded image as
+	// a page.
+	OCRPages int64 `json:"ocr_pages"`
+	// EmbeddingTokens is the number of tokens of the embedded chunks; chunks without a token
+	// count count their words.
+	EmbeddingTokens int64 `json:"embedding_tokens"`
+	// ContentBytes is the size of the extracted content in bytes.
+	ContentBytes int64 `json:"content_bytes"`
+}
+
+func (u *Usage) add(other Usage) {
+	u.Documents += other.Documents
+	u.FailedDocuments += other.FailedDocuments
+	u.CacheHits += other.CacheHits
+	u.Pages += other.Pages
+	u.OCRPages += other.OCRPages
+	u.EmbeddingTokens += other.EmbeddingTokens
+	u.ContentBytes += other.ContentBytes
+}
+
+// resultUsage meters a successful extraction.
+func resultUsage(result *ExtractionResult) Usage {
+	usage := Usage{Documents: 1, ContentBytes: int64(len(result.Content))}
+	pages := len(result.Pages)
+	if count, _ := result.GetPageCount(); count > pages {
+		pages = count
+	}
+	if pdf, ok := result.Metadata.PdfMetadata(); ok && pdf.PageCount != nil && *pdf.PageCount > pages {
+		pages = *pdf.PageCount
+	}
+	usage.Pages = int64(max(pages, 1))
+	if _, ok := result.Metadata.OcrMetadata(); ok {
+		usage.OCRPages = usage.Pages
+	} else {
+		for _, image := range result.Images {
+			if image.OCRResult != nil {
+				usage.OCRPages++
+			}
+		}
+	}
+	for _, chunk := range result.Chunks {
+		if chunk.Embedding == nil {
+			continue
+		}
+		if chunk.Metadata.TokenCount != nil {
+			usage.EmbeddingTokens += int64(*chunk.Metadata.TokenCount)
+		} else {
+			usage.EmbeddingTokens += int64(len(strings.Fields(chunk.Content)))
+		}
+	}
+	return usage
+}
+
+// UsageMeterOptions configures a UsageMeter.
+type UsageMeterOptions struct {
+	// TenantLabel is the ExtractionConfig.Labels key identifying the tenant (default "tenant").
+	// Extractions without it are metered under the empty tenant.
+	TenantLabel string
+}
+
+// UsageMeter accounts the usage of extractions per tenant. It is an EventHandler: subscribe it
+// to a Client, or to package-level extractions, with SubscribeEvents. It is safe for concurrent
+// use.
+//
+//	meter := kreuzberg.NewUsageMeter(nil)
+//	client.SubscribeEvents(meter)
+//	http.Handle("/usage", meter.Handler())
+//	http.Handle("/metrics", meter.MetricsHandler())
+type UsageMeter struct {
+	tenantLabel string
+
+	mu    sync.Mutex
+	usage map[string]*Usage
+}
+
+// NewUsageMeter returns an empty UsageMeter.
+func NewUsageMeter(opts *UsageMeterOptions) *UsageMeter {
+	m := &UsageMeter{tenantLabel: defaultUsageTenantLabel, usage: map[string]*Usage{}}
+	if opts != nil && opts.TenantLabel != "" {
+		m.tenantLabel = opts.TenantLabel
+	}
+	return m
+}
+
+// HandleEvent meters completed and failed extractions and cache hits.
+func (m *UsageMeter) HandleEvent(_ context.Context, event Event) {
+	var usage Usage
+	switch event.Type {
+	case EventExtractionCompleted:
+		if event.Result == nil {
+			return
+		}
+		usage = resultUsage(event.Result)
+	case EventExtractionFailed:
+		usage.FailedDocuments = 1
+	case EventCacheHit:
+		usage.CacheHits = 1
+	default:
+		return
+	}
+	m.Record(event.Labels[m.tenantLabel], usage)
+}
+
+// Record adds usage to tenant, e.g. for work metered outside of extractions.
+func (m *UsageMeter) Record(tenant string, usage Usage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	total, ok := m.usage[tenant]
+	if !ok {
+		total = &Usage{}
+		m.usage[tenant] = total
+	}
+	total.add(usage)
+}
+
+// Usage returns the usage of tenant.
+func (m *UsageMeter) Usage(tenant string) Usage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if usage, ok := m.usage[tenant]; ok {
+		return *usage
+	}
+	return Usage{}
+}
+
+// Snapshot returns the usage of every tenant.
+func (m *UsageMeter) Snapshot() map[string]Usage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.snapshot()
+}
+
+// Reset returns the usage of every tenant and clears it, e.g. at the end of a billing period.
+// No usage is lost or counted twice between the two.
+func (m *UsageMeter) Reset() map[string]Usage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snapshot := m.snapshot()
+	clear(m.usage)
+	return snapshot
+}
+
+func (m *UsageMeter) snapshot() map[string]Usage {
+	snapshot := make(map[string]Usage, len(m.usage))
+	for tenant, usage := range m.usage {
+		snapshot[tenant] = *usage
+	}
+	return snapshot
+}
+
+// usageMetrics lists the metrics WriteMetrics exports.
+var usageMetrics = []struct {
+	name  string
+	help  string
+	value func(Usage) int64
+}{
+	{"kreuzberg_usage_documents_total", "Documents extracted successfully.", func(u Usage) int64 { return u.Documents }},
+	{"kreuzberg_usage_failed_documents_total", "Documents whose extraction failed.", func(u Usage) int64 { return u.FailedDocuments }},
+	{"kreuzberg_usage_cache_hits_total", "Documents served from the result cache.", func(u Usage) int64 { return u.CacheHits }},
+	{"kreuzberg_usage_pages_total", "Pages, slides or sheets extracted.", func(u Usage) int64 { return u.Pages }},
+	{"kreuzberg_usage_ocr_pages_total", "Pages extracted with OCR.", func(u Usage) int64 { return u.OCRPages }},
+	{"kreuzberg_usage_embedding_tokens_total", "Tokens of embedded chunks.", func(u Usage) int64 { return u.EmbeddingTokens }},
+	{"kreuzberg_usage_content_bytes_total", "Bytes of extracted content.", func(u Usage) int64 { return u.ContentBytes }},
+}
+
+// prometheusLabelEscaper escapes Prometheus label values.
+var prometheusLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WriteMetrics writes the usage of every tenant as counters in the Prometheus text exposition
+// format, labelled with the tenant.
+func (m *UsageMeter) WriteMetrics(w io.Writer) error {
+	snapshot := m.Snapshot()
+	tenants := slices.Sorted(maps.Keys(snapshot))
+	var b strings.Builder
+	for _, metric := range usageMetrics {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n", metric.name, metric.help, metric.name)
+		for _, tenant := range tenants {
+			fmt.Fprintf(&b, "%s{tenant=\"%s\"} %d\n", metric.name, prometheusLabelEscaper.Replace(tenant), metric.value(snapshot[tenant]))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// MetricsHandler serves WriteMetrics for Prometheus scrapes.
+func (m *UsageMeter) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		m.WriteMetrics(w)
+	})
+}
+
+// Handler serves the usage as JSON: the usage of every tenant, keyed by tenant, or with a
+// "tenant" query parameter the usage of that tenant.
+func (m *UsageMeter) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body any = m.Snapshot()
+		if r.URL.Query().Has("tenant") {
+			body = m.Usage(r.URL.Query().Get("tenant"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(body)
+	})
+}
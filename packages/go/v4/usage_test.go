@@ -0,0 +1,80 @@
+package kreuzberg
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResultUsage(t *testing.T) {
+	tokens := 7
+	result := &ExtractionResult{
+		Content: "four",
+		Pages:   []PageContent{{PageNumber: 1}, {PageNumber: 2}},
+		Images:  []ExtractedImage{{OCRResult: &ExtractionResult{}}, {}},
+		Chunks: []Chunk{
+			{Content: "one two three", Embedding: []float32{1}},
+			{Content: "ignored", Metadata: ChunkMetadata{TokenCount: &tokens}},
+			{Content: "x", Embedding: []float32{1}, Metadata: ChunkMetadata{TokenCount: &tokens}},
+		},
+	}
+	want := Usage{Documents: 1, Pages: 2, OCRPages: 1, EmbeddingTokens: 10, ContentBytes: 4}
+	if got := resultUsage(result); got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	if got := resultUsage(&ExtractionResult{Metadata: Metadata{Format: FormatMetadata{Type: FormatOCR, OCR: &OcrMetadata{}}}}); got.Pages != 1 || got.OCRPages != 1 {
+		t.Fatalf("expected an OCRed image to count as one OCR page, got %+v", got)
+	}
+}
+
+func TestUsageMeter(t *testing.T) {
+	meter := NewUsageMeter(&UsageMeterOptions{TenantLabel: "customer"})
+	client := NewClient(&ExtractionConfig{
+		Labels:          map[string]string{"customer": `acme "eu"`},
+		CacheEncryption: &CacheEncryptionConfig{Key: bytes.Repeat([]byte{1}, 32), Dir: t.TempDir()},
+	})
+	client.SubscribeEvents(meter)
+	client.DisableFormats(FeatureStructured)
+	for range 2 {
+		if _, err := client.ExtractBytes(t.Context(), []byte(testSRT), mimeSRT); err != nil {
+			t.Fatalf("extract: %v", err)
+		}
+	}
+	client.ExtractBytes(t.Context(), []byte(`{"a": 1}`), mimeJSON)
+	meter.Record("", Usage{OCRPages: 3})
+
+	want := Usage{Documents: 2, FailedDocuments: 1, CacheHits: 1, Pages: 2, ContentBytes: 2 * int64(len(wantSRTContent))}
+	if got := meter.Usage(`acme "eu"`); got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+
+	var metrics strings.Builder
+	if err := meter.WriteMetrics(&metrics); err != nil {
+		t.Fatalf("write metrics: %v", err)
+	}
+	for _, line := range []string{
+		"# TYPE kreuzberg_usage_documents_total counter",
+		`kreuzberg_usage_documents_total{tenant="acme \"eu\""} 2`,
+		`kreuzberg_usage_ocr_pages_total{tenant=""} 3`,
+	} {
+		if !strings.Contains(metrics.String(), line+"\n") {
+			t.Fatalf("expected %q in\n%s", line, metrics.String())
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	meter.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/usage?tenant=acme+%22eu%22", nil))
+	var usage Usage
+	if err := json.Unmarshal(rec.Body.Bytes(), &usage); err != nil || usage != want {
+		t.Fatalf("unexpected tenant usage %s, %v", rec.Body, err)
+	}
+
+	if snapshot := meter.Reset(); len(snapshot) != 2 || snapshot[""].OCRPages != 3 {
+		t.Fatalf("unexpected snapshot %+v", snapshot)
+	}
+	if snapshot := meter.Snapshot(); len(snapshot) != 0 {
+		t.Fatalf("expected the reset to clear the usage, got %+v", snapshot)
+	}
+}
@@ -19,8 +19,8 @@ func ValidateBinarizationMethod(method string) error {
 		return newValidationErrorWithContext("binarization method cannot be empty", nil, ErrorCodeValidation, nil)
 	}
 
-	cMethod := C.CString(method)
-	defer C.free(unsafe.Pointer(cMethod))
+	cMethod := newCString(method)
+	defer freeCBuffer(unsafe.Pointer(cMethod))
 
 	result := int32(C.kreuzberg_validate_binarization_method(cMethod))
 	if result != 1 {
@@ -36,8 +36,8 @@ func ValidateOCRBackend(backend string) error {
 		return newValidationErrorWithContext("OCR backend cannot be empty", nil, ErrorCodeValidation, nil)
 	}
 
-	cBackend := C.CString(backend)
-	defer C.free(unsafe.Pointer(cBackend))
+	cBackend := newCString(backend)
+	defer freeCBuffer(unsafe.Pointer(cBackend))
 
 	result := int32(C.kreuzberg_validate_ocr_backend(cBackend))
 	if result != 1 {
@@ -53,8 +53,8 @@ func ValidateLanguageCode(code string) error {
 		return newValidationErrorWithContext("language code cannot be empty", nil, ErrorCodeValidation, nil)
 	}
 
-	cCode := C.CString(code)
-	defer C.free(unsafe.Pointer(cCode))
+	cCode := newCString(code)
+	defer freeCBuffer(unsafe.Pointer(cCode))
 
 	result := int32(C.kreuzberg_validate_language_code(cCode))
 	if result != 1 {
@@ -70,8 +70,8 @@ func ValidateTokenReductionLevel(level string) error {
 		return newValidationErrorWithContext("token reduction level cannot be empty", nil, ErrorCodeValidation, nil)
 	}
 
-	cLevel := C.CString(level)
-	defer C.free(unsafe.Pointer(cLevel))
+	cLevel := newCString(level)
+	defer freeCBuffer(unsafe.Pointer(cLevel))
 
 	result := int32(C.kreuzberg_validate_token_reduction_level(cLevel))
 	if result != 1 {
@@ -107,8 +107,8 @@ func ValidateOutputFormat(format string) error {
 		return newValidationErrorWithContext("output format cannot be empty", nil, ErrorCodeValidation, nil)
 	}
 
-	cFormat := C.CString(format)
-	defer C.free(unsafe.Pointer(cFormat))
+	cFormat := newCString(format)
+	defer freeCBuffer(unsafe.Pointer(cFormat))
 
 	result := int32(C.kreuzberg_validate_output_format(cFormat))
 	if result != 1 {
@@ -159,11 +159,11 @@ func ValidateChunkingParams(maxChars int, maxOverlap int) error {
 
 // GetValidBinarizationMethods returns a list of all valid binarization methods.
 func GetValidBinarizationMethods() ([]string, error) {
-	ptr := C.kreuzberg_get_valid_binarization_methods()
+	ptr := trackFFIAlloc(FFIResourceString, C.kreuzberg_get_valid_binarization_methods())
 	if ptr == nil {
 		return nil, lastError()
 	}
-	defer C.kreuzberg_free_string(ptr)
+	defer freeNativeString(ptr)
 
 	jsonStr := C.GoString(ptr)
 	var methods []string
@@ -175,11 +175,11 @@ func GetValidBinarizationMethods() ([]string, error) {
 
 // GetValidLanguageCodes returns a list of all valid language codes.
 func GetValidLanguageCodes() ([]string, error) {
-	ptr := C.kreuzberg_get_valid_language_codes()
+	ptr := trackFFIAlloc(FFIResourceString, C.kreuzberg_get_valid_language_codes())
 	if ptr == nil {
 		return nil, lastError()
 	}
-	defer C.kreuzberg_free_string(ptr)
+	defer freeNativeString(ptr)
 
 	jsonStr := C.GoString(ptr)
 	var codes []string
@@ -191,11 +191,11 @@ func GetValidLanguageCodes() ([]string, error) {
 
 // GetValidOCRBackends returns a list of all valid OCR backends.
 func GetValidOCRBackends() ([]string, error) {
-	ptr := C.kreuzberg_get_valid_ocr_backends()
+	ptr := trackFFIAlloc(FFIResourceString, C.kreuzberg_get_valid_ocr_backends())
 	if ptr == nil {
 		return nil, lastError()
 	}
-	defer C.kreuzberg_free_string(ptr)
+	defer freeNativeString(ptr)
 
 	jsonStr := C.GoString(ptr)
 	var backends []string
@@ -207,11 +207,11 @@ func GetValidOCRBackends() ([]string, error) {
 
 // GetValidTokenReductionLevels returns a list of all valid token reduction levels.
 func GetValidTokenReductionLevels() ([]string, error) {
-	ptr := C.kreuzberg_get_valid_token_reduction_levels()
+	ptr := trackFFIAlloc(FFIResourceString, C.kreuzberg_get_valid_token_reduction_levels())
 	if ptr == nil {
 		return nil, lastError()
 	}
-	defer C.kreuzberg_free_string(ptr)
+	defer freeNativeString(ptr)
 
 	jsonStr := C.GoString(ptr)
 	var levels []string
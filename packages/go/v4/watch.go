@@ -0,0 +1,645 @@
+package kreuzberg
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchStateVersion versions the state file layout of WatchDir and SyncDir.
+const watchStateVersion = 1
+
+// DefaultWatchIgnore lists the patterns WatchDir and SyncDir skip when WatchOptions.Ignore is
+// nil: hidden files and the temporary files of editors, downloads and copy tools.
+var DefaultWatchIgnore = []string{".*", "~*", "*.tmp", "*.part", "*.crdownload"}
+
+// WatchOptions controls WatchDir and SyncDir.
+type WatchOptions struct {
+	// Handle receives each extracted file, and each path that could not be scanned (required).
+	// Returning an error stops the watcher; the file is then not recorded as processed and is
+	// extracted again on the next run.
+	Handle func(WatchEvent) error
+	// StateFile persists the content hashes of processed files, so a restarted watcher only
+	// extracts files that are new or have changed. When empty, state is kept in memory only.
+	StateFile string
+	// Recursive includes files in subdirectories.
+	Recursive bool
+	// Ignore lists filepath.Match patterns of files and directories to skip. A pattern matches
+	// the base name or the slash-separated path relative to the watched directory (default:
+	// DefaultWatchIgnore).
+	Ignore []string
+	// Debounce is how long a file's size and modification time must stay unchanged before
+	// WatchDir extracts it, so files are not read while still being written (default 2s).
+	Debounce time.Duration
+	// PollInterval is how often WatchDir scans the directory when it polls (default 1s).
+	PollInterval time.Duration
+	// Poll makes WatchDir scan the directory every PollInterval instead of subscribing to file
+	// system notifications, for network shares (NFS, SMB) and container volumes where changes
+	// made by other hosts are not notified.
+	Poll bool
+}
+
+// WatchEvent reports a file extracted by WatchDir or SyncDir.
+type WatchEvent struct {
+	// Path is the file's path (the watched directory joined with the relative path).
+	Path string
+	// Hash is the hex SHA-256 digest of the content that was extracted.
+	Hash string
+	// Result is the extraction result (nil when extraction failed).
+	Result *ExtractionResult
+	// Err reports why the file could not be extracted. Failed files are recorded as processed
+	// too, so they are only retried once their content changes.
+	//
+	// Err also reports a file or directory below the watched directory that could not be read
+	// while scanning, with Hash and Result unset. It is reported once until it can be read
+	// again, and the files below it that were already processed are kept as they are.
+	Err error
+}
+
+// WatchDir monitors dir as a "hot folder" and extracts files that are added or modified, until
+// ctx is cancelled, returning ctx.Err(). Files are picked up once they have stopped changing for
+// opts.Debounce, and each one is extracted like ExtractFileWithContext and handed to
+// opts.Handle. Files whose content was already processed, according to opts.StateFile, are
+// skipped, so the watcher can be stopped and restarted at any time; the state file is updated
+// after every file.
+//
+// WatchDir subscribes to file system notifications (inotify, kqueue, ReadDirectoryChangesW) and
+// rescans the paths they name, and the whole directory only when notifications were dropped. It
+// polls instead when opts.Poll is set, or when the
+// platform or the notification limits (e.g. fs.inotify.max_user_watches) do not allow watching
+// the directory.
+func WatchDir(ctx context.Context, dir string, config *ExtractionConfig, opts WatchOptions) error {
+	return watchDir(ctx, defaultPluginRegistry, dir, config, opts)
+}
+
+// WatchDir is WatchDir using the client's config and plugins.
+func (c *Client) WatchDir(ctx context.Context, dir string, opts WatchOptions) error {
+	return watchDir(ctx, c.plugins, dir, c.config, opts)
+}
+
+// SyncDir makes a single pass over dir, extracting the files that are new or have changed
+// since the last pass recorded in opts.StateFile, and returns how many files were handled. It
+// does not debounce: run it (e.g. from cron) when no files are being written, or use WatchDir.
+func SyncDir(ctx context.Context, dir string, config *ExtractionConfig, opts WatchOptions) (int, error) {
+	return syncDir(ctx, defaultPluginRegistry, dir, config, opts)
+}
+
+// SyncDir is SyncDir using the client's config and plugins.
+func (c *Client) SyncDir(ctx context.Context, dir string, opts WatchOptions) (int, error) {
+	return syncDir(ctx, c.plugins, dir, c.config, opts)
+}
+
+func watchDir(ctx context.Context, plugins *pluginRegistry, dir string, config *ExtractionConfig, opts WatchOptions) error {
+	w, err := newDirWatcher(plugins, dir, config, opts)
+	if err != nil {
+		return err
+	}
+	if !opts.Poll {
+		if notifier, err := w.subscribe(); err == nil {
+			defer notifier.Close()
+			return w.watchNotifications(ctx, notifier)
+		}
+	}
+	return w.poll(ctx)
+}
+
+// poll scans the directory every PollInterval.
+func (w *dirWatcher) poll(ctx context.Context) error {
+	interval := w.opts.PollInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := w.scan(ctx, true); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// subscribe watches the directory, and its subdirectories when the watch is recursive, for
+// file system notifications.
+func (w *dirWatcher) subscribe() (*fsnotify.Watcher, error) {
+	notifier, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := w.addWatches(notifier, w.dir); err != nil {
+		notifier.Close()
+		return nil, err
+	}
+	return notifier, nil
+}
+
+// addWatches watches root and, for recursive watches, the directories below it that are not
+// ignored.
+func (w *dirWatcher) addWatches(notifier *fsnotify.Watcher, root string) error {
+	if !w.opts.Recursive {
+		return notifier.Add(root)
+	}
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if rel, err := filepath.Rel(w.dir, path); err == nil && rel != "." && w.ignored(rel) {
+			return filepath.SkipDir
+		}
+		return notifier.Add(path)
+	})
+}
+
+// watchNotifications rescans the paths notifications arrive for, and the changed files that are
+// due to have been stable for the debounce period. The whole directory is scanned at first and
+// again when notifications were dropped.
+func (w *dirWatcher) watchNotifications(ctx context.Context, notifier *fsnotify.Watcher) error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	full := true
+	var paths []string
+	for {
+		var err error
+		if full {
+			_, err = w.scan(ctx, true)
+		} else {
+			_, err = w.scanPaths(ctx, paths)
+		}
+		if err != nil {
+			return err
+		}
+		full, paths = false, nil
+		if wait, ok := w.nextDue(); ok {
+			timer.Reset(wait)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			for rel := range w.pending {
+				paths = append(paths, filepath.Join(w.dir, rel))
+			}
+		case event, ok := <-notifier.Events:
+			if !ok {
+				return w.poll(ctx)
+			}
+			w.watchCreated(notifier, event)
+			paths = append(paths, event.Name)
+			// Rescan once for a burst of notifications.
+			for drained := false; !drained; {
+				select {
+				case event := <-notifier.Events:
+					w.watchCreated(notifier, event)
+					paths = append(paths, event.Name)
+				default:
+					drained = true
+				}
+			}
+		case err, ok := <-notifier.Errors:
+			if !ok {
+				return w.poll(ctx)
+			}
+			if !errors.Is(err, fsnotify.ErrEventOverflow) {
+				return newIOErrorWithContext(fmt.Sprintf("failed to watch %q", w.dir), err, ErrorCodeIo, nil)
+			}
+			// Dropped notifications are recovered by a full rescan.
+			full = true
+		}
+	}
+}
+
+// watchCreated starts watching a directory created inside a recursive watch.
+func (w *dirWatcher) watchCreated(notifier *fsnotify.Watcher, event fsnotify.Event) {
+	if !w.opts.Recursive || !event.Has(fsnotify.Create) {
+		return
+	}
+	if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+		// A directory that cannot be watched is still picked up by rescans.
+		w.addWatches(notifier, event.Name)
+	}
+}
+
+// nextDue returns how long until the earliest changed file has been stable for the debounce
+// period, and false when no file is waiting.
+func (w *dirWatcher) nextDue() (time.Duration, bool) {
+	var due time.Time
+	for _, p := range w.pending {
+		if at := p.since.Add(w.debounce); due.IsZero() || at.Before(due) {
+			due = at
+		}
+	}
+	if due.IsZero() {
+		return 0, false
+	}
+	return max(time.Until(due), time.Millisecond), true
+}
+
+func syncDir(ctx context.Context, plugins *pluginRegistry, dir string, config *ExtractionConfig, opts WatchOptions) (int, error) {
+	w, err := newDirWatcher(plugins, dir, config, opts)
+	if err != nil {
+		return 0, err
+	}
+	return w.scan(ctx, false)
+}
+
+// watchState is the content of WatchOptions.StateFile.
+type watchState struct {
+	Version int                    `json:"version"`
+	Files   map[string]watchedFile `json:"files"`
+}
+
+// watchedFile records a processed file by its path relative to the watched directory.
+type watchedFile struct {
+	Hash    string    `json:"sha256"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// watchPending tracks a changed file until it has been stable for the debounce period.
+type watchPending struct {
+	size    int64
+	modTime time.Time
+	since   time.Time
+}
+
+type dirWatcher struct {
+	plugins  *pluginRegistry
+	dir      string
+	config   *ExtractionConfig
+	opts     WatchOptions
+	ignore   []string
+	debounce time.Duration
+	state    watchState
+	pending  map[string]watchPending
+	// failing holds the relative paths whose scan error was reported, until they can be read.
+	failing map[string]bool
+	// skip holds the absolute paths of the state file and its temporary copy.
+	skip map[string]bool
+}
+
+func newDirWatcher(plugins *pluginRegistry, dir string, config *ExtractionConfig, opts WatchOptions) (*dirWatcher, error) {
+	if opts.Handle == nil {
+		return nil, newValidationErrorWithContext("WatchOptions.Handle is required", nil, ErrorCodeValidation, nil)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, newValidationErrorWithContext(fmt.Sprintf("cannot watch %q: not a directory", dir), err, ErrorCodeValidation, nil)
+	}
+	w := &dirWatcher{
+		plugins:  plugins,
+		dir:      dir,
+		config:   config,
+		opts:     opts,
+		ignore:   opts.Ignore,
+		debounce: opts.Debounce,
+		state:    watchState{Version: watchStateVersion, Files: map[string]watchedFile{}},
+		pending:  map[string]watchPending{},
+		failing:  map[string]bool{},
+		skip:     map[string]bool{},
+	}
+	if w.ignore == nil {
+		w.ignore = DefaultWatchIgnore
+	}
+	for _, pattern := range w.ignore {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, newValidationErrorWithContext(fmt.Sprintf("invalid ignore pattern %q", pattern), err, ErrorCodeValidation, nil)
+		}
+	}
+	if w.debounce <= 0 {
+		w.debounce = 2 * time.Second
+	}
+	if opts.StateFile != "" {
+		for _, path := range []string{opts.StateFile, opts.StateFile + ".tmp"} {
+			if abs, err := filepath.Abs(path); err == nil {
+				w.skip[abs] = true
+			}
+		}
+		if err := w.loadState(); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// loadState reads the state file. A missing or unusable state file starts over.
+func (w *dirWatcher) loadState() error {
+	data, err := os.ReadFile(w.opts.StateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return newIOErrorWithContext("failed to read watch state", err, ErrorCodeIo, nil)
+	}
+	var state watchState
+	if json.Unmarshal(data, &state) == nil && state.Version == watchStateVersion && state.Files != nil {
+		w.state = state
+	}
+	return nil
+}
+
+// saveState replaces the state file, writing to a temporary file first so a crash leaves
+// either the old or the new state.
+func (w *dirWatcher) saveState() error {
+	if w.opts.StateFile == "" {
+		return nil
+	}
+	data, err := json.Marshal(w.state)
+	if err != nil {
+		return newSerializationErrorWithContext("failed to encode watch state", err, ErrorCodeValidation, nil)
+	}
+	tmp := w.opts.StateFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return newIOErrorWithContext("failed to write watch state", err, ErrorCodeIo, nil)
+	}
+	if err := os.Rename(tmp, w.opts.StateFile); err != nil {
+		return newIOErrorWithContext("failed to write watch state", err, ErrorCodeIo, nil)
+	}
+	return nil
+}
+
+func (w *dirWatcher) ignored(rel string) bool {
+	base := filepath.Base(rel)
+	slashed := filepath.ToSlash(rel)
+	for _, pattern := range w.ignore {
+		if ok, _ := filepath.Match(pattern, base); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, slashed); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoredPath reports whether rel or one of the directories above it is ignored.
+func (w *dirWatcher) ignoredPath(rel string) bool {
+	for ; rel != "."; rel = filepath.Dir(rel) {
+		if w.ignored(rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// watchScan is what a scan of part of the watched directory found.
+type watchScan struct {
+	files map[string]fs.FileInfo
+	// scopes are the relative paths the scan covered, "." for the whole directory. Processed
+	// files within them that were not found have been removed.
+	scopes []string
+	// failed holds the relative paths that could not be read.
+	failed map[string]error
+}
+
+func newWatchScan(scopes ...string) *watchScan {
+	return &watchScan{files: map[string]fs.FileInfo{}, scopes: scopes, failed: map[string]error{}}
+}
+
+// watchWalkDir walks the watched directory; tests replace it.
+var watchWalkDir = filepath.WalkDir
+
+// walk adds the files below root to found. Paths that cannot be read are recorded in found, and
+// paths that vanish while walking are skipped; only a failure to read the watched directory
+// itself is returned.
+func (w *dirWatcher) walk(root string, found *watchScan) error {
+	return watchWalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		rel, relErr := filepath.Rel(w.dir, path)
+		if relErr != nil {
+			return relErr
+		}
+		if err != nil {
+			if rel == "." {
+				return err
+			}
+			if !errors.Is(err, fs.ErrNotExist) {
+				found.failed[rel] = err
+			}
+			return nil
+		}
+		if rel == "." {
+			return nil
+		}
+		if d.IsDir() {
+			if !w.opts.Recursive || w.ignored(rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || w.ignored(rel) {
+			return nil
+		}
+		if abs, err := filepath.Abs(path); err == nil && w.skip[abs] {
+			return nil
+		}
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			found.failed[rel] = err
+			return nil
+		}
+		found.files[rel] = info
+		return nil
+	})
+}
+
+// scan extracts the files that are new or have changed, waiting for each to be stable for the
+// debounce period when debounce is set, and forgets files that were removed.
+func (w *dirWatcher) scan(ctx context.Context, debounce bool) (int, error) {
+	found := newWatchScan(".")
+	if err := w.walk(w.dir, found); err != nil {
+		return 0, newIOErrorWithContext(fmt.Sprintf("failed to scan %q", w.dir), err, ErrorCodeIo, nil)
+	}
+	return w.update(ctx, found, debounce)
+}
+
+// scanPaths is scan limited to paths named by file system notifications. A directory is scanned
+// with the files below it, and a path that no longer exists is forgotten with everything below
+// it.
+func (w *dirWatcher) scanPaths(ctx context.Context, paths []string) (int, error) {
+	found := newWatchScan()
+	for _, path := range paths {
+		rel, err := filepath.Rel(w.dir, path)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) ||
+			slices.Contains(found.scopes, rel) || w.ignoredPath(rel) {
+			continue
+		}
+		found.scopes = append(found.scopes, rel)
+		info, err := os.Lstat(path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+		case err != nil:
+			found.failed[rel] = err
+		case info.IsDir():
+			if err := w.walk(path, found); err != nil {
+				return 0, newIOErrorWithContext(fmt.Sprintf("failed to scan %q", w.dir), err, ErrorCodeIo, nil)
+			}
+		case info.Mode().IsRegular():
+			if abs, err := filepath.Abs(path); err != nil || !w.skip[abs] {
+				found.files[rel] = info
+			}
+		}
+	}
+	return w.update(ctx, found, true)
+}
+
+// update reports the paths of found that could not be read, extracts the files of found that
+// are new or have changed, and forgets the files within its scopes that were removed.
+func (w *dirWatcher) update(ctx context.Context, found *watchScan, debounce bool) (int, error) {
+	for rel := range w.failing {
+		if _, ok := found.failed[rel]; !ok && found.covers(rel) {
+			delete(w.failing, rel)
+		}
+	}
+	for rel, err := range found.failed {
+		if w.failing[rel] {
+			continue
+		}
+		path := filepath.Join(w.dir, rel)
+		event := WatchEvent{Path: path, Err: newIOErrorWithContext(fmt.Sprintf("failed to scan %q", path), err, ErrorCodeIo, nil)}
+		if err := w.opts.Handle(event); err != nil {
+			return 0, err
+		}
+		w.failing[rel] = true
+	}
+
+	now := time.Now()
+	handled := 0
+	for rel, info := range found.files {
+		if err := ctx.Err(); err != nil {
+			return handled, err
+		}
+		size, modTime := info.Size(), info.ModTime()
+		if seen, ok := w.state.Files[rel]; ok && seen.Size == size && seen.ModTime.Equal(modTime) {
+			delete(w.pending, rel)
+			continue
+		}
+		if debounce {
+			p, ok := w.pending[rel]
+			if !ok || p.size != size || !p.modTime.Equal(modTime) {
+				w.pending[rel] = watchPending{size: size, modTime: modTime, since: now}
+				continue
+			}
+			if now.Sub(p.since) < w.debounce {
+				continue
+			}
+			delete(w.pending, rel)
+		}
+		processed, err := w.process(ctx, rel, size, modTime)
+		if err != nil {
+			return handled, err
+		}
+		if processed {
+			handled++
+		}
+	}
+
+	removed := false
+	for rel := range w.state.Files {
+		if found.removed(rel) {
+			delete(w.state.Files, rel)
+			removed = true
+		}
+	}
+	for rel := range w.pending {
+		if found.removed(rel) {
+			delete(w.pending, rel)
+		}
+	}
+	if removed {
+		return handled, w.saveState()
+	}
+	return handled, nil
+}
+
+// covers reports whether rel is within one of the scopes of s.
+func (s *watchScan) covers(rel string) bool {
+	for _, scope := range s.scopes {
+		if scope == "." || withinPath(rel, scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// removed reports whether the file at rel is within the scopes of s but was not found, and not
+// below a path that could not be read.
+func (s *watchScan) removed(rel string) bool {
+	if _, ok := s.files[rel]; ok || !s.covers(rel) {
+		return false
+	}
+	for failed := range s.failed {
+		if withinPath(rel, failed) {
+			return false
+		}
+	}
+	return true
+}
+
+// withinPath reports whether rel is dir or below it.
+func withinPath(rel, dir string) bool {
+	return rel == dir || strings.HasPrefix(rel, dir+string(filepath.Separator))
+}
+
+// process extracts the file at rel unless its content was already processed, and records it.
+func (w *dirWatcher) process(ctx context.Context, rel string, size int64, modTime time.Time) (bool, error) {
+	path := filepath.Join(w.dir, rel)
+	hash, err := hashFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	record := watchedFile{Hash: hash, Size: size, ModTime: modTime}
+	if seen, ok := w.state.Files[rel]; ok && seen.Hash == hash {
+		// Touched but unchanged.
+		w.state.Files[rel] = record
+		return false, w.saveState()
+	}
+	event := WatchEvent{Path: path, Hash: hash}
+	event.Result, event.Err = extractFile(ctx, w.plugins, path, w.config)
+	if err := w.opts.Handle(event); err != nil {
+		return false, err
+	}
+	w.state.Files[rel] = record
+	return true, w.saveState()
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		return "", newIOErrorWithContext("failed to open watched file", err, ErrorCodeIo, nil)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", newIOErrorWithContext("failed to read watched file", err, ErrorCodeIo, nil)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
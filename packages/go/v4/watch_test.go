@@ -0,0 +1,253 @@
+package kreuzberg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyncDirExtractsNewAndChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	write("a.srt", testSRT)
+	write("b.srt", testSRT)
+	write(".hidden.srt", testSRT)
+	write("sub/c.srt", testSRT)
+	write("data.bin", "\x00\x01\x02")
+
+	events := map[string]WatchEvent{}
+	opts := WatchOptions{
+		StateFile: filepath.Join(dir, "state.json"),
+		Handle: func(e WatchEvent) error {
+			rel, _ := filepath.Rel(dir, e.Path)
+			events[filepath.ToSlash(rel)] = e
+			return nil
+		},
+	}
+	sync := func(opts WatchOptions) int {
+		t.Helper()
+		clear(events)
+		n, err := SyncDir(context.Background(), dir, nil, opts)
+		if err != nil {
+			t.Fatalf("sync: %v", err)
+		}
+		return n
+	}
+
+	if n := sync(opts); n != 3 || events["a.srt"].Result == nil || events["a.srt"].Result.Content != wantSRTContent {
+		t.Fatalf("expected a.srt, b.srt and data.bin, got %d: %+v", n, events)
+	}
+	if events["data.bin"].Err == nil || events["data.bin"].Result != nil {
+		t.Fatalf("expected data.bin to fail, got %+v", events["data.bin"])
+	}
+	if n := sync(opts); n != 0 {
+		t.Fatalf("expected nothing new, got %v", events)
+	}
+
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(filepath.Join(dir, "a.srt"), later, later)
+	write("b.srt", testSRT+"\n")
+	if n := sync(opts); n != 1 || events["b.srt"].Hash == "" {
+		t.Fatalf("expected only the changed file, got %v", events)
+	}
+
+	// A restarted watcher resumes from the state file; removed files are forgotten.
+	os.Remove(filepath.Join(dir, "a.srt"))
+	opts.Recursive = true
+	if n := sync(opts); n != 1 || events["sub/c.srt"].Result == nil {
+		t.Fatalf("expected only the file in the subdirectory, got %v", events)
+	}
+	state, _ := os.ReadFile(opts.StateFile)
+	if strings.Contains(string(state), `"a.srt"`) || !strings.Contains(string(state), `"b.srt"`) {
+		t.Fatalf("unexpected state %s", state)
+	}
+}
+
+func TestSyncDirHandleErrorStops(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.srt"), []byte(testSRT), 0o600)
+	stop := errors.New("stop")
+	opts := WatchOptions{Handle: func(WatchEvent) error { return stop }}
+	if _, err := SyncDir(context.Background(), dir, nil, opts); !errors.Is(err, stop) {
+		t.Fatalf("expected the handler's error, got %v", err)
+	}
+	opts.Handle = func(WatchEvent) error { return nil }
+	if n, err := SyncDir(context.Background(), dir, nil, opts); err != nil || n != 1 {
+		t.Fatalf("expected the file to be extracted again, got %d, %v", n, err)
+	}
+	var validationErr *ValidationError
+	if _, err := SyncDir(context.Background(), dir, nil, WatchOptions{}); !errors.As(err, &validationErr) {
+		t.Fatalf("expected a ValidationError without a handler, got %v", err)
+	}
+}
+
+func TestWatchDirDebouncesNewFiles(t *testing.T) {
+	for _, poll := range []bool{false, true} {
+		t.Run(fmt.Sprintf("poll=%v", poll), func(t *testing.T) {
+			dir := t.TempDir()
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			var got []WatchEvent
+			done := make(chan error, 1)
+			go func() {
+				done <- WatchDir(ctx, dir, nil, WatchOptions{
+					Debounce:     50 * time.Millisecond,
+					PollInterval: 10 * time.Millisecond,
+					Poll:         poll,
+					Handle: func(e WatchEvent) error {
+						got = append(got, e)
+						cancel()
+						return nil
+					},
+				})
+			}()
+
+			path := filepath.Join(dir, "a.srt")
+			written := time.Now()
+			os.WriteFile(path, []byte(testSRT), 0o600)
+			if err := <-done; !errors.Is(err, context.Canceled) {
+				t.Fatalf("expected the watcher to stop on cancellation, got %v", err)
+			}
+			if len(got) != 1 || got[0].Path != path || got[0].Result.Content != wantSRTContent {
+				t.Fatalf("unexpected events %+v", got)
+			}
+			if elapsed := time.Since(written); elapsed < 50*time.Millisecond {
+				t.Fatalf("expected the file to be debounced, extracted after %v", elapsed)
+			}
+		})
+	}
+}
+
+func TestWatchDirNotifiesNewSubdirectories(t *testing.T) {
+	dir := t.TempDir()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var got []WatchEvent
+	done := make(chan error, 1)
+	go func() {
+		done <- WatchDir(ctx, dir, nil, WatchOptions{
+			Recursive: true,
+			Debounce:  50 * time.Millisecond,
+			// Only notifications can pick the file up in time.
+			PollInterval: time.Hour,
+			Handle: func(e WatchEvent) error {
+				got = append(got, e)
+				cancel()
+				return nil
+			},
+		})
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	sub := filepath.Join(dir, "inbox")
+	os.Mkdir(sub, 0o700)
+	time.Sleep(50 * time.Millisecond)
+	path := filepath.Join(sub, "a.srt")
+	os.WriteFile(path, []byte(testSRT), 0o600)
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the watcher to stop on cancellation, got %v", err)
+	}
+	if len(got) != 1 || got[0].Path != path {
+		t.Fatalf("expected the file in the new subdirectory, got %+v", got)
+	}
+}
+
+func TestSyncDirReportsUnreadablePaths(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.srt"), []byte(testSRT), 0o600)
+	os.Mkdir(filepath.Join(dir, "locked"), 0o700)
+	os.WriteFile(filepath.Join(dir, "locked", "b.srt"), []byte(testSRT), 0o600)
+
+	var events []WatchEvent
+	opts := WatchOptions{Recursive: true, Handle: func(e WatchEvent) error {
+		events = append(events, e)
+		return nil
+	}}
+	w, err := newDirWatcher(defaultPluginRegistry, dir, nil, opts)
+	if err != nil {
+		t.Fatalf("new watcher: %v", err)
+	}
+	if n, err := w.scan(t.Context(), false); err != nil || n != 2 {
+		t.Fatalf("expected both files, got %d, %v", n, err)
+	}
+
+	// Fail to read the directory, like a directory without read permission.
+	denied := errors.New("permission denied")
+	watchWalkDir = func(root string, fn fs.WalkDirFunc) error {
+		return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err == nil && d.IsDir() && d.Name() == "locked" {
+				if err := fn(path, d, denied); err != nil {
+					return err
+				}
+				return filepath.SkipDir
+			}
+			return fn(path, d, err)
+		})
+	}
+	t.Cleanup(func() { watchWalkDir = filepath.WalkDir })
+	for i := range 2 {
+		events = nil
+		if n, err := w.scan(t.Context(), false); err != nil || n != 0 {
+			t.Fatalf("expected the scan to carry on, got %d, %v", n, err)
+		}
+		// The error is reported by the first scan only.
+		if want := 1 - i; len(events) != want || want == 1 && (!errors.Is(events[0].Err, denied) || events[0].Path != filepath.Join(dir, "locked")) {
+			t.Fatalf("scan %d: expected %d error events, got %+v", i, want, events)
+		}
+	}
+	if len(w.state.Files) != 2 {
+		t.Fatalf("expected the files below the unreadable directory to be kept, got %v", w.state.Files)
+	}
+
+	watchWalkDir = filepath.WalkDir
+	events = nil
+	w.scan(t.Context(), false)
+	if len(events) != 0 || len(w.failing) != 0 {
+		t.Fatalf("expected the directory to recover silently, got %+v", events)
+	}
+}
+
+func TestWatchScanPathsForgetsRemovedPaths(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.srt"), []byte(testSRT), 0o600)
+	os.Mkdir(filepath.Join(dir, "sub"), 0o700)
+	os.WriteFile(filepath.Join(dir, "sub", "b.srt"), []byte(testSRT), 0o600)
+	w, err := newDirWatcher(defaultPluginRegistry, dir, nil, WatchOptions{Recursive: true, Handle: func(WatchEvent) error { return nil }})
+	if err != nil {
+		t.Fatalf("new watcher: %v", err)
+	}
+	if n, err := w.scan(t.Context(), false); err != nil || n != 2 {
+		t.Fatalf("expected both files, got %d, %v", n, err)
+	}
+
+	os.RemoveAll(filepath.Join(dir, "sub"))
+	os.Remove(filepath.Join(dir, "a.srt"))
+	os.WriteFile(filepath.Join(dir, "c.srt"), []byte(testSRT), 0o600)
+	if n, err := w.scanPaths(t.Context(), []string{filepath.Join(dir, "sub"), filepath.Join(dir, "c.srt")}); err != nil || n != 0 {
+		t.Fatalf("expected the new file to wait for the debounce period, got %d, %v", n, err)
+	}
+	if _, ok := w.state.Files[filepath.Join("sub", "b.srt")]; ok {
+		t.Fatalf("expected the removed directory to be forgotten, got %v", w.state.Files)
+	}
+	if _, ok := w.state.Files["a.srt"]; !ok {
+		t.Fatalf("expected a path without a notification to be left alone, got %v", w.state.Files)
+	}
+	if _, ok := w.pending["c.srt"]; !ok {
+		t.Fatalf("expected the new file to be pending, got %v", w.pending)
+	}
+}
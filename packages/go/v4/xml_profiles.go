@@ -0,0 +1,813 @@
+package kreuzberg
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// XMLProfile names an XML documentation standard with a dedicated extraction profile.
+type XMLProfile string
+
+const (
+	// XMLProfileAuto detects the profile from the root element.
+	XMLProfileAuto    XMLProfile = ""
+	XMLProfileDocBook XMLProfile = "docbook"
+	XMLProfileTEI     XMLProfile = "tei"
+	// XMLProfileJATS covers JATS/NLM articles, including PubMed Central.
+	XMLProfileJATS XMLProfile = "jats"
+	// XMLProfilePubMed covers PubMed/MEDLINE citation sets.
+	XMLProfilePubMed XMLProfile = "pubmed"
+)
+
+const (
+	mimeXML     = "application/xml"
+	docbookNS   = "http://docbook.org/ns/docbook"
+	teiNS       = "http://www.tei-c.org/ns/1.0"
+	xmlMaxLevel = 6
+)
+
+// XMLProfileConfig enables semantic extraction of DocBook, TEI, JATS and PubMed XML. Matching
+// documents are rendered as Markdown with headings, lists and tables, and their title, authors,
+// sections and references are reported in XMLMetadata.Document. Other XML documents are
+// extracted natively.
+type XMLProfileConfig struct {
+	// Profile forces a profile (default: detected from the root element).
+	Profile XMLProfile
+}
+
+// xmlElem is a parsed XML element. Mixed content is kept in document order.
+type xmlElem struct {
+	name  string
+	space string
+	attrs map[string]string
+	nodes []xmlNode
+}
+
+// xmlNode is either a child element or a run of character data.
+type xmlNode struct {
+	elem *xmlElem
+	text string
+}
+
+func (e *xmlElem) attr(name string) string {
+	if e == nil {
+		return ""
+	}
+	return e.attrs[name]
+}
+
+// id returns the xml:id or id attribute.
+func (e *xmlElem) id() string {
+	if id := e.attr("xml:id"); id != "" {
+		return id
+	}
+	return e.attr("id")
+}
+
+func (e *xmlElem) elements() []*xmlElem {
+	if e == nil {
+		return nil
+	}
+	var out []*xmlElem
+	for _, n := range e.nodes {
+		if n.elem != nil {
+			out = append(out, n.elem)
+		}
+	}
+	return out
+}
+
+// child returns the first child element with one of the given names.
+func (e *xmlElem) child(names ...string) *xmlElem {
+	for _, c := range e.elements() {
+		if containsString(names, c.name) {
+			return c
+		}
+	}
+	return nil
+}
+
+func (e *xmlElem) childrenNamed(name string) []*xmlElem {
+	var out []*xmlElem
+	for _, c := range e.elements() {
+		if c.name == name {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// find follows a slash-separated path of child element names.
+func (e *xmlElem) find(path string) *xmlElem {
+	for _, name := range strings.Split(path, "/") {
+		if e = e.child(name); e == nil {
+			return nil
+		}
+	}
+	return e
+}
+
+// descendants returns all descendant elements with the given name, in document order.
+func (e *xmlElem) descendants(name string) []*xmlElem {
+	var out []*xmlElem
+	for _, c := range e.elements() {
+		if c.name == name {
+			out = append(out, c)
+		}
+		out = append(out, c.descendants(name)...)
+	}
+	return out
+}
+
+// text returns the whitespace-normalized character data of e and its descendants, skipping
+// elements named in skip.
+func (e *xmlElem) text(skip ...string) string {
+	if e == nil {
+		return ""
+	}
+	var b strings.Builder
+	var walk func(*xmlElem)
+	walk = func(el *xmlElem) {
+		for _, n := range el.nodes {
+			switch {
+			case n.elem == nil:
+				b.WriteString(n.text)
+			case !containsString(skip, n.elem.name):
+				walk(n.elem)
+			}
+		}
+	}
+	walk(e)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// parseXMLTree parses data into an element tree, returning the element count and the sorted
+// unique element names. HTML entities are accepted since JATS and DocBook files often rely on
+// DTD-declared entities.
+func parseXMLTree(data []byte) (*xmlElem, int, []string, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	dec.Entity = xml.HTMLEntity
+	dec.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		raw, err := io.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		text, _, err := decodeText(raw, charset)
+		return strings.NewReader(text), err
+	}
+
+	var root *xmlElem
+	var stack []*xmlElem
+	count := 0
+	unique := map[string]bool{}
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			el := &xmlElem{name: t.Name.Local, space: t.Name.Space, attrs: make(map[string]string, len(t.Attr))}
+			for _, a := range t.Attr {
+				key := a.Name.Local
+				if a.Name.Space == "xml" || a.Name.Space == "http://www.w3.org/XML/1998/namespace" {
+					key = "xml:" + key
+				}
+				el.attrs[key] = a.Value
+			}
+			count++
+			unique[el.name] = true
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.nodes = append(parent.nodes, xmlNode{elem: el})
+			} else if root == nil {
+				root = el
+			}
+			stack = append(stack, el)
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.nodes = append(parent.nodes, xmlNode{text: string(t)})
+			}
+		}
+	}
+	if root == nil {
+		return nil, 0, nil, errors.New("document has no root element")
+	}
+	names := make([]string, 0, len(unique))
+	for name := range unique {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return root, count, names, nil
+}
+
+// detectXMLProfile recognizes the standard of a document from its root element.
+func detectXMLProfile(root *xmlElem) XMLProfile {
+	switch {
+	case root.name == "TEI" || root.name == "teiCorpus" || root.space == teiNS:
+		return XMLProfileTEI
+	case root.name == "PubmedArticleSet" || root.name == "PubmedArticle" || root.name == "MedlineCitation":
+		return XMLProfilePubMed
+	case root.name == "article" && root.child("front") != nil:
+		return XMLProfileJATS
+	case root.space == docbookNS:
+		return XMLProfileDocBook
+	case containsString([]string{"book", "article", "chapter", "set", "part", "refentry", "sect1"}, root.name) && root.child("title", "info", "bookinfo", "articleinfo") != nil:
+		return XMLProfileDocBook
+	}
+	return XMLProfileAuto
+}
+
+// xmlBodyRoles maps element names to the block roles used when rendering a document body.
+type xmlBodyRoles struct {
+	sections   []string
+	titles     []string
+	paragraphs []string
+	listItems  []string
+	code       []string
+	tables     []string
+	rows       []string
+	cells      []string
+	// skip lists elements whose content is reported elsewhere (headers, bibliographies).
+	skip []string
+}
+
+var xmlProfileRoles = map[XMLProfile]xmlBodyRoles{
+	XMLProfileDocBook: {
+		sections:   []string{"set", "book", "part", "chapter", "appendix", "preface", "article", "section", "sect1", "sect2", "sect3", "sect4", "sect5", "simplesect", "refentry", "refsect1", "refsect2", "refsect3", "glossary"},
+		titles:     []string{"title"},
+		paragraphs: []string{"para", "simpara", "literallayout", "blockquote", "glossterm", "glossdef"},
+		listItems:  []string{"listitem"},
+		code:       []string{"programlisting", "screen", "synopsis"},
+		tables:     []string{"table", "informaltable"},
+		rows:       []string{"row", "tr"},
+		cells:      []string{"entry", "td", "th"},
+		skip:       []string{"info", "bookinfo", "articleinfo", "chapterinfo", "sectioninfo", "titleabbrev", "subtitle", "bibliography", "indexterm", "index", "remark"},
+	},
+	XMLProfileTEI: {
+		sections:   []string{"div", "div1", "div2", "div3", "div4", "div5", "div6", "div7"},
+		titles:     []string{"head"},
+		paragraphs: []string{"p", "ab", "l", "quote", "sp"},
+		listItems:  []string{"item"},
+		code:       []string{"code", "eg"},
+		tables:     []string{"table"},
+		rows:       []string{"row"},
+		cells:      []string{"cell"},
+		skip:       []string{"teiHeader", "listBibl", "facsimile", "pb", "lb"},
+	},
+	XMLProfileJATS: {
+		sections:   []string{"sec", "app", "ack", "boxed-text"},
+		titles:     []string{"title"},
+		paragraphs: []string{"p", "disp-quote", "disp-formula", "def", "term"},
+		listItems:  []string{"list-item"},
+		code:       []string{"code", "preformat"},
+		tables:     []string{"table"},
+		rows:       []string{"tr"},
+		cells:      []string{"td", "th"},
+		skip:       []string{"front", "ref-list", "label", "fn-group", "object-id", "alternatives"},
+	},
+}
+
+// xmlRenderer renders a profiled document to Markdown while collecting its structure.
+type xmlRenderer struct {
+	roles  xmlBodyRoles
+	doc    *XMLDocument
+	blocks []string
+	tables []Table
+}
+
+func (r *xmlRenderer) heading(level int, title, id string) {
+	if title == "" {
+		return
+	}
+	r.blocks = append(r.blocks, strings.Repeat("#", min(level+1, xmlMaxLevel))+" "+title)
+	r.doc.Sections = append(r.doc.Sections, XMLSection{Level: level, Title: title, ID: id})
+}
+
+// body renders the block content of e; level is the heading depth of sections found inside it.
+func (r *xmlRenderer) body(e *xmlElem, level int) {
+	for _, c := range e.elements() {
+		switch {
+		case containsString(r.roles.skip, c.name) || containsString(r.roles.titles, c.name):
+		case containsString(r.roles.sections, c.name):
+			title := c.child(r.roles.titles...)
+			if title == nil {
+				if info := c.child("info"); info != nil {
+					title = info.child(r.roles.titles...)
+				}
+			}
+			if heading := title.text(); heading != "" {
+				r.heading(level, heading, c.id())
+				r.body(c, level+1)
+			} else {
+				r.body(c, level)
+			}
+		case containsString(r.roles.paragraphs, c.name):
+			if text := c.text(); text != "" {
+				r.blocks = append(r.blocks, text)
+			}
+		case containsString(r.roles.listItems, c.name):
+			if text := c.text(); text != "" {
+				r.blocks = append(r.blocks, "- "+text)
+			}
+		case containsString(r.roles.code, c.name):
+			r.blocks = append(r.blocks, "```\n"+strings.Trim(c.rawText(), "\n")+"\n```")
+		case containsString(r.roles.tables, c.name):
+			r.table(c)
+		default:
+			r.body(c, level)
+		}
+	}
+}
+
+// rawText returns the character data of e without whitespace normalization, for code blocks.
+func (e *xmlElem) rawText() string {
+	if e == nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, n := range e.nodes {
+		if n.elem != nil {
+			b.WriteString(n.elem.rawText())
+		} else {
+			b.WriteString(n.text)
+		}
+	}
+	return b.String()
+}
+
+func (r *xmlRenderer) table(e *xmlElem) {
+	var rows [][]string
+	var collect func(*xmlElem)
+	collect = func(el *xmlElem) {
+		for _, c := range el.elements() {
+			if !containsString(r.roles.rows, c.name) {
+				collect(c)
+				continue
+			}
+			var row []string
+			for _, cell := range c.elements() {
+				if containsString(r.roles.cells, cell.name) {
+					row = append(row, cell.text())
+				}
+			}
+			rows = append(rows, row)
+		}
+	}
+	collect(e)
+	if len(rows) == 0 {
+		return
+	}
+	markdown := spreadsheetMarkdown(rows)
+	r.tables = append(r.tables, Table{Cells: rows, Markdown: markdown, PageNumber: 1})
+	r.blocks = append(r.blocks, strings.TrimRight(markdown, "\n"))
+}
+
+// listSection renders a titled list of entries, such as keywords or references.
+func (r *xmlRenderer) listSection(title string, items []string, ordered bool) {
+	if len(items) == 0 {
+		return
+	}
+	lines := make([]string, len(items))
+	for i, item := range items {
+		if ordered {
+			lines[i] = fmt.Sprintf("%d. %s", i+1, item)
+		} else {
+			lines[i] = "- " + item
+		}
+	}
+	r.blocks = append(r.blocks, "## "+title, strings.Join(lines, "\n"))
+}
+
+// front renders the document title, authors and abstract.
+func (r *xmlRenderer) front() {
+	if r.doc.Title != "" {
+		r.blocks = append(r.blocks, "# "+r.doc.Title)
+	}
+	if len(r.doc.Authors) > 0 {
+		names := make([]string, len(r.doc.Authors))
+		for i, a := range r.doc.Authors {
+			names[i] = a.Name
+		}
+		r.blocks = append(r.blocks, strings.Join(names, ", "))
+	}
+	if r.doc.Abstract != "" {
+		r.blocks = append(r.blocks, "## Abstract", r.doc.Abstract)
+	}
+	if len(r.doc.Keywords) > 0 {
+		r.blocks = append(r.blocks, "Keywords: "+strings.Join(r.doc.Keywords, ", "))
+	}
+}
+
+func (r *xmlRenderer) references() {
+	items := make([]string, len(r.doc.References))
+	for i, ref := range r.doc.References {
+		items[i] = ref.Text
+	}
+	r.listSection("References", items, true)
+}
+
+func joinNonEmpty(sep string, parts ...string) string {
+	var kept []string
+	for _, p := range parts {
+		if p != "" {
+			kept = append(kept, p)
+		}
+	}
+	return strings.Join(kept, sep)
+}
+
+func renderDocBook(root *xmlElem, r *xmlRenderer) {
+	doc := r.doc
+	info := root.child("info", root.name+"info", "bookinfo", "articleinfo")
+	doc.Title = root.child("title").text()
+	if doc.Title == "" {
+		doc.Title = info.child("title").text()
+	}
+	doc.Language = root.attr("xml:lang")
+	if doc.Language == "" {
+		doc.Language = root.attr("lang")
+	}
+
+	holders := []*xmlElem{root, info}
+	if group := info.child("authorgroup"); group != nil {
+		holders = append(holders, group)
+	}
+	for _, holder := range holders {
+		for _, a := range holder.elements() {
+			if a.name != "author" && a.name != "editor" {
+				continue
+			}
+			name := a.child("personname").text()
+			if name == "" {
+				name = joinNonEmpty(" ", a.child("honorific").text(), a.child("firstname").text(), a.child("surname").text())
+			}
+			if name == "" {
+				name = a.child("orgname").text()
+			}
+			doc.Authors = append(doc.Authors, XMLAuthor{Name: name, Affiliation: a.child("affiliation").text(), Email: a.child("email").text()})
+		}
+	}
+	doc.Date = info.child("pubdate", "date").text()
+	doc.Abstract = info.child("abstract").text("title")
+	for _, k := range info.child("keywordset").childrenNamed("keyword") {
+		doc.Keywords = append(doc.Keywords, k.text())
+	}
+	for _, id := range info.elements() {
+		switch id.name {
+		case "biblioid":
+			doc.Identifiers[strings.ToLower(joinNonEmpty("", id.attr("class"), id.attr("otherclass")))] = id.text()
+		case "isbn", "issn":
+			doc.Identifiers[id.name] = id.text()
+		}
+	}
+	for _, entry := range append(root.descendants("biblioentry"), root.descendants("bibliomixed")...) {
+		doc.References = append(doc.References, XMLReference{ID: entry.id(), Label: entry.child("abbrev").text(), Text: entry.text("abbrev")})
+	}
+
+	r.front()
+	r.body(root, 1)
+	r.references()
+}
+
+func renderTEI(root *xmlElem, r *xmlRenderer) {
+	doc := r.doc
+	header := root.child("teiHeader")
+	titleStmt := header.find("fileDesc/titleStmt")
+	doc.Title = titleStmt.child("title").text()
+	for _, a := range titleStmt.childrenNamed("author") {
+		name := a.child("persName", "name").text()
+		if name == "" {
+			name = a.text("affiliation", "email")
+		}
+		doc.Authors = append(doc.Authors, XMLAuthor{Name: name, Affiliation: a.child("affiliation").text(), Email: a.child("email").text()})
+	}
+	publication := header.find("fileDesc/publicationStmt")
+	if date := publication.child("date"); date != nil {
+		doc.Date = date.attr("when")
+		if doc.Date == "" {
+			doc.Date = date.text()
+		}
+	}
+	for _, id := range publication.childrenNamed("idno") {
+		kind := strings.ToLower(id.attr("type"))
+		if kind == "" {
+			kind = "idno"
+		}
+		doc.Identifiers[kind] = id.text()
+	}
+	doc.Source = header.find("fileDesc/sourceDesc/bibl").text()
+	profile := header.child("profileDesc")
+	if lang := profile.find("langUsage/language"); lang != nil {
+		doc.Language = lang.attr("ident")
+	}
+	if doc.Language == "" {
+		doc.Language = root.child("text").attr("xml:lang")
+	}
+	doc.Abstract = profile.child("abstract").text("head")
+	for _, term := range profile.find("textClass/keywords").descendants("term") {
+		doc.Keywords = append(doc.Keywords, term.text())
+	}
+	for _, list := range root.descendants("listBibl") {
+		for _, entry := range list.elements() {
+			if entry.name == "bibl" || entry.name == "biblStruct" {
+				ref := XMLReference{ID: entry.id(), Text: entry.text()}
+				for _, id := range entry.descendants("idno") {
+					switch strings.ToLower(id.attr("type")) {
+					case "doi":
+						ref.DOI = id.text()
+					case "pmid":
+						ref.PMID = id.text()
+					}
+				}
+				doc.References = append(doc.References, ref)
+			}
+		}
+	}
+
+	r.front()
+	r.body(root, 1)
+	r.references()
+}
+
+func renderJATS(root *xmlElem, r *xmlRenderer) {
+	doc := r.doc
+	front := root.child("front")
+	meta := front.child("article-meta")
+	doc.Title = meta.find("title-group/article-title").text()
+	doc.Language = root.attr("xml:lang")
+	doc.Source = front.find("journal-meta/journal-title-group/journal-title").text()
+	if doc.Source == "" {
+		doc.Source = front.find("journal-meta/journal-title").text()
+	}
+	for _, id := range meta.childrenNamed("article-id") {
+		doc.Identifiers[id.attr("pub-id-type")] = id.text()
+	}
+
+	affiliations := map[string]string{}
+	for _, aff := range append(meta.descendants("aff"), front.childrenNamed("aff")...) {
+		affiliations[aff.id()] = aff.text("label")
+	}
+	for _, contrib := range meta.descendants("contrib") {
+		if kind := contrib.attr("contrib-type"); kind != "" && kind != "author" {
+			continue
+		}
+		name := contrib.child("name")
+		author := XMLAuthor{
+			Name:  joinNonEmpty(" ", name.child("given-names").text(), name.child("surname").text()),
+			Email: contrib.descendantText("email"),
+		}
+		if author.Name == "" {
+			author.Name = contrib.child("string-name", "collab").text()
+		}
+		if aff := contrib.child("aff"); aff != nil {
+			author.Affiliation = aff.text("label")
+		}
+		for _, xref := range contrib.childrenNamed("xref") {
+			if rids := strings.Fields(xref.attr("rid")); xref.attr("ref-type") == "aff" && author.Affiliation == "" && len(rids) > 0 {
+				author.Affiliation = affiliations[rids[0]]
+			}
+		}
+		doc.Authors = append(doc.Authors, author)
+	}
+
+	dates := meta.childrenNamed("pub-date")
+	for _, d := range dates {
+		if t := d.attr("pub-type") + d.attr("date-type"); t == "epub" || t == "pub" {
+			dates = []*xmlElem{d}
+			break
+		}
+	}
+	if len(dates) > 0 {
+		d := dates[0]
+		doc.Date = joinNonEmpty("-", d.child("year").text(), zeroPad(d.child("month").text()), zeroPad(d.child("day").text()))
+	}
+	if abstract := meta.child("abstract"); abstract != nil {
+		doc.Abstract = abstract.text("title", "label")
+	}
+	for _, kwd := range meta.descendants("kwd") {
+		doc.Keywords = append(doc.Keywords, kwd.text())
+	}
+	for _, ref := range root.child("back").descendants("ref") {
+		citation := ref.child("mixed-citation", "element-citation", "citation", "nlm-citation")
+		entry := XMLReference{ID: ref.id(), Label: ref.child("label").text(), Text: citation.text()}
+		for _, id := range citation.descendants("pub-id") {
+			switch id.attr("pub-id-type") {
+			case "doi":
+				entry.DOI = id.text()
+			case "pmid":
+				entry.PMID = id.text()
+			}
+		}
+		doc.References = append(doc.References, entry)
+	}
+
+	r.front()
+	r.body(root, 1)
+	r.references()
+}
+
+// descendantText returns the text of the first descendant named name.
+func (e *xmlElem) descendantText(name string) string {
+	if found := e.descendants(name); len(found) > 0 {
+		return found[0].text()
+	}
+	return ""
+}
+
+func zeroPad(s string) string {
+	if len(s) == 1 {
+		return "0" + s
+	}
+	return s
+}
+
+func renderPubMed(root *xmlElem, r *xmlRenderer) {
+	var articles []*xmlElem
+	switch root.name {
+	case "PubmedArticle":
+		articles = []*xmlElem{root}
+	case "MedlineCitation":
+		// A bare citation is wrapped so it reads like a PubmedArticle without PubmedData.
+		articles = []*xmlElem{{name: "PubmedArticle", nodes: []xmlNode{{elem: root}}}}
+	default:
+		articles = root.descendants("PubmedArticle")
+	}
+
+	for i, article := range articles {
+		citation := article.child("MedlineCitation")
+		art := citation.child("Article")
+		title := art.child("ArticleTitle").text()
+
+		var authors []XMLAuthor
+		for _, a := range art.find("AuthorList").childrenNamed("Author") {
+			name := joinNonEmpty(" ", a.child("ForeName").text(), a.child("LastName").text())
+			if name == "" {
+				name = a.child("CollectiveName").text()
+			}
+			authors = append(authors, XMLAuthor{Name: name, Affiliation: a.find("AffiliationInfo/Affiliation").text()})
+		}
+		var abstractParts []string
+		for _, part := range art.find("Abstract").childrenNamed("AbstractText") {
+			abstractParts = append(abstractParts, joinNonEmpty(": ", part.attr("Label"), part.text()))
+		}
+		var keywords []string
+		for _, k := range citation.descendants("Keyword") {
+			keywords = append(keywords, k.text())
+		}
+
+		if i == 0 {
+			doc := r.doc
+			doc.Title, doc.Authors, doc.Abstract, doc.Keywords = title, authors, strings.Join(abstractParts, "\n\n"), keywords
+			doc.Source = art.find("Journal/Title").text()
+			doc.Language = art.child("Language").text()
+			pubDate := art.find("Journal/JournalIssue/PubDate")
+			doc.Date = joinNonEmpty(" ", pubDate.child("Year").text(), pubDate.child("Month").text(), pubDate.child("Day").text())
+			if doc.Date == "" {
+				doc.Date = pubDate.child("MedlineDate").text()
+			}
+			doc.Identifiers["pmid"] = citation.child("PMID").text()
+			for _, id := range article.find("PubmedData/ArticleIdList").childrenNamed("ArticleId") {
+				doc.Identifiers[id.attr("IdType")] = id.text()
+			}
+			for _, ref := range article.find("PubmedData/ReferenceList").childrenNamed("Reference") {
+				entry := XMLReference{Text: ref.child("Citation").text()}
+				for _, id := range ref.find("ArticleIdList").childrenNamed("ArticleId") {
+					switch id.attr("IdType") {
+					case "doi":
+						entry.DOI = id.text()
+					case "pubmed":
+						entry.PMID = id.text()
+					}
+				}
+				doc.References = append(doc.References, entry)
+			}
+		}
+
+		if len(articles) == 1 {
+			r.front()
+		} else {
+			r.heading(1, title, citation.child("PMID").text())
+			names := make([]string, len(authors))
+			for j, a := range authors {
+				names[j] = a.Name
+			}
+			if len(names) > 0 {
+				r.blocks = append(r.blocks, strings.Join(names, ", "))
+			}
+			if len(abstractParts) > 0 {
+				r.blocks = append(r.blocks, strings.Join(abstractParts, "\n\n"))
+			}
+		}
+	}
+	if len(articles) > 1 {
+		r.doc.Title, r.doc.Authors, r.doc.Abstract, r.doc.Keywords = "", nil, "", nil
+	}
+	r.references()
+}
+
+var xmlProfileRenderers = map[XMLProfile]func(*xmlElem, *xmlRenderer){
+	XMLProfileDocBook: renderDocBook,
+	XMLProfileTEI:     renderTEI,
+	XMLProfileJATS:    renderJATS,
+	XMLProfilePubMed:  renderPubMed,
+}
+
+// extractXMLProfile renders DocBook, TEI, JATS and PubMed documents semantically and feeds as
+// entries; other XML is passed to the native extractor.
+func extractXMLProfile(src documentSource, mimeType string, config *ExtractionConfig) (*ExtractionResult, error) {
+	data, err := src.bytes()
+	if err != nil {
+		return nil, err
+	}
+	root, count, names, err := parseXMLTree(data)
+	if err != nil {
+		return nil, newParsingErrorWithContext("failed to parse XML document", err, ErrorCodeParsing, nil)
+	}
+
+	profile := config.XMLProfile.Profile
+	if profile == XMLProfileAuto {
+		profile = detectXMLProfile(root)
+	}
+	render, ok := xmlProfileRenderers[profile]
+	if !ok {
+		if profile != XMLProfileAuto {
+			return nil, newValidationErrorWithContext(fmt.Sprintf("unknown XML profile %q", profile), nil, ErrorCodeValidation, nil)
+		}
+		if feed := parseFeed(root); feed != nil {
+			return feedResult(feed, mimeType, config)
+		}
+		if src.path != "" {
+			return extractFileNative(src.path, config)
+		}
+		return extractBytesNative(src.data, mimeType, config)
+	}
+
+	doc := &XMLDocument{Profile: profile, Identifiers: map[string]string{}}
+	r := &xmlRenderer{roles: xmlProfileRoles[profile], doc: doc, tables: []Table{}}
+	render(root, r)
+	for key, value := range doc.Identifiers {
+		if key == "" || value == "" {
+			delete(doc.Identifiers, key)
+		}
+	}
+
+	result := &ExtractionResult{
+		MimeType: mimeType,
+		Content:  strings.Join(r.blocks, "\n\n"),
+		Tables:   r.tables,
+		Success:  true,
+	}
+	result.Metadata.Format = FormatMetadata{
+		Type: FormatXML,
+		XML:  &XMLMetadata{ElementCount: count, UniqueElements: names, Document: doc},
+	}
+	if doc.Language != "" {
+		result.Metadata.Language = stringPtr(doc.Language)
+	}
+	if doc.Date != "" {
+		result.Metadata.Date = stringPtr(doc.Date)
+	}
+	return result, nil
+}
+
+// MarshalJSON omits empty identifier maps so documents without identifiers stay compact.
+func (d XMLDocument) MarshalJSON() ([]byte, error) {
+	type plain XMLDocument
+	if len(d.Identifiers) == 0 {
+		d.Identifiers = nil
+	}
+	return json.Marshal(plain(d))
+}
+
+func init() {
+	registerGoPrimaryExtractor(goPrimaryExtractor{
+		name:      "xml-profiles",
+		mimeTypes: []string{mimeXML, "text/xml", "application/docbook+xml", "application/tei+xml", "application/jats+xml"},
+		extensions: map[string]string{
+			"xml":  mimeXML,
+			"dbk":  "application/docbook+xml",
+			"tei":  "application/tei+xml",
+			"nxml": "application/jats+xml",
+		},
+		enabled: func(config *ExtractionConfig) bool {
+			return config != nil && config.XMLProfile != nil
+		},
+		extract: extractXMLProfile,
+	})
+}
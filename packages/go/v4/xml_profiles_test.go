@@ -0,0 +1,224 @@
+package kreuzberg
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const testDocBook = `<?xml version="1.0" encoding="UTF-8"?>
+<book xmlns="http://docbook.org/ns/docbook" version="5.0" xml:lang="en">
+  <info>
+    <title>Field Guide</title>
+    <author><personname><firstname>Ada</firstname> <surname>Lovelace</surname></personname><email>ada@example.org</email></author>
+    <pubdate>2024-03-01</pubdate>
+    <biblioid class="isbn">978-0-00-000000-0</biblioid>
+    <keywordset><keyword>birds</keyword><keyword>maps</keyword></keywordset>
+  </info>
+  <chapter xml:id="intro">
+    <title>Introduction</title>
+    <para>Birds are <emphasis>everywhere</emphasis>.</para>
+    <itemizedlist><listitem><para>Sparrows</para></listitem></itemizedlist>
+    <section>
+      <title>Equipment</title>
+      <programlisting>binoculars --zoom 8</programlisting>
+      <informaltable><tgroup cols="2"><tbody>
+        <row><entry>Item</entry><entry>Weight</entry></row>
+        <row><entry>Scope</entry><entry>1 kg</entry></row>
+      </tbody></tgroup></informaltable>
+    </section>
+  </chapter>
+  <bibliography><biblioentry xml:id="sibley"><abbrev>Sib00</abbrev><title>The Sibley Guide</title></biblioentry></bibliography>
+</book>`
+
+const testTEI = `<TEI xmlns="http://www.tei-c.org/ns/1.0">
+  <teiHeader>
+    <fileDesc>
+      <titleStmt><title>Parish Register of St Mary</title><author><persName>J. Smith</persName></author></titleStmt>
+      <publicationStmt><date when="1851-06-30">1851</date><idno type="DOI">10.1000/tei</idno></publicationStmt>
+      <sourceDesc><bibl>County archive, box 12</bibl></sourceDesc>
+    </fileDesc>
+    <profileDesc><langUsage><language ident="en-GB">English</language></langUsage></profileDesc>
+  </teiHeader>
+  <text>
+    <body>
+      <div type="baptisms"><head>Baptisms</head><p>John, son of <persName>William</persName>.</p>
+        <div><head>1851</head><list><item>June</item></list></div>
+      </div>
+    </body>
+    <back><listBibl><bibl xml:id="b1">Census 1851 <idno type="DOI">10.1000/census</idno></bibl></listBibl></back>
+  </text>
+</TEI>`
+
+const testJATS = `<!DOCTYPE article PUBLIC "-//NLM//DTD JATS (Z39.96) Journal Publishing DTD v1.2 20190208//EN" "JATS-journalpublishing1.dtd">
+<article article-type="research-article" xml:lang="en">
+  <front>
+    <journal-meta><journal-title-group><journal-title>J Test Sci</journal-title></journal-title-group></journal-meta>
+    <article-meta>
+      <article-id pub-id-type="doi">10.1000/jts.1</article-id>
+      <article-id pub-id-type="pmid">123</article-id>
+      <title-group><article-title>Measuring &ndash; things</article-title></title-group>
+      <contrib-group>
+        <contrib contrib-type="author"><name><surname>Curie</surname><given-names>Marie</given-names></name><xref ref-type="aff" rid="aff1"/></contrib>
+        <contrib contrib-type="editor"><name><surname>Editor</surname></name></contrib>
+        <aff id="aff1"><label>1</label>Sorbonne</aff>
+      </contrib-group>
+      <pub-date pub-type="epub"><day>5</day><month>2</month><year>2020</year></pub-date>
+      <abstract><title>Abstract</title><p>We measured things.</p></abstract>
+      <kwd-group><kwd>measurement</kwd></kwd-group>
+    </article-meta>
+  </front>
+  <body>
+    <sec id="s1"><title>Methods</title><p>Carefully.</p>
+      <table-wrap><table><tr><th>A</th></tr><tr><td>1</td></tr></table></table-wrap>
+    </sec>
+  </body>
+  <back>
+    <ref-list><ref id="r1"><label>1</label><mixed-citation>Smith J. Prior work. <pub-id pub-id-type="pmid">42</pub-id></mixed-citation></ref></ref-list>
+  </back>
+</article>`
+
+const testPubMed = `<PubmedArticleSet><PubmedArticle>
+  <MedlineCitation><PMID>999</PMID>
+    <Article>
+      <Journal><Title>Lancet</Title><JournalIssue><PubDate><Year>2021</Year><Month>Jan</Month></PubDate></JournalIssue></Journal>
+      <ArticleTitle>A trial.</ArticleTitle>
+      <Abstract><AbstractText Label="BACKGROUND">Why.</AbstractText><AbstractText Label="RESULTS">It worked.</AbstractText></Abstract>
+      <AuthorList><Author><LastName>Doe</LastName><ForeName>Jane</ForeName><AffiliationInfo><Affiliation>Oxford</Affiliation></AffiliationInfo></Author></AuthorList>
+      <Language>eng</Language>
+    </Article>
+  </MedlineCitation>
+  <PubmedData><ArticleIdList><ArticleId IdType="doi">10.1/x</ArticleId></ArticleIdList>
+    <ReferenceList><Reference><Citation>Old trial.</Citation><ArticleIdList><ArticleId IdType="pubmed">7</ArticleId></ArticleIdList></Reference></ReferenceList>
+  </PubmedData>
+</PubmedArticle></PubmedArticleSet>`
+
+func extractTestXML(t *testing.T, data string) (*ExtractionResult, *XMLDocument) {
+	t.Helper()
+	result, err := extractXMLProfile(documentSource{data: []byte(data)}, mimeXML, &ExtractionConfig{XMLProfile: &XMLProfileConfig{}})
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if result.Metadata.Format.XML == nil || result.Metadata.Format.XML.Document == nil {
+		t.Fatalf("missing XML document metadata: %+v", result.Metadata.Format)
+	}
+	return result, result.Metadata.Format.XML.Document
+}
+
+func TestXMLProfileDocBook(t *testing.T) {
+	result, doc := extractTestXML(t, testDocBook)
+	if doc.Profile != XMLProfileDocBook || doc.Title != "Field Guide" || doc.Language != "en" || doc.Date != "2024-03-01" {
+		t.Fatalf("unexpected document: %+v", doc)
+	}
+	if len(doc.Authors) != 1 || doc.Authors[0] != (XMLAuthor{Name: "Ada Lovelace", Email: "ada@example.org"}) {
+		t.Fatalf("unexpected authors: %+v", doc.Authors)
+	}
+	wantSections := []XMLSection{{Level: 1, Title: "Introduction", ID: "intro"}, {Level: 2, Title: "Equipment"}}
+	if len(doc.Sections) != 2 || doc.Sections[0] != wantSections[0] || doc.Sections[1] != wantSections[1] {
+		t.Fatalf("unexpected sections: %+v", doc.Sections)
+	}
+	if doc.Identifiers["isbn"] != "978-0-00-000000-0" || len(doc.References) != 1 || doc.References[0].Label != "Sib00" || doc.References[0].Text != "The Sibley Guide" {
+		t.Fatalf("unexpected identifiers or references: %+v %+v", doc.Identifiers, doc.References)
+	}
+	for _, want := range []string{"# Field Guide", "## Introduction", "Birds are everywhere.", "- Sparrows", "### Equipment", "```\nbinoculars --zoom 8\n```", "| Scope | 1 kg |", "## References\n\n1. The Sibley Guide"} {
+		if !strings.Contains(result.Content, want) {
+			t.Fatalf("content missing %q:\n%s", want, result.Content)
+		}
+	}
+	if len(result.Tables) != 1 || result.Tables[0].Cells[1][0] != "Scope" {
+		t.Fatalf("unexpected tables: %+v", result.Tables)
+	}
+	if result.Metadata.Date == nil || *result.Metadata.Date != "2024-03-01" {
+		t.Fatalf("document date not promoted: %v", result.Metadata.Date)
+	}
+}
+
+func TestXMLProfileTEI(t *testing.T) {
+	result, doc := extractTestXML(t, testTEI)
+	if doc.Profile != XMLProfileTEI || doc.Title != "Parish Register of St Mary" || doc.Date != "1851-06-30" || doc.Language != "en-GB" || doc.Source != "County archive, box 12" {
+		t.Fatalf("unexpected document: %+v", doc)
+	}
+	if len(doc.Authors) != 1 || doc.Authors[0].Name != "J. Smith" || doc.Identifiers["doi"] != "10.1000/tei" {
+		t.Fatalf("unexpected authors or identifiers: %+v %v", doc.Authors, doc.Identifiers)
+	}
+	if len(doc.Sections) != 2 || doc.Sections[1] != (XMLSection{Level: 2, Title: "1851"}) {
+		t.Fatalf("unexpected sections: %+v", doc.Sections)
+	}
+	if len(doc.References) != 1 || doc.References[0].ID != "b1" || doc.References[0].DOI != "10.1000/census" {
+		t.Fatalf("unexpected references: %+v", doc.References)
+	}
+	if strings.Contains(result.Content, "County archive") || !strings.Contains(result.Content, "## Baptisms\n\nJohn, son of William.\n\n### 1851\n\n- June") {
+		t.Fatalf("unexpected content:\n%s", result.Content)
+	}
+}
+
+func TestXMLProfileJATS(t *testing.T) {
+	result, doc := extractTestXML(t, testJATS)
+	if doc.Profile != XMLProfileJATS || doc.Title != "Measuring – things" || doc.Source != "J Test Sci" || doc.Date != "2020-02-05" {
+		t.Fatalf("unexpected document: %+v", doc)
+	}
+	if len(doc.Authors) != 1 || doc.Authors[0] != (XMLAuthor{Name: "Marie Curie", Affiliation: "Sorbonne"}) {
+		t.Fatalf("unexpected authors: %+v", doc.Authors)
+	}
+	if doc.Abstract != "We measured things." || doc.Identifiers["doi"] != "10.1000/jts.1" || len(doc.Keywords) != 1 {
+		t.Fatalf("unexpected front matter: %+v", doc)
+	}
+	if len(doc.References) != 1 || doc.References[0] != (XMLReference{ID: "r1", Label: "1", Text: "Smith J. Prior work. 42", PMID: "42"}) {
+		t.Fatalf("unexpected references: %+v", doc.References)
+	}
+	if !strings.Contains(result.Content, "## Methods\n\nCarefully.") || strings.Contains(result.Content, "Sorbonne") {
+		t.Fatalf("unexpected content:\n%s", result.Content)
+	}
+	if len(result.Tables) != 1 {
+		t.Fatalf("expected one table, got %+v", result.Tables)
+	}
+}
+
+func TestXMLProfilePubMed(t *testing.T) {
+	result, doc := extractTestXML(t, testPubMed)
+	if doc.Profile != XMLProfilePubMed || doc.Title != "A trial." || doc.Source != "Lancet" || doc.Date != "2021 Jan" || doc.Language != "eng" {
+		t.Fatalf("unexpected document: %+v", doc)
+	}
+	if doc.Identifiers["pmid"] != "999" || doc.Identifiers["doi"] != "10.1/x" {
+		t.Fatalf("unexpected identifiers: %v", doc.Identifiers)
+	}
+	if doc.Abstract != "BACKGROUND: Why.\n\nRESULTS: It worked." || doc.Authors[0] != (XMLAuthor{Name: "Jane Doe", Affiliation: "Oxford"}) {
+		t.Fatalf("unexpected front matter: %+v", doc)
+	}
+	if len(doc.References) != 1 || doc.References[0].PMID != "7" || !strings.Contains(result.Content, "1. Old trial.") {
+		t.Fatalf("unexpected references: %+v\n%s", doc.References, result.Content)
+	}
+}
+
+func TestXMLProfileMetadataJSON(t *testing.T) {
+	result, _ := extractTestXML(t, testTEI)
+	encoded, err := json.Marshal(result.Metadata)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var decoded Metadata
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	xmlMeta, ok := decoded.XMLMetadata()
+	if !ok || xmlMeta.Document == nil || xmlMeta.Document.Title != "Parish Register of St Mary" || xmlMeta.ElementCount == 0 {
+		t.Fatalf("document metadata did not round-trip: %s", encoded)
+	}
+}
+
+func TestXMLProfileRouting(t *testing.T) {
+	if extractor, _ := selectGoPrimaryExtractor(documentSource{path: "article.nxml"}, nil); extractor != nil && extractor.name == "xml-profiles" {
+		t.Fatalf("xml profiles should be opt-in")
+	}
+	config := &ExtractionConfig{XMLProfile: &XMLProfileConfig{}}
+	extractor, mimeType := selectGoPrimaryExtractor(documentSource{path: "article.nxml"}, config)
+	if extractor == nil || extractor.name != "xml-profiles" || mimeType != "application/jats+xml" {
+		t.Fatalf("unexpected routing: %v %q", extractor, mimeType)
+	}
+	if _, err := extractXMLProfile(documentSource{data: []byte("<a/>")}, mimeXML, &ExtractionConfig{XMLProfile: &XMLProfileConfig{Profile: "odf"}}); err == nil {
+		t.Fatalf("expected error for unknown profile")
+	}
+	if detectXMLProfile(&xmlElem{name: "catalog"}) != XMLProfileAuto {
+		t.Fatalf("generic XML should not match a profile")
+	}
+}
@@ -0,0 +1,332 @@
+package kreuzberg
+
+import (
+	"bytes"
+	"cmp"
+	"compress/zlib"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Namespaces of commonly used XMP schemas.
+const (
+	XMPNamespaceDC        = "http://purl.org/dc/elements/1.1/"
+	XMPNamespaceXMP       = "http://ns.adobe.com/xap/1.0/"
+	XMPNamespaceXMPRights = "http://ns.adobe.com/xap/1.0/rights/"
+	XMPNamespaceXMPMM     = "http://ns.adobe.com/xap/1.0/mm/"
+	XMPNamespacePDF       = "http://ns.adobe.com/pdf/1.3/"
+	XMPNamespacePhotoshop = "http://ns.adobe.com/photoshop/1.0/"
+	XMPNamespaceIPTCCore  = "http://iptc.org/std/Iptc4xmpCore/1.0/xmlns/"
+
+	xmpNamespaceRDF = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	xmlNamespace    = "http://www.w3.org/XML/1998/namespace"
+)
+
+// maxXMPStreamSize bounds the inflated size of a compressed PDF metadata stream.
+const maxXMPStreamSize = 16 << 20
+
+// XMPPacket is a parsed XMP packet. Properties are keyed by namespace URI, since prefixes are
+// chosen freely by each writer, and then by property name.
+type XMPPacket struct {
+	// Namespaces maps the prefixes declared in the packet to namespace URIs.
+	Namespaces map[string]string `json:"namespaces"`
+	// Properties maps namespace URI and property name to the property value.
+	Properties map[string]map[string]XMPValue `json:"properties"`
+}
+
+// XMPValue is the value of an XMP property: a simple value, an array (Items) or a structure
+// (Fields).
+type XMPValue struct {
+	// Value is the text of a simple value, or the URI of a resource reference.
+	Value string `json:"value,omitempty"`
+	// Lang is the xml:lang qualifier, used by language alternatives.
+	Lang string `json:"lang,omitempty"`
+	// ArrayType is "Seq", "Bag" or "Alt" for arrays.
+	ArrayType string `json:"array_type,omitempty"`
+	// Items are the array items.
+	Items []XMPValue `json:"items,omitempty"`
+	// Fields maps namespace URI and field name to the fields of a structure.
+	Fields map[string]map[string]XMPValue `json:"fields,omitempty"`
+}
+
+// Get returns the property name in namespace.
+func (p *XMPPacket) Get(namespace, name string) (XMPValue, bool) {
+	value, ok := p.Properties[namespace][name]
+	return value, ok
+}
+
+// Text returns a display string for the value: the value itself for simple values, the
+// "x-default" (or first) item of a language alternative, and the items joined by "; " for other
+// arrays. Structures have no text.
+func (v XMPValue) Text() string {
+	switch v.ArrayType {
+	case "":
+		return v.Value
+	case "Alt":
+		for _, item := range v.Items {
+			if item.Lang == "x-default" {
+				return item.Text()
+			}
+		}
+		if len(v.Items) > 0 {
+			return v.Items[0].Text()
+		}
+		return ""
+	default:
+		return strings.Join(v.Strings(), "; ")
+	}
+}
+
+// Strings returns the text of each array item, or the value itself for simple values.
+func (v XMPValue) Strings() []string {
+	if v.ArrayType == "" {
+		if v.Value == "" {
+			return nil
+		}
+		return []string{v.Value}
+	}
+	var out []string
+	for _, item := range v.Items {
+		if text := item.Text(); text != "" {
+			out = append(out, text)
+		}
+	}
+	return out
+}
+
+// XMP returns the XMP packet recorded when ExtractionConfig.XMP was set.
+func (m Metadata) XMP() (*XMPPacket, bool) {
+	var packet XMPPacket
+	if found, err := m.Decode("xmp", &packet); !found || err != nil {
+		return nil, false
+	}
+	return &packet, true
+}
+
+var (
+	xmpPacketStart = regexp.MustCompile(`<x:x[am]pmeta\b`)
+	xmpPacketEnd   = regexp.MustCompile(`</x:x[am]pmeta>`)
+	// pdfMetadataStream matches the dictionary of a Flate-compressed PDF metadata stream.
+	pdfMetadataStream = regexp.MustCompile(`<<((?:[^<>]|<[^<]|>[^>])*/Type\s*/Metadata(?:[^<>]|<[^<]|>[^>])*)>>\s*stream\r?\n`)
+)
+
+// ExtractXMP finds and parses the XMP packets embedded in a document, in document order. Packets
+// are found wherever they are stored uncompressed (JPEG APP1, PNG iTXt, TIFF, WebP, SVG, PDF) and
+// in Flate-compressed PDF metadata streams. Packets that are not well-formed are skipped. In a
+// PDF, the last packet is usually the document metadata after incremental updates.
+func ExtractXMP(data []byte) []*XMPPacket {
+	found := parseXMPPackets(data, 0)
+	if bytes.HasPrefix(data, []byte("%PDF-")) {
+		for _, loc := range pdfMetadataStream.FindAllSubmatchIndex(data, -1) {
+			if !bytes.Contains(data[loc[2]:loc[3]], []byte("/FlateDecode")) {
+				continue
+			}
+			zr, err := zlib.NewReader(bytes.NewReader(data[loc[1]:]))
+			if err != nil {
+				continue
+			}
+			inflated, _ := io.ReadAll(io.LimitReader(zr, maxXMPStreamSize))
+			found = append(found, parseXMPPackets(inflated, loc[1])...)
+		}
+	}
+	slices.SortStableFunc(found, func(a, b offsetXMPPacket) int { return cmp.Compare(a.offset, b.offset) })
+	packets := make([]*XMPPacket, len(found))
+	for i, f := range found {
+		packets[i] = f.packet
+	}
+	return packets
+}
+
+// offsetXMPPacket is a packet with the document offset it was found at.
+type offsetXMPPacket struct {
+	offset int
+	packet *XMPPacket
+}
+
+// parseXMPPackets parses the packets stored uncompressed in data. Offsets are relative to data,
+// or fixed at offset when offset is nonzero (data inflated from a stream).
+func parseXMPPackets(data []byte, offset int) []offsetXMPPacket {
+	var packets []offsetXMPPacket
+	pos := 0
+	for pos < len(data) {
+		start := xmpPacketStart.FindIndex(data[pos:])
+		if start == nil {
+			break
+		}
+		begin := pos + start[0]
+		end := xmpPacketEnd.FindIndex(data[begin:])
+		if end == nil {
+			break
+		}
+		if packet, err := ParseXMP(data[begin : begin+end[1]]); err == nil {
+			at := offset
+			if offset == 0 {
+				at = begin
+			}
+			packets = append(packets, offsetXMPPacket{offset: at, packet: packet})
+		}
+		pos = begin + end[1]
+	}
+	return packets
+}
+
+// xmpNode is an element of a parsed XMP packet.
+type xmpNode struct {
+	name     xml.Name
+	attrs    []xml.Attr
+	children []*xmpNode
+	text     strings.Builder
+}
+
+func (n *xmpNode) attr(space, local string) (string, bool) {
+	for _, a := range n.attrs {
+		if a.Name.Local == local && (a.Name.Space == space || (space == xmlNamespace && a.Name.Space == "xml")) {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// ParseXMP parses one XMP packet (an x:xmpmeta element or a bare rdf:RDF element).
+func ParseXMP(packet []byte) (*XMPPacket, error) {
+	root := &xmpNode{}
+	stack := []*xmpNode{root}
+	namespaces := map[string]string{}
+	dec := xml.NewDecoder(bytes.NewReader(packet))
+	dec.Strict = false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, newParsingErrorWithContext("failed to parse XMP packet", err, ErrorCodeParsing, nil)
+		}
+		top := stack[len(stack)-1]
+		switch t := tok.(type) {
+		case xml.StartElement:
+			node := &xmpNode{name: t.Name, attrs: t.Attr}
+			for _, a := range t.Attr {
+				if a.Name.Space == "xmlns" {
+					namespaces[a.Name.Local] = a.Value
+				}
+			}
+			top.children = append(top.children, node)
+			stack = append(stack, node)
+		case xml.CharData:
+			top.text.Write(t)
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+
+	xmp := &XMPPacket{Namespaces: namespaces, Properties: map[string]map[string]XMPValue{}}
+	var walk func(n *xmpNode)
+	walk = func(n *xmpNode) {
+		if n.name == (xml.Name{Space: xmpNamespaceRDF, Local: "Description"}) {
+			addXMPProperties(xmp.Properties, n)
+			return
+		}
+		for _, child := range n.children {
+			walk(child)
+		}
+	}
+	walk(root)
+	return xmp, nil
+}
+
+// addXMPProperties adds the properties of an rdf:Description, given as attributes or child
+// elements, to props.
+func addXMPProperties(props map[string]map[string]XMPValue, desc *xmpNode) {
+	set := func(name xml.Name, value XMPValue) {
+		if props[name.Space] == nil {
+			props[name.Space] = map[string]XMPValue{}
+		}
+		props[name.Space][name.Local] = value
+	}
+	for _, a := range desc.attrs {
+		if isXMPPropertyAttr(a) {
+			set(a.Name, XMPValue{Value: a.Value})
+		}
+	}
+	for _, child := range desc.children {
+		set(child.name, xmpPropertyValue(child))
+	}
+}
+
+// isXMPPropertyAttr reports whether an attribute is a property rather than RDF syntax, a
+// namespace declaration or a language qualifier.
+func isXMPPropertyAttr(a xml.Attr) bool {
+	switch a.Name.Space {
+	case "", "xmlns", "xml", xmlNamespace, xmpNamespaceRDF:
+		return false
+	}
+	return a.Name.Local != "xmlns"
+}
+
+// xmpPropertyValue interprets a property element (or an rdf:li item).
+func xmpPropertyValue(n *xmpNode) XMPValue {
+	var value XMPValue
+	value.Lang, _ = n.attr(xmlNamespace, "lang")
+	if resource, ok := n.attr(xmpNamespaceRDF, "resource"); ok {
+		value.Value = resource
+		return value
+	}
+	if parseType, _ := n.attr(xmpNamespaceRDF, "parseType"); parseType == "Resource" {
+		value.Fields = map[string]map[string]XMPValue{}
+		addXMPProperties(value.Fields, n)
+		return value
+	}
+	for _, child := range n.children {
+		if child.name.Space != xmpNamespaceRDF {
+			continue
+		}
+		switch child.name.Local {
+		case "Seq", "Bag", "Alt":
+			value.ArrayType = child.name.Local
+			for _, li := range child.children {
+				if li.name == (xml.Name{Space: xmpNamespaceRDF, Local: "li"}) {
+					value.Items = append(value.Items, xmpPropertyValue(li))
+				}
+			}
+			return value
+		case "Description":
+			value.Fields = map[string]map[string]XMPValue{}
+			addXMPProperties(value.Fields, child)
+			return value
+		}
+	}
+	// Shorthand structures carry their fields as attributes.
+	for _, a := range n.attrs {
+		if isXMPPropertyAttr(a) {
+			value.Fields = map[string]map[string]XMPValue{}
+			addXMPProperties(value.Fields, n)
+			return value
+		}
+	}
+	value.Value = strings.TrimSpace(n.text.String())
+	return value
+}
+
+// annotateXMP stores the last XMP packet of data in result.Metadata.Additional["xmp"].
+func annotateXMP(result *ExtractionResult, data []byte) error {
+	packets := ExtractXMP(data)
+	if len(packets) == 0 {
+		return nil
+	}
+	raw, err := json.Marshal(packets[len(packets)-1])
+	if err != nil {
+		return newSerializationErrorWithContext("failed to encode XMP metadata", err, ErrorCodeValidation, nil)
+	}
+	if result.Metadata.Additional == nil {
+		result.Metadata.Additional = map[string]json.RawMessage{}
+	}
+	result.Metadata.Additional["xmp"] = raw
+	return nil
+}
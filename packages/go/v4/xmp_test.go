@@ -0,0 +1,129 @@
+package kreuzberg
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testXMPCustom = `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+<rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:acme="http://example.com/ns/acme/1.0/"
+  xmlns:Iptc4xmpCore="http://iptc.org/std/Iptc4xmpCore/1.0/xmlns/" xmlns:xmpRights="http://ns.adobe.com/xap/1.0/rights/"
+  acme:Project="Apollo" xmpRights:Marked="True">
+<dc:title><rdf:Alt><rdf:li xml:lang="de">Jahresbericht</rdf:li><rdf:li xml:lang="x-default">Annual Report</rdf:li></rdf:Alt></dc:title>
+<dc:creator><rdf:Seq><rdf:li>Ada Lovelace</rdf:li><rdf:li>Charles Babbage</rdf:li></rdf:Seq></dc:creator>
+<acme:Reviewers><rdf:Bag><rdf:li>Grace</rdf:li><rdf:li>Alan</rdf:li></rdf:Bag></acme:Reviewers>
+<acme:Source rdf:resource="https://example.com/reports/2024"/>
+<Iptc4xmpCore:CreatorContactInfo rdf:parseType="Resource">
+  <Iptc4xmpCore:CiAdrCity>London</Iptc4xmpCore:CiAdrCity>
+  <Iptc4xmpCore:CiEmailWork>ada@example.com</Iptc4xmpCore:CiEmailWork>
+</Iptc4xmpCore:CreatorContactInfo>
+<acme:Budget acme:Currency="EUR" acme:Amount="1200"/>
+</rdf:Description></rdf:RDF></x:xmpmeta>`
+
+func TestParseXMPNamespacesAndValueKinds(t *testing.T) {
+	packet, err := ParseXMP([]byte(testXMPCustom))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	const acme = "http://example.com/ns/acme/1.0/"
+	if packet.Namespaces["acme"] != acme || packet.Namespaces["dc"] != XMPNamespaceDC {
+		t.Fatalf("unexpected namespaces: %v", packet.Namespaces)
+	}
+	get := func(namespace, name string) XMPValue {
+		t.Helper()
+		value, ok := packet.Get(namespace, name)
+		if !ok {
+			t.Fatalf("missing %s%s in %+v", namespace, name, packet.Properties)
+		}
+		return value
+	}
+	if title := get(XMPNamespaceDC, "title"); title.ArrayType != "Alt" || len(title.Items) != 2 || title.Text() != "Annual Report" || title.Items[0].Lang != "de" {
+		t.Fatalf("unexpected title: %+v", title)
+	}
+	if creators := get(XMPNamespaceDC, "creator").Strings(); len(creators) != 2 || creators[1] != "Charles Babbage" {
+		t.Fatalf("unexpected creators: %v", creators)
+	}
+	if reviewers := get(acme, "Reviewers"); reviewers.ArrayType != "Bag" || reviewers.Text() != "Grace; Alan" {
+		t.Fatalf("unexpected reviewers: %+v", reviewers)
+	}
+	if project := get(acme, "Project"); project.Value != "Apollo" {
+		t.Fatalf("unexpected attribute property: %+v", project)
+	}
+	if marked := get(XMPNamespaceXMPRights, "Marked"); marked.Value != "True" {
+		t.Fatalf("unexpected rights property: %+v", marked)
+	}
+	if source := get(acme, "Source"); source.Value != "https://example.com/reports/2024" {
+		t.Fatalf("unexpected resource reference: %+v", source)
+	}
+	contact := get(XMPNamespaceIPTCCore, "CreatorContactInfo")
+	if city := contact.Fields[XMPNamespaceIPTCCore]["CiAdrCity"]; city.Value != "London" || contact.Text() != "" {
+		t.Fatalf("unexpected structure: %+v", contact)
+	}
+	if budget := get(acme, "Budget"); budget.Fields[acme]["Currency"].Value != "EUR" || budget.Fields[acme]["Amount"].Value != "1200" {
+		t.Fatalf("unexpected shorthand structure: %+v", budget)
+	}
+	if _, ok := packet.Properties[xmpNamespaceRDF]; ok {
+		t.Fatalf("RDF syntax attributes reported as properties: %+v", packet.Properties[xmpNamespaceRDF])
+	}
+
+	if _, err := ParseXMP([]byte(`<x:xmpmeta><rdf:RDF>`)); err == nil {
+		t.Fatalf("expected an error for a truncated packet")
+	}
+}
+
+func TestExtractXMPFromCompressedPDFStream(t *testing.T) {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write([]byte(testXMPCustom))
+	zw.Close()
+	pdf := []byte("%PDF-1.7\n8 0 obj\n<< /Type /Metadata /Subtype /XML >>\nstream\n" + testXMP + "\nendstream\nendobj\n" +
+		fmt.Sprintf("9 0 obj\n<< /Type /Metadata /Subtype /XML /Filter /FlateDecode /Length %d >>\nstream\n", compressed.Len()) +
+		compressed.String() + "\nendstream\nendobj\n%%EOF\n")
+
+	packets := ExtractXMP(pdf)
+	if len(packets) != 2 {
+		t.Fatalf("expected the plain and the compressed packet, got %d", len(packets))
+	}
+	if producer, _ := packets[0].Get(XMPNamespacePDF, "Producer"); producer.Value != "Distiller 9" {
+		t.Fatalf("expected the plain packet first, got %+v", packets[0].Properties)
+	}
+	if project, _ := packets[1].Get("http://example.com/ns/acme/1.0/", "Project"); project.Value != "Apollo" {
+		t.Fatalf("expected the compressed packet last, got %+v", packets[1].Properties)
+	}
+	if packets := ExtractXMP([]byte("no metadata here")); len(packets) != 0 {
+		t.Fatalf("expected no packets, got %d", len(packets))
+	}
+}
+
+func TestXMPDuringExtraction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "guide.xml")
+	doc := bytes.Replace([]byte(testDocBook), []byte("<info>"), []byte("<info>"+testXMPCustom), 1)
+	if err := os.WriteFile(path, doc, 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	enabled := true
+	config := &ExtractionConfig{XMLProfile: &XMLProfileConfig{}, XMP: &enabled}
+	result, err := ExtractFileSync(path, config)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	packet, ok := result.Metadata.XMP()
+	if !ok {
+		t.Fatalf("missing XMP metadata: %v", result.Metadata.Additional)
+	}
+	if title, _ := packet.Get(XMPNamespaceDC, "title"); title.Text() != "Annual Report" {
+		t.Fatalf("unexpected title: %+v", title)
+	}
+
+	result, err = ExtractBytesSync([]byte(testDocBook), mimeXML, config)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if _, ok := result.Metadata.XMP(); ok {
+		t.Fatalf("expected no XMP metadata for a document without a packet")
+	}
+}